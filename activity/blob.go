@@ -0,0 +1,15 @@
+package activity
+
+import (
+	"context"
+	"io"
+
+	"github.com/cschleiden/go-workflows/internal/activity"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// OpenBlob returns a reader streaming the content of the given blob input from the blob store of the backend. The
+// reader has to be closed when done.
+func OpenBlob(ctx context.Context, input workflow.BlobInput) (io.ReadCloser, error) {
+	return activity.GetActivityState(ctx).Blobs.Open(ctx, input)
+}
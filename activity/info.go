@@ -0,0 +1,44 @@
+package activity
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/activity"
+)
+
+// ActivityInfo describes the current execution of an activity
+type ActivityInfo struct {
+	// WorkflowInstance is the workflow instance that scheduled the activity
+	WorkflowInstance *core.WorkflowInstance
+
+	// ActivityID is the unique ID of the scheduled activity
+	ActivityID string
+
+	// ScheduleEventID is the ID of the event that scheduled the activity in the history of the workflow instance
+	ScheduleEventID int64
+
+	// Attempt is the current attempt of this activity execution
+	Attempt int
+
+	// Name is the name of the activity
+	Name string
+
+	// CorrelationID is the correlation ID given in the activity options, empty if none was given
+	CorrelationID string
+}
+
+// GetInfo returns information about the current activity execution, e.g., to correlate log lines or to derive
+// idempotency keys for calls to other services
+func GetInfo(ctx context.Context) ActivityInfo {
+	as := activity.GetActivityState(ctx)
+
+	return ActivityInfo{
+		WorkflowInstance: as.Instance,
+		ActivityID:       as.ActivityID,
+		ScheduleEventID:  as.ScheduleEventID,
+		Attempt:          as.Attempt,
+		Name:             as.Name,
+		CorrelationID:    as.CorrelationID,
+	}
+}
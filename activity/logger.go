@@ -5,6 +5,7 @@ import (
 	"log/slog"
 
 	"github.com/cschleiden/go-workflows/internal/activity"
+	"github.com/cschleiden/go-workflows/internal/contextvalue"
 )
 
 // Logger returns a logger with the workflow instance this activity is executed for set as default fields
@@ -16,3 +17,9 @@ func Logger(ctx context.Context) *slog.Logger {
 func Attempt(ctx context.Context) int {
 	return activity.GetActivityState(ctx).Attempt
 }
+
+// Header returns the value of the given header passed in client.WorkflowInstanceOptions when the workflow instance
+// was created, or an empty string if the header is not set
+func Header(ctx context.Context, key string) string {
+	return contextvalue.Headers(ctx)[key]
+}
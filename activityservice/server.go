@@ -0,0 +1,384 @@
+// Package activityservice provides a gRPC service exposing the activity queue of a backend to external workers,
+// for example activity workers implemented in other languages. The service is defined in activityservice.proto.
+package activityservice
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/workflowerrors"
+	"github.com/cschleiden/go-workflows/workflow"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative activityservice.proto
+
+type Options struct {
+	// PollTimeout is the maximum time PollActivityTask waits for a task before returning a response without a task.
+	// Defaults to 30 seconds.
+	PollTimeout time.Duration
+
+	// PollingInterval is the interval between checking the backend for tasks while waiting in PollActivityTask.
+	// Note that if you use a backend that can wait for tasks to be available (e.g. redis) this field has no effect.
+	// Defaults to 200ms.
+	PollingInterval time.Duration
+
+	// TokenKey is the key task tokens are signed with. Servers sharing a backend behind a load balancer need to use
+	// the same key, so that tokens issued by one server are accepted by the others. If not set, a random key is
+	// generated and tokens are only accepted by the server that issued them.
+	TokenKey []byte
+}
+
+var DefaultOptions = Options{
+	PollTimeout:     30 * time.Second,
+	PollingInterval: 200 * time.Millisecond,
+}
+
+// Server implements the activity service on top of a backend. Payloads are passed through as they are stored by
+// the backend, they are not decoded or re-encoded by the server.
+type Server struct {
+	UnimplementedActivityServiceServer
+
+	backend backend.Backend
+	options Options
+
+	// prepared are the queues that have been prepared for consumption
+	prepared sync.Map
+}
+
+var _ ActivityServiceServer = (*Server)(nil)
+
+// NewServer creates a new activity service server for the given backend.
+func NewServer(b backend.Backend, options *Options) *Server {
+	if options == nil {
+		options = &DefaultOptions
+	}
+
+	opts := *options
+	if opts.PollTimeout <= 0 {
+		opts.PollTimeout = DefaultOptions.PollTimeout
+	}
+
+	if opts.PollingInterval <= 0 {
+		opts.PollingInterval = DefaultOptions.PollingInterval
+	}
+
+	if len(opts.TokenKey) == 0 {
+		opts.TokenKey = make([]byte, 32)
+		if _, err := rand.Read(opts.TokenKey); err != nil {
+			panic(fmt.Errorf("generating task token key: %w", err))
+		}
+	}
+
+	return &Server{
+		backend: b,
+		options: opts,
+	}
+}
+
+// Register registers the activity service with the given gRPC server.
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	RegisterActivityServiceServer(r, s)
+}
+
+// taskToken identifies an activity task handed out to an external worker. It contains everything the backend needs
+// to extend and complete the task, so the server does not have to keep track of tasks in flight. Tokens are signed,
+// so workers cannot extend or complete tasks they have not been given.
+type taskToken struct {
+	ID              string                 `json:"id,omitempty"`
+	ActivityID      string                 `json:"activity_id,omitempty"`
+	Queue           workflow.Queue         `json:"queue,omitempty"`
+	Instance        *core.WorkflowInstance `json:"instance,omitempty"`
+	ScheduleEventID int64                  `json:"schedule_event_id,omitempty"`
+	CorrelationID   string                 `json:"correlation_id,omitempty"`
+
+	// StartedAt is when the task was handed out to the worker
+	StartedAt time.Time `json:"started_at,omitempty"`
+}
+
+func (t *taskToken) task() *backend.ActivityTask {
+	return &backend.ActivityTask{
+		ID:               t.ID,
+		ActivityID:       t.ActivityID,
+		Queue:            t.Queue,
+		WorkflowInstance: t.Instance,
+	}
+}
+
+// encodeTaskToken serializes the given token followed by its signature
+func (s *Server) encodeTaskToken(t *taskToken) ([]byte, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, s.sign(data)...), nil
+}
+
+func (s *Server) decodeTaskToken(token []byte) (*taskToken, error) {
+	if len(token) <= sha256.Size {
+		return nil, status.Error(codes.InvalidArgument, "invalid task token")
+	}
+
+	data, signature := token[:len(token)-sha256.Size], token[len(token)-sha256.Size:]
+	if !hmac.Equal(signature, s.sign(data)) {
+		return nil, status.Error(codes.PermissionDenied, "task token was not issued by this server")
+	}
+
+	var t taskToken
+	if err := json.Unmarshal(data, &t); err != nil || t.Instance == nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid task token")
+	}
+
+	return &t, nil
+}
+
+func (s *Server) sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, s.options.TokenKey)
+	mac.Write(data)
+
+	return mac.Sum(nil)
+}
+
+func (s *Server) PollActivityTask(ctx context.Context, req *PollActivityTaskRequest) (*PollActivityTaskResponse, error) {
+	queues, err := s.queues(ctx, req.GetQueues())
+	if err != nil {
+		return nil, err
+	}
+
+	pollCtx, cancel := context.WithTimeout(ctx, s.options.PollTimeout)
+	defer cancel()
+
+	task, err := s.waitForTask(pollCtx, queues)
+	if err != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+
+		if pollCtx.Err() != nil {
+			// No task became available, the worker polls again
+			return &PollActivityTaskResponse{}, nil
+		}
+
+		return nil, err
+	}
+
+	t, err := s.activityTask(task)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "converting activity task: %v", err)
+	}
+
+	return &PollActivityTaskResponse{Task: t}, nil
+}
+
+func (s *Server) StreamActivityTasks(stream ActivityService_StreamActivityTasksServer) error {
+	ctx := stream.Context()
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		queues, err := s.queues(ctx, req.GetQueues())
+		if err != nil {
+			return err
+		}
+
+		task, err := s.waitForTask(ctx, queues)
+		if err != nil {
+			if err := ctx.Err(); err != nil {
+				return status.FromContextError(err).Err()
+			}
+
+			return err
+		}
+
+		t, err := s.activityTask(task)
+		if err != nil {
+			return status.Errorf(codes.Internal, "converting activity task: %v", err)
+		}
+
+		// If the task cannot be sent, it is delivered again once its lock expires
+		if err := stream.Send(t); err != nil {
+			return err
+		}
+	}
+}
+
+// queues returns the given queues, or the default queue if none are given, after preparing them for consumption
+func (s *Server) queues(ctx context.Context, names []string) ([]workflow.Queue, error) {
+	queues := make([]workflow.Queue, 0, len(names))
+	for _, q := range names {
+		queues = append(queues, workflow.Queue(q))
+	}
+
+	if len(queues) == 0 {
+		queues = append(queues, workflow.QueueDefault)
+	}
+
+	if err := s.prepareQueues(ctx, queues); err != nil {
+		return nil, status.Errorf(codes.Internal, "preparing activity queues: %v", err)
+	}
+
+	return queues, nil
+}
+
+// waitForTask returns the next activity task on the given queues, waiting until one becomes available or the
+// context is done.
+func (s *Server) waitForTask(ctx context.Context, queues []workflow.Queue) (*backend.ActivityTask, error) {
+	for {
+		task, err := s.backend.GetActivityTask(ctx, queues)
+		if err != nil && ctx.Err() == nil {
+			return nil, status.Errorf(codes.Internal, "getting activity task: %v", err)
+		}
+
+		if task != nil {
+			return task, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-time.After(s.options.PollingInterval):
+		}
+	}
+}
+
+func (s *Server) prepareQueues(ctx context.Context, queues []workflow.Queue) error {
+	var unprepared []workflow.Queue
+	for _, q := range queues {
+		if _, ok := s.prepared.Load(q); !ok {
+			unprepared = append(unprepared, q)
+		}
+	}
+
+	if len(unprepared) == 0 {
+		return nil
+	}
+
+	if err := s.backend.PrepareActivityQueues(ctx, unprepared); err != nil {
+		return err
+	}
+
+	for _, q := range unprepared {
+		s.prepared.Store(q, struct{}{})
+	}
+
+	return nil
+}
+
+func (s *Server) activityTask(task *backend.ActivityTask) (*ActivityTask, error) {
+	a := task.Event.Attributes.(*history.ActivityScheduledAttributes)
+
+	token, err := s.encodeTaskToken(&taskToken{
+		ID:              task.ID,
+		ActivityID:      task.ActivityID,
+		Queue:           task.Queue,
+		Instance:        task.WorkflowInstance,
+		ScheduleEventID: task.Event.ScheduleEventID,
+		CorrelationID:   a.CorrelationID,
+		StartedAt:       time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([][]byte, 0, len(a.Inputs))
+	for _, input := range a.Inputs {
+		inputs = append(inputs, input)
+	}
+
+	return &ActivityTask{
+		TaskToken:     token,
+		ActivityName:  a.Name,
+		Inputs:        inputs,
+		InstanceId:    task.WorkflowInstance.InstanceID,
+		ExecutionId:   task.WorkflowInstance.ExecutionID,
+		ActivityId:    task.ActivityID,
+		Attempt:       int32(a.Attempt),
+		Queue:         string(task.Queue),
+		CorrelationId: a.CorrelationID,
+	}, nil
+}
+
+func (s *Server) ExtendActivityTask(ctx context.Context, req *ExtendActivityTaskRequest) (*ExtendActivityTaskResponse, error) {
+	token, err := s.decodeTaskToken(req.GetTaskToken())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.backend.ExtendActivityTask(ctx, token.task()); err != nil {
+		return nil, status.Errorf(codes.Internal, "extending activity task: %v", err)
+	}
+
+	return &ExtendActivityTaskResponse{}, nil
+}
+
+func (s *Server) CompleteActivityTask(ctx context.Context, req *CompleteActivityTaskRequest) (*CompleteActivityTaskResponse, error) {
+	token, err := s.decodeTaskToken(req.GetTaskToken())
+	if err != nil {
+		return nil, err
+	}
+
+	identity := req.GetIdentity()
+
+	var duration time.Duration
+	if !token.StartedAt.IsZero() {
+		duration = time.Since(token.StartedAt)
+	}
+
+	var event *history.Event
+	if e := req.GetError(); e != nil {
+		event = history.NewPendingEvent(
+			time.Now(),
+			history.EventType_ActivityFailed,
+			&history.ActivityFailedAttributes{
+				Error: &workflowerrors.Error{
+					Type:       e.GetType(),
+					Message:    e.GetMessage(),
+					Permanent:  e.GetPermanent(),
+					Stacktrace: e.GetStacktrace(),
+				},
+				CorrelationID: token.CorrelationID,
+				Worker:        identity,
+				Duration:      duration,
+			},
+			history.ScheduleEventID(token.ScheduleEventID),
+		)
+	} else {
+		event = history.NewPendingEvent(
+			time.Now(),
+			history.EventType_ActivityCompleted,
+			&history.ActivityCompletedAttributes{
+				Result:        payload.Payload(req.GetResult()),
+				CorrelationID: token.CorrelationID,
+				Worker:        identity,
+				Duration:      duration,
+			},
+			history.ScheduleEventID(token.ScheduleEventID),
+		)
+	}
+
+	if err := s.backend.CompleteActivityTask(ctx, token.task(), event); err != nil {
+		return nil, status.Errorf(codes.Internal, "completing activity task: %v", err)
+	}
+
+	return &CompleteActivityTaskResponse{}, nil
+}
@@ -0,0 +1,169 @@
+package activityservice
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func Test_Server(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	b := sqlite.NewInMemoryBackend()
+	t.Cleanup(func() { b.Close() })
+
+	// Only process workflows, activities are executed via the activity service
+	w := worker.NewWorkflowWorker(b, &worker.DefaultOptions.WorkflowWorkerOptions)
+
+	wf := func(ctx workflow.Context, input string) (string, error) {
+		return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, "external", input).Get(ctx)
+	}
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.Start(ctx))
+
+	lis := bufconn.Listen(1024 * 1024)
+	gs := grpc.NewServer()
+	NewServer(b, &Options{PollTimeout: 100 * time.Millisecond, PollingInterval: 10 * time.Millisecond}).Register(gs)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	c := client.New(b)
+	ac := NewActivityServiceClient(conn)
+
+	poll := func() *ActivityTask {
+		for {
+			res, err := ac.PollActivityTask(ctx, &PollActivityTaskRequest{})
+			require.NoError(t, err)
+
+			if res.GetTask() != nil {
+				return res.GetTask()
+			}
+		}
+	}
+
+	t.Run("PollTimeout", func(t *testing.T) {
+		res, err := ac.PollActivityTask(ctx, &PollActivityTaskRequest{})
+		require.NoError(t, err)
+		require.Nil(t, res.GetTask())
+	})
+
+	t.Run("Complete", func(t *testing.T) {
+		instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}, wf, "hello")
+		require.NoError(t, err)
+
+		task := poll()
+		require.Equal(t, "external", task.GetActivityName())
+		require.Equal(t, instance.InstanceID, task.GetInstanceId())
+		require.Equal(t, [][]byte{[]byte(`"hello"`)}, task.GetInputs())
+
+		_, err = ac.ExtendActivityTask(ctx, &ExtendActivityTaskRequest{TaskToken: task.GetTaskToken()})
+		require.NoError(t, err)
+
+		_, err = ac.CompleteActivityTask(ctx, &CompleteActivityTaskRequest{
+			TaskToken: task.GetTaskToken(),
+			Result:    []byte(`"hello world"`),
+			Identity:  "external-worker",
+		})
+		require.NoError(t, err)
+
+		r, err := client.GetWorkflowResult[string](ctx, c, instance, 10*time.Second)
+		require.NoError(t, err)
+		require.Equal(t, "hello world", r)
+	})
+
+	t.Run("Fail", func(t *testing.T) {
+		instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}, wf, "hello")
+		require.NoError(t, err)
+
+		task := poll()
+		_, err = ac.CompleteActivityTask(ctx, &CompleteActivityTaskRequest{
+			TaskToken: task.GetTaskToken(),
+			Error:     &ActivityError{Message: "activity failed", Permanent: true},
+		})
+		require.NoError(t, err)
+
+		_, err = client.GetWorkflowResult[string](ctx, c, instance, 10*time.Second)
+		require.ErrorContains(t, err, "activity failed")
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		stream, err := ac.StreamActivityTasks(ctx)
+		require.NoError(t, err)
+
+		instances := make(map[string]*workflow.Instance)
+		for i := 0; i < 2; i++ {
+			instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}, wf, "stream")
+			require.NoError(t, err)
+			instances[instance.InstanceID] = instance
+		}
+
+		for i := 0; i < 2; i++ {
+			require.NoError(t, stream.Send(&PollActivityTaskRequest{}))
+
+			task, err := stream.Recv()
+			require.NoError(t, err)
+			require.Contains(t, instances, task.GetInstanceId())
+
+			_, err = ac.CompleteActivityTask(ctx, &CompleteActivityTaskRequest{
+				TaskToken: task.GetTaskToken(),
+				Result:    []byte(`"streamed"`),
+			})
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, stream.CloseSend())
+
+		for _, instance := range instances {
+			r, err := client.GetWorkflowResult[string](ctx, c, instance, 10*time.Second)
+			require.NoError(t, err)
+			require.Equal(t, "streamed", r)
+		}
+	})
+
+	t.Run("InvalidTaskToken", func(t *testing.T) {
+		_, err := ac.CompleteActivityTask(ctx, &CompleteActivityTaskRequest{TaskToken: []byte("invalid")})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("ForgedTaskToken", func(t *testing.T) {
+		instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}, wf, "hello")
+		require.NoError(t, err)
+
+		task := poll()
+
+		// Tokens signed with a different key are rejected
+		other := NewServer(b, &Options{TokenKey: []byte("other-key")})
+		token, err := other.encodeTaskToken(&taskToken{Instance: &workflow.Instance{InstanceID: instance.InstanceID}})
+		require.NoError(t, err)
+
+		_, err = ac.CompleteActivityTask(ctx, &CompleteActivityTaskRequest{TaskToken: token, Result: []byte(`"forged"`)})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = ac.CompleteActivityTask(ctx, &CompleteActivityTaskRequest{TaskToken: task.GetTaskToken(), Result: []byte(`"hello world"`)})
+		require.NoError(t, err)
+
+		r, err := client.GetWorkflowResult[string](ctx, c, instance, 10*time.Second)
+		require.NoError(t, err)
+		require.Equal(t, "hello world", r)
+	})
+}
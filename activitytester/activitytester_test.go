@@ -21,3 +21,11 @@ func TestActivityTester(t *testing.T) {
 	require.Equal(t, 47, r)
 	require.NoError(t, err)
 }
+
+func TestActivityTester_Info(t *testing.T) {
+	ctx := WithActivityTestState(context.Background(), "activityID", "instanceID", nil)
+
+	info := activity.GetInfo(ctx)
+	require.Equal(t, "activityID", info.ActivityID)
+	require.Equal(t, "instanceID", info.WorkflowInstance.InstanceID)
+}
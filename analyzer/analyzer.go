@@ -2,6 +2,7 @@ package analyzer
 
 import (
 	"go/ast"
+	"go/token"
 	"go/types"
 
 	"golang.org/x/tools/go/analysis"
@@ -33,6 +34,13 @@ func run(pass *analysis.Pass) (interface{}, error) {
 
 	workflowImportName := "workflow"
 
+	// Functions of this package called from workflows are checked as well, they run as part of the workflow
+	c := &callGraph{
+		decls:       funcDecls(pass),
+		checked:     map[*ast.FuncDecl]bool{},
+		sideEffects: map[*ast.FuncLit]bool{},
+	}
+
 	inspector.Nodes([]ast.Node{
 		(*ast.File)(nil),
 		(*ast.FuncDecl)(nil),
@@ -40,7 +48,10 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		(*ast.RangeStmt)(nil),
 		(*ast.SelectStmt)(nil),
 		(*ast.GoStmt)(nil),
+		(*ast.SendStmt)(nil),
+		(*ast.UnaryExpr)(nil),
 		(*ast.CallExpr)(nil),
+		(*ast.FuncLit)(nil),
 	}, func(node ast.Node, push bool) bool {
 		if _, ok := node.(*ast.File); ok {
 			// New file, reset state
@@ -66,6 +77,10 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		}
 
 		switch n := node.(type) {
+		case *ast.FuncLit:
+			// Side effects are not replayed, they don't have to be deterministic
+			return !c.sideEffects[n]
+
 		case *ast.ImportSpec:
 			if n.Path.Value == `"github.com/cschleiden/go-workflows/workflow"` {
 				if n.Name != nil {
@@ -82,6 +97,7 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			}
 
 			inWorkflow = true
+			c.checked[n] = true
 
 			// Check return types
 			if n.Name.IsExported() || checkPrivateReturnValues {
@@ -108,63 +124,203 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			// Continue with the function's children
 			return true
 
-		case *ast.RangeStmt:
-			{
-				t := pass.TypesInfo.TypeOf(n.X)
-				if t == nil {
-					break
-				}
-
-				switch t.(type) {
-				case *types.Map:
-					pass.Reportf(n.Pos(), "iterating over a `map` is not deterministic and not allowed in workflows")
+		default:
+			checkNode(pass, c, node)
+		}
 
-				case *types.Chan:
-					pass.Reportf(n.Pos(), "using native channels is not allowed in workflows, use `workflow.Channel` instead")
-				}
-			}
+		// Continue with the children
+		return true
+	})
 
-		case *ast.SelectStmt:
-			pass.Reportf(n.Pos(), "`select` statements are not allowed in workflows, use `workflow.Select` instead")
+	// Check the functions called from workflows, and the functions called from them
+	for len(c.queue) > 0 {
+		decl := c.queue[0]
+		c.queue = c.queue[1:]
 
-		case *ast.GoStmt:
-			pass.Reportf(n.Pos(), "use `workflow.Go` instead of `go` in workflows")
+		if funcScope := pass.TypesInfo.Scopes[decl.Type]; funcScope != nil {
+			checkVarsInScope(pass, funcScope)
+		}
 
-		case *ast.CallExpr:
-			var pkg *ast.Ident
-			var id *ast.Ident
-			switch fun := n.Fun.(type) {
-			case *ast.SelectorExpr:
-				pkg, _ = fun.X.(*ast.Ident)
-				id = fun.Sel
+		ast.Inspect(decl.Body, func(node ast.Node) bool {
+			if fl, ok := node.(*ast.FuncLit); ok && c.sideEffects[fl] {
+				return false
 			}
 
-			if pkg == nil || id == nil {
-				break
+			if node != nil {
+				checkNode(pass, c, node)
 			}
 
-			pkgInfo := pass.TypesInfo.Uses[pkg]
-			pkgName, _ := pkgInfo.(*types.PkgName)
-			if pkgName == nil {
-				break
+			return true
+		})
+	}
+
+	return nil, nil
+}
+
+// callGraph tracks the functions of the package under analysis that are called from workflows
+type callGraph struct {
+	decls   map[*types.Func]*ast.FuncDecl
+	checked map[*ast.FuncDecl]bool
+	queue   []*ast.FuncDecl
+
+	// sideEffects are the function literals passed to workflow.SideEffect, they are not checked
+	sideEffects map[*ast.FuncLit]bool
+}
+
+// called queues the function called by the given expression for checking, if it is declared in the package under
+// analysis and has not been checked yet
+func (c *callGraph) called(pass *analysis.Pass, call *ast.CallExpr) {
+	var id *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	}
+
+	if id == nil {
+		return
+	}
+
+	fn, ok := pass.TypesInfo.Uses[id].(*types.Func)
+	if !ok {
+		return
+	}
+
+	decl, ok := c.decls[fn]
+	if !ok || decl.Body == nil || c.checked[decl] {
+		return
+	}
+
+	c.checked[decl] = true
+	c.queue = append(c.queue, decl)
+}
+
+// sideEffect records the function literals passed to the given call, if it is a call to workflow.SideEffect
+func (c *callGraph) sideEffect(pass *analysis.Pass, call *ast.CallExpr) {
+	fun := call.Fun
+	switch f := fun.(type) {
+	case *ast.IndexExpr:
+		// Explicitly instantiated, e.g., workflow.SideEffect[int]
+		fun = f.X
+	case *ast.IndexListExpr:
+		fun = f.X
+	}
+
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+
+	fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "github.com/cschleiden/go-workflows/workflow" || fn.Name() != "SideEffect" {
+		return
+	}
+
+	for _, arg := range call.Args {
+		if fl, ok := arg.(*ast.FuncLit); ok {
+			c.sideEffects[fl] = true
+		}
+	}
+}
+
+func funcDecls(pass *analysis.Pass) map[*types.Func]*ast.FuncDecl {
+	decls := map[*types.Func]*ast.FuncDecl{}
+
+	for _, f := range pass.Files {
+		for _, d := range f.Decls {
+			decl, ok := d.(*ast.FuncDecl)
+			if !ok {
+				continue
 			}
 
-			switch pkgName.Imported().Path() {
-			case "time":
-				switch id.Name {
-				case "Now":
-					pass.Reportf(n.Pos(), "`time.Now` is not allowed in workflows, use `workflow.Now` instead")
-				case "Sleep":
-					pass.Reportf(n.Pos(), "`time.Sleep` is not allowed in workflows, use `workflow.Sleep` instead")
-				}
+			if fn, ok := pass.TypesInfo.Defs[decl.Name].(*types.Func); ok {
+				decls[fn] = decl
 			}
 		}
+	}
 
-		// Continue with the children
-		return true
-	})
+	return decls
+}
 
-	return nil, nil
+// checkNode reports non-deterministic constructs in code executed as part of a workflow
+func checkNode(pass *analysis.Pass, c *callGraph, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.RangeStmt:
+		t := pass.TypesInfo.TypeOf(n.X)
+		if t == nil {
+			break
+		}
+
+		switch t.Underlying().(type) {
+		case *types.Map:
+			pass.Reportf(n.Pos(), "iterating over a `map` is not deterministic and not allowed in workflows")
+
+		case *types.Chan:
+			pass.Reportf(n.Pos(), "using native channels is not allowed in workflows, use `workflow.Channel` instead")
+		}
+
+	case *ast.SelectStmt:
+		pass.Reportf(n.Pos(), "`select` statements are not allowed in workflows, use `workflow.Select` instead")
+
+	case *ast.GoStmt:
+		pass.Reportf(n.Pos(), "use `workflow.Go` instead of `go` in workflows")
+
+	case *ast.SendStmt:
+		pass.Reportf(n.Pos(), "using native channels is not allowed in workflows, use `workflow.Channel` instead")
+
+	case *ast.UnaryExpr:
+		if n.Op == token.ARROW {
+			pass.Reportf(n.Pos(), "using native channels is not allowed in workflows, use `workflow.Channel` instead")
+		}
+
+	case *ast.CallExpr:
+		c.called(pass, n)
+		c.sideEffect(pass, n)
+		checkCall(pass, n)
+	}
+}
+
+// checkCall reports calls to functions of the standard library that are not deterministic
+func checkCall(pass *analysis.Pass, n *ast.CallExpr) {
+	var pkg *ast.Ident
+	var id *ast.Ident
+	switch fun := n.Fun.(type) {
+	case *ast.SelectorExpr:
+		pkg, _ = fun.X.(*ast.Ident)
+		id = fun.Sel
+	}
+
+	if pkg == nil || id == nil {
+		return
+	}
+
+	pkgInfo := pass.TypesInfo.Uses[pkg]
+	pkgName, _ := pkgInfo.(*types.PkgName)
+	if pkgName == nil {
+		return
+	}
+
+	switch pkgName.Imported().Path() {
+	case "time":
+		switch id.Name {
+		case "Now", "Since", "Until":
+			pass.Reportf(n.Pos(), "`time.%v` is not allowed in workflows, use `workflow.Now` instead", id.Name)
+		case "Sleep":
+			pass.Reportf(n.Pos(), "`time.Sleep` is not allowed in workflows, use `workflow.Sleep` instead")
+		case "After", "AfterFunc", "NewTimer", "Tick", "NewTicker":
+			pass.Reportf(n.Pos(), "`time.%v` is not allowed in workflows, use `workflow.ScheduleTimer` instead", id.Name)
+		}
+
+	case "math/rand", "math/rand/v2", "crypto/rand":
+		switch id.Name {
+		case "New", "NewSource", "NewPCG", "NewChaCha8", "NewZipf":
+			// Generators with a seed chosen by the workflow are deterministic
+			return
+		}
+
+		pass.Reportf(n.Pos(), "random values are not deterministic and not allowed in workflows, generate them in `workflow.SideEffect` instead")
+	}
 }
 
 func checkVarsInScope(pass *analysis.Pass, scope *types.Scope) {
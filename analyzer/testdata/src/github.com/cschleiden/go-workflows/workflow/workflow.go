@@ -1,3 +1,11 @@
 package workflow
 
 type Context interface{}
+
+type Future[T any] interface {
+	Get(ctx Context) (T, error)
+}
+
+func SideEffect[T any](ctx Context, f func(ctx Context) T) Future[T] {
+	return nil
+}
@@ -4,6 +4,7 @@ package p
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/cschleiden/go-workflows/workflow"
@@ -67,7 +68,7 @@ func wfChanRange(ctx workflow.Context) error {
 	for range make(chan int, 0) { // want "using native channels is not allowed in workflows, use `workflow.Channel` instead"
 		if ctx == nil {
 			v := make(chan int, 0) // want "using native channels is not allowed in workflows, use `workflow.Channel` instead"
-			<-v
+			<-v                    // want "using native channels is not allowed in workflows, use `workflow.Channel` instead"
 
 			for range make(chan int, 0) { // want "using native channels is not allowed in workflows, use `workflow.Channel` instead"
 			}
@@ -104,6 +105,60 @@ func wfFunctionUsage(ctx workflow.Context) error {
 	return nil
 }
 
+func wfTimeAndRandom(ctx workflow.Context) error {
+	fmt.Println(time.After(time.Second))     // want "`time.After` is not allowed in workflows, use `workflow.ScheduleTimer` instead"
+	fmt.Println(time.Since(time.Unix(0, 0))) // want "`time.Since` is not allowed in workflows, use `workflow.Now` instead"
+	fmt.Println(rand.Intn(10))               // want "random values are not deterministic and not allowed in workflows, generate them in `workflow.SideEffect` instead"
+	fmt.Println(rand.New(rand.NewSource(42)).Intn(10))
+
+	return nil
+}
+
+func wfSideEffect(ctx workflow.Context) error {
+	id, err := workflow.SideEffect(ctx, func(ctx workflow.Context) int {
+		return rand.Intn(10)
+	}).Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	workflow.SideEffect[time.Time](ctx, func(ctx workflow.Context) time.Time {
+		return time.Now()
+	})
+
+	fmt.Println(id, time.Now()) // want "`time.Now` is not allowed in workflows, use `workflow.Now` instead"
+
+	return nil
+}
+
+func wfChanSend(ctx workflow.Context) error {
+	c := make(chan int, 1) // want "using native channels is not allowed in workflows, use `workflow.Channel` instead"
+	c <- 42                // want "using native channels is not allowed in workflows, use `workflow.Channel` instead"
+
+	return nil
+}
+
+func wfCallingHelper(ctx workflow.Context) error {
+	helper()
+	helper()
+
+	return nil
+}
+
+func helper() {
+	fmt.Println(time.Now()) // want "`time.Now` is not allowed in workflows, use `workflow.Now` instead"
+
+	nestedHelper()
+}
+
+func nestedHelper() {
+	go fmt.Println("test") // want "use `workflow.Go` instead of `go` in workflows"
+}
+
+func notCalledFromWorkflow() {
+	fmt.Println(time.Now())
+}
+
 func activity(ctx context.Context) error {
 	go fmt.Println("test")
 
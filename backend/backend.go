@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/backend/metrics"
+	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/cschleiden/go-workflows/core"
 	"github.com/cschleiden/go-workflows/workflow"
 	"go.opentelemetry.io/otel/trace"
@@ -15,6 +17,24 @@ import (
 var ErrInstanceNotFound = errors.New("workflow instance not found")
 var ErrInstanceAlreadyExists = errors.New("workflow instance already exists")
 var ErrInstanceNotFinished = errors.New("workflow instance is not finished")
+var ErrInvalidResetPoint = errors.New("invalid reset point")
+var ErrInstanceNotErrored = errors.New("workflow instance is not errored")
+
+// ErrSubWorkflowsActive is returned when resetting a workflow instance while sub-workflows it started after the
+// reset point are still active or errored.
+var ErrSubWorkflowsActive = errors.New("sub-workflows started after the reset point are still active")
+
+// ErrBackendBusy is returned by backends when they are overloaded. Workers back off from polling, and execute fewer
+// tasks concurrently, when polling for or completing tasks returns it.
+var ErrBackendBusy = errors.New("backend busy")
+
+// ErrMaxActiveInstances is returned when a workflow instance could not be created because the maximum number of
+// active instances configured for the backend has been reached.
+var ErrMaxActiveInstances = errors.New("maximum number of active workflow instances reached")
+
+// ErrTaskLockLost is returned when extending the lock of a workflow task fails because the task is no longer locked
+// by the worker, e.g., because the lock expired and another worker picked up the task. Workers abandon such tasks.
+var ErrTaskLockLost = errors.New("task lock lost")
 
 type ErrNotSupported struct {
 	Message string
@@ -28,8 +48,9 @@ const TracerName = "go-workflow"
 
 //go:generate mockery --name=Backend --inpackage
 type Backend interface {
-	// CreateWorkflowInstance creates a new workflow instance
-	CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error
+	// CreateWorkflowInstance creates a new workflow instance. The given signal events are added after the started
+	// event, so they are delivered with the first workflow task of the instance.
+	CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event, signals ...*history.Event) error
 
 	// CancelWorkflowInstance cancels a running workflow instance
 	CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance, cancelEvent *history.Event) error
@@ -43,15 +64,93 @@ type Backend interface {
 	// GetWorkflowInstanceState returns the state of the given workflow instance
 	GetWorkflowInstanceState(ctx context.Context, instance *workflow.Instance) (core.WorkflowInstanceState, error)
 
+	// GetWorkflowInstanceProgress returns the latest progress reported by the given workflow instance, or nil if
+	// it has not reported any progress. Progress is stored when completing a workflow task, see WorkflowTask.Progress.
+	//
+	// If the given instance does not exist, it will return ErrInstanceNotFound
+	GetWorkflowInstanceProgress(ctx context.Context, instance *workflow.Instance) (payload.Payload, error)
+
+	// GetWorkflowInstanceUsage returns the resources used by the given execution of a workflow instance so far. Usage is
+	// accumulated whenever a workflow task is completed.
+	//
+	// If the given instance does not exist, it will return ErrInstanceNotFound
+	GetWorkflowInstanceUsage(ctx context.Context, instance *workflow.Instance) (*WorkflowInstanceUsage, error)
+
+	// GetLatestWorkflowInstance returns the most recent execution of the workflow instance with the given ID,
+	// independent of its state.
+	//
+	// If no execution exists for the given instance ID, it will return ErrInstanceNotFound
+	GetLatestWorkflowInstance(ctx context.Context, instanceID string) (*workflow.Instance, error)
+
+	// GetLatestWorkflowInstanceState returns the state of the most recent execution of the workflow instance with the
+	// given ID.
+	//
+	// If no execution exists for the given instance ID, it will return ErrInstanceNotFound
+	GetLatestWorkflowInstanceState(ctx context.Context, instanceID string) (core.WorkflowInstanceState, error)
+
+	// GetChildWorkflowInstances returns the sub-workflow instances started by the given execution, in the order they
+	// were started. For sub-workflows that continued as new, the most recent execution is returned.
+	GetChildWorkflowInstances(ctx context.Context, instance *workflow.Instance) ([]*workflow.Instance, error)
+
+	// GetWorkflowInstancesByTag returns all executions of workflow instances that have been started with the given
+	// tag, in the order they were created. Executions continued as new keep the tags of the previous execution.
+	//
+	// Backends that do not support tags return ErrNotSupported.
+	GetWorkflowInstancesByTag(ctx context.Context, tag string) ([]*TaggedWorkflowInstance, error)
+
+	// ResetWorkflowInstance resets the given workflow instance to the history event with the given sequence ID.
+	// History after that event is discarded, and the instance is re-executed from that point on.
+	//
+	// Activities and timers scheduled after the reset point are discarded, results received for work scheduled
+	// before the reset point as well as signals are delivered again. Sub-workflows started after the reset point are
+	// not canceled, as long as any of them is active or errored the reset is rejected with ErrSubWorkflowsActive.
+	//
+	// If the sequence ID is not part of the instance's history, it will return ErrInvalidResetPoint
+	ResetWorkflowInstance(ctx context.Context, instance *workflow.Instance, sequenceID int64) error
+
+	// RetryWorkflowInstance transitions an errored workflow instance back to active, resets the number of its
+	// failed workflow tasks, and queues it to be executed again with its pending events.
+	//
+	// If the instance is not errored, it will return ErrInstanceNotErrored
+	RetryWorkflowInstance(ctx context.Context, instance *workflow.Instance) error
+
 	// GetWorkflowInstanceHistory returns the workflow history for the given instance. When lastSequenceID
 	// is given, only events after that event are returned. Otherwise the full history is returned.
-	GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, lastSequenceID *int64) ([]*history.Event, error)
+	GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, lastSequenceID *int64, options ...HistoryOption) ([]*history.Event, error)
 
 	// SignalWorkflow signals a running workflow instance
 	//
 	// If the given instance does not exist, it will return an error
 	SignalWorkflow(ctx context.Context, instanceID string, event *history.Event) error
 
+	// SubscribeWorkflowInstanceUpdates returns a channel that receives an update every time a workflow task for
+	// any execution of the given instance is completed. If the latest execution has already finished, its final
+	// update is emitted right away. The channel is closed once an execution finishes, or the context is canceled.
+	//
+	// If the given instance does not exist, it will return ErrInstanceNotFound. Backends that do not support
+	// subscriptions return ErrNotSupported.
+	SubscribeWorkflowInstanceUpdates(ctx context.Context, instanceID string) (<-chan *WorkflowInstanceUpdate, error)
+
+	// TailWorkflowInstanceLogs returns a channel that receives the log lines written by workflows and activities of
+	// the given workflow instance, across all workers. The channel is closed when the context is canceled.
+	//
+	// Backends that do not support publishing instance logs, or are not configured to, return ErrNotSupported.
+	TailWorkflowInstanceLogs(ctx context.Context, instanceID string) (<-chan *InstanceLogEntry, error)
+
+	// GetActivityResult returns the activity result cached under the given key, and false if there is no
+	// result or it has expired. Backends that do not support caching activity results return ErrNotSupported.
+	GetActivityResult(ctx context.Context, key string) (payload.Payload, bool, error)
+
+	// CacheActivityResult caches the given activity result under the given key for the given duration
+	CacheActivityResult(ctx context.Context, key string, result payload.Payload, ttl time.Duration) error
+
+	// CacheActivityResultIfAbsent atomically caches the given activity result under the given key for the given
+	// duration, if no result is cached under the key yet. It returns false if a result was already cached.
+	CacheActivityResultIfAbsent(ctx context.Context, key string, result payload.Payload, ttl time.Duration) (bool, error)
+
+	// InvalidateActivityResult removes the activity result cached under the given key, if any
+	InvalidateActivityResult(ctx context.Context, key string) error
+
 	// PrepareWorkflowQueues prepares workflow queues for later consumption using this backend instane
 	PrepareWorkflowQueues(ctx context.Context, queues []workflow.Queue) error
 
@@ -68,11 +167,18 @@ type Backend interface {
 	//
 	// This checkpoints the execution. events are new events from the last workflow execution
 	// which will be added to the workflow instance history. workflowEvents are new events for the
-	// completed or other workflow instances.
+	// completed or other workflow instances. If the task has progress set, it replaces the stored progress of the
+	// workflow instance.
 	CompleteWorkflowTask(
 		ctx context.Context, task *WorkflowTask, state core.WorkflowInstanceState,
 		executedEvents, activityEvents, timerEvents []*history.Event, workflowEvents []*history.WorkflowEvent) error
 
+	// FailWorkflowTask records that executing a workflow task retrieved using GetWorkflowTask failed. The task is
+	// not completed, it is executed again once its lock expires. If the workflow tasks of the instance have failed
+	// maxFailures times in a row, the task is released and the instance becomes errored instead, and is not executed
+	// again until it is retried with RetryWorkflowInstance. Completing a workflow task resets the number of failures.
+	FailWorkflowTask(ctx context.Context, task *WorkflowTask, maxFailures int) error
+
 	// GetActivityTask returns a pending activity task or nil if there are no pending activities
 	GetActivityTask(ctx context.Context, queues []workflow.Queue) (*ActivityTask, error)
 
@@ -82,9 +188,26 @@ type Backend interface {
 	// CompleteActivityTask completes an activity task retrieved using GetActivityTask
 	CompleteActivityTask(ctx context.Context, task *ActivityTask, result *history.Event) error
 
+	// PauseActivityDispatch pauses or resumes dispatching activity tasks to the workers of all processes using the
+	// same storage, e.g., during maintenance of a downstream system. While paused, GetActivityTask returns nil even
+	// if tasks are pending. Workflows continue to run, and activity tasks they schedule are dispatched once resumed.
+	PauseActivityDispatch(ctx context.Context, paused bool) error
+
+	// DeregisterWorker releases work held by the worker with the given ID, see WithWorkerID, e.g., when it shuts down
+	// gracefully. Workflow instances are no longer sticky to the worker, and tasks that are locked by it but have not
+	// been completed become available to other workers right away instead of after their lock expires. Work of other
+	// workers using the same backend instance is not affected. An empty ID releases the work retrieved without a
+	// worker ID.
+	DeregisterWorker(ctx context.Context, workerID string) error
+
 	// GetStats returns stats about the backend
 	GetStats(ctx context.Context) (*Stats, error)
 
+	// QueueLag returns the number of workflow and activity tasks across all queues that have not been completed by
+	// the workers of this backend, both tasks that have not been delivered to a worker yet and tasks that are being
+	// worked on. A growing lag means workers cannot keep up.
+	QueueLag(ctx context.Context) (workflowLag, activityLag int64, err error)
+
 	// Tracer returns the configured trace provider for the backend
 	Tracer() trace.Tracer
 
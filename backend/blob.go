@@ -0,0 +1,25 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrBlobNotFound is returned by a BlobStore when the requested blob does not exist
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores large inputs outside of the history of workflow instances, see workflow.BlobInput. Blobs are
+// written and read as streams, so they never have to be held in memory as a whole.
+type BlobStore interface {
+	// Put stores the content of the given reader as the blob with the given key. The blob must only become readable
+	// once all of its content has been stored.
+	Put(ctx context.Context, key string, r io.Reader) error
+
+	// Get returns a reader for the content of the blob with the given key. If the blob does not exist, it returns
+	// ErrBlobNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the blob with the given key. Removing a blob that does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,57 @@
+package converter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"github.com/cschleiden/go-workflows/backend/payload"
+)
+
+// NewAESGCMCodec returns a codec encrypting payloads with AES-GCM. The key has to be 16, 24, or 32 bytes long to
+// select AES-128, AES-192, or AES-256. Each payload is encrypted with a random nonce, which is prepended to it.
+func NewAESGCMCodec(key []byte) (PayloadCodec, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGCMCodec{aead: aead}, nil
+}
+
+type aesGCMCodec struct {
+	aead cipher.AEAD
+}
+
+func (c *aesGCMCodec) Encode(p payload.Payload) (payload.Payload, error) {
+	if len(p) == 0 {
+		return p, nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(p)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return c.aead.Seal(nonce, nonce, p, nil), nil
+}
+
+func (c *aesGCMCodec) Decode(p payload.Payload) (payload.Payload, error) {
+	if len(p) == 0 {
+		return p, nil
+	}
+
+	if len(p) < c.aead.NonceSize() {
+		return nil, errors.New("encrypted payload too short")
+	}
+
+	nonce, ciphertext := p[:c.aead.NonceSize()], p[c.aead.NonceSize():]
+
+	return c.aead.Open(nil, nonce, ciphertext, nil)
+}
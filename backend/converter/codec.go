@@ -0,0 +1,83 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend/payload"
+)
+
+// PayloadCodec transforms serialized payloads, e.g., to compress or encrypt them before they are stored. Empty
+// payloads, which do not hold a value, should be returned unchanged.
+type PayloadCodec interface {
+	// Encode transforms the given payload before it is stored
+	Encode(p payload.Payload) (payload.Payload, error)
+
+	// Decode reverses Encode
+	Decode(p payload.Payload) (payload.Payload, error)
+}
+
+// NewCodecConverter returns a converter that serializes values using the given converter, and then passes payloads
+// through the given codecs in order, e.g., compression followed by encryption. When reading payloads, codecs are
+// applied in reverse order.
+//
+// All workers and clients of a backend need to use the same codecs, and payloads of existing instances must remain
+// readable: codecs can only be changed once no instance relies on the previous configuration.
+//
+// Codecs are only applied to payloads. Errors of workflows and activities, including their messages and stack
+// traces, are stored unencoded.
+func NewCodecConverter(c Converter, codecs ...PayloadCodec) *CodecConverter {
+	return &CodecConverter{
+		converter: c,
+		codecs:    codecs,
+	}
+}
+
+// CodecConverter applies a chain of PayloadCodecs to the payloads of a converter
+type CodecConverter struct {
+	converter Converter
+	codecs    []PayloadCodec
+}
+
+var _ Converter = (*CodecConverter)(nil)
+
+func (cc *CodecConverter) To(v interface{}) (payload.Payload, error) {
+	p, err := cc.converter.To(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return cc.Encode(p)
+}
+
+func (cc *CodecConverter) From(data payload.Payload, v interface{}) error {
+	p, err := cc.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	return cc.converter.From(p, v)
+}
+
+// Encode applies the codecs to a payload serialized by the wrapped converter
+func (cc *CodecConverter) Encode(p payload.Payload) (payload.Payload, error) {
+	for _, codec := range cc.codecs {
+		var err error
+		if p, err = codec.Encode(p); err != nil {
+			return nil, fmt.Errorf("encoding payload: %w", err)
+		}
+	}
+
+	return p, nil
+}
+
+// Decode reverses Encode, returning the payload as serialized by the wrapped converter
+func (cc *CodecConverter) Decode(p payload.Payload) (payload.Payload, error) {
+	for i := len(cc.codecs) - 1; i >= 0; i-- {
+		var err error
+		if p, err = cc.codecs[i].Decode(p); err != nil {
+			return nil, fmt.Errorf("decoding payload: %w", err)
+		}
+	}
+
+	return p, nil
+}
@@ -0,0 +1,168 @@
+package converter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/stretchr/testify/require"
+)
+
+type testValue struct {
+	Name  string
+	Count int
+}
+
+func Test_CodecConverter_RoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	gz, err := NewGzipCodec(gzip.BestCompression)
+	require.NoError(t, err)
+
+	aesgcm, err := NewAESGCMCodec(key)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		codecs []PayloadCodec
+	}{
+		{"None", nil},
+		{"Gzip", []PayloadCodec{gz}},
+		{"AESGCM", []PayloadCodec{aesgcm}},
+		{"GzipThenAESGCM", []PayloadCodec{gz, aesgcm}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCodecConverter(DefaultConverter, tt.codecs...)
+
+			v := testValue{Name: "secret-value", Count: 42}
+
+			p, err := c.To(v)
+			require.NoError(t, err)
+
+			if len(tt.codecs) > 0 {
+				require.False(t, bytes.Contains(p, []byte("secret-value")))
+			}
+
+			var r testValue
+			require.NoError(t, c.From(p, &r))
+			require.Equal(t, v, r)
+		})
+	}
+}
+
+func Test_CodecConverter_AppliesCodecsInOrder(t *testing.T) {
+	key := make([]byte, 16)
+	aesgcm, err := NewAESGCMCodec(key)
+	require.NoError(t, err)
+
+	gz, err := NewGzipCodec(gzip.DefaultCompression)
+	require.NoError(t, err)
+
+	c := NewCodecConverter(DefaultConverter, gz, aesgcm)
+
+	p, err := c.To("hello")
+	require.NoError(t, err)
+
+	// Encrypted last, so the payload needs to be decrypted before it can be decompressed
+	compressed, err := aesgcm.Decode(p)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(compressed, gzipMagic))
+
+	plain, err := gz.Decode(compressed)
+	require.NoError(t, err)
+	require.Equal(t, payload.Payload(`"hello"`), plain)
+}
+
+func Test_GzipCodec_DecodesUncompressedPayloads(t *testing.T) {
+	gz, err := NewGzipCodec(gzip.DefaultCompression)
+	require.NoError(t, err)
+
+	p, err := gz.Decode(payload.Payload(`{"a":1}`))
+	require.NoError(t, err)
+	require.Equal(t, payload.Payload(`{"a":1}`), p)
+
+	_, err = NewGzipCodec(42)
+	require.Error(t, err)
+}
+
+func Test_AESGCMCodec(t *testing.T) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	c, err := NewAESGCMCodec(key)
+	require.NoError(t, err)
+
+	// Same plaintext is encrypted differently every time
+	p1, err := c.Encode(payload.Payload("data"))
+	require.NoError(t, err)
+	p2, err := c.Encode(payload.Payload("data"))
+	require.NoError(t, err)
+	require.NotEqual(t, p1, p2)
+
+	// Tampered payloads are rejected
+	p1[len(p1)-1] ^= 0xff
+	_, err = c.Decode(p1)
+	require.Error(t, err)
+
+	// Wrong key
+	other, err := NewAESGCMCodec(make([]byte, 32))
+	require.NoError(t, err)
+	_, err = other.Decode(p2)
+	require.Error(t, err)
+
+	// Empty payloads are not encrypted
+	p, err := c.Encode(nil)
+	require.NoError(t, err)
+	require.Empty(t, p)
+
+	_, err = NewAESGCMCodec([]byte("short"))
+	require.Error(t, err)
+}
+
+func Test_CodecConverter_Stream(t *testing.T) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	gz, err := NewGzipCodec(gzip.BestCompression)
+	require.NoError(t, err)
+
+	aesgcm, err := NewAESGCMCodec(key)
+	require.NoError(t, err)
+
+	cc := NewCodecConverter(DefaultConverter, gz, aesgcm)
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{"Empty", []byte{}},
+		{"SingleChunk", []byte("hello world")},
+		{"MultipleChunks", bytes.Repeat([]byte("0123456789"), 3*streamChunkSize/10+7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := cc.EncodeStream(bytes.NewReader(tt.content))
+			stored, err := io.ReadAll(encoded)
+			require.NoError(t, err)
+			require.NoError(t, encoded.Close())
+
+			if len(tt.content) > 0 {
+				require.False(t, bytes.Contains(stored, tt.content[:10]))
+			}
+
+			decoded, err := io.ReadAll(cc.DecodeStream(bytes.NewReader(stored)))
+			require.NoError(t, err)
+			require.Equal(t, tt.content, append([]byte{}, decoded...))
+		})
+	}
+}
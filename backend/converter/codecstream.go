@@ -0,0 +1,111 @@
+package converter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cschleiden/go-workflows/backend/payload"
+)
+
+// streamChunkSize is the size of the chunks streams are split into before passing them through the codecs
+const streamChunkSize = 64 * 1024
+
+// EncodeStream returns a reader with the content of the given reader passed through the codecs. The content is
+// split into chunks which are encoded separately, so the stream never has to be held in memory as a whole. Every
+// encoded chunk is prefixed with its length. The returned reader has to be closed once it is no longer read from.
+func (cc *CodecConverter) EncodeStream(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		buf := make([]byte, streamChunkSize)
+		header := make([]byte, binary.MaxVarintLen64)
+
+		for {
+			n, err := io.ReadFull(r, buf)
+			if n > 0 {
+				p, encErr := cc.Encode(payload.Payload(buf[:n]))
+				if encErr != nil {
+					pw.CloseWithError(encErr)
+					return
+				}
+
+				l := binary.PutUvarint(header, uint64(len(p)))
+				if _, err := pw.Write(header[:l]); err != nil {
+					return
+				}
+
+				if _, err := pw.Write(p); err != nil {
+					return
+				}
+			}
+
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				pw.Close()
+				return
+			} else if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	return pr
+}
+
+// DecodeStream reverses EncodeStream
+func (cc *CodecConverter) DecodeStream(r io.Reader) io.Reader {
+	return &decodingReader{cc: cc, r: bufio.NewReader(r)}
+}
+
+type decodingReader struct {
+	cc  *CodecConverter
+	r   *bufio.Reader
+	buf []byte
+	err error
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		d.buf, d.err = d.next()
+	}
+
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+
+	return n, nil
+}
+
+// next reads and decodes the next chunk of the stream
+func (d *decodingReader) next() ([]byte, error) {
+	l, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+
+		return nil, fmt.Errorf("reading chunk length: %w", err)
+	}
+
+	if l > 2*streamChunkSize+1024 {
+		return nil, errors.New("invalid chunk length")
+	}
+
+	chunk := make([]byte, l)
+	if _, err := io.ReadFull(d.r, chunk); err != nil {
+		return nil, fmt.Errorf("reading chunk: %w", err)
+	}
+
+	p, err := d.cc.Decode(chunk)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
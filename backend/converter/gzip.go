@@ -0,0 +1,64 @@
+package converter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/cschleiden/go-workflows/backend/payload"
+)
+
+// gzipMagic are the first bytes of every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// NewGzipCodec returns a codec compressing payloads with gzip at the given level, e.g., gzip.DefaultCompression.
+// Payloads that are not gzip compressed are decoded unchanged, so compression can be enabled for existing instances.
+func NewGzipCodec(level int) (PayloadCodec, error) {
+	// Validate the level up front
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		return nil, err
+	}
+
+	return &gzipCodec{level: level}, nil
+}
+
+type gzipCodec struct {
+	level int
+}
+
+func (c *gzipCodec) Encode(p payload.Payload) (payload.Payload, error) {
+	if len(p) == 0 {
+		return p, nil
+	}
+
+	var buf bytes.Buffer
+
+	w, err := gzip.NewWriterLevel(&buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(p); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *gzipCodec) Decode(p payload.Payload) (payload.Payload, error) {
+	if !bytes.HasPrefix(p, gzipMagic) {
+		return p, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(p))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
@@ -1,17 +1,198 @@
 package converter
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"time"
 
 	"github.com/cschleiden/go-workflows/backend/payload"
 )
 
-type jsonConverter struct{}
+// JSONOption configures the JSON converter
+type JSONOption func(*jsonConverter)
+
+// WithTimeLocation preserves the location of time.Time values, e.g., "Europe/Berlin". By default, time.Time values
+// are stored with nanosecond precision and their UTC offset, and are restored in a location with a fixed offset.
+// Locations are only preserved for time.Time values passed directly, not for values nested in other types. Values
+// are restored with their offset if the location is not known to the decoding worker.
+func WithTimeLocation() JSONOption {
+	return func(jc *jsonConverter) {
+		jc.timeLocation = true
+	}
+}
+
+// WithUseNumber decodes numbers in values of type interface{}, e.g., the results of SideEffect[any] or values in a
+// map[string]interface{}, as json.Number instead of float64. float64 cannot represent all integers above 2^53, so
+// large int64 values, like IDs, lose precision otherwise. Numbers decoded into typed values are not affected.
+func WithUseNumber() JSONOption {
+	return func(jc *jsonConverter) {
+		jc.useNumber = true
+	}
+}
+
+// NewJSONConverter creates a converter that serializes values as JSON. Without options, it behaves like
+// DefaultConverter.
+func NewJSONConverter(opts ...JSONOption) Converter {
+	jc := &jsonConverter{}
+	for _, opt := range opts {
+		opt(jc)
+	}
+
+	return jc
+}
+
+type jsonConverter struct {
+	timeLocation bool
+	useNumber    bool
+}
+
+// locationTime is a time.Time value together with the name of its location
+type locationTime struct {
+	Time     time.Time `json:"time"`
+	Location string    `json:"location,omitempty"`
+}
 
 func (jc *jsonConverter) To(v interface{}) (payload.Payload, error) {
+	if jc.timeLocation {
+		switch t := v.(type) {
+		case time.Time:
+			return json.Marshal(&locationTime{Time: t, Location: t.Location().String()})
+		case *time.Time:
+			if t != nil {
+				return json.Marshal(&locationTime{Time: *t, Location: t.Location().String()})
+			}
+		}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil || v == nil || sortsKeys(reflect.TypeOf(v)) {
+		return data, err
+	}
+
+	return sortKeys(data)
+}
+
+var (
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+	timeType      = reflect.TypeFor[time.Time]()
+	numberType    = reflect.TypeFor[json.Number]()
+)
+
+// sortedTypes caches for types whether encoding/json sorts the keys of all objects when encoding their values
+var sortedTypes sync.Map
+
+// sortsKeys returns true if encoding/json sorts the keys of all objects when encoding values of the given type.
+// Keys of maps are always sorted, and fields of structs are encoded in the order they are declared in. The output of
+// custom json.Marshaler implementations, and values stored in interfaces, are not known in advance.
+func sortsKeys(t reflect.Type) bool {
+	if sorted, ok := sortedTypes.Load(t); ok {
+		return sorted.(bool)
+	}
+
+	sorted := typeSortsKeys(t, map[reflect.Type]bool{})
+	sortedTypes.Store(t, sorted)
+
+	return sorted
+}
+
+func typeSortsKeys(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		// Recursive type, the outcome depends on the other fields
+		return true
+	}
+
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	if t == timeType || t == numberType {
+		return true
+	}
+
+	if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return false
+
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return typeSortsKeys(t.Elem(), visiting)
+
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() || f.Anonymous {
+				if !typeSortsKeys(f.Type, visiting) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// sortKeys re-encodes the given JSON value with the keys of all objects sorted, so that values are always converted
+// to the same payload, regardless of how custom json.Marshaler implementations order keys.
+func sortKeys(data []byte) ([]byte, error) {
+	if !bytes.ContainsRune(data, '{') {
+		return data, nil
+	}
+
+	// Keep numbers as they are, decoding them as float64 might lose precision
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
 	return json.Marshal(v)
 }
 
 func (jc *jsonConverter) From(data payload.Payload, vptr interface{}) error {
+	// Values written with WithTimeLocation can always be read
+	if t, ok := vptr.(*time.Time); ok && bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var lt locationTime
+		if err := json.Unmarshal(data, &lt); err != nil {
+			return err
+		}
+
+		*t = lt.Time
+		if lt.Location == "" {
+			return nil
+		}
+
+		// Only use the location if it matches the stored offset, it might differ between workers
+		if loc, err := time.LoadLocation(lt.Location); err == nil {
+			_, offset := lt.Time.Zone()
+			if _, locOffset := lt.Time.In(loc).Zone(); locOffset == offset {
+				*t = lt.Time.In(loc)
+			}
+		}
+
+		return nil
+	}
+
+	if jc.useNumber {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(vptr); err != nil {
+			return err
+		}
+
+		// Match json.Unmarshal, which does not allow data after the value
+		if _, err := dec.Token(); err != io.EOF {
+			return errors.New("invalid data after JSON value")
+		}
+
+		return nil
+	}
+
 	return json.Unmarshal(data, vptr)
 }
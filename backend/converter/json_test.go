@@ -0,0 +1,168 @@
+package converter
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_JSONConverter_Time(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	times := []time.Time{
+		time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.UTC),
+		time.Date(2024, 7, 1, 12, 30, 45, 1, berlin),
+		time.Date(2024, 12, 1, 23, 59, 59, 999999999, newYork),
+		time.Date(2024, 1, 1, 0, 0, 0, 500000000, time.FixedZone("", 5*60*60+30*60)),
+	}
+
+	tests := []struct {
+		name         string
+		c            Converter
+		keepLocation bool
+	}{
+		{"Default", DefaultConverter, false},
+		{"WithTimeLocation", NewJSONConverter(WithTimeLocation()), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, tm := range times {
+				p, err := tt.c.To(tm)
+				require.NoError(t, err)
+
+				var r time.Time
+				require.NoError(t, tt.c.From(p, &r))
+
+				// Nanosecond precision and offset are always preserved
+				require.True(t, tm.Equal(r), "expected %v, got %v", tm, r)
+				require.Equal(t, tm.Format(time.RFC3339Nano), r.Format(time.RFC3339Nano))
+
+				if tt.keepLocation {
+					require.Equal(t, tm.Location().String(), r.Location().String())
+				}
+			}
+		})
+	}
+}
+
+func Test_JSONConverter_TimeLocation_ReadByDefaultConverter(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tm := time.Date(2024, 7, 1, 12, 30, 45, 1, berlin)
+
+	p, err := NewJSONConverter(WithTimeLocation()).To(tm)
+	require.NoError(t, err)
+
+	var r time.Time
+	require.NoError(t, DefaultConverter.From(p, &r))
+	require.True(t, tm.Equal(r))
+	require.Equal(t, "Europe/Berlin", r.Location().String())
+}
+
+func Test_JSONConverter_TimeLocation_UnknownLocation(t *testing.T) {
+	var r time.Time
+	require.NoError(t, DefaultConverter.From([]byte(`{"time":"2024-07-01T12:30:45.000000001+02:00","location":"Unknown/Location"}`), &r))
+
+	_, offset := r.Zone()
+	require.Equal(t, 2*60*60, offset)
+	require.Equal(t, 1, r.Nanosecond())
+}
+
+func Test_JSONConverter_Duration(t *testing.T) {
+	for _, d := range []time.Duration{time.Nanosecond, 90 * time.Minute, -time.Millisecond, 1<<63 - 1} {
+		p, err := DefaultConverter.To(d)
+		require.NoError(t, err)
+
+		var r time.Duration
+		require.NoError(t, DefaultConverter.From(p, &r))
+		require.Equal(t, d, r)
+	}
+}
+
+func Test_JSONConverter_LargeIntegers(t *testing.T) {
+	// Above 2^53, not every integer can be represented as float64
+	const large = int64(1<<53 + 1)
+
+	t.Run("Default", func(t *testing.T) {
+		p, err := DefaultConverter.To(large)
+		require.NoError(t, err)
+
+		var r interface{}
+		require.NoError(t, DefaultConverter.From(p, &r))
+		require.IsType(t, float64(0), r)
+		require.NotEqual(t, large, int64(r.(float64)))
+	})
+
+	t.Run("WithUseNumber", func(t *testing.T) {
+		c := NewJSONConverter(WithUseNumber())
+
+		p, err := c.To(map[string]interface{}{"id": large})
+		require.NoError(t, err)
+
+		var r interface{}
+		require.NoError(t, c.From(p, &r))
+
+		n, ok := r.(map[string]interface{})["id"].(json.Number)
+		require.True(t, ok)
+
+		i, err := n.Int64()
+		require.NoError(t, err)
+		require.Equal(t, large, i)
+
+		// Typed values are decoded as usual
+		var typed map[string]int64
+		require.NoError(t, c.From(p, &typed))
+		require.Equal(t, large, typed["id"])
+	})
+
+	t.Run("WithUseNumber_TrailingData", func(t *testing.T) {
+		var r interface{}
+		require.Error(t, NewJSONConverter(WithUseNumber()).From([]byte(`1 2`), &r))
+	})
+}
+
+type unsortedMarshaler struct{}
+
+func (unsortedMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{"z":1,"a":{"y":2,"b":3}}`), nil
+}
+
+func Test_JSONConverter_DeterministicMaps(t *testing.T) {
+	v := map[string]interface{}{
+		"zeta":  1,
+		"alpha": map[string]interface{}{"c": "x", "b": []interface{}{map[string]int{"z": 1, "a": 2}}, "a": true},
+		"mid":   unsortedMarshaler{},
+		"large": int64(1<<53 + 1),
+	}
+
+	expected := `{"alpha":{"a":true,"b":[{"a":2,"z":1}],"c":"x"},"large":9007199254740993,"mid":{"a":{"b":3,"y":2},"z":1},"zeta":1}`
+
+	for _, c := range []Converter{DefaultConverter, NewJSONConverter(WithUseNumber())} {
+		for i := 0; i < 100; i++ {
+			p, err := c.To(v)
+			require.NoError(t, err)
+			require.Equal(t, expected, string(p))
+		}
+	}
+
+	// Custom marshalers are sorted also when they are not nested
+	p, err := DefaultConverter.To(unsortedMarshaler{})
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"b":3,"y":2},"z":1}`, string(p))
+
+	// Structs keep their field order
+	p, err = DefaultConverter.To(struct {
+		B int
+		A int
+	}{1, 2})
+	require.NoError(t, err)
+	require.Equal(t, `{"B":1,"A":2}`, string(p))
+}
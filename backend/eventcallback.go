@@ -0,0 +1,99 @@
+package backend
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/log"
+)
+
+// EventCallback is invoked for every event added to the history of a workflow instance, after the workflow task
+// that executed the event has been persisted.
+type EventCallback func(instance *core.WorkflowInstance, event *history.Event)
+
+// EventDispatcher invokes an EventCallback asynchronously. Events of the same instance are passed to the callback one
+// at a time and in the order they were dispatched, events of different instances are delivered concurrently.
+//
+// A nil *EventDispatcher is valid and drops all events.
+type EventDispatcher struct {
+	callback EventCallback
+	logger   *slog.Logger
+
+	mu     sync.Mutex
+	queues map[string][]eventDispatch
+}
+
+type eventDispatch struct {
+	instance *core.WorkflowInstance
+	event    *history.Event
+}
+
+// NewEventDispatcher returns a dispatcher for the given callback, or nil if callback is nil.
+func NewEventDispatcher(callback EventCallback, logger *slog.Logger) *EventDispatcher {
+	if callback == nil {
+		return nil
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &EventDispatcher{
+		callback: callback,
+		logger:   logger,
+		queues:   make(map[string][]eventDispatch),
+	}
+}
+
+// Dispatch queues the events of the given instance for the callback without blocking
+func (d *EventDispatcher) Dispatch(instance *core.WorkflowInstance, events []*history.Event) {
+	if d == nil || len(events) == 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	queue, running := d.queues[instance.InstanceID]
+	for _, event := range events {
+		queue = append(queue, eventDispatch{instance: instance, event: event})
+	}
+	d.queues[instance.InstanceID] = queue
+
+	if !running {
+		go d.run(instance.InstanceID)
+	}
+}
+
+// run delivers the queued events of an instance until its queue is drained
+func (d *EventDispatcher) run(instanceID string) {
+	for {
+		d.mu.Lock()
+		queue := d.queues[instanceID]
+		if len(queue) == 0 {
+			delete(d.queues, instanceID)
+			d.mu.Unlock()
+			return
+		}
+		next := queue[0]
+		d.queues[instanceID] = queue[1:]
+		d.mu.Unlock()
+
+		d.invoke(next)
+	}
+}
+
+func (d *EventDispatcher) invoke(e eventDispatch) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.logger.Error("event callback panicked",
+				log.InstanceIDKey, e.instance.InstanceID,
+				log.EventIDKey, e.event.ID,
+				"panic", r)
+		}
+	}()
+
+	d.callback(e.instance, e.event)
+}
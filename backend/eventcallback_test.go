@@ -0,0 +1,80 @@
+package backend
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EventDispatcher_OrdersEventsPerInstance(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string][]int64)
+
+	var wg sync.WaitGroup
+	wg.Add(200)
+
+	d := NewEventDispatcher(func(instance *core.WorkflowInstance, event *history.Event) {
+		defer wg.Done()
+
+		// Slow callbacks must not reorder events of an instance
+		if event.SequenceID%10 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		received[instance.InstanceID] = append(received[instance.InstanceID], event.SequenceID)
+	}, nil)
+
+	a := core.NewWorkflowInstance("a", "")
+	b := core.NewWorkflowInstance("b", "")
+
+	for i := int64(0); i < 100; i += 5 {
+		for _, instance := range []*core.WorkflowInstance{a, b} {
+			events := make([]*history.Event, 0, 5)
+			for j := i; j < i+5; j++ {
+				events = append(events, &history.Event{SequenceID: j})
+			}
+
+			d.Dispatch(instance, events)
+		}
+	}
+
+	wg.Wait()
+
+	for _, id := range []string{"a", "b"} {
+		require.Len(t, received[id], 100)
+		for i, seqID := range received[id] {
+			require.Equal(t, int64(i), seqID)
+		}
+	}
+}
+
+func Test_EventDispatcher_RecoversPanics(t *testing.T) {
+	done := make(chan int64, 2)
+
+	d := NewEventDispatcher(func(instance *core.WorkflowInstance, event *history.Event) {
+		done <- event.SequenceID
+
+		if event.SequenceID == 1 {
+			panic("projection failed")
+		}
+	}, nil)
+
+	d.Dispatch(core.NewWorkflowInstance("a", ""), []*history.Event{{SequenceID: 1}, {SequenceID: 2}})
+
+	require.Equal(t, int64(1), <-done)
+	require.Equal(t, int64(2), <-done)
+}
+
+func Test_EventDispatcher_Nil(t *testing.T) {
+	d := NewEventDispatcher(nil, nil)
+	require.Nil(t, d)
+
+	// Dispatching on a nil dispatcher is a no-op
+	d.Dispatch(core.NewWorkflowInstance("a", ""), []*history.Event{{SequenceID: 1}})
+}
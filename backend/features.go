@@ -4,4 +4,8 @@ type Feature int
 
 const (
 	Feature_Expiration Feature = iota
+
+	// Feature_TimerCompaction is supported by backends removing canceled timers from the history, see
+	// WithCanceledTimerCompaction.
+	Feature_TimerCompaction
 )
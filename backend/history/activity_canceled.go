@@ -0,0 +1,3 @@
+package history
+
+type ActivityCanceledAttributes struct{}
@@ -1,7 +1,20 @@
 package history
 
-import "github.com/cschleiden/go-workflows/backend/payload"
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/payload"
+)
 
 type ActivityCompletedAttributes struct {
 	Result payload.Payload `json:"result,omitempty"`
+
+	// CorrelationID is the correlation ID the activity was scheduled with
+	CorrelationID string `json:"correlation_id,omitempty"`
+
+	// Worker is the identity of the worker that executed the activity
+	Worker string `json:"worker,omitempty"`
+
+	// Duration is how long executing the activity took
+	Duration time.Duration `json:"duration,omitempty"`
 }
@@ -1,6 +1,8 @@
 package history
 
 import (
+	"time"
+
 	"github.com/cschleiden/go-workflows/backend/metadata"
 	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/cschleiden/go-workflows/core"
@@ -11,9 +13,35 @@ type ActivityScheduledAttributes struct {
 
 	Attempt int `json:"attempt,omitempty"`
 
+	// ActivityID links the attempts of an activity. It is the schedule event ID of the first attempt.
+	ActivityID int64 `json:"activity_id,omitempty"`
+
 	Inputs []payload.Payload `json:"inputs,omitempty"`
 
 	Metadata *metadata.WorkflowMetadata `json:"metadata,omitempty"`
 
 	Queue core.Queue `json:"queue,omitempty"`
+
+	// CorrelationID is the optional ID given to correlate the activity with external systems
+	CorrelationID string `json:"correlation_id,omitempty"`
+
+	// Deadline is the deadline of the workflow execution that scheduled the activity, if it has one
+	Deadline *time.Time `json:"deadline,omitempty"`
+
+	// RetryOptions are the effective retry options of the activity. Histories recorded before they were added do not
+	// contain them.
+	RetryOptions *RetryOptions `json:"retry_options,omitempty"`
+}
+
+// RetryOptions are the retry options an activity has been scheduled with
+type RetryOptions struct {
+	MaxAttempts int `json:"max_attempts,omitempty"`
+
+	FirstRetryInterval time.Duration `json:"first_retry_interval,omitempty"`
+
+	MaxRetryInterval time.Duration `json:"max_retry_interval,omitempty"`
+
+	BackoffCoefficient float64 `json:"backoff_coefficient,omitempty"`
+
+	RetryTimeout time.Duration `json:"retry_timeout,omitempty"`
 }
@@ -0,0 +1,120 @@
+package history
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	attributeTypesMu sync.RWMutex
+	attributeTypes   = map[EventType]func() interface{}{}
+
+	attributeUpgraders = map[EventType]map[int]AttributesUpgrader{}
+)
+
+// VersionedAttributes is implemented by attributes whose schema is versioned. The version is stored with the
+// serialized attributes. When attributes of an older version are deserialized, they are converted to the current
+// version by the upgraders registered with RegisterAttributeUpgrader.
+type VersionedAttributes interface {
+	// AttributesVersion returns the current version of the attributes schema, starting at 1. Attributes serialized
+	// before the type was versioned have version 0.
+	AttributesVersion() int
+}
+
+// AttributesUpgrader converts serialized attributes of one version to the next version
+type AttributesUpgrader func(attributes []byte) ([]byte, error)
+
+func init() {
+	mustRegister := func(eventType EventType, newAttributes func() interface{}) {
+		if err := RegisterAttributeType(eventType, newAttributes); err != nil {
+			panic(err)
+		}
+	}
+
+	mustRegister(EventType_WorkflowExecutionStarted, func() interface{} { return &ExecutionStartedAttributes{} })
+	mustRegister(EventType_WorkflowExecutionContinuedAsNew, func() interface{} { return &ExecutionContinuedAsNewAttributes{} })
+	mustRegister(EventType_WorkflowExecutionFinished, func() interface{} { return &ExecutionCompletedAttributes{} })
+	mustRegister(EventType_WorkflowExecutionCanceled, func() interface{} { return &ExecutionCanceledAttributes{} })
+	mustRegister(EventType_WorkflowExecutionTerminated, func() interface{} { return &ExecutionTerminatedAttributes{} })
+	mustRegister(EventType_WorkflowExecutionReset, func() interface{} { return &ExecutionResetAttributes{} })
+
+	mustRegister(EventType_WorkflowTaskStarted, func() interface{} { return &WorkflowTaskStartedAttributes{} })
+
+	mustRegister(EventType_ActivityScheduled, func() interface{} { return &ActivityScheduledAttributes{} })
+	mustRegister(EventType_ActivityCompleted, func() interface{} { return &ActivityCompletedAttributes{} })
+	mustRegister(EventType_ActivityFailed, func() interface{} { return &ActivityFailedAttributes{} })
+	mustRegister(EventType_ActivityCanceled, func() interface{} { return &ActivityCanceledAttributes{} })
+
+	mustRegister(EventType_SignalReceived, func() interface{} { return &SignalReceivedAttributes{} })
+
+	mustRegister(EventType_SideEffectResult, func() interface{} { return &SideEffectResultAttributes{} })
+
+	mustRegister(EventType_TraceStarted, func() interface{} { return &TraceStartedAttributes{} })
+
+	mustRegister(EventType_VersionRecorded, func() interface{} { return &VersionRecordedAttributes{} })
+
+	mustRegister(EventType_TimerScheduled, func() interface{} { return &TimerScheduledAttributes{} })
+	mustRegister(EventType_TimerFired, func() interface{} { return &TimerFiredAttributes{} })
+	mustRegister(EventType_TimerCanceled, func() interface{} { return &TimerCanceledAttributes{} })
+
+	mustRegister(EventType_SubWorkflowScheduled, func() interface{} { return &SubWorkflowScheduledAttributes{} })
+	mustRegister(EventType_SubWorkflowCancellationRequested, func() interface{} { return &SubWorkflowCancellationRequestedAttributes{} })
+	mustRegister(EventType_SubWorkflowCompleted, func() interface{} { return &SubWorkflowCompletedAttributes{} })
+	mustRegister(EventType_SubWorkflowFailed, func() interface{} { return &SubWorkflowFailedAttributes{} })
+}
+
+// RegisterAttributeType registers the attributes type for the given event type. newAttributes has to return a
+// pointer to a new, empty attributes value, which is used when deserializing events of that type.
+func RegisterAttributeType(eventType EventType, newAttributes func() interface{}) error {
+	attributeTypesMu.Lock()
+	defer attributeTypesMu.Unlock()
+
+	if _, ok := attributeTypes[eventType]; ok {
+		return fmt.Errorf("attributes for event type %v already registered", eventType)
+	}
+
+	attributeTypes[eventType] = newAttributes
+
+	return nil
+}
+
+// RegisterAttributeUpgrader registers a function converting serialized attributes of the given event type from
+// fromVersion to fromVersion+1. Attributes that are multiple versions behind are upgraded step by step, so an
+// upgrader has to be registered for every version between the oldest stored version and the current one.
+func RegisterAttributeUpgrader(eventType EventType, fromVersion int, upgrade AttributesUpgrader) error {
+	attributeTypesMu.Lock()
+	defer attributeTypesMu.Unlock()
+
+	if attributeUpgraders[eventType] == nil {
+		attributeUpgraders[eventType] = map[int]AttributesUpgrader{}
+	}
+
+	if _, ok := attributeUpgraders[eventType][fromVersion]; ok {
+		return fmt.Errorf("upgrader for event type %v from version %v already registered", eventType, fromVersion)
+	}
+
+	attributeUpgraders[eventType][fromVersion] = upgrade
+
+	return nil
+}
+
+func attributeUpgrader(eventType EventType, fromVersion int) (AttributesUpgrader, bool) {
+	attributeTypesMu.RLock()
+	defer attributeTypesMu.RUnlock()
+
+	upgrade, ok := attributeUpgraders[eventType][fromVersion]
+	return upgrade, ok
+}
+
+// newAttributes returns new, empty attributes for the given event type
+func newAttributes(eventType EventType) (interface{}, bool) {
+	attributeTypesMu.RLock()
+	defer attributeTypesMu.RUnlock()
+
+	newAttributes, ok := attributeTypes[eventType]
+	if !ok {
+		return nil, false
+	}
+
+	return newAttributes(), true
+}
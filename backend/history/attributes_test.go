@@ -0,0 +1,140 @@
+package history
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type customAttributes struct {
+	Value string `json:"value,omitempty"`
+}
+
+func TestRegisterAttributeType(t *testing.T) {
+	const customEventType EventType = 1000
+
+	require.NoError(t, RegisterAttributeType(customEventType, func() interface{} { return &customAttributes{} }))
+	t.Cleanup(func() {
+		attributeTypesMu.Lock()
+		defer attributeTypesMu.Unlock()
+
+		delete(attributeTypes, customEventType)
+	})
+
+	event := NewHistoryEvent(1, time.Now(), customEventType, &customAttributes{Value: "test"})
+
+	b, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	var event2 Event
+	require.NoError(t, json.Unmarshal(b, &event2))
+	require.Equal(t, &customAttributes{Value: "test"}, event2.Attributes)
+}
+
+func TestRegisterAttributeType_AlreadyRegistered(t *testing.T) {
+	err := RegisterAttributeType(EventType_ActivityScheduled, func() interface{} { return &customAttributes{} })
+	require.Error(t, err)
+
+	attr, err := DeserializeAttributes(EventType_ActivityScheduled, []byte(`{"name":"a"}`))
+	require.NoError(t, err)
+	require.Equal(t, &ActivityScheduledAttributes{Name: "a"}, attr)
+}
+
+func TestDeserializeAttributes_UnknownEventType(t *testing.T) {
+	_, err := DeserializeAttributes(EventType(1001), []byte(`{}`))
+	require.Error(t, err)
+}
+
+// customAttributesV2 is the current version of customAttributes, Value has been renamed to Name
+type customAttributesV2 struct {
+	Name string `json:"name,omitempty"`
+}
+
+func (a *customAttributesV2) AttributesVersion() int {
+	return 2
+}
+
+func TestRegisterAttributeUpgrader(t *testing.T) {
+	const customEventType EventType = 1002
+
+	require.NoError(t, RegisterAttributeType(customEventType, func() interface{} { return &customAttributesV2{} }))
+
+	// Version 0 did not record a version, version 1 renamed the field
+	require.NoError(t, RegisterAttributeUpgrader(customEventType, 0, func(attributes []byte) ([]byte, error) {
+		var old customAttributes
+		if err := json.Unmarshal(attributes, &old); err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(map[string]interface{}{"_v": 1, "title": old.Value})
+	}))
+	require.NoError(t, RegisterAttributeUpgrader(customEventType, 1, func(attributes []byte) ([]byte, error) {
+		var old struct {
+			Title string `json:"title"`
+		}
+		if err := json.Unmarshal(attributes, &old); err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(map[string]interface{}{"_v": 2, "name": old.Title})
+	}))
+	require.Error(t, RegisterAttributeUpgrader(customEventType, 1, func(attributes []byte) ([]byte, error) { return attributes, nil }))
+
+	t.Cleanup(func() {
+		attributeTypesMu.Lock()
+		defer attributeTypesMu.Unlock()
+
+		delete(attributeTypes, customEventType)
+		delete(attributeUpgraders, customEventType)
+	})
+
+	// Old payloads are upgraded step by step
+	attr, err := DeserializeAttributes(customEventType, []byte(`{"value":"old"}`))
+	require.NoError(t, err)
+	require.Equal(t, &customAttributesV2{Name: "old"}, attr)
+
+	attr, err = DeserializeAttributes(customEventType, []byte(`{"_v":1,"title":"older"}`))
+	require.NoError(t, err)
+	require.Equal(t, &customAttributesV2{Name: "older"}, attr)
+
+	// Current payloads are read as is
+	b, err := SerializeAttributes(&customAttributesV2{Name: "current"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"_v":2,"name":"current"}`, string(b))
+
+	attr, err = DeserializeAttributes(customEventType, b)
+	require.NoError(t, err)
+	require.Equal(t, &customAttributesV2{Name: "current"}, attr)
+
+	// Events keep the version when marshaled
+	event := NewHistoryEvent(1, time.Now(), customEventType, &customAttributesV2{Name: "event"})
+	b, err = json.Marshal(event)
+	require.NoError(t, err)
+
+	var event2 Event
+	require.NoError(t, json.Unmarshal(b, &event2))
+	require.Equal(t, &customAttributesV2{Name: "event"}, event2.Attributes)
+}
+
+func TestDeserializeAttributes_MissingUpgrader(t *testing.T) {
+	const customEventType EventType = 1003
+
+	require.NoError(t, RegisterAttributeType(customEventType, func() interface{} { return &customAttributesV2{} }))
+	t.Cleanup(func() {
+		attributeTypesMu.Lock()
+		defer attributeTypesMu.Unlock()
+
+		delete(attributeTypes, customEventType)
+	})
+
+	_, err := DeserializeAttributes(customEventType, []byte(`{"value":"old"}`))
+	require.ErrorContains(t, err, "no upgrader registered")
+}
+
+func TestSerializeAttributes_EmptyVersioned(t *testing.T) {
+	b, err := SerializeAttributes(&customAttributesV2{})
+	require.NoError(t, err)
+	require.Equal(t, `{"_v":2}`, string(b))
+}
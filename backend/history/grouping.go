@@ -2,6 +2,10 @@ package history
 
 import "github.com/cschleiden/go-workflows/core"
 
+// EventsByWorkflowInstance groups the given events by the workflow instance they are sent to.
+//
+// Deprecated: The groups are keyed by the whole WorkflowInstance value including its parent, and are iterated in
+// random order. Use EventGroupsByWorkflowInstance instead.
 func EventsByWorkflowInstance(events []*WorkflowEvent) map[core.WorkflowInstance][]*WorkflowEvent {
 	groupedEvents := make(map[core.WorkflowInstance][]*WorkflowEvent)
 
@@ -17,3 +21,42 @@ func EventsByWorkflowInstance(events []*WorkflowEvent) map[core.WorkflowInstance
 
 	return groupedEvents
 }
+
+// WorkflowEventGroup holds the events sent to a single workflow instance
+type WorkflowEventGroup struct {
+	// Instance is the target of the events, as given by the first event of the group
+	Instance *core.WorkflowInstance
+
+	Events []*WorkflowEvent
+}
+
+// EventGroupsByWorkflowInstance groups the given events by the workflow instance and execution they are sent to.
+// Groups are returned in the order their first event was given, and events keep their order within a group, so events
+// are always delivered to their targets in the same order.
+func EventGroupsByWorkflowInstance(events []*WorkflowEvent) []*WorkflowEventGroup {
+	type target struct {
+		instanceID  string
+		executionID string
+	}
+
+	groups := make([]*WorkflowEventGroup, 0)
+	groupsByTarget := make(map[target]*WorkflowEventGroup)
+
+	for _, m := range events {
+		t := target{m.WorkflowInstance.InstanceID, m.WorkflowInstance.ExecutionID}
+
+		group, ok := groupsByTarget[t]
+		if !ok {
+			group = &WorkflowEventGroup{
+				Instance: m.WorkflowInstance,
+			}
+
+			groupsByTarget[t] = group
+			groups = append(groups, group)
+		}
+
+		group.Events = append(group.Events, m)
+	}
+
+	return groups
+}
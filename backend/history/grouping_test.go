@@ -29,3 +29,65 @@ func TestGrouping_MultipleEventsSameInstance(t *testing.T) {
 	require.Equal(t, r[*instance][0].HistoryEvent.Type, EventType_SubWorkflowScheduled)
 	require.Equal(t, r[*instance][1].HistoryEvent.Type, EventType_SignalReceived)
 }
+
+func TestEventGroups_MultipleEventsSameInstance(t *testing.T) {
+	id := uuid.NewString()
+	instance := core.NewWorkflowInstance(id, "exid")
+
+	r := EventGroupsByWorkflowInstance([]*WorkflowEvent{
+		{
+			WorkflowInstance: instance,
+			HistoryEvent:     NewPendingEvent(time.Now(), EventType_SubWorkflowScheduled, &SubWorkflowScheduledAttributes{}),
+		},
+		{
+			WorkflowInstance: instance,
+			HistoryEvent:     NewPendingEvent(time.Now(), EventType_SignalReceived, &SubWorkflowScheduledAttributes{}),
+		},
+	})
+
+	require.Len(t, r, 1)
+	require.Equal(t, instance, r[0].Instance)
+	require.Len(t, r[0].Events, 2)
+	require.Equal(t, r[0].Events[0].HistoryEvent.Type, EventType_SubWorkflowScheduled)
+	require.Equal(t, r[0].Events[1].HistoryEvent.Type, EventType_SignalReceived)
+}
+
+func TestEventGroups_KeepsOrder(t *testing.T) {
+	parent := core.NewWorkflowInstance("parent", "exid")
+
+	// Same target, once with and once without a parent
+	a := core.NewSubWorkflowInstance("a", "exid", parent, 1)
+	a2 := core.NewWorkflowInstance("a", "exid")
+	b := core.NewWorkflowInstance("b", "exid")
+	c := core.NewWorkflowInstance("c", "exid")
+
+	events := []*WorkflowEvent{
+		{WorkflowInstance: c, HistoryEvent: NewPendingEvent(time.Now(), EventType_SignalReceived, &SignalReceivedAttributes{Name: "c1"})},
+		{WorkflowInstance: a, HistoryEvent: NewPendingEvent(time.Now(), EventType_SignalReceived, &SignalReceivedAttributes{Name: "a1"})},
+		{WorkflowInstance: b, HistoryEvent: NewPendingEvent(time.Now(), EventType_SignalReceived, &SignalReceivedAttributes{Name: "b1"})},
+		{WorkflowInstance: a2, HistoryEvent: NewPendingEvent(time.Now(), EventType_SignalReceived, &SignalReceivedAttributes{Name: "a2"})},
+		{WorkflowInstance: c, HistoryEvent: NewPendingEvent(time.Now(), EventType_SignalReceived, &SignalReceivedAttributes{Name: "c2"})},
+		{WorkflowInstance: a, HistoryEvent: NewPendingEvent(time.Now(), EventType_SignalReceived, &SignalReceivedAttributes{Name: "a3"})},
+	}
+
+	for i := 0; i < 10; i++ {
+		r := EventGroupsByWorkflowInstance(events)
+
+		names := map[string][]string{}
+		order := []string{}
+		for _, group := range r {
+			order = append(order, group.Instance.InstanceID)
+			for _, e := range group.Events {
+				names[group.Instance.InstanceID] = append(names[group.Instance.InstanceID], e.HistoryEvent.Attributes.(*SignalReceivedAttributes).Name)
+			}
+		}
+
+		require.Equal(t, []string{"c", "a", "b"}, order)
+		require.Equal(t, map[string][]string{
+			"a": {"a1", "a2", "a3"},
+			"b": {"b1"},
+			"c": {"c1", "c2"},
+		}, names)
+		require.Same(t, a, r[1].Instance)
+	}
+}
@@ -58,6 +58,15 @@ const (
 
 	// Distributed tracing span has been started
 	EventType_TraceStarted
+
+	// Workflow has been reset to an earlier point in its history
+	EventType_WorkflowExecutionReset
+
+	// Activity task has been canceled by the workflow. A result reported for the activity afterwards is ignored.
+	EventType_ActivityCanceled
+
+	// Version of a change to the workflow code has been recorded
+	EventType_VersionRecorded
 )
 
 func (et EventType) String() string {
@@ -108,6 +117,15 @@ func (et EventType) String() string {
 	case EventType_TraceStarted:
 		return "TraceStarted"
 
+	case EventType_WorkflowExecutionReset:
+		return "WorkflowExecutionReset"
+
+	case EventType_ActivityCanceled:
+		return "ActivityCanceled"
+
+	case EventType_VersionRecorded:
+		return "VersionRecorded"
+
 	default:
 		return "Unknown"
 	}
@@ -177,3 +195,13 @@ func NewPendingEvent(timestamp time.Time, eventType EventType, attributes interf
 func NewWorkflowCancellationEvent(timestamp time.Time) *Event {
 	return NewPendingEvent(timestamp, EventType_WorkflowExecutionCanceled, &ExecutionCanceledAttributes{})
 }
+
+func NewWorkflowTerminationEvent(timestamp time.Time) *Event {
+	return NewPendingEvent(timestamp, EventType_WorkflowExecutionTerminated, &ExecutionTerminatedAttributes{})
+}
+
+func NewWorkflowResetEvent(timestamp time.Time, sequenceID int64) *Event {
+	return NewPendingEvent(timestamp, EventType_WorkflowExecutionReset, &ExecutionResetAttributes{
+		SequenceID: sequenceID,
+	})
+}
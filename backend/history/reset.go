@@ -0,0 +1,67 @@
+package history
+
+import "github.com/cschleiden/go-workflows/core"
+
+// ResetEvents determines what needs to happen to the events removed from a workflow instance's history when
+// the instance is reset. truncated are the history events after the reset point, in order.
+//
+// Work scheduled after the reset point (activities, timers, sub-workflows) will be scheduled again when the
+// workflow is re-executed, so any pending events or tasks for it have to be discarded. Their schedule event
+// IDs are returned in discard. Events that were received for work scheduled before the reset point, as well
+// as signals and cancellation requests, are returned in redeliver. They have to be added as pending events
+// again, otherwise the re-executed workflow would never receive them.
+func ResetEvents(truncated []*Event) (redeliver []*Event, discard []int64) {
+	scheduledAfterReset := make(map[int64]bool)
+
+	for _, event := range truncated {
+		switch event.Type {
+		case EventType_ActivityScheduled, EventType_TimerScheduled, EventType_SubWorkflowScheduled:
+			scheduledAfterReset[event.ScheduleEventID] = true
+			discard = append(discard, event.ScheduleEventID)
+		}
+	}
+
+	for _, event := range truncated {
+		switch event.Type {
+		case EventType_ActivityCompleted, EventType_ActivityFailed, EventType_TimerFired,
+			EventType_SubWorkflowCompleted, EventType_SubWorkflowFailed:
+			if scheduledAfterReset[event.ScheduleEventID] {
+				continue
+			}
+
+		case EventType_SignalReceived, EventType_WorkflowExecutionCanceled:
+
+		default:
+			// Event was generated by the workflow itself, it will be generated again.
+			continue
+		}
+
+		redeliver = append(redeliver, &Event{
+			ID:              event.ID,
+			Type:            event.Type,
+			Timestamp:       event.Timestamp,
+			ScheduleEventID: event.ScheduleEventID,
+			Attributes:      event.Attributes,
+		})
+	}
+
+	return redeliver, discard
+}
+
+// ResetSubWorkflows returns the sub-workflow instances started after the reset point of a workflow instance.
+// truncated are the history events after the reset point. Resets are rejected while any of them are still
+// running, their results could otherwise be delivered to the re-executed workflow.
+func ResetSubWorkflows(truncated []*Event) []*core.WorkflowInstance {
+	var instances []*core.WorkflowInstance
+
+	for _, event := range truncated {
+		if event.Type != EventType_SubWorkflowScheduled {
+			continue
+		}
+
+		a := event.Attributes.(*SubWorkflowScheduledAttributes)
+		instances = append(instances, a.SubWorkflowInstance)
+	}
+
+	return instances
+}
@@ -0,0 +1,51 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetEvents(t *testing.T) {
+	now := time.Now()
+
+	truncated := []*Event{
+		NewHistoryEvent(5, now, EventType_WorkflowTaskStarted, &WorkflowTaskStartedAttributes{}),
+		// Result for activity scheduled before the reset point
+		NewHistoryEvent(6, now, EventType_ActivityCompleted, &ActivityCompletedAttributes{}, ScheduleEventID(1)),
+		NewHistoryEvent(7, now, EventType_SignalReceived, &SignalReceivedAttributes{Name: "signal"}),
+		NewHistoryEvent(8, now, EventType_ActivityScheduled, &ActivityScheduledAttributes{}, ScheduleEventID(2)),
+		NewHistoryEvent(9, now, EventType_TimerScheduled, &TimerScheduledAttributes{}, ScheduleEventID(3)),
+		NewHistoryEvent(10, now, EventType_WorkflowTaskStarted, &WorkflowTaskStartedAttributes{}),
+		// Result for activity scheduled after the reset point
+		NewHistoryEvent(11, now, EventType_ActivityCompleted, &ActivityCompletedAttributes{}, ScheduleEventID(2)),
+	}
+
+	redeliver, discard := ResetEvents(truncated)
+
+	require.Len(t, redeliver, 2)
+	require.Equal(t, EventType_ActivityCompleted, redeliver[0].Type)
+	require.Equal(t, int64(1), redeliver[0].ScheduleEventID)
+	require.Equal(t, truncated[1].ID, redeliver[0].ID)
+	require.Zero(t, redeliver[0].SequenceID)
+	require.Equal(t, EventType_SignalReceived, redeliver[1].Type)
+
+	require.Equal(t, []int64{2, 3}, discard)
+}
+
+func TestResetSubWorkflows(t *testing.T) {
+	now := time.Now()
+	parent := core.NewWorkflowInstance("parent", "exid")
+	sub := core.NewSubWorkflowInstance("sub", "subexid", parent, 2)
+
+	truncated := []*Event{
+		NewHistoryEvent(5, now, EventType_WorkflowTaskStarted, &WorkflowTaskStartedAttributes{}),
+		NewHistoryEvent(6, now, EventType_ActivityScheduled, &ActivityScheduledAttributes{}, ScheduleEventID(1)),
+		NewHistoryEvent(7, now, EventType_SubWorkflowScheduled, &SubWorkflowScheduledAttributes{SubWorkflowInstance: sub}, ScheduleEventID(2)),
+	}
+
+	require.Equal(t, []*core.WorkflowInstance{sub}, ResetSubWorkflows(truncated))
+	require.Empty(t, ResetSubWorkflows(truncated[:2]))
+}
@@ -1,10 +1,39 @@
 package history
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 )
 
+// attributesVersionKey is the key the version of VersionedAttributes is stored under in the serialized attributes
+const attributesVersionKey = "_v"
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	type Aevent Event
+	a := &struct {
+		// Attributes are serialized with SerializeAttributes to record their version. Has to match the struct
+		// tag in Event
+		Attributes json.RawMessage `json:"attr,omitempty"`
+		*Aevent
+	}{
+		Aevent: (*Aevent)(&e),
+	}
+
+	if e.Attributes != nil {
+		attributes, err := SerializeAttributes(e.Attributes)
+		if err != nil {
+			return nil, err
+		}
+
+		a.Attributes = attributes
+	}
+
+	return json.Marshal(a)
+}
+
 func (e *Event) UnmarshalJSON(data []byte) error {
 	type Aevent Event
 	a := &struct {
@@ -28,60 +57,114 @@ func (e *Event) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// SerializeAttributes serializes the given event attributes. For VersionedAttributes, the current version is
+// stored with the attributes.
 func SerializeAttributes(attributes interface{}) ([]byte, error) {
-	return json.Marshal(attributes)
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	va, ok := attributes.(VersionedAttributes)
+	if !ok || len(data) < 2 || data[0] != '{' {
+		return data, nil
+	}
+
+	version := []byte(`{"` + attributesVersionKey + `":` + strconv.Itoa(va.AttributesVersion()))
+	if len(bytes.TrimSpace(data[1:])) > 1 {
+		version = append(version, ',')
+	}
+
+	return append(version, data[1:]...), nil
 }
 
-func DeserializeAttributes(eventType EventType, attributes []byte) (attr interface{}, err error) {
-	switch eventType {
-	case EventType_WorkflowExecutionStarted:
-		attr = &ExecutionStartedAttributes{}
-	case EventType_WorkflowExecutionContinuedAsNew:
-		attr = &ExecutionContinuedAsNewAttributes{}
-	case EventType_WorkflowExecutionFinished:
-		attr = &ExecutionCompletedAttributes{}
-	case EventType_WorkflowExecutionCanceled:
-		attr = &ExecutionCanceledAttributes{}
-
-	case EventType_WorkflowTaskStarted:
-		attr = &WorkflowTaskStartedAttributes{}
-
-	case EventType_ActivityScheduled:
-		attr = &ActivityScheduledAttributes{}
-	case EventType_ActivityCompleted:
-		attr = &ActivityCompletedAttributes{}
-	case EventType_ActivityFailed:
-		attr = &ActivityFailedAttributes{}
-
-	case EventType_SignalReceived:
-		attr = &SignalReceivedAttributes{}
-
-	case EventType_SideEffectResult:
-		attr = &SideEffectResultAttributes{}
-
-	case EventType_TraceStarted:
-		attr = &TraceStartedAttributes{}
-
-	case EventType_TimerScheduled:
-		attr = &TimerScheduledAttributes{}
-	case EventType_TimerFired:
-		attr = &TimerFiredAttributes{}
-	case EventType_TimerCanceled:
-		attr = &TimerCanceledAttributes{}
-
-	case EventType_SubWorkflowScheduled:
-		attr = &SubWorkflowScheduledAttributes{}
-	case EventType_SubWorkflowCancellationRequested:
-		attr = &SubWorkflowCancellationRequestedAttributes{}
-	case EventType_SubWorkflowCompleted:
-		attr = &SubWorkflowCompletedAttributes{}
-	case EventType_SubWorkflowFailed:
-		attr = &SubWorkflowFailedAttributes{}
-
-	default:
+// DeserializeAttributes deserializes the attributes for the given event type. Attribute types are looked up
+// from the types registered with RegisterAttributeType. Attributes of an older version are upgraded with the
+// upgraders registered with RegisterAttributeUpgrader.
+func DeserializeAttributes(eventType EventType, attributes []byte) (interface{}, error) {
+	attr, ok := newAttributes(eventType)
+	if !ok {
 		return nil, errors.New("unknown event type when deserializing attributes")
 	}
 
-	err = json.Unmarshal([]byte(attributes), &attr)
+	attributes, err := upgradeAttributes(eventType, attr, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(attributes, attr)
 	return attr, err
 }
+
+// upgradeAttributes converts the serialized attributes to the current version of attr, if it is versioned
+func upgradeAttributes(eventType EventType, attr interface{}, attributes []byte) ([]byte, error) {
+	va, ok := attr.(VersionedAttributes)
+	if !ok {
+		return attributes, nil
+	}
+
+	// Events without attributes, e.g., when attributes are stored separately
+	if len(attributes) == 0 || bytes.Equal(attributes, []byte("null")) {
+		return attributes, nil
+	}
+
+	var stored map[string]json.RawMessage
+	if err := json.Unmarshal(attributes, &stored); err != nil {
+		return nil, err
+	}
+
+	version := 0
+	if v, ok := stored[attributesVersionKey]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return nil, fmt.Errorf("reading attributes version: %w", err)
+		}
+	}
+
+	for current := va.AttributesVersion(); version < current; version++ {
+		upgrade, ok := attributeUpgrader(eventType, version)
+		if !ok {
+			return nil, fmt.Errorf("no upgrader registered for event type %v from version %v", eventType, version)
+		}
+
+		upgraded, err := upgrade(attributes)
+		if err != nil {
+			return nil, fmt.Errorf("upgrading attributes for event type %v from version %v: %w", eventType, version, err)
+		}
+
+		attributes = upgraded
+	}
+
+	return attributes, nil
+}
+
+// DeserializeAttributesWithoutInputs deserializes event attributes like DeserializeAttributes, but skips
+// the inputs of scheduled activities. Those are not required when replaying a workflow.
+func DeserializeAttributesWithoutInputs(eventType EventType, attributes []byte) (interface{}, error) {
+	if eventType != EventType_ActivityScheduled {
+		return DeserializeAttributes(eventType, attributes)
+	}
+
+	attr := &ActivityScheduledAttributes{}
+	a := &struct {
+		*ActivityScheduledAttributes
+		// Inputs shadows the field of the embedded attributes. Has to match the struct tag in ActivityScheduledAttributes
+		Inputs skippedPayloads `json:"inputs,omitempty"`
+	}{
+		ActivityScheduledAttributes: attr,
+	}
+
+	attributes, err := upgradeAttributes(eventType, attr, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(attributes, a)
+	return attr, err
+}
+
+// skippedPayloads discards payloads without decoding them
+type skippedPayloads struct{}
+
+func (skippedPayloads) UnmarshalJSON([]byte) error {
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,3 +27,34 @@ func TestRoundtripJSON(t *testing.T) {
 	require.Equal(t, event.VisibleAt, event2.VisibleAt)
 	require.Equal(t, event.Attributes, event2.Attributes)
 }
+
+func TestDeserializeAttributesWithoutInputs(t *testing.T) {
+	b, err := SerializeAttributes(&ActivityScheduledAttributes{
+		Name:    "my-activity",
+		Attempt: 2,
+		Inputs:  []payload.Payload{payload.Payload("42"), payload.Payload(`"input"`)},
+		Queue:   "queue",
+	})
+	require.NoError(t, err)
+
+	attr, err := DeserializeAttributesWithoutInputs(EventType_ActivityScheduled, b)
+	require.NoError(t, err)
+	require.Equal(t, &ActivityScheduledAttributes{
+		Name:    "my-activity",
+		Attempt: 2,
+		Queue:   "queue",
+	}, attr)
+
+	b, err = SerializeAttributes(&ExecutionStartedAttributes{
+		Name:   "my-workflow",
+		Inputs: []payload.Payload{payload.Payload("42")},
+	})
+	require.NoError(t, err)
+
+	attr, err = DeserializeAttributesWithoutInputs(EventType_WorkflowExecutionStarted, b)
+	require.NoError(t, err)
+	require.Equal(t, &ExecutionStartedAttributes{
+		Name:   "my-workflow",
+		Inputs: []payload.Payload{payload.Payload("42")},
+	}, attr)
+}
@@ -0,0 +1,7 @@
+package history
+
+type VersionRecordedAttributes struct {
+	ChangeID string `json:"change_id,omitempty"`
+
+	Version int `json:"version,omitempty"`
+}
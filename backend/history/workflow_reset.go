@@ -0,0 +1,6 @@
+package history
+
+type ExecutionResetAttributes struct {
+	// SequenceID is the sequence ID of the last history event kept by the reset
+	SequenceID int64 `json:"sid,omitempty"`
+}
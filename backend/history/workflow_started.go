@@ -1,6 +1,8 @@
 package history
 
 import (
+	"time"
+
 	"github.com/cschleiden/go-workflows/backend/metadata"
 	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/cschleiden/go-workflows/core"
@@ -15,5 +17,32 @@ type ExecutionStartedAttributes struct {
 
 	Inputs []payload.Payload `json:"inputs,omitempty"`
 
+	// InputsCompressed is set if the inputs have been gzip compressed by the client, after being encoded by the
+	// converter
+	InputsCompressed bool `json:"inputs_compressed,omitempty"`
+
 	WorkflowSpanID [8]byte `json:"workflowSpanID,omitempty"`
+
+	// UniqueKey is the optional business key of the instance, only one active instance can hold a given key
+	UniqueKey string `json:"unique_key,omitempty"`
+
+	// Priority is the optional dispatch priority of the instance, higher values are dispatched first
+	Priority int `json:"priority,omitempty"`
+
+	// Tags are optional labels of the instance, for example to group instances belonging to the same batch job
+	Tags []string `json:"tags,omitempty"`
+
+	// ExecutionTimeout is the optional time budget of the execution, starting when the execution has been created
+	ExecutionTimeout time.Duration `json:"execution_timeout,omitempty"`
+
+	// CompletionWebhook is the optional URL the outcome of the instance is posted to when it has finished
+	CompletionWebhook string `json:"completion_webhook,omitempty"`
+
+	// InitialSignals is the number of signal events directly following the started event, they are received by the
+	// execution before it first runs
+	InitialSignals int `json:"initial_signals,omitempty"`
+
+	// OrderedSignals is set for executions that receive buffered signals in the order they were sent. Executions
+	// started before this was introduced receive them in reverse order, which is kept so their replay doesn't change.
+	OrderedSignals bool `json:"ordered_signals,omitempty"`
 }
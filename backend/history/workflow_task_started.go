@@ -1,4 +1,6 @@
 package history
 
 type WorkflowTaskStartedAttributes struct {
+	// Worker is the identity of the worker that executed the workflow task
+	Worker string `json:"worker,omitempty"`
 }
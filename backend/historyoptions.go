@@ -0,0 +1,18 @@
+package backend
+
+type HistoryOptions struct {
+	// SkipActivityInputs omits the inputs of scheduled activities from the returned events. They are
+	// not required for replaying a workflow and can be large.
+	SkipActivityInputs bool
+}
+
+var DefaultHistoryOptions = HistoryOptions{}
+
+type HistoryOption func(o *HistoryOptions)
+
+// HistoryWithoutActivityInputs returns history events without the inputs of scheduled activities.
+func HistoryWithoutActivityInputs() HistoryOption {
+	return func(o *HistoryOptions) {
+		o.SkipActivityInputs = true
+	}
+}
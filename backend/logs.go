@@ -0,0 +1,18 @@
+package backend
+
+import (
+	"log/slog"
+	"time"
+)
+
+// InstanceLogEntry is a log line written by a workflow or activity of a workflow instance
+type InstanceLogEntry struct {
+	Time time.Time
+
+	Level slog.Level
+
+	Message string
+
+	// Attributes are the attributes of the log line, including the ones added by the worker like the execution ID
+	Attributes map[string]string
+}
@@ -12,6 +12,10 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 
+	payload "github.com/cschleiden/go-workflows/backend/payload"
+
+	time "time"
+
 	trace "go.opentelemetry.io/otel/trace"
 )
 
@@ -76,13 +80,20 @@ func (_m *MockBackend) CompleteWorkflowTask(ctx context.Context, task *WorkflowT
 	return r0
 }
 
-// CreateWorkflowInstance provides a mock function with given fields: ctx, instance, event
-func (_m *MockBackend) CreateWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, event *history.Event) error {
-	ret := _m.Called(ctx, instance, event)
+// CreateWorkflowInstance provides a mock function with given fields: ctx, instance, event, signals
+func (_m *MockBackend) CreateWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, event *history.Event, signals ...*history.Event) error {
+	_va := make([]interface{}, len(signals))
+	for _i := range signals {
+		_va[_i] = signals[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, instance, event)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance, *history.Event) error); ok {
-		r0 = rf(ctx, instance, event)
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance, *history.Event, ...*history.Event) error); ok {
+		r0 = rf(ctx, instance, event, signals...)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -158,6 +169,108 @@ func (_m *MockBackend) GetActivityTask(ctx context.Context, queues []core.Queue)
 	return r0, r1
 }
 
+// GetLatestWorkflowInstance provides a mock function with given fields: ctx, instanceID
+func (_m *MockBackend) GetLatestWorkflowInstance(ctx context.Context, instanceID string) (*core.WorkflowInstance, error) {
+	ret := _m.Called(ctx, instanceID)
+
+	var r0 *core.WorkflowInstance
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*core.WorkflowInstance, error)); ok {
+		return rf(ctx, instanceID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *core.WorkflowInstance); ok {
+		r0 = rf(ctx, instanceID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*core.WorkflowInstance)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, instanceID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLatestWorkflowInstanceState provides a mock function with given fields: ctx, instanceID
+func (_m *MockBackend) GetLatestWorkflowInstanceState(ctx context.Context, instanceID string) (core.WorkflowInstanceState, error) {
+	ret := _m.Called(ctx, instanceID)
+
+	var r0 core.WorkflowInstanceState
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (core.WorkflowInstanceState, error)); ok {
+		return rf(ctx, instanceID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) core.WorkflowInstanceState); ok {
+		r0 = rf(ctx, instanceID)
+	} else {
+		r0 = ret.Get(0).(core.WorkflowInstanceState)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, instanceID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetChildWorkflowInstances provides a mock function with given fields: ctx, instance
+func (_m *MockBackend) GetChildWorkflowInstances(ctx context.Context, instance *core.WorkflowInstance) ([]*core.WorkflowInstance, error) {
+	ret := _m.Called(ctx, instance)
+
+	var r0 []*core.WorkflowInstance
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance) ([]*core.WorkflowInstance, error)); ok {
+		return rf(ctx, instance)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance) []*core.WorkflowInstance); ok {
+		r0 = rf(ctx, instance)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*core.WorkflowInstance)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *core.WorkflowInstance) error); ok {
+		r1 = rf(ctx, instance)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWorkflowInstancesByTag provides a mock function with given fields: ctx, tag
+func (_m *MockBackend) GetWorkflowInstancesByTag(ctx context.Context, tag string) ([]*TaggedWorkflowInstance, error) {
+	ret := _m.Called(ctx, tag)
+
+	var r0 []*TaggedWorkflowInstance
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*TaggedWorkflowInstance, error)); ok {
+		return rf(ctx, tag)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*TaggedWorkflowInstance); ok {
+		r0 = rf(ctx, tag)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*TaggedWorkflowInstance)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tag)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetStats provides a mock function with given fields: ctx
 func (_m *MockBackend) GetStats(ctx context.Context) (*Stats, error) {
 	ret := _m.Called(ctx)
@@ -184,25 +297,115 @@ func (_m *MockBackend) GetStats(ctx context.Context) (*Stats, error) {
 	return r0, r1
 }
 
-// GetWorkflowInstanceHistory provides a mock function with given fields: ctx, instance, lastSequenceID
-func (_m *MockBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64) ([]*history.Event, error) {
-	ret := _m.Called(ctx, instance, lastSequenceID)
+// QueueLag provides a mock function with given fields: ctx
+func (_m *MockBackend) QueueLag(ctx context.Context) (int64, int64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	var r1 int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int64, int64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) int64); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context) error); ok {
+		r2 = rf(ctx)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetWorkflowInstanceHistory provides a mock function with given fields: ctx, instance, lastSequenceID, options
+func (_m *MockBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64, options ...HistoryOption) ([]*history.Event, error) {
+	_va := make([]interface{}, len(options))
+	for _i := range options {
+		_va[_i] = options[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, instance, lastSequenceID)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	var r0 []*history.Event
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance, *int64) ([]*history.Event, error)); ok {
-		return rf(ctx, instance, lastSequenceID)
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance, *int64, ...HistoryOption) ([]*history.Event, error)); ok {
+		return rf(ctx, instance, lastSequenceID, options...)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance, *int64) []*history.Event); ok {
-		r0 = rf(ctx, instance, lastSequenceID)
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance, *int64, ...HistoryOption) []*history.Event); ok {
+		r0 = rf(ctx, instance, lastSequenceID, options...)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*history.Event)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, *core.WorkflowInstance, *int64) error); ok {
-		r1 = rf(ctx, instance, lastSequenceID)
+	if rf, ok := ret.Get(1).(func(context.Context, *core.WorkflowInstance, *int64, ...HistoryOption) error); ok {
+		r1 = rf(ctx, instance, lastSequenceID, options...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWorkflowInstanceProgress provides a mock function with given fields: ctx, instance
+func (_m *MockBackend) GetWorkflowInstanceProgress(ctx context.Context, instance *core.WorkflowInstance) (payload.Payload, error) {
+	ret := _m.Called(ctx, instance)
+
+	var r0 payload.Payload
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance) (payload.Payload, error)); ok {
+		return rf(ctx, instance)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance) payload.Payload); ok {
+		r0 = rf(ctx, instance)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(payload.Payload)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *core.WorkflowInstance) error); ok {
+		r1 = rf(ctx, instance)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWorkflowInstanceUsage provides a mock function with given fields: ctx, instance
+func (_m *MockBackend) GetWorkflowInstanceUsage(ctx context.Context, instance *core.WorkflowInstance) (*WorkflowInstanceUsage, error) {
+	ret := _m.Called(ctx, instance)
+
+	var r0 *WorkflowInstanceUsage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance) (*WorkflowInstanceUsage, error)); ok {
+		return rf(ctx, instance)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance) *WorkflowInstanceUsage); ok {
+		r0 = rf(ctx, instance)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*WorkflowInstanceUsage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *core.WorkflowInstance) error); ok {
+		r1 = rf(ctx, instance)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -306,6 +509,20 @@ func (_m *MockBackend) PrepareActivityQueues(ctx context.Context, queues []core.
 	return r0
 }
 
+// PauseActivityDispatch provides a mock function with given fields: ctx, paused
+func (_m *MockBackend) PauseActivityDispatch(ctx context.Context, paused bool) error {
+	ret := _m.Called(ctx, paused)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, bool) error); ok {
+		r0 = rf(ctx, paused)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // PrepareWorkflowQueues provides a mock function with given fields: ctx, queues
 func (_m *MockBackend) PrepareWorkflowQueues(ctx context.Context, queues []core.Queue) error {
 	ret := _m.Called(ctx, queues)
@@ -355,6 +572,48 @@ func (_m *MockBackend) RemoveWorkflowInstances(ctx context.Context, options ...R
 	return r0
 }
 
+// ResetWorkflowInstance provides a mock function with given fields: ctx, instance, sequenceID
+func (_m *MockBackend) ResetWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, sequenceID int64) error {
+	ret := _m.Called(ctx, instance, sequenceID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance, int64) error); ok {
+		r0 = rf(ctx, instance, sequenceID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RetryWorkflowInstance provides a mock function with given fields: ctx, instance
+func (_m *MockBackend) RetryWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance) error {
+	ret := _m.Called(ctx, instance)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance) error); ok {
+		r0 = rf(ctx, instance)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FailWorkflowTask provides a mock function with given fields: ctx, task, maxFailures
+func (_m *MockBackend) FailWorkflowTask(ctx context.Context, task *WorkflowTask, maxFailures int) error {
+	ret := _m.Called(ctx, task, maxFailures)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *WorkflowTask, int) error); ok {
+		r0 = rf(ctx, task, maxFailures)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SignalWorkflow provides a mock function with given fields: ctx, instanceID, event
 func (_m *MockBackend) SignalWorkflow(ctx context.Context, instanceID string, event *history.Event) error {
 	ret := _m.Called(ctx, instanceID, event)
@@ -369,6 +628,157 @@ func (_m *MockBackend) SignalWorkflow(ctx context.Context, instanceID string, ev
 	return r0
 }
 
+// DeregisterWorker provides a mock function with given fields: ctx, workerID
+func (_m *MockBackend) DeregisterWorker(ctx context.Context, workerID string) error {
+	ret := _m.Called(ctx, workerID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, workerID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetActivityResult provides a mock function with given fields: ctx, key
+func (_m *MockBackend) GetActivityResult(ctx context.Context, key string) (payload.Payload, bool, error) {
+	ret := _m.Called(ctx, key)
+
+	var r0 payload.Payload
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (payload.Payload, bool, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) payload.Payload); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(payload.Payload)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) bool); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, key)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// CacheActivityResult provides a mock function with given fields: ctx, key, result, ttl
+func (_m *MockBackend) CacheActivityResult(ctx context.Context, key string, result payload.Payload, ttl time.Duration) error {
+	ret := _m.Called(ctx, key, result, ttl)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, payload.Payload, time.Duration) error); ok {
+		r0 = rf(ctx, key, result, ttl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CacheActivityResultIfAbsent provides a mock function with given fields: ctx, key, result, ttl
+func (_m *MockBackend) CacheActivityResultIfAbsent(ctx context.Context, key string, result payload.Payload, ttl time.Duration) (bool, error) {
+	ret := _m.Called(ctx, key, result, ttl)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, payload.Payload, time.Duration) (bool, error)); ok {
+		return rf(ctx, key, result, ttl)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, payload.Payload, time.Duration) bool); ok {
+		r0 = rf(ctx, key, result, ttl)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, payload.Payload, time.Duration) error); ok {
+		r1 = rf(ctx, key, result, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InvalidateActivityResult provides a mock function with given fields: ctx, key
+func (_m *MockBackend) InvalidateActivityResult(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SubscribeWorkflowInstanceUpdates provides a mock function with given fields: ctx, instanceID
+func (_m *MockBackend) SubscribeWorkflowInstanceUpdates(ctx context.Context, instanceID string) (<-chan *WorkflowInstanceUpdate, error) {
+	ret := _m.Called(ctx, instanceID)
+
+	var r0 <-chan *WorkflowInstanceUpdate
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (<-chan *WorkflowInstanceUpdate, error)); ok {
+		return rf(ctx, instanceID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) <-chan *WorkflowInstanceUpdate); ok {
+		r0 = rf(ctx, instanceID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan *WorkflowInstanceUpdate)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, instanceID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TailWorkflowInstanceLogs provides a mock function with given fields: ctx, instanceID
+func (_m *MockBackend) TailWorkflowInstanceLogs(ctx context.Context, instanceID string) (<-chan *InstanceLogEntry, error) {
+	ret := _m.Called(ctx, instanceID)
+
+	var r0 <-chan *InstanceLogEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (<-chan *InstanceLogEntry, error)); ok {
+		return rf(ctx, instanceID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) <-chan *InstanceLogEntry); ok {
+		r0 = rf(ctx, instanceID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan *InstanceLogEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, instanceID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Tracer provides a mock function with given fields:
 func (_m *MockBackend) Tracer() trace.Tracer {
 	ret := _m.Called()
@@ -4,7 +4,6 @@ import (
 	"context"
 	"log/slog"
 	"reflect"
-	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/history"
@@ -75,8 +74,8 @@ func (b *monoprocessBackend) GetActivityTask(ctx context.Context, queues []workf
 	}
 }
 
-func (b *monoprocessBackend) CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
-	if err := b.Backend.CreateWorkflowInstance(ctx, instance, event); err != nil {
+func (b *monoprocessBackend) CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event, signals ...*history.Event) error {
+	if err := b.Backend.CreateWorkflowInstance(ctx, instance, event, signals...); err != nil {
 		return err
 	}
 	b.notifyWorkflowWorker(ctx)
@@ -108,7 +107,8 @@ func (b *monoprocessBackend) CompleteWorkflowTask(
 		// Note that the worker will be notified even if the timer event gets
 		// cancelled. This is ok, because the poller will simply find no task
 		// and continue.
-		time.AfterFunc(time.Until(attr.At), func() {
+		clock := b.Options().Clock
+		clock.AfterFunc(clock.Until(attr.At), func() {
 			b.notifyWorkflowWorker(ctx)
 		})
 	}
@@ -133,6 +133,22 @@ func (b *monoprocessBackend) CancelWorkflowInstance(ctx context.Context, instanc
 	return nil
 }
 
+func (b *monoprocessBackend) ResetWorkflowInstance(ctx context.Context, instance *workflow.Instance, sequenceID int64) error {
+	if err := b.Backend.ResetWorkflowInstance(ctx, instance, sequenceID); err != nil {
+		return err
+	}
+	b.notifyWorkflowWorker(ctx)
+	return nil
+}
+
+func (b *monoprocessBackend) RetryWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	if err := b.Backend.RetryWorkflowInstance(ctx, instance); err != nil {
+		return err
+	}
+	b.notifyWorkflowWorker(ctx)
+	return nil
+}
+
 func (b *monoprocessBackend) SignalWorkflow(ctx context.Context, instanceID string, event *history.Event) error {
 	if err := b.Backend.SignalWorkflow(ctx, instanceID, event); err != nil {
 		return err
@@ -17,7 +17,7 @@ func Test_MonoprocessBackend(t *testing.T) {
 		t.Skip()
 	}
 
-	test.BackendTest(t, func(options ...backend.BackendOption) test.TestBackend {
+	test.RunSuite(t, func(options ...backend.BackendOption) test.TestBackend {
 		// Disable sticky workflow behavior for the test execution
 		options = append(options, backend.WithStickyTimeout(0))
 
@@ -27,19 +27,6 @@ func Test_MonoprocessBackend(t *testing.T) {
 	})
 }
 
-func Test_EndToEndMonoprocessBackend(t *testing.T) {
-	if testing.Short() {
-		t.Skip()
-	}
-
-	test.EndToEndBackendTest(t, func(options ...backend.BackendOption) test.TestBackend {
-		// Disable sticky workflow behavior for the test execution
-		options = append(options, backend.WithStickyTimeout(0))
-
-		return NewMonoprocessBackend(sqlite.NewInMemoryBackend(sqlite.WithBackendOptions(options...)))
-	}, nil)
-}
-
 var _ test.TestBackend = (*monoprocessBackend)(nil)
 
 func (b *monoprocessBackend) GetFutureEvents(ctx context.Context) ([]*history.Event, error) {
@@ -0,0 +1,6 @@
+-- Settings shared by all workers using the database, e.g., whether activity dispatch is paused
+CREATE TABLE IF NOT EXISTS `settings` (
+  `name` NVARCHAR(128) NOT NULL,
+  `value` NVARCHAR(256) NOT NULL,
+  PRIMARY KEY(`name`)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE `instances` DROP COLUMN `usage_workflow_tasks`;
+ALTER TABLE `instances` DROP COLUMN `usage_activities`;
+ALTER TABLE `instances` DROP COLUMN `usage_activity_duration`;
+ALTER TABLE `instances` DROP COLUMN `usage_timers`;
+ALTER TABLE `instances` DROP COLUMN `usage_history_events`;
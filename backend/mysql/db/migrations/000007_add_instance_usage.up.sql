@@ -0,0 +1,6 @@
+-- Add resources used by workflow instances
+ALTER TABLE `instances` ADD COLUMN `usage_workflow_tasks` BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE `instances` ADD COLUMN `usage_activities` BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE `instances` ADD COLUMN `usage_activity_duration` BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE `instances` ADD COLUMN `usage_timers` BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE `instances` ADD COLUMN `usage_history_events` BIGINT NOT NULL DEFAULT 0;
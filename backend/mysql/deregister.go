@@ -0,0 +1,47 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+)
+
+func (b *mysqlBackend) DeregisterWorker(ctx context.Context, workerID string) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	worker := b.worker(backend.WithWorkerID(ctx, workerID))
+
+	// Release sticky and locked instances
+	if _, err := tx.ExecContext(
+		ctx,
+		"UPDATE `instances` SET locked_until = NULL, sticky_until = NULL, worker = NULL WHERE worker = ?",
+		worker,
+	); err != nil {
+		return fmt.Errorf("releasing workflow instances: %w", err)
+	}
+
+	// Release locked activities
+	if _, err := tx.ExecContext(
+		ctx,
+		"UPDATE `activities` SET locked_until = NULL, worker = NULL WHERE worker = ?",
+		worker,
+	); err != nil {
+		return fmt.Errorf("releasing activities: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// worker returns the name tasks are locked with for the worker the call is made on behalf of, see backend.WithWorkerID.
+func (b *mysqlBackend) worker(ctx context.Context) string {
+	if workerID := backend.WorkerID(ctx); workerID != "" {
+		return workerID
+	}
+
+	return b.workerName
+}
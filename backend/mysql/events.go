@@ -81,3 +81,69 @@ func removeFutureEvent(ctx context.Context, tx *sql.Tx, instance *core.WorkflowI
 
 	return err
 }
+
+// compactCanceledTimers removes the events of timers that have been canceled from the history of the given instance.
+// Timers canceled in the last event of the history are kept until more events have been added, so that the sequence
+// ID of the last event does not change.
+func compactCanceledTimers(ctx context.Context, tx *sql.Tx, instance *core.WorkflowInstance) error {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT h.event_id FROM `history` h WHERE h.instance_id = ? AND h.execution_id = ? AND h.event_type IN (?, ?, ?) AND h.schedule_event_id IN "+
+			"(SELECT c.schedule_event_id FROM `history` c WHERE c.instance_id = ? AND c.execution_id = ? AND c.event_type = ? AND c.sequence_id < "+
+			"(SELECT MAX(m.sequence_id) FROM `history` m WHERE m.instance_id = ? AND m.execution_id = ?))",
+		instance.InstanceID,
+		instance.ExecutionID,
+		history.EventType_TimerScheduled,
+		history.EventType_TimerFired,
+		history.EventType_TimerCanceled,
+		instance.InstanceID,
+		instance.ExecutionID,
+		history.EventType_TimerCanceled,
+		instance.InstanceID,
+		instance.ExecutionID,
+	)
+	if err != nil {
+		return fmt.Errorf("finding canceled timers: %w", err)
+	}
+
+	ids := make([]interface{}, 0)
+
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("scanning id: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("finding canceled timers: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := append([]interface{}{instance.InstanceID, instance.ExecutionID}, ids...)
+	placeholders := strings.Repeat(",?", len(ids)-1)
+
+	if _, err := tx.ExecContext(
+		ctx,
+		"DELETE FROM `history` WHERE instance_id = ? AND execution_id = ? AND event_id IN (?"+placeholders+")",
+		args...,
+	); err != nil {
+		return fmt.Errorf("removing canceled timers: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		"DELETE FROM `attributes` WHERE instance_id = ? AND execution_id = ? AND event_id IN (?"+placeholders+")",
+		args...,
+	); err != nil {
+		return fmt.Errorf("removing attributes: %w", err)
+	}
+
+	return nil
+}
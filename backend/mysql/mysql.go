@@ -15,6 +15,7 @@ import (
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/backend/metadata"
 	"github.com/cschleiden/go-workflows/backend/metrics"
+	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/cschleiden/go-workflows/core"
 	"github.com/cschleiden/go-workflows/internal/metrickeys"
 	"github.com/cschleiden/go-workflows/internal/workflowerrors"
@@ -57,6 +58,7 @@ func NewMysqlBackend(host string, port int, user, password, database string, opt
 		db:         db,
 		workerName: fmt.Sprintf("worker-%v", uuid.NewString()),
 		options:    options,
+		events:     backend.NewEventDispatcher(options.EventCallback, options.Logger),
 	}
 
 	if options.ApplyMigrations {
@@ -73,6 +75,7 @@ type mysqlBackend struct {
 	db         *sql.DB
 	workerName string
 	options    *options
+	events     *backend.EventDispatcher
 }
 
 func (mb *mysqlBackend) FeatureSupported(feature backend.Feature) bool {
@@ -131,7 +134,7 @@ func (b *mysqlBackend) Options() *backend.Options {
 	return b.options.Options
 }
 
-func (b *mysqlBackend) CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
+func (b *mysqlBackend) CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event, signals ...*history.Event) error {
 	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelReadCommitted,
 	})
@@ -141,6 +144,23 @@ func (b *mysqlBackend) CreateWorkflowInstance(ctx context.Context, instance *wor
 	defer tx.Rollback()
 
 	a := event.Attributes.(*history.ExecutionStartedAttributes)
+	if a.UniqueKey != "" {
+		return backend.ErrNotSupported{
+			Message: "unique keys",
+		}
+	}
+
+	if a.Priority != 0 {
+		return backend.ErrNotSupported{
+			Message: "workflow instance priorities",
+		}
+	}
+
+	if len(a.Tags) > 0 {
+		return backend.ErrNotSupported{
+			Message: "workflow instance tags",
+		}
+	}
 
 	// Create workflow instance
 	if err := createInstance(ctx, tx, a.Queue, instance, a.Metadata); err != nil {
@@ -148,8 +168,8 @@ func (b *mysqlBackend) CreateWorkflowInstance(ctx context.Context, instance *wor
 	}
 
 	// Initial history is empty, store only new events
-	if err := insertPendingEvents(ctx, tx, instance, []*history.Event{event}); err != nil {
-		return fmt.Errorf("inserting new event: %w", err)
+	if err := insertPendingEvents(ctx, tx, instance, append([]*history.Event{event}, signals...)); err != nil {
+		return fmt.Errorf("inserting new events: %w", err)
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -182,7 +202,7 @@ func (b *mysqlBackend) removeWorkflowInstance(ctx context.Context, instance *cor
 		}
 	}
 
-	if state == core.WorkflowInstanceStateActive {
+	if state == core.WorkflowInstanceStateActive || state == core.WorkflowInstanceStateErrored {
 		return backend.ErrInstanceNotFinished
 	}
 
@@ -295,14 +315,24 @@ func (b *mysqlBackend) CancelWorkflowInstance(ctx context.Context, instance *wor
 	return tx.Commit()
 }
 
-func (b *mysqlBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, lastSequenceID *int64) ([]*history.Event, error) {
+func (b *mysqlBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, lastSequenceID *int64, options ...backend.HistoryOption) ([]*history.Event, error) {
+	ho := backend.DefaultHistoryOptions
+	for _, opt := range options {
+		opt(&ho)
+	}
+
 	tx, err := b.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
+	return getHistory(ctx, tx, instance, lastSequenceID, ho)
+}
+
+func getHistory(ctx context.Context, tx *sql.Tx, instance *workflow.Instance, lastSequenceID *int64, options backend.HistoryOptions) ([]*history.Event, error) {
 	var historyEvents *sql.Rows
+	var err error
 	if lastSequenceID != nil {
 		historyEvents, err = tx.QueryContext(
 			ctx,
@@ -325,6 +355,11 @@ func (b *mysqlBackend) GetWorkflowInstanceHistory(ctx context.Context, instance
 
 	defer historyEvents.Close()
 
+	deserialize := history.DeserializeAttributes
+	if options.SkipActivityInputs {
+		deserialize = history.DeserializeAttributesWithoutInputs
+	}
+
 	h := make([]*history.Event, 0)
 
 	for historyEvents.Next() {
@@ -344,7 +379,7 @@ func (b *mysqlBackend) GetWorkflowInstanceHistory(ctx context.Context, instance
 			return nil, fmt.Errorf("scanning event: %w", err)
 		}
 
-		a, err := history.DeserializeAttributes(historyEvent.Type, attributes)
+		a, err := deserialize(historyEvent.Type, attributes)
 		if err != nil {
 			return nil, fmt.Errorf("deserializing attributes: %w", err)
 		}
@@ -357,6 +392,54 @@ func (b *mysqlBackend) GetWorkflowInstanceHistory(ctx context.Context, instance
 	return h, nil
 }
 
+func (b *mysqlBackend) GetWorkflowInstanceProgress(ctx context.Context, instance *workflow.Instance) (payload.Payload, error) {
+	row := b.db.QueryRowContext(
+		ctx,
+		"SELECT progress FROM instances WHERE instance_id = ? AND execution_id = ?",
+		instance.InstanceID,
+		instance.ExecutionID,
+	)
+
+	var progress []byte
+	if err := row.Scan(&progress); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, fmt.Errorf("getting progress: %w", err)
+	}
+
+	if progress == nil {
+		return nil, nil
+	}
+
+	return payload.Payload(progress), nil
+}
+
+func (b *mysqlBackend) GetWorkflowInstanceUsage(ctx context.Context, instance *workflow.Instance) (*backend.WorkflowInstanceUsage, error) {
+	row := b.db.QueryRowContext(
+		ctx,
+		`SELECT usage_workflow_tasks, usage_activities, usage_activity_duration, usage_timers, usage_history_events
+		FROM instances WHERE instance_id = ? AND execution_id = ?`,
+		instance.InstanceID,
+		instance.ExecutionID,
+	)
+
+	var usage backend.WorkflowInstanceUsage
+	var activityDuration int64
+	if err := row.Scan(&usage.WorkflowTasks, &usage.Activities, &activityDuration, &usage.Timers, &usage.HistoryEvents); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, fmt.Errorf("getting usage: %w", err)
+	}
+
+	usage.ActivityDuration = time.Duration(activityDuration)
+
+	return &usage, nil
+}
+
 func (b *mysqlBackend) GetWorkflowInstanceState(ctx context.Context, instance *workflow.Instance) (core.WorkflowInstanceState, error) {
 	row := b.db.QueryRowContext(
 		ctx,
@@ -375,13 +458,116 @@ func (b *mysqlBackend) GetWorkflowInstanceState(ctx context.Context, instance *w
 	return state, nil
 }
 
+func (b *mysqlBackend) GetWorkflowInstancesByTag(ctx context.Context, tag string) ([]*backend.TaggedWorkflowInstance, error) {
+	return nil, backend.ErrNotSupported{
+		Message: "workflow instance tags",
+	}
+}
+
+func (b *mysqlBackend) TailWorkflowInstanceLogs(ctx context.Context, instanceID string) (<-chan *backend.InstanceLogEntry, error) {
+	return nil, backend.ErrNotSupported{
+		Message: "tailing workflow instance logs",
+	}
+}
+
+func (b *mysqlBackend) GetLatestWorkflowInstance(ctx context.Context, instanceID string) (*workflow.Instance, error) {
+	row := b.db.QueryRowContext(
+		ctx,
+		"SELECT execution_id, parent_instance_id, parent_execution_id, parent_schedule_event_id FROM instances WHERE instance_id = ? ORDER BY id DESC LIMIT 1",
+		instanceID,
+	)
+
+	var executionID string
+	var parentInstanceID, parentExecutionID *string
+	var parentEventID *int64
+	if err := row.Scan(&executionID, &parentInstanceID, &parentExecutionID, &parentEventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, fmt.Errorf("reading latest instance: %w", err)
+	}
+
+	if parentInstanceID != nil {
+		return core.NewSubWorkflowInstance(instanceID, executionID, core.NewWorkflowInstance(*parentInstanceID, *parentExecutionID), *parentEventID), nil
+	}
+
+	return core.NewWorkflowInstance(instanceID, executionID), nil
+}
+
+func (b *mysqlBackend) GetLatestWorkflowInstanceState(ctx context.Context, instanceID string) (core.WorkflowInstanceState, error) {
+	row := b.db.QueryRowContext(
+		ctx,
+		"SELECT state FROM instances WHERE instance_id = ? ORDER BY id DESC LIMIT 1",
+		instanceID,
+	)
+
+	var state core.WorkflowInstanceState
+	if err := row.Scan(&state); err != nil {
+		if err == sql.ErrNoRows {
+			return core.WorkflowInstanceStateActive, backend.ErrInstanceNotFound
+		}
+
+		return core.WorkflowInstanceStateActive, fmt.Errorf("reading latest instance state: %w", err)
+	}
+
+	return state, nil
+}
+
+func (b *mysqlBackend) GetChildWorkflowInstances(ctx context.Context, instance *workflow.Instance) ([]*workflow.Instance, error) {
+	rows, err := b.db.QueryContext(
+		ctx,
+		"SELECT instance_id, execution_id, parent_schedule_event_id FROM instances WHERE parent_instance_id = ? AND parent_execution_id = ? ORDER BY id",
+		instance.InstanceID,
+		instance.ExecutionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying child instances: %w", err)
+	}
+	defer rows.Close()
+
+	return scanChildInstances(rows, instance)
+}
+
+// scanChildInstances reads child instances ordered by creation, keeping the most recent execution of each
+func scanChildInstances(rows *sql.Rows, parent *workflow.Instance) ([]*workflow.Instance, error) {
+	children := make([]*workflow.Instance, 0)
+	byID := make(map[string]int)
+
+	for rows.Next() {
+		var instanceID, executionID string
+		var parentEventID int64
+		if err := rows.Scan(&instanceID, &executionID, &parentEventID); err != nil {
+			return nil, fmt.Errorf("scanning child instance: %w", err)
+		}
+
+		child := core.NewSubWorkflowInstance(instanceID, executionID, parent, parentEventID)
+
+		// Sub-workflows continued as new keep their parent
+		if i, ok := byID[instanceID]; ok {
+			children[i] = child
+			continue
+		}
+
+		byID[instanceID] = len(children)
+		children = append(children, child)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading child instances: %w", err)
+	}
+
+	return children, nil
+}
+
 func createInstance(ctx context.Context, tx *sql.Tx, queue workflow.Queue, wfi *workflow.Instance, metadata *workflow.Metadata) error {
 	// Check for existing instance
 	if err := tx.QueryRowContext(
 		ctx,
-		"SELECT 1 FROM `instances` WHERE instance_id = ? AND state = ? LIMIT 1",
+		"SELECT 1 FROM `instances` WHERE instance_id = ? AND state IN (?, ?) LIMIT 1",
 		wfi.InstanceID,
-		core.WorkflowInstanceStateActive).
+		core.WorkflowInstanceStateActive,
+		core.WorkflowInstanceStateErrored).
 		Scan(new(int)); err != sql.ErrNoRows {
 		return backend.ErrInstanceAlreadyExists
 	}
@@ -429,7 +615,8 @@ func (b *mysqlBackend) SignalWorkflow(ctx context.Context, instanceID string, ev
 	defer tx.Rollback()
 
 	// TODO: Combine this with the event insertion
-	res := tx.QueryRowContext(ctx, "SELECT execution_id FROM `instances` WHERE instance_id = ? AND state = ? LIMIT 1", instanceID, core.WorkflowInstanceStateActive)
+	res := tx.QueryRowContext(ctx, "SELECT execution_id FROM `instances` WHERE instance_id = ? AND state IN (?, ?) LIMIT 1",
+		instanceID, core.WorkflowInstanceStateActive, core.WorkflowInstanceStateErrored)
 	var executionID string
 	if err := res.Scan(&executionID); err == sql.ErrNoRows {
 		return backend.ErrInstanceNotFound
@@ -444,6 +631,36 @@ func (b *mysqlBackend) SignalWorkflow(ctx context.Context, instanceID string, ev
 	return tx.Commit()
 }
 
+func (b *mysqlBackend) GetActivityResult(ctx context.Context, key string) (payload.Payload, bool, error) {
+	return nil, false, backend.ErrNotSupported{
+		Message: "caching activity results",
+	}
+}
+
+func (b *mysqlBackend) CacheActivityResult(ctx context.Context, key string, result payload.Payload, ttl time.Duration) error {
+	return backend.ErrNotSupported{
+		Message: "caching activity results",
+	}
+}
+
+func (b *mysqlBackend) CacheActivityResultIfAbsent(ctx context.Context, key string, result payload.Payload, ttl time.Duration) (bool, error) {
+	return false, backend.ErrNotSupported{
+		Message: "caching activity results",
+	}
+}
+
+func (b *mysqlBackend) InvalidateActivityResult(ctx context.Context, key string) error {
+	return backend.ErrNotSupported{
+		Message: "caching activity results",
+	}
+}
+
+func (b *mysqlBackend) SubscribeWorkflowInstanceUpdates(ctx context.Context, instanceID string) (<-chan *backend.WorkflowInstanceUpdate, error) {
+	return nil, backend.ErrNotSupported{
+		Message: "subscribing to workflow instance updates",
+	}
+}
+
 func (b *mysqlBackend) PrepareWorkflowQueues(ctx context.Context, queues []workflow.Queue) error {
 	return nil
 }
@@ -462,13 +679,13 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context, queues []workflow.Qu
 	}
 	defer tx.Rollback()
 
-	now := time.Now()
+	now := b.options.Clock.Now()
 	args := []any{
 		core.WorkflowInstanceStateActive, // state
 		now,                              // event.visible_at
 		now,                              // locked_until
 		now,                              // sticky_until
-		b.workerName,                     // worker
+		b.worker(ctx),                    // worker
 	}
 
 	queuePlaceholders := strings.Repeat(",?", len(queues)-1)
@@ -513,7 +730,7 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context, queues []workflow.Qu
 			SET locked_until = ?, worker = ?
 			WHERE id = ?`,
 		now.Add(b.options.WorkflowLockTimeout),
-		b.workerName,
+		b.worker(ctx),
 		id,
 	)
 	if err != nil {
@@ -640,22 +857,41 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 
 	instance := task.WorkflowInstance
 
+	// Usage is accumulated with the instance, so it is updated atomically with the task
+	usage := backend.WorkflowTaskUsage(executedEvents)
+
+	// Keep the stored progress if the task did not report any
+	var progress any
+	if task.Progress != nil {
+		progress = []byte(task.Progress)
+	}
+
 	// Unlock instance, but keep it sticky to the current worker
 	var completedAt *time.Time
 	if state == core.WorkflowInstanceStateContinuedAsNew || state == core.WorkflowInstanceStateFinished {
-		t := time.Now()
+		t := b.options.Clock.Now()
 		completedAt = &t
 	}
 
 	res, err := tx.ExecContext(
 		ctx,
-		`UPDATE instances SET locked_until = NULL, sticky_until = ?, completed_at = ?, state = ? WHERE instance_id = ? AND execution_id = ? AND worker = ?`,
-		time.Now().Add(b.options.StickyTimeout),
+		`UPDATE instances SET locked_until = NULL, sticky_until = ?, completed_at = ?, state = ?,
+			usage_workflow_tasks = usage_workflow_tasks + ?, usage_activities = usage_activities + ?,
+			usage_activity_duration = usage_activity_duration + ?, usage_timers = usage_timers + ?,
+			usage_history_events = usage_history_events + ?, progress = COALESCE(?, progress), task_failures = 0
+		WHERE instance_id = ? AND execution_id = ? AND worker = ?`,
+		b.options.Clock.Now().Add(b.options.StickyTimeout),
 		completedAt,
 		state,
+		usage.WorkflowTasks,
+		usage.Activities,
+		int64(usage.ActivityDuration),
+		usage.Timers,
+		usage.HistoryEvents,
+		progress,
 		instance.InstanceID,
 		instance.ExecutionID,
-		b.workerName,
+		b.worker(ctx),
 	)
 	if err != nil {
 		return fmt.Errorf("unlocking instance: %w", err)
@@ -718,10 +954,18 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 		}
 	}
 
+	if b.options.CompactCanceledTimers {
+		if err := compactCanceledTimers(ctx, tx, instance); err != nil {
+			return fmt.Errorf("compacting history: %w", err)
+		}
+	}
+
 	// Insert new workflow events
-	groupedEvents := history.EventsByWorkflowInstance(workflowEvents)
+	groupedEvents := history.EventGroupsByWorkflowInstance(workflowEvents)
+
+	for _, group := range groupedEvents {
+		targetInstance, events := group.Instance, group.Events
 
-	for targetInstance, events := range groupedEvents {
 		// Are we creating a new sub-workflow instance?
 		m := events[0]
 		if m.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted {
@@ -736,7 +980,7 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 			if err := createInstance(ctx, tx, queue, m.WorkflowInstance, a.Metadata); err != nil {
 				if err == backend.ErrInstanceAlreadyExists {
 					if err := insertPendingEvents(ctx, tx, instance, []*history.Event{
-						history.NewPendingEvent(time.Now(), history.EventType_SubWorkflowFailed, &history.SubWorkflowFailedAttributes{
+						history.NewPendingEvent(b.options.Clock.Now(), history.EventType_SubWorkflowFailed, &history.SubWorkflowFailedAttributes{
 							Error: workflowerrors.FromError(backend.ErrInstanceAlreadyExists),
 						}, history.ScheduleEventID(m.WorkflowInstance.ParentEventID)),
 					}); err != nil {
@@ -755,7 +999,7 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 		for _, m := range events {
 			historyEvents = append(historyEvents, m.HistoryEvent)
 		}
-		if err := insertPendingEvents(ctx, tx, &targetInstance, historyEvents); err != nil {
+		if err := insertPendingEvents(ctx, tx, targetInstance, historyEvents); err != nil {
 			return fmt.Errorf("inserting messages: %w", err)
 		}
 	}
@@ -770,6 +1014,8 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 		return fmt.Errorf("committing complete workflow transaction: %w", err)
 	}
 
+	b.events.Dispatch(instance, executedEvents)
+
 	return nil
 }
 
@@ -780,14 +1026,14 @@ func (b *mysqlBackend) ExtendWorkflowTask(ctx context.Context, task *backend.Wor
 	}
 	defer tx.Rollback()
 
-	until := time.Now().Add(b.options.WorkflowLockTimeout)
+	until := b.options.Clock.Now().Add(b.options.WorkflowLockTimeout)
 	res, err := tx.ExecContext(
 		ctx,
 		`UPDATE instances SET locked_until = ? WHERE instance_id = ? AND execution_id = ? AND worker = ?`,
 		until,
 		task.WorkflowInstance.InstanceID,
 		task.WorkflowInstance.ExecutionID,
-		b.workerName,
+		b.worker(ctx),
 	)
 	if err != nil {
 		return fmt.Errorf("extending workflow task lock: %w", err)
@@ -796,12 +1042,71 @@ func (b *mysqlBackend) ExtendWorkflowTask(ctx context.Context, task *backend.Wor
 	if rowsAffected, err := res.RowsAffected(); err != nil {
 		return fmt.Errorf("determining if workflow task was extended: %w", err)
 	} else if rowsAffected == 0 {
-		return errors.New("could not extend workflow task")
+		return fmt.Errorf("could not extend workflow task: %w", backend.ErrTaskLockLost)
 	}
 
 	return tx.Commit()
 }
 
+func (b *mysqlBackend) FailWorkflowTask(ctx context.Context, task *backend.WorkflowTask, maxFailures int) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The instance becomes errored and is unlocked once it reaches the maximum number of failures, otherwise the
+	// task stays locked until its lock expires. Assignments refer to the current number of failures, so it has to
+	// be incremented last.
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE instances SET
+			state = CASE WHEN ? > 0 AND task_failures + 1 >= ? THEN ? ELSE state END,
+			locked_until = CASE WHEN ? > 0 AND task_failures + 1 >= ? THEN NULL ELSE locked_until END,
+			task_failures = task_failures + 1
+		WHERE instance_id = ? AND execution_id = ? AND worker = ? AND state = ?`,
+		maxFailures, maxFailures, core.WorkflowInstanceStateErrored,
+		maxFailures, maxFailures,
+		task.WorkflowInstance.InstanceID,
+		task.WorkflowInstance.ExecutionID,
+		b.worker(ctx),
+		core.WorkflowInstanceStateActive,
+	)
+	if err != nil {
+		return fmt.Errorf("recording failed workflow task: %w", err)
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("determining if failed workflow task was recorded: %w", err)
+	} else if rowsAffected == 0 {
+		return fmt.Errorf("could not record failed workflow task: %w", backend.ErrTaskLockLost)
+	}
+
+	return tx.Commit()
+}
+
+// activityDispatchPausedSetting is the name of the setting pausing activity dispatch, it exists while paused
+const activityDispatchPausedSetting = "activity_dispatch_paused"
+
+func (b *mysqlBackend) PauseActivityDispatch(ctx context.Context, paused bool) error {
+	if !paused {
+		if _, err := b.db.ExecContext(ctx, "DELETE FROM `settings` WHERE `name` = ?", activityDispatchPausedSetting); err != nil {
+			return fmt.Errorf("resuming activity dispatch: %w", err)
+		}
+
+		return nil
+	}
+
+	if _, err := b.db.ExecContext(
+		ctx, "INSERT INTO `settings` (`name`, `value`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `value` = VALUES(`value`)",
+		activityDispatchPausedSetting, "true",
+	); err != nil {
+		return fmt.Errorf("pausing activity dispatch: %w", err)
+	}
+
+	return nil
+}
+
 // GetActivityTask returns a pending activity task or nil if there are no pending activities
 func (b *mysqlBackend) GetActivityTask(ctx context.Context, queues []workflow.Queue) (*backend.ActivityTask, error) {
 	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
@@ -815,7 +1120,7 @@ func (b *mysqlBackend) GetActivityTask(ctx context.Context, queues []workflow.Qu
 	// Lock next activity
 	queuePlaceholders := strings.Repeat(",?", len(queues)-1)
 
-	now := time.Now()
+	now := b.options.Clock.Now()
 
 	args := make([]interface{}, 0, len(queues)+1)
 	args = append(args, now)
@@ -823,6 +1128,8 @@ func (b *mysqlBackend) GetActivityTask(ctx context.Context, queues []workflow.Qu
 		args = append(args, string(q))
 	}
 
+	args = append(args, activityDispatchPausedSetting)
+
 	res := tx.QueryRowContext(
 		ctx,
 		fmt.Sprintf(`SELECT a.id, a.activity_id, a.instance_id, a.execution_id, a.queue,
@@ -830,6 +1137,7 @@ func (b *mysqlBackend) GetActivityTask(ctx context.Context, queues []workflow.Qu
 			FROM activities a
 			JOIN attributes at ON at.event_id = a.activity_id AND at.instance_id = a.instance_id AND at.execution_id = a.execution_id
 			WHERE (a.locked_until IS NULL OR a.locked_until < ?) AND a.queue IN (?%s)
+				AND NOT EXISTS (SELECT 1 FROM settings WHERE name = ?)
 			LIMIT 1
 			FOR UPDATE SKIP LOCKED`, queuePlaceholders),
 		args...,
@@ -861,7 +1169,7 @@ func (b *mysqlBackend) GetActivityTask(ctx context.Context, queues []workflow.Qu
 		ctx,
 		`UPDATE activities SET locked_until = ?, worker = ? WHERE id = ?`,
 		now.Add(b.options.ActivityLockTimeout),
-		b.workerName,
+		b.worker(ctx),
 		id,
 	); err != nil {
 		return nil, fmt.Errorf("locking activity: %w", err)
@@ -899,7 +1207,7 @@ func (b *mysqlBackend) CompleteActivityTask(ctx context.Context, task *backend.A
 		task.ActivityID,
 		task.WorkflowInstance.InstanceID,
 		task.WorkflowInstance.ExecutionID,
-		b.workerName,
+		b.worker(ctx),
 		task.Queue,
 	); err != nil {
 		return fmt.Errorf("completing activity: %w", err)
@@ -933,13 +1241,13 @@ func (b *mysqlBackend) ExtendActivityTask(ctx context.Context, task *backend.Act
 	}
 	defer tx.Rollback()
 
-	until := time.Now().Add(b.options.ActivityLockTimeout)
+	until := b.options.Clock.Now().Add(b.options.ActivityLockTimeout)
 	_, err = tx.ExecContext(
 		ctx,
 		`UPDATE activities SET locked_until = ? WHERE activity_id = ? AND worker = ?`,
 		until,
 		task.ActivityID,
-		b.workerName,
+		b.worker(ctx),
 	)
 	if err != nil {
 		return fmt.Errorf("extending activity lock: %w", err)
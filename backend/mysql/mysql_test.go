@@ -26,52 +26,7 @@ func Test_MysqlBackend(t *testing.T) {
 
 	var dbName string
 
-	test.BackendTest(t, func(options ...backend.BackendOption) test.TestBackend {
-		db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@/?parseTime=true&interpolateParams=true", testUser, testPassword))
-		if err != nil {
-			panic(err)
-		}
-
-		dbName = "test_" + strings.Replace(uuid.NewString(), "-", "", -1)
-		if _, err := db.Exec("CREATE DATABASE " + dbName); err != nil {
-			panic(fmt.Errorf("creating database: %w", err))
-		}
-
-		if err := db.Close(); err != nil {
-			panic(err)
-		}
-
-		options = append(options, backend.WithStickyTimeout(0))
-
-		return NewMysqlBackend("localhost", 3306, testUser, testPassword, dbName, WithBackendOptions(options...))
-	}, func(b test.TestBackend) {
-		if err := b.(*mysqlBackend).db.Close(); err != nil {
-			panic(err)
-		}
-
-		db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@/?parseTime=true&interpolateParams=true", testUser, testPassword))
-		if err != nil {
-			panic(err)
-		}
-
-		if _, err := db.Exec("DROP DATABASE IF EXISTS " + dbName); err != nil {
-			panic(fmt.Errorf("dropping database: %w", err))
-		}
-
-		if err := db.Close(); err != nil {
-			panic(err)
-		}
-	})
-}
-
-func TestMySqlBackendE2E(t *testing.T) {
-	if testing.Short() {
-		t.Skip()
-	}
-
-	var dbName string
-
-	test.EndToEndBackendTest(t, func(options ...backend.BackendOption) test.TestBackend {
+	test.RunSuite(t, func(options ...backend.BackendOption) test.TestBackend {
 		db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@/?parseTime=true&interpolateParams=true", testUser, testPassword))
 		if err != nil {
 			panic(err)
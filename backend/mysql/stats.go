@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/core"
@@ -39,7 +38,7 @@ func (b *mysqlBackend) GetStats(ctx context.Context) (*backend.Stats, error) {
 	s.ActiveWorkflowInstances = activeInstances
 
 	// Get workflow instances ready to be picked up
-	now := time.Now()
+	now := b.options.Clock.Now()
 	workflowRows, err := tx.QueryContext(
 		ctx,
 		`SELECT i.queue, COUNT(*)
@@ -92,3 +91,30 @@ func (b *mysqlBackend) GetStats(ctx context.Context) (*backend.Stats, error) {
 
 	return s, nil
 }
+
+// QueueLag returns the number of workflow instances with events waiting to be processed, including instances
+// locked by a worker, and the number of activities that have not been completed
+func (b *mysqlBackend) QueueLag(ctx context.Context) (int64, int64, error) {
+	var workflowLag, activityLag int64
+
+	now := b.options.Clock.Now()
+	if err := b.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(DISTINCT i.instance_id, i.execution_id)
+			FROM instances i
+			INNER JOIN pending_events pe ON i.instance_id = pe.instance_id AND i.execution_id = pe.execution_id
+			WHERE
+				i.state = ? AND i.completed_at IS NULL
+				AND (pe.visible_at IS NULL OR pe.visible_at <= ?)`,
+		core.WorkflowInstanceStateActive,
+		now, // event.visible_at
+	).Scan(&workflowLag); err != nil {
+		return 0, 0, fmt.Errorf("failed to query workflow queue lag: %w", err)
+	}
+
+	if err := b.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM activities").Scan(&activityLag); err != nil {
+		return 0, 0, fmt.Errorf("failed to query activity queue lag: %w", err)
+	}
+
+	return workflowLag, activityLag, nil
+}
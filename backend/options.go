@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend/converter"
 	"github.com/cschleiden/go-workflows/backend/metrics"
 	mi "github.com/cschleiden/go-workflows/internal/metrics"
@@ -20,6 +21,10 @@ type Options struct {
 
 	TracerProvider trace.TracerProvider
 
+	// Clock is used for all time reads in the backend, e.g., when locking tasks or scheduling future events. If not
+	// explicitly set, the real wall clock is used.
+	Clock clock.Clock
+
 	// Converter is the converter to use for serializing and deserializing inputs and results. If not explicitly set
 	// converter.DefaultConverter is used.
 	Converter converter.Converter
@@ -43,6 +48,18 @@ type Options struct {
 	// removed immediately, including their history. If set to false, the instance will be removed after the configured
 	// retention period or never.
 	RemoveContinuedAsNewInstances bool
+
+	// CompactCanceledTimers determines whether timers that were scheduled and later canceled are removed from the
+	// history of a workflow instance when completing workflow tasks. See WithCanceledTimerCompaction.
+	CompactCanceledTimers bool
+
+	// BlobStore stores the content of blob inputs, see WithBlobStore. If not set, backends providing their own blob
+	// store use it, otherwise blob inputs are not supported.
+	BlobStore BlobStore
+
+	// EventCallback, if set, is invoked asynchronously for every event added to the history of a workflow instance
+	// after the workflow task executing it has been completed. Events of an instance are delivered in order.
+	EventCallback EventCallback
 }
 
 var DefaultOptions Options = Options{
@@ -53,9 +70,14 @@ var DefaultOptions Options = Options{
 	Logger:         slog.Default(),
 	Metrics:        mi.NewNoopMetricsClient(),
 	TracerProvider: noop.NewTracerProvider(),
+	Clock:          clock.New(),
 	Converter:      converter.DefaultConverter,
 
-	ContextPropagators: []workflow.ContextPropagator{&propagators.TracingContextPropagator{}},
+	ContextPropagators: []workflow.ContextPropagator{
+		&propagators.TracingContextPropagator{},
+		&propagators.HeadersContextPropagator{},
+		&propagators.NamespaceContextPropagator{},
+	},
 
 	RemoveContinuedAsNewInstances: false,
 }
@@ -86,6 +108,12 @@ func WithTracerProvider(tp trace.TracerProvider) BackendOption {
 	}
 }
 
+func WithClock(clock clock.Clock) BackendOption {
+	return func(o *Options) {
+		o.Clock = clock
+	}
+}
+
 func WithConverter(converter converter.Converter) BackendOption {
 	return func(o *Options) {
 		o.Converter = converter
@@ -104,6 +132,41 @@ func WithRemoveContinuedAsNewInstances() BackendOption {
 	}
 }
 
+// WithCanceledTimerCompaction removes the events of canceled timers from the history of workflow instances when
+// completing workflow tasks, which keeps the history of workflows scheduling many
+// short-lived timers, e.g., for timeouts, small.
+//
+// This is safe because a timer is only recorded as canceled if it was canceled in a later workflow task than the one
+// scheduling it. When replaying the compacted history, the workflow schedules and cancels the timer again while
+// replaying the events of these tasks, and the timer command is discarded like the command of a timer canceled in the
+// task scheduling it. Timers canceled in the last recorded event are only compacted once more events have been added,
+// so the sequence ID of the last event of an instance never changes.
+//
+// Compaction is only performed by backends supporting Feature_TimerCompaction.
+func WithCanceledTimerCompaction() BackendOption {
+	return func(o *Options) {
+		o.CompactCanceledTimers = true
+	}
+}
+
+// WithEventCallback registers a callback that is invoked for every event added to the history of a workflow
+// instance. The callback is called from a separate goroutine and must not block for long, since later events of the
+// same instance are delivered only after it returns.
+func WithEventCallback(cb EventCallback) BackendOption {
+	return func(o *Options) {
+		o.EventCallback = cb
+	}
+}
+
+// WithBlobStore sets the store used for the content of blob inputs created with client.CreateBlobInput. All clients
+// and workers of a backend need to use the same store. If the converter is a converter.CodecConverter, its codecs are
+// applied to the content of blobs as well.
+func WithBlobStore(store BlobStore) BackendOption {
+	return func(o *Options) {
+		o.BlobStore = store
+	}
+}
+
 func ApplyOptions(opts ...BackendOption) *Options {
 	options := DefaultOptions
 
@@ -115,5 +178,9 @@ func ApplyOptions(opts ...BackendOption) *Options {
 		options.Logger = slog.Default()
 	}
 
+	if options.Clock == nil {
+		options.Clock = clock.New()
+	}
+
 	return &options
 }
@@ -2,26 +2,95 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/internal/log"
 	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/redis/go-redis/v9"
 )
 
 func (rb *redisBackend) PrepareActivityQueues(ctx context.Context, queues []workflow.Queue) error {
 	return rb.activityQueue.Prepare(ctx, rb.rdb, queues)
 }
 
+// errActivityTaskClaimed is returned when an activity task has been claimed by another worker
+var errActivityTaskClaimed = fmt.Errorf("activity task has been claimed by another worker: %w", backend.ErrTaskLockLost)
+
 func (rb *redisBackend) GetActivityTask(ctx context.Context, queues []workflow.Queue) (*backend.ActivityTask, error) {
-	activityTask, err := rb.activityQueue.Dequeue(ctx, rb.rdb, queues, rb.options.ActivityLockTimeout, rb.options.BlockTimeout)
+	var task *backend.ActivityTask
+	err := rb.retry(ctx, "GetActivityTask", func(int) error {
+		var err error
+		task, err = rb.getActivityTask(ctx, queues)
+		return err
+	})
+
+	return task, err
+}
+
+func (rb *redisBackend) PauseActivityDispatch(ctx context.Context, paused bool) error {
+	if !paused {
+		return rb.rdb.Del(ctx, rb.keys.activityDispatchPausedKey()).Err()
+	}
+
+	return rb.rdb.Set(ctx, rb.keys.activityDispatchPausedKey(), "true", 0).Err()
+}
+
+func (rb *redisBackend) getActivityTask(ctx context.Context, queues []workflow.Queue) (*backend.ActivityTask, error) {
+	paused, err := rb.rdb.Exists(ctx, rb.keys.activityDispatchPausedKey()).Result()
 	if err != nil {
-		return nil, err
+		return nil, wrapBusyError(fmt.Errorf("checking if activity dispatch is paused: %w", err))
+	}
+
+	if paused > 0 {
+		// Wait like a blocking dequeue without tasks, so workers do not poll in a tight loop
+		select {
+		case <-time.After(rb.options.BlockTimeout):
+		case <-ctx.Done():
+		}
+
+		return nil, nil
+	}
+
+	activityTask, err := rb.activityQueue.Dequeue(ctx, rb.rdb, queues, activityRecoverAfter(rb.options), rb.options.BlockTimeout)
+	if err != nil {
+		return nil, wrapBusyError(err)
 	}
 
 	if activityTask == nil {
 		return nil, nil
 	}
 
+	if activityTask.Data.PayloadStored {
+		if err := rb.loadEventPayloads(ctx, activityTask.Data.Instance, []*history.Event{activityTask.Data.Event}, history.DeserializeAttributes); err != nil {
+			if !errors.Is(err, ErrPayloadNotFound) {
+				return nil, fmt.Errorf("loading activity payload: %w", err)
+			}
+
+			// The task can never be executed, for example because the payloads of its instance have been removed.
+			// Drop it instead of delivering it again and again.
+			rb.options.Logger.Error("dropping activity task without payload",
+				log.InstanceIDKey, activityTask.Data.Instance.InstanceID,
+				log.ActivityIDKey, activityTask.Data.ID,
+				log.ErrorKey, err)
+
+			p := rb.rdb.Pipeline()
+			if _, err := rb.activityQueue.Complete(ctx, p, workflow.Queue(activityTask.Data.Queue), activityTask.TaskID); err != nil {
+				return nil, err
+			}
+
+			// The task might have been completed already
+			if _, err := p.Exec(ctx); err != nil && err != redis.Nil {
+				return nil, fmt.Errorf("dropping activity task: %w", err)
+			}
+
+			return nil, nil
+		}
+	}
+
 	return &backend.ActivityTask{
 		WorkflowInstance: activityTask.Data.Instance,
 		Queue:            workflow.Queue(activityTask.Data.Queue),
@@ -31,34 +100,114 @@ func (rb *redisBackend) GetActivityTask(ctx context.Context, queues []workflow.Q
 	}, nil
 }
 
-func (rb *redisBackend) ExtendActivityTask(ctx context.Context, task *backend.ActivityTask) error {
-	p := rb.rdb.Pipeline()
+// activityRecoverAfter returns the time after which claimed activity tasks that have not been extended are recovered
+// from other workers when dequeueing
+func activityRecoverAfter(o *RedisOptions) time.Duration {
+	recoverAfter := o.ActivityLockTimeout
+	if o.ActivityVisibilityTimeout > 0 {
+		recoverAfter = o.ActivityVisibilityTimeout
+	}
 
-	if err := rb.activityQueue.Extend(ctx, p, task.Queue, task.ID); err != nil {
-		return err
+	if o.WorkStealingThreshold > 0 && o.WorkStealingThreshold < recoverAfter {
+		recoverAfter = o.WorkStealingThreshold
 	}
 
-	_, err := p.Exec(ctx)
-	return err
+	return recoverAfter
+}
+
+func (rb *redisBackend) ExtendActivityTask(ctx context.Context, task *backend.ActivityTask) error {
+	return rb.retry(ctx, "ExtendActivityTask", func(int) error {
+		if rb.options.WorkStealingThreshold > 0 {
+			// Extending claims the task, make sure not to take it back from a worker that has stolen it
+			if err := rb.activityQueue.ExtendOwned(ctx, rb.rdb, task.Queue, 0, task.ID); err != nil {
+				if errors.Is(err, errTaskNotOwned) {
+					return errActivityTaskClaimed
+				}
+
+				return err
+			}
+
+			return nil
+		}
+
+		p := rb.rdb.Pipeline()
+
+		if err := rb.activityQueue.Extend(ctx, p, task.Queue, task.ID); err != nil {
+			return err
+		}
+
+		_, err := p.Exec(ctx)
+		return err
+	})
 }
 
 func (rb *redisBackend) CompleteActivityTask(ctx context.Context, task *backend.ActivityTask, result *history.Event) error {
-	instanceState, err := readInstance(ctx, rb.rdb, rb.keys.instanceKey(task.WorkflowInstance))
+	// Only the worker holding the task reports its result
+	if rb.options.WorkStealingThreshold > 0 {
+		if err := rb.activityQueue.Owned(ctx, rb.rdb, task.Queue, task.ID); err != nil {
+			if errors.Is(err, errTaskNotOwned) {
+				return errActivityTaskClaimed
+			}
+
+			return wrapBusyError(err)
+		}
+	}
+
+	eventData, err := marshalEventWithoutAttributes(result)
+	if err != nil {
+		return fmt.Errorf("marshaling event: %w", err)
+	}
+
+	payloadArgs, err := rb.eventPayloadArgs(ctx, task.WorkflowInstance, []*history.Event{result})
 	if err != nil {
 		return err
 	}
 
-	p := rb.rdb.TxPipeline()
+	// Deliver the result to the instance first, the activity task is completed afterwards. If completing the task
+	// fails, the result is not delivered again.
+	args := []interface{}{task.ID, eventData}
+	args = append(args, payloadArgs...)
 
-	if err := rb.addWorkflowInstanceEventP(ctx, p, workflow.Queue(instanceState.Queue), task.WorkflowInstance, result); err != nil {
-		return err
+	r, err := completeActivityTaskCmd.Run(ctx, rb.rdb, []string{
+		rb.keys.instanceKey(task.WorkflowInstance),
+		rb.keys.pendingEventsKey(task.WorkflowInstance),
+		rb.keys.payloadKey(task.WorkflowInstance),
+		rb.keys.deliveriesKey(task.WorkflowInstance),
+	}, args...).Slice()
+	if err != nil {
+		if err == redis.Nil {
+			return backend.ErrInstanceNotFound
+		}
+
+		return wrapBusyError(fmt.Errorf("delivering activity result: %w", err))
 	}
 
-	// Unlock activity
+	p := rb.rdb.TxPipeline()
+
 	if _, err := rb.activityQueue.Complete(ctx, p, task.Queue, task.ID); err != nil {
 		return err
 	}
 
-	_, err = p.Exec(ctx)
-	return err
+	// Queue a workflow task
+	if pending := r[2].(int64); pending > 0 {
+		if err := rb.workflowQueue.Enqueue(ctx, p, workflow.Queue(r[0].(string)), int(r[1].(int64)), instanceSegment(task.WorkflowInstance), nil); err != nil {
+			return fmt.Errorf("queueing workflow: %w", err)
+		}
+	}
+
+	// The task might have been completed already
+	if _, err := p.Exec(ctx); err != nil && err != redis.Nil {
+		return wrapBusyError(fmt.Errorf("completing activity task: %w", err))
+	}
+
+	// Reset inactivity expiration
+	if rb.options.MaxInactivityTTL > 0 {
+		if err := rb.refreshWorkflowInstanceExpiration(ctx, task.WorkflowInstance); err != nil {
+			return fmt.Errorf("refreshing workflow instance expiration: %w", err)
+		}
+	}
+
+	rb.checkPendingEvents(ctx, task.WorkflowInstance)
+
+	return nil
 }
@@ -0,0 +1,250 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_activityData_Marshal(t *testing.T) {
+	instance := core.NewWorkflowInstance("instance", "execution")
+	event := history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+		Name:   "activity",
+		Inputs: []payload.Payload{[]byte(`"large input"`)},
+	}, history.ScheduleEventID(1))
+
+	t.Run("PayloadStored", func(t *testing.T) {
+		data, err := json.Marshal(&activityData{
+			Instance:      instance,
+			ID:            event.ID,
+			Event:         event,
+			PayloadStored: true,
+		})
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "large input")
+
+		var d activityData
+		require.NoError(t, json.Unmarshal(data, &d))
+		require.True(t, d.PayloadStored)
+		require.Equal(t, event.ID, d.Event.ID)
+		require.Equal(t, int64(1), d.Event.ScheduleEventID)
+	})
+
+	t.Run("Legacy", func(t *testing.T) {
+		data, err := json.Marshal(&activityData{
+			Instance: instance,
+			ID:       event.ID,
+			Event:    event,
+		})
+		require.NoError(t, err)
+
+		var d activityData
+		require.NoError(t, json.Unmarshal(data, &d))
+		require.False(t, d.PayloadStored)
+		require.Equal(t, "activity", d.Event.Attributes.(*history.ActivityScheduledAttributes).Name)
+	})
+}
+
+func Test_activityRecoverAfter(t *testing.T) {
+	tests := []struct {
+		name                 string
+		visibilityTimeout    time.Duration
+		workStealing         time.Duration
+		expectedRecoverAfter time.Duration
+	}{
+		{"LockTimeout", 0, 0, time.Minute * 2},
+		{"VisibilityTimeout", time.Second * 30, 0, time.Second * 30},
+		{"WorkStealingShorter", time.Second * 30, time.Second * 10, time.Second * 10},
+		{"VisibilityTimeoutShorter", time.Second * 10, time.Second * 30, time.Second * 10},
+		{"WorkStealingOnly", 0, time.Second * 30, time.Second * 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &RedisOptions{
+				Options:                   backend.ApplyOptions(),
+				ActivityVisibilityTimeout: tt.visibilityTimeout,
+				WorkStealingThreshold:     tt.workStealing,
+			}
+
+			require.Equal(t, tt.expectedRecoverAfter, activityRecoverAfter(o))
+		})
+	}
+}
+
+func Test_CompleteWorkflowTask_SchedulesActivityOnce(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+
+	redisClient := getClient()
+	setup := getCreateBackend(redisClient)
+	b := setup()
+	defer b.Close()
+
+	startedEvent := history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+		Queue: workflow.QueueDefault,
+	})
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, wfi, startedEvent))
+
+	queues := []workflow.Queue{workflow.QueueDefault}
+	require.NoError(t, b.PrepareWorkflowQueues(ctx, queues))
+	require.NoError(t, b.PrepareActivityQueues(ctx, queues))
+
+	task, err := b.GetWorkflowTask(ctx, queues)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	activityScheduledEvent := history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{}, history.ScheduleEventID(1))
+	activityScheduledEvent.SequenceID = 2
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, task, core.WorkflowInstanceStateActive,
+		[]*history.Event{activityScheduledEvent}, []*history.Event{activityScheduledEvent}, nil, nil))
+
+	// The task has been completed, it cannot be completed again
+	redeliveredEvent := history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{}, history.ScheduleEventID(1))
+	require.ErrorIs(t, b.CompleteWorkflowTask(ctx, task, core.WorkflowInstanceStateActive,
+		nil, []*history.Event{redeliveredEvent}, nil, nil), backend.ErrTaskLockLost)
+
+	// A later task scheduling the activity again, with a new event ID, does not enqueue it again
+	require.NoError(t, b.SignalWorkflow(ctx, wfi.InstanceID, history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{
+		Name: "signal",
+	})))
+
+	task, err = b.GetWorkflowTask(ctx, queues)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, task, core.WorkflowInstanceStateActive,
+		nil, []*history.Event{redeliveredEvent}, nil, nil))
+
+	activityTask, err := b.GetActivityTask(ctx, queues)
+	require.NoError(t, err)
+	require.NotNil(t, activityTask)
+	require.Equal(t, activityScheduledEvent.ID, activityTask.ActivityID)
+
+	activityTask, err = b.GetActivityTask(ctx, queues)
+	require.NoError(t, err)
+	require.Nil(t, activityTask)
+}
+
+func Test_GetActivityTask_DropsTaskWithoutPayload(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+
+	redisClient := getClient()
+	setup := getCreateBackend(redisClient)
+	b := setup()
+	defer b.Close()
+
+	startedEvent := history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+		Queue: workflow.QueueDefault,
+	})
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, wfi, startedEvent))
+
+	queues := []workflow.Queue{workflow.QueueDefault}
+	require.NoError(t, b.PrepareWorkflowQueues(ctx, queues))
+	require.NoError(t, b.PrepareActivityQueues(ctx, queues))
+
+	task, err := b.GetWorkflowTask(ctx, queues)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	activityScheduledEvent := history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{}, history.ScheduleEventID(1))
+	activityScheduledEvent.SequenceID = 2
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, task, core.WorkflowInstanceStateActive,
+		[]*history.Event{activityScheduledEvent}, []*history.Event{activityScheduledEvent}, nil, nil))
+
+	// Payloads of the instance are gone, the activity task can never be executed
+	require.NoError(t, redisClient.Del(ctx, b.(*redisBackend).keys.payloadKey(wfi)).Err())
+
+	activityTask, err := b.GetActivityTask(ctx, queues)
+	require.NoError(t, err)
+	require.Nil(t, activityTask)
+
+	lag, err := b.(*redisBackend).activityQueue.Lag(ctx, redisClient)
+	require.NoError(t, err)
+	require.Zero(t, lag)
+}
+
+func Test_CompleteActivityTask_StolenTask(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+
+	redisClient := getClient()
+	setup := getCreateBackend(redisClient, WithWorkStealing(time.Millisecond*10))
+	b := setup()
+	defer b.Close()
+
+	// Second worker, sharing the same redis
+	b2, err := NewRedisBackend(redisClient, WithBlockTimeout(time.Millisecond*10), WithWorkStealing(time.Millisecond*10))
+	require.NoError(t, err)
+
+	startedEvent := history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+		Queue: workflow.QueueDefault,
+	})
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, wfi, startedEvent))
+
+	queues := []workflow.Queue{workflow.QueueDefault}
+	require.NoError(t, b.PrepareWorkflowQueues(ctx, queues))
+	require.NoError(t, b.PrepareActivityQueues(ctx, queues))
+	require.NoError(t, b2.PrepareActivityQueues(ctx, queues))
+
+	task, err := b.GetWorkflowTask(ctx, queues)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	activityScheduledEvent := history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{}, history.ScheduleEventID(1))
+	activityScheduledEvent.SequenceID = 2
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, task, core.WorkflowInstanceStateActive,
+		[]*history.Event{activityScheduledEvent}, []*history.Event{activityScheduledEvent}, nil, nil))
+
+	activityTask, err := b.GetActivityTask(ctx, queues)
+	require.NoError(t, err)
+	require.NotNil(t, activityTask)
+
+	// Not extended in time, the second worker steals the task
+	time.Sleep(time.Millisecond * 20)
+
+	stolenTask, err := b2.GetActivityTask(ctx, queues)
+	require.NoError(t, err)
+	require.NotNil(t, stolenTask)
+	require.Equal(t, activityTask.ID, stolenTask.ID)
+
+	result := history.NewPendingEvent(time.Now(), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{}, history.ScheduleEventID(1))
+
+	require.ErrorIs(t, b.ExtendActivityTask(ctx, activityTask), backend.ErrTaskLockLost)
+	require.ErrorIs(t, b.CompleteActivityTask(ctx, activityTask, result), backend.ErrTaskLockLost)
+
+	pendingEventsKey := b.(*redisBackend).keys.pendingEventsKey(wfi)
+	require.Zero(t, redisClient.XLen(ctx, pendingEventsKey).Val())
+
+	require.NoError(t, b2.ExtendActivityTask(ctx, stolenTask))
+	require.NoError(t, b2.CompleteActivityTask(ctx, stolenTask, result))
+	require.Equal(t, int64(1), redisClient.XLen(ctx, pendingEventsKey).Val())
+}
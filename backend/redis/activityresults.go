@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/redis/go-redis/v9"
+)
+
+func (rb *redisBackend) GetActivityResult(ctx context.Context, key string) (payload.Payload, bool, error) {
+	result, err := rb.rdb.Get(ctx, rb.keys.activityResultKey(key)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	return result, true, nil
+}
+
+func (rb *redisBackend) CacheActivityResult(ctx context.Context, key string, result payload.Payload, ttl time.Duration) error {
+	return rb.rdb.Set(ctx, rb.keys.activityResultKey(key), []byte(result), ttl).Err()
+}
+
+func (rb *redisBackend) CacheActivityResultIfAbsent(ctx context.Context, key string, result payload.Payload, ttl time.Duration) (bool, error) {
+	return rb.rdb.SetNX(ctx, rb.keys.activityResultKey(key), []byte(result), ttl).Result()
+}
+
+func (rb *redisBackend) InvalidateActivityResult(ctx context.Context, key string) error {
+	return rb.rdb.Del(ctx, rb.keys.activityResultKey(key)).Err()
+}
@@ -0,0 +1,120 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// blobChunkSize is the size of the chunks blobs are stored in
+const blobChunkSize = 256 * 1024
+
+// redisBlobStore stores blobs as a LIST of chunks. The first element is an empty header, so empty blobs exist as
+// well. Blobs are written to an upload key first and renamed once complete, so partial blobs are never visible.
+type redisBlobStore struct {
+	rdb  redis.UniversalClient
+	keys *keys
+}
+
+var _ backend.BlobStore = (*redisBlobStore)(nil)
+
+func (s *redisBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	uploadKey := s.keys.blobUploadKey(key, uuid.NewString())
+
+	if err := s.put(ctx, uploadKey, r); err != nil {
+		// Use a new context, the given one might have been canceled
+		s.rdb.Del(context.Background(), uploadKey)
+
+		return err
+	}
+
+	if err := s.rdb.Rename(ctx, uploadKey, s.keys.blobKey(key)).Err(); err != nil {
+		return fmt.Errorf("completing blob: %w", err)
+	}
+
+	return nil
+}
+
+func (s *redisBlobStore) put(ctx context.Context, uploadKey string, r io.Reader) error {
+	if err := s.rdb.RPush(ctx, uploadKey, "").Err(); err != nil {
+		return fmt.Errorf("writing blob header: %w", err)
+	}
+
+	buf := make([]byte, blobChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if err := s.rdb.RPush(ctx, uploadKey, buf[:n]).Err(); err != nil {
+				return fmt.Errorf("writing blob chunk: %w", err)
+			}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reading blob content: %w", err)
+		}
+	}
+}
+
+func (s *redisBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	blobKey := s.keys.blobKey(key)
+
+	n, err := s.rdb.LLen(ctx, blobKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("reading blob: %w", err)
+	}
+
+	if n == 0 {
+		return nil, backend.ErrBlobNotFound
+	}
+
+	return &redisBlobReader{ctx: ctx, rdb: s.rdb, key: blobKey, next: 1, chunks: n}, nil
+}
+
+func (s *redisBlobStore) Delete(ctx context.Context, key string) error {
+	return s.rdb.Del(ctx, s.keys.blobKey(key)).Err()
+}
+
+// redisBlobReader reads the chunks of a blob one at a time
+type redisBlobReader struct {
+	ctx    context.Context
+	rdb    redis.UniversalClient
+	key    string
+	next   int64
+	chunks int64
+	buf    []byte
+}
+
+func (r *redisBlobReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.next >= r.chunks {
+			return 0, io.EOF
+		}
+
+		chunk, err := r.rdb.LIndex(r.ctx, r.key, r.next).Bytes()
+		if err != nil {
+			if err == redis.Nil {
+				return 0, backend.ErrBlobNotFound
+			}
+
+			return 0, fmt.Errorf("reading blob chunk: %w", err)
+		}
+
+		r.buf = chunk
+		r.next++
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
+
+func (r *redisBlobReader) Close() error {
+	return nil
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/redis/go-redis/v9"
+)
+
+// busyErrorPrefixes are prefixes of redis error replies signaling that the server is overloaded or temporarily
+// unable to serve requests.
+var busyErrorPrefixes = []string{
+	"BUSY",     // A script is running for longer than the configured limit
+	"LOADING",  // Dataset is still being loaded into memory
+	"OOM",      // maxmemory has been reached
+	"TRYAGAIN", // Cluster is resharding
+}
+
+// wrapBusyError marks errors caused by an overloaded redis server with backend.ErrBackendBusy
+func wrapBusyError(err error) error {
+	if err == nil || !isBusyError(err) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", backend.ErrBackendBusy, err)
+}
+
+func isBusyError(err error) bool {
+	var rerr redis.Error
+	if !errors.As(err, &rerr) {
+		return false
+	}
+
+	msg := rerr.Error()
+	for _, prefix := range busyErrorPrefixes {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
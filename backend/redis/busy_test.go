@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/stretchr/testify/require"
+)
+
+type testRedisError string
+
+func (e testRedisError) Error() string { return string(e) }
+
+func (testRedisError) RedisError() {}
+
+func Test_wrapBusyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		busy bool
+	}{
+		{"nil", nil, false},
+		{"other error", errors.New("some error"), false},
+		{"other redis error", testRedisError("ERR unknown command"), false},
+		{"script busy", testRedisError("BUSY Redis is busy running a script"), true},
+		{"loading", testRedisError("LOADING Redis is loading the dataset in memory"), true},
+		{"out of memory", fmt.Errorf("dequeueing task: %w", testRedisError("OOM command not allowed")), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wrapBusyError(tt.err)
+
+			require.Equal(t, tt.busy, errors.Is(err, backend.ErrBackendBusy))
+
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
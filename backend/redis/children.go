@@ -0,0 +1,88 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+func (rb *redisBackend) GetChildWorkflowInstances(ctx context.Context, instance *workflow.Instance) ([]*workflow.Instance, error) {
+	childrenKey := rb.keys.childrenKey(instance)
+
+	instanceIDs, err := rb.rdb.ZRange(ctx, childrenKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("getting child instances: %w", err)
+	}
+
+	if len(instanceIDs) == 0 {
+		return nil, nil
+	}
+
+	// Look up the most recent execution of every child
+	latestKeys := make([]string, len(instanceIDs))
+	for i, instanceID := range instanceIDs {
+		latestKeys[i] = rb.keys.latestInstanceExecutionKey(instanceID)
+	}
+
+	executionIDs, err := getKeys(ctx, rb.rdb, latestKeys)
+	if err != nil {
+		return nil, fmt.Errorf("getting latest executions: %w", err)
+	}
+
+	children := make([]*workflow.Instance, 0, len(instanceIDs))
+	removed := make([]interface{}, 0)
+
+	for i, e := range executionIDs {
+		executionID, ok := e.(string)
+		if !ok {
+			// Instance has expired or has been removed
+			removed = append(removed, instanceIDs[i])
+			continue
+		}
+
+		children = append(children, core.NewWorkflowInstance(instanceIDs[i], executionID))
+	}
+
+	if len(children) > 0 {
+		instanceKeys := make([]string, len(children))
+		for i, child := range children {
+			instanceKeys[i] = rb.keys.instanceKey(child)
+		}
+
+		states, err := getKeys(ctx, rb.rdb, instanceKeys)
+		if err != nil {
+			return nil, fmt.Errorf("getting instances: %w", err)
+		}
+
+		existing := children[:0]
+		for i, s := range states {
+			str, ok := s.(string)
+			if !ok {
+				removed = append(removed, children[i].InstanceID)
+				continue
+			}
+
+			var state instanceState
+			if err := json.Unmarshal([]byte(str), &state); err != nil {
+				return nil, fmt.Errorf("unmarshaling instance state: %w", err)
+			}
+
+			// Include the link to the parent
+			existing = append(existing, state.Instance)
+		}
+
+		children = existing
+	}
+
+	// Children are not removed from the index when they expire or are removed, clean them up lazily
+	if len(removed) > 0 {
+		if err := rb.rdb.ZRem(ctx, childrenKey, removed...).Err(); err != nil {
+			rb.Options().Logger.WarnContext(ctx, "removing child instances", "instance", instance.InstanceID, "error", err)
+		}
+	}
+
+	return children, nil
+}
@@ -0,0 +1,302 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/redis/go-redis/v9"
+)
+
+// pendingCompletion holds the steps of a workflow task completion that are executed after the task has been committed
+// to its instance. On a cluster, the keys of other instances and the keys shared by all instances are stored in other
+// slots than the keys of the instance, so a completion cannot be executed by a single script. The script committing
+// the task stores the remaining steps with the instance. They are executed by the worker completing the task or, if
+// that fails, by the next worker dequeueing a task for the instance. Every step can be executed more than once.
+type pendingCompletion struct {
+	// ID identifies the completion, events are delivered to other instances once per completion
+	ID string `json:"id"`
+
+	// TaskID is the ID of the completed workflow task
+	TaskID string `json:"task_id"`
+
+	// Queue and Priority identify the stream the task was read from
+	Queue    workflow.Queue `json:"queue"`
+	Priority int            `json:"priority,omitempty"`
+
+	Instance *core.WorkflowInstance     `json:"instance"`
+	State    core.WorkflowInstanceState `json:"state"`
+
+	// UniqueKey and Tags of the instance, they are handed over to the new execution when continuing as new
+	UniqueKey string   `json:"unique_key,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+
+	// CompletedAt is the time the task was completed, as a unix timestamp
+	CompletedAt int64 `json:"completed_at"`
+
+	// CanceledTimers are the schedule event IDs of timers canceled by the task
+	CanceledTimers []int64 `json:"canceled_timers,omitempty"`
+
+	// Timers are the timers scheduled by the task
+	Timers []*pendingTimer `json:"timers,omitempty"`
+
+	// Activities are the activity tasks to queue
+	Activities []*activityData `json:"activities,omitempty"`
+
+	// Deliveries are the events sent to other instances, grouped by instance
+	Deliveries []*pendingDelivery `json:"deliveries,omitempty"`
+}
+
+type pendingTimer struct {
+	ScheduleEventID int64 `json:"schedule_event_id"`
+
+	// VisibleAt is the time the timer fires, in unix milliseconds
+	VisibleAt int64 `json:"visible_at"`
+}
+
+type pendingDelivery struct {
+	Instance *core.WorkflowInstance `json:"instance"`
+
+	// State is the state of the new instance, if the events start one
+	State string `json:"state,omitempty"`
+
+	// ContinuedFrom is the execution ID of the execution the new instance continues as new
+	ContinuedFrom string `json:"continued_from,omitempty"`
+
+	Events []string `json:"events"`
+
+	// Payloads of the events, if they are stored inline
+	Payloads map[string]string `json:"payloads,omitempty"`
+
+	// ConflictEvent is added to the pending events of the sending instance if the new instance cannot be started
+	ConflictEvent string `json:"conflict_event,omitempty"`
+}
+
+// KEYS[1] - unique key
+// ARGV[1] - instance segment
+var releaseUniqueKeyCmd = redis.NewScript(
+	`if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("DEL", KEYS[1])
+	end
+	return 0`)
+
+// executePendingCompletion executes the remaining steps of the last workflow task completion of the given instance,
+// if there are any. Returns the executed completion.
+func (rb *redisBackend) executePendingCompletion(ctx context.Context, instance *core.WorkflowInstance) (*pendingCompletion, error) {
+	data, err := rb.rdb.Get(ctx, rb.keys.completionKey(instance)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("reading pending completion: %w", err)
+	}
+
+	var c pendingCompletion
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return nil, fmt.Errorf("unmarshaling pending completion: %w", err)
+	}
+
+	if err := rb.executeCompletion(ctx, &c); err != nil {
+		return nil, wrapBusyError(fmt.Errorf("completing workflow task: %w", err))
+	}
+
+	return &c, nil
+}
+
+// executeCompletion executes the steps of the given completion after the task has been committed to its instance
+func (rb *redisBackend) executeCompletion(ctx context.Context, c *pendingCompletion) error {
+	sender := instanceSegment(c.Instance)
+
+	type delivered struct {
+		delivery *pendingDelivery
+		queue    workflow.Queue
+		priority int
+	}
+
+	// Send events to other workflow instances
+	deliveries := make([]delivered, 0, len(c.Deliveries))
+	children := make([]interface{}, 0)
+	conflicts := make([]interface{}, 0)
+	for _, d := range c.Deliveries {
+		args := []interface{}{sender, c.ID}
+
+		if d.State != "" {
+			activeInstance, err := json.Marshal(d.Instance)
+			if err != nil {
+				return fmt.Errorf("marshaling instance: %w", err)
+			}
+
+			args = append(args, 1, d.State, string(activeInstance), d.Instance.ExecutionID, d.ContinuedFrom)
+		} else {
+			args = append(args, 0)
+		}
+
+		args = append(args, len(d.Events))
+		for _, event := range d.Events {
+			args = append(args, event)
+		}
+
+		args = append(args, len(d.Payloads))
+		for eventID, payload := range d.Payloads {
+			args = append(args, eventID, payload)
+		}
+
+		r, err := deliverWorkflowEventsCmd.Run(ctx, rb.rdb, []string{
+			rb.keys.instanceKey(d.Instance),
+			rb.keys.activeInstanceExecutionKey(d.Instance.InstanceID),
+			rb.keys.latestInstanceExecutionKey(d.Instance.InstanceID),
+			rb.keys.pendingEventsKey(d.Instance),
+			rb.keys.payloadKey(d.Instance),
+			rb.keys.deliveriesKey(d.Instance),
+		}, args...).Result()
+		if err != nil {
+			if err == redis.Nil {
+				// Instance has expired or has been removed
+				continue
+			}
+
+			return fmt.Errorf("delivering events to instance: %w", err)
+		}
+
+		target, ok := r.([]interface{})
+		if !ok {
+			// Another execution of the instance is active
+			conflicts = append(conflicts, d.ConflictEvent)
+			continue
+		}
+
+		deliveries = append(deliveries, delivered{
+			delivery: d,
+			queue:    workflow.Queue(target[0].(string)),
+			priority: int(target[1].(int64)),
+		})
+
+		// Executions continued as new keep their position with the parent
+		if d.State != "" && d.Instance.Parent != nil &&
+			d.Instance.Parent.InstanceID == c.Instance.InstanceID && d.Instance.Parent.ExecutionID == c.Instance.ExecutionID {
+			children = append(children, d.Instance.InstanceID)
+		}
+	}
+
+	// The shared keys are updated together with completing the task. Once the task has been completed, they must not
+	// be updated again, activities might have been executed already.
+	workflowKeys := rb.workflowQueue.KeysWithPriority(c.Queue, c.Priority)
+	keys := []string{
+		workflowKeys.SetKey,
+		workflowKeys.StreamKey,
+		rb.keys.futureEventsKey(),
+		rb.keys.instancesActive(),
+		rb.keys.instancesByCreation(),
+	}
+	args := []interface{}{rb.workflowQueue.groupName, c.TaskID, sender}
+
+	args = append(args, len(c.CanceledTimers))
+	for _, scheduleEventID := range c.CanceledTimers {
+		args = append(args, futureEventMember(c.Instance, scheduleEventID))
+	}
+
+	args = append(args, len(c.Timers))
+	for _, timer := range c.Timers {
+		args = append(args, futureEventMember(c.Instance, timer.ScheduleEventID), timer.VisibleAt)
+	}
+
+	args = append(args, len(c.Activities))
+	for _, activity := range c.Activities {
+		data, err := json.Marshal(activity)
+		if err != nil {
+			return fmt.Errorf("marshaling activity task: %w", err)
+		}
+
+		keys = append(keys, rb.activityQueue.enqueueKeys(workflow.Queue(activity.Queue), 0)...)
+		args = append(args, activity.ID, string(data))
+	}
+
+	// Workflow tasks do not carry any data
+	workflowTaskData, err := json.Marshal((*workflowData)(nil))
+	if err != nil {
+		return fmt.Errorf("marshaling workflow task: %w", err)
+	}
+
+	args = append(args, len(deliveries))
+	for _, d := range deliveries {
+		keys = append(keys, rb.workflowQueue.enqueueKeys(d.queue, d.priority)...)
+		args = append(args, instanceSegment(d.delivery.Instance), d.priority, string(workflowTaskData))
+
+		if d.delivery.State == "" {
+			args = append(args, 0)
+			continue
+		}
+
+		args = append(args, 1, c.CompletedAt)
+
+		// Hand over the unique key and the tags when continuing as new. Tag sets are scored in nanoseconds, like for
+		// newly created instances.
+		if d.delivery.ContinuedFrom != "" && c.UniqueKey != "" {
+			keys = append(keys, rb.keys.uniqueKey(c.UniqueKey))
+			args = append(args, 1)
+		} else {
+			args = append(args, 0)
+		}
+
+		tags := 0
+		if d.delivery.ContinuedFrom != "" {
+			for _, tag := range c.Tags {
+				keys = append(keys, rb.keys.tagKey(tag))
+			}
+
+			tags = len(c.Tags)
+		}
+
+		args = append(args, tags, c.CompletedAt*1_000_000_000)
+	}
+
+	if c.State == core.WorkflowInstanceStateFinished || c.State == core.WorkflowInstanceStateContinuedAsNew {
+		args = append(args, 1)
+
+		// Release the unique key, when continued as new it is handed over to the new execution
+		if c.State == core.WorkflowInstanceStateFinished && c.UniqueKey != "" {
+			keys = append(keys, rb.keys.uniqueKey(c.UniqueKey))
+			args = append(args, 1)
+		} else {
+			args = append(args, 0)
+		}
+	} else {
+		args = append(args, 0)
+	}
+
+	// Nothing is updated if the task has been completed by another worker executing the completion in the meantime
+	if err := completeWorkflowTaskSharedCmd.Run(ctx, rb.rdb, keys, args...).Err(); err != nil {
+		return fmt.Errorf("completing workflow task: %w", err)
+	}
+
+	args = []interface{}{c.ID, len(children)}
+	args = append(args, children...)
+	args = append(args, len(conflicts))
+	args = append(args, conflicts...)
+
+	pending, err := finishWorkflowTaskCmd.Run(ctx, rb.rdb, []string{
+		rb.keys.pendingEventsKey(c.Instance),
+		rb.keys.childrenKey(c.Instance),
+		rb.keys.completionKey(c.Instance),
+	}, args...).Int64()
+	if err != nil {
+		return fmt.Errorf("finishing workflow task: %w", err)
+	}
+
+	// If there are pending events, queue the instance again
+	if pending > 0 {
+		p := rb.rdb.Pipeline()
+		if err := rb.workflowQueue.Enqueue(ctx, p, c.Queue, c.Priority, sender, nil); err != nil {
+			return fmt.Errorf("queueing workflow: %w", err)
+		}
+
+		if _, err := p.Exec(ctx); err != nil {
+			return fmt.Errorf("queueing workflow: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// completion is a workflow task completion waiting to be executed as part of a batch
+type completion struct {
+	// instance identifies the workflow instance the task belongs to
+	instance string
+
+	keys []string
+	args []interface{}
+
+	done chan error
+}
+
+// completionBatcher combines workflow task completions of different workflow instances, so that they can be
+// executed in a single round-trip.
+type completionBatcher struct {
+	maxBatch int
+	maxDelay time.Duration
+
+	// exec executes a batch of completions. It has to report the result for every completion.
+	exec func(ctx context.Context, batch []*completion)
+
+	mu        sync.Mutex
+	pending   []*completion
+	instances map[string]struct{}
+	timer     *time.Timer
+}
+
+func newCompletionBatcher(maxBatch int, maxDelay time.Duration, exec func(ctx context.Context, batch []*completion)) *completionBatcher {
+	return &completionBatcher{
+		maxBatch:  maxBatch,
+		maxDelay:  maxDelay,
+		exec:      exec,
+		instances: make(map[string]struct{}),
+	}
+}
+
+// Complete adds the completion to the current batch and waits until the batch has been executed. If the context is
+// canceled before the batch is executed, the completion is dropped and the context error is returned.
+func (b *completionBatcher) Complete(ctx context.Context, instance string, keys []string, args []interface{}) error {
+	c := &completion{
+		instance: instance,
+		keys:     keys,
+		args:     args,
+		done:     make(chan error, 1),
+	}
+
+	// The batch is executed independently of the context of any single completion
+	execCtx := context.WithoutCancel(ctx)
+
+	b.mu.Lock()
+
+	// Completions for the same instance are never part of the same batch, execute the current batch first
+	if _, ok := b.instances[instance]; ok {
+		batch := b.take()
+		go b.exec(execCtx, batch)
+	}
+
+	b.pending = append(b.pending, c)
+	b.instances[instance] = struct{}{}
+
+	var batch []*completion
+	if len(b.pending) >= b.maxBatch {
+		batch = b.take()
+	} else if len(b.pending) == 1 {
+		b.timer = time.AfterFunc(b.maxDelay, func() {
+			b.mu.Lock()
+			batch := b.take()
+			b.mu.Unlock()
+
+			if len(batch) > 0 {
+				b.exec(execCtx, batch)
+			}
+		})
+	}
+
+	b.mu.Unlock()
+
+	if batch != nil {
+		b.exec(execCtx, batch)
+	}
+
+	select {
+	case err := <-c.done:
+		return err
+	case <-ctx.Done():
+		// Canceled completions that are still waiting for their batch are not executed. Once the batch is being
+		// executed, wait for its result so that callers never miss a completion that has been applied.
+		if b.remove(c) {
+			return ctx.Err()
+		}
+
+		return <-c.done
+	}
+}
+
+// remove removes the completion from the current batch. It returns false if the completion is not pending anymore.
+func (b *completionBatcher) remove(c *completion) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, pc := range b.pending {
+		if pc == c {
+			b.pending = append(b.pending[:i], b.pending[i+1:]...)
+			delete(b.instances, c.instance)
+
+			if len(b.pending) == 0 && b.timer != nil {
+				b.timer.Stop()
+				b.timer = nil
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// take removes and returns the current batch. Must be called with the lock held.
+func (b *completionBatcher) take() []*completion {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	batch := b.pending
+	b.pending = nil
+	clear(b.instances)
+
+	return batch
+}
+
+// execCompletions runs the complete workflow task script for all completions in a single pipeline. Every script
+// invocation is still atomic on its own, so each instance is updated completely or not at all.
+func (rb *redisBackend) execCompletions(ctx context.Context, batch []*completion) {
+	p := rb.rdb.Pipeline()
+
+	cmds := make([]*redis.Cmd, len(batch))
+	for i, c := range batch {
+		cmds[i] = completeWorkflowTaskCmd.EvalSha(ctx, p, c.keys, c.args...)
+	}
+
+	// Errors are reported for the individual commands
+	_, _ = p.Exec(ctx)
+
+	for i, c := range batch {
+		err := cmds[i].Err()
+		if redis.HasErrorPrefix(err, "NOSCRIPT") {
+			// Script has been flushed from the script cache, nothing has been executed yet
+			err = completeWorkflowTaskCmd.Run(ctx, rb.rdb, c.keys, c.args...).Err()
+		}
+
+		c.done <- err
+	}
+}
@@ -0,0 +1,181 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingExec struct {
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+}
+
+func (r *recordingExec) exec(ctx context.Context, batch []*completion) {
+	r.mu.Lock()
+	instances := make([]string, len(batch))
+	for i, c := range batch {
+		instances[i] = c.instance
+	}
+	r.batches = append(r.batches, instances)
+	r.mu.Unlock()
+
+	for _, c := range batch {
+		c.done <- r.err
+	}
+}
+
+func (r *recordingExec) Batches() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.batches
+}
+
+func Test_CompletionBatcher_ExecutesFullBatch(t *testing.T) {
+	r := &recordingExec{}
+	b := newCompletionBatcher(3, time.Hour, r.exec)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			require.NoError(t, b.Complete(context.Background(), fmt.Sprintf("instance-%d", i), nil, nil))
+		}(i)
+	}
+
+	wg.Wait()
+
+	require.Len(t, r.Batches(), 1)
+	require.ElementsMatch(t, []string{"instance-0", "instance-1", "instance-2"}, r.Batches()[0])
+}
+
+func Test_CompletionBatcher_ExecutesAfterDelay(t *testing.T) {
+	r := &recordingExec{}
+	b := newCompletionBatcher(10, time.Millisecond*10, r.exec)
+
+	require.NoError(t, b.Complete(context.Background(), "instance", nil, nil))
+	require.Equal(t, [][]string{{"instance"}}, r.Batches())
+}
+
+func Test_CompletionBatcher_DoesNotBatchSameInstance(t *testing.T) {
+	r := &recordingExec{}
+	b := newCompletionBatcher(10, time.Millisecond*50, r.exec)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, b.Complete(context.Background(), "instance", nil, nil))
+		}()
+
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	wg.Wait()
+
+	require.Equal(t, [][]string{{"instance"}, {"instance"}}, r.Batches())
+}
+
+func Test_CompletionBatcher_ReportsErrors(t *testing.T) {
+	r := &recordingExec{err: errors.New("script failed")}
+	b := newCompletionBatcher(1, time.Hour, r.exec)
+
+	require.ErrorIs(t, b.Complete(context.Background(), "instance", nil, nil), r.err)
+}
+
+func Test_CompletionBatcher_CanceledCompletionIsNotExecuted(t *testing.T) {
+	r := &recordingExec{}
+	b := newCompletionBatcher(10, time.Millisecond*50, r.exec)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*5)
+	defer cancel()
+
+	require.ErrorIs(t, b.Complete(ctx, "canceled", nil, nil), context.DeadlineExceeded)
+	require.NoError(t, b.Complete(context.Background(), "instance", nil, nil))
+
+	require.Equal(t, [][]string{{"instance"}}, r.Batches())
+}
+
+func Test_EndToEndRedisBackend_CompletionBatching(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	client := getClient()
+	setup := getCreateBackend(client, WithCompletionBatching(10, time.Millisecond))
+
+	test.EndToEndBackendTest(t, setup, nil)
+}
+
+// Benchmark_CompletionBatching runs many short workflows concurrently, with and without batching completions
+func Benchmark_CompletionBatching(b *testing.B) {
+	tests := []struct {
+		name    string
+		options []RedisBackendOption
+	}{
+		{"Unbatched", nil},
+		{"Batched", []RedisBackendOption{WithCompletionBatching(50, time.Millisecond)}},
+	}
+
+	wf := func(ctx workflow.Context, i int) (int, error) {
+		return i, nil
+	}
+
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			be := getCreateBackend(getClient(), tt.options...)()
+			defer be.Close()
+
+			wo := worker.DefaultOptions
+			wo.WorkflowPollers = 8
+			wo.MaxParallelWorkflowTasks = 200
+			wo.WorkflowPollingInterval = 0
+
+			w := worker.New(be, &wo)
+			require.NoError(b, w.RegisterWorkflow(wf))
+			require.NoError(b, w.Start(ctx))
+
+			c := client.New(be)
+
+			b.ResetTimer()
+
+			var wg sync.WaitGroup
+			for i := 0; i < b.N; i++ {
+				instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+					InstanceID: fmt.Sprintf("instance-%d", i),
+				}, wf, i)
+				require.NoError(b, err)
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := client.GetWorkflowResult[int](ctx, c, instance, time.Minute)
+					require.NoError(b, err)
+				}()
+			}
+
+			wg.Wait()
+
+			b.StopTimer()
+
+			cancel()
+			require.NoError(b, w.WaitForCompletion())
+		})
+	}
+}
@@ -11,13 +11,25 @@ import (
 // KEYS[1] - instance key
 // KEYS[2] - pending events key
 // KEYS[3] - history key
-// KEYS[4] - payload key
-// KEYS[5] - active-instance-execution key
-// KEYS[6] - instances-by-creation key
-// ARGV[1] - instance segment
+// KEYS[4] - active-instance-execution key
+// KEYS[5] - latest-instance-execution key
+// KEYS[6..n] - other instance keys to delete
+// ARGV[1] - execution id
+//
+// When continued as new, the active and latest execution keys belong to the new execution already
 var deleteCmd = redis.NewScript(
-	`redis.call("DEL", KEYS[1], KEYS[2], KEYS[3], KEYS[4], KEYS[5])
-	return redis.call("ZREM", KEYS[6], ARGV[1])`)
+	`redis.call("DEL", KEYS[1], KEYS[2], KEYS[3])
+	for i = 6, #KEYS do
+		redis.call("DEL", KEYS[i])
+	end
+	local active = redis.call("GET", KEYS[4])
+	if active and cjson.decode(active)["execution_id"] == ARGV[1] then
+		redis.call("DEL", KEYS[4])
+	end
+	if redis.call("GET", KEYS[5]) == ARGV[1] then
+		redis.call("DEL", KEYS[5])
+	end
+	return true`)
 
 // deleteInstance deletes an instance from Redis. It does not attempt to remove any future events or pending
 // workflow tasks. It's assumed that the instance is in the finished state.
@@ -28,12 +40,26 @@ func (rb *redisBackend) deleteInstance(ctx context.Context, instance *core.Workf
 		rb.keys.instanceKey(instance),
 		rb.keys.pendingEventsKey(instance),
 		rb.keys.historyKey(instance),
-		rb.keys.payloadKey(instance),
 		rb.keys.activeInstanceExecutionKey(instance.InstanceID),
-		rb.keys.instancesByCreation(),
-	}, instanceSegment(instance)).Err(); err != nil {
+		rb.keys.latestInstanceExecutionKey(instance.InstanceID),
+		rb.keys.childrenKey(instance),
+		rb.keys.progressKey(instance),
+		rb.keys.scheduledActivitiesKey(instance),
+		rb.keys.usageKey(instance),
+		rb.keys.completionKey(instance),
+		rb.keys.resetKey(instance),
+		rb.keys.deliveriesKey(instance),
+	}, instance.ExecutionID).Err(); err != nil {
 		return fmt.Errorf("failed to delete instance: %w", err)
 	}
 
+	if err := rb.rdb.ZRem(ctx, rb.keys.instancesByCreation(), instanceSegment(instance)).Err(); err != nil {
+		return fmt.Errorf("failed to remove instance from index: %w", err)
+	}
+
+	if err := rb.options.PayloadStore.Delete(ctx, instance); err != nil {
+		return fmt.Errorf("failed to delete payloads: %w", err)
+	}
+
 	return nil
 }
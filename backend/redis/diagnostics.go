@@ -56,7 +56,7 @@ func (rb *redisBackend) GetWorkflowInstances(ctx context.Context, afterInstanceI
 		return nil, nil
 	}
 
-	instances, err := rb.rdb.MGet(ctx, instanceKeys...).Result()
+	instances, err := getKeys(ctx, rb.rdb, instanceKeys)
 	if err != nil {
 		return nil, fmt.Errorf("getting instances: %w", err)
 	}
@@ -79,6 +79,8 @@ func (rb *redisBackend) GetWorkflowInstances(ctx context.Context, afterInstanceI
 			CompletedAt: state.CompletedAt,
 			State:       state.State,
 			Queue:       state.Queue,
+
+			LastActivityAt: state.LastActivityAt,
 		})
 	}
 
@@ -106,5 +108,7 @@ func mapWorkflowInstance(instance *instanceState) *diag.WorkflowInstanceRef {
 		CompletedAt: instance.CompletedAt,
 		State:       instance.State,
 		Queue:       instance.Queue,
+
+		LastActivityAt: instance.LastActivityAt,
 	}
 }
@@ -3,9 +3,13 @@ package redis
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/cschleiden/go-workflows/client"
 	"github.com/cschleiden/go-workflows/diag"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -42,3 +46,52 @@ func Test_Diag_GetWorkflowInstances(t *testing.T) {
 	require.Len(t, instances, 1)
 	require.Equal(t, "ex1", instances[0].Instance.InstanceID)
 }
+
+func Test_Diag_GetWorkflowInstance_LastActivityAt(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	rclient := getClient()
+	setup := getCreateBackend(rclient)
+
+	b := setup()
+
+	t.Cleanup(func() {
+		b.Close()
+	})
+
+	bd := b.(diag.Backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := client.New(b)
+	w := worker.New(b, nil)
+
+	wf := func(ctx workflow.Context) error {
+		return nil
+	}
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.Start(ctx))
+
+	wfi, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, wf)
+	require.NoError(t, err)
+
+	// No task has been completed yet
+	instance, err := bd.GetWorkflowInstance(ctx, wfi)
+	require.NoError(t, err)
+	require.Nil(t, instance.LastActivityAt)
+
+	require.NoError(t, c.WaitForWorkflowInstance(ctx, wfi, time.Second*10))
+
+	instance, err = bd.GetWorkflowInstance(ctx, wfi)
+	require.NoError(t, err)
+	require.NotNil(t, instance.LastActivityAt)
+	require.WithinDuration(t, time.Now(), *instance.LastActivityAt, time.Second*10)
+
+	cancel()
+	require.NoError(t, w.WaitForCompletion())
+}
@@ -0,0 +1,239 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/redis/go-redis/v9"
+)
+
+// DispatchCandidate is a workflow task that is ready to be dispatched to a worker
+type DispatchCandidate struct {
+	// Instance is the workflow instance the task is for
+	Instance *core.WorkflowInstance
+
+	// EnqueuedAt is the time the task was added to the queue
+	EnqueuedAt time.Time
+
+	// CreatedAt is the time the workflow instance was created
+	CreatedAt time.Time
+
+	// Priority is the priority the workflow instance was created with
+	Priority int
+
+	// PendingEvents is the number of events waiting to be processed for the instance. Only set when a pending events
+	// threshold is configured.
+	PendingEvents int64
+}
+
+// DispatchPolicy decides which of the ready workflow tasks is dispatched next.
+type DispatchPolicy interface {
+	// Select returns the index of the candidate to dispatch. Candidates are passed in queue order, there is always
+	// at least one candidate.
+	Select(candidates []DispatchCandidate) int
+}
+
+type oldestFirstDispatchPolicy struct{}
+
+// OldestFirstDispatchPolicy dispatches tasks for the workflow instance that was created first.
+func OldestFirstDispatchPolicy() DispatchPolicy {
+	return &oldestFirstDispatchPolicy{}
+}
+
+func (p *oldestFirstDispatchPolicy) Select(candidates []DispatchCandidate) int {
+	selected := 0
+	for i, c := range candidates {
+		if c.CreatedAt.Before(candidates[selected].CreatedAt) {
+			selected = i
+		}
+	}
+
+	return selected
+}
+
+type priorityDispatchPolicy struct {
+	priority func(instance *core.WorkflowInstance) int
+}
+
+// PriorityDispatchPolicy dispatches tasks for the workflow instance with the highest priority. Tasks with the same
+// priority are dispatched in queue order.
+func PriorityDispatchPolicy(priority func(instance *core.WorkflowInstance) int) DispatchPolicy {
+	return &priorityDispatchPolicy{priority: priority}
+}
+
+func (p *priorityDispatchPolicy) Select(candidates []DispatchCandidate) int {
+	selected := 0
+	selectedPriority := p.priority(candidates[0].Instance)
+	for i := 1; i < len(candidates); i++ {
+		if priority := p.priority(candidates[i].Instance); priority > selectedPriority {
+			selected, selectedPriority = i, priority
+		}
+	}
+
+	return selected
+}
+
+// roundRobinHistorySize is the number of instances the round-robin policy remembers
+const roundRobinHistorySize = 10_000
+
+type instancePriorityDispatchPolicy struct{}
+
+// InstancePriorityDispatchPolicy dispatches tasks for the workflow instance with the highest priority given in
+// client.WorkflowInstanceOptions. Tasks with the same priority are dispatched in queue order.
+func InstancePriorityDispatchPolicy() DispatchPolicy {
+	return &instancePriorityDispatchPolicy{}
+}
+
+func (p *instancePriorityDispatchPolicy) Select(candidates []DispatchCandidate) int {
+	selected := 0
+	for i, c := range candidates {
+		if c.Priority > candidates[selected].Priority {
+			selected = i
+		}
+	}
+
+	return selected
+}
+
+type roundRobinDispatchPolicy struct {
+	mu sync.Mutex
+
+	// counter is incremented for every dispatched task
+	counter uint64
+
+	// lastDispatched maps instance IDs to the counter value at which they were last dispatched
+	lastDispatched map[string]uint64
+}
+
+// RoundRobinDispatchPolicy dispatches tasks for the workflow instance that was dispatched least recently, so that
+// very active instances cannot starve others. Instances that have never been dispatched go first.
+func RoundRobinDispatchPolicy() DispatchPolicy {
+	return &roundRobinDispatchPolicy{
+		lastDispatched: make(map[string]uint64),
+	}
+}
+
+func (p *roundRobinDispatchPolicy) Select(candidates []DispatchCandidate) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	selected := 0
+	selectedLast := p.lastDispatched[candidates[0].Instance.InstanceID]
+	for i := 1; i < len(candidates); i++ {
+		if last := p.lastDispatched[candidates[i].Instance.InstanceID]; last < selectedLast {
+			selected, selectedLast = i, last
+		}
+	}
+
+	p.counter++
+	p.lastDispatched[candidates[selected].Instance.InstanceID] = p.counter
+
+	// Forget instances that haven't been dispatched in a while
+	if len(p.lastDispatched) > roundRobinHistorySize {
+		for id, last := range p.lastDispatched {
+			if p.counter-last > roundRobinHistorySize/2 {
+				delete(p.lastDispatched, id)
+			}
+		}
+	}
+
+	return selected
+}
+
+// workflowTaskSelector returns the selector used by the workflow task queue to apply the configured dispatch policy
+func (rb *redisBackend) workflowTaskSelector(policy DispatchPolicy) taskSelector[workflowData] {
+	return func(ctx context.Context, rdb redis.UniversalClient, tasks []*TaskItem[workflowData]) (int, error) {
+		segments := make([]string, len(tasks))
+		instanceKeys := make([]string, len(tasks))
+		for i, task := range tasks {
+			segments[i] = task.ID
+			instanceKeys[i] = rb.keys.instanceKeyFromSegment(task.ID)
+		}
+
+		p := rdb.Pipeline()
+		createdCmd := p.ZMScore(ctx, rb.keys.instancesByCreation(), segments...)
+		instanceCmds := make([]*redis.StringCmd, len(tasks))
+		for i, instanceKey := range instanceKeys {
+			instanceCmds[i] = p.Get(ctx, instanceKey)
+		}
+
+		var pendingCmds []*redis.IntCmd
+		if rb.options.PendingEventsThreshold > 0 {
+			pendingCmds = make([]*redis.IntCmd, len(tasks))
+			for i, task := range tasks {
+				pendingCmds[i] = p.XLen(ctx, rb.keys.pendingEventsKey(instanceFromSegment(task.ID)))
+			}
+		}
+
+		// Instances might have been removed in the meantime
+		if _, err := p.Exec(ctx); err != nil && err != redis.Nil {
+			return 0, fmt.Errorf("reading instances: %w", err)
+		}
+
+		created := createdCmd.Val()
+
+		candidates := make([]DispatchCandidate, len(tasks))
+		for i, task := range tasks {
+			candidates[i] = DispatchCandidate{
+				Instance:   instanceFromSegment(task.ID),
+				EnqueuedAt: taskEnqueuedAt(task.TaskID),
+				CreatedAt:  time.Unix(0, int64(created[i])),
+				Priority:   instancePriority(instanceCmds[i].Val()),
+			}
+
+			if pendingCmds != nil {
+				candidates[i].PendingEvents = pendingCmds[i].Val()
+			}
+		}
+
+		// Instances that are falling behind take precedence over the policy
+		if pendingCmds != nil {
+			if selected := rb.backloggedCandidate(candidates); selected >= 0 {
+				return selected, nil
+			}
+		}
+
+		selected := policy.Select(candidates)
+		if selected < 0 || selected >= len(candidates) {
+			return 0, fmt.Errorf("dispatch policy selected invalid candidate %d of %d", selected, len(candidates))
+		}
+
+		return selected, nil
+	}
+}
+
+// instancePriority returns the priority stored in the given serialized instance state. Instances that could not be
+// read, e.g., because they have been removed in the meantime, have the default priority.
+func instancePriority(s string) int {
+	var state instanceState
+	if err := json.Unmarshal([]byte(s), &state); err != nil {
+		return 0
+	}
+
+	return state.Priority
+}
+
+func instanceFromSegment(segment string) *core.WorkflowInstance {
+	// Instance IDs may contain colons, execution IDs do not
+	idx := strings.LastIndex(segment, ":")
+	if idx < 0 {
+		return core.NewWorkflowInstance(segment, "")
+	}
+
+	return core.NewWorkflowInstance(segment[:idx], segment[idx+1:])
+}
+
+// taskEnqueuedAt returns the time a task was enqueued, based on its stream message ID
+func taskEnqueuedAt(taskID string) time.Time {
+	var ms int64
+	if _, err := fmt.Sscanf(taskID, "%d-", &ms); err != nil {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(ms)
+}
@@ -0,0 +1,107 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/stretchr/testify/require"
+)
+
+func candidates(ids ...string) []DispatchCandidate {
+	now := time.Now()
+
+	c := make([]DispatchCandidate, len(ids))
+	for i, id := range ids {
+		c[i] = DispatchCandidate{
+			Instance:   core.NewWorkflowInstance(id, "e"),
+			EnqueuedAt: now.Add(time.Duration(i) * time.Second),
+			CreatedAt:  now,
+		}
+	}
+
+	return c
+}
+
+func Test_OldestFirstDispatchPolicy(t *testing.T) {
+	c := candidates("a", "b", "c")
+	c[0].CreatedAt = c[0].CreatedAt.Add(time.Minute)
+	c[2].CreatedAt = c[2].CreatedAt.Add(-time.Minute)
+
+	require.Equal(t, 2, OldestFirstDispatchPolicy().Select(c))
+
+	// Ties are dispatched in queue order
+	require.Equal(t, 0, OldestFirstDispatchPolicy().Select(candidates("a", "b")))
+}
+
+func Test_PriorityDispatchPolicy(t *testing.T) {
+	p := PriorityDispatchPolicy(func(instance *core.WorkflowInstance) int {
+		if instance.InstanceID == "important" {
+			return 10
+		}
+
+		return 0
+	})
+
+	require.Equal(t, 1, p.Select(candidates("a", "important", "b")))
+	require.Equal(t, 0, p.Select(candidates("a", "b")))
+}
+
+func Test_InstancePriorityDispatchPolicy(t *testing.T) {
+	p := InstancePriorityDispatchPolicy()
+
+	c := candidates("a", "b", "c", "d")
+	c[1].Priority = 5
+	c[2].Priority = 10
+	c[3].Priority = 10
+	require.Equal(t, 2, p.Select(c))
+
+	// Default priority preserves queue order
+	require.Equal(t, 0, p.Select(candidates("a", "b")))
+}
+
+func Test_InstancePriority(t *testing.T) {
+	require.Equal(t, 5, instancePriority(`{"queue":"default","priority":5}`))
+	require.Equal(t, 0, instancePriority(`{"queue":"default"}`))
+	require.Equal(t, 0, instancePriority(""))
+}
+
+func Test_BackloggedCandidate(t *testing.T) {
+	rb := &redisBackend{options: &RedisOptions{PendingEventsThreshold: 10}}
+
+	c := candidates("a", "b", "c", "d")
+	c[0].PendingEvents = 10
+	c[1].PendingEvents = 11
+	c[2].PendingEvents = 50
+	c[3].PendingEvents = 50
+	require.Equal(t, 2, rb.backloggedCandidate(c))
+
+	// No instance above the threshold
+	require.Equal(t, -1, rb.backloggedCandidate(candidates("a", "b")))
+}
+
+func Test_RoundRobinDispatchPolicy(t *testing.T) {
+	p := RoundRobinDispatchPolicy()
+
+	// Never dispatched instances go first, in queue order
+	require.Equal(t, 0, p.Select(candidates("busy", "a")))
+	require.Equal(t, 1, p.Select(candidates("busy", "a")))
+
+	// busy was dispatched before a
+	require.Equal(t, 0, p.Select(candidates("busy", "a")))
+
+	// New instances are preferred, then the least recently dispatched one
+	require.Equal(t, 2, p.Select(candidates("busy", "a", "b")))
+	require.Equal(t, 1, p.Select(candidates("busy", "a")))
+}
+
+func Test_InstanceFromSegment(t *testing.T) {
+	instance := core.NewWorkflowInstance("some:instance", "execution")
+
+	require.Equal(t, instance, instanceFromSegment(instanceSegment(instance)))
+}
+
+func Test_TaskEnqueuedAt(t *testing.T) {
+	require.Equal(t, time.UnixMilli(1700000000123), taskEnqueuedAt("1700000000123-0"))
+	require.True(t, taskEnqueuedAt("invalid").IsZero())
+}
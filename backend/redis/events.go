@@ -3,10 +3,8 @@ package redis
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	"github.com/cschleiden/go-workflows/backend/history"
-	"github.com/cschleiden/go-workflows/core"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -15,11 +13,14 @@ type eventWithoutAttributes struct {
 }
 
 func (e *eventWithoutAttributes) MarshalJSON() ([]byte, error) {
+	// Drop the methods of history.Event, so that its custom marshaling doesn't include the attributes
+	type Aevent history.Event
+
 	return json.Marshal(&struct {
-		*history.Event
+		*Aevent
 		Attributes interface{} `json:"attr"`
 	}{
-		Event:      e.Event,
+		Aevent:     (*Aevent)(e.Event),
 		Attributes: nil,
 	})
 }
@@ -33,29 +34,73 @@ func marshalEventWithoutAttributes(event *history.Event) (string, error) {
 	return string(data), nil
 }
 
-// KEYS[1 - payload key
-// ARGV[1..n] - payload values
-var addPayloadsCmd = redis.NewScript(`
-	for i = 1, #ARGV, 2 do
-		redis.pcall("HSETNX", KEYS[1], ARGV[i], ARGV[i+1])
-	end
+// historyEvent is an event as stored in the history stream. Scheduled activities keep their attributes without inputs
+// with the event, so that replaying a workflow doesn't need to load their potentially large payloads.
+type historyEvent struct {
+	*history.Event
+
+	scheduledActivity json.RawMessage
+}
+
+func (e *historyEvent) MarshalJSON() ([]byte, error) {
+	type Aevent history.Event
+
+	return json.Marshal(&struct {
+		*Aevent
+		Attributes        interface{}     `json:"attr"`
+		ScheduledActivity json.RawMessage `json:"sa,omitempty"`
+	}{
+		Aevent:            (*Aevent)(e.Event),
+		Attributes:        nil,
+		ScheduledActivity: e.scheduledActivity,
+	})
+}
 
-	return 0
-`)
+// marshalHistoryEvent marshals an event for the history stream. Like marshalEventWithoutAttributes, attributes are
+// stored separately, except for the attributes of scheduled activities without their inputs.
+func marshalHistoryEvent(event *history.Event) (string, error) {
+	e := &historyEvent{Event: event}
 
-func (rb *redisBackend) addEventPayloadsP(ctx context.Context, p redis.Pipeliner, instance *core.WorkflowInstance, events []*history.Event) error {
-	args := make([]interface{}, 0)
+	if a, ok := event.Attributes.(*history.ActivityScheduledAttributes); ok {
+		withoutInputs := *a
+		withoutInputs.Inputs = nil
 
-	for _, event := range events {
-		payload, err := json.Marshal(event.Attributes)
+		attributes, err := history.SerializeAttributes(&withoutInputs)
 		if err != nil {
-			return fmt.Errorf("marshaling event payload: %w", err)
+			return "", err
 		}
 
-		args = append(args, event.ID, string(payload))
+		e.scheduledActivity = attributes
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// unmarshalHistoryEvent unmarshals an event from the history stream. For scheduled activities, it also returns the
+// attributes without inputs, if they have been stored with the event.
+func unmarshalHistoryEvent(data string) (*history.Event, []byte, error) {
+	var event *history.Event
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return nil, nil, err
+	}
+
+	if event.Type != history.EventType_ActivityScheduled {
+		return event, nil, nil
+	}
+
+	var e struct {
+		ScheduledActivity json.RawMessage `json:"sa,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return nil, nil, err
 	}
 
-	return addPayloadsCmd.Run(ctx, p, []string{rb.keys.payloadKey(instance)}, args...).Err()
+	return event, e.ScheduledActivity, nil
 }
 
 func addEventToStreamP(ctx context.Context, p redis.Pipeliner, streamKey string, event *history.Event) error {
@@ -4,19 +4,101 @@ import (
 	"context"
 	"fmt"
 	"strconv"
-	"time"
+	"strings"
 
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/workflow"
 	redis "github.com/redis/go-redis/v9"
 )
 
+// futureEventMember returns the member of the set of future events for the given timer
+func futureEventMember(instance *core.WorkflowInstance, scheduleEventID int64) string {
+	return fmt.Sprintf("%v:%v", instanceSegment(instance), scheduleEventID)
+}
+
+// parseFutureEventMember returns the instance and the schedule event ID of the timer the given member of the set of
+// future events refers to
+func parseFutureEventMember(member string) (*core.WorkflowInstance, int64, error) {
+	idx := strings.LastIndex(member, ":")
+	if idx < 0 {
+		return nil, 0, fmt.Errorf("invalid future event %q", member)
+	}
+
+	scheduleEventID, err := strconv.ParseInt(member[idx+1:], 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid future event %q: %w", member, err)
+	}
+
+	return instanceFromSegment(member[:idx]), scheduleEventID, nil
+}
+
 func scheduleFutureEvents(ctx context.Context, rb *redisBackend) error {
-	now := time.Now().UnixMilli()
+	now := rb.options.Clock.Now().UnixMilli()
 	nowStr := strconv.FormatInt(now, 10)
-	if _, err := futureEventsCmd.Run(ctx, rb.rdb, []string{
-		rb.keys.futureEventsKey(),
-	}, nowStr, rb.keys.prefix).Result(); err != nil && err != redis.Nil {
+
+	members, err := rb.rdb.ZRangeByScore(ctx, rb.keys.futureEventsKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: nowStr,
+	}).Result()
+	if err != nil {
 		return fmt.Errorf("checking future events: %w", err)
 	}
 
+	if len(members) == 0 {
+		return nil
+	}
+
+	// Events of an instance are moved to its pending events together
+	instances := make([]*core.WorkflowInstance, 0)
+	futureEventKeys := make(map[core.WorkflowInstance][]string)
+	for _, member := range members {
+		instance, scheduleEventID, err := parseFutureEventMember(member)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := futureEventKeys[*instance]; !ok {
+			instances = append(instances, instance)
+		}
+
+		futureEventKeys[*instance] = append(futureEventKeys[*instance], rb.keys.futureEventKey(instance, scheduleEventID))
+	}
+
+	p := rb.rdb.TxPipeline()
+
+	for _, instance := range instances {
+		keys := append([]string{
+			rb.keys.instanceKey(instance),
+			rb.keys.pendingEventsKey(instance),
+		}, futureEventKeys[*instance]...)
+
+		r, err := futureEventsCmd.Run(ctx, rb.rdb, keys).Slice()
+		if err != nil {
+			if err == redis.Nil {
+				// Instance has expired or has been removed
+				continue
+			}
+
+			return fmt.Errorf("scheduling future events: %w", err)
+		}
+
+		// Queue a workflow task, events that were moved by another worker might not have been processed yet
+		if pending := r[2].(int64); pending > 0 {
+			if err := rb.workflowQueue.Enqueue(ctx, p, workflow.Queue(r[0].(string)), int(r[1].(int64)), instanceSegment(instance), nil); err != nil {
+				return fmt.Errorf("queueing workflow: %w", err)
+			}
+		}
+	}
+
+	removed := make([]interface{}, len(members))
+	for i, member := range members {
+		removed[i] = member
+	}
+	p.ZRem(ctx, rb.keys.futureEventsKey(), removed...)
+
+	if _, err := p.Exec(ctx); err != nil {
+		return fmt.Errorf("scheduling future events: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MarshalEventWithoutAttributes(t *testing.T) {
+	event := history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+		Name: "activity",
+	})
+
+	data, err := marshalEventWithoutAttributes(event)
+	require.NoError(t, err)
+	require.Contains(t, data, `"attr":null`)
+	require.NotContains(t, data, "activity")
+}
+
+func Test_MarshalHistoryEvent(t *testing.T) {
+	event := history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+		Name:   "activity",
+		Inputs: []payload.Payload{[]byte(`"large input"`)},
+	})
+
+	data, err := marshalHistoryEvent(event)
+	require.NoError(t, err)
+	require.Contains(t, data, "activity")
+	require.NotContains(t, data, "large input")
+
+	e, scheduledActivity, err := unmarshalHistoryEvent(data)
+	require.NoError(t, err)
+	require.Equal(t, event.ID, e.ID)
+
+	attributes, err := history.DeserializeAttributesWithoutInputs(history.EventType_ActivityScheduled, scheduledActivity)
+	require.NoError(t, err)
+	require.Equal(t, "activity", attributes.(*history.ActivityScheduledAttributes).Name)
+}
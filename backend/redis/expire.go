@@ -2,30 +2,150 @@ package redis
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"time"
 
+	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/core"
+	redis "github.com/redis/go-redis/v9"
 )
 
+// FinalState is the outcome a workflow run finished with
+type FinalState int
+
+const (
+	// FinalStateCompleted is the outcome of runs that finished without an error
+	FinalStateCompleted FinalState = iota
+
+	// FinalStateFailed is the outcome of runs that finished with an error, including canceled runs that returned the
+	// cancellation error
+	FinalStateFailed
+
+	// FinalStateTerminated is the outcome of runs that were terminated
+	FinalStateTerminated
+)
+
+// finalState returns the outcome of a run finished by a workflow task with the given executed events
+func finalState(executedEvents []*history.Event) FinalState {
+	state := FinalStateCompleted
+
+	for _, event := range executedEvents {
+		switch event.Type {
+		case history.EventType_WorkflowExecutionTerminated:
+			return FinalStateTerminated
+
+		case history.EventType_WorkflowExecutionFinished:
+			if a, ok := event.Attributes.(*history.ExecutionCompletedAttributes); ok && a.Error != nil {
+				state = FinalStateFailed
+			}
+		}
+	}
+
+	return state
+}
+
+// finishedExpiration returns the duration after which a run finished by a workflow task expires. 0 means the run does
+// not expire.
+func (o *RedisOptions) finishedExpiration(state core.WorkflowInstanceState, executedEvents []*history.Event) time.Duration {
+	if state == core.WorkflowInstanceStateContinuedAsNew {
+		if o.AutoExpirationContinueAsNew > 0 {
+			return o.AutoExpirationContinueAsNew
+		}
+
+		return o.AutoExpiration
+	}
+
+	if expiration, ok := o.RetentionPolicy[finalState(executedEvents)]; ok {
+		return expiration
+	}
+
+	return o.AutoExpiration
+}
+
 func (rb *redisBackend) setWorkflowInstanceExpiration(ctx context.Context, instance *core.WorkflowInstance, expiration time.Duration) error {
-	now := time.Now().UnixMilli()
+	if err := rb.expireWorkflowInstance(ctx, instance, expiration, []string{
+		rb.keys.instanceKey(instance),
+		rb.keys.pendingEventsKey(instance),
+		rb.keys.historyKey(instance),
+		rb.keys.childrenKey(instance),
+		rb.keys.progressKey(instance),
+		rb.keys.scheduledActivitiesKey(instance),
+		rb.keys.usageKey(instance),
+		rb.keys.completionKey(instance),
+		rb.keys.resetKey(instance),
+		rb.keys.deliveriesKey(instance),
+	}); err != nil {
+		return err
+	}
+
+	return rb.options.PayloadStore.Expire(ctx, instance, expiration)
+}
+
+// refreshWorkflowInstanceExpiration resets the inactivity expiration for an active workflow instance. In addition to
+// the keys expired for finished instances, this also expires the keys tracking the active execution, so that a new
+// instance with the same ID can be started once an abandoned instance has expired.
+func (rb *redisBackend) refreshWorkflowInstanceExpiration(ctx context.Context, instance *core.WorkflowInstance) error {
+	expiration := rb.options.MaxInactivityTTL
+
+	if err := rb.expireWorkflowInstance(ctx, instance, expiration, []string{
+		rb.keys.instanceKey(instance),
+		rb.keys.pendingEventsKey(instance),
+		rb.keys.historyKey(instance),
+		rb.keys.activeInstanceExecutionKey(instance.InstanceID),
+		rb.keys.childrenKey(instance),
+		rb.keys.progressKey(instance),
+		rb.keys.scheduledActivitiesKey(instance),
+		rb.keys.usageKey(instance),
+		rb.keys.completionKey(instance),
+		rb.keys.resetKey(instance),
+		rb.keys.deliveriesKey(instance),
+	}); err != nil {
+		return err
+	}
+
+	return rb.options.PayloadStore.Expire(ctx, instance, expiration)
+}
+
+// expireWorkflowInstance expires the given keys of the instance, and then tracks the expiration in the indexes shared
+// by all instances. The unique key of the instance expires with it, if the instance still holds it.
+func (rb *redisBackend) expireWorkflowInstance(
+	ctx context.Context, instance *core.WorkflowInstance, expiration time.Duration, instanceKeys []string,
+) error {
+	now := rb.options.Clock.Now().UnixMilli()
 	nowStr := strconv.FormatInt(now, 10)
 
-	exp := time.Now().Add(expiration).UnixMilli()
+	exp := rb.options.Clock.Now().Add(expiration).UnixMilli()
 	expStr := strconv.FormatInt(exp, 10)
 
-	return expireWorkflowInstanceCmd.Run(ctx, rb.rdb, []string{
+	// The instance key needs to be the first of the instance keys
+	keys := append([]string{rb.keys.latestInstanceExecutionKey(instance.InstanceID)}, instanceKeys...)
+
+	uniqueKey, err := expireWorkflowInstanceCmd.Run(ctx, rb.rdb, keys,
+		expiration.Seconds(),
+		instance.ExecutionID,
+	).Text()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("expiring instance: %w", err)
+	}
+
+	indexKeys := []string{
 		rb.keys.instancesByCreation(),
 		rb.keys.instancesExpiring(),
-		rb.keys.instanceKey(instance),
-		rb.keys.pendingEventsKey(instance),
-		rb.keys.historyKey(instance),
-		rb.keys.payloadKey(instance),
-	},
+		rb.keys.instancesActive(),
+	}
+	if uniqueKey != "" {
+		indexKeys = append(indexKeys, rb.keys.uniqueKey(uniqueKey))
+	}
+
+	if err := expireWorkflowInstanceIndexCmd.Run(ctx, rb.rdb, indexKeys,
 		nowStr,
 		expiration.Seconds(),
 		expStr,
 		instanceSegment(instance),
-	).Err()
+	).Err(); err != nil {
+		return fmt.Errorf("tracking instance expiration: %w", err)
+	}
+
+	return nil
 }
@@ -2,11 +2,15 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/workflowerrors"
 	"github.com/cschleiden/go-workflows/worker"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
@@ -60,6 +64,51 @@ func Test_AutoExpiration(t *testing.T) {
 	require.NoError(t, w.WaitForCompletion())
 }
 
+func Test_finishedExpiration(t *testing.T) {
+	completed := []*history.Event{
+		history.NewPendingEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{}),
+	}
+	failed := []*history.Event{
+		history.NewPendingEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{
+			Error: workflowerrors.FromError(errors.New("failed")),
+		}),
+	}
+	terminated := []*history.Event{
+		history.NewWorkflowTerminationEvent(time.Now()),
+		history.NewPendingEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{
+			Error: workflowerrors.FromError(errors.New("workflow terminated")),
+		}),
+	}
+
+	policy := map[FinalState]time.Duration{
+		FinalStateCompleted:  time.Hour * 24,
+		FinalStateFailed:     time.Hour * 24 * 30,
+		FinalStateTerminated: 0,
+	}
+
+	tests := []struct {
+		name           string
+		options        RedisOptions
+		state          core.WorkflowInstanceState
+		executedEvents []*history.Event
+		want           time.Duration
+	}{
+		{"AutoExpiration", RedisOptions{AutoExpiration: time.Hour}, core.WorkflowInstanceStateFinished, failed, time.Hour},
+		{"Completed", RedisOptions{AutoExpiration: time.Hour, RetentionPolicy: policy}, core.WorkflowInstanceStateFinished, completed, time.Hour * 24},
+		{"Failed", RedisOptions{AutoExpiration: time.Hour, RetentionPolicy: policy}, core.WorkflowInstanceStateFinished, failed, time.Hour * 24 * 30},
+		{"Terminated", RedisOptions{AutoExpiration: time.Hour, RetentionPolicy: policy}, core.WorkflowInstanceStateFinished, terminated, 0},
+		{"NoEntry", RedisOptions{AutoExpiration: time.Hour, RetentionPolicy: map[FinalState]time.Duration{FinalStateFailed: time.Minute}}, core.WorkflowInstanceStateFinished, completed, time.Hour},
+		{"ContinuedAsNew", RedisOptions{AutoExpiration: time.Hour, RetentionPolicy: policy}, core.WorkflowInstanceStateContinuedAsNew, nil, time.Hour},
+		{"AutoExpirationContinueAsNew", RedisOptions{AutoExpiration: time.Hour, AutoExpirationContinueAsNew: time.Minute}, core.WorkflowInstanceStateContinuedAsNew, nil, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.options.finishedExpiration(tt.state, tt.executedEvents))
+		})
+	}
+}
+
 func Test_AutoExpiration_SubWorkflow(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
@@ -195,3 +244,98 @@ func Test_AutoExpiration_ContinueAsNew_SubWorkflow(t *testing.T) {
 		require.ErrorIs(t, err, backend.ErrInstanceNotFound)
 	}
 }
+
+func Test_MaxInactivityTTL(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	inactivityTTL := time.Second * 2
+
+	redisClient := getClient()
+	setup := getCreateBackend(redisClient, WithMaxInactivityTTL(inactivityTTL))
+	b := setup()
+
+	c := client.New(b)
+	w := worker.New(b, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	require.NoError(t, w.Start(ctx))
+	defer func() {
+		cancel()
+
+		require.NoError(t, w.WaitForCompletion())
+	}()
+
+	wf := func(ctx workflow.Context) error {
+		// Wait for a signal that never arrives
+		workflow.NewSignalChannel[int](ctx, "signal").Receive(ctx)
+
+		return nil
+	}
+
+	w.RegisterWorkflow(wf)
+
+	instanceID := uuid.NewString()
+	wfi, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: instanceID,
+	}, wf)
+	require.NoError(t, err)
+
+	// Keep the instance active for longer than the TTL
+	for i := 0; i < 3; i++ {
+		time.Sleep(inactivityTTL / 2)
+
+		require.NoError(t, c.SignalWorkflow(ctx, instanceID, "other", i))
+	}
+
+	state, err := b.GetWorkflowInstanceState(ctx, wfi)
+	require.NoError(t, err)
+	require.Equal(t, core.WorkflowInstanceStateActive, state)
+
+	// Wait for redis to expire the abandoned instance
+	time.Sleep(inactivityTTL * 2)
+
+	_, err = b.GetWorkflowInstanceState(ctx, wfi)
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+	// A new instance with the same ID can be started
+	_, err = c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: instanceID,
+	}, wf)
+	require.NoError(t, err)
+}
+
+func Test_setWorkflowInstanceExpiration_LatestExecution(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+
+	redisClient := getClient()
+	setup := getCreateBackend(redisClient)
+	b := setup()
+	defer b.Close()
+
+	rb := b.(*redisBackend)
+
+	startedEvent := history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+		Queue: workflow.QueueDefault,
+	})
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, wfi, startedEvent))
+
+	latestKey := rb.keys.latestInstanceExecutionKey(wfi.InstanceID)
+
+	// The latest execution expires together with the instance
+	require.NoError(t, rb.setWorkflowInstanceExpiration(ctx, wfi, time.Hour))
+	require.Greater(t, redisClient.TTL(ctx, latestKey).Val(), time.Duration(0))
+
+	// A newer execution has been started, it is kept
+	require.NoError(t, redisClient.Set(ctx, latestKey, uuid.NewString(), 0).Err())
+	require.NoError(t, rb.setWorkflowInstanceExpiration(ctx, wfi, time.Hour))
+	require.Equal(t, time.Duration(-1), redisClient.TTL(ctx, latestKey).Val())
+}
@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -14,7 +15,7 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-func (rb *redisBackend) CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
+func (rb *redisBackend) CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event, signals ...*history.Event) error {
 	a := event.Attributes.(*history.ExecutionStartedAttributes)
 
 	instanceState, err := json.Marshal(&instanceState{
@@ -22,7 +23,10 @@ func (rb *redisBackend) CreateWorkflowInstance(ctx context.Context, instance *wo
 		Instance:  instance,
 		State:     core.WorkflowInstanceStateActive,
 		Metadata:  a.Metadata,
-		CreatedAt: time.Now(),
+		CreatedAt: rb.options.Clock.Now(),
+		UniqueKey: a.UniqueKey,
+		Priority:  a.Priority,
+		Tags:      a.Tags,
 	})
 	if err != nil {
 		return fmt.Errorf("marshaling instance state: %w", err)
@@ -33,51 +37,149 @@ func (rb *redisBackend) CreateWorkflowInstance(ctx context.Context, instance *wo
 		return fmt.Errorf("marshaling instance: %w", err)
 	}
 
-	eventData, err := marshalEventWithoutAttributes(event)
+	events := append([]*history.Event{event}, signals...)
+
+	eventsData := make([]interface{}, 0, len(events))
+	for _, event := range events {
+		eventData, err := marshalEventWithoutAttributes(event)
+		if err != nil {
+			return fmt.Errorf("marshaling event: %w", err)
+		}
+
+		eventsData = append(eventsData, eventData)
+	}
+
+	// Instances are registered with the shared indexes first, the unique key and the limit of active instances are
+	// enforced there. Registering is undone if the instance cannot be created.
+	exists, err := rb.rdb.Exists(ctx, rb.keys.activeInstanceExecutionKey(instance.InstanceID)).Result()
 	if err != nil {
-		return fmt.Errorf("marshaling event: %w", err)
+		return fmt.Errorf("checking for active execution: %w", err)
+	}
+
+	if exists == 1 {
+		return backend.ErrInstanceAlreadyExists
+	}
+
+	segment := instanceSegment(instance)
+
+	registerKeys := []string{
+		rb.keys.instancesActive(),
+		rb.keys.instancesByCreation(),
 	}
+	hasUniqueKey := 0
+	if a.UniqueKey != "" {
+		registerKeys = append(registerKeys, rb.keys.uniqueKey(a.UniqueKey))
+		hasUniqueKey = 1
+	}
+	for _, tag := range a.Tags {
+		registerKeys = append(registerKeys, rb.keys.tagKey(tag))
+	}
+
+	if err := registerWorkflowInstanceCmd.Run(ctx, rb.rdb, registerKeys,
+		segment,
+		rb.options.MaxActiveInstances,
+		rb.options.Clock.Now().UTC().UnixNano(),
+		hasUniqueKey,
+	).Err(); err != nil {
+		if _, ok := err.(redis.Error); ok {
+			switch err.Error() {
+			case "ERR InstanceAlreadyExists":
+				return backend.ErrInstanceAlreadyExists
+			case "ERR MaxActiveInstances":
+				return backend.ErrMaxActiveInstances
+			}
+		}
 
-	payloadData, err := json.Marshal(event.Attributes)
+		return fmt.Errorf("registering workflow instance: %w", err)
+	}
+
+	payloadArgs, err := rb.eventPayloadArgs(ctx, instance, events)
 	if err != nil {
-		return fmt.Errorf("marshaling event payload: %w", err)
+		return errors.Join(err, rb.unregisterWorkflowInstance(ctx, instance, a.UniqueKey, a.Tags))
+	}
+
+	args := []interface{}{
+		string(instanceState),
+		string(activeInstance),
+		instance.ExecutionID,
+		len(eventsData),
 	}
+	args = append(args, eventsData...)
+	args = append(args, payloadArgs...)
 
-	keyInfo := rb.workflowQueue.Keys(a.Queue)
-	_, err = createWorkflowInstanceCmd.Run(ctx, rb.rdb, []string{
+	if err := createWorkflowInstanceCmd.Run(ctx, rb.rdb, []string{
 		rb.keys.instanceKey(instance),
 		rb.keys.activeInstanceExecutionKey(instance.InstanceID),
+		rb.keys.latestInstanceExecutionKey(instance.InstanceID),
 		rb.keys.pendingEventsKey(instance),
 		rb.keys.payloadKey(instance),
-		rb.keys.instancesActive(),
-		rb.keys.instancesByCreation(),
-		keyInfo.SetKey,
-		keyInfo.StreamKey,
-		rb.workflowQueue.queueSetKey,
-	},
-		instanceSegment(instance),
-		string(instanceState),
-		string(activeInstance),
-		event.ID,
-		eventData,
-		payloadData,
-		time.Now().UTC().UnixNano(),
-	).Result()
+	}, args...).Err(); err != nil {
+		if unregisterErr := rb.unregisterWorkflowInstance(ctx, instance, a.UniqueKey, a.Tags); unregisterErr != nil {
+			return unregisterErr
+		}
 
-	if err != nil {
-		if _, ok := err.(redis.Error); ok {
-			if err.Error() == "ERR InstanceAlreadyExists" {
-				return backend.ErrInstanceAlreadyExists
+		if _, ok := err.(redis.Error); ok && err.Error() == "ERR InstanceAlreadyExists" {
+			// Payloads kept outside of redis were stored for the new execution, clean them up again unless they
+			// belong to an existing execution with the same ID
+			if !rb.storesPayloadsInline() {
+				exists, err := rb.rdb.Exists(ctx, rb.keys.instanceKey(instance)).Result()
+				if err != nil {
+					return fmt.Errorf("checking for existing execution: %w", err)
+				}
+
+				if exists == 0 {
+					if err := rb.options.PayloadStore.Delete(ctx, instance); err != nil {
+						return fmt.Errorf("removing payloads: %w", err)
+					}
+				}
 			}
+
+			return backend.ErrInstanceAlreadyExists
 		}
 
 		return fmt.Errorf("creating workflow instance: %w", err)
 	}
 
+	// Queue workflow task, in the stream for the priority of the instance
+	p := rb.rdb.Pipeline()
+	if err := rb.workflowQueue.Enqueue(ctx, p, a.Queue, a.Priority, segment, nil); err != nil {
+		return fmt.Errorf("queueing workflow: %w", err)
+	}
+
+	if _, err := p.Exec(ctx); err != nil {
+		return fmt.Errorf("queueing workflow: %w", err)
+	}
+
 	return nil
 }
 
-func (rb *redisBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64) ([]*history.Event, error) {
+// unregisterWorkflowInstance removes an instance that could not be created from the shared indexes
+func (rb *redisBackend) unregisterWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, uniqueKey string, tags []string) error {
+	segment := instanceSegment(instance)
+
+	p := rb.rdb.TxPipeline()
+	p.SRem(ctx, rb.keys.instancesActive(), segment)
+	p.ZRem(ctx, rb.keys.instancesByCreation(), segment)
+	for _, tag := range tags {
+		p.ZRem(ctx, rb.keys.tagKey(tag), segment)
+	}
+	if uniqueKey != "" {
+		releaseUniqueKeyCmd.Run(ctx, p, []string{rb.keys.uniqueKey(uniqueKey)}, segment)
+	}
+
+	if _, err := p.Exec(ctx); err != nil {
+		return fmt.Errorf("unregistering workflow instance: %w", err)
+	}
+
+	return nil
+}
+
+func (rb *redisBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64, options ...backend.HistoryOption) ([]*history.Event, error) {
+	ho := backend.DefaultHistoryOptions
+	for _, opt := range options {
+		opt(&ho)
+	}
+
 	start := "-"
 
 	if lastSequenceID != nil {
@@ -89,28 +191,36 @@ func (rb *redisBackend) GetWorkflowInstanceHistory(ctx context.Context, instance
 		return nil, err
 	}
 
-	payloadKeys := make([]string, 0, len(msgs))
 	var events []*history.Event
+	payloadEvents := make([]*history.Event, 0, len(msgs))
 	for _, msg := range msgs {
-		var event *history.Event
-		if err := json.Unmarshal([]byte(msg.Values["event"].(string)), &event); err != nil {
+		event, scheduledActivity, err := unmarshalHistoryEvent(msg.Values["event"].(string))
+		if err != nil {
 			return nil, fmt.Errorf("unmarshaling event: %w", err)
 		}
 
-		payloadKeys = append(payloadKeys, event.ID)
 		events = append(events, event)
+
+		// Attributes of scheduled activities are stored with the event, only their inputs need to be loaded
+		if ho.SkipActivityInputs && scheduledActivity != nil {
+			event.Attributes, err = history.DeserializeAttributesWithoutInputs(event.Type, scheduledActivity)
+			if err != nil {
+				return nil, fmt.Errorf("deserializing attributes for event %v: %w", event.Type, err)
+			}
+
+			continue
+		}
+
+		payloadEvents = append(payloadEvents, event)
 	}
 
-	res, err := rb.rdb.HMGet(ctx, rb.keys.payloadKey(instance), payloadKeys...).Result()
-	if err != nil {
-		return nil, fmt.Errorf("reading payloads: %w", err)
+	deserialize := history.DeserializeAttributes
+	if ho.SkipActivityInputs {
+		deserialize = history.DeserializeAttributesWithoutInputs
 	}
 
-	for i, event := range events {
-		event.Attributes, err = history.DeserializeAttributes(event.Type, []byte(res[i].(string)))
-		if err != nil {
-			return nil, fmt.Errorf("deserializing attributes for event %v: %w", event.Type, err)
-		}
+	if err := rb.loadEventPayloads(ctx, instance, payloadEvents, deserialize); err != nil {
+		return nil, err
 	}
 
 	return events, nil
@@ -122,9 +232,57 @@ func (rb *redisBackend) GetWorkflowInstanceState(ctx context.Context, instance *
 		return core.WorkflowInstanceStateActive, err
 	}
 
+	// The task that finished the instance might not have updated the shared keys yet. Report the instance as active
+	// until it has, the remaining steps are executed by the worker completing the task, or by the worker dequeueing
+	// it again.
+	if instanceState.State == core.WorkflowInstanceStateFinished ||
+		instanceState.State == core.WorkflowInstanceStateContinuedAsNew {
+		pending, err := rb.rdb.Exists(ctx, rb.keys.completionKey(instance)).Result()
+		if err != nil {
+			return core.WorkflowInstanceStateActive, fmt.Errorf("checking pending completion: %w", err)
+		}
+
+		if pending > 0 {
+			return core.WorkflowInstanceStateActive, nil
+		}
+	}
+
 	return instanceState.State, nil
 }
 
+func (rb *redisBackend) GetLatestWorkflowInstance(ctx context.Context, instanceID string) (*core.WorkflowInstance, error) {
+	executionID, err := rb.rdb.Get(ctx, rb.keys.latestInstanceExecutionKey(instanceID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, fmt.Errorf("reading latest instance execution: %w", err)
+	}
+
+	// The latest execution might have expired already, make sure it still exists
+	instanceState, err := readInstance(ctx, rb.rdb, rb.keys.instanceKey(core.NewWorkflowInstance(instanceID, executionID)))
+	if err != nil {
+		return nil, err
+	}
+
+	return instanceState.Instance, nil
+}
+
+func (rb *redisBackend) GetLatestWorkflowInstanceState(ctx context.Context, instanceID string) (core.WorkflowInstanceState, error) {
+	executionID, err := rb.rdb.Get(ctx, rb.keys.latestInstanceExecutionKey(instanceID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return core.WorkflowInstanceStateActive, backend.ErrInstanceNotFound
+		}
+
+		return core.WorkflowInstanceStateActive, fmt.Errorf("reading latest instance execution: %w", err)
+	}
+
+	// The latest execution might have expired since, this returns ErrInstanceNotFound then
+	return rb.GetWorkflowInstanceState(ctx, core.NewWorkflowInstance(instanceID, executionID))
+}
+
 func (rb *redisBackend) CancelWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, event *history.Event) error {
 	// Read the instance to check if it exists
 	instanceState, err := readInstance(ctx, rb.rdb, rb.keys.instanceKey(instance))
@@ -133,13 +291,12 @@ func (rb *redisBackend) CancelWorkflowInstance(ctx context.Context, instance *co
 	}
 
 	// Cancel instance
-	if _, err := rb.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
-		return rb.addWorkflowInstanceEventP(ctx, p, workflow.Queue(instanceState.Queue), instance, event)
-	}); err != nil {
-		// fmt.Println(cmds)
+	if err := rb.addWorkflowInstanceEvent(ctx, workflow.Queue(instanceState.Queue), instanceState.Priority, instance, event); err != nil {
 		return fmt.Errorf("adding cancellation event to workflow instance: %w", err)
 	}
 
+	rb.checkPendingEvents(ctx, instance)
+
 	return nil
 }
 
@@ -173,6 +330,18 @@ type instanceState struct {
 	CreatedAt   time.Time  `json:"created_at,omitempty"`
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
 
+	// UniqueKey is the business key held by this instance while it is active
+	UniqueKey string `json:"unique_key,omitempty"`
+
+	// Priority is the dispatch priority of the instance
+	Priority int `json:"priority,omitempty"`
+
+	// Tags are the tags the instance was started with
+	Tags []string `json:"tags,omitempty"`
+
+	// LastActivityAt is the time the last workflow task for this instance was completed
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+
 	LastSequenceID int64 `json:"last_sequence_id,omitempty"`
 }
 
@@ -187,6 +356,30 @@ func readInstance(ctx context.Context, rdb redis.UniversalClient, instanceKey st
 	return readInstancePipelineCmd(cmd)
 }
 
+// getKeys returns the values of the given keys like MGET, nil for keys that do not exist. Keys are read with separate
+// commands, on a cluster they can be stored in different slots.
+func getKeys(ctx context.Context, rdb redis.UniversalClient, keys []string) ([]interface{}, error) {
+	p := rdb.Pipeline()
+
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = p.Get(ctx, key)
+	}
+
+	if _, err := p.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, cmd := range cmds {
+		if val, err := cmd.Result(); err == nil {
+			values[i] = val
+		}
+	}
+
+	return values, nil
+}
+
 func readInstanceP(ctx context.Context, p redis.Pipeliner, instanceKey string) *redis.StringCmd {
 	return p.Get(ctx, instanceKey)
 }
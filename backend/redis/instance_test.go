@@ -0,0 +1,159 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MaxActiveInstances(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	redisClient := getClient()
+	setup := getCreateBackend(redisClient, WithMaxActiveInstances(2))
+	b := setup()
+
+	c := client.New(b)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wf := func(ctx workflow.Context) error {
+		return nil
+	}
+
+	create := func() (*workflow.Instance, error) {
+		return c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+			InstanceID: uuid.NewString(),
+		}, wf)
+	}
+
+	// Instances stay active until a worker has run them
+	wfi1, err := create()
+	require.NoError(t, err)
+	wfi2, err := create()
+	require.NoError(t, err)
+
+	rejected, err := create()
+	require.ErrorIs(t, err, backend.ErrMaxActiveInstances)
+	require.Nil(t, rejected)
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.Start(ctx))
+
+	require.NoError(t, c.WaitForWorkflowInstance(ctx, wfi1, time.Second*10))
+	require.NoError(t, c.WaitForWorkflowInstance(ctx, wfi2, time.Second*10))
+
+	// Finished instances no longer count towards the limit
+	_, err = create()
+	require.NoError(t, err)
+
+	cancel()
+	require.NoError(t, w.WaitForCompletion())
+}
+
+func Test_GetWorkflowInstanceHistory_SkipsActivityInputPayloads(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+
+	redisClient := getClient()
+	setup := getCreateBackend(redisClient)
+	b := setup()
+	defer b.Close()
+
+	startedEvent := history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+		Queue: workflow.QueueDefault,
+	})
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, wfi, startedEvent))
+
+	queues := []workflow.Queue{workflow.QueueDefault}
+	require.NoError(t, b.PrepareWorkflowQueues(ctx, queues))
+	require.NoError(t, b.PrepareActivityQueues(ctx, queues))
+
+	task, err := b.GetWorkflowTask(ctx, queues)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	activityScheduledEvent := history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+		Name:   "activity",
+		Inputs: []payload.Payload{[]byte(`"large input"`)},
+	}, history.ScheduleEventID(1))
+	activityScheduledEvent.SequenceID = 1
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, task, core.WorkflowInstanceStateActive,
+		[]*history.Event{activityScheduledEvent}, []*history.Event{activityScheduledEvent}, nil, nil))
+
+	// Replaying the workflow does not read the payload of the scheduled activity
+	require.NoError(t, redisClient.HDel(ctx, b.(*redisBackend).keys.payloadKey(wfi), activityScheduledEvent.ID).Err())
+
+	h, err := b.GetWorkflowInstanceHistory(ctx, wfi, nil, backend.HistoryWithoutActivityInputs())
+	require.NoError(t, err)
+	require.Len(t, h, 1)
+	require.Equal(t, "activity", h[0].Attributes.(*history.ActivityScheduledAttributes).Name)
+	require.Empty(t, h[0].Attributes.(*history.ActivityScheduledAttributes).Inputs)
+
+	_, err = b.GetWorkflowInstanceHistory(ctx, wfi, nil)
+	require.ErrorIs(t, err, ErrPayloadNotFound)
+}
+
+func Test_CreateWorkflowInstance_DequeuesHigherPriorityFirst(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+
+	redisClient := getClient()
+	setup := getCreateBackend(redisClient)
+	b := setup()
+	defer b.Close()
+
+	c := client.New(b)
+
+	wf := func(ctx workflow.Context) error {
+		return nil
+	}
+
+	create := func(priority int) *workflow.Instance {
+		wfi, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+			InstanceID: uuid.NewString(),
+			Priority:   priority,
+		}, wf)
+		require.NoError(t, err)
+
+		return wfi
+	}
+
+	low := create(0)
+	high := create(10)
+
+	queues := []workflow.Queue{workflow.QueueDefault}
+	require.NoError(t, b.PrepareWorkflowQueues(ctx, queues))
+
+	// No dispatch policy is configured, the task of the instance with the higher priority is dequeued first
+	task, err := b.GetWorkflowTask(ctx, queues)
+	require.NoError(t, err)
+	require.Equal(t, high.InstanceID, task.WorkflowInstance.InstanceID)
+
+	task, err = b.GetWorkflowTask(ctx, queues)
+	require.NoError(t, err)
+	require.Equal(t, low.InstanceID, task.WorkflowInstance.InstanceID)
+}
@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RedisBackend_KeyPrefixIsolatesBackends(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rdb := getClient()
+	require.NoError(t, rdb.FlushDB(ctx).Err())
+
+	wf := func(ctx workflow.Context, env string) (string, error) {
+		// Timers go through the future events of the backend
+		if _, err := workflow.ScheduleTimer(ctx, time.Millisecond*10).Get(ctx); err != nil {
+			return "", err
+		}
+
+		return env, nil
+	}
+
+	type deployment struct {
+		b *redisBackend
+		c *client.Client
+		w *worker.Worker
+	}
+
+	deployments := map[string]*deployment{}
+	for _, env := range []string{"staging", "prod"} {
+		b, err := NewRedisBackend(rdb, WithKeyPrefix(env), WithBlockTimeout(time.Millisecond*10))
+		require.NoError(t, err)
+
+		w := worker.New(b, nil)
+		require.NoError(t, w.RegisterWorkflow(wf))
+		require.NoError(t, w.Start(ctx))
+
+		deployments[env] = &deployment{b: b, c: client.New(b), w: w}
+	}
+
+	// Both deployments use the same instance ID
+	for env, d := range deployments {
+		instance, err := d.c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+			InstanceID: "instance",
+		}, wf, env)
+		require.NoError(t, err)
+
+		r, err := client.GetWorkflowResult[string](ctx, d.c, instance, time.Second*10)
+		require.NoError(t, err)
+		require.Equal(t, env, r, "workflow has to be executed by the worker of its own deployment")
+	}
+
+	keys, err := rdb.Keys(ctx, "*").Result()
+	require.NoError(t, err)
+	require.NotEmpty(t, keys)
+
+	for _, key := range keys {
+		require.True(t, strings.HasPrefix(key, "staging:") || strings.HasPrefix(key, "prod:"), "key %q is not prefixed", key)
+	}
+
+	// Removing the instance of one deployment does not affect the other one
+	staging := deployments["staging"]
+	instance, err := staging.b.GetLatestWorkflowInstance(ctx, "instance")
+	require.NoError(t, err)
+	require.NoError(t, staging.c.RemoveWorkflowInstance(ctx, instance))
+
+	_, err = staging.c.GetInstanceState(ctx, "instance")
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+	_, err = deployments["prod"].c.GetInstanceState(ctx, "instance")
+	require.NoError(t, err)
+
+	cancel()
+	for _, d := range deployments {
+		require.NoError(t, d.w.WaitForCompletion())
+	}
+}
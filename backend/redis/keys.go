@@ -6,68 +6,205 @@ import (
 	"github.com/cschleiden/go-workflows/core"
 )
 
+// keys builds the names of all keys used by the backend. On a redis cluster, scripts and transactions may only access
+// keys in the same hash slot. With cluster keys, keys of a workflow instance contain its instance ID as hash tag, so
+// that all executions of an instance are stored in the same slot, while different instances are spread across the
+// cluster. Keys that are not specific to an instance, like the task queues and the indexes of all instances, share a
+// hash tag derived from the prefix. Without cluster keys, keys do not contain hash tags.
 type keys struct {
 	// Ensure prefix ends with `:`
 	prefix string
+
+	// shared is the prefix of the keys that are not specific to an instance, it includes their hash tag
+	shared string
+
+	// groupSuffix is the suffix of the keys that are kept per consumer group, see groupKeySuffix
+	groupSuffix string
+
+	// cluster is set if keys contain hash tags, see WithClusterKeys
+	cluster bool
 }
 
-func newKeys(prefix string) *keys {
+func newKeys(prefix string, groupName string, cluster bool) *keys {
 	if prefix != "" && prefix[len(prefix)-1] != ':' {
 		prefix += ":"
 	}
 
-	return &keys{prefix: prefix}
+	shared := prefix
+	if cluster {
+		shared = sharedKeyPrefix(prefix)
+	}
+
+	return &keys{
+		prefix:      prefix,
+		shared:      shared,
+		groupSuffix: groupKeySuffix(groupName),
+		cluster:     cluster,
+	}
+}
+
+// sharedKeyPrefix returns the prefix of the keys with the given prefix that are not specific to an instance. It
+// includes the prefix in the hash tag, so that the shared keys of backends with different prefixes are not all stored
+// in the same slot.
+func sharedKeyPrefix(prefix string) string {
+	return fmt.Sprintf("%s{%sshared}:", prefix, prefix)
+}
+
+// hashTag returns the given value as hash tag when using cluster keys, and the value itself otherwise
+func (k *keys) hashTag(value string) string {
+	if k.cluster {
+		return "{" + value + "}"
+	}
+
+	return value
 }
 
 // activeInstanceExecutionKey returns the key for the latest execution of the given instance
 func (k *keys) activeInstanceExecutionKey(instanceID string) string {
-	return fmt.Sprintf("%sactive-instance-execution:%v", k.prefix, instanceID)
+	return fmt.Sprintf("%sactive-instance-execution:%v", k.prefix, k.hashTag(instanceID))
+}
+
+// uniqueKey returns the key holding the segment of the active instance for the given business key
+func (k *keys) uniqueKey(key string) string {
+	return fmt.Sprintf("%sunique-key:%v", k.shared, key)
+}
+
+// latestInstanceExecutionKey returns the key holding the execution ID of the most recently started execution of the
+// given instance. Unlike the active execution key, it is kept after the execution has finished.
+func (k *keys) latestInstanceExecutionKey(instanceID string) string {
+	return fmt.Sprintf("%slatest-instance-execution:%v", k.prefix, k.hashTag(instanceID))
 }
 
 func instanceSegment(instance *core.WorkflowInstance) string {
 	return fmt.Sprintf("%v:%v", instance.InstanceID, instance.ExecutionID)
 }
 
+// instanceKeyName returns the key with the given name for the given execution
+func (k *keys) instanceKeyName(name string, instance *core.WorkflowInstance) string {
+	return fmt.Sprintf("%s%s:%v:%v", k.prefix, name, k.hashTag(instance.InstanceID), instance.ExecutionID)
+}
+
 func (k *keys) instanceKey(instance *core.WorkflowInstance) string {
-	return k.instanceKeyFromSegment(instanceSegment(instance))
+	return k.instanceKeyName("instance", instance)
 }
 
 func (k *keys) instanceKeyFromSegment(segment string) string {
-	return fmt.Sprintf("%sinstance:%v", k.prefix, segment)
+	return k.instanceKey(instanceFromSegment(segment))
 }
 
 // instancesByCreation returns the key for the ZSET that contains all instances sorted by creation date. The score is the
 // creation time as a unix timestamp. Used for listing all workflow instances in the diagnostics UI.
 func (k *keys) instancesByCreation() string {
-	return fmt.Sprintf("%sinstances-by-creation", k.prefix)
+	return fmt.Sprintf("%sinstances-by-creation", k.shared)
 }
 
 // instancesActive returns the key for the SET that contains all active instances. Used for reporting active workflow
 // instances in stats.
 func (k *keys) instancesActive() string {
-	return fmt.Sprintf("%sinstances-active", k.prefix)
+	return fmt.Sprintf("%sinstances-active", k.shared)
+}
+
+// instanceUpdatesChannel returns the pub/sub channel updates for any execution of the given instance are published to
+func (k *keys) instanceUpdatesChannel(instanceID string) string {
+	return fmt.Sprintf("%sinstance-updates:%v", k.prefix, instanceID)
+}
+
+// instanceLogsKey returns the key for the stream of log lines of all executions of the given instance
+func (k *keys) instanceLogsKey(instanceID string) string {
+	return fmt.Sprintf("%sinstance-logs:%v", k.prefix, k.hashTag(instanceID))
 }
 
 func (k *keys) instancesExpiring() string {
-	return fmt.Sprintf("%sinstances-expiring", k.prefix)
+	return fmt.Sprintf("%sinstances-expiring", k.shared)
 }
 
 func (k *keys) pendingEventsKey(instance *core.WorkflowInstance) string {
-	return fmt.Sprintf("%spending-events:%v", k.prefix, instanceSegment(instance))
+	return k.instanceKeyName("pending-events", instance)
 }
 
 func (k *keys) historyKey(instance *core.WorkflowInstance) string {
-	return fmt.Sprintf("%shistory:%v", k.prefix, instanceSegment(instance))
+	return k.instanceKeyName("history", instance)
 }
 
+// futureEventsKey returns the key for the ZSET that contains the timers of all instances of the consumer group, scored
+// by the time they fire. Members are the segment of the instance followed by the schedule event ID of the timer, see
+// futureEventMember.
 func (k *keys) futureEventsKey() string {
-	return fmt.Sprintf("%sfuture-events", k.prefix)
+	return fmt.Sprintf("%sfuture-events%s", k.shared, k.groupSuffix)
 }
 
+// futureEventKey returns the key for the HASH that contains the event of the given timer until it fires
 func (k *keys) futureEventKey(instance *core.WorkflowInstance, scheduleEventID int64) string {
-	return fmt.Sprintf("%sfuture-event:%v:%v", k.prefix, instanceSegment(instance), scheduleEventID)
+	return fmt.Sprintf("%s:%v", k.instanceKeyName("future-event", instance), scheduleEventID)
 }
 
 func (k *keys) payloadKey(instance *core.WorkflowInstance) string {
-	return fmt.Sprintf("%spayload:%v", k.prefix, instanceSegment(instance))
+	return k.instanceKeyName("payload", instance)
+}
+
+// tagKey returns the key for the ZSET that contains all instances started with the given tag. The score is the
+// creation time of the instance.
+func (k *keys) tagKey(tag string) string {
+	return fmt.Sprintf("%stag:%v", k.shared, tag)
+}
+
+// childrenKey returns the key for the ZSET that contains the IDs of all sub-workflow instances started by the given
+// execution. The score is the order in which they were started.
+func (k *keys) childrenKey(instance *core.WorkflowInstance) string {
+	return k.instanceKeyName("children", instance)
+}
+
+// progressKey returns the key for the latest progress reported by the given execution
+func (k *keys) progressKey(instance *core.WorkflowInstance) string {
+	return k.instanceKeyName("progress", instance)
+}
+
+// usageKey returns the key for the HASH that contains the resources used by the given execution
+func (k *keys) usageKey(instance *core.WorkflowInstance) string {
+	return k.instanceKeyName("usage", instance)
+}
+
+// scheduledActivitiesKey returns the key for the SET that contains the schedule event IDs of all activities enqueued
+// for the given execution. Used to enqueue every activity only once.
+func (k *keys) scheduledActivitiesKey(instance *core.WorkflowInstance) string {
+	return k.instanceKeyName("scheduled-activities", instance)
+}
+
+// completionKey returns the key holding the steps of the last workflow task completion of the given execution that
+// have not been executed yet, see pendingCompletion
+func (k *keys) completionKey(instance *core.WorkflowInstance) string {
+	return k.instanceKeyName("completion", instance)
+}
+
+// resetKey returns the key holding the steps of the last reset of the given execution that have not been executed yet,
+// see pendingReset
+func (k *keys) resetKey(instance *core.WorkflowInstance) string {
+	return k.instanceKeyName("reset", instance)
+}
+
+// deliveriesKey returns the key for the HASH that contains, for every instance that has sent events to the given
+// execution, the ID of the completion that delivered them last, and the IDs of the activity tasks whose results have
+// been delivered. Used to deliver events only once.
+func (k *keys) deliveriesKey(instance *core.WorkflowInstance) string {
+	return k.instanceKeyName("deliveries", instance)
+}
+
+// blobKey returns the key for the LIST that contains the chunks of the given blob, after a header element
+func (k *keys) blobKey(key string) string {
+	return fmt.Sprintf("%sblob:%v", k.prefix, k.hashTag(key))
+}
+
+// blobUploadKey returns the key the given blob is written to before it is moved to its final key. It's in the same slot
+// as the blob key.
+func (k *keys) blobUploadKey(key string, upload string) string {
+	return fmt.Sprintf("%sblob-upload:%v:%v", k.prefix, k.hashTag(key), upload)
+}
+
+func (k *keys) activityResultKey(key string) string {
+	return fmt.Sprintf("%sactivity-result:%v", k.prefix, key)
+}
+
+// activityDispatchPausedKey returns the key that exists while activity dispatch is paused
+func (k *keys) activityDispatchPausedKey() string {
+	return fmt.Sprintf("%sactivity-dispatch-paused", k.shared)
 }
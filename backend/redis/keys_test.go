@@ -1,24 +1,92 @@
 package redis
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/core"
 	"github.com/stretchr/testify/require"
 )
 
+func Test_keys_HashTags(t *testing.T) {
+	k := newKeys("prefix", defaultGroupName, true)
+	instance := core.NewWorkflowInstance("instance", "execution")
+
+	// All keys of an instance are stored in the slot of its instance ID
+	for _, key := range []string{
+		k.instanceKey(instance),
+		k.pendingEventsKey(instance),
+		k.historyKey(instance),
+		k.payloadKey(instance),
+		k.futureEventKey(instance, 1),
+		k.completionKey(instance),
+		k.deliveriesKey(instance),
+		k.activeInstanceExecutionKey(instance.InstanceID),
+		k.latestInstanceExecutionKey(instance.InstanceID),
+		k.instanceLogsKey(instance.InstanceID),
+	} {
+		require.True(t, strings.HasPrefix(key, "prefix:"), key)
+		require.Contains(t, key, "{instance}", key)
+	}
+
+	// Keys shared by all instances are stored in the same slot
+	for _, key := range []string{
+		k.instancesActive(),
+		k.instancesByCreation(),
+		k.instancesExpiring(),
+		k.futureEventsKey(),
+		k.uniqueKey("key"),
+		k.tagKey("tag"),
+		k.activityDispatchPausedKey(),
+	} {
+		require.True(t, strings.HasPrefix(key, "prefix:{prefix:shared}:"), key)
+	}
+}
+
+func Test_keys_WithoutClusterKeys(t *testing.T) {
+	k := newKeys("prefix", defaultGroupName, false)
+	instance := core.NewWorkflowInstance("instance", "execution")
+
+	require.Equal(t, "prefix:instance:instance:execution", k.instanceKey(instance))
+	require.Equal(t, "prefix:history:instance:execution", k.historyKey(instance))
+	require.Equal(t, "prefix:future-event:instance:execution:1", k.futureEventKey(instance, 1))
+	require.Equal(t, "prefix:active-instance-execution:instance", k.activeInstanceExecutionKey(instance.InstanceID))
+	require.Equal(t, "prefix:instances-by-creation", k.instancesByCreation())
+	require.Equal(t, "prefix:future-events", k.futureEventsKey())
+	require.Equal(t, "prefix:", k.shared)
+}
+
+func Test_EndToEndRedisBackend_ClusterKeys(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	client := getClient()
+	setup := getCreateBackend(client, WithClusterKeys())
+
+	test.EndToEndBackendTest(t, setup, nil)
+}
+
 func Test_newKeys(t *testing.T) {
 	t.Run("WithEmptyPrefix", func(t *testing.T) {
-		k := newKeys("")
+		k := newKeys("", defaultGroupName, false)
 		require.Equal(t, "", k.prefix)
 	})
 
 	t.Run("WithNonEmptyPrefixWithoutColon", func(t *testing.T) {
-		k := newKeys("prefix")
+		k := newKeys("prefix", defaultGroupName, false)
 		require.Equal(t, "prefix:", k.prefix)
 	})
 
 	t.Run("WithNonEmptyPrefixWithColon", func(t *testing.T) {
-		k := newKeys("prefix:")
+		k := newKeys("prefix:", defaultGroupName, false)
 		require.Equal(t, "prefix:", k.prefix)
 	})
+
+	t.Run("WithConsumerGroup", func(t *testing.T) {
+		k := newKeys("prefix", "canary", true)
+		require.Equal(t, "prefix:{prefix:shared}:future-events:canary", k.futureEventsKey())
+		require.Equal(t, "prefix:{prefix:shared}:future-events", newKeys("prefix", defaultGroupName, true).futureEventsKey())
+	})
 }
@@ -0,0 +1,347 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/redis/go-redis/v9"
+)
+
+type instanceLogEntry struct {
+	Time       time.Time         `json:"time"`
+	Level      slog.Level        `json:"level"`
+	Message    string            `json:"msg"`
+	Attributes map[string]string `json:"attrs,omitempty"`
+}
+
+// instanceLogHandler passes log records on to the wrapped handler, and additionally publishes records of workflow
+// instances to the log stream of the instance.
+type instanceLogHandler struct {
+	handler slog.Handler
+
+	publisher *instanceLogPublisher
+
+	instanceID string
+	attrs      map[string]string
+	group      string
+}
+
+func newInstanceLogHandler(handler slog.Handler, publisher *instanceLogPublisher) *instanceLogHandler {
+	return &instanceLogHandler{
+		handler:   handler,
+		publisher: publisher,
+		attrs:     map[string]string{},
+	}
+}
+
+// Enabled implements slog.Handler.
+func (h *instanceLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *instanceLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	err := h.handler.Handle(ctx, r)
+
+	instanceID := h.instanceID
+	attrs := maps.Clone(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		if h.group == "" && a.Key == log.InstanceIDKey {
+			instanceID = a.Value.String()
+		}
+
+		addAttr(attrs, h.group, a)
+		return true
+	})
+
+	if instanceID != "" {
+		h.publisher.publish(instanceID, &instanceLogEntry{
+			Time:       r.Time,
+			Level:      r.Level,
+			Message:    r.Message,
+			Attributes: attrs,
+		})
+	}
+
+	return err
+}
+
+// WithAttrs implements slog.Handler.
+func (h *instanceLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	nh := *h
+	nh.handler = h.handler.WithAttrs(attrs)
+	nh.attrs = maps.Clone(h.attrs)
+
+	for _, a := range attrs {
+		if h.group == "" && a.Key == log.InstanceIDKey {
+			nh.instanceID = a.Value.String()
+		}
+
+		addAttr(nh.attrs, h.group, a)
+	}
+
+	return &nh
+}
+
+// WithGroup implements slog.Handler.
+func (h *instanceLogHandler) WithGroup(name string) slog.Handler {
+	nh := *h
+	nh.handler = h.handler.WithGroup(name)
+	nh.group = groupKey(h.group, name)
+
+	return &nh
+}
+
+var _ slog.Handler = (*instanceLogHandler)(nil)
+
+const (
+	// instanceLogBufferSize is the number of log lines waiting to be published, before further lines are dropped
+	instanceLogBufferSize = 1024
+
+	// instanceLogBatchSize is the maximum number of log lines published in a single round-trip
+	instanceLogBatchSize = 100
+
+	// instanceLogWriteTimeout bounds the time a batch of log lines can take to be published
+	instanceLogWriteTimeout = 5 * time.Second
+)
+
+type pendingInstanceLogEntry struct {
+	instanceID string
+	data       []byte
+}
+
+// instanceLogPublisher publishes log lines to the log streams of their instances in the background, so that logging
+// never waits for redis. Lines are dropped when redis cannot keep up.
+type instanceLogPublisher struct {
+	rdb    redis.UniversalClient
+	keys   *keys
+	maxLen int64
+	ttl    time.Duration
+
+	entries chan *pendingInstanceLogEntry
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newInstanceLogPublisher(rdb redis.UniversalClient, keys *keys, maxLen int64, ttl time.Duration) *instanceLogPublisher {
+	p := &instanceLogPublisher{
+		rdb:     rdb,
+		keys:    keys,
+		maxLen:  maxLen,
+		ttl:     ttl,
+		entries: make(chan *pendingInstanceLogEntry, instanceLogBufferSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go p.run()
+
+	return p
+}
+
+// publish queues the entry for the log stream of the instance. Errors are dropped, logging them would publish
+// another entry.
+func (p *instanceLogPublisher) publish(instanceID string, entry *instanceLogEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	select {
+	case p.entries <- &pendingInstanceLogEntry{instanceID: instanceID, data: data}:
+	default:
+		// Buffer is full, drop the entry
+	}
+}
+
+// close publishes the entries that are still buffered and stops the publisher.
+func (p *instanceLogPublisher) close() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+
+	<-p.done
+}
+
+func (p *instanceLogPublisher) run() {
+	defer close(p.done)
+
+	for {
+		select {
+		case entry := <-p.entries:
+			p.write(p.batch(entry))
+
+		case <-p.stop:
+			for {
+				select {
+				case entry := <-p.entries:
+					p.write(p.batch(entry))
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// batch adds entries that are already waiting to the given one
+func (p *instanceLogPublisher) batch(entry *pendingInstanceLogEntry) []*pendingInstanceLogEntry {
+	batch := []*pendingInstanceLogEntry{entry}
+
+	for len(batch) < instanceLogBatchSize {
+		select {
+		case entry := <-p.entries:
+			batch = append(batch, entry)
+		default:
+			return batch
+		}
+	}
+
+	return batch
+}
+
+func (p *instanceLogPublisher) write(batch []*pendingInstanceLogEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), instanceLogWriteTimeout)
+	defer cancel()
+
+	pipe := p.rdb.Pipeline()
+	for _, entry := range batch {
+		key := p.keys.instanceLogsKey(entry.instanceID)
+
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: key,
+			MaxLen: p.maxLen,
+			Approx: true,
+			Values: map[string]interface{}{
+				"entry": string(entry.data),
+			},
+		})
+
+		if p.ttl > 0 {
+			pipe.Expire(ctx, key, p.ttl)
+		}
+	}
+
+	_, _ = pipe.Exec(ctx)
+}
+
+func addAttr(attrs map[string]string, group string, a slog.Attr) {
+	v := a.Value.Resolve()
+
+	if v.Kind() == slog.KindGroup {
+		for _, ga := range v.Group() {
+			addAttr(attrs, groupKey(group, a.Key), ga)
+		}
+
+		return
+	}
+
+	attrs[groupKey(group, a.Key)] = v.String()
+}
+
+func groupKey(group, key string) string {
+	if group == "" {
+		return key
+	}
+
+	if key == "" {
+		return group
+	}
+
+	return group + "." + key
+}
+
+func (rb *redisBackend) TailWorkflowInstanceLogs(ctx context.Context, instanceID string) (<-chan *backend.InstanceLogEntry, error) {
+	if rb.options.InstanceLogsMaxLen <= 0 {
+		return nil, backend.ErrNotSupported{
+			Message: "tailing workflow instance logs, instance logs are not published",
+		}
+	}
+
+	key := rb.keys.instanceLogsKey(instanceID)
+
+	c := make(chan *backend.InstanceLogEntry, 10)
+
+	go func() {
+		defer close(c)
+
+		// Start with the log lines that are still kept, then follow new ones
+		lastID := "0"
+
+		for {
+			streams, err := rb.rdb.XRead(ctx, &redis.XReadArgs{
+				Streams: []string{key, lastID},
+				Count:   100,
+				Block:   rb.options.BlockTimeout,
+			}).Result()
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				if errors.Is(err, redis.Nil) {
+					continue
+				}
+
+				rb.options.Logger.Error("reading instance logs", log.InstanceIDKey, instanceID, log.ErrorKey, err)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(rb.options.BlockTimeout):
+				}
+
+				continue
+			}
+
+			for _, stream := range streams {
+				for _, msg := range stream.Messages {
+					lastID = msg.ID
+
+					entry, err := decodeInstanceLogEntry(msg)
+					if err != nil {
+						rb.options.Logger.Error("decoding instance log entry", log.InstanceIDKey, instanceID, log.ErrorKey, err)
+						continue
+					}
+
+					select {
+					case <-ctx.Done():
+						return
+					case c <- entry:
+					}
+				}
+			}
+		}
+	}()
+
+	return c, nil
+}
+
+func decodeInstanceLogEntry(msg redis.XMessage) (*backend.InstanceLogEntry, error) {
+	data, ok := msg.Values["entry"].(string)
+	if !ok {
+		return nil, fmt.Errorf("log entry %s has no data", msg.ID)
+	}
+
+	var e instanceLogEntry
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return nil, fmt.Errorf("unmarshaling log entry: %w", err)
+	}
+
+	return &backend.InstanceLogEntry{
+		Time:       e.Time,
+		Level:      e.Level,
+		Message:    e.Message,
+		Attributes: e.Attributes,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/activity"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_addAttr_FlattensGroups(t *testing.T) {
+	attrs := map[string]string{}
+
+	addAttr(attrs, "", slog.Int("a", 1))
+	addAttr(attrs, "g", slog.String("b", "x"))
+	addAttr(attrs, "", slog.Group("h", slog.Bool("c", true)))
+
+	require.Equal(t, map[string]string{
+		"a":   "1",
+		"g.b": "x",
+		"h.c": "true",
+	}, attrs)
+}
+
+func Test_instanceLogPublisher_DropsEntriesWhenFull(t *testing.T) {
+	p := &instanceLogPublisher{
+		entries: make(chan *pendingInstanceLogEntry, 1),
+	}
+
+	p.publish("instance", &instanceLogEntry{Message: "first"})
+	p.publish("instance", &instanceLogEntry{Message: "second"})
+
+	require.Len(t, p.entries, 1)
+	require.Contains(t, string((<-p.entries).data), "first")
+}
+
+func Test_RedisBackend_TailInstanceLogs(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rdb := getClient()
+	require.NoError(t, rdb.FlushDB(ctx).Err())
+
+	b, err := NewRedisBackend(rdb, WithBlockTimeout(time.Millisecond*10), WithInstanceLogs(100, time.Minute))
+	require.NoError(t, err)
+
+	a := func(ctx context.Context) error {
+		activity.Logger(ctx).Info("from activity")
+		return nil
+	}
+
+	wf := func(ctx workflow.Context) error {
+		workflow.Logger(ctx).Info("from workflow")
+		_, err := workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+		return err
+	}
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.RegisterActivity(a))
+	require.NoError(t, w.Start(ctx))
+
+	c := client.New(b)
+
+	instanceID := uuid.NewString()
+
+	// Lines of other instances are not received
+	b.Options().Logger.Info("other instance", log.InstanceIDKey, uuid.NewString())
+
+	logs, err := c.TailInstanceLogs(ctx, instanceID)
+	require.NoError(t, err)
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: instanceID,
+	}, wf)
+	require.NoError(t, err)
+	require.NoError(t, c.WaitForWorkflowInstance(ctx, instance, time.Second*10))
+
+	messages := map[string]bool{}
+	require.Eventually(t, func() bool {
+		for {
+			select {
+			case e := <-logs:
+				require.Equal(t, instanceID, e.Attributes[log.InstanceIDKey])
+				messages[e.Message] = true
+			default:
+				return messages["from workflow"] && messages["from activity"]
+			}
+		}
+	}, time.Second*10, time.Millisecond*10)
+
+	require.NotContains(t, messages, "other instance")
+}
@@ -14,18 +14,120 @@ type RedisOptions struct {
 	AutoExpiration              time.Duration
 	AutoExpirationContinueAsNew time.Duration
 
+	// RetentionPolicy sets the expiration of finished runs by the outcome they finished with. Outcomes without an
+	// entry use AutoExpiration.
+	RetentionPolicy map[FinalState]time.Duration
+
+	MaxInactivityTTL time.Duration
+
+	// DispatchPolicy decides the order in which ready workflow tasks are dispatched. If not set, tasks are
+	// dispatched in the order they were queued.
+	DispatchPolicy DispatchPolicy
+
+	// DispatchBatchSize is the maximum number of ready workflow tasks the dispatch policy selects from
+	DispatchBatchSize int
+
+	// ActivityVisibilityTimeout is the time after which activity tasks that have not been extended by the worker
+	// holding them become visible to other workers again. If 0, the activity lock timeout is used.
+	ActivityVisibilityTimeout time.Duration
+
+	// WorkStealingThreshold is the time after which activity tasks that have not been extended by the worker
+	// holding them can be claimed by other workers. If 0, tasks can only be claimed once their lock has expired.
+	WorkStealingThreshold time.Duration
+
+	// PendingEventsThreshold is the number of pending events above which a workflow instance is considered to be
+	// falling behind. If 0, pending events are not checked.
+	PendingEventsThreshold int
+
+	// CompletionBatchSize is the maximum number of workflow task completions executed in a single round-trip. If
+	// less than 2, completions are not batched.
+	CompletionBatchSize int
+
+	// CompletionBatchDelay is the maximum time a workflow task completion waits for other completions to batch with
+	CompletionBatchDelay time.Duration
+
+	// MaxActiveInstances is the maximum number of active workflow instances. If 0, the number of instances is not
+	// limited.
+	MaxActiveInstances int64
+
+	// PruneActivityInputs removes the inputs of finished activities from their stored payloads
+	PruneActivityInputs bool
+
 	KeyPrefix string
+
+	// ClusterKeys adds hash tags to all keys, so that the backend can be used with a redis cluster
+	ClusterKeys bool
+
+	// ConsumerGroup is the name of the consumer group workers read workflow and activity tasks with
+	ConsumerGroup string
+
+	// PayloadStore stores the attributes of history events. If not set, payloads are stored in redis.
+	PayloadStore PayloadStore
+
+	// InstanceLogsMaxLen is the approximate number of log lines kept per workflow instance. If 0, log lines are not
+	// published.
+	InstanceLogsMaxLen int64
+
+	// InstanceLogsTTL is the time after the last log line when the log lines of an instance are removed
+	InstanceLogsTTL time.Duration
+
+	// RetryPolicy is used to retry operations that fail with a transient redis error. If nil, errors are returned
+	// right away.
+	RetryPolicy *RetryPolicy
 }
 
 type RedisBackendOption func(*RedisOptions)
 
-// WithKeyPrefix sets the prefix for all keys used in the Redis backend.
+// WithKeyPrefix sets the prefix for all keys used in the Redis backend. Backends with different prefixes can share
+// a redis instance without affecting each other.
 func WithKeyPrefix(prefix string) RedisBackendOption {
 	return func(o *RedisOptions) {
 		o.KeyPrefix = prefix
 	}
 }
 
+// WithClusterKeys names keys for a redis cluster. The keys of a workflow instance contain its instance ID as hash tag,
+// and the keys shared by all instances contain a hash tag derived from the key prefix, so that scripts and
+// transactions only access keys of a single slot. Keys are named differently than without this option: enabling it
+// for an existing deployment requires letting running workflow instances finish and draining the task queues first.
+// Defaults to keys without hash tags.
+func WithClusterKeys() RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.ClusterKeys = true
+	}
+}
+
+// WithConsumerGroup sets the name of the consumer group used to read workflow and activity tasks from the queue
+// streams. Every consumer group has its own task queues and timers: tasks are only executed by workers of the group
+// of the backend that queued them, and the pending tasks of one group are not affected by other groups. Clients and
+// workers working on the same workflow instances need to use the same group.
+// Defaults to "task-workers".
+func WithConsumerGroup(name string) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.ConsumerGroup = name
+	}
+}
+
+// WithInstanceLogs publishes the log lines written by workflows and activities to a stream per workflow instance,
+// which can be followed with client.TailInstanceLogs. Up to approximately maxLen lines are kept per instance, and
+// removed ttl after the last line has been written. Log lines are written to redis in the background, lines are
+// dropped if redis does not keep up.
+func WithInstanceLogs(maxLen int64, ttl time.Duration) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.InstanceLogsMaxLen = maxLen
+		o.InstanceLogsTTL = ttl
+	}
+}
+
+// WithBackendRetry retries getting, extending, and completing workflow tasks, and getting and extending activity
+// tasks, when they fail with a transient redis error like a timeout or a cluster topology change. Completing
+// activity tasks is not retried, an attempt that failed might have delivered the result already.
+func WithBackendRetry(policy RetryPolicy) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.RetryPolicy = &policy
+	}
+}
+
 // WithBlockTimeout sets the timeout for blocking operations like dequeuing a workflow or activity task
 func WithBlockTimeout(timeout time.Duration) RedisBackendOption {
 	return func(o *RedisOptions) {
@@ -50,6 +152,114 @@ func WithAutoExpirationContinueAsNew(expireContinuedAsNewRunsAfter time.Duration
 	}
 }
 
+// WithRetentionPolicy sets the duration after which finished runs expire from the data store, depending on the
+// outcome they finished with. For example, failed runs can be kept longer for debugging than successful ones.
+// Outcomes without an entry expire after the duration set with `WithAutoExpiration`, a duration of 0 keeps runs with
+// that outcome until they are removed manually. Runs completed with `ContinueAsNew` are not affected.
+func WithRetentionPolicy(policy map[FinalState]time.Duration) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.RetentionPolicy = policy
+	}
+}
+
+// WithMaxInactivityTTL sets the duration after which workflow instances without any activity expire from the data
+// store. The expiration is reset whenever a workflow task is completed or an event, like a signal, is added to the
+// instance, so only abandoned instances expire. The duration should be longer than the longest timer or activity of
+// any workflow, otherwise active instances might expire while they are waiting.
+// Finished instances expire after the duration set with `WithAutoExpiration`, or after the inactivity TTL if no
+// auto expiration is configured.
+// If set to 0 (default), unfinished instances never expire.
+func WithMaxInactivityTTL(ttl time.Duration) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.MaxInactivityTTL = ttl
+	}
+}
+
+// WithActivityVisibilityTimeout sets the time after which claimed activity tasks that have not been extended by the
+// worker holding them, e.g., because the worker has crashed, are reclaimed by other workers. Workers reclaim such
+// tasks whenever they poll for new activity tasks. This allows recovering from crashed workers sooner than the
+// activity lock timeout, without changing how long workflow tasks are locked. The timeout should be longer than the
+// activity heartbeat interval of the workers, so that tasks being executed are not reclaimed.
+// If set to 0 (default), tasks are reclaimed once the activity lock timeout has expired.
+func WithActivityVisibilityTimeout(timeout time.Duration) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.ActivityVisibilityTimeout = timeout
+	}
+}
+
+// WithWorkStealing allows workers to claim activity tasks that another worker has locked but not extended for the
+// given threshold, e.g., because the worker is busy and the task is still waiting to be executed, or because the
+// worker has crashed. Workers check for such tasks whenever they poll for new activity tasks. The threshold should be
+// shorter than the activity lock timeout and longer than the activity heartbeat interval of the workers, so that
+// tasks being executed are not claimed. Workers that lose a task to another worker fail to extend and complete it.
+func WithWorkStealing(threshold time.Duration) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.WorkStealingThreshold = threshold
+	}
+}
+
+// WithDispatchPolicy sets the policy that decides which of the ready workflow tasks is dispatched next. Workers read
+// up to `batchSize` tasks at once and hand them out in the order chosen by the policy, the tasks are locked by the
+// worker while they wait. If not set (default), tasks are dispatched in the order they were queued.
+func WithDispatchPolicy(policy DispatchPolicy, batchSize int) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.DispatchPolicy = policy
+		o.DispatchBatchSize = batchSize
+	}
+}
+
+// WithPendingEventsThreshold configures a safety valve for instances that receive events, e.g., signals, faster than
+// their workflow tasks process them. Whenever an event is added to an instance with more than `threshold` pending
+// events, the `workflows.workflow.pending_events.exceeded` metric is incremented and a warning is logged. When a
+// dispatch policy is configured, tasks of such instances are dispatched before all other tasks in the batch the
+// policy selects from, starting with the instance with the most pending events.
+func WithPendingEventsThreshold(threshold int) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.PendingEventsThreshold = threshold
+	}
+}
+
+// WithCompletionBatching combines workflow task completions of different workflow instances into fewer round-trips
+// to redis, which increases throughput for workers processing many small workflow tasks concurrently. Completions
+// wait up to `maxDelay` for other completions, and up to `maxBatch` completions are executed together. Every
+// completion is still applied atomically, and completions for the same instance are never batched together.
+// If not set (default), every completion is executed right away.
+func WithCompletionBatching(maxBatch int, maxDelay time.Duration) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.CompletionBatchSize = maxBatch
+		o.CompletionBatchDelay = maxDelay
+	}
+}
+
+// WithMaxActiveInstances limits the number of active workflow instances across all clients and workers sharing the
+// backend. When the limit is reached, CreateWorkflowInstance fails with backend.ErrMaxActiveInstances until other
+// instances have finished. The limit is checked atomically with creating the instance. Sub-workflows and executions
+// continued as new are not subject to the limit.
+func WithMaxActiveInstances(n int64) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.MaxActiveInstances = n
+	}
+}
+
+// WithPayloadStore sets the store used for event payloads. This allows keeping large payloads outside of redis,
+// only events and coordination state are stored in redis then.
+func WithPayloadStore(store PayloadStore) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.PayloadStore = store
+	}
+}
+
+// WithActivityInputPruning removes the inputs of activities from the payload store once they have finished and their
+// result or error has been recorded, which reduces the memory used by workflows passing large inputs to activities.
+// Replaying workflows only requires the recorded results, but the inputs are no longer part of the history returned
+// for diagnostics, and cannot be used to execute finished activities again, e.g., when resetting workflow instances.
+// Custom payload stores have to implement PayloadReplacer to support this.
+func WithActivityInputPruning() RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.PruneActivityInputs = true
+	}
+}
+
 func WithBackendOptions(opts ...backend.BackendOption) RedisBackendOption {
 	return func(o *RedisOptions) {
 		for _, opt := range opts {
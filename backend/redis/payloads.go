@@ -0,0 +1,215 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/redis/go-redis/v9"
+)
+
+// PayloadStore stores the serialized attributes of history events, keyed by workflow instance and event ID. Events
+// themselves are always kept in redis, implementations can store the potentially large payloads elsewhere.
+type PayloadStore interface {
+	// Store persists the given payloads. Payloads never change once written, so existing entries do not need to
+	// be overwritten.
+	Store(ctx context.Context, instance *core.WorkflowInstance, payloads map[string][]byte) error
+
+	// Load returns the payloads for the given event IDs, in the same order. If a payload does not exist, it returns
+	// an error wrapping ErrPayloadNotFound.
+	Load(ctx context.Context, instance *core.WorkflowInstance, eventIDs []string) ([][]byte, error)
+
+	// Delete removes all payloads for the given instance.
+	Delete(ctx context.Context, instance *core.WorkflowInstance) error
+
+	// Expire removes all payloads for the given instance after the given duration.
+	Expire(ctx context.Context, instance *core.WorkflowInstance, expiration time.Duration) error
+}
+
+// ErrPayloadNotFound is returned by payload stores when a payload does not exist
+var ErrPayloadNotFound = errors.New("payload not found")
+
+// KEYS[1 - payload key
+// ARGV[1..n] - payload values
+var addPayloadsCmd = redis.NewScript(`
+	for i = 1, #ARGV, 2 do
+		redis.pcall("HSETNX", KEYS[1], ARGV[i], ARGV[i+1])
+	end
+
+	return 0
+`)
+
+// hashPayloadStore stores payloads in a redis hash per workflow instance
+type hashPayloadStore struct {
+	rdb  redis.UniversalClient
+	keys *keys
+}
+
+var _ PayloadStore = (*hashPayloadStore)(nil)
+var _ PayloadReplacer = (*hashPayloadStore)(nil)
+
+func (s *hashPayloadStore) Store(ctx context.Context, instance *core.WorkflowInstance, payloads map[string][]byte) error {
+	if len(payloads) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(payloads)*2)
+	for eventID, payload := range payloads {
+		args = append(args, eventID, string(payload))
+	}
+
+	return addPayloadsCmd.Run(ctx, s.rdb, []string{s.keys.payloadKey(instance)}, args...).Err()
+}
+
+func (s *hashPayloadStore) Load(ctx context.Context, instance *core.WorkflowInstance, eventIDs []string) ([][]byte, error) {
+	if len(eventIDs) == 0 {
+		return [][]byte{}, nil
+	}
+
+	res, err := s.rdb.HMGet(ctx, s.keys.payloadKey(instance), eventIDs...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	payloads := make([][]byte, len(res))
+	for i, r := range res {
+		payload, ok := r.(string)
+		if !ok {
+			return nil, fmt.Errorf("payload for event %v: %w", eventIDs[i], ErrPayloadNotFound)
+		}
+
+		payloads[i] = []byte(payload)
+	}
+
+	return payloads, nil
+}
+
+func (s *hashPayloadStore) Replace(ctx context.Context, instance *core.WorkflowInstance, payloads map[string][]byte) error {
+	if len(payloads) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(payloads)*2)
+	for eventID, payload := range payloads {
+		args = append(args, eventID, string(payload))
+	}
+
+	return s.rdb.HSet(ctx, s.keys.payloadKey(instance), args...).Err()
+}
+
+func (s *hashPayloadStore) Delete(ctx context.Context, instance *core.WorkflowInstance) error {
+	return s.rdb.Del(ctx, s.keys.payloadKey(instance)).Err()
+}
+
+func (s *hashPayloadStore) Expire(ctx context.Context, instance *core.WorkflowInstance, expiration time.Duration) error {
+	return s.rdb.Expire(ctx, s.keys.payloadKey(instance), expiration).Err()
+}
+
+// storesPayloadsInline returns true if payloads are kept in the per-instance payload hash. Those payloads are
+// written in the same script or transaction as the events referencing them.
+func (rb *redisBackend) storesPayloadsInline() bool {
+	_, ok := rb.options.PayloadStore.(*hashPayloadStore)
+	return ok
+}
+
+func serializeEventPayloads(events []*history.Event) (map[string][]byte, error) {
+	payloads := make(map[string][]byte, len(events))
+	for _, event := range events {
+		payload, err := history.SerializeAttributes(event.Attributes)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling event payload: %w", err)
+		}
+
+		payloads[event.ID] = payload
+	}
+
+	return payloads, nil
+}
+
+// storeEventPayloads stores the attributes of the given events in the payload store. This needs to happen
+// before the events themselves are written.
+func (rb *redisBackend) storeEventPayloads(ctx context.Context, instance *core.WorkflowInstance, events []*history.Event) error {
+	payloads, err := serializeEventPayloads(events)
+	if err != nil {
+		return err
+	}
+
+	if err := rb.options.PayloadStore.Store(ctx, instance, payloads); err != nil {
+		return fmt.Errorf("storing payloads: %w", err)
+	}
+
+	return nil
+}
+
+// storeEventPayloadsP stores the attributes of the given events as part of the given transaction if payloads are
+// kept inline, otherwise they are stored in the payload store right away.
+func (rb *redisBackend) storeEventPayloadsP(ctx context.Context, p redis.Pipeliner, instance *core.WorkflowInstance, events []*history.Event) error {
+	if !rb.storesPayloadsInline() {
+		return rb.storeEventPayloads(ctx, instance, events)
+	}
+
+	payloads, err := serializeEventPayloads(events)
+	if err != nil {
+		return err
+	}
+
+	for eventID, payload := range payloads {
+		p.HSetNX(ctx, rb.keys.payloadKey(instance), eventID, string(payload))
+	}
+
+	return nil
+}
+
+// eventPayloadArgs returns the script arguments for storing the attributes of the given events inline: their count
+// followed by event ID and payload pairs. If payloads are not kept inline, they are stored in the payload store
+// right away and no pairs are returned.
+func (rb *redisBackend) eventPayloadArgs(ctx context.Context, instance *core.WorkflowInstance, events []*history.Event) ([]interface{}, error) {
+	if !rb.storesPayloadsInline() {
+		if err := rb.storeEventPayloads(ctx, instance, events); err != nil {
+			return nil, err
+		}
+
+		return []interface{}{0}, nil
+	}
+
+	payloads, err := serializeEventPayloads(events)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]interface{}, 0, 1+len(payloads)*2)
+	args = append(args, len(payloads))
+	for eventID, payload := range payloads {
+		args = append(args, eventID, string(payload))
+	}
+
+	return args, nil
+}
+
+// loadEventPayloads loads and deserializes the attributes for the given events from the payload store
+func (rb *redisBackend) loadEventPayloads(
+	ctx context.Context, instance *core.WorkflowInstance, events []*history.Event,
+	deserialize func(history.EventType, []byte) (interface{}, error),
+) error {
+	eventIDs := make([]string, 0, len(events))
+	for _, event := range events {
+		eventIDs = append(eventIDs, event.ID)
+	}
+
+	payloads, err := rb.options.PayloadStore.Load(ctx, instance, eventIDs)
+	if err != nil {
+		return fmt.Errorf("reading payloads: %w", err)
+	}
+
+	for i, event := range events {
+		event.Attributes, err = deserialize(event.Type, payloads[i])
+		if err != nil {
+			return fmt.Errorf("deserializing attributes for event %v: %w", event.Type, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryPayloadStore struct {
+	mu       sync.Mutex
+	payloads map[memoryPayloadKey]map[string][]byte
+}
+
+// memoryPayloadKey identifies an execution, the same instance might be passed with or without its parent
+type memoryPayloadKey struct {
+	instanceID  string
+	executionID string
+}
+
+func newMemoryPayloadStore() *memoryPayloadStore {
+	return &memoryPayloadStore{
+		payloads: make(map[memoryPayloadKey]map[string][]byte),
+	}
+}
+
+func payloadKeyOf(instance *core.WorkflowInstance) memoryPayloadKey {
+	return memoryPayloadKey{instance.InstanceID, instance.ExecutionID}
+}
+
+func (s *memoryPayloadStore) Store(_ context.Context, instance *core.WorkflowInstance, payloads map[string][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payloads[payloadKeyOf(instance)]
+	if !ok {
+		p = make(map[string][]byte)
+		s.payloads[payloadKeyOf(instance)] = p
+	}
+
+	for eventID, payload := range payloads {
+		if _, ok := p[eventID]; !ok {
+			p[eventID] = payload
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryPayloadStore) Load(_ context.Context, instance *core.WorkflowInstance, eventIDs []string) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := make([][]byte, 0, len(eventIDs))
+	for _, eventID := range eventIDs {
+		payload, ok := s.payloads[payloadKeyOf(instance)][eventID]
+		if !ok {
+			return nil, fmt.Errorf("payload for event %v: %w", eventID, ErrPayloadNotFound)
+		}
+
+		r = append(r, payload)
+	}
+
+	return r, nil
+}
+
+func (s *memoryPayloadStore) Delete(_ context.Context, instance *core.WorkflowInstance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.payloads, payloadKeyOf(instance))
+
+	return nil
+}
+
+func (s *memoryPayloadStore) Expire(_ context.Context, _ *core.WorkflowInstance, _ time.Duration) error {
+	return nil
+}
+
+func Test_EndToEndRedisBackend_PayloadStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	client := getClient()
+	setup := getCreateBackend(client, WithPayloadStore(newMemoryPayloadStore()))
+
+	test.EndToEndBackendTest(t, setup, nil)
+}
+
+func Test_CompleteWorkflowTask_RemovesPayloadOfCanceledTimer(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+
+	redisClient := getClient()
+	setup := getCreateBackend(redisClient)
+	b := setup()
+	defer b.Close()
+
+	startedEvent := history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+		Queue: workflow.QueueDefault,
+	})
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, wfi, startedEvent))
+
+	queues := []workflow.Queue{workflow.QueueDefault}
+	require.NoError(t, b.PrepareWorkflowQueues(ctx, queues))
+
+	task, err := b.GetWorkflowTask(ctx, queues)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	timerScheduledEvent := history.NewPendingEvent(time.Now(), history.EventType_TimerScheduled, &history.TimerScheduledAttributes{}, history.ScheduleEventID(1))
+	timerScheduledEvent.SequenceID = 2
+	timerFiredEvent := history.NewPendingEvent(time.Now(), history.EventType_TimerFired, &history.TimerFiredAttributes{},
+		history.ScheduleEventID(1), history.VisibleAt(time.Now().Add(time.Hour)))
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, task, core.WorkflowInstanceStateActive,
+		[]*history.Event{timerScheduledEvent}, nil, []*history.Event{timerFiredEvent}, nil))
+
+	payloadKey := b.(*redisBackend).keys.payloadKey(wfi)
+	require.True(t, redisClient.HExists(ctx, payloadKey, timerFiredEvent.ID).Val())
+
+	require.NoError(t, b.SignalWorkflow(ctx, wfi.InstanceID, history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{
+		Name: "signal",
+	})))
+
+	task, err = b.GetWorkflowTask(ctx, queues)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	timerCanceledEvent := history.NewPendingEvent(time.Now(), history.EventType_TimerCanceled, &history.TimerCanceledAttributes{}, history.ScheduleEventID(1))
+	timerCanceledEvent.SequenceID = 3
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, task, core.WorkflowInstanceStateActive,
+		[]*history.Event{timerCanceledEvent}, nil, nil, nil))
+
+	require.False(t, redisClient.HExists(ctx, payloadKey, timerFiredEvent.ID).Val())
+	require.True(t, redisClient.HExists(ctx, payloadKey, timerCanceledEvent.ID).Val())
+}
@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/backend/metrics"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+)
+
+// checkPendingEvents reports instances whose pending events exceed the configured threshold, i.e., instances that
+// receive events faster than their workflow tasks process them
+func (rb *redisBackend) checkPendingEvents(ctx context.Context, instance *core.WorkflowInstance) {
+	if rb.options.PendingEventsThreshold <= 0 {
+		return
+	}
+
+	pending, err := rb.rdb.XLen(ctx, rb.keys.pendingEventsKey(instance)).Result()
+	if err != nil {
+		rb.options.Logger.Error("reading pending events", log.InstanceIDKey, instance.InstanceID, log.ErrorKey, err)
+		return
+	}
+
+	if pending <= int64(rb.options.PendingEventsThreshold) {
+		return
+	}
+
+	rb.Metrics().Counter(metrickeys.WorkflowPendingEventsExceeded, metrics.Tags{}, 1)
+	rb.options.Logger.Warn("workflow instance exceeds pending events threshold",
+		log.InstanceIDKey, instance.InstanceID,
+		log.PendingEventsKey, pending)
+}
+
+// backloggedCandidate returns the index of the candidate with the most pending events above the configured
+// threshold, or -1 if no candidate exceeds it
+func (rb *redisBackend) backloggedCandidate(candidates []DispatchCandidate) int {
+	selected := -1
+	for i, c := range candidates {
+		if c.PendingEvents <= int64(rb.options.PendingEventsThreshold) {
+			continue
+		}
+
+		if selected < 0 || c.PendingEvents > candidates[selected].PendingEvents {
+			selected = i
+		}
+	}
+
+	return selected
+}
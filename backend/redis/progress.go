@@ -0,0 +1,28 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+func (rb *redisBackend) GetWorkflowInstanceProgress(ctx context.Context, instance *workflow.Instance) (payload.Payload, error) {
+	values, err := rb.rdb.MGet(ctx, rb.keys.instanceKey(instance), rb.keys.progressKey(instance)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("getting progress: %w", err)
+	}
+
+	if values[0] == nil {
+		return nil, backend.ErrInstanceNotFound
+	}
+
+	progress, ok := values[1].(string)
+	if !ok {
+		return nil, nil
+	}
+
+	return payload.Payload(progress), nil
+}
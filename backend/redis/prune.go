@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/core"
+)
+
+// PayloadReplacer is implemented by payload stores that allow overwriting stored payloads. It is required for
+// pruning the inputs of finished activities.
+type PayloadReplacer interface {
+	// Replace overwrites the payloads for the given event IDs.
+	Replace(ctx context.Context, instance *core.WorkflowInstance, payloads map[string][]byte) error
+}
+
+// pruneHistoryPageSize is the number of history events read at once when looking for scheduled activities
+const pruneHistoryPageSize = 100
+
+// pruneActivityInputs removes the inputs from the stored payloads of activities that finished with the given events.
+// Inputs of scheduled activities are not needed to replay a workflow, only the recorded results are.
+func (rb *redisBackend) pruneActivityInputs(ctx context.Context, instance *core.WorkflowInstance, executedEvents []*history.Event) error {
+	scheduleEventIDs := make(map[int64]struct{})
+	for _, event := range executedEvents {
+		switch event.Type {
+		case history.EventType_ActivityCompleted, history.EventType_ActivityFailed:
+			scheduleEventIDs[event.ScheduleEventID] = struct{}{}
+		}
+	}
+
+	if len(scheduleEventIDs) == 0 {
+		return nil
+	}
+
+	// Find the scheduled events, starting with the most recent ones
+	eventIDs := make([]string, 0, len(scheduleEventIDs))
+	end := "+"
+	for len(scheduleEventIDs) > 0 {
+		msgs, err := rb.rdb.XRevRangeN(ctx, rb.keys.historyKey(instance), end, "-", pruneHistoryPageSize).Result()
+		if err != nil {
+			return fmt.Errorf("reading history: %w", err)
+		}
+
+		for _, msg := range msgs {
+			var event *history.Event
+			if err := json.Unmarshal([]byte(msg.Values["event"].(string)), &event); err != nil {
+				return fmt.Errorf("unmarshaling event: %w", err)
+			}
+
+			if event.Type != history.EventType_ActivityScheduled {
+				continue
+			}
+
+			if _, ok := scheduleEventIDs[event.ScheduleEventID]; ok {
+				eventIDs = append(eventIDs, event.ID)
+				delete(scheduleEventIDs, event.ScheduleEventID)
+			}
+		}
+
+		if len(msgs) < pruneHistoryPageSize {
+			break
+		}
+
+		end = "(" + msgs[len(msgs)-1].ID
+	}
+
+	payloads, err := rb.options.PayloadStore.Load(ctx, instance, eventIDs)
+	if err != nil {
+		return fmt.Errorf("reading payloads: %w", err)
+	}
+
+	pruned := make(map[string][]byte, len(payloads))
+	for i, payload := range payloads {
+		attributes, err := history.DeserializeAttributesWithoutInputs(history.EventType_ActivityScheduled, payload)
+		if err != nil {
+			return fmt.Errorf("deserializing attributes: %w", err)
+		}
+
+		data, err := history.SerializeAttributes(attributes)
+		if err != nil {
+			return fmt.Errorf("serializing attributes: %w", err)
+		}
+
+		pruned[eventIDs[i]] = data
+	}
+
+	if err := rb.options.PayloadStore.(PayloadReplacer).Replace(ctx, instance, pruned); err != nil {
+		return fmt.Errorf("replacing payloads: %w", err)
+	}
+
+	return nil
+}
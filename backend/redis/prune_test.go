@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ActivityInputPruning(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	redisClient := getClient()
+	setup := getCreateBackend(redisClient, WithActivityInputPruning())
+	b := setup()
+
+	c := client.New(b)
+	w := worker.New(b, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := func(ctx context.Context, input string) (int, error) {
+		return len(input), nil
+	}
+
+	wf := func(ctx workflow.Context) (int, error) {
+		r1, err := workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a, "large input").Get(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		// Finish in a later workflow task, after the inputs have been pruned
+		workflow.Sleep(ctx, time.Millisecond)
+
+		return r1, nil
+	}
+
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.RegisterActivity(a))
+	require.NoError(t, w.Start(ctx))
+
+	wfi, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, wf)
+	require.NoError(t, err)
+
+	r, err := client.GetWorkflowResult[int](ctx, c, wfi, time.Second*10)
+	require.NoError(t, err)
+	require.Equal(t, 11, r)
+
+	h, err := b.GetWorkflowInstanceHistory(ctx, wfi, nil)
+	require.NoError(t, err)
+
+	var scheduled, completed bool
+	for _, event := range h {
+		switch a := event.Attributes.(type) {
+		case *history.ActivityScheduledAttributes:
+			scheduled = true
+			require.NotEmpty(t, a.Name)
+			require.Empty(t, a.Inputs)
+		case *history.ActivityCompletedAttributes:
+			completed = true
+			require.NotEmpty(t, a.Result)
+		}
+	}
+
+	require.True(t, scheduled)
+	require.True(t, completed)
+
+	cancel()
+	require.NoError(t, w.WaitForCompletion())
+}
+
+func Test_ActivityInputPruning_RequiresPayloadReplacer(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	_, err := NewRedisBackend(getClient(), WithPayloadStore(newMemoryPayloadStore()), WithActivityInputPruning())
+	require.Error(t, err)
+}
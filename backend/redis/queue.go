@@ -1,12 +1,17 @@
 package redis
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
@@ -18,14 +23,44 @@ type taskQueue[T any] struct {
 	groupName   string
 	workerName  string
 	queueSetKey string
+
+	// preparedQueues are the queues this worker has prepared for consumption
+	preparedMu     sync.Mutex
+	preparedQueues []workflow.Queue
+
+	// preparedStreams are the priority streams this worker has created its consumer group for
+	preparedStreams map[string]bool
+
+	// selector, if set, picks the next task from a batch of ready tasks. Tasks that are not selected are kept
+	// locked by this worker and considered again in the next call to Dequeue.
+	selector     taskSelector[T]
+	selectBatch  int
+	selectMu     sync.Mutex
+	selectBuffer []*bufferedTask[T]
+}
+
+// defaultGroupName is the consumer group used unless another one is configured. Its keys don't include the group
+// name, they are the same as before consumer groups could be configured.
+const defaultGroupName = "task-workers"
+
+// taskSelector returns the index of the task to dequeue next
+type taskSelector[T any] func(ctx context.Context, rdb redis.UniversalClient, tasks []*TaskItem[T]) (int, error)
+
+type bufferedTask[T any] struct {
+	task      *TaskItem[T]
+	streamKey string
+	consumer  string
+	readAt    time.Time
 }
 
 var (
 	prepareCmd  *redis.Script
 	enqueueCmd  *redis.Script
 	completeCmd *redis.Script
+	extendCmd   *redis.Script
 	recoverCmd  *redis.Script
-	sizeCmd     *redis.Script
+	dequeueCmd  *redis.Script
+	lagCmd      *redis.Script
 )
 
 type TaskItem[T any] struct {
@@ -37,25 +72,34 @@ type TaskItem[T any] struct {
 
 	// Optional data stored with a task, needs to be serializable
 	Data T
+
+	// Priority is the priority of the stream the task was read from
+	Priority int
+
+	streamKey string
 }
 
 type KeyInfo struct {
 	StreamKey string
 	SetKey    string
+
+	// PrioritiesKey is the sorted set of the streams holding tasks with a priority other than the default one
+	PrioritiesKey string
 }
 
-func newTaskQueue[T any](ctx context.Context, rdb redis.UniversalClient, keyPrefix string, tasktype string) (*taskQueue[T], error) {
+func newTaskQueue[T any](ctx context.Context, rdb redis.UniversalClient, keyPrefix string, tasktype string, groupName string) (*taskQueue[T], error) {
 	// Ensure the key prefix ends with a colon
 	if keyPrefix != "" && keyPrefix[len(keyPrefix)-1] != ':' {
 		keyPrefix += ":"
 	}
 
 	tq := &taskQueue[T]{
-		keyPrefix:   keyPrefix,
-		tasktype:    tasktype,
-		groupName:   "task-workers",
-		workerName:  uuid.NewString(),
-		queueSetKey: fmt.Sprintf("%s%s:queues", keyPrefix, tasktype),
+		keyPrefix:       keyPrefix,
+		tasktype:        tasktype,
+		groupName:       groupName,
+		workerName:      uuid.NewString(),
+		queueSetKey:     fmt.Sprintf("%s%s:queues%s", keyPrefix, tasktype, groupKeySuffix(groupName)),
+		preparedStreams: map[string]bool{},
 	}
 
 	// Load all Lua scripts
@@ -63,8 +107,10 @@ func newTaskQueue[T any](ctx context.Context, rdb redis.UniversalClient, keyPref
 		"queue/prepare.lua":  &prepareCmd,
 		"queue/enqueue.lua":  &enqueueCmd,
 		"queue/recover.lua":  &recoverCmd,
+		"queue/dequeue.lua":  &dequeueCmd,
 		"queue/complete.lua": &completeCmd,
-		"queue/size.lua":     &sizeCmd,
+		"queue/extend.lua":   &extendCmd,
+		"queue/lag.lua":      &lagCmd,
 	}
 
 	if err := loadScripts(ctx, rdb, cmdMapping); err != nil {
@@ -74,7 +120,105 @@ func newTaskQueue[T any](ctx context.Context, rdb redis.UniversalClient, keyPref
 	return tq, nil
 }
 
+// Prepare verifies that redis can be reached and ensures the consumer groups for the given queues exist. This
+// needs to happen before any tasks are dequeued, otherwise reading from the streams fails.
 func (q *taskQueue[T]) Prepare(ctx context.Context, rdb redis.UniversalClient, queues []workflow.Queue) error {
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("connecting to redis: %w", err)
+	}
+
+	if err := q.createGroups(ctx, rdb, queues); err != nil {
+		return fmt.Errorf("preparing queues: %w", err)
+	}
+
+	q.preparedMu.Lock()
+	for _, queue := range queues {
+		if !slices.Contains(q.preparedQueues, queue) {
+			q.preparedQueues = append(q.preparedQueues, queue)
+		}
+	}
+	q.preparedMu.Unlock()
+
+	return nil
+}
+
+// releasedConsumer is the consumer tasks released by workers are handed to, until another worker recovers them
+const releasedConsumer = "released"
+
+// releasedIdle is the idle time released tasks are marked with, it exceeds any lock timeout
+const releasedIdle = 365 * 24 * time.Hour
+
+// consumer returns the consumer tasks are read with for the worker the call is made on behalf of, see
+// backend.WithWorkerID.
+func (q *taskQueue[T]) consumer(ctx context.Context) string {
+	if workerID := backend.WorkerID(ctx); workerID != "" {
+		return workerID
+	}
+
+	return q.workerName
+}
+
+// Release hands tasks that are locked by the given consumer but have not been completed to other workers, and removes
+// the consumer from the consumer groups of the queues this worker has prepared. Tasks of other consumers are not
+// affected.
+func (q *taskQueue[T]) Release(ctx context.Context, rdb redis.UniversalClient, consumer string) error {
+	q.selectMu.Lock()
+	q.selectBuffer = slices.DeleteFunc(q.selectBuffer, func(bt *bufferedTask[T]) bool {
+		return bt.consumer == consumer
+	})
+	q.selectMu.Unlock()
+
+	q.preparedMu.Lock()
+	queues := slices.Clone(q.preparedQueues)
+	q.preparedMu.Unlock()
+
+	streams, err := q.streams(ctx, rdb, queues)
+	if err != nil {
+		return err
+	}
+
+	for _, stream := range streams {
+		streamKey := stream.key
+
+		for {
+			pending, err := rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+				Stream:   streamKey,
+				Group:    q.groupName,
+				Start:    "-",
+				End:      "+",
+				Count:    100,
+				Consumer: consumer,
+			}).Result()
+			if err != nil && err != redis.Nil {
+				return fmt.Errorf("reading pending tasks: %w", err)
+			}
+
+			if len(pending) == 0 {
+				break
+			}
+
+			// Mark the tasks as idle for long enough that any worker recovers them with its next dequeue
+			args := []interface{}{"XCLAIM", streamKey, q.groupName, releasedConsumer, 0}
+			for _, p := range pending {
+				args = append(args, p.ID)
+			}
+			args = append(args, "IDLE", releasedIdle.Milliseconds(), "JUSTID")
+
+			if err := rdb.Do(ctx, args...).Err(); err != nil && err != redis.Nil {
+				return fmt.Errorf("releasing pending tasks: %w", err)
+			}
+		}
+
+		if err := rdb.XGroupDelConsumer(ctx, streamKey, q.groupName, consumer).Err(); err != nil {
+			return fmt.Errorf("removing consumer: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// createGroups idempotently creates the consumer group for each queue stream, creating the stream if necessary
+func (q *taskQueue[T]) createGroups(ctx context.Context, rdb redis.UniversalClient, queues []workflow.Queue) error {
 	keys := []string{}
 	for _, queue := range queues {
 		keys = append(keys, q.Keys(queue).StreamKey)
@@ -82,58 +226,252 @@ func (q *taskQueue[T]) Prepare(ctx context.Context, rdb redis.UniversalClient, q
 
 	_, err := prepareCmd.Run(ctx, rdb, keys, q.groupName).Result()
 	if err != nil && err != redis.Nil {
-		return fmt.Errorf("preparing queues: %w", err)
+		return fmt.Errorf("creating consumer groups: %w", err)
 	}
 
 	return nil
 }
 
+// groupKeySuffix returns the suffix of the keys that are kept per consumer group. The keys of the default group don't
+// have a suffix, they are the same as before consumer groups could be configured.
+func groupKeySuffix(groupName string) string {
+	if groupName == defaultGroupName {
+		return ""
+	}
+
+	return ":" + groupName
+}
+
+// Keys returns the keys of the given queue. Every consumer group has its own streams and set of enqueued task IDs,
+// tasks are only read by workers of the group they have been enqueued for.
 func (q *taskQueue[T]) Keys(queue workflow.Queue) KeyInfo {
+	return q.KeysWithPriority(queue, 0)
+}
+
+// KeysWithPriority returns the keys of the given queue for tasks with the given priority. Tasks with the default
+// priority are kept in the stream of the queue, every other priority has its own stream. The set of enqueued task IDs
+// is shared by all priorities.
+func (q *taskQueue[T]) KeysWithPriority(queue workflow.Queue, priority int) KeyInfo {
+	suffix := groupKeySuffix(q.groupName)
+
+	streamKey := fmt.Sprintf("%stask-stream:%s:%s%s", q.keyPrefix, queue, q.tasktype, suffix)
+	if priority != 0 {
+		streamKey = fmt.Sprintf("%s:priority:%d", streamKey, priority)
+	}
+
 	return KeyInfo{
-		StreamKey: fmt.Sprintf("%stask-stream:%s:%s", q.keyPrefix, queue, q.tasktype),
-		SetKey:    fmt.Sprintf("%stask-set:%s:%s", q.keyPrefix, queue, q.tasktype),
+		StreamKey:     streamKey,
+		SetKey:        fmt.Sprintf("%stask-set:%s:%s%s", q.keyPrefix, queue, q.tasktype, suffix),
+		PrioritiesKey: fmt.Sprintf("%stask-priorities:%s:%s%s", q.keyPrefix, queue, q.tasktype, suffix),
+	}
+}
+
+// queueStream is a stream of a queue, holding the tasks with the given priority
+type queueStream struct {
+	key      string
+	priority int
+}
+
+// streams returns the streams of the given queues, ordered by priority from highest to lowest. Streams with the same
+// priority are ordered like the given queues.
+func (q *taskQueue[T]) streams(ctx context.Context, rdb redis.UniversalClient, queues []workflow.Queue) ([]queueStream, error) {
+	p := rdb.Pipeline()
+	cmds := make([]*redis.ZSliceCmd, len(queues))
+	for i, queue := range queues {
+		cmds[i] = p.ZRangeWithScores(ctx, q.Keys(queue).PrioritiesKey, 0, -1)
+	}
+
+	if _, err := p.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("reading queue priorities: %w", err)
+	}
+
+	streams := make([]queueStream, 0, len(queues))
+	for i, queue := range queues {
+		streams = append(streams, queueStream{key: q.Keys(queue).StreamKey})
+
+		for _, z := range cmds[i].Val() {
+			streams = append(streams, queueStream{key: z.Member.(string), priority: int(z.Score)})
+		}
 	}
+
+	slices.SortStableFunc(streams, func(a, b queueStream) int {
+		return cmp.Compare(b.priority, a.priority)
+	})
+
+	return streams, nil
+}
+
+// prepareStreams ensures the consumer group exists for priority streams this worker hasn't read from before. The
+// streams of the queues themselves are prepared in Prepare.
+func (q *taskQueue[T]) prepareStreams(ctx context.Context, rdb redis.UniversalClient, streams []queueStream) error {
+	q.preparedMu.Lock()
+	defer q.preparedMu.Unlock()
+
+	keys := []string{}
+	for _, stream := range streams {
+		if stream.priority != 0 && !q.preparedStreams[stream.key] {
+			keys = append(keys, stream.key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if err := prepareCmd.Run(ctx, rdb, keys, q.groupName).Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("creating consumer groups: %w", err)
+	}
+
+	for _, key := range keys {
+		q.preparedStreams[key] = true
+	}
+
+	return nil
+}
+
+func streamKeysOf(streams []queueStream) []string {
+	keys := make([]string, len(streams))
+	for i, stream := range streams {
+		keys[i] = stream.key
+	}
+
+	return keys
+}
+
+func priorityOf(streams []queueStream, streamKey string) int {
+	for _, stream := range streams {
+		if stream.key == streamKey {
+			return stream.priority
+		}
+	}
+
+	return 0
 }
 
 func (q *taskQueue[T]) Size(ctx context.Context, rdb redis.UniversalClient) (map[workflow.Queue]int64, error) {
-	sizeData, err := sizeCmd.Run(ctx, rdb, []string{q.queueSetKey}).Slice()
+	setKeys, err := rdb.SMembers(ctx, q.queueSetKey).Result()
 	if err != nil {
-		return nil, fmt.Errorf("getting queue size: %w", err)
+		return nil, fmt.Errorf("getting queues: %w", err)
+	}
+
+	queues := make([]workflow.Queue, 0, len(setKeys))
+	p := rdb.Pipeline()
+	cmds := make([]*redis.IntCmd, 0, len(setKeys))
+	for _, setKey := range setKeys {
+		queues = append(queues, q.queueOfSetKey(setKey))
+		cmds = append(cmds, p.SCard(ctx, setKey))
+	}
+
+	if len(cmds) > 0 {
+		if _, err := p.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("getting queue size: %w", err)
+		}
 	}
 
 	res := map[workflow.Queue]int64{}
+	for i, queue := range queues {
+		res[queue] = cmds[i].Val()
+	}
 
-	for i := 0; i < len(sizeData); i += 2 {
-		queueName := sizeData[i].(string)
+	return res, nil
+}
 
-		// Parse queue name from key
-		queueName = strings.TrimPrefix(queueName, q.keyPrefix)
-		queueName = strings.Split(queueName, ":")[1] // queue name is the third part of the key (0-indexed)
+// queueOfSetKey returns the queue of the given task set key
+func (q *taskQueue[T]) queueOfSetKey(setKey string) workflow.Queue {
+	name := strings.TrimPrefix(setKey, q.keyPrefix+"task-set:")
+	return workflow.Queue(strings.TrimSuffix(name, ":"+q.tasktype+groupKeySuffix(q.groupName)))
+}
 
-		queue := workflow.Queue(queueName)
-		size := sizeData[i+1].(int64)
-		res[queue] = size
+// AllKeys returns the keys of all queues tasks have been enqueued to for the consumer group of this queue
+func (q *taskQueue[T]) AllKeys(ctx context.Context, rdb redis.UniversalClient) ([]KeyInfo, error) {
+	queues, err := q.allQueues(ctx, rdb)
+	if err != nil {
+		return nil, err
 	}
 
-	return res, nil
+	keys := make([]KeyInfo, 0, len(queues))
+	for _, queue := range queues {
+		keys = append(keys, q.Keys(queue))
+	}
+
+	return keys, nil
+}
+
+// allQueues returns all queues tasks have been enqueued to for the consumer group of this queue
+func (q *taskQueue[T]) allQueues(ctx context.Context, rdb redis.UniversalClient) ([]workflow.Queue, error) {
+	setKeys, err := rdb.SMembers(ctx, q.queueSetKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("getting queues: %w", err)
+	}
+
+	queues := make([]workflow.Queue, 0, len(setKeys))
+	for _, setKey := range setKeys {
+		if queue := q.queueOfSetKey(setKey); !slices.Contains(queues, queue) {
+			queues = append(queues, queue)
+		}
+	}
+
+	return queues, nil
+}
+
+// Lag returns the number of tasks across all queues that have not been completed by the consumer group of this
+// queue, both tasks that have not been delivered and tasks that are pending.
+func (q *taskQueue[T]) Lag(ctx context.Context, rdb redis.UniversalClient) (int64, error) {
+	queues, err := q.allQueues(ctx, rdb)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(queues) == 0 {
+		return 0, nil
+	}
+
+	streams, err := q.streams(ctx, rdb, queues)
+	if err != nil {
+		return 0, err
+	}
+
+	lag, err := lagCmd.Run(ctx, rdb, streamKeysOf(streams), q.groupName).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("getting queue lag: %w", err)
+	}
+
+	return lag, nil
 }
 
-func (q *taskQueue[T]) Enqueue(ctx context.Context, p redis.Pipeliner, queue workflow.Queue, id string, data *T) error {
+// Enqueue adds a task with the given priority to the queue. Workers dequeue tasks with a higher priority before
+// tasks with a lower one, the default priority is 0.
+func (q *taskQueue[T]) Enqueue(ctx context.Context, p redis.Pipeliner, queue workflow.Queue, priority int, id string, data *T) error {
 	ds, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
-	keys := q.Keys(queue)
-
-	enqueueCmd.Run(ctx, p, []string{q.queueSetKey, keys.SetKey, keys.StreamKey}, q.groupName, id, string(ds))
+	enqueueCmd.Run(ctx, p, q.enqueueKeys(queue, priority), q.groupName, id, string(ds), priority)
 
 	return nil
 }
 
+// enqueueKeys returns the keys a task with the given priority is enqueued with, in the order queue/enqueue.lua expects
+// them
+func (q *taskQueue[T]) enqueueKeys(queue workflow.Queue, priority int) []string {
+	keys := q.KeysWithPriority(queue, priority)
+
+	return []string{q.queueSetKey, keys.SetKey, keys.StreamKey, keys.PrioritiesKey}
+}
+
 func (q *taskQueue[T]) Dequeue(ctx context.Context, rdb redis.UniversalClient, queues []workflow.Queue, lockTimeout, timeout time.Duration) (*TaskItem[T], error) {
+	streams, err := q.streams(ctx, rdb, queues)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.prepareStreams(ctx, rdb, streams); err != nil {
+		return nil, err
+	}
+
 	// Try to recover abandoned tasks
-	task, err := q.recover(ctx, rdb, queues, lockTimeout)
+	task, err := q.recover(ctx, rdb, streams, lockTimeout)
 	if err != nil {
 		return nil, fmt.Errorf("checking for abandoned tasks: %w", err)
 	}
@@ -142,20 +480,32 @@ func (q *taskQueue[T]) Dequeue(ctx context.Context, rdb redis.UniversalClient, q
 		return task, nil
 	}
 
-	// Check for new tasks
-	streamKeys := []string{}
-	streamIds := []string{}
-	for _, queue := range queues {
-		keys := q.Keys(queue)
-		streamKeys = append(streamKeys, keys.StreamKey)
-		streamIds = append(streamIds, ">")
+	if q.selector != nil {
+		return q.dequeueSelected(ctx, rdb, streams, lockTimeout, timeout)
 	}
 
-	// Try to dequeue from all given queues
-	ids, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+	// Check for new tasks, in order of priority
+	task, err = q.scriptTask(dequeueCmd.Run(ctx, rdb, streamKeysOf(streams), q.groupName, q.consumer(ctx)).Slice())
+	if err != nil {
+		return nil, fmt.Errorf("dequeueing task: %w", err)
+	}
+
+	if task != nil {
+		task.Priority = priorityOf(streams, task.streamKey)
+		return task, nil
+	}
+
+	// Wait for new tasks in any of the streams
+	streamKeys := streamKeysOf(streams)
+	streamIds := make([]string, len(streamKeys))
+	for i := range streamIds {
+		streamIds[i] = ">"
+	}
+
+	read, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
 		Streams:  append(streamKeys, streamIds...),
 		Group:    q.groupName,
-		Consumer: q.workerName,
+		Consumer: q.consumer(ctx),
 		Count:    1,
 		Block:    timeout,
 	}).Result()
@@ -163,12 +513,161 @@ func (q *taskQueue[T]) Dequeue(ctx context.Context, rdb redis.UniversalClient, q
 		return nil, fmt.Errorf("dequeueing task: %w", err)
 	}
 
-	if len(ids) == 0 || len(ids[0].Messages) == 0 || err == redis.Nil {
+	// A task is read from every stream that has one. Keep the one with the highest priority and hand the others to
+	// other workers.
+	var selected *redis.XStream
+	for i := range read {
+		if len(read[i].Messages) > 0 && (selected == nil || priorityOf(streams, read[i].Stream) > priorityOf(streams, selected.Stream)) {
+			selected = &read[i]
+		}
+	}
+
+	for i := range read {
+		if len(read[i].Messages) > 0 && &read[i] != selected {
+			if err := q.handBack(ctx, rdb, read[i].Stream, read[i].Messages[0].ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if selected == nil {
+		return nil, nil
+	}
+
+	task, err = msgToTaskItem[T](&selected.Messages[0])
+	if err != nil {
+		return nil, err
+	}
+
+	task.Priority = priorityOf(streams, selected.Stream)
+	return task, nil
+}
+
+// handBack releases a task this worker has read but won't process, so that any worker recovers it with its next
+// dequeue.
+func (q *taskQueue[T]) handBack(ctx context.Context, rdb redis.UniversalClient, streamKey string, taskID string) error {
+	err := rdb.Do(ctx, "XCLAIM", streamKey, q.groupName, releasedConsumer, 0, taskID, "IDLE", releasedIdle.Milliseconds(), "JUSTID").Err()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("releasing task: %w", err)
+	}
+
+	return nil
+}
+
+// setSelector configures the queue to read up to batchSize tasks at once and use the given selector to pick the task
+// to dequeue.
+func (q *taskQueue[T]) setSelector(selector taskSelector[T], batchSize int) {
+	q.selector = selector
+	q.selectBatch = batchSize
+}
+
+func (q *taskQueue[T]) dequeueSelected(ctx context.Context, rdb redis.UniversalClient, streams []queueStream, lockTimeout, timeout time.Duration) (*TaskItem[T], error) {
+	consumer := q.consumer(ctx)
+	streamKeys := streamKeysOf(streams)
+
+	q.selectMu.Lock()
+	// Tasks that have been buffered for too long might be recovered by other workers, don't hand them out anymore.
+	// They will be recovered once the lock timeout expires.
+	buffer := q.selectBuffer[:0]
+	for _, bt := range q.selectBuffer {
+		if time.Since(bt.readAt) < lockTimeout/2 {
+			buffer = append(buffer, bt)
+		}
+	}
+	q.selectBuffer = buffer
+	buffered := len(q.selectBuffer)
+	q.selectMu.Unlock()
+
+	if needed := q.selectBatch - buffered; needed > 0 {
+		// Only block waiting for new tasks if there are no buffered ones
+		block := time.Duration(-1)
+		if buffered == 0 {
+			block = timeout
+		}
+
+		streamIds := make([]string, len(streamKeys))
+		for i := range streamIds {
+			streamIds[i] = ">"
+		}
+
+		readStreams, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Streams:  append(streamKeys, streamIds...),
+			Group:    q.groupName,
+			Consumer: consumer,
+			Count:    int64(needed),
+			Block:    block,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("dequeueing task: %w", err)
+		}
+
+		now := time.Now()
+		read := make([]*bufferedTask[T], 0)
+		for _, stream := range readStreams {
+			for i := range stream.Messages {
+				task, err := msgToTaskItem[T](&stream.Messages[i])
+				if err != nil {
+					return nil, err
+				}
+
+				task.Priority = priorityOf(streams, stream.Stream)
+
+				read = append(read, &bufferedTask[T]{task: task, streamKey: stream.Stream, consumer: consumer, readAt: now})
+			}
+		}
+
+		q.selectMu.Lock()
+		q.selectBuffer = append(q.selectBuffer, read...)
+		q.selectMu.Unlock()
+	}
+
+	q.selectMu.Lock()
+	defer q.selectMu.Unlock()
+
+	// Only consider tasks for the requested queues
+	candidates := make([]*bufferedTask[T], 0, len(q.selectBuffer))
+	for _, bt := range q.selectBuffer {
+		if slices.Contains(streamKeys, bt.streamKey) {
+			candidates = append(candidates, bt)
+		}
+	}
+
+	if len(candidates) == 0 {
 		return nil, nil
 	}
 
-	msg := ids[0].Messages[0]
-	return msgToTaskItem[T](&msg)
+	tasks := make([]*TaskItem[T], len(candidates))
+	for i, bt := range candidates {
+		tasks[i] = bt.task
+	}
+
+	idx := 0
+	if len(tasks) > 1 {
+		var err error
+		idx, err = q.selector(ctx, rdb, tasks)
+		if err != nil {
+			return nil, fmt.Errorf("selecting task: %w", err)
+		}
+	}
+
+	selected := candidates[idx]
+	q.selectBuffer = slices.DeleteFunc(q.selectBuffer, func(bt *bufferedTask[T]) bool {
+		return bt == selected
+	})
+
+	// Reset the idle time of the task, it might have been waiting in the buffer for a while. This also hands tasks
+	// read by another consumer to the one dequeueing it.
+	if _, err := rdb.XClaimJustID(ctx, &redis.XClaimArgs{
+		Stream:   selected.streamKey,
+		Group:    q.groupName,
+		Consumer: consumer,
+		Messages: []string{selected.task.TaskID},
+		MinIdle:  0,
+	}).Result(); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("claiming task: %w", err)
+	}
+
+	return selected.task, nil
 }
 
 func (q *taskQueue[T]) Extend(ctx context.Context, p redis.Pipeliner, queue workflow.Queue, taskID string) error {
@@ -177,7 +676,7 @@ func (q *taskQueue[T]) Extend(ctx context.Context, p redis.Pipeliner, queue work
 	_, err := p.XClaim(ctx, &redis.XClaimArgs{
 		Stream:   q.Keys(queue).StreamKey,
 		Group:    q.groupName,
-		Consumer: q.workerName,
+		Consumer: q.consumer(ctx),
 		Messages: []string{taskID},
 		MinIdle:  0, // Always claim this message
 	}).Result()
@@ -188,10 +687,54 @@ func (q *taskQueue[T]) Extend(ctx context.Context, p redis.Pipeliner, queue work
 	return nil
 }
 
+// errTaskNotOwned is returned when a task is not locked by this worker anymore
+var errTaskNotOwned = errors.New("task is not locked by this worker")
+
+// ExtendOwned extends the lease of the given task with the given priority, like Extend, but only if the task is still
+// locked by this worker. Returns errTaskNotOwned otherwise.
+func (q *taskQueue[T]) ExtendOwned(ctx context.Context, rdb redis.Scripter, queue workflow.Queue, priority int, taskID string) error {
+	err := extendCmd.Run(ctx, rdb, []string{q.KeysWithPriority(queue, priority).StreamKey}, q.groupName, q.consumer(ctx), taskID).Err()
+	if redis.HasErrorPrefix(err, "TaskNotOwned") {
+		return errTaskNotOwned
+	}
+
+	if err != nil {
+		return fmt.Errorf("extending lease: %w", err)
+	}
+
+	return nil
+}
+
+// Owned returns errTaskNotOwned if the given task is not locked by this worker anymore
+func (q *taskQueue[T]) Owned(ctx context.Context, rdb redis.UniversalClient, queue workflow.Queue, taskID string) error {
+	pending, err := rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: q.Keys(queue).StreamKey,
+		Group:  q.groupName,
+		Start:  taskID,
+		End:    taskID,
+		Count:  1,
+	}).Result()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("checking task lock: %w", err)
+	}
+
+	if len(pending) == 0 || pending[0].Consumer != q.consumer(ctx) {
+		return errTaskNotOwned
+	}
+
+	return nil
+}
+
 func (q *taskQueue[T]) Complete(ctx context.Context, p redis.Pipeliner, queue workflow.Queue, taskID string) (*redis.Cmd, error) {
+	return q.CompleteWithPriority(ctx, p, queue, 0, taskID)
+}
+
+// CompleteWithPriority completes a task read from the stream for the given priority, like Complete
+func (q *taskQueue[T]) CompleteWithPriority(ctx context.Context, p redis.Pipeliner, queue workflow.Queue, priority int, taskID string) (*redis.Cmd, error) {
+	keys := q.KeysWithPriority(queue, priority)
 	cmd := completeCmd.Run(ctx, p, []string{
-		q.Keys(queue).SetKey,
-		q.Keys(queue).StreamKey,
+		keys.SetKey,
+		keys.StreamKey,
 	}, taskID, q.groupName)
 	if err := cmd.Err(); err != nil && err != redis.Nil {
 		return nil, fmt.Errorf("completing task: %w", err)
@@ -209,51 +752,61 @@ func (q *taskQueue[T]) Data(ctx context.Context, p redis.Pipeliner, queue workfl
 	return msgToTaskItem[T](&msg[0])
 }
 
-func (q *taskQueue[T]) recover(ctx context.Context, rdb redis.UniversalClient, queues []workflow.Queue, idleTimeout time.Duration) (*TaskItem[T], error) {
-	keys := []string{}
-
-	for _, queue := range queues {
-		keys = append(keys, q.Keys(queue).StreamKey)
-	}
-
-	r, err := recoverCmd.Run(
+func (q *taskQueue[T]) recover(ctx context.Context, rdb redis.UniversalClient, streams []queueStream, idleTimeout time.Duration) (*TaskItem[T], error) {
+	task, err := q.scriptTask(recoverCmd.Run(
 		ctx, rdb,
-		keys,
+		streamKeysOf(streams),
 		q.groupName,
-		q.workerName,
+		q.consumer(ctx),
 		idleTimeout.Milliseconds(),
 		"0",
-	).Slice()
+	).Slice())
+	if err != nil {
+		return nil, fmt.Errorf("recovering abandoned task: %w", err)
+	}
+
+	if task != nil {
+		task.Priority = priorityOf(streams, task.streamKey)
+	}
+
+	return task, nil
+}
+
+// scriptTask parses the stream and the task read from it returned by the dequeue and recover scripts
+func (q *taskQueue[T]) scriptTask(r []interface{}, err error) (*TaskItem[T], error) {
 	if err != nil {
 		if err == redis.Nil {
 			return nil, nil
 		}
 
-		return nil, fmt.Errorf("recovering abandoned task: %w", err)
+		return nil, err
 	}
 
-	if len(r) > 1 {
-		msgs := r[1].([]interface{})
-		if len(msgs) > 0 && msgs[0] != nil {
-			msgData := msgs[0].([]interface{})
+	if len(r) < 2 {
+		return nil, nil
+	}
 
-			id := msgData[0].(string)
-			rawValues := msgData[1].([]interface{})
-			values := make(map[string]interface{})
-			for i := 0; i < len(rawValues); i += 2 {
-				key := rawValues[i].(string)
-				value := rawValues[i+1].(string)
-				values[key] = value
-			}
+	msgData := r[1].([]interface{})
 
-			return msgToTaskItem[T](&redis.XMessage{
-				ID:     id,
-				Values: values,
-			})
-		}
+	id := msgData[0].(string)
+	rawValues := msgData[1].([]interface{})
+	values := make(map[string]interface{})
+	for i := 0; i < len(rawValues); i += 2 {
+		key := rawValues[i].(string)
+		value := rawValues[i+1].(string)
+		values[key] = value
+	}
+
+	task, err := msgToTaskItem[T](&redis.XMessage{
+		ID:     id,
+		Values: values,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, nil
+	task.streamKey = r[0].(string)
+	return task, nil
 }
 
 func msgToTaskItem[T any](msg *redis.XMessage) (*TaskItem[T], error) {
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/core"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/redis/go-redis/v9"
@@ -40,7 +41,7 @@ func Test_TaskQueue(t *testing.T) {
 				ctx := context.Background()
 
 				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
-					return q.Enqueue(ctx, p, workflow.QueueDefault, "t1", nil)
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
 				})
 				require.NoError(t, err)
 
@@ -50,13 +51,63 @@ func Test_TaskQueue(t *testing.T) {
 				require.Equal(t, "t1", task.ID)
 			},
 		},
+		{
+			name: "Dequeue with selector",
+			f: func(t *testing.T, q *taskQueue[any]) {
+				ctx := context.Background()
+
+				// Always pick the last of the ready tasks
+				q.setSelector(func(ctx context.Context, rdb redis.UniversalClient, tasks []*TaskItem[any]) (int, error) {
+					return len(tasks) - 1, nil
+				}, 10)
+
+				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
+					for _, id := range []string{"t1", "t2", "t3"} {
+						if err := q.Enqueue(ctx, p, workflow.QueueDefault, 0, id, nil); err != nil {
+							return err
+						}
+					}
+
+					return nil
+				})
+				require.NoError(t, err)
+
+				for _, expected := range []string{"t3", "t2", "t1"} {
+					task, err := q.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Second, blockTimeout)
+					require.NoError(t, err)
+					require.NotNil(t, task)
+					require.Equal(t, expected, task.ID)
+				}
+
+				task, err := q.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Second, blockTimeout)
+				require.NoError(t, err)
+				require.Nil(t, task)
+			},
+		},
+		{
+			name: "Prepare creates consumer groups idempotently",
+			f: func(t *testing.T, q *taskQueue[any]) {
+				ctx := context.Background()
+
+				queues := []workflow.Queue{core.QueueSystem, workflow.QueueDefault}
+				require.NoError(t, q.Prepare(ctx, client, queues))
+				require.NoError(t, q.Prepare(ctx, client, queues))
+
+				for _, queue := range queues {
+					groups, err := client.XInfoGroups(ctx, q.Keys(queue).StreamKey).Result()
+					require.NoError(t, err)
+					require.Len(t, groups, 1)
+					require.Equal(t, q.groupName, groups[0].Name)
+				}
+			},
+		},
 		{
 			name: "Size",
 			f: func(t *testing.T, q *taskQueue[any]) {
 				ctx := context.Background()
 
 				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
-					return q.Enqueue(ctx, p, workflow.QueueDefault, "t1", nil)
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
 				})
 				require.NoError(t, err)
 
@@ -71,12 +122,12 @@ func Test_TaskQueue(t *testing.T) {
 				ctx := context.Background()
 
 				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
-					return q.Enqueue(ctx, p, workflow.QueueDefault, "t1", nil)
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
 				})
 				require.NoError(t, err)
 
 				_, err = client.Pipelined(ctx, func(p redis.Pipeliner) error {
-					return q.Enqueue(ctx, p, workflow.QueueDefault, "t1", nil)
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
 				})
 				require.NoError(t, err)
 
@@ -91,7 +142,7 @@ func Test_TaskQueue(t *testing.T) {
 				require.NoError(t, err)
 
 				_, err = client.Pipelined(ctx, func(p redis.Pipeliner) error {
-					return q.Enqueue(ctx, p, workflow.QueueDefault, "t1", nil)
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
 				})
 				require.NoError(t, err)
 			},
@@ -106,11 +157,11 @@ func Test_TaskQueue(t *testing.T) {
 
 				ctx := context.Background()
 
-				q, err := newTaskQueue[foo](context.Background(), client, "prefix", taskType)
+				q, err := newTaskQueue[foo](context.Background(), client, "prefix", taskType, "task-workers")
 				require.NoError(t, err)
 
 				_, err = client.Pipelined(ctx, func(p redis.Pipeliner) error {
-					return q.Enqueue(ctx, p, workflow.QueueDefault, "t1", &foo{
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", &foo{
 						Count: 1,
 						Name:  "bar",
 					})
@@ -131,11 +182,11 @@ func Test_TaskQueue(t *testing.T) {
 				ctx := context.Background()
 
 				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
-					return q.Enqueue(ctx, p, workflow.QueueDefault, "t1", nil)
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
 				})
 				require.NoError(t, err)
 
-				q2, _ := newTaskQueue[any](context.Background(), client, "prefix", taskType)
+				q2, _ := newTaskQueue[any](context.Background(), client, "prefix", taskType, "task-workers")
 				require.NoError(t, err)
 
 				// Dequeue using second worker
@@ -145,15 +196,54 @@ func Test_TaskQueue(t *testing.T) {
 				require.Equal(t, "t1", task.ID)
 			},
 		},
+		{
+			name: "Consumer groups have separate queues",
+			f: func(t *testing.T, q *taskQueue[any]) {
+				ctx := context.Background()
+
+				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
+				})
+				require.NoError(t, err)
+
+				q2, err := newTaskQueue[any](ctx, client, "prefix", taskType, "canary")
+				require.NoError(t, err)
+				require.NoError(t, q2.Prepare(ctx, client, []workflow.Queue{workflow.QueueDefault}))
+
+				// Tasks are only read by the group they have been enqueued for
+				task2, err := q2.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, lockTimeout, blockTimeout)
+				require.NoError(t, err)
+				require.Nil(t, task2)
+
+				_, err = client.Pipelined(ctx, func(p redis.Pipeliner) error {
+					return q2.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
+				})
+				require.NoError(t, err)
+
+				task2, err = q2.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, lockTimeout, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task2)
+				require.Equal(t, "t1", task2.ID)
+
+				task, err := q.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, lockTimeout, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, "t1", task.ID)
+
+				pending, err := client.XPending(ctx, q2.Keys(workflow.QueueDefault).StreamKey, "canary").Result()
+				require.NoError(t, err)
+				require.Equal(t, int64(1), pending.Count)
+			},
+		},
 		{
 			name: "Complete removes task",
 			f: func(t *testing.T, q *taskQueue[any]) {
-				q2, _ := newTaskQueue[any](context.Background(), client, "prefix", taskType)
+				q2, _ := newTaskQueue[any](context.Background(), client, "prefix", taskType, "task-workers")
 
 				ctx := context.Background()
 
 				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
-					return q.Enqueue(ctx, p, workflow.QueueDefault, "t1", nil)
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
 				})
 				require.NoError(t, err)
 
@@ -182,18 +272,18 @@ func Test_TaskQueue(t *testing.T) {
 				type taskData struct {
 					Count int `json:"count"`
 				}
-				q, _ := newTaskQueue[taskData](context.Background(), client, "prefix", taskType)
+				q, _ := newTaskQueue[taskData](context.Background(), client, "prefix", taskType, "task-workers")
 
 				ctx := context.Background()
 
 				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
-					return q.Enqueue(ctx, p, workflow.QueueDefault, "t1", &taskData{
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", &taskData{
 						Count: 42,
 					})
 				})
 				require.NoError(t, err)
 
-				q2, _ := newTaskQueue[taskData](context.Background(), client, "prefix", taskType)
+				q2, _ := newTaskQueue[taskData](context.Background(), client, "prefix", taskType, "task-workers")
 				require.NoError(t, err)
 
 				task, err := q2.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, lockTimeout, blockTimeout)
@@ -216,12 +306,12 @@ func Test_TaskQueue(t *testing.T) {
 				ctx := context.Background()
 
 				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
-					return q.Enqueue(ctx, p, workflow.QueueDefault, "t1", nil)
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
 				})
 				require.NoError(t, err)
 
 				// Create second worker (with different name)
-				q2, _ := newTaskQueue[any](context.Background(), client, "prefix", taskType)
+				q2, _ := newTaskQueue[any](context.Background(), client, "prefix", taskType, "task-workers")
 				require.NoError(t, err)
 
 				task, err := q2.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, lockTimeout, blockTimeout)
@@ -242,13 +332,159 @@ func Test_TaskQueue(t *testing.T) {
 				require.Nil(t, recoveredTask)
 			},
 		},
+		{
+			name: "ExtendOwned only extends claimed tasks",
+			f: func(t *testing.T, q *taskQueue[any]) {
+				ctx := context.Background()
+
+				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
+				})
+				require.NoError(t, err)
+
+				task, err := q.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Second, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				require.NoError(t, q.ExtendOwned(ctx, client, workflow.QueueDefault, 0, task.TaskID))
+
+				time.Sleep(time.Millisecond * 10)
+
+				// Second worker steals the task before the first one extends it
+				q2, _ := newTaskQueue[any](ctx, client, "prefix", taskType, "task-workers")
+				stolen, err := q2.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Millisecond*5, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, stolen)
+				require.Equal(t, task.TaskID, stolen.TaskID)
+
+				require.ErrorIs(t, q.ExtendOwned(ctx, client, workflow.QueueDefault, 0, task.TaskID), errTaskNotOwned)
+				require.NoError(t, q2.ExtendOwned(ctx, client, workflow.QueueDefault, 0, task.TaskID))
+			},
+		},
+		{
+			name: "Consumer groups complete tasks independently",
+			f: func(t *testing.T, q *taskQueue[any]) {
+				ctx := context.Background()
+				queues := []workflow.Queue{workflow.QueueDefault}
+
+				q2, err := newTaskQueue[any](ctx, client, "prefix", taskType, "canary")
+				require.NoError(t, err)
+				require.NoError(t, q2.Prepare(ctx, client, queues))
+
+				_, err = client.Pipelined(ctx, func(p redis.Pipeliner) error {
+					if err := q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil); err != nil {
+						return err
+					}
+
+					return q2.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
+				})
+				require.NoError(t, err)
+
+				task, err := q.Dequeue(ctx, client, queues, time.Hour, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				task2, err := q2.Dequeue(ctx, client, queues, time.Hour, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task2)
+
+				_, err = client.Pipelined(ctx, func(p redis.Pipeliner) error {
+					_, err := q.Complete(ctx, p, workflow.QueueDefault, task.TaskID)
+					return err
+				})
+				require.NoError(t, err)
+
+				// The task is still enqueued for the other group
+				size, err := q.Size(ctx, client)
+				require.NoError(t, err)
+				require.Equal(t, int64(0), size[workflow.QueueDefault])
+
+				size, err = q2.Size(ctx, client)
+				require.NoError(t, err)
+				require.Equal(t, int64(1), size[workflow.QueueDefault])
+
+				require.NoError(t, q2.ExtendOwned(ctx, client, workflow.QueueDefault, 0, task2.TaskID))
+
+				lag, err := q.Lag(ctx, client)
+				require.NoError(t, err)
+				require.Equal(t, int64(0), lag)
+
+				lag, err = q2.Lag(ctx, client)
+				require.NoError(t, err)
+				require.Equal(t, int64(1), lag)
+			},
+		},
+		{
+			name: "Release hands pending tasks to other workers",
+			f: func(t *testing.T, q *taskQueue[any]) {
+				ctx := context.Background()
+
+				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
+				})
+				require.NoError(t, err)
+
+				task, err := q.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Hour, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				require.NoError(t, q.Release(ctx, client, q.workerName))
+
+				// Task is recovered right away, even though the lock timeout hasn't expired
+				q2, _ := newTaskQueue[any](ctx, client, "prefix", taskType, "task-workers")
+				recovered, err := q2.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Hour, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, recovered)
+				require.Equal(t, task.TaskID, recovered.TaskID)
+			},
+		},
+		{
+			name: "Release only hands over tasks of the given consumer",
+			f: func(t *testing.T, q *taskQueue[any]) {
+				ctx := context.Background()
+
+				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
+					if err := q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil); err != nil {
+						return err
+					}
+
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t2", nil)
+				})
+				require.NoError(t, err)
+
+				// Two workers sharing the queue
+				ctx1 := backend.WithWorkerID(ctx, "worker-1")
+				ctx2 := backend.WithWorkerID(ctx, "worker-2")
+
+				task1, err := q.Dequeue(ctx1, client, []workflow.Queue{workflow.QueueDefault}, time.Hour, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task1)
+
+				task2, err := q.Dequeue(ctx2, client, []workflow.Queue{workflow.QueueDefault}, time.Hour, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task2)
+
+				require.NoError(t, q.Release(ctx, client, q.consumer(ctx1)))
+
+				recovered, err := q.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Hour, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, recovered)
+				require.Equal(t, task1.TaskID, recovered.TaskID)
+
+				none, err := q.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Hour, blockTimeout)
+				require.NoError(t, err)
+				require.Nil(t, none)
+
+				require.NoError(t, q.ExtendOwned(ctx2, client, workflow.QueueDefault, 0, task2.TaskID))
+			},
+		},
 		{
 			name: "Will only dequeue from given queue",
 			f: func(t *testing.T, q *taskQueue[any]) {
 				ctx := context.Background()
 
 				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
-					return q.Enqueue(ctx, p, workflow.QueueDefault, "t1", nil)
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 0, "t1", nil)
 				})
 				require.NoError(t, err)
 
@@ -263,6 +499,84 @@ func Test_TaskQueue(t *testing.T) {
 				require.NotNil(t, task)
 			},
 		},
+		{
+			name: "Dequeue tasks with higher priority first",
+			f: func(t *testing.T, q *taskQueue[any]) {
+				ctx := context.Background()
+
+				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
+					if err := q.Enqueue(ctx, p, workflow.QueueDefault, -5, "low", nil); err != nil {
+						return err
+					}
+
+					if err := q.Enqueue(ctx, p, workflow.QueueDefault, 0, "default", nil); err != nil {
+						return err
+					}
+
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 10, "high", nil)
+				})
+				require.NoError(t, err)
+
+				for _, expected := range []struct {
+					id       string
+					priority int
+				}{{"high", 10}, {"default", 0}, {"low", -5}} {
+					task, err := q.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Hour, blockTimeout)
+					require.NoError(t, err)
+					require.NotNil(t, task)
+					require.Equal(t, expected.id, task.ID)
+					require.Equal(t, expected.priority, task.Priority)
+
+					require.NoError(t, q.ExtendOwned(ctx, client, workflow.QueueDefault, task.Priority, task.TaskID))
+				}
+
+				task, err := q.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Hour, blockTimeout)
+				require.NoError(t, err)
+				require.Nil(t, task)
+
+				lag, err := q.Lag(ctx, client)
+				require.NoError(t, err)
+				require.Equal(t, int64(3), lag)
+			},
+		},
+		{
+			name: "Waiting for tasks hands back tasks with lower priority",
+			f: func(t *testing.T, q *taskQueue[any]) {
+				ctx := context.Background()
+
+				_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
+					return q.Enqueue(ctx, p, workflow.QueueDefault, 10, "high", nil)
+				})
+				require.NoError(t, err)
+
+				task, err := q.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Hour, blockTimeout)
+				require.NoError(t, err)
+				require.Equal(t, "high", task.ID)
+
+				// Tasks arriving in both streams while waiting are read together
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+
+					client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+						if err := q.Enqueue(ctx, p, workflow.QueueDefault, 0, "default", nil); err != nil {
+							return err
+						}
+
+						return q.Enqueue(ctx, p, workflow.QueueDefault, 10, "high2", nil)
+					})
+				}()
+
+				task, err = q.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Hour, time.Second)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, "high2", task.ID)
+
+				task, err = q.Dequeue(ctx, client, []workflow.Queue{workflow.QueueDefault}, time.Hour, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, "default", task.ID)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -281,7 +595,7 @@ func Test_TaskQueue(t *testing.T) {
 
 			ctx := context.Background()
 
-			q, err := newTaskQueue[any](ctx, client, "prefix", taskType)
+			q, err := newTaskQueue[any](ctx, client, "prefix", taskType, "task-workers")
 			require.NoError(t, err)
 
 			q.Prepare(ctx, client, []workflow.Queue{workflow.QueueDefault})
@@ -290,3 +604,18 @@ func Test_TaskQueue(t *testing.T) {
 		})
 	}
 }
+
+func Test_TaskQueue_PrepareUnreachable(t *testing.T) {
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:       []string{"localhost:1"},
+		DialTimeout: time.Millisecond * 100,
+		MaxRetries:  -1,
+	})
+
+	q := &taskQueue[any]{
+		groupName: "task-workers",
+	}
+
+	err := q.Prepare(context.Background(), client, []workflow.Queue{workflow.QueueDefault})
+	require.ErrorContains(t, err, "connecting to redis")
+}
@@ -3,8 +3,11 @@ package redis
 import (
 	"context"
 	"embed"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
@@ -23,17 +26,28 @@ var _ backend.Backend = (*redisBackend)(nil)
 var luaScripts embed.FS
 
 var (
-	createWorkflowInstanceCmd *redis.Script
-	completeWorkflowTaskCmd   *redis.Script
-	futureEventsCmd           *redis.Script
-	expireWorkflowInstanceCmd *redis.Script
+	registerWorkflowInstanceCmd    *redis.Script
+	createWorkflowInstanceCmd      *redis.Script
+	completeWorkflowTaskCmd        *redis.Script
+	completeWorkflowTaskSharedCmd  *redis.Script
+	deliverWorkflowEventsCmd       *redis.Script
+	finishWorkflowTaskCmd          *redis.Script
+	completeActivityTaskCmd        *redis.Script
+	futureEventsCmd                *redis.Script
+	expireWorkflowInstanceCmd      *redis.Script
+	expireWorkflowInstanceIndexCmd *redis.Script
+	resetWorkflowInstanceCmd       *redis.Script
+	resetWorkflowInstanceTasksCmd  *redis.Script
+	retryWorkflowInstanceCmd       *redis.Script
+	failWorkflowTaskCmd            *redis.Script
 )
 
 func NewRedisBackend(client redis.UniversalClient, opts ...RedisBackendOption) (*redisBackend, error) {
 	// Default options
 	options := &RedisOptions{
-		Options:      backend.ApplyOptions(),
-		BlockTimeout: time.Second * 2,
+		Options:       backend.ApplyOptions(),
+		BlockTimeout:  time.Second * 2,
+		ConsumerGroup: defaultGroupName,
 	}
 
 	for _, opt := range opts {
@@ -42,12 +56,16 @@ func NewRedisBackend(client redis.UniversalClient, opts ...RedisBackendOption) (
 
 	ctx := context.Background()
 
-	workflowQueue, err := newTaskQueue[workflowData](ctx, client, options.KeyPrefix, "workflows")
+	keys := newKeys(options.KeyPrefix, options.ConsumerGroup, options.ClusterKeys)
+
+	// Queues are shared by all instances, with cluster keys their keys are stored in the same slot as the other shared
+	// keys
+	workflowQueue, err := newTaskQueue[workflowData](ctx, client, keys.shared, "workflows", options.ConsumerGroup)
 	if err != nil {
 		return nil, fmt.Errorf("creating workflow task queue: %w", err)
 	}
 
-	activityQueue, err := newTaskQueue[activityData](ctx, client, options.KeyPrefix, "activities")
+	activityQueue, err := newTaskQueue[activityData](ctx, client, keys.shared, "activities", options.ConsumerGroup)
 	if err != nil {
 		return nil, fmt.Errorf("creating activity task queue: %w", err)
 	}
@@ -55,17 +73,50 @@ func NewRedisBackend(client redis.UniversalClient, opts ...RedisBackendOption) (
 	rb := &redisBackend{
 		rdb:     client,
 		options: options,
-		keys:    newKeys(options.KeyPrefix),
+		keys:    keys,
 
 		workflowQueue: workflowQueue,
 		activityQueue: activityQueue,
+
+		events: backend.NewEventDispatcher(options.EventCallback, options.Logger),
+	}
+
+	if options.InstanceLogsMaxLen > 0 {
+		rb.logs = newInstanceLogPublisher(client, rb.keys, options.InstanceLogsMaxLen, options.InstanceLogsTTL)
+		options.Logger = slog.New(newInstanceLogHandler(options.Logger.Handler(), rb.logs))
+	}
+
+	if options.DispatchPolicy != nil {
+		batchSize := options.DispatchBatchSize
+		if batchSize <= 0 {
+			batchSize = 1
+		}
+
+		workflowQueue.setSelector(rb.workflowTaskSelector(options.DispatchPolicy), batchSize)
+	}
+
+	if options.CompletionBatchSize > 1 {
+		rb.completions = newCompletionBatcher(options.CompletionBatchSize, options.CompletionBatchDelay, rb.execCompletions)
+	}
+
+	if options.PayloadStore == nil {
+		options.PayloadStore = &hashPayloadStore{rdb: client, keys: rb.keys}
+	}
+
+	if options.BlobStore == nil {
+		options.BlobStore = &redisBlobStore{rdb: client, keys: rb.keys}
+	}
+
+	if _, ok := options.PayloadStore.(PayloadReplacer); options.PruneActivityInputs && !ok {
+		return nil, errors.New("pruning activity inputs requires a payload store implementing PayloadReplacer")
 	}
 
 	// Preload scripts here. Usually redis-go attempts to execute them first, and if redis doesn't know
 	// them, loads them. This doesn't work when using (transactional) pipelines, so eagerly load them on startup.
 	cmds := map[string]*redis.StringCmd{
-		"deleteInstanceCmd": deleteCmd.Load(ctx, rb.rdb),
-		"addPayloadsCmd":    addPayloadsCmd.Load(ctx, rb.rdb),
+		"deleteInstanceCmd":   deleteCmd.Load(ctx, rb.rdb),
+		"addPayloadsCmd":      addPayloadsCmd.Load(ctx, rb.rdb),
+		"releaseUniqueKeyCmd": releaseUniqueKeyCmd.Load(ctx, rb.rdb),
 	}
 	for name, cmd := range cmds {
 		// fmt.Println(name, cmd.Val())
@@ -77,10 +128,20 @@ func NewRedisBackend(client redis.UniversalClient, opts ...RedisBackendOption) (
 
 	// Load all Lua scripts
 	cmdMapping := map[string]**redis.Script{
-		"create_workflow_instance.lua": &createWorkflowInstanceCmd,
-		"complete_workflow_task.lua":   &completeWorkflowTaskCmd,
-		"schedule_future_events.lua":   &futureEventsCmd,
-		"expire_workflow_instance.lua": &expireWorkflowInstanceCmd,
+		"register_workflow_instance.lua":     &registerWorkflowInstanceCmd,
+		"create_workflow_instance.lua":       &createWorkflowInstanceCmd,
+		"complete_workflow_task.lua":         &completeWorkflowTaskCmd,
+		"complete_workflow_task_shared.lua":  &completeWorkflowTaskSharedCmd,
+		"deliver_workflow_events.lua":        &deliverWorkflowEventsCmd,
+		"finish_workflow_task.lua":           &finishWorkflowTaskCmd,
+		"complete_activity_task.lua":         &completeActivityTaskCmd,
+		"schedule_future_events.lua":         &futureEventsCmd,
+		"expire_workflow_instance.lua":       &expireWorkflowInstanceCmd,
+		"expire_workflow_instance_index.lua": &expireWorkflowInstanceIndexCmd,
+		"reset_workflow_instance.lua":        &resetWorkflowInstanceCmd,
+		"reset_workflow_instance_tasks.lua":  &resetWorkflowInstanceTasksCmd,
+		"retry_workflow_instance.lua":        &retryWorkflowInstanceCmd,
+		"fail_workflow_task.lua":             &failWorkflowTaskCmd,
 	}
 
 	if err := loadScripts(ctx, rb.rdb, cmdMapping); err != nil {
@@ -117,15 +178,47 @@ type redisBackend struct {
 
 	workflowQueue *taskQueue[workflowData]
 	activityQueue *taskQueue[activityData]
+
+	// completions batches workflow task completions, if configured
+	completions *completionBatcher
+
+	// logs publishes log lines of workflow instances, if configured
+	logs *instanceLogPublisher
+
+	// events delivers persisted history events to the configured event callback
+	events *backend.EventDispatcher
 }
 
 type workflowData struct{}
 
+// activityData describes an activity task in the queue. The attributes of the scheduled event, which include the
+// potentially large inputs, are not part of the task but loaded from the payload store when the task is dequeued.
 type activityData struct {
 	Instance *core.WorkflowInstance `json:"instance,omitempty"`
 	Queue    string                 `json:"queue,omitempty"`
 	ID       string                 `json:"id,omitempty"`
 	Event    *history.Event         `json:"event,omitempty"`
+
+	// PayloadStored is set when the attributes of the event have been left out. Tasks queued by earlier versions
+	// contain the attributes.
+	PayloadStored bool `json:"payload_stored,omitempty"`
+}
+
+func (d *activityData) MarshalJSON() ([]byte, error) {
+	// Drop the methods of activityData to avoid recursion
+	type adata activityData
+
+	if !d.PayloadStored {
+		return json.Marshal((*adata)(d))
+	}
+
+	return json.Marshal(&struct {
+		*adata
+		Event *eventWithoutAttributes `json:"event,omitempty"`
+	}{
+		adata: (*adata)(d),
+		Event: &eventWithoutAttributes{d.Event},
+	})
 }
 
 func (rb *redisBackend) Metrics() metrics.Client {
@@ -141,14 +234,32 @@ func (b *redisBackend) Options() *backend.Options {
 }
 
 func (rb *redisBackend) Close() error {
+	if rb.logs != nil {
+		rb.logs.close()
+	}
+
 	return rb.rdb.Close()
 }
 
 func (rb *redisBackend) FeatureSupported(feature backend.Feature) bool {
 	switch feature {
-	case backend.Feature_Expiration:
+	case backend.Feature_Expiration, backend.Feature_TimerCompaction:
 		return false
 	}
 
 	return true
 }
+
+func (rb *redisBackend) DeregisterWorker(ctx context.Context, workerID string) error {
+	workerCtx := backend.WithWorkerID(ctx, workerID)
+
+	if err := rb.workflowQueue.Release(ctx, rb.rdb, rb.workflowQueue.consumer(workerCtx)); err != nil {
+		return fmt.Errorf("releasing workflow tasks: %w", err)
+	}
+
+	if err := rb.activityQueue.Release(ctx, rb.rdb, rb.activityQueue.consumer(workerCtx)); err != nil {
+		return fmt.Errorf("releasing activity tasks: %w", err)
+	}
+
+	return nil
+}
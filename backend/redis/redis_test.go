@@ -28,18 +28,7 @@ func Test_RedisBackend(t *testing.T) {
 	client := getClient()
 	setup := getCreateBackend(client)
 
-	test.BackendTest(t, setup, nil)
-}
-
-func Test_EndToEndRedisBackend(t *testing.T) {
-	if testing.Short() {
-		t.Skip()
-	}
-
-	client := getClient()
-	setup := getCreateBackend(client)
-
-	test.EndToEndBackendTest(t, setup, nil)
+	test.RunSuite(t, setup, nil)
 }
 
 func getClient() redis.UniversalClient {
@@ -101,7 +90,12 @@ func (rb *redisBackend) GetFutureEvents(ctx context.Context) ([]*history.Event,
 	events := make([]*history.Event, 0)
 
 	for _, eventID := range r {
-		eventStr, err := rb.rdb.HGet(ctx, eventID, "event").Result()
+		instance, scheduleEventID, err := parseFutureEventMember(eventID)
+		if err != nil {
+			return nil, err
+		}
+
+		eventStr, err := rb.rdb.HGet(ctx, rb.keys.futureEventKey(instance, scheduleEventID), "event").Result()
 		if err != nil {
 			return nil, fmt.Errorf("getting event %v: %w", eventID, err)
 		}
@@ -0,0 +1,308 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/redis/go-redis/v9"
+)
+
+// pendingReset holds the steps of a reset that update the keys shared by all instances. On a cluster, they are stored
+// in other slots than the keys of the instance. The script resetting the instance stores them with the instance, and
+// they are executed afterwards by the caller resetting the instance or, if that fails, before the next workflow task
+// of the instance is executed or the instance is reset again. No workflow task of the instance is executed while the
+// steps are pending, so executing them more than once does not remove work scheduled after the reset.
+type pendingReset struct {
+	Queue    workflow.Queue `json:"queue"`
+	Priority int            `json:"priority,omitempty"`
+
+	UniqueKey string `json:"unique_key,omitempty"`
+
+	// Discard are the schedule event IDs of work scheduled after the reset point
+	Discard []int64 `json:"discard,omitempty"`
+}
+
+func (rb *redisBackend) ResetWorkflowInstance(ctx context.Context, instance *workflow.Instance, sequenceID int64) error {
+	state, err := readInstance(ctx, rb.rdb, rb.keys.instanceKey(instance))
+	if err != nil {
+		return err
+	}
+
+	if state.State == core.WorkflowInstanceStateContinuedAsNew {
+		return backend.ErrNotSupported{Message: "resetting a workflow instance that continued as new"}
+	}
+
+	// The remaining steps of the last workflow task completion must not be executed after the reset
+	if _, err := rb.executePendingCompletion(ctx, instance); err != nil {
+		return err
+	}
+
+	// A previous reset might not have been finished
+	if _, err := rb.executePendingReset(ctx, instance); err != nil {
+		return err
+	}
+
+	h, err := rb.GetWorkflowInstanceHistory(ctx, instance, &sequenceID)
+	if err != nil {
+		return fmt.Errorf("getting history: %w", err)
+	}
+
+	// Results of sub-workflows started after the reset point could be delivered to the re-executed workflow
+	for _, subWorkflowInstance := range history.ResetSubWorkflows(h) {
+		activeExecution, err := rb.readActiveInstanceExecution(ctx, subWorkflowInstance.InstanceID)
+		if err != nil {
+			return fmt.Errorf("checking sub-workflows: %w", err)
+		}
+
+		if activeExecution != nil && activeExecution.ExecutionID == subWorkflowInstance.ExecutionID {
+			return backend.ErrSubWorkflowsActive
+		}
+	}
+
+	redeliver, discard := history.ResetEvents(h)
+
+	// Trigger re-execution of the workflow
+	pendingEvents := append(redeliver, history.NewWorkflowResetEvent(rb.options.Clock.Now(), sequenceID))
+	payloadArgs, err := rb.eventPayloadArgs(ctx, instance, pendingEvents)
+	if err != nil {
+		return err
+	}
+
+	activeInstance, err := json.Marshal(instance)
+	if err != nil {
+		return fmt.Errorf("marshaling instance: %w", err)
+	}
+
+	// The unique key released when the instance finished might have been taken by another instance since
+	segment := instanceSegment(instance)
+	if state.UniqueKey != "" {
+		holder, err := rb.rdb.Get(ctx, rb.keys.uniqueKey(state.UniqueKey)).Result()
+		if err != nil && err != redis.Nil {
+			return fmt.Errorf("reading unique key: %w", err)
+		}
+
+		if holder != "" && holder != segment {
+			return backend.ErrInstanceAlreadyExists
+		}
+	}
+
+	keys := []string{
+		rb.keys.instanceKey(instance),
+		rb.keys.historyKey(instance),
+		rb.keys.pendingEventsKey(instance),
+		rb.keys.activeInstanceExecutionKey(instance.InstanceID),
+		rb.keys.latestInstanceExecutionKey(instance.InstanceID),
+		rb.keys.scheduledActivitiesKey(instance),
+		rb.keys.resetKey(instance),
+	}
+
+	pr, err := json.Marshal(&pendingReset{
+		Queue:     workflow.Queue(state.Queue),
+		Priority:  state.Priority,
+		UniqueKey: state.UniqueKey,
+		Discard:   discard,
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling pending reset: %w", err)
+	}
+
+	args := []interface{}{
+		instance.ExecutionID,
+		sequenceID,
+		string(activeInstance),
+		int(core.WorkflowInstanceStateActive),
+		string(pr),
+	}
+
+	// Work scheduled after the reset point
+	args = append(args, len(discard))
+	for _, scheduleEventID := range discard {
+		args = append(args, scheduleEventID)
+	}
+
+	args = append(args, len(pendingEvents))
+	for _, event := range pendingEvents {
+		eventData, err := marshalEventWithoutAttributes(event)
+		if err != nil {
+			return fmt.Errorf("marshaling event: %w", err)
+		}
+
+		args = append(args, eventData)
+	}
+
+	// Timers are only scheduled for schedule event IDs of timers, other keys do not exist
+	args = append(args, len(discard))
+	for _, scheduleEventID := range discard {
+		keys = append(keys, rb.keys.futureEventKey(instance, scheduleEventID))
+	}
+
+	// Keys expired when the instance finished. Payloads kept in a custom payload store might expire regardless.
+	persistKeys := []string{
+		rb.keys.instanceKey(instance),
+		rb.keys.pendingEventsKey(instance),
+		rb.keys.historyKey(instance),
+		rb.keys.childrenKey(instance),
+		rb.keys.progressKey(instance),
+		rb.keys.scheduledActivitiesKey(instance),
+		rb.keys.usageKey(instance),
+		rb.keys.payloadKey(instance),
+		rb.keys.deliveriesKey(instance),
+	}
+	args = append(args, len(persistKeys))
+	keys = append(keys, persistKeys...)
+
+	keys = append(keys, rb.keys.payloadKey(instance))
+	args = append(args, payloadArgs...)
+
+	if err := resetWorkflowInstanceCmd.Run(ctx, rb.rdb, keys, args...).Err(); err != nil {
+		if _, ok := err.(redis.Error); ok {
+			switch err.Error() {
+			case "ERR InstanceNotFound":
+				return backend.ErrInstanceNotFound
+			case "ERR InstanceAlreadyExists":
+				return backend.ErrInstanceAlreadyExists
+			case "ERR InvalidResetPoint":
+				return backend.ErrInvalidResetPoint
+			}
+		}
+
+		return fmt.Errorf("resetting workflow instance: %w", err)
+	}
+
+	if _, err := rb.executePendingReset(ctx, instance); err != nil {
+		return err
+	}
+
+	if rb.options.MaxInactivityTTL > 0 {
+		if err := rb.refreshWorkflowInstanceExpiration(ctx, instance); err != nil {
+			return fmt.Errorf("refreshing workflow instance expiration: %w", err)
+		}
+	}
+
+	rb.publishInstanceUpdate(ctx, instance, core.WorkflowInstanceStateActive)
+
+	return nil
+}
+
+// executePendingReset executes the remaining steps of the last reset of the given instance, if there are any. Returns
+// whether there were remaining steps.
+func (rb *redisBackend) executePendingReset(ctx context.Context, instance *core.WorkflowInstance) (bool, error) {
+	data, err := rb.rdb.Get(ctx, rb.keys.resetKey(instance)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("reading pending reset: %w", err)
+	}
+
+	var r pendingReset
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		return false, fmt.Errorf("unmarshaling pending reset: %w", err)
+	}
+
+	activityQueues, err := rb.activityQueue.AllKeys(ctx, rb.rdb)
+	if err != nil {
+		return false, fmt.Errorf("getting activity queues: %w", err)
+	}
+
+	// Remove tasks and timers for work scheduled after the reset point, and queue a workflow task to re-execute the
+	// workflow
+	segment := instanceSegment(instance)
+	queueKeys := rb.workflowQueue.KeysWithPriority(r.Queue, r.Priority)
+	taskKeys := []string{
+		rb.keys.instancesActive(),
+		rb.keys.instancesExpiring(),
+		rb.keys.futureEventsKey(),
+		queueKeys.SetKey,
+		queueKeys.StreamKey,
+	}
+
+	taskArgs := []interface{}{
+		segment,
+		instance.InstanceID,
+		instance.ExecutionID,
+		rb.options.ConsumerGroup,
+	}
+
+	taskArgs = append(taskArgs, len(r.Discard))
+	for _, scheduleEventID := range r.Discard {
+		taskArgs = append(taskArgs, scheduleEventID)
+	}
+
+	taskArgs = append(taskArgs, len(r.Discard))
+	for _, scheduleEventID := range r.Discard {
+		taskArgs = append(taskArgs, futureEventMember(instance, scheduleEventID))
+	}
+
+	taskArgs = append(taskArgs, len(activityQueues))
+	for _, queueKeys := range activityQueues {
+		taskKeys = append(taskKeys, queueKeys.SetKey, queueKeys.StreamKey)
+	}
+
+	if r.UniqueKey != "" {
+		taskKeys = append(taskKeys, rb.keys.uniqueKey(r.UniqueKey))
+	}
+
+	p := rb.rdb.TxPipeline()
+	resetWorkflowInstanceTasksCmd.Run(ctx, p, taskKeys, taskArgs...)
+	if err := rb.workflowQueue.Enqueue(ctx, p, r.Queue, r.Priority, segment, nil); err != nil {
+		return false, fmt.Errorf("queueing workflow: %w", err)
+	}
+
+	if _, err := p.Exec(ctx); err != nil {
+		return false, fmt.Errorf("resetting workflow instance tasks: %w", err)
+	}
+
+	// The steps can be executed again until the pending reset has been removed
+	if err := rb.rdb.Del(ctx, rb.keys.resetKey(instance)).Err(); err != nil {
+		return false, fmt.Errorf("removing pending reset: %w", err)
+	}
+
+	return true, nil
+}
+
+func (rb *redisBackend) RetryWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	state, err := readInstance(ctx, rb.rdb, rb.keys.instanceKey(instance))
+	if err != nil {
+		return err
+	}
+
+	if err := retryWorkflowInstanceCmd.Run(ctx, rb.rdb, []string{
+		rb.keys.instanceKey(instance),
+	},
+		int(core.WorkflowInstanceStateErrored),
+		int(core.WorkflowInstanceStateActive),
+	).Err(); err != nil {
+		if _, ok := err.(redis.Error); ok {
+			switch err.Error() {
+			case "ERR InstanceNotFound":
+				return backend.ErrInstanceNotFound
+			case "ERR InstanceNotErrored":
+				return backend.ErrInstanceNotErrored
+			}
+		}
+
+		return fmt.Errorf("retrying workflow instance: %w", err)
+	}
+
+	// Queue a workflow task to process the pending events again. If the instance is still queued, the task is executed
+	// now that the instance is active again.
+	p := rb.rdb.Pipeline()
+	if err := rb.workflowQueue.Enqueue(ctx, p, workflow.Queue(state.Queue), state.Priority, instanceSegment(instance), nil); err != nil {
+		return fmt.Errorf("queueing workflow: %w", err)
+	}
+
+	if _, err := p.Exec(ctx); err != nil {
+		return fmt.Errorf("queueing workflow: %w", err)
+	}
+
+	rb.publishInstanceUpdate(ctx, instance, core.WorkflowInstanceStateActive)
+
+	return nil
+}
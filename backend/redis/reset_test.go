@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ResetWorkflowInstance_FinishesPendingReset(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+
+	redisClient := getClient()
+	setup := getCreateBackend(redisClient)
+	b := setup()
+	defer b.Close()
+
+	rb := b.(*redisBackend)
+	c := client.New(b)
+
+	executions := 0
+	a := func(ctx context.Context) (int, error) {
+		executions++
+		return executions, nil
+	}
+
+	wf := func(ctx workflow.Context) (int, error) {
+		return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+	}
+
+	startWorker := func() func() {
+		w := worker.New(b, nil)
+		require.NoError(t, w.RegisterWorkflow(wf))
+		require.NoError(t, w.RegisterActivity(a))
+
+		wctx, cancel := context.WithCancel(ctx)
+		require.NoError(t, w.Start(wctx))
+
+		return func() {
+			cancel()
+			require.NoError(t, w.WaitForCompletion())
+		}
+	}
+
+	stop := startWorker()
+
+	wfi, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, wf)
+	require.NoError(t, err)
+
+	r, err := client.GetWorkflowResult[int](ctx, c, wfi, time.Second*10)
+	require.NoError(t, err)
+	require.Equal(t, 1, r)
+
+	stop()
+
+	h, err := b.GetWorkflowInstanceHistory(ctx, wfi, nil)
+	require.NoError(t, err)
+
+	// Reset to right after the workflow was started, before the activity was scheduled
+	var sequenceID int64
+	for _, event := range h {
+		if event.Type == history.EventType_WorkflowExecutionStarted {
+			sequenceID = event.SequenceID
+			break
+		}
+	}
+
+	require.NoError(t, c.ResetWorkflow(ctx, wfi.InstanceID, sequenceID))
+
+	// Removing the pending reset failed, its steps are executed again before the workflow is re-executed
+	pr, err := json.Marshal(&pendingReset{Queue: workflow.QueueDefault})
+	require.NoError(t, err)
+	require.NoError(t, redisClient.Set(ctx, rb.keys.resetKey(wfi), string(pr), 0).Err())
+
+	stop = startWorker()
+	defer stop()
+
+	r, err = client.GetWorkflowResult[int](ctx, c, wfi, time.Second*10)
+	require.NoError(t, err)
+	require.Equal(t, 2, r)
+
+	n, err := redisClient.Exists(ctx, rb.keys.resetKey(wfi)).Result()
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// RetryPolicy configures how backend operations are retried when they fail with a transient redis error
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one
+	MaxAttempts int
+
+	// FirstRetryInterval is the time to wait before the first retry
+	FirstRetryInterval time.Duration
+
+	// MaxRetryInterval is the maximum time to wait between two attempts
+	MaxRetryInterval time.Duration
+
+	// BackoffCoefficient is the factor the time to wait is multiplied with after every retry
+	BackoffCoefficient float64
+}
+
+// DefaultRetryPolicy retries operations for up to about a second, long enough to ride out a redis failover in
+// most setups.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:        5,
+	FirstRetryInterval: time.Millisecond * 50,
+	MaxRetryInterval:   time.Millisecond * 500,
+	BackoffCoefficient: 2,
+}
+
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	coefficient := p.BackoffCoefficient
+	if coefficient < 1 {
+		coefficient = 1
+	}
+
+	d := time.Duration(float64(p.FirstRetryInterval) * math.Pow(coefficient, float64(retry-1)))
+	if p.MaxRetryInterval > 0 && d > p.MaxRetryInterval {
+		d = p.MaxRetryInterval
+	}
+
+	return d
+}
+
+// retryableErrorPrefixes are prefixes of redis error replies that are caused by changes of the topology of a
+// cluster or a failover, and succeed when retried.
+var retryableErrorPrefixes = []string{
+	"MOVED",       // Slot has moved to another node
+	"ASK",         // Slot is being migrated
+	"CLUSTERDOWN", // Cluster is not able to serve requests
+	"MASTERDOWN",  // Replica lost the connection to its master
+	"READONLY",    // Write was sent to a node that has just been demoted to replica
+}
+
+// isRetryableError returns true if the error is a transient redis error, like a timeout, a dropped connection, or a
+// cluster topology change. Logical errors, for example a missing key or a failed script, are not retryable.
+func isRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, redis.Nil) {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return true
+	}
+
+	var rerr redis.Error
+	if errors.As(err, &rerr) {
+		msg := rerr.Error()
+		for _, prefix := range retryableErrorPrefixes {
+			if strings.HasPrefix(msg, prefix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// retry executes the given operation, and retries it with the configured policy when it fails with a transient
+// error. fn is passed the number of the attempt, starting at 0. Operations must be safe to execute again after an
+// attempt that failed with a transient error, which might have been applied nevertheless.
+func (rb *redisBackend) retry(ctx context.Context, operation string, fn func(attempt int) error) error {
+	policy := rb.options.RetryPolicy
+
+	for attempt := 0; ; attempt++ {
+		err := fn(attempt)
+		if err == nil || policy == nil || attempt+1 >= policy.MaxAttempts || !isRetryableError(err) {
+			return err
+		}
+
+		backoff := policy.backoff(attempt + 1)
+
+		rb.options.Logger.Warn("retrying redis operation",
+			"operation", operation,
+			log.AttemptKey, attempt+1,
+			log.ErrorKey, err,
+		)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_isRetryableError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"nil", nil, false},
+		{"other error", errors.New("some error"), false},
+		{"nil reply", redis.Nil, false},
+		{"canceled", fmt.Errorf("dequeueing task: %w", context.Canceled), false},
+		{"script error", testRedisError("ERR Error running script"), false},
+		{"busy", testRedisError("BUSY Redis is busy running a script"), false},
+		{"connection closed", fmt.Errorf("reading history: %w", io.EOF), true},
+		{"moved", testRedisError("MOVED 3999 127.0.0.1:6381"), true},
+		{"readonly", testRedisError("READONLY You can't write against a read only replica."), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.retryable, isRetryableError(tt.err))
+		})
+	}
+}
+
+func Test_RetryPolicy_backoff(t *testing.T) {
+	p := &RetryPolicy{
+		FirstRetryInterval: time.Millisecond * 10,
+		MaxRetryInterval:   time.Millisecond * 30,
+		BackoffCoefficient: 2,
+	}
+
+	require.Equal(t, time.Millisecond*10, p.backoff(1))
+	require.Equal(t, time.Millisecond*20, p.backoff(2))
+	require.Equal(t, time.Millisecond*30, p.backoff(3))
+}
+
+func Test_redisBackend_retry(t *testing.T) {
+	newBackend := func(policy *RetryPolicy) *redisBackend {
+		return &redisBackend{
+			options: &RedisOptions{
+				Options:     backend.ApplyOptions(),
+				RetryPolicy: policy,
+			},
+		}
+	}
+
+	policy := &RetryPolicy{
+		MaxAttempts:        3,
+		FirstRetryInterval: time.Millisecond,
+	}
+
+	t.Run("RetriesTransientErrors", func(t *testing.T) {
+		attempts := 0
+		err := newBackend(policy).retry(context.Background(), "test", func(attempt int) error {
+			require.Equal(t, attempts, attempt)
+			attempts++
+
+			if attempts < 3 {
+				return io.EOF
+			}
+
+			return nil
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("StopsAfterMaxAttempts", func(t *testing.T) {
+		attempts := 0
+		err := newBackend(policy).retry(context.Background(), "test", func(int) error {
+			attempts++
+			return io.EOF
+		})
+
+		require.ErrorIs(t, err, io.EOF)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("DoesNotRetryLogicalErrors", func(t *testing.T) {
+		attempts := 0
+		err := newBackend(policy).retry(context.Background(), "test", func(int) error {
+			attempts++
+			return redis.Nil
+		})
+
+		require.ErrorIs(t, err, redis.Nil)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("WithoutPolicy", func(t *testing.T) {
+		attempts := 0
+		err := newBackend(nil).retry(context.Background(), "test", func(int) error {
+			attempts++
+			return io.EOF
+		})
+
+		require.ErrorIs(t, err, io.EOF)
+		require.Equal(t, 1, attempts)
+	})
+}
@@ -0,0 +1,29 @@
+-- Delivers the result of an activity to its workflow instance
+--
+-- KEYS[1] = instance key
+-- KEYS[2] = pending events stream of the instance
+-- KEYS[3] = payload hash of the instance
+-- KEYS[4] = deliveries hash of the instance
+-- ARGV[1] = activity task id
+-- ARGV[2] = result event data
+-- ARGV[3] = number of payloads to store, followed by event id and payload pairs
+--
+-- Returns nil if the instance does not exist, otherwise the queue and priority of the instance and the number of its
+-- pending events
+local instanceData = redis.call("GET", KEYS[1])
+if not instanceData then
+    return nil
+end
+
+-- The result of a task is only delivered once, the task might be completed again after its result has been delivered
+if redis.call("HSETNX", KEYS[4], "activity:" .. ARGV[1], 1) == 1 then
+    redis.call("XADD", KEYS[2], "*", "event", ARGV[2])
+
+    local payloads = tonumber(ARGV[3])
+    for i = 1, payloads do
+        redis.call("HSETNX", KEYS[3], ARGV[2 + i * 2], ARGV[3 + i * 2])
+    end
+end
+
+local instance = cjson.decode(instanceData)
+return { instance["queue"], instance["priority"] or 0, redis.call("XLEN", KEYS[2]) }
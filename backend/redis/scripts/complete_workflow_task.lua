@@ -1,3 +1,5 @@
+-- Commits a workflow task to its instance. All keys belong to the instance, steps affecting other instances and the
+-- shared keys are stored with the instance and executed afterwards, see pendingCompletion.
 local keyIdx = 1
 local argvIdx = 1
 
@@ -14,54 +16,58 @@ local getArgv = function()
     return argv
 end
 
--- Shared keys
 local instanceKey = getKey()
 local historyStreamKey = getKey()
 local pendingEventsKey = getKey()
-local payloadHashKey = getKey()
-local futureEventZSetKey = getKey()
-local activeInstancesKey = getKey()
-local instancesByCreation = getKey()
-
-local workflowSetKey = getKey()
-local workflowStreamKey = getKey()
-local workflowQueuesSetKey = getKey()
-
-local prefix = getArgv()
-local instanceSegment = getArgv()
+local activeInstanceExecutionKey = getKey()
+local scheduledActivitiesKey = getKey()
+local progressKey = getKey()
+local payloadKey = getKey()
+local usageKey = getKey()
+local completionKey = getKey()
 
-local storePayload = function(eventId, payload)
-    redis.pcall("HSETNX", payloadHashKey, eventId, payload)
+local lastPendingEventMessageId = getArgv()
+local hasProgress = tonumber(getArgv())
+local progress = getArgv()
+
+-- Make sure the task has not been completed or the instance reset in the meantime. Completing the task removes the
+-- pending events it has executed, resetting the instance replaces all pending events.
+local instanceData = redis.call("GET", instanceKey)
+if not instanceData
+    or #redis.call("XRANGE", pendingEventsKey, lastPendingEventMessageId, lastPendingEventMessageId) == 0 then
+    return redis.error_reply("ERR TaskLockLost")
 end
 
 -- Read instance
-local instance = cjson.decode(redis.call("GET", instanceKey))
+local instance = cjson.decode(instanceData)
+
+-- Add the usage of this task to the usage of the instance
+local usageCounters = tonumber(getArgv())
+for i = 1, usageCounters do
+    local field = getArgv()
+    local increment = getArgv()
+    redis.call("HINCRBY", usageKey, field, increment)
+end
 
--- Add executed events to history
+-- Add executed events to history, payloads are stored below
 local executedEvents = tonumber(getArgv())
 local lastSequenceId = 0
 for i = 1, executedEvents do
-    local eventId = getArgv()
     local eventData = getArgv()
-    local payloadData = getArgv()
     local sequenceId = getArgv()
 
     -- Add event to history
     redis.call("XADD", historyStreamKey, sequenceId, "event", eventData)
 
-    storePayload(eventId, payloadData)
-
     lastSequenceId = tonumber(sequenceId)
 end
 
 -- Remove executed pending events
-local lastPendingEventMessageId = getArgv()
 redis.call("XTRIM", pendingEventsKey, "MINID", lastPendingEventMessageId)
 redis.call("XDEL", pendingEventsKey, lastPendingEventMessageId)
 
 -- Update instance state
 local now = getArgv()
-local nowUnix = tonumber(getArgv())
 local state = tonumber(getArgv())
 
 -- State constants
@@ -69,16 +75,16 @@ local ContinuedAsNew = tonumber(getArgv())
 local Finished = tonumber(getArgv())
 
 instance["state"] = state
+instance["last_activity_at"] = now
+instance["task_failures"] = nil
 
--- If workflow instance finished, remove active execution
-local activeInstanceExecutionKey = getKey()
 if state == ContinuedAsNew or state == Finished then
-    -- Remove active execution
-    redis.call("DEL", activeInstanceExecutionKey)
-
     instance["completed_at"] = now
 
-    redis.call("SREM", activeInstancesKey, instanceSegment)
+    -- Remove active execution. When continued as new, the new execution replaces it.
+    if state == Finished then
+        redis.call("DEL", activeInstanceExecutionKey)
+    end
 end
 
 if lastSequenceId > 0 then
@@ -87,148 +93,55 @@ end
 
 redis.call("SET", instanceKey, cjson.encode(instance))
 
+-- Store progress, expire it together with the instance
+if hasProgress == 1 then
+    local ttl = redis.call("PTTL", instanceKey)
+    if ttl > 0 then
+        redis.call("SET", progressKey, progress, "PX", ttl)
+    else
+        redis.call("SET", progressKey, progress)
+    end
+end
+
 -- Remove canceled timers
 local timersToCancel = tonumber(getArgv())
 for i = 1, timersToCancel do
     local futureEventKey = getKey()
 
-    local eventRemoved = redis.call("ZREM", futureEventZSetKey, futureEventKey)
-    -- Event might've become visible while this task was being processed, in that
-    -- case it would be already removed from futureEventZSetKey
-    if eventRemoved == 1 then
-        -- remove payload
-        local eventId = redis.call("HGET", futureEventKey, "id")
-        redis.call("HDEL", payloadHashKey, eventId)
-        -- remove event hash
+    -- Timer might've fired while this task was being processed, in that case its event has been delivered already
+    local eventId = redis.call("HGET", futureEventKey, "id")
+    if eventId then
+        -- remove payload of the event that will not fire anymore, and the event hash
+        redis.call("HDEL", payloadKey, eventId)
         redis.call("DEL", futureEventKey)
     end
 end
 
--- Schedule timers
+-- Schedule timers, they are added to the set of future events of all instances afterwards
 local timersToSchedule = tonumber(getArgv())
 for i = 1, timersToSchedule do
     local eventId = getArgv()
-    local timestamp = getArgv()
     local eventData = getArgv()
-    local payloadData = getArgv()
 
     local futureEventKey = getKey()
-
-    redis.call("ZADD", futureEventZSetKey, timestamp, futureEventKey)
-	redis.call("HSET", futureEventKey, "instance", instanceSegment, "id", eventId, "event", eventData, "queue", instance["queue"])
-	storePayload(eventId, payloadData)
+    redis.call("HSET", futureEventKey, "id", eventId, "event", eventData)
 end
 
--- Schedule activities
+-- Activities are only enqueued once per schedule event, the tasks are queued afterwards
 local activities = tonumber(getArgv())
-
 for i = 1, activities do
-    local activityQueue = getArgv()
-    local activityId = getArgv()
-    local activityData = getArgv()
-
-    local activitySetKey = prefix .. "task-set:" .. activityQueue .. ":activities"
-    local activityStreamKey = prefix .. "task-stream:" .. activityQueue .. ":activities"
-    redis.call("SADD", prefix .. "activities:queues", activitySetKey)
-
-    local added = redis.call("SADD", activitySetKey, activityId)
-	if added == 1 then
-		redis.call("XADD", activityStreamKey, "*", "id", activityId, "data", activityData)
-	end
-end
-
--- Send events to other workflow instances
-local otherWorkflowInstances = tonumber(getArgv())
-for i = 1, otherWorkflowInstances do
-    local targetInstanceKey = getKey()
-    local targetActiveInstanceExecutionKey = getKey()
-
-    local targetInstanceSegment = getArgv()
-    local targetInstanceId = getArgv()
-    local createNewInstance = tonumber(getArgv())
-    local eventsToDeliver = tonumber(getArgv())
-    local skipEvents = false
-
-    -- Creating a new instance?
-    if createNewInstance == 1 then
-        local targetInstanceState = getArgv()
-        local targetActiveInstanceExecutionState = getArgv()
-
-        local conflictEventId = getArgv()
-        local conflictEventData = getArgv()
-        local conflictEventPayloadData = getArgv()
-
-        -- Does the instance exist already?
-        local instanceExists = redis.call("EXISTS", targetActiveInstanceExecutionKey)
-        if instanceExists == 1 then
-            redis.call("XADD", pendingEventsKey, "*", "event", conflictEventData)
-            storePayload(conflictEventId, conflictEventPayloadData)
-            redis.call("ECHO",
-                "Conflict detected, event " ..
-                conflictEventId .. " was not delivered to instance " .. targetInstanceSegment .. ".")
-
-            skipEvents = true
-        else
-            -- Create new instance
-            redis.call("SETNX", targetInstanceKey, targetInstanceState)
-
-            -- Set active execution
-            redis.call("SET", targetActiveInstanceExecutionKey, targetActiveInstanceExecutionState)
-
-            -- Track active instance
-            redis.call("SADD", activeInstancesKey, targetInstanceSegment)
-            redis.call("ZADD", instancesByCreation, nowUnix, targetInstanceSegment)
-        end
-    end
-
-    local instanceQueueSetKey = getKey()
-    local instanceQueueStreamKey = getKey()
-    local instancePendingEventsKey = getKey()
-    local instancePayloadHashKey = getKey()
-
-    for j = 1, eventsToDeliver do
-        local eventId = getArgv()
-        local eventData = getArgv()
-        local payloadData = getArgv()
-
-        if not skipEvents then
-            -- Add event to pending events
-            redis.call("XADD", instancePendingEventsKey, "*", "event", eventData)
-
-            -- Store payload
-            redis.pcall("HSETNX", instancePayloadHashKey, eventId, payloadData)
-        end
-    end
-
-    -- If events were delivered, try to queue a workflow task
-    if not skipEvents then
-        -- Enqueue workflow task
-        redis.call("SADD", workflowQueuesSetKey, instanceQueueSetKey)
-        local added = redis.call("SADD", instanceQueueSetKey, targetInstanceSegment)
-        if added == 1 then
-            redis.call("XADD", instanceQueueStreamKey, "*", "id", targetInstanceSegment, "data", "")
-        end
-    end
+    redis.call("SADD", scheduledActivitiesKey, getArgv())
 end
 
--- Complete workflow task and mark instance task as completed
-local taskId = getArgv()
-local groupName = getArgv()
-local task = redis.call("XRANGE", workflowStreamKey, taskId, taskId)
-if #task ~= 0 then
-    local id = task[1][2][2]
-    redis.call("SREM", workflowSetKey, id)
-    redis.call("XACK", workflowStreamKey, groupName, taskId)
-    redis.call("XDEL", workflowStreamKey, taskId)
+-- Store payloads of the events written by this task, unless they are kept outside of redis
+local payloads = tonumber(getArgv())
+for i = 1, payloads do
+    local eventId = getArgv()
+    local payload = getArgv()
+    redis.call("HSETNX", payloadKey, eventId, payload)
 end
 
--- If there are pending events, queue the instance again
-local pending_events = redis.call("XLEN", pendingEventsKey)
-if pending_events > 0 then
-    local added = redis.call("SADD", workflowSetKey, instanceSegment)
-    if added == 1 then
-        redis.call("XADD", workflowStreamKey, "*", "id", instanceSegment, "data", "")
-    end
-end
+-- Keep the remaining steps of the completion until they have been executed
+redis.call("SET", completionKey, getArgv())
 
-return true
\ No newline at end of file
+return true
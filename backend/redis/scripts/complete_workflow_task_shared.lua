@@ -0,0 +1,136 @@
+-- Updates the keys shared by all instances for a workflow task completion and completes the task. The shared keys must
+-- only be updated once: if the task is no longer pending, the completion has been executed by another worker already,
+-- and nothing is updated.
+--
+-- KEYS[1] = workflow task set
+-- KEYS[2] = workflow task stream
+-- KEYS[3] = future events zset
+-- KEYS[4] = active instances set
+-- KEYS[5] = instances by creation zset
+-- ARGV[1] = consumer group
+-- ARGV[2] = task id
+-- ARGV[3] = instance segment
+--
+-- Returns 1 if the task has been completed, 0 if it was not pending anymore
+local keyIdx = 1
+local argvIdx = 1
+
+local getKey = function()
+    local key = KEYS[keyIdx]
+    keyIdx = keyIdx + 1
+    return key
+end
+
+local getArgv = function()
+    local argv = ARGV[argvIdx]
+    argvIdx = argvIdx + 1
+    return argv
+end
+
+local workflowSetKey = getKey()
+local workflowStreamKey = getKey()
+local futureEventZSetKey = getKey()
+local activeInstancesKey = getKey()
+local instancesByCreationKey = getKey()
+
+local groupName = getArgv()
+local taskId = getArgv()
+local instanceSegment = getArgv()
+
+local pending = redis.call("XPENDING", workflowStreamKey, groupName, taskId, taskId, 1)
+if #pending == 0 then
+    return 0
+end
+
+-- Same as queue/enqueue.lua, the keys are the queues set, the task set, the stream, and the priority streams
+local enqueue = function(id, data, priority)
+    local queuesSetKey = getKey()
+    local setKey = getKey()
+    local streamKey = getKey()
+    local prioritiesKey = getKey()
+
+    redis.call("SADD", queuesSetKey, setKey)
+    local added = redis.call("SADD", setKey, id)
+    if added == 1 then
+        redis.call("XADD", streamKey, "*", "id", id, "data", data)
+
+        if priority ~= 0 then
+            redis.call("ZADD", prioritiesKey, priority, streamKey)
+        end
+    end
+end
+
+-- Remove canceled timers
+local canceledTimers = tonumber(getArgv())
+for i = 1, canceledTimers do
+    redis.call("ZREM", futureEventZSetKey, getArgv())
+end
+
+-- Schedule timers
+local timers = tonumber(getArgv())
+for i = 1, timers do
+    local member = getArgv()
+    local visibleAt = getArgv()
+    redis.call("ZADD", futureEventZSetKey, visibleAt, member)
+end
+
+-- Queue activity tasks
+local activities = tonumber(getArgv())
+for i = 1, activities do
+    local activityId = getArgv()
+    local activityData = getArgv()
+    enqueue(activityId, activityData, 0)
+end
+
+-- Queue workflow tasks for the instances events have been delivered to
+local deliveries = tonumber(getArgv())
+for i = 1, deliveries do
+    local targetInstanceSegment = getArgv()
+    local priority = tonumber(getArgv())
+    enqueue(targetInstanceSegment, getArgv(), priority)
+
+    -- Track started instances
+    local started = tonumber(getArgv())
+    if started == 1 then
+        local createdAt = getArgv()
+        redis.call("SADD", activeInstancesKey, targetInstanceSegment)
+        redis.call("ZADD", instancesByCreationKey, createdAt, targetInstanceSegment)
+
+        -- Hand over the unique key when continuing as new, and track the new execution for each of the tags
+        local uniqueKeys = tonumber(getArgv())
+        for j = 1, uniqueKeys do
+            redis.call("SET", getKey(), targetInstanceSegment)
+        end
+
+        local tags = tonumber(getArgv())
+        local tagScore = getArgv()
+        for j = 1, tags do
+            redis.call("ZADD", getKey(), tagScore, targetInstanceSegment)
+        end
+    end
+end
+
+-- Untrack finished instance
+local finished = tonumber(getArgv())
+if finished == 1 then
+    redis.call("SREM", activeInstancesKey, instanceSegment)
+
+    -- Release the unique key, when continued as new it is handed over to the new execution above
+    local uniqueKeys = tonumber(getArgv())
+    for i = 1, uniqueKeys do
+        local uniqueKey = getKey()
+        if redis.call("GET", uniqueKey) == instanceSegment then
+            redis.call("DEL", uniqueKey)
+        end
+    end
+end
+
+-- Complete workflow task, same as queue/complete.lua
+local task = redis.call("XRANGE", workflowStreamKey, taskId, taskId)
+if #task ~= 0 then
+    redis.call("SREM", workflowSetKey, task[1][2][2])
+    redis.call("XACK", workflowStreamKey, groupName, taskId)
+    redis.call("XDEL", workflowStreamKey, taskId)
+end
+
+return 1
@@ -1,65 +1,49 @@
-local keyIdx = 1
+-- Creates a new workflow instance, it has been registered with the shared indexes already
+--
+-- KEYS[1] = instance key
+-- KEYS[2] = active-instance-execution key
+-- KEYS[3] = latest-instance-execution key
+-- KEYS[4] = pending events stream of the instance
+-- KEYS[5] = payload hash of the instance
+-- ARGV[1] = instance state
+-- ARGV[2] = active execution
+-- ARGV[3] = execution id
+-- ARGV[4] = number of events, followed by the event data
+-- ARGV[n] = number of payloads to store, followed by event id and payload pairs
 local argvIdx = 1
 
-local getKey = function()
-    local key = KEYS[keyIdx]
-    keyIdx = keyIdx + 1
-    return key
-end
-
 local getArgv = function()
     local argv = ARGV[argvIdx]
     argvIdx = argvIdx + 1
     return argv
 end
 
-local instanceKey = getKey()
-local activeInstanceExecutionKey = getKey()
-local pendingEventsKey = getKey()
-local payloadHashKey = getKey()
-
-local instancesActiveKey = getKey()
-local instancesByCreation = getKey()
-
-local workflowSetKey = getKey()
-local workflowStreamKey = getKey()
-local workflowQueuesSet = getKey()
-
-local instanceSegment = getArgv()
-
 -- Is there an existing instance with active execution?
-local instanceExists = redis.call("EXISTS", activeInstanceExecutionKey)
-if instanceExists == 1 then
+if redis.call("EXISTS", KEYS[2]) == 1 then
   return redis.error_reply("ERR InstanceAlreadyExists")
 end
 
 -- Create new instance
-local instanceState = getArgv()
-redis.call("SETNX", instanceKey, instanceState)
+redis.call("SETNX", KEYS[1], getArgv())
 
 -- Set active execution
-local activeInstanceExecutionState = getArgv()
-redis.call("SET", activeInstanceExecutionKey, activeInstanceExecutionState)
-
--- Track active instance
-redis.call("SADD", instancesActiveKey, instanceSegment)
+redis.call("SET", KEYS[2], getArgv())
 
--- add initial event & payload
-local eventId = getArgv()
-local eventData = getArgv()
-redis.call("XADD", pendingEventsKey, "*", "event", eventData)
+-- Set latest execution
+redis.call("SET", KEYS[3], getArgv())
 
-local payload = getArgv()
-redis.pcall("HSETNX", payloadHashKey, eventId, payload)
-
-local creationTimestamp = tonumber(getArgv())
-redis.call("ZADD", instancesByCreation, creationTimestamp, instanceSegment)
+-- add started event and initial signals
+local events = tonumber(getArgv())
+for i = 1, events do
+  redis.call("XADD", KEYS[4], "*", "event", getArgv())
+end
 
--- queue workflow task
-redis.call("SADD", workflowQueuesSet, workflowSetKey) -- track queue
-local added = redis.call("SADD", workflowSetKey, instanceSegment)
-if added == 1 then
-    redis.call("XADD", workflowStreamKey, "*", "id", instanceSegment, "data", "")
+-- Store payloads of the events, unless they are kept outside of redis
+local payloads = tonumber(getArgv())
+for i = 1, payloads do
+  local eventId = getArgv()
+  local payload = getArgv()
+  redis.call("HSETNX", KEYS[5], eventId, payload)
 end
 
-return true
\ No newline at end of file
+return true
@@ -0,0 +1,75 @@
+-- Delivers events sent by a workflow task to another instance, and creates the instance if the events start a new one.
+-- The events of a completion are only delivered once, even if the completion is executed again.
+--
+-- KEYS[1] = instance key
+-- KEYS[2] = active-instance-execution key
+-- KEYS[3] = latest-instance-execution key
+-- KEYS[4] = pending events stream of the instance
+-- KEYS[5] = payload hash of the instance
+-- KEYS[6] = deliveries hash of the instance
+-- ARGV[1] = segment of the sending instance
+-- ARGV[2] = completion id
+-- ARGV[3] = 1 if a new instance is created
+--   ARGV[4] = instance state
+--   ARGV[5] = active execution
+--   ARGV[6] = execution id
+--   ARGV[7] = execution id of the execution that continues as the new one, if any
+-- ARGV[n] = number of events, followed by the event data
+-- ARGV[m] = number of payloads to store, followed by event id and payload pairs
+--
+-- Returns 0 if the new instance could not be created because another execution is active, nil if the instance does
+-- not exist, otherwise the queue and priority of the instance.
+local argvIdx = 1
+
+local getArgv = function()
+    local argv = ARGV[argvIdx]
+    argvIdx = argvIdx + 1
+    return argv
+end
+
+local sender = getArgv()
+local completionId = getArgv()
+
+if redis.call("HGET", KEYS[6], sender) ~= completionId then
+    if tonumber(getArgv()) == 1 then
+        local instanceState = getArgv()
+        local activeExecution = getArgv()
+        local executionId = getArgv()
+        local replacedExecutionId = getArgv()
+
+        -- Is there another active execution?
+        local active = redis.call("GET", KEYS[2])
+        if active and cjson.decode(active)["execution_id"] ~= replacedExecutionId then
+            return 0
+        end
+
+        redis.call("SETNX", KEYS[1], instanceState)
+        redis.call("SET", KEYS[2], activeExecution)
+        redis.call("SET", KEYS[3], executionId)
+    elseif redis.call("EXISTS", KEYS[1]) == 0 then
+        -- Instance has expired or has been removed
+        return nil
+    end
+
+    local events = tonumber(getArgv())
+    for i = 1, events do
+        redis.call("XADD", KEYS[4], "*", "event", getArgv())
+    end
+
+    local payloads = tonumber(getArgv())
+    for i = 1, payloads do
+        local eventId = getArgv()
+        local payload = getArgv()
+        redis.call("HSETNX", KEYS[5], eventId, payload)
+    end
+
+    redis.call("HSET", KEYS[6], sender, completionId)
+end
+
+local instanceData = redis.call("GET", KEYS[1])
+if not instanceData then
+    return nil
+end
+
+local instance = cjson.decode(instanceData)
+return { instance["queue"], instance["priority"] or 0 }
@@ -1,29 +1,25 @@
--- Set the given expiration time on all keys passed in
--- KEYS[1] - instances-by-creation key
--- KEYS[2] - instances-expiring key
--- KEYS[3] - instance key
--- KEYS[4] - pending events key
--- KEYS[5] - history key
--- KEYS[6] - payload key
--- ARGV[1] - current timestamp
--- ARGV[2] - expiration time in seconds
--- ARGV[3] - expiration timestamp in unix milliseconds
--- ARGV[4] - instance segment
+-- Set the given expiration time on all keys of an instance passed in
+-- KEYS[1] - latest-instance-execution key
+-- KEYS[2] - instance key
+-- KEYS[3..n] - other instance keys to expire
+-- ARGV[1] - expiration time in seconds
+-- ARGV[2] - execution id
+--
+-- Returns the unique key of the instance, if it has one
 
--- Find instances which have already expired and remove from the index set
-local expiredInstances = redis.call("ZRANGE", KEYS[2], "-inf", ARGV[1], "BYSCORE")
-for i = 1, #expiredInstances do
-  local instanceSegment = expiredInstances[i]
-  redis.call("ZREM", KEYS[1], instanceSegment) -- index set
-  redis.call("ZREM", KEYS[2], instanceSegment) -- expiration set
+-- Set expiration on all keys
+for i = 2, #KEYS do
+  redis.call("EXPIRE", KEYS[i], ARGV[1])
 end
 
--- Add expiration time for future cleanup
-redis.call("ZADD", KEYS[2], ARGV[3], ARGV[4])
+-- Expire the latest execution with the instance, unless a newer execution has been started since
+if redis.call("GET", KEYS[1]) == ARGV[2] then
+  redis.call("EXPIRE", KEYS[1], ARGV[1])
+end
 
--- Set expiration on all keys
-for i = 3, #KEYS do
-  redis.call("EXPIRE", KEYS[i], ARGV[2])
+local instance = redis.call("GET", KEYS[2])
+if instance then
+  return cjson.decode(instance)["unique_key"]
 end
 
-return 0
+return nil
@@ -0,0 +1,28 @@
+-- Track the expiration of an instance in the indexes shared by all instances
+-- KEYS[1] - instances-by-creation key
+-- KEYS[2] - instances-expiring key
+-- KEYS[3] - instances-active key
+-- KEYS[4] - unique key of the instance, if it has one
+-- ARGV[1] - current timestamp
+-- ARGV[2] - expiration time in seconds
+-- ARGV[3] - expiration timestamp in unix milliseconds
+-- ARGV[4] - instance segment
+
+-- Find instances which have already expired and remove from the index sets
+local expiredInstances = redis.call("ZRANGE", KEYS[2], "-inf", ARGV[1], "BYSCORE")
+for i = 1, #expiredInstances do
+  local instanceSegment = expiredInstances[i]
+  redis.call("ZREM", KEYS[1], instanceSegment) -- index set
+  redis.call("ZREM", KEYS[2], instanceSegment) -- expiration set
+  redis.call("SREM", KEYS[3], instanceSegment) -- active set, for instances expired due to inactivity
+end
+
+-- Add expiration time for future cleanup
+redis.call("ZADD", KEYS[2], ARGV[3], ARGV[4])
+
+-- Expire the unique key with the instance, if it still holds it
+if KEYS[4] and redis.call("GET", KEYS[4]) == ARGV[4] then
+  redis.call("EXPIRE", KEYS[4], ARGV[2])
+end
+
+return 0
@@ -0,0 +1,29 @@
+local instanceKey = KEYS[1]
+local pendingEventsKey = KEYS[2]
+
+local lastPendingEventMessageId = ARGV[1]
+local maxFailures = tonumber(ARGV[2])
+local erroredState = tonumber(ARGV[3])
+
+-- Make sure the task has not been completed in the meantime, or the instance removed or reset
+local instanceData = redis.call("GET", instanceKey)
+if not instanceData
+    or #redis.call("XRANGE", pendingEventsKey, lastPendingEventMessageId, lastPendingEventMessageId) == 0 then
+    return redis.error_reply("ERR TaskLockLost")
+end
+
+local instance = cjson.decode(instanceData)
+
+local failures = (instance["task_failures"] or 0) + 1
+instance["task_failures"] = failures
+
+-- Pending events are kept until the instance is retried
+local errored = 0
+if maxFailures > 0 and failures >= maxFailures then
+    instance["state"] = erroredState
+    errored = 1
+end
+
+redis.call("SET", instanceKey, cjson.encode(instance), "KEEPTTL")
+
+return errored
@@ -0,0 +1,44 @@
+-- Concludes a workflow task completion after its steps outside of the instance have been executed
+--
+-- KEYS[1] = pending events stream of the instance
+-- KEYS[2] = children set of the instance
+-- KEYS[3] = pending completion of the instance
+-- ARGV[1] = completion id
+-- ARGV[2] = number of sub-workflow instances started by the completion, followed by their instance ids
+-- ARGV[n] = number of events for sub-workflow instances that could not be started, followed by the event data
+--
+-- Returns the number of pending events of the instance
+local argvIdx = 1
+
+local getArgv = function()
+    local argv = ARGV[argvIdx]
+    argvIdx = argvIdx + 1
+    return argv
+end
+
+local completionId = getArgv()
+
+-- The completion might have been concluded by another worker already
+local completion = redis.call("GET", KEYS[3])
+if completion and cjson.decode(completion)["id"] == completionId then
+    -- Index sub-workflows by the execution that started them, scored by insertion order
+    local children = tonumber(getArgv())
+    for i = 1, children do
+        local last = redis.call("ZRANGE", KEYS[2], -1, -1, "WITHSCORES")
+        local score = 0
+        if #last > 0 then
+            score = tonumber(last[2]) + 1
+        end
+        redis.call("ZADD", KEYS[2], "NX", score, getArgv())
+    end
+
+    -- Let the workflow know about sub-workflows that could not be started
+    local conflicts = tonumber(getArgv())
+    for i = 1, conflicts do
+        redis.call("XADD", KEYS[1], "*", "event", getArgv())
+    end
+
+    redis.call("DEL", KEYS[3])
+end
+
+return redis.call("XLEN", KEYS[1])
@@ -4,7 +4,6 @@
 -- KEYS[2] = stream
 -- ARGV[1] = task id
 -- ARGV[2] = group
--- We have to XACK _and_ XDEL here. See https://github.com/redis/redis/issues/5754
 local task = redis.call("XRANGE", KEYS[2], ARGV[1], ARGV[1])
 if #task == 0 then
     return nil
@@ -12,10 +11,12 @@ end
 
 local id = task[1][2][2]
 redis.call("SREM", KEYS[1], id)
-redis.call("XACK", KEYS[2], "NOMKSTREAM", ARGV[2], ARGV[1])
 
--- Delete the task here. Overall we'll keep the stream at a small size, so fragmentation
--- is not an issue for us.
+-- We have to XACK _and_ XDEL here. See https://github.com/redis/redis/issues/5754
+redis.call("XACK", KEYS[2], ARGV[2], ARGV[1])
+
+-- Delete the task here. Every consumer group has its own streams, and overall we'll keep the streams at a small size,
+-- so fragmentation is not an issue for us.
 redis.call("XDEL", KEYS[2], ARGV[1])
 
-return true
\ No newline at end of file
+return true
@@ -0,0 +1,13 @@
+-- Reads the next task from the first of the given streams that has one
+-- KEYS[1..n] = queue stream keys, ordered by priority
+-- ARGV[1] = group name
+-- ARGV[2] = consumer/worker name
+for i = 1, #KEYS do
+  local stream = KEYS[i]
+  local read = redis.call("XREADGROUP", "GROUP", ARGV[1], ARGV[2], "COUNT", 1, "STREAMS", stream, ">")
+  if read and #read > 0 and #read[1][2] > 0 then
+    return {stream, read[1][2][1]}
+  end
+end
+
+return nil
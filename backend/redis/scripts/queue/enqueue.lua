@@ -1,13 +1,21 @@
 -- KEYS[1] = queues set
 -- KEYS[2] = set
 -- KEYS[3] = stream
+-- KEYS[4] = priority streams of the queue
 -- ARGV[1] = consumer group
 -- ARGV[2] = caller provided id of the task
 -- ARGV[3] = additional data to store with the task
+-- ARGV[4] = priority of the task
 redis.call("SADD", KEYS[1], KEYS[2])
 local added = redis.call("SADD", KEYS[2], ARGV[2])
 if added == 1 then
   redis.call("XADD", KEYS[3], "*", "id", ARGV[2], "data", ARGV[3])
+
+  -- Tasks with the default priority are kept in the stream of the queue, register the streams of other priorities
+  local priority = tonumber(ARGV[4])
+  if priority ~= 0 then
+    redis.call("ZADD", KEYS[4], priority, KEYS[3])
+  end
 end
 
-return true
\ No newline at end of file
+return true
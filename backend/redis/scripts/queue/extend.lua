@@ -0,0 +1,14 @@
+-- Extends the lease of a task, if it is still locked by the given consumer
+-- KEYS[1] = stream
+-- ARGV[1] = group
+-- ARGV[2] = consumer
+-- ARGV[3] = task id
+local pending = redis.call("XPENDING", KEYS[1], ARGV[1], ARGV[3], ARGV[3], 1)
+if #pending == 0 or pending[1][2] ~= ARGV[2] then
+    return redis.error_reply("ERR TaskNotOwned")
+end
+
+-- Claiming a message resets the idle timer, and increases the retry counter
+redis.call("XCLAIM", KEYS[1], ARGV[1], ARGV[2], 0, ARGV[3])
+
+return true
@@ -0,0 +1,38 @@
+-- Return the number of tasks in the given streams the consumer group has not completed, i.e., tasks that have not
+-- been delivered yet and tasks that have been delivered but not acknowledged.
+-- KEYS[1..n] = streams
+-- ARGV[1] = group
+local lag = 0
+
+for i = 1, #KEYS do
+    local stream = KEYS[i]
+
+    if redis.call("EXISTS", stream) == 1 then
+        -- Without the group, none of the entries have been delivered
+        local streamLag = redis.call("XLEN", stream)
+
+        local groups = redis.call("XINFO", "GROUPS", stream)
+        for _, group in ipairs(groups) do
+            local info = {}
+            for j = 1, #group, 2 do
+                info[group[j]] = group[j + 1]
+            end
+
+            if info["name"] == ARGV[1] then
+                -- The lag of the group is not reported before Redis 7, or when it cannot be determined. Without the
+                -- number of entries read, the reported lag is not reliable either, so count the entries after the
+                -- last delivered one instead.
+                if info["lag"] and info["entries-read"] then
+                    streamLag = info["lag"] + info["pending"]
+                else
+                    local undelivered = redis.call("XRANGE", stream, "(" .. info["last-delivered-id"], "+")
+                    streamLag = #undelivered + info["pending"]
+                end
+            end
+        end
+
+        lag = lag + streamLag
+    end
+end
+
+return lag
@@ -1,16 +1,16 @@
--- KEYS[1..n] = queue stream keys
+-- KEYS[1..n] = queue stream keys, ordered by priority
 -- ARGV[1] = group name
 -- ARGV[2] = consumer/worker name
 -- ARGV[3] = min-idle time in ms
 -- ARGV[4] = start
 
--- Try to recover abandoned tasks
+-- Try to recover abandoned tasks, returns the stream and the recovered task
 for i = 1, #KEYS do
   local stream = KEYS[i]
   local recovered = redis.call("XAUTOCLAIM", stream, ARGV[1], ARGV[2], ARGV[3], ARGV[4], "COUNT", 1)
-  if #recovered > 0 then
-      if #recovered[1] > 0 then
-        return recovered
-      end
+  if #recovered > 1 and #recovered[2] > 0 and recovered[2][1] then
+    return {stream, recovered[2][1]}
   end
-end
\ No newline at end of file
+end
+
+return nil
@@ -0,0 +1,40 @@
+-- Registers a new workflow instance with the indexes shared by all instances
+--
+-- KEYS[1] = instances-active set
+-- KEYS[2] = instances-by-creation set
+-- KEYS[3] = unique key, if ARGV[4] is 1
+-- KEYS[n..] = tag sets
+-- ARGV[1] = instance segment
+-- ARGV[2] = maximum number of active instances
+-- ARGV[3] = creation timestamp
+-- ARGV[4] = 1 if the instance has a unique key
+local instanceSegment = ARGV[1]
+local maxActiveInstances = tonumber(ARGV[2])
+local creationTimestamp = tonumber(ARGV[3])
+
+-- Is the maximum number of active instances reached?
+if maxActiveInstances > 0 and redis.call("SCARD", KEYS[1]) >= maxActiveInstances then
+  return redis.error_reply("ERR MaxActiveInstances")
+end
+
+local tagsIdx = 3
+
+-- Is there an active instance holding the unique key?
+if tonumber(ARGV[4]) == 1 then
+  if redis.call("SET", KEYS[3], instanceSegment, "NX") == false then
+    return redis.error_reply("ERR InstanceAlreadyExists")
+  end
+
+  tagsIdx = 4
+end
+
+-- Track active instance
+redis.call("SADD", KEYS[1], instanceSegment)
+redis.call("ZADD", KEYS[2], creationTimestamp, instanceSegment)
+
+-- Track the instance for each of its tags
+for i = tagsIdx, #KEYS do
+  redis.call("ZADD", KEYS[i], creationTimestamp, instanceSegment)
+end
+
+return true
@@ -0,0 +1,120 @@
+local keyIdx = 1
+local argvIdx = 1
+
+local getKey = function()
+    local key = KEYS[keyIdx]
+    keyIdx = keyIdx + 1
+    return key
+end
+
+local getArgv = function()
+    local argv = ARGV[argvIdx]
+    argvIdx = argvIdx + 1
+    return argv
+end
+
+local instanceKey = getKey()
+local historyStreamKey = getKey()
+local pendingEventsKey = getKey()
+local activeInstanceExecutionKey = getKey()
+local latestInstanceExecutionKey = getKey()
+local scheduledActivitiesKey = getKey()
+local resetKey = getKey()
+
+local executionId = getArgv()
+local sequenceId = tonumber(getArgv())
+local activeInstance = getArgv()
+local activeState = tonumber(getArgv())
+local pendingReset = getArgv()
+
+local instanceData = redis.call("GET", instanceKey)
+if not instanceData then
+    return redis.error_reply("ERR InstanceNotFound")
+end
+
+local instance = cjson.decode(instanceData)
+
+-- Make sure there is no other active execution for this instance
+local activeExecution = redis.call("GET", activeInstanceExecutionKey)
+if activeExecution and cjson.decode(activeExecution)["execution_id"] ~= executionId then
+    return redis.error_reply("ERR InstanceAlreadyExists")
+end
+
+if #redis.call("XRANGE", historyStreamKey, sequenceId, sequenceId) == 0 then
+    return redis.error_reply("ERR InvalidResetPoint")
+end
+
+-- Schedule event IDs of work scheduled after the reset point
+local discard = {}
+local discardCount = tonumber(getArgv())
+for i = 1, discardCount do
+    local scheduleEventId = tonumber(getArgv())
+    discard[scheduleEventId] = true
+
+    -- Activities are enqueued again when the workflow schedules them again
+    redis.call("SREM", scheduledActivitiesKey, scheduleEventId)
+end
+
+-- Remove history after the reset point. Entries are added with their sequence IDs as stream IDs, which have to be
+-- greater than the stream's last ID even after entries were deleted, so the stream is rebuilt from the kept entries.
+local kept = redis.call("XRANGE", historyStreamKey, "-", sequenceId)
+redis.call("DEL", historyStreamKey)
+for _, entry in ipairs(kept) do
+    redis.call("XADD", historyStreamKey, entry[1], unpack(entry[2]))
+end
+
+-- Replace the pending events, dropping the ones for work scheduled after the reset point. The remaining events are
+-- added again with new IDs, so that a workflow task that is currently being processed cannot be completed anymore.
+local pending = redis.call("XRANGE", pendingEventsKey, "-", "+")
+for _, entry in ipairs(pending) do
+    redis.call("XDEL", pendingEventsKey, entry[1])
+end
+
+for _, entry in ipairs(pending) do
+    local event = cjson.decode(entry[2][2])
+    if not (event["seid"] and discard[event["seid"]]) then
+        redis.call("XADD", pendingEventsKey, "*", "event", entry[2][2])
+    end
+end
+
+-- Add events to be redelivered and the reset event, payloads have already been stored
+local pendingEvents = tonumber(getArgv())
+for i = 1, pendingEvents do
+    redis.call("XADD", pendingEventsKey, "*", "event", getArgv())
+end
+
+-- Remove timers scheduled after the reset point, they are removed from the set of future events afterwards
+local timers = tonumber(getArgv())
+for i = 1, timers do
+    redis.call("DEL", getKey())
+end
+
+-- Keep the instance from expiring
+local persistKeys = tonumber(getArgv())
+for i = 1, persistKeys do
+    redis.call("PERSIST", getKey())
+end
+
+-- Store payloads of the new pending events, unless they are kept outside of redis
+local payloadKey = getKey()
+local payloads = tonumber(getArgv())
+for i = 1, payloads do
+    local eventId = getArgv()
+    local payload = getArgv()
+    redis.call("HSETNX", payloadKey, eventId, payload)
+end
+
+-- Re-activate the instance
+instance["state"] = activeState
+instance["completed_at"] = nil
+instance["last_sequence_id"] = sequenceId
+instance["task_failures"] = nil
+redis.call("SET", instanceKey, cjson.encode(instance))
+
+redis.call("SET", activeInstanceExecutionKey, activeInstance)
+redis.call("SET", latestInstanceExecutionKey, executionId)
+
+-- Store the steps updating the keys shared by all instances, they are executed afterwards
+redis.call("SET", resetKey, pendingReset)
+
+return true
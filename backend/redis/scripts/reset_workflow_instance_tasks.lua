@@ -0,0 +1,81 @@
+local keyIdx = 1
+local argvIdx = 1
+
+local getKey = function()
+    local key = KEYS[keyIdx]
+    keyIdx = keyIdx + 1
+    return key
+end
+
+local getArgv = function()
+    local argv = ARGV[argvIdx]
+    argvIdx = argvIdx + 1
+    return argv
+end
+
+local activeInstancesKey = getKey()
+local expiringInstancesKey = getKey()
+local futureEventZSetKey = getKey()
+local workflowSetKey = getKey()
+local workflowStreamKey = getKey()
+
+local instanceSegment = getArgv()
+local instanceId = getArgv()
+local executionId = getArgv()
+local groupName = getArgv()
+
+-- Schedule event IDs of work scheduled after the reset point
+local discard = {}
+local discardCount = tonumber(getArgv())
+for i = 1, discardCount do
+    discard[tonumber(getArgv())] = true
+end
+
+-- Remove timers scheduled after the reset point
+local timers = tonumber(getArgv())
+for i = 1, timers do
+    redis.call("ZREM", futureEventZSetKey, getArgv())
+end
+
+-- Remove queued activities scheduled after the reset point. Activities that are currently executing cannot be
+-- completed anymore, their task has been removed. Tasks are removed for all consumer groups, not only acknowledged.
+local activityQueues = tonumber(getArgv())
+for i = 1, activityQueues do
+    local activitySetKey = getKey()
+    local activityStreamKey = getKey()
+
+    local tasks = redis.call("XRANGE", activityStreamKey, "-", "+")
+    for _, task in ipairs(tasks) do
+        local data = cjson.decode(task[2][4])
+        local taskInstance = data["instance"]
+        if taskInstance and taskInstance["instance_id"] == instanceId and taskInstance["execution_id"] == executionId
+            and discard[data["event"]["seid"]] then
+            redis.call("SREM", activitySetKey, task[2][2])
+            redis.call("XACK", activityStreamKey, groupName, task[1])
+            redis.call("XDEL", activityStreamKey, task[1])
+        end
+    end
+end
+
+-- Remove the task of the instance, a new one is queued to re-execute the workflow
+local workflowTasks = redis.call("XRANGE", workflowStreamKey, "-", "+")
+for _, task in ipairs(workflowTasks) do
+    if task[2][2] == instanceSegment then
+        redis.call("XACK", workflowStreamKey, groupName, task[1])
+        redis.call("XDEL", workflowStreamKey, task[1])
+    end
+end
+redis.call("SREM", workflowSetKey, instanceSegment)
+
+-- Keep the instance from expiring
+redis.call("ZREM", expiringInstancesKey, instanceSegment)
+
+-- Hand the unique key back to the instance
+local uniqueKey = getKey()
+if uniqueKey then
+    redis.call("SET", uniqueKey, instanceSegment)
+end
+
+redis.call("SADD", activeInstancesKey, instanceSegment)
+
+return true
@@ -0,0 +1,20 @@
+local instanceKey = KEYS[1]
+
+local erroredState = tonumber(ARGV[1])
+local activeState = tonumber(ARGV[2])
+
+local instanceData = redis.call("GET", instanceKey)
+if not instanceData then
+    return redis.error_reply("ERR InstanceNotFound")
+end
+
+local instance = cjson.decode(instanceData)
+if instance["state"] ~= erroredState then
+    return redis.error_reply("ERR InstanceNotErrored")
+end
+
+instance["state"] = activeState
+instance["task_failures"] = nil
+redis.call("SET", instanceKey, cjson.encode(instance), "KEEPTTL")
+
+return true
@@ -1,39 +1,29 @@
--- Find all due future events. For each event:
--- - Look up event data
--- - Add to pending event stream for workflow instance
--- - Try to queue workflow task for workflow instance
--- - Remove event from future event set and delete event data
+-- Moves the due future events of a workflow instance to its pending events
 --
--- KEYS[1] - future event set key
--- ARGV[1] - current timestamp for zrange
--- ARGV[2] - redis key prefix
+-- KEYS[1] - instance key
+-- KEYS[2] - pending events stream of the instance
+-- KEYS[3..n] - future event hashes of the due events
 --
--- Note: this does not work with Redis Cluster since not all keys are passed into the script.
--- Find events which should become visible now
-local now = ARGV[1]
-local events = redis.call("ZRANGE", KEYS[1], "-inf", now, "BYSCORE")
-local prefix = ARGV[2]
-for i = 1, #events do
-  local instanceSegment = redis.call("HGET", events[i], "instance")
-  local queue = redis.call("HGET", events[i], "queue")
-
-  local setKey = prefix .. "task-set:" .. queue .. ":workflows"
-  local streamKey = prefix .. "task-stream:" .. queue .. ":workflows"
-
-  -- Try to queue workflow task. If a workflow task is already queued, ignore this event for now.
-  local added = redis.call("SADD", setKey, instanceSegment)
-  if added == 1 then
-    redis.call("XADD", streamKey, "*", "id", instanceSegment, "data", "")
+-- Returns nil if the instance does not exist anymore, otherwise the queue and priority of the instance and the
+-- number of its pending events
+local instanceData = redis.call("GET", KEYS[1])
+if not instanceData then
+  -- Instance has expired or has been removed, drop its events
+  for i = 3, #KEYS do
+    redis.call("DEL", KEYS[i])
+  end
 
-    -- Add event to pending event stream
-    local eventData = redis.call("HGET", events[i], "event")
-    local pending_events_key = prefix .. "pending-events:" .. instanceSegment
-    redis.call("XADD", pending_events_key, "*", "event", eventData)
+  return nil
+end
 
-    -- Delete event hash data
-    redis.call("DEL", events[i])
-    redis.call("ZREM", KEYS[1], events[i])
+for i = 3, #KEYS do
+  -- Events might have been moved already, or their timer has been canceled
+  local eventData = redis.call("HGET", KEYS[i], "event")
+  if eventData then
+    redis.call("XADD", KEYS[2], "*", "event", eventData)
+    redis.call("DEL", KEYS[i])
   end
 end
 
-return #events
+local instance = cjson.decode(instanceData)
+return { instance["queue"], instance["priority"] or 0, redis.call("XLEN", KEYS[2]) }
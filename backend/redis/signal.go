@@ -7,7 +7,6 @@ import (
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/workflow"
-	"github.com/redis/go-redis/v9"
 )
 
 func (rb *redisBackend) SignalWorkflow(ctx context.Context, instanceID string, event *history.Event) error {
@@ -26,15 +25,11 @@ func (rb *redisBackend) SignalWorkflow(ctx context.Context, instanceID string, e
 		return err
 	}
 
-	if _, err = rb.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
-		if err := rb.addWorkflowInstanceEventP(ctx, p, workflow.Queue(instanceState.Queue), instanceState.Instance, event); err != nil {
-			return fmt.Errorf("adding event to stream: %w", err)
-		}
-
-		return nil
-	}); err != nil {
-		return err
+	if err := rb.addWorkflowInstanceEvent(ctx, workflow.Queue(instanceState.Queue), instanceState.Priority, instanceState.Instance, event); err != nil {
+		return fmt.Errorf("adding event to stream: %w", err)
 	}
 
+	rb.checkPendingEvents(ctx, instanceState.Instance)
+
 	return nil
 }
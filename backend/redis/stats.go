@@ -38,3 +38,19 @@ func (rb *redisBackend) GetStats(ctx context.Context) (*backend.Stats, error) {
 
 	return s, nil
 }
+
+// QueueLag returns the lag of the consumer group of this backend, computed from XINFO GROUPS and the length of the
+// task streams
+func (rb *redisBackend) QueueLag(ctx context.Context) (int64, int64, error) {
+	workflowLag, err := rb.workflowQueue.Lag(ctx, rb.rdb)
+	if err != nil {
+		return 0, 0, fmt.Errorf("getting workflow queue lag: %w", err)
+	}
+
+	activityLag, err := rb.activityQueue.Lag(ctx, rb.rdb)
+	if err != nil {
+		return 0, 0, fmt.Errorf("getting activity queue lag: %w", err)
+	}
+
+	return workflowLag, activityLag, nil
+}
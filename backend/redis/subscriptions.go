@@ -0,0 +1,152 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/redis/go-redis/v9"
+)
+
+type instanceUpdate struct {
+	Instance  *core.WorkflowInstance     `json:"instance"`
+	State     core.WorkflowInstanceState `json:"state"`
+	Timestamp time.Time                  `json:"timestamp"`
+}
+
+// publishInstanceUpdate notifies subscribers of the instance. Errors are only logged, subscribers re-check the
+// instance state after reconnecting so a lost update does not hide completion.
+func (rb *redisBackend) publishInstanceUpdate(ctx context.Context, instance *core.WorkflowInstance, state core.WorkflowInstanceState) {
+	data, err := json.Marshal(&instanceUpdate{
+		Instance:  instance,
+		State:     state,
+		Timestamp: rb.options.Clock.Now(),
+	})
+	if err != nil {
+		rb.options.Logger.Error("marshaling instance update", log.ErrorKey, err)
+		return
+	}
+
+	if err := rb.rdb.Publish(ctx, rb.keys.instanceUpdatesChannel(instance.InstanceID), data).Err(); err != nil {
+		rb.options.Logger.Error("publishing instance update", log.InstanceIDKey, instance.InstanceID, log.ErrorKey, err)
+	}
+}
+
+func (rb *redisBackend) SubscribeWorkflowInstanceUpdates(ctx context.Context, instanceID string) (<-chan *backend.WorkflowInstanceUpdate, error) {
+	ps := rb.rdb.Subscribe(ctx, rb.keys.instanceUpdatesChannel(instanceID))
+
+	// Wait for the subscription to be established, so that no update published after the state check below is missed
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, fmt.Errorf("subscribing to instance updates: %w", err)
+	}
+
+	latest, err := rb.latestInstanceUpdate(ctx, instanceID)
+	if err != nil {
+		ps.Close()
+		return nil, err
+	}
+
+	c := make(chan *backend.WorkflowInstanceUpdate, 1)
+
+	go func() {
+		defer close(c)
+		defer ps.Close()
+
+		if latest.State == core.WorkflowInstanceStateFinished {
+			c <- latest
+			return
+		}
+
+		// Receive subscription confirmations as well, they are sent again after a reconnect
+		msgs := ps.ChannelWithSubscriptions()
+
+		for {
+			var update *backend.WorkflowInstanceUpdate
+
+			select {
+			case <-ctx.Done():
+				return
+
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+
+				switch msg := msg.(type) {
+				case *redis.Subscription:
+					// Reconnected, updates might have been published while disconnected. Check whether the
+					// latest execution has finished in the meantime.
+					latest, err := rb.latestInstanceUpdate(ctx, instanceID)
+					if err != nil {
+						rb.options.Logger.Error("reading instance state after reconnect", log.InstanceIDKey, instanceID, log.ErrorKey, err)
+						continue
+					}
+
+					if latest.State != core.WorkflowInstanceStateFinished {
+						continue
+					}
+
+					update = latest
+
+				case *redis.Message:
+					var u instanceUpdate
+					if err := json.Unmarshal([]byte(msg.Payload), &u); err != nil {
+						rb.options.Logger.Error("unmarshaling instance update", log.InstanceIDKey, instanceID, log.ErrorKey, err)
+						continue
+					}
+
+					update = &backend.WorkflowInstanceUpdate{
+						Instance:  u.Instance,
+						State:     u.State,
+						Timestamp: u.Timestamp,
+					}
+
+				default:
+					continue
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case c <- update:
+			}
+
+			if update.State == core.WorkflowInstanceStateFinished {
+				return
+			}
+		}
+	}()
+
+	return c, nil
+}
+
+func (rb *redisBackend) latestInstanceUpdate(ctx context.Context, instanceID string) (*backend.WorkflowInstanceUpdate, error) {
+	instance, err := rb.GetLatestWorkflowInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := readInstance(ctx, rb.rdb, rb.keys.instanceKey(instance))
+	if err != nil {
+		return nil, err
+	}
+
+	update := &backend.WorkflowInstanceUpdate{
+		Instance: instance,
+		State:    state.State,
+	}
+
+	if state.CompletedAt != nil {
+		update.Timestamp = *state.CompletedAt
+	} else if state.LastActivityAt != nil {
+		update.Timestamp = *state.LastActivityAt
+	}
+
+	return update, nil
+}
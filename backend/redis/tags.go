@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+)
+
+func (rb *redisBackend) GetWorkflowInstancesByTag(ctx context.Context, tag string) ([]*backend.TaggedWorkflowInstance, error) {
+	tagKey := rb.keys.tagKey(tag)
+
+	segments, err := rb.rdb.ZRange(ctx, tagKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("getting tagged instances: %w", err)
+	}
+
+	if len(segments) == 0 {
+		return nil, nil
+	}
+
+	instanceKeys := make([]string, len(segments))
+	for i, segment := range segments {
+		instanceKeys[i] = rb.keys.instanceKeyFromSegment(segment)
+	}
+
+	states, err := getKeys(ctx, rb.rdb, instanceKeys)
+	if err != nil {
+		return nil, fmt.Errorf("getting instances: %w", err)
+	}
+
+	instances := make([]*backend.TaggedWorkflowInstance, 0, len(segments))
+	removed := make([]interface{}, 0)
+
+	for i, s := range states {
+		str, ok := s.(string)
+		if !ok {
+			// Instance has expired or has been removed
+			removed = append(removed, segments[i])
+			continue
+		}
+
+		var state instanceState
+		if err := json.Unmarshal([]byte(str), &state); err != nil {
+			return nil, fmt.Errorf("unmarshaling instance state: %w", err)
+		}
+
+		instances = append(instances, &backend.TaggedWorkflowInstance{
+			Instance:    state.Instance,
+			State:       state.State,
+			CreatedAt:   state.CreatedAt,
+			CompletedAt: state.CompletedAt,
+		})
+	}
+
+	// Tag sets are not updated when instances expire or are removed, clean them up lazily
+	if len(removed) > 0 {
+		if err := rb.rdb.ZRem(ctx, tagKey, removed...).Err(); err != nil {
+			rb.Options().Logger.WarnContext(ctx, "removing instances from tag", "tag", tag, "error", err)
+		}
+	}
+
+	return instances, nil
+}
@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+const (
+	usageWorkflowTasks    = "workflow_tasks"
+	usageActivities       = "activities"
+	usageActivityDuration = "activity_duration"
+	usageTimers           = "timers"
+	usageHistoryEvents    = "history_events"
+)
+
+// workflowInstanceUsageArgs returns the script arguments for adding the usage of a completed workflow task to the
+// usage of its execution: the number of counters followed by field and increment pairs.
+func workflowInstanceUsageArgs(usage *backend.WorkflowInstanceUsage) []interface{} {
+	return []interface{}{
+		5,
+		usageWorkflowTasks, usage.WorkflowTasks,
+		usageActivities, usage.Activities,
+		usageActivityDuration, int64(usage.ActivityDuration),
+		usageTimers, usage.Timers,
+		usageHistoryEvents, usage.HistoryEvents,
+	}
+}
+
+func (rb *redisBackend) GetWorkflowInstanceUsage(ctx context.Context, instance *workflow.Instance) (*backend.WorkflowInstanceUsage, error) {
+	p := rb.rdb.Pipeline()
+	existsCmd := p.Exists(ctx, rb.keys.instanceKey(instance))
+	usageCmd := p.HGetAll(ctx, rb.keys.usageKey(instance))
+
+	if _, err := p.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("getting usage: %w", err)
+	}
+
+	if existsCmd.Val() == 0 {
+		return nil, backend.ErrInstanceNotFound
+	}
+
+	values := usageCmd.Val()
+	counter := func(field string) int64 {
+		// Missing fields mean the execution has not used the resource yet
+		v, _ := strconv.ParseInt(values[field], 10, 64)
+		return v
+	}
+
+	return &backend.WorkflowInstanceUsage{
+		WorkflowTasks:    counter(usageWorkflowTasks),
+		Activities:       counter(usageActivities),
+		ActivityDuration: time.Duration(counter(usageActivityDuration)),
+		Timers:           counter(usageTimers),
+		HistoryEvents:    counter(usageHistoryEvents),
+	}, nil
+}
@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/cschleiden/go-workflows/internal/propagators"
 	"github.com/cschleiden/go-workflows/internal/workflowerrors"
 	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -22,32 +24,83 @@ func (rb *redisBackend) PrepareWorkflowQueues(ctx context.Context, queues []work
 }
 
 func (rb *redisBackend) GetWorkflowTask(ctx context.Context, queues []workflow.Queue) (*backend.WorkflowTask, error) {
+	var task *backend.WorkflowTask
+	err := rb.retry(ctx, "GetWorkflowTask", func(int) error {
+		var err error
+		task, err = rb.getWorkflowTask(ctx, queues)
+		return err
+	})
+
+	return task, err
+}
+
+func (rb *redisBackend) getWorkflowTask(ctx context.Context, queues []workflow.Queue) (*backend.WorkflowTask, error) {
 	if err := scheduleFutureEvents(ctx, rb); err != nil {
-		return nil, fmt.Errorf("scheduling future events: %w", err)
+		return nil, wrapBusyError(fmt.Errorf("scheduling future events: %w", err))
 	}
 
 	// Try to get a workflow task, this locks the instance when it dequeues one
 	instanceTask, err := rb.workflowQueue.Dequeue(ctx, rb.rdb, queues, rb.options.WorkflowLockTimeout, rb.options.BlockTimeout)
 	if err != nil {
-		return nil, err
+		return nil, wrapBusyError(err)
 	}
 
 	if instanceTask == nil {
 		return nil, nil
 	}
 
-	instanceState, err := readInstance(ctx, rb.rdb, rb.keys.instanceKeyFromSegment(instanceTask.ID))
+	instance := instanceFromSegment(instanceTask.ID)
+
+	// Steps of the previous completion might not have been executed, for example if the worker completing it crashed
+	completion, err := rb.executePendingCompletion(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	if completion != nil && completion.TaskID == instanceTask.TaskID {
+		// This task has been completed already
+		return nil, nil
+	}
+
+	// The last reset of the instance might not have been finished. Finishing it replaces this task with a new one.
+	reset, err := rb.executePendingReset(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	if reset {
+		return nil, nil
+	}
+
+	instanceState, err := readInstance(ctx, rb.rdb, rb.keys.instanceKey(instance))
 	if err != nil {
 		return nil, fmt.Errorf("reading workflow instance: %w", err)
 	}
 
+	// Events for errored instances are kept, but they are not executed until the instance is retried
+	if instanceState.State == core.WorkflowInstanceStateErrored {
+		if err := rb.releaseWorkflowTask(ctx, instance, workflow.Queue(instanceState.Queue), instanceTask.Priority, instanceTask.TaskID); err != nil {
+			return nil, fmt.Errorf("dropping task of errored workflow instance: %w", err)
+		}
+
+		return nil, nil
+	}
+
 	// Read all pending events for this instance
 	msgs, err := rb.rdb.XRange(ctx, rb.keys.pendingEventsKey(instanceState.Instance), "-", "+").Result()
 	if err != nil {
 		return nil, fmt.Errorf("reading event stream: %w", err)
 	}
 
-	payloadKeys := make([]string, 0, len(msgs))
+	// Events might have been processed by a task that was queued while they were delivered
+	if len(msgs) == 0 {
+		if err := rb.releaseWorkflowTask(ctx, instance, workflow.Queue(instanceState.Queue), instanceTask.Priority, instanceTask.TaskID); err != nil {
+			return nil, fmt.Errorf("dropping workflow task without pending events: %w", err)
+		}
+
+		return nil, nil
+	}
+
 	newEvents := make([]*history.Event, 0, len(msgs))
 	for _, msg := range msgs {
 		var event *history.Event
@@ -56,23 +109,12 @@ func (rb *redisBackend) GetWorkflowTask(ctx context.Context, queues []workflow.Q
 			return nil, fmt.Errorf("unmarshaling event: %w", err)
 		}
 
-		payloadKeys = append(payloadKeys, event.ID)
 		newEvents = append(newEvents, event)
 	}
 
 	// Fetch event payloads
-	if len(payloadKeys) > 0 {
-		res, err := rb.rdb.HMGet(ctx, rb.keys.payloadKey(instanceState.Instance), payloadKeys...).Result()
-		if err != nil {
-			return nil, fmt.Errorf("reading payloads: %w", err)
-		}
-
-		for i, event := range newEvents {
-			event.Attributes, err = history.DeserializeAttributes(event.Type, []byte(res[i].(string)))
-			if err != nil {
-				return nil, fmt.Errorf("deserializing attributes for event %v: %w", event.Type, err)
-			}
-		}
+	if err := rb.loadEventPayloads(ctx, instanceState.Instance, newEvents, history.DeserializeAttributes); err != nil {
+		return nil, err
 	}
 
 	return &backend.WorkflowTask{
@@ -83,16 +125,118 @@ func (rb *redisBackend) GetWorkflowTask(ctx context.Context, queues []workflow.Q
 		Metadata:              instanceState.Metadata,
 		LastSequenceID:        instanceState.LastSequenceID,
 		NewEvents:             newEvents,
-		CustomData:            msgs[len(msgs)-1].ID, // Id of last pending message in stream at this point
+		CustomData: &workflowTaskData{
+			LastPendingEventMessageID: msgs[len(msgs)-1].ID,
+			Priority:                  instanceTask.Priority,
+			InstancePriority:          instanceState.Priority,
+			UniqueKey:                 instanceState.UniqueKey,
+			Tags:                      instanceState.Tags,
+		},
 	}, nil
 }
 
+// workflowTaskData is kept with a workflow task until it is completed
+type workflowTaskData struct {
+	// LastPendingEventMessageID is the ID of the last pending event in the stream when the task was dequeued
+	LastPendingEventMessageID string
+
+	// Priority is the priority of the stream the task was read from
+	Priority int
+
+	// InstancePriority is the priority of the workflow instance
+	InstancePriority int
+
+	// UniqueKey and Tags of the instance, they are handed over to the new execution when continuing as new
+	UniqueKey string
+	Tags      []string
+}
+
+// releaseWorkflowTask completes the given task without executing it. If the instance is not errored and has pending
+// events, it's queued again, it might have been retried while the task was being released.
+func (rb *redisBackend) releaseWorkflowTask(ctx context.Context, instance *core.WorkflowInstance, queue workflow.Queue, priority int, taskID string) error {
+	p := rb.rdb.Pipeline()
+	if _, err := rb.workflowQueue.CompleteWithPriority(ctx, p, queue, priority, taskID); err != nil {
+		return err
+	}
+
+	// The task might have been completed already
+	if _, err := p.Exec(ctx); err != nil && err != redis.Nil {
+		return fmt.Errorf("completing workflow task: %w", err)
+	}
+
+	p = rb.rdb.Pipeline()
+	instanceCmd := readInstanceP(ctx, p, rb.keys.instanceKey(instance))
+	pendingCmd := p.XLen(ctx, rb.keys.pendingEventsKey(instance))
+
+	// Errors are checked when checking the cmds
+	_, _ = p.Exec(ctx)
+
+	instanceState, err := readInstancePipelineCmd(instanceCmd)
+	if err != nil {
+		if errors.Is(err, backend.ErrInstanceNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	pending, err := pendingCmd.Result()
+	if err != nil {
+		return fmt.Errorf("reading pending events: %w", err)
+	}
+
+	if instanceState.State == core.WorkflowInstanceStateErrored || pending == 0 {
+		return nil
+	}
+
+	p = rb.rdb.Pipeline()
+	if err := rb.workflowQueue.Enqueue(ctx, p, workflow.Queue(instanceState.Queue), instanceState.Priority, instanceSegment(instance), nil); err != nil {
+		return fmt.Errorf("queueing workflow: %w", err)
+	}
+
+	if _, err := p.Exec(ctx); err != nil {
+		return fmt.Errorf("queueing workflow: %w", err)
+	}
+
+	return nil
+}
+
 func (rb *redisBackend) ExtendWorkflowTask(ctx context.Context, task *backend.WorkflowTask) error {
-	_, err := rb.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
-		return rb.workflowQueue.Extend(ctx, p, task.Queue, task.ID)
+	return rb.retry(ctx, "ExtendWorkflowTask", func(int) error {
+		// Claiming the task always succeeds, only extend it if it has not been picked up by another worker in the meantime
+		err := rb.workflowQueue.ExtendOwned(ctx, rb.rdb, task.Queue, task.CustomData.(*workflowTaskData).Priority, task.ID)
+		if errors.Is(err, errTaskNotOwned) {
+			return backend.ErrTaskLockLost
+		}
+
+		return err
 	})
+}
+
+func (rb *redisBackend) FailWorkflowTask(ctx context.Context, task *backend.WorkflowTask, maxFailures int) error {
+	taskData := task.CustomData.(*workflowTaskData)
+
+	// Not retried, an attempt that failed with a transient error might have counted the failure already
+	errored, err := failWorkflowTaskCmd.Run(ctx, rb.rdb,
+		[]string{rb.keys.instanceKey(task.WorkflowInstance), rb.keys.pendingEventsKey(task.WorkflowInstance)},
+		taskData.LastPendingEventMessageID, maxFailures, int(core.WorkflowInstanceStateErrored),
+	).Int()
+	if err != nil {
+		if redis.HasErrorPrefix(err, "TaskLockLost") {
+			return backend.ErrTaskLockLost
+		}
+
+		return fmt.Errorf("recording failed workflow task: %w", err)
+	}
+
+	// Release the task without queueing it again, pending events are kept until the instance is retried
+	if errored == 1 {
+		if err := rb.releaseWorkflowTask(ctx, task.WorkflowInstance, task.Queue, taskData.Priority, task.ID); err != nil {
+			return fmt.Errorf("releasing failed workflow task: %w", err)
+		}
+	}
 
-	return err
+	return nil
 }
 
 func (rb *redisBackend) CompleteWorkflowTask(
@@ -102,60 +246,76 @@ func (rb *redisBackend) CompleteWorkflowTask(
 	executedEvents, activityEvents, timerEvents []*history.Event,
 	workflowEvents []*history.WorkflowEvent,
 ) error {
-	keys := make([]string, 0)
-	args := make([]interface{}, 0)
-
 	instance := task.WorkflowInstance
+	taskData := task.CustomData.(*workflowTaskData)
+
+	now := rb.options.Clock.Now().UTC()
+	nowStr := now.Format(time.RFC3339)
+
+	// Steps affecting other instances and the keys shared by all instances are executed after the task has been
+	// committed to the instance
+	completion := &pendingCompletion{
+		ID:          uuid.NewString(),
+		TaskID:      task.ID,
+		Queue:       task.Queue,
+		Priority:    taskData.Priority,
+		Instance:    instance,
+		State:       state,
+		UniqueKey:   taskData.UniqueKey,
+		Tags:        taskData.Tags,
+		CompletedAt: now.Unix(),
+	}
 
-	queueKeys := rb.workflowQueue.Keys(task.Queue)
-	keys = append(keys,
+	keys := []string{
 		rb.keys.instanceKey(instance),
 		rb.keys.historyKey(instance),
 		rb.keys.pendingEventsKey(instance),
+		rb.keys.activeInstanceExecutionKey(instance.InstanceID),
+		rb.keys.scheduledActivitiesKey(instance),
+		rb.keys.progressKey(instance),
 		rb.keys.payloadKey(instance),
-		rb.keys.futureEventsKey(),
-		rb.keys.instancesActive(),
-		rb.keys.instancesByCreation(),
-		queueKeys.SetKey,
-		queueKeys.StreamKey,
-		rb.workflowQueue.queueSetKey,
-	)
-	args = append(args, rb.keys.prefix, instanceSegment(instance))
+		rb.keys.usageKey(instance),
+		rb.keys.completionKey(instance),
+	}
+
+	// Make sure the executed pending events have not been removed in the meantime, and remove them
+	args := []interface{}{taskData.LastPendingEventMessageID}
+
+	// Store progress together with the task completion
+	if task.Progress != nil {
+		args = append(args, 1, []byte(task.Progress))
+	} else {
+		args = append(args, 0, "")
+	}
+
+	// Add the usage of this task to the usage of the instance
+	args = append(args, workflowInstanceUsageArgs(backend.WorkflowTaskUsage(executedEvents))...)
+
+	// Payloads of all events written by this task
+	payloadEvents := map[core.WorkflowInstance][]*history.Event{
+		*instance: append(append([]*history.Event{}, executedEvents...), timerEvents...),
+	}
 
 	// Add executed events to the history
 	args = append(args, len(executedEvents))
 
 	for _, event := range executedEvents {
-		eventData, err := marshalEventWithoutAttributes(event)
+		eventData, err := marshalHistoryEvent(event)
 		if err != nil {
 			return fmt.Errorf("marshaling event: %w", err)
 		}
 
-		payloadData, err := json.Marshal(event.Attributes)
-		if err != nil {
-			return fmt.Errorf("marshaling event payload: %w", err)
-		}
-
-		args = append(args, event.ID, eventData, payloadData, event.SequenceID)
+		args = append(args, eventData, event.SequenceID)
 	}
 
-	// Remove executed pending events
-	lastPendingEventMessageID := task.CustomData.(string)
-	args = append(args, lastPendingEventMessageID)
-
 	// Update instance state and update active execution
-	now := time.Now().UTC()
-	nowStr := now.Format(time.RFC3339)
-	nowUnix := now.Unix()
 	args = append(
 		args,
-		string(nowStr),
-		nowUnix,
+		nowStr,
 		int(state),
 		int(core.WorkflowInstanceStateContinuedAsNew),
 		int(core.WorkflowInstanceStateFinished),
 	)
-	keys = append(keys, rb.keys.activeInstanceExecutionKey(instance.InstanceID))
 
 	// Remove canceled timers
 	timersToCancel := make([]*history.Event, 0)
@@ -169,6 +329,7 @@ func (rb *redisBackend) CompleteWorkflowTask(
 	args = append(args, len(timersToCancel))
 	for _, event := range timersToCancel {
 		keys = append(keys, rb.keys.futureEventKey(instance, event.ScheduleEventID))
+		completion.CanceledTimers = append(completion.CanceledTimers, event.ScheduleEventID)
 	}
 
 	// Schedule timers
@@ -179,13 +340,24 @@ func (rb *redisBackend) CompleteWorkflowTask(
 			return fmt.Errorf("marshaling event: %w", err)
 		}
 
-		payloadEventData, err := json.Marshal(timerEvent.Attributes)
-		if err != nil {
-			return fmt.Errorf("marshaling event payload: %w", err)
-		}
-
-		args = append(args, timerEvent.ID, strconv.FormatInt(timerEvent.VisibleAt.UnixMilli(), 10), eventData, payloadEventData)
+		args = append(args, timerEvent.ID, eventData)
 		keys = append(keys, rb.keys.futureEventKey(instance, timerEvent.ScheduleEventID))
+		completion.Timers = append(completion.Timers, &pendingTimer{
+			ScheduleEventID: timerEvent.ScheduleEventID,
+			VisibleAt:       timerEvent.VisibleAt.UnixMilli(),
+		})
+	}
+
+	// Activities are only enqueued once per schedule event, a redelivered workflow task might schedule them again.
+	// The task holds the instance, the set cannot change until the task is committed.
+	scheduledActivities, err := rb.rdb.SMembers(ctx, rb.keys.scheduledActivitiesKey(instance)).Result()
+	if err != nil {
+		return wrapBusyError(fmt.Errorf("reading scheduled activities: %w", err))
+	}
+
+	scheduled := make(map[string]bool, len(scheduledActivities))
+	for _, scheduleEventID := range scheduledActivities {
+		scheduled[scheduleEventID] = true
 	}
 
 	// Schedule activities
@@ -198,34 +370,34 @@ func (rb *redisBackend) CompleteWorkflowTask(
 			queue = task.Queue
 		}
 
-		activityData, err := json.Marshal(&activityData{
-			Instance: instance,
-			ID:       activityEvent.ID,
-			Event:    activityEvent,
-			Queue:    string(queue),
-		})
-		if err != nil {
-			return fmt.Errorf("marshaling activity data: %w", err)
+		args = append(args, activityEvent.ScheduleEventID)
+
+		if scheduled[strconv.FormatInt(activityEvent.ScheduleEventID, 10)] {
+			continue
 		}
 
-		activityQueue := string(queue)
-		args = append(args, activityQueue, activityEvent.ID, activityData)
+		// The scheduled event is part of the executed events, its payload is stored with them
+		completion.Activities = append(completion.Activities, &activityData{
+			Instance:      instance,
+			ID:            activityEvent.ID,
+			Event:         activityEvent,
+			Queue:         string(queue),
+			PayloadStored: true,
+		})
 	}
 
 	// Send new workflow events to the respective streams
-	groupedEvents := history.EventsByWorkflowInstance(workflowEvents)
-	args = append(args, len(groupedEvents))
-	for targetInstance, events := range groupedEvents {
-		keys = append(keys, rb.keys.instanceKey(&targetInstance), rb.keys.activeInstanceExecutionKey(targetInstance.InstanceID))
-		args = append(args, instanceSegment(&targetInstance), targetInstance.InstanceID)
+	groupedEvents := history.EventGroupsByWorkflowInstance(workflowEvents)
+	for _, group := range groupedEvents {
+		targetInstance, events := group.Instance, group.Events
+
+		delivery := &pendingDelivery{
+			Instance: targetInstance,
+		}
 
 		// Are we creating a new workflow instance?
 		m := events[0]
-		createNewInstance := m.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted
-		args = append(args, createNewInstance)
-		args = append(args, len(events))
-
-		if createNewInstance {
+		if m.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted {
 			a := m.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
 
 			queue := a.Queue
@@ -233,70 +405,165 @@ func (rb *redisBackend) CompleteWorkflowTask(
 				queue = task.Queue
 			}
 
-			isb, err := json.Marshal(&instanceState{
+			targetState := &instanceState{
 				Queue:     string(queue),
-				Instance:  &targetInstance,
+				Instance:  targetInstance,
 				State:     core.WorkflowInstanceStateActive,
 				Metadata:  a.Metadata,
-				CreatedAt: time.Now(),
-			})
-			if err != nil {
-				return fmt.Errorf("marshaling new instance state: %w", err)
+				CreatedAt: rb.options.Clock.Now(),
+				Priority:  a.Priority,
+			}
+
+			// Instances continued as new keep their unique key, priority, and tags
+			if state == core.WorkflowInstanceStateContinuedAsNew && targetInstance.InstanceID == instance.InstanceID {
+				targetState.UniqueKey = taskData.UniqueKey
+				targetState.Priority = taskData.InstancePriority
+				targetState.Tags = taskData.Tags
+
+				delivery.ContinuedFrom = instance.ExecutionID
 			}
 
-			ib, err := json.Marshal(targetInstance)
+			isb, err := json.Marshal(targetState)
 			if err != nil {
-				return fmt.Errorf("marshaling instance: %w", err)
+				return fmt.Errorf("marshaling new instance state: %w", err)
 			}
 
-			args = append(args, isb, ib)
+			delivery.State = string(isb)
 
 			// Create pending event for conflicts
-			pfe := history.NewPendingEvent(time.Now(), history.EventType_SubWorkflowFailed, &history.SubWorkflowFailedAttributes{
+			pfe := history.NewPendingEvent(rb.options.Clock.Now(), history.EventType_SubWorkflowFailed, &history.SubWorkflowFailedAttributes{
 				Error: workflowerrors.FromError(backend.ErrInstanceAlreadyExists),
 			}, history.ScheduleEventID(m.WorkflowInstance.ParentEventID))
-			eventData, payloadEventData, err := marshalEvent(pfe)
+			eventData, err := marshalEventWithoutAttributes(pfe)
 			if err != nil {
 				return fmt.Errorf("marshaling event: %w", err)
 			}
 
-			args = append(args, pfe.ID, eventData, payloadEventData)
+			delivery.ConflictEvent = eventData
+			payloadEvents[*instance] = append(payloadEvents[*instance], pfe)
+		}
 
-			queueKeys := rb.workflowQueue.Keys(queue)
-			keys = append(keys, queueKeys.SetKey, queueKeys.StreamKey)
-		} else {
-			targetInstanceState, err := readInstance(ctx, rb.rdb, rb.keys.instanceKey(&targetInstance))
+		targetEvents := make([]*history.Event, 0, len(events))
+		for _, m := range events {
+			eventData, err := marshalEventWithoutAttributes(m.HistoryEvent)
 			if err != nil {
-				return fmt.Errorf("reading target instance: %w", err)
+				return fmt.Errorf("marshaling event: %w", err)
 			}
 
-			queueKeys := rb.workflowQueue.Keys(core.Queue(targetInstanceState.Queue))
-			keys = append(keys, queueKeys.SetKey, queueKeys.StreamKey)
+			delivery.Events = append(delivery.Events, eventData)
+			targetEvents = append(targetEvents, m.HistoryEvent)
 		}
 
-		keys = append(keys, rb.keys.pendingEventsKey(&targetInstance), rb.keys.payloadKey(&targetInstance))
-		for _, m := range events {
-			eventData, payloadEventData, err := marshalEvent(m.HistoryEvent)
+		if rb.storesPayloadsInline() {
+			payloads, err := serializeEventPayloads(targetEvents)
 			if err != nil {
-				return fmt.Errorf("marshaling event: %w", err)
+				return err
+			}
+
+			delivery.Payloads = make(map[string]string, len(payloads))
+			for eventID, payload := range payloads {
+				delivery.Payloads[eventID] = string(payload)
+			}
+		} else {
+			payloadEvents[*targetInstance] = append(payloadEvents[*targetInstance], targetEvents...)
+		}
+
+		completion.Deliveries = append(completion.Deliveries, delivery)
+	}
+
+	// Store payloads in the same script as the events. Payloads of events sent to other instances are stored when
+	// the events are delivered. Payloads kept outside of redis are stored before any of the events are written, they
+	// might be orphaned if the task cannot be completed, but events never reference a missing payload.
+	if rb.storesPayloadsInline() {
+		payloads, err := serializeEventPayloads(payloadEvents[*instance])
+		if err != nil {
+			return err
+		}
+
+		args = append(args, len(payloads))
+		for eventID, payload := range payloads {
+			args = append(args, eventID, string(payload))
+		}
+	} else {
+		args = append(args, 0)
+
+		// Storing payloads can be repeated, they are keyed by event
+		if err := rb.retry(ctx, "CompleteWorkflowTask", func(int) error {
+			for payloadInstance, events := range payloadEvents {
+				if err := rb.storeEventPayloads(ctx, &payloadInstance, events); err != nil {
+					return err
+				}
 			}
 
-			args = append(args, m.HistoryEvent.ID, eventData, payloadEventData)
+			return nil
+		}); err != nil {
+			return err
 		}
 	}
 
-	// Complete workflow task and unlock instance.
-	args = append(args, task.ID, rb.workflowQueue.groupName)
+	completionData, err := json.Marshal(completion)
+	if err != nil {
+		return fmt.Errorf("marshaling completion: %w", err)
+	}
 
-	// If there are pending events, queue the instance again
-	// 	No args/keys needed
+	args = append(args, string(completionData))
 
 	// Run script
-	_, err := completeWorkflowTaskCmd.Run(ctx, rb.rdb, keys, args...).Result()
+	runScript := func(attempt int) error {
+		if attempt > 0 {
+			// An attempt that failed with a transient error might have been applied. The script adds the
+			// executed events to the history, if the last one is there, this task has been completed.
+			applied, err := rb.historyContains(ctx, instance, executedEvents[len(executedEvents)-1].SequenceID)
+			if err != nil {
+				return err
+			}
+
+			if applied {
+				return nil
+			}
+		}
+
+		if rb.completions != nil {
+			return rb.completions.Complete(ctx, instanceSegment(instance), keys, args)
+		}
+
+		_, err := completeWorkflowTaskCmd.Run(ctx, rb.rdb, keys, args...).Result()
+		return err
+	}
+
+	if len(executedEvents) > 0 {
+		err = rb.retry(ctx, "CompleteWorkflowTask", runScript)
+	} else {
+		// Without executed events, there is no way to tell whether a failed attempt has been applied
+		err = runScript(0)
+	}
 	if err != nil {
-		return fmt.Errorf("completing workflow task: %w", err)
+		if redis.HasErrorPrefix(err, "TaskLockLost") {
+			return backend.ErrTaskLockLost
+		}
+
+		return wrapBusyError(fmt.Errorf("completing workflow task: %w", err))
 	}
 
+	// The task has been committed, execute the remaining steps. If this fails, they are executed when the next task
+	// for the instance is dequeued, or this task is recovered.
+	if err := rb.retry(ctx, "CompleteWorkflowTask", func(int) error {
+		return rb.executeCompletion(ctx, completion)
+	}); err != nil {
+		return wrapBusyError(fmt.Errorf("completing workflow task: %w", err))
+	}
+
+	// The task has been completed at this point, failing to prune inputs only leaves them in place
+	if rb.options.PruneActivityInputs {
+		if err := rb.pruneActivityInputs(ctx, instance, executedEvents); err != nil {
+			rb.options.Logger.Warn("pruning activity inputs", log.ErrorKey, err)
+		}
+	}
+
+	rb.events.Dispatch(instance, executedEvents)
+
+	rb.publishInstanceUpdate(ctx, instance, state)
+
 	if state == core.WorkflowInstanceStateFinished || state == core.WorkflowInstanceStateContinuedAsNew {
 		// Trace workflow completion
 		ctx, err = (&propagators.TracingContextPropagator{}).Extract(ctx, task.Metadata)
@@ -305,15 +572,16 @@ func (rb *redisBackend) CompleteWorkflowTask(
 		}
 
 		// Auto expiration
-		expiration := rb.options.AutoExpiration
-		if state == core.WorkflowInstanceStateContinuedAsNew && rb.options.AutoExpirationContinueAsNew > 0 {
-			expiration = rb.options.AutoExpirationContinueAsNew
-		}
+		expiration := rb.options.finishedExpiration(state, executedEvents)
 
 		if expiration > 0 {
 			if err := rb.setWorkflowInstanceExpiration(ctx, instance, expiration); err != nil {
 				return fmt.Errorf("setting workflow instance expiration: %w", err)
 			}
+		} else if rb.options.MaxInactivityTTL > 0 {
+			if err := rb.refreshWorkflowInstanceExpiration(ctx, instance); err != nil {
+				return fmt.Errorf("refreshing workflow instance expiration: %w", err)
+			}
 		}
 
 		if rb.options.RemoveContinuedAsNewInstances && state == core.WorkflowInstanceStateContinuedAsNew {
@@ -321,38 +589,62 @@ func (rb *redisBackend) CompleteWorkflowTask(
 				return fmt.Errorf("removing workflow instance: %w", err)
 			}
 		}
+	} else if rb.options.MaxInactivityTTL > 0 {
+		// Instance is still active, reset inactivity expiration
+		if err := rb.refreshWorkflowInstanceExpiration(ctx, instance); err != nil {
+			return fmt.Errorf("refreshing workflow instance expiration: %w", err)
+		}
 	}
 
 	return nil
 }
 
-func marshalEvent(event *history.Event) (string, string, error) {
-	eventData, err := marshalEventWithoutAttributes(event)
-	if err != nil {
-		return "", "", fmt.Errorf("marshaling event payload: %w", err)
-	}
+// addWorkflowInstanceEvent adds the given event to the pending events of the instance and queues a workflow task
+func (rb *redisBackend) addWorkflowInstanceEvent(ctx context.Context, queue workflow.Queue, priority int, instance *core.WorkflowInstance, event *history.Event) error {
+	p := rb.rdb.TxPipeline()
 
-	payloadEventData, err := json.Marshal(event.Attributes)
-	if err != nil {
-		return "", "", fmt.Errorf("marshaling event payload: %w", err)
+	// Payloads are stored in the same transaction, or right away if they are not kept in redis
+	if err := rb.storeEventPayloadsP(ctx, p, instance, []*history.Event{event}); err != nil {
+		return err
 	}
-	return eventData, string(payloadEventData), nil
-}
 
-func (rb *redisBackend) addWorkflowInstanceEventP(ctx context.Context, p redis.Pipeliner, queue workflow.Queue, instance *core.WorkflowInstance, event *history.Event) error {
 	// Add event to pending events for instance
-	if err := rb.addEventPayloadsP(ctx, p, instance, []*history.Event{event}); err != nil {
+	if err := addEventToStreamP(ctx, p, rb.keys.pendingEventsKey(instance), event); err != nil {
 		return err
 	}
 
-	if err := addEventToStreamP(ctx, p, rb.keys.pendingEventsKey(instance), event); err != nil {
+	if _, err := p.Exec(ctx); err != nil {
 		return err
 	}
 
 	// Queue workflow task
-	if err := rb.workflowQueue.Enqueue(ctx, p, queue, instanceSegment(instance), nil); err != nil {
+	p = rb.rdb.Pipeline()
+	if err := rb.workflowQueue.Enqueue(ctx, p, queue, priority, instanceSegment(instance), nil); err != nil {
 		return fmt.Errorf("queueing workflow: %w", err)
 	}
 
+	if _, err := p.Exec(ctx); err != nil {
+		return fmt.Errorf("queueing workflow: %w", err)
+	}
+
+	// Reset inactivity expiration
+	if rb.options.MaxInactivityTTL > 0 {
+		if err := rb.refreshWorkflowInstanceExpiration(ctx, instance); err != nil {
+			return fmt.Errorf("refreshing workflow instance expiration: %w", err)
+		}
+	}
+
 	return nil
 }
+
+// historyContains returns true if the history of the given instance contains the event with the given sequence ID
+func (rb *redisBackend) historyContains(ctx context.Context, instance *core.WorkflowInstance, sequenceID int64) (bool, error) {
+	id := strconv.FormatInt(sequenceID, 10)
+
+	msgs, err := rb.rdb.XRange(ctx, rb.keys.historyKey(instance), id, id).Result()
+	if err != nil {
+		return false, fmt.Errorf("reading history: %w", err)
+	}
+
+	return len(msgs) > 0, nil
+}
@@ -30,3 +30,24 @@ func scheduleActivity(ctx context.Context, tx *sql.Tx, queue workflow.Queue, ins
 
 	return nil
 }
+
+// activityDispatchPausedSetting is the name of the setting pausing activity dispatch, it exists while paused
+const activityDispatchPausedSetting = "activity_dispatch_paused"
+
+func (sb *sqliteBackend) PauseActivityDispatch(ctx context.Context, paused bool) error {
+	if !paused {
+		if _, err := sb.db.ExecContext(ctx, "DELETE FROM `settings` WHERE `name` = ?", activityDispatchPausedSetting); err != nil {
+			return fmt.Errorf("resuming activity dispatch: %w", err)
+		}
+
+		return nil
+	}
+
+	if _, err := sb.db.ExecContext(
+		ctx, "INSERT OR REPLACE INTO `settings` (`name`, `value`) VALUES (?, ?)", activityDispatchPausedSetting, "true",
+	); err != nil {
+		return fmt.Errorf("pausing activity dispatch: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/payload"
+)
+
+func (sb *sqliteBackend) GetActivityResult(ctx context.Context, key string) (payload.Payload, bool, error) {
+	row := sb.db.QueryRowContext(
+		ctx,
+		"SELECT result FROM `activity_results` WHERE `key` = ? AND expires_at > ?",
+		key,
+		sb.options.Clock.Now(),
+	)
+
+	var result []byte
+	if err := row.Scan(&result); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("reading activity result: %w", err)
+	}
+
+	return result, true, nil
+}
+
+func (sb *sqliteBackend) CacheActivityResult(ctx context.Context, key string, result payload.Payload, ttl time.Duration) error {
+	tx, err := sb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := sb.options.Clock.Now()
+
+	// Remove expired results
+	if _, err := tx.ExecContext(ctx, "DELETE FROM `activity_results` WHERE expires_at <= ?", now); err != nil {
+		return fmt.Errorf("removing expired activity results: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		"INSERT OR REPLACE INTO `activity_results` (`key`, result, expires_at) VALUES (?, ?, ?)",
+		key,
+		[]byte(result),
+		now.Add(ttl),
+	); err != nil {
+		return fmt.Errorf("caching activity result: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (sb *sqliteBackend) CacheActivityResultIfAbsent(ctx context.Context, key string, result payload.Payload, ttl time.Duration) (bool, error) {
+	tx, err := sb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	now := sb.options.Clock.Now()
+
+	// Remove expired results, so they don't prevent caching a new one
+	if _, err := tx.ExecContext(ctx, "DELETE FROM `activity_results` WHERE expires_at <= ?", now); err != nil {
+		return false, fmt.Errorf("removing expired activity results: %w", err)
+	}
+
+	res, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO `activity_results` (`key`, result, expires_at) VALUES (?, ?, ?) ON CONFLICT (`key`) DO NOTHING",
+		key,
+		[]byte(result),
+		now.Add(ttl),
+	)
+	if err != nil {
+		return false, fmt.Errorf("caching activity result: %w", err)
+	}
+
+	added, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("caching activity result: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return added == 1, nil
+}
+
+func (sb *sqliteBackend) InvalidateActivityResult(ctx context.Context, key string) error {
+	if _, err := sb.db.ExecContext(ctx, "DELETE FROM `activity_results` WHERE `key` = ?", key); err != nil {
+		return fmt.Errorf("invalidating activity result: %w", err)
+	}
+
+	return nil
+}
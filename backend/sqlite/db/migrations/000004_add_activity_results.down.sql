@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS `idx_activity_results_expires_at`;
+DROP TABLE IF EXISTS `activity_results`;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS `activity_results` (
+  `key` TEXT PRIMARY KEY,
+  `result` BLOB NOT NULL,
+  `expires_at` DATETIME NOT NULL
+);
+
+CREATE INDEX `idx_activity_results_expires_at` ON `activity_results` (`expires_at`);
@@ -0,0 +1,2 @@
+-- Add latest progress reported by workflow instances
+ALTER TABLE `instances` ADD COLUMN `progress` BLOB NULL;
@@ -0,0 +1,5 @@
+-- Settings shared by all workers using the database, e.g., whether activity dispatch is paused
+CREATE TABLE IF NOT EXISTS `settings` (
+  `name` TEXT PRIMARY KEY,
+  `value` TEXT NOT NULL
+);
@@ -0,0 +1,6 @@
+-- Add resources used by workflow instances
+ALTER TABLE `instances` ADD COLUMN `usage_workflow_tasks` INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE `instances` ADD COLUMN `usage_activities` INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE `instances` ADD COLUMN `usage_activity_duration` INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE `instances` ADD COLUMN `usage_timers` INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE `instances` ADD COLUMN `usage_history_events` INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Add number of workflow tasks of an instance that failed in a row
+ALTER TABLE `instances` ADD COLUMN `task_failures` INTEGER NOT NULL DEFAULT 0;
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/backend/test"
 	"github.com/cschleiden/go-workflows/core"
@@ -69,11 +70,12 @@ func (sb *sqliteBackend) GetFutureEvents(ctx context.Context) ([]*history.Event,
 	return f, nil
 }
 
-func getPendingEvents(ctx context.Context, tx *sql.Tx, instance *core.WorkflowInstance) ([]*history.Event, error) {
-	now := time.Now()
+// getPendingEvents returns the visible pending events of the given instance in the order they were added, so that, e.g.,
+// signals are delivered to the workflow in the order they were sent.
+func getPendingEvents(ctx context.Context, tx *sql.Tx, instance *core.WorkflowInstance, now time.Time) ([]*history.Event, error) {
 	events, err := tx.QueryContext(
 		ctx,
-		"SELECT pe.*, a.data FROM `pending_events` pe INNER JOIN `attributes` a ON a.id = pe.id AND a.instance_id = pe.instance_id AND a.execution_id = pe.execution_id WHERE pe.instance_id = ? AND pe.execution_id = ? AND (pe.`visible_at` IS NULL OR pe.`visible_at` <= ?)",
+		"SELECT pe.*, a.data FROM `pending_events` pe INNER JOIN `attributes` a ON a.id = pe.id AND a.instance_id = pe.instance_id AND a.execution_id = pe.execution_id WHERE pe.instance_id = ? AND pe.execution_id = ? AND (pe.`visible_at` IS NULL OR pe.`visible_at` <= ?) ORDER BY pe.rowid",
 		instance.InstanceID,
 		instance.ExecutionID,
 		now,
@@ -88,7 +90,7 @@ func getPendingEvents(ctx context.Context, tx *sql.Tx, instance *core.WorkflowIn
 	pendingEvents := make([]*history.Event, 0)
 
 	for events.Next() {
-		pendingEvent, err := scanEvent(events)
+		pendingEvent, err := scanEvent(events, history.DeserializeAttributes)
 		if err != nil {
 			return nil, fmt.Errorf("reading event: %w", err)
 		}
@@ -99,15 +101,15 @@ func getPendingEvents(ctx context.Context, tx *sql.Tx, instance *core.WorkflowIn
 	return pendingEvents, nil
 }
 
-func getHistory(ctx context.Context, tx *sql.Tx, instance *core.WorkflowInstance, lastSequenceID *int64) ([]*history.Event, error) {
+func getHistory(ctx context.Context, tx *sql.Tx, instance *core.WorkflowInstance, lastSequenceID *int64, options backend.HistoryOptions) ([]*history.Event, error) {
 	var historyEvents *sql.Rows
 	var err error
 	if lastSequenceID != nil {
 		historyEvents, err = tx.QueryContext(
-			ctx, "SELECT h.*, a.data FROM `history` h INNER JOIN `attributes` a ON a.id = h.id AND a.instance_id = h.instance_id AND a.execution_id = h.execution_id WHERE h.instance_id = ? AND h.execution_id = ? AND h.sequence_id > ?", instance.InstanceID, instance.ExecutionID, *lastSequenceID)
+			ctx, "SELECT h.*, a.data FROM `history` h INNER JOIN `attributes` a ON a.id = h.id AND a.instance_id = h.instance_id AND a.execution_id = h.execution_id WHERE h.instance_id = ? AND h.execution_id = ? AND h.sequence_id > ? ORDER BY h.sequence_id", instance.InstanceID, instance.ExecutionID, *lastSequenceID)
 	} else {
 		historyEvents, err = tx.QueryContext(
-			ctx, "SELECT h.*, a.data FROM `history` h INNER JOIN `attributes` a ON a.id = h.id AND a.instance_id = h.instance_id AND a.execution_id = h.execution_id WHERE h.instance_id = ? AND h.execution_id = ?", instance.InstanceID, instance.ExecutionID)
+			ctx, "SELECT h.*, a.data FROM `history` h INNER JOIN `attributes` a ON a.id = h.id AND a.instance_id = h.instance_id AND a.execution_id = h.execution_id WHERE h.instance_id = ? AND h.execution_id = ? ORDER BY h.sequence_id", instance.InstanceID, instance.ExecutionID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("getting history: %w", err)
@@ -115,10 +117,15 @@ func getHistory(ctx context.Context, tx *sql.Tx, instance *core.WorkflowInstance
 
 	defer historyEvents.Close()
 
+	deserialize := history.DeserializeAttributes
+	if options.SkipActivityInputs {
+		deserialize = history.DeserializeAttributesWithoutInputs
+	}
+
 	events := make([]*history.Event, 0)
 
 	for historyEvents.Next() {
-		historyEvent, err := scanEvent(historyEvents)
+		historyEvent, err := scanEvent(historyEvents, deserialize)
 		if err != nil {
 			return nil, fmt.Errorf("reading event: %w", err)
 		}
@@ -133,7 +140,7 @@ type Scanner interface {
 	Scan(dest ...interface{}) error
 }
 
-func scanEvent(row Scanner) (*history.Event, error) {
+func scanEvent(row Scanner, deserialize func(history.EventType, []byte) (interface{}, error)) (*history.Event, error) {
 	var instanceID, executionID string
 	var attributes []byte
 
@@ -153,7 +160,7 @@ func scanEvent(row Scanner) (*history.Event, error) {
 		return historyEvent, fmt.Errorf("scanning event: %w", err)
 	}
 
-	a, err := history.DeserializeAttributes(historyEvent.Type, attributes)
+	a, err := deserialize(historyEvent.Type, attributes)
 	if err != nil {
 		return historyEvent, fmt.Errorf("deserializing attributes: %w", err)
 	}
@@ -255,3 +262,69 @@ func removeFutureEvent(ctx context.Context, tx *sql.Tx, instance *core.WorkflowI
 
 	return err
 }
+
+// compactCanceledTimers removes the events of timers that have been canceled from the history of the given instance.
+// Timers canceled in the last event of the history are kept until more events have been added, so that the sequence
+// ID of the last event does not change.
+func compactCanceledTimers(ctx context.Context, tx *sql.Tx, instance *core.WorkflowInstance) error {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT id FROM `history` WHERE instance_id = ? AND execution_id = ? AND event_type IN (?, ?, ?) AND schedule_event_id IN "+
+			"(SELECT schedule_event_id FROM `history` WHERE instance_id = ? AND execution_id = ? AND event_type = ? AND sequence_id < "+
+			"(SELECT MAX(sequence_id) FROM `history` WHERE instance_id = ? AND execution_id = ?))",
+		instance.InstanceID,
+		instance.ExecutionID,
+		history.EventType_TimerScheduled,
+		history.EventType_TimerFired,
+		history.EventType_TimerCanceled,
+		instance.InstanceID,
+		instance.ExecutionID,
+		history.EventType_TimerCanceled,
+		instance.InstanceID,
+		instance.ExecutionID,
+	)
+	if err != nil {
+		return fmt.Errorf("finding canceled timers: %w", err)
+	}
+
+	ids := make([]interface{}, 0)
+
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("scanning id: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("finding canceled timers: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := append([]interface{}{instance.InstanceID, instance.ExecutionID}, ids...)
+	placeholders := strings.Repeat(",?", len(ids)-1)
+
+	if _, err := tx.ExecContext(
+		ctx,
+		"DELETE FROM `history` WHERE instance_id = ? AND execution_id = ? AND id IN (?"+placeholders+")",
+		args...,
+	); err != nil {
+		return fmt.Errorf("removing canceled timers: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		"DELETE FROM `attributes` WHERE instance_id = ? AND execution_id = ? AND id IN (?"+placeholders+")",
+		args...,
+	); err != nil {
+		return fmt.Errorf("removing attributes: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+func (sb *sqliteBackend) ResetWorkflowInstance(ctx context.Context, instance *workflow.Instance, sequenceID int64) error {
+	tx, err := sb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, "SELECT state FROM `instances` WHERE id = ? AND execution_id = ? LIMIT 1", instance.InstanceID, instance.ExecutionID)
+	var state core.WorkflowInstanceState
+	if err := row.Scan(&state); err != nil {
+		if err == sql.ErrNoRows {
+			return backend.ErrInstanceNotFound
+		}
+
+		return fmt.Errorf("reading instance: %w", err)
+	}
+
+	if state == core.WorkflowInstanceStateContinuedAsNew {
+		return backend.ErrNotSupported{Message: "resetting a workflow instance that continued as new"}
+	}
+
+	// Make sure there is no other active execution for this instance
+	if err := tx.QueryRowContext(
+		ctx,
+		"SELECT 1 FROM `instances` WHERE id = ? AND execution_id != ? AND state IN (?, ?) LIMIT 1",
+		instance.InstanceID,
+		instance.ExecutionID,
+		core.WorkflowInstanceStateActive,
+		core.WorkflowInstanceStateErrored,
+	).Scan(new(int)); err != sql.ErrNoRows {
+		if err != nil {
+			return fmt.Errorf("checking for active executions: %w", err)
+		}
+
+		return backend.ErrInstanceAlreadyExists
+	}
+
+	if err := tx.QueryRowContext(
+		ctx,
+		"SELECT 1 FROM `history` WHERE instance_id = ? AND execution_id = ? AND sequence_id = ? LIMIT 1",
+		instance.InstanceID,
+		instance.ExecutionID,
+		sequenceID,
+	).Scan(new(int)); err != nil {
+		if err == sql.ErrNoRows {
+			return backend.ErrInvalidResetPoint
+		}
+
+		return fmt.Errorf("checking reset point: %w", err)
+	}
+
+	truncated, err := getHistory(ctx, tx, instance, &sequenceID, backend.DefaultHistoryOptions)
+	if err != nil {
+		return fmt.Errorf("getting history: %w", err)
+	}
+
+	// Results of sub-workflows started after the reset point could be delivered to the re-executed workflow
+	for _, subWorkflowInstance := range history.ResetSubWorkflows(truncated) {
+		if err := tx.QueryRowContext(
+			ctx,
+			"SELECT 1 FROM `instances` WHERE id = ? AND execution_id = ? AND state IN (?, ?) LIMIT 1",
+			subWorkflowInstance.InstanceID,
+			subWorkflowInstance.ExecutionID,
+			core.WorkflowInstanceStateActive,
+			core.WorkflowInstanceStateErrored,
+		).Scan(new(int)); err != sql.ErrNoRows {
+			if err != nil {
+				return fmt.Errorf("checking sub-workflows: %w", err)
+			}
+
+			return backend.ErrSubWorkflowsActive
+		}
+	}
+
+	redeliver, discard := history.ResetEvents(truncated)
+
+	// Remove history after the reset point
+	if _, err := tx.ExecContext(
+		ctx,
+		"DELETE FROM `history` WHERE instance_id = ? AND execution_id = ? AND sequence_id > ?",
+		instance.InstanceID,
+		instance.ExecutionID,
+		sequenceID,
+	); err != nil {
+		return fmt.Errorf("removing history: %w", err)
+	}
+
+	// Attributes of redelivered events are re-used for the new pending events
+	redelivered := make(map[string]bool, len(redeliver))
+	for _, event := range redeliver {
+		redelivered[event.ID] = true
+	}
+
+	attributeIDs := make([]interface{}, 0, len(truncated))
+	for _, event := range truncated {
+		if !redelivered[event.ID] {
+			attributeIDs = append(attributeIDs, event.ID)
+		}
+	}
+
+	// Remove pending events and activities for work scheduled after the reset point. Activities that are
+	// currently executing cannot be completed anymore, their results are dropped.
+	if len(discard) > 0 {
+		for _, table := range []string{"pending_events", "activities"} {
+			ids, err := deleteByScheduleEventIDs(ctx, tx, table, instance, discard)
+			if err != nil {
+				return err
+			}
+
+			attributeIDs = append(attributeIDs, ids...)
+		}
+	}
+
+	if len(attributeIDs) > 0 {
+		args := append([]interface{}{instance.InstanceID, instance.ExecutionID}, attributeIDs...)
+		if _, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM `attributes` WHERE instance_id = ? AND execution_id = ? AND id IN (?"+strings.Repeat(",?", len(attributeIDs)-1)+")",
+			args...,
+		); err != nil {
+			return fmt.Errorf("removing attributes: %w", err)
+		}
+	}
+
+	// Trigger re-execution of the workflow
+	pendingEvents := append(redeliver, history.NewWorkflowResetEvent(sb.options.Clock.Now(), sequenceID))
+	if err := insertPendingEvents(ctx, tx, instance, pendingEvents); err != nil {
+		return fmt.Errorf("inserting pending events: %w", err)
+	}
+
+	// Re-activate and unlock the instance. If a workflow task is currently being processed, it cannot be
+	// completed anymore.
+	if _, err := tx.ExecContext(
+		ctx,
+		"UPDATE `instances` SET state = ?, completed_at = NULL, locked_until = NULL, sticky_until = NULL, worker = NULL, task_failures = 0 WHERE id = ? AND execution_id = ?",
+		core.WorkflowInstanceStateActive,
+		instance.InstanceID,
+		instance.ExecutionID,
+	); err != nil {
+		return fmt.Errorf("updating instance: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (sb *sqliteBackend) RetryWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	tx, err := sb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, "SELECT state FROM `instances` WHERE id = ? AND execution_id = ? LIMIT 1", instance.InstanceID, instance.ExecutionID)
+	var state core.WorkflowInstanceState
+	if err := row.Scan(&state); err != nil {
+		if err == sql.ErrNoRows {
+			return backend.ErrInstanceNotFound
+		}
+
+		return fmt.Errorf("reading instance: %w", err)
+	}
+
+	if state != core.WorkflowInstanceStateErrored {
+		return backend.ErrInstanceNotErrored
+	}
+
+	// The instance is picked up again with its pending events
+	if _, err := tx.ExecContext(
+		ctx,
+		"UPDATE `instances` SET state = ?, locked_until = NULL, sticky_until = NULL, worker = NULL, task_failures = 0 WHERE id = ? AND execution_id = ?",
+		core.WorkflowInstanceStateActive,
+		instance.InstanceID,
+		instance.ExecutionID,
+	); err != nil {
+		return fmt.Errorf("updating instance: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func deleteByScheduleEventIDs(ctx context.Context, tx *sql.Tx, table string, instance *workflow.Instance, scheduleEventIDs []int64) ([]interface{}, error) {
+	args := []interface{}{instance.InstanceID, instance.ExecutionID}
+	for _, id := range scheduleEventIDs {
+		args = append(args, id)
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"DELETE FROM `"+table+"` WHERE instance_id = ? AND execution_id = ? AND schedule_event_id IN (?"+strings.Repeat(",?", len(scheduleEventIDs)-1)+") RETURNING id",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("removing %s: %w", table, err)
+	}
+
+	defer rows.Close()
+
+	ids := make([]interface{}, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning id: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
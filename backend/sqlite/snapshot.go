@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Snapshot returns a copy of the entire state of the backend: workflow instances, their history, pending events,
+// future events, and queued activities. Restore brings a backend back to that state, e.g., to prepare a scenario in
+// an in-memory backend once and reset it before every test case.
+//
+// Subscriptions and event callbacks are not part of the snapshot.
+func (sb *sqliteBackend) Snapshot(ctx context.Context) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "go-workflows-snapshot")
+	if err != nil {
+		return nil, fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "snapshot.sqlite")
+
+	if _, err := sb.db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return nil, fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	return os.ReadFile(path)
+}
+
+// Restore replaces the state of the backend with the given snapshot taken with Snapshot. The snapshot has to be
+// taken from a backend with the same migrations applied.
+//
+// Workers should be stopped while restoring, tasks they are working on are lost.
+func (sb *sqliteBackend) Restore(ctx context.Context, snapshot []byte) error {
+	dir, err := os.MkdirTemp("", "go-workflows-snapshot")
+	if err != nil {
+		return fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "snapshot.sqlite")
+	if err := os.WriteFile(path, snapshot, 0o600); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	// Attached databases are only visible to the connection attaching them
+	conn, err := sb.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS snapshot", path); err != nil {
+		return fmt.Errorf("attaching snapshot: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "DETACH DATABASE snapshot")
+
+	var version, snapshotVersion int64
+	if err := conn.QueryRowContext(ctx, "SELECT version FROM main.schema_migrations").Scan(&version); err != nil {
+		return fmt.Errorf("getting schema version: %w", err)
+	}
+
+	if err := conn.QueryRowContext(ctx, "SELECT version FROM snapshot.schema_migrations").Scan(&snapshotVersion); err != nil {
+		return fmt.Errorf("getting snapshot schema version: %w", err)
+	}
+
+	if version != snapshotVersion {
+		return fmt.Errorf("snapshot has schema version %d, backend has schema version %d", snapshotVersion, version)
+	}
+
+	rows, err := conn.QueryContext(
+		ctx, "SELECT name FROM main.sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'schema_migrations'")
+	if err != nil {
+		return fmt.Errorf("getting tables: %w", err)
+	}
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning table: %w", err)
+		}
+
+		tables = append(tables, table)
+	}
+
+	if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+		return fmt.Errorf("getting tables: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range tables {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM main.%q", table)); err != nil {
+			return fmt.Errorf("clearing table %s: %w", table, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO main.%q SELECT * FROM snapshot.%q", table, table)); err != nil {
+			return fmt.Errorf("restoring table %s: %w", table, err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SnapshotRestore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewInMemoryBackend()
+	defer b.Close()
+
+	c := client.New(b)
+
+	wf := func(ctx workflow.Context, msg string) (string, error) {
+		return msg + " world", nil
+	}
+
+	prepared, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, wf, "hello")
+	require.NoError(t, err)
+
+	snapshot, err := b.Snapshot(ctx)
+	require.NoError(t, err)
+
+	added, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, wf, "hello")
+	require.NoError(t, err)
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.Start(ctx))
+
+	r, err := client.GetWorkflowResult[string](ctx, c, prepared, time.Second*10)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", r)
+
+	cancel()
+	require.NoError(t, w.WaitForCompletion())
+
+	require.NoError(t, b.Restore(context.Background(), snapshot))
+
+	// Instances created after the snapshot are gone, finished instances are pending again
+	_, err = b.GetWorkflowInstanceState(context.Background(), added)
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+	state, err := b.GetWorkflowInstanceState(context.Background(), prepared)
+	require.NoError(t, err)
+	require.Equal(t, core.WorkflowInstanceStateActive, state)
+
+	task, err := b.GetWorkflowTask(context.Background(), []workflow.Queue{workflow.QueueDefault})
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	require.Equal(t, prepared.InstanceID, task.WorkflowInstance.InstanceID)
+}
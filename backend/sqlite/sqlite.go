@@ -15,6 +15,7 @@ import (
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/backend/metadata"
 	"github.com/cschleiden/go-workflows/backend/metrics"
+	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/cschleiden/go-workflows/core"
 	"github.com/cschleiden/go-workflows/internal/metrickeys"
 	"github.com/cschleiden/go-workflows/internal/workflowerrors"
@@ -78,6 +79,9 @@ func newSqliteBackend(dsn string, opts ...option) *sqliteBackend {
 		db:         db,
 		workerName: fmt.Sprintf("worker-%v", uuid.NewString()),
 		options:    options,
+
+		subscriptions: newSubscriptions(),
+		events:        backend.NewEventDispatcher(options.EventCallback, options.Logger),
 	}
 
 	// Apply migrations
@@ -96,6 +100,9 @@ type sqliteBackend struct {
 	options    *options
 
 	memConn *sql.Conn
+
+	subscriptions *subscriptions
+	events        *backend.EventDispatcher
 }
 
 var _ backend.Backend = (*sqliteBackend)(nil)
@@ -156,7 +163,7 @@ func (sb *sqliteBackend) Options() *backend.Options {
 	return sb.options.Options
 }
 
-func (sb *sqliteBackend) CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
+func (sb *sqliteBackend) CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event, signals ...*history.Event) error {
 	tx, err := sb.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("starting transaction: %w", err)
@@ -164,14 +171,31 @@ func (sb *sqliteBackend) CreateWorkflowInstance(ctx context.Context, instance *w
 	defer tx.Rollback()
 
 	a := event.Attributes.(*history.ExecutionStartedAttributes)
+	if a.UniqueKey != "" {
+		return backend.ErrNotSupported{
+			Message: "unique keys",
+		}
+	}
+
+	if a.Priority != 0 {
+		return backend.ErrNotSupported{
+			Message: "workflow instance priorities",
+		}
+	}
+
+	if len(a.Tags) > 0 {
+		return backend.ErrNotSupported{
+			Message: "workflow instance tags",
+		}
+	}
 
 	// Create workflow instance
 	if err := createInstance(ctx, tx, a.Queue, instance, a.Metadata); err != nil {
 		return err
 	}
 
-	if err := insertPendingEvents(ctx, tx, instance, []*history.Event{event}); err != nil {
-		return fmt.Errorf("inserting new event: %w", err)
+	if err := insertPendingEvents(ctx, tx, instance, append([]*history.Event{event}, signals...)); err != nil {
+		return fmt.Errorf("inserting new events: %w", err)
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -181,9 +205,56 @@ func (sb *sqliteBackend) CreateWorkflowInstance(ctx context.Context, instance *w
 	return nil
 }
 
+func (sb *sqliteBackend) GetChildWorkflowInstances(ctx context.Context, instance *workflow.Instance) ([]*workflow.Instance, error) {
+	rows, err := sb.db.QueryContext(
+		ctx,
+		"SELECT id, execution_id, parent_schedule_event_id FROM instances WHERE parent_instance_id = ? AND parent_execution_id = ? ORDER BY rowid",
+		instance.InstanceID,
+		instance.ExecutionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying child instances: %w", err)
+	}
+	defer rows.Close()
+
+	return scanChildInstances(rows, instance)
+}
+
+// scanChildInstances reads child instances ordered by creation, keeping the most recent execution of each
+func scanChildInstances(rows *sql.Rows, parent *workflow.Instance) ([]*workflow.Instance, error) {
+	children := make([]*workflow.Instance, 0)
+	byID := make(map[string]int)
+
+	for rows.Next() {
+		var instanceID, executionID string
+		var parentEventID int64
+		if err := rows.Scan(&instanceID, &executionID, &parentEventID); err != nil {
+			return nil, fmt.Errorf("scanning child instance: %w", err)
+		}
+
+		child := core.NewSubWorkflowInstance(instanceID, executionID, parent, parentEventID)
+
+		// Sub-workflows continued as new keep their parent
+		if i, ok := byID[instanceID]; ok {
+			children[i] = child
+			continue
+		}
+
+		byID[instanceID] = len(children)
+		children = append(children, child)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading child instances: %w", err)
+	}
+
+	return children, nil
+}
+
 func createInstance(ctx context.Context, tx *sql.Tx, queue workflow.Queue, wfi *workflow.Instance, metadata *workflow.Metadata) error {
 	// Check for existing instance
-	if err := tx.QueryRowContext(ctx, "SELECT 1 FROM `instances` WHERE id = ? AND state = ? LIMIT 1", wfi.InstanceID, core.WorkflowInstanceStateActive).
+	if err := tx.QueryRowContext(ctx, "SELECT 1 FROM `instances` WHERE id = ? AND state IN (?, ?) LIMIT 1",
+		wfi.InstanceID, core.WorkflowInstanceStateActive, core.WorkflowInstanceStateErrored).
 		Scan(new(int)); err != sql.ErrNoRows {
 		return backend.ErrInstanceAlreadyExists
 	}
@@ -246,7 +317,7 @@ func (sb *sqliteBackend) removeWorkflowInstance(ctx context.Context, instance *c
 		}
 	}
 
-	if state == core.WorkflowInstanceStateActive {
+	if state == core.WorkflowInstanceStateActive || state == core.WorkflowInstanceStateErrored {
 		return backend.ErrInstanceNotFinished
 	}
 
@@ -358,7 +429,12 @@ func (sb *sqliteBackend) CancelWorkflowInstance(ctx context.Context, instance *w
 	return tx.Commit()
 }
 
-func (sb *sqliteBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, lastSequenceID *int64) ([]*history.Event, error) {
+func (sb *sqliteBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, lastSequenceID *int64, options ...backend.HistoryOption) ([]*history.Event, error) {
+	ho := backend.DefaultHistoryOptions
+	for _, opt := range options {
+		opt(&ho)
+	}
+
 	tx, err := sb.db.BeginTx(ctx, &sql.TxOptions{
 		ReadOnly: true,
 	})
@@ -367,7 +443,7 @@ func (sb *sqliteBackend) GetWorkflowInstanceHistory(ctx context.Context, instanc
 	}
 	defer tx.Rollback()
 
-	h, err := getHistory(ctx, tx, instance, lastSequenceID)
+	h, err := getHistory(ctx, tx, instance, lastSequenceID, ho)
 	if err != nil {
 		return nil, fmt.Errorf("getting workflow history: %w", err)
 	}
@@ -375,6 +451,54 @@ func (sb *sqliteBackend) GetWorkflowInstanceHistory(ctx context.Context, instanc
 	return h, nil
 }
 
+func (sb *sqliteBackend) GetWorkflowInstanceProgress(ctx context.Context, instance *workflow.Instance) (payload.Payload, error) {
+	row := sb.db.QueryRowContext(
+		ctx,
+		"SELECT progress FROM instances WHERE id = ? AND execution_id = ?",
+		instance.InstanceID,
+		instance.ExecutionID,
+	)
+
+	var progress []byte
+	if err := row.Scan(&progress); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, fmt.Errorf("getting progress: %w", err)
+	}
+
+	if progress == nil {
+		return nil, nil
+	}
+
+	return payload.Payload(progress), nil
+}
+
+func (sb *sqliteBackend) GetWorkflowInstanceUsage(ctx context.Context, instance *workflow.Instance) (*backend.WorkflowInstanceUsage, error) {
+	row := sb.db.QueryRowContext(
+		ctx,
+		`SELECT usage_workflow_tasks, usage_activities, usage_activity_duration, usage_timers, usage_history_events
+		FROM instances WHERE id = ? AND execution_id = ?`,
+		instance.InstanceID,
+		instance.ExecutionID,
+	)
+
+	var usage backend.WorkflowInstanceUsage
+	var activityDuration int64
+	if err := row.Scan(&usage.WorkflowTasks, &usage.Activities, &activityDuration, &usage.Timers, &usage.HistoryEvents); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, fmt.Errorf("getting usage: %w", err)
+	}
+
+	usage.ActivityDuration = time.Duration(activityDuration)
+
+	return &usage, nil
+}
+
 func (sb *sqliteBackend) GetWorkflowInstanceState(ctx context.Context, instance *workflow.Instance) (core.WorkflowInstanceState, error) {
 	tx, err := sb.db.BeginTx(ctx, &sql.TxOptions{
 		ReadOnly: true,
@@ -401,6 +525,70 @@ func (sb *sqliteBackend) GetWorkflowInstanceState(ctx context.Context, instance
 	return state, nil
 }
 
+func (sb *sqliteBackend) GetWorkflowInstancesByTag(ctx context.Context, tag string) ([]*backend.TaggedWorkflowInstance, error) {
+	return nil, backend.ErrNotSupported{
+		Message: "workflow instance tags",
+	}
+}
+
+func (sb *sqliteBackend) TailWorkflowInstanceLogs(ctx context.Context, instanceID string) (<-chan *backend.InstanceLogEntry, error) {
+	return nil, backend.ErrNotSupported{
+		Message: "tailing workflow instance logs",
+	}
+}
+
+func (sb *sqliteBackend) GetLatestWorkflowInstance(ctx context.Context, instanceID string) (*workflow.Instance, error) {
+	tx, err := sb.db.BeginTx(ctx, &sql.TxOptions{
+		ReadOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(
+		ctx,
+		"SELECT execution_id, parent_instance_id, parent_execution_id, parent_schedule_event_id FROM instances WHERE id = ? ORDER BY rowid DESC LIMIT 1",
+		instanceID,
+	)
+
+	var executionID string
+	var parentInstanceID, parentExecutionID *string
+	var parentEventID *int64
+	if err := row.Scan(&executionID, &parentInstanceID, &parentExecutionID, &parentEventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, fmt.Errorf("reading latest instance: %w", err)
+	}
+
+	if parentInstanceID != nil {
+		return core.NewSubWorkflowInstance(instanceID, executionID, core.NewWorkflowInstance(*parentInstanceID, *parentExecutionID), *parentEventID), nil
+	}
+
+	return core.NewWorkflowInstance(instanceID, executionID), nil
+}
+
+func (sb *sqliteBackend) GetLatestWorkflowInstanceState(ctx context.Context, instanceID string) (core.WorkflowInstanceState, error) {
+	row := sb.db.QueryRowContext(
+		ctx,
+		"SELECT state FROM instances WHERE id = ? ORDER BY rowid DESC LIMIT 1",
+		instanceID,
+	)
+
+	var state core.WorkflowInstanceState
+	if err := row.Scan(&state); err != nil {
+		if err == sql.ErrNoRows {
+			return core.WorkflowInstanceStateActive, backend.ErrInstanceNotFound
+		}
+
+		return core.WorkflowInstanceStateActive, fmt.Errorf("reading latest instance state: %w", err)
+	}
+
+	return state, nil
+}
+
 func (sb *sqliteBackend) SignalWorkflow(ctx context.Context, instanceID string, event *history.Event) error {
 	tx, err := sb.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -410,7 +598,8 @@ func (sb *sqliteBackend) SignalWorkflow(ctx context.Context, instanceID string,
 
 	// TODO: Combine this with the event insertion
 	var executionID string
-	res := tx.QueryRowContext(ctx, "SELECT execution_id FROM `instances` WHERE id = ? AND state = ? LIMIT 1", instanceID, core.WorkflowInstanceStateActive)
+	res := tx.QueryRowContext(ctx, "SELECT execution_id FROM `instances` WHERE id = ? AND state IN (?, ?) LIMIT 1",
+		instanceID, core.WorkflowInstanceStateActive, core.WorkflowInstanceStateErrored)
 	if err := res.Scan(&executionID); err == sql.ErrNoRows {
 		return backend.ErrInstanceNotFound
 	}
@@ -439,14 +628,14 @@ func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context, queues []workflow.
 
 	// Lock next workflow task by finding an unlocked instance with new events to process
 	// (work around missing LIMIT support in sqlite driver for UPDATE statements by using sub-query)
-	now := time.Now()
+	now := sb.options.Clock.Now()
 
 	args := []any{
 		now.Add(sb.options.WorkflowLockTimeout), // new locked_until
-		sb.workerName,
+		sb.worker(ctx),
 		now,                              // locked_until
 		now,                              // sticky_until
-		sb.workerName,                    // worker
+		sb.worker(ctx),                   // worker
 		core.WorkflowInstanceStateActive, // state
 	}
 
@@ -517,7 +706,7 @@ func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context, queues []workflow.
 	}
 
 	// Get new events
-	pendingEvents, err := getPendingEvents(ctx, tx, wfi)
+	pendingEvents, err := getPendingEvents(ctx, tx, wfi, now)
 	if err != nil {
 		return nil, fmt.Errorf("getting pending events: %w", err)
 	}
@@ -561,22 +750,41 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 
 	instance := task.WorkflowInstance
 
+	// Usage is accumulated with the instance, so it is updated atomically with the task
+	usage := backend.WorkflowTaskUsage(executedEvents)
+
+	// Keep the stored progress if the task did not report any
+	var progress any
+	if task.Progress != nil {
+		progress = []byte(task.Progress)
+	}
+
 	var completedAt *time.Time
 	if state == core.WorkflowInstanceStateContinuedAsNew || state == core.WorkflowInstanceStateFinished {
-		t := time.Now()
+		t := sb.options.Clock.Now()
 		completedAt = &t
 	}
 
 	// Unlock instance, but keep it sticky to the current worker
 	if res, err := tx.ExecContext(
 		ctx,
-		`UPDATE instances SET locked_until = NULL, sticky_until = ?, completed_at = ?, state = ? WHERE id = ? AND execution_id = ? AND worker = ?`,
-		time.Now().Add(sb.options.StickyTimeout),
+		`UPDATE instances SET locked_until = NULL, sticky_until = ?, completed_at = ?, state = ?,
+			usage_workflow_tasks = usage_workflow_tasks + ?, usage_activities = usage_activities + ?,
+			usage_activity_duration = usage_activity_duration + ?, usage_timers = usage_timers + ?,
+			usage_history_events = usage_history_events + ?, progress = COALESCE(?, progress), task_failures = 0
+		WHERE id = ? AND execution_id = ? AND worker = ?`,
+		sb.options.Clock.Now().Add(sb.options.StickyTimeout),
 		completedAt,
 		state,
+		usage.WorkflowTasks,
+		usage.Activities,
+		int64(usage.ActivityDuration),
+		usage.Timers,
+		usage.HistoryEvents,
+		progress,
 		instance.InstanceID,
 		instance.ExecutionID,
-		sb.workerName,
+		sb.worker(ctx),
 	); err != nil {
 		return fmt.Errorf("unlocking workflow instance: %w", err)
 	} else if n, err := res.RowsAffected(); err != nil {
@@ -635,10 +843,18 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 		}
 	}
 
+	if sb.options.CompactCanceledTimers {
+		if err := compactCanceledTimers(ctx, tx, instance); err != nil {
+			return fmt.Errorf("compacting history: %w", err)
+		}
+	}
+
 	// Insert new workflow events
-	groupedEvents := history.EventsByWorkflowInstance(workflowEvents)
+	groupedEvents := history.EventGroupsByWorkflowInstance(workflowEvents)
+
+	for _, group := range groupedEvents {
+		targetInstance, events := group.Instance, group.Events
 
-	for targetInstance, events := range groupedEvents {
 		// Are we creating a new sub-workflow instance?
 		m := events[0]
 		if m.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted {
@@ -653,7 +869,7 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 			if err := createInstance(ctx, tx, queue, m.WorkflowInstance, a.Metadata); err != nil {
 				if err == backend.ErrInstanceAlreadyExists {
 					if err := insertPendingEvents(ctx, tx, instance, []*history.Event{
-						history.NewPendingEvent(time.Now(), history.EventType_SubWorkflowFailed, &history.SubWorkflowFailedAttributes{
+						history.NewPendingEvent(sb.options.Clock.Now(), history.EventType_SubWorkflowFailed, &history.SubWorkflowFailedAttributes{
 							Error: workflowerrors.FromError(backend.ErrInstanceAlreadyExists),
 						}, history.ScheduleEventID(m.WorkflowInstance.ParentEventID)),
 					}); err != nil {
@@ -672,7 +888,7 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 		for _, m := range events {
 			historyEvents = append(historyEvents, m.HistoryEvent)
 		}
-		if err := insertPendingEvents(ctx, tx, &targetInstance, historyEvents); err != nil {
+		if err := insertPendingEvents(ctx, tx, targetInstance, historyEvents); err != nil {
 			return fmt.Errorf("inserting messages: %w", err)
 		}
 	}
@@ -683,7 +899,19 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	sb.events.Dispatch(instance, executedEvents)
+
+	sb.subscriptions.notify(&backend.WorkflowInstanceUpdate{
+		Instance:  instance,
+		State:     state,
+		Timestamp: sb.options.Clock.Now(),
+	})
+
+	return nil
 }
 
 func (sb *sqliteBackend) ExtendWorkflowTask(ctx context.Context, task *backend.WorkflowTask) error {
@@ -693,14 +921,14 @@ func (sb *sqliteBackend) ExtendWorkflowTask(ctx context.Context, task *backend.W
 	}
 	defer tx.Rollback()
 
-	until := time.Now().Add(sb.options.WorkflowLockTimeout)
+	until := sb.options.Clock.Now().Add(sb.options.WorkflowLockTimeout)
 	res, err := tx.ExecContext(
 		ctx,
 		`UPDATE instances SET locked_until = ? WHERE id = ? AND execution_id = ? AND worker = ?`,
 		until,
 		task.WorkflowInstance.InstanceID,
 		task.WorkflowInstance.ExecutionID,
-		sb.workerName,
+		sb.worker(ctx),
 	)
 	if err != nil {
 		return fmt.Errorf("extending workflow task lock: %w", err)
@@ -709,7 +937,44 @@ func (sb *sqliteBackend) ExtendWorkflowTask(ctx context.Context, task *backend.W
 	if rowsAffected, err := res.RowsAffected(); err != nil {
 		return fmt.Errorf("determining if workflow task was extended: %w", err)
 	} else if rowsAffected == 0 {
-		return errors.New("could not extend workflow task")
+		return fmt.Errorf("could not extend workflow task: %w", backend.ErrTaskLockLost)
+	}
+
+	return tx.Commit()
+}
+
+func (sb *sqliteBackend) FailWorkflowTask(ctx context.Context, task *backend.WorkflowTask, maxFailures int) error {
+	tx, err := sb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The instance becomes errored and is unlocked once it reaches the maximum number of failures, otherwise the
+	// task stays locked until its lock expires. Assignments refer to the current number of failures, so it has to
+	// be incremented last.
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE instances SET
+			state = CASE WHEN ? > 0 AND task_failures + 1 >= ? THEN ? ELSE state END,
+			locked_until = CASE WHEN ? > 0 AND task_failures + 1 >= ? THEN NULL ELSE locked_until END,
+			task_failures = task_failures + 1
+		WHERE id = ? AND execution_id = ? AND worker = ? AND state = ?`,
+		maxFailures, maxFailures, core.WorkflowInstanceStateErrored,
+		maxFailures, maxFailures,
+		task.WorkflowInstance.InstanceID,
+		task.WorkflowInstance.ExecutionID,
+		sb.worker(ctx),
+		core.WorkflowInstanceStateActive,
+	)
+	if err != nil {
+		return fmt.Errorf("recording failed workflow task: %w", err)
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("determining if failed workflow task was recorded: %w", err)
+	} else if rowsAffected == 0 {
+		return fmt.Errorf("could not record failed workflow task: %w", backend.ErrTaskLockLost)
 	}
 
 	return tx.Commit()
@@ -724,11 +989,11 @@ func (sb *sqliteBackend) GetActivityTask(ctx context.Context, queues []workflow.
 
 	// Lock next activity
 	// (work around missing LIMIT support in sqlite driver for UPDATE statements by using sub-query)
-	now := time.Now()
+	now := sb.options.Clock.Now()
 
 	args := []interface{}{
 		now.Add(sb.options.ActivityLockTimeout),
-		sb.workerName,
+		sb.worker(ctx),
 		now,
 	}
 
@@ -736,12 +1001,15 @@ func (sb *sqliteBackend) GetActivityTask(ctx context.Context, queues []workflow.
 		args = append(args, string(q))
 	}
 
+	args = append(args, activityDispatchPausedSetting)
+
 	row := tx.QueryRowContext(
 		ctx,
 		fmt.Sprintf(`UPDATE activities
 			SET locked_until = ?, worker = ?
 			WHERE rowid = (
-				SELECT rowid FROM activities WHERE (locked_until IS NULL OR locked_until < ?) AND queue IN (?%s) LIMIT 1
+				SELECT rowid FROM activities WHERE (locked_until IS NULL OR locked_until < ?) AND queue IN (?%s)
+					AND NOT EXISTS (SELECT 1 FROM settings WHERE name = ?) LIMIT 1
 			) RETURNING id, instance_id, execution_id, event_type, timestamp, schedule_event_id, visible_at`, strings.Repeat(",?", len(queues)-1)),
 		args...,
 	)
@@ -818,7 +1086,7 @@ func (sb *sqliteBackend) CompleteActivityTask(ctx context.Context, task *backend
 		task.WorkflowInstance.InstanceID,
 		task.WorkflowInstance.ExecutionID,
 		task.ActivityID,
-		sb.workerName,
+		sb.worker(ctx),
 	); err != nil {
 		return fmt.Errorf("unlocking instance: %w", err)
 	} else if n, err := res.RowsAffected(); err != nil {
@@ -842,13 +1110,13 @@ func (sb *sqliteBackend) ExtendActivityTask(ctx context.Context, task *backend.A
 	}
 	defer tx.Rollback()
 
-	until := time.Now().Add(sb.options.ActivityLockTimeout)
+	until := sb.options.Clock.Now().Add(sb.options.ActivityLockTimeout)
 	res, err := tx.ExecContext(
 		ctx,
 		`UPDATE activities SET locked_until = ? WHERE id = ? AND worker = ?`,
 		until,
 		task.ActivityID,
-		sb.workerName,
+		sb.worker(ctx),
 	)
 	if err != nil {
 		return fmt.Errorf("extending activity lock: %w", err)
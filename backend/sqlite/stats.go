@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/core"
@@ -37,7 +36,7 @@ func (b *sqliteBackend) GetStats(ctx context.Context) (*backend.Stats, error) {
 	s.ActiveWorkflowInstances = activeInstances
 
 	// Get workflow instances ready to be picked up
-	now := time.Now()
+	now := b.options.Clock.Now()
 	workflowRows, err := tx.QueryContext(
 		ctx,
 		`SELECT i.queue, COUNT(*) FROM instances i
@@ -92,3 +91,32 @@ func (b *sqliteBackend) GetStats(ctx context.Context) (*backend.Stats, error) {
 
 	return s, nil
 }
+
+// QueueLag returns the number of workflow instances with events waiting to be processed, including instances
+// locked by a worker, and the number of activities that have not been completed
+func (b *sqliteBackend) QueueLag(ctx context.Context) (int64, int64, error) {
+	var workflowLag, activityLag int64
+
+	now := b.options.Clock.Now()
+	if err := b.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM instances i
+			WHERE
+				i.state = ? AND i.completed_at IS NULL
+				AND EXISTS (
+					SELECT 1
+						FROM pending_events
+						WHERE instance_id = i.id AND execution_id = i.execution_id AND (visible_at IS NULL OR visible_at <= ?)
+				)`,
+		core.WorkflowInstanceStateActive, // state
+		now,                              // pending_event.visible_at
+	).Scan(&workflowLag); err != nil {
+		return 0, 0, fmt.Errorf("failed to query workflow queue lag: %w", err)
+	}
+
+	if err := b.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM activities").Scan(&activityLag); err != nil {
+		return 0, 0, fmt.Errorf("failed to query activity queue lag: %w", err)
+	}
+
+	return workflowLag, activityLag, nil
+}
@@ -0,0 +1,140 @@
+package sqlite
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/core"
+)
+
+// subscriptions keeps track of in-process subscribers to workflow instance updates
+type subscriptions struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	mu      sync.Mutex
+	pending []*backend.WorkflowInstanceUpdate
+
+	// signal is notified whenever an update is added to pending
+	signal chan struct{}
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{
+		subscribers: make(map[string]map[*subscriber]struct{}),
+	}
+}
+
+func (s *subscriptions) add(instanceID string) *subscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := &subscriber{
+		signal: make(chan struct{}, 1),
+	}
+
+	if s.subscribers[instanceID] == nil {
+		s.subscribers[instanceID] = make(map[*subscriber]struct{})
+	}
+	s.subscribers[instanceID][sub] = struct{}{}
+
+	return sub
+}
+
+func (s *subscriptions) remove(instanceID string, sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.subscribers[instanceID], sub)
+	if len(s.subscribers[instanceID]) == 0 {
+		delete(s.subscribers, instanceID)
+	}
+}
+
+// notify queues the update for all subscribers of the instance without blocking
+func (s *subscriptions) notify(update *backend.WorkflowInstanceUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subscribers[update.Instance.InstanceID] {
+		sub.push(update)
+	}
+}
+
+func (sub *subscriber) push(update *backend.WorkflowInstanceUpdate) {
+	sub.mu.Lock()
+	sub.pending = append(sub.pending, update)
+	sub.mu.Unlock()
+
+	select {
+	case sub.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (sub *subscriber) take() []*backend.WorkflowInstanceUpdate {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	updates := sub.pending
+	sub.pending = nil
+
+	return updates
+}
+
+func (sb *sqliteBackend) SubscribeWorkflowInstanceUpdates(ctx context.Context, instanceID string) (<-chan *backend.WorkflowInstanceUpdate, error) {
+	// Register before checking the state, so that no update is missed in between
+	sub := sb.subscriptions.add(instanceID)
+
+	instance, err := sb.GetLatestWorkflowInstance(ctx, instanceID)
+	if err != nil {
+		sb.subscriptions.remove(instanceID, sub)
+		return nil, err
+	}
+
+	state, err := sb.GetWorkflowInstanceState(ctx, instance)
+	if err != nil {
+		sb.subscriptions.remove(instanceID, sub)
+		return nil, err
+	}
+
+	if state == core.WorkflowInstanceStateFinished {
+		sub.push(&backend.WorkflowInstanceUpdate{
+			Instance:  instance,
+			State:     state,
+			Timestamp: sb.options.Clock.Now(),
+		})
+	}
+
+	c := make(chan *backend.WorkflowInstanceUpdate, 1)
+
+	go func() {
+		defer close(c)
+		defer sb.subscriptions.remove(instanceID, sub)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sub.signal:
+			}
+
+			for _, update := range sub.take() {
+				select {
+				case <-ctx.Done():
+					return
+				case c <- update:
+				}
+
+				if update.State == core.WorkflowInstanceStateFinished {
+					return
+				}
+			}
+		}
+	}()
+
+	return c, nil
+}
@@ -0,0 +1,19 @@
+package backend
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/core"
+)
+
+// WorkflowInstanceUpdate is emitted to subscribers whenever a workflow task for an instance has been completed
+type WorkflowInstanceUpdate struct {
+	// Instance is the execution that was updated
+	Instance *core.WorkflowInstance
+
+	// State is the state of the execution after the update
+	State core.WorkflowInstanceState
+
+	// Timestamp is the time of the update
+	Timestamp time.Time
+}
@@ -0,0 +1,19 @@
+package backend
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/core"
+)
+
+// TaggedWorkflowInstance is an execution of a workflow instance that has been started with a tag
+type TaggedWorkflowInstance struct {
+	Instance *core.WorkflowInstance
+
+	State core.WorkflowInstanceState
+
+	CreatedAt time.Time
+
+	// CompletedAt is the time the execution finished, nil while it is active
+	CompletedAt *time.Time
+}
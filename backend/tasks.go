@@ -3,6 +3,7 @@ package backend
 import (
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/backend/metadata"
+	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/cschleiden/go-workflows/core"
 	"github.com/cschleiden/go-workflows/workflow"
 )
@@ -30,6 +31,10 @@ type WorkflowTask struct {
 	// NewEvents are new events since the last task execution
 	NewEvents []*history.Event
 
+	// Progress is the latest progress reported by the workflow while executing this task, if any. It's set by the
+	// worker and stored by the backend when the task is completed.
+	Progress payload.Payload
+
 	// Backend specific data, only the producer of the task should rely on this.
 	CustomData any
 }
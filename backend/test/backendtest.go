@@ -6,7 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/converter"
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/backend/metadata"
 	"github.com/cschleiden/go-workflows/backend/payload"
@@ -20,8 +22,9 @@ import (
 
 func BackendTest(t *testing.T, setup func(options ...backend.BackendOption) TestBackend, teardown func(b TestBackend)) {
 	tests := []struct {
-		name string
-		f    func(t *testing.T, ctx context.Context, b backend.Backend)
+		name    string
+		options []backend.BackendOption
+		f       func(t *testing.T, ctx context.Context, b backend.Backend)
 	}{
 		{
 			name: "CreateWorkflowInstance_DoesNotError",
@@ -93,6 +96,44 @@ func BackendTest(t *testing.T, setup func(options ...backend.BackendOption) Test
 				require.Equal(t, *metadata, *task.Metadata)
 			},
 		},
+		{
+			name: "CreateWorkflowInstance_Signals",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+				arg, err := converter.DefaultConverter.To("value")
+				require.NoError(t, err)
+
+				err = b.CreateWorkflowInstance(
+					ctx,
+					wfi,
+					history.NewPendingEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+						Queue:          workflow.QueueDefault,
+						InitialSignals: 1,
+					}),
+					history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{
+						Name: "signal",
+						Arg:  arg,
+					}),
+				)
+				require.NoError(t, err)
+
+				queues := []workflow.Queue{workflow.QueueDefault, core.QueueSystem}
+				require.NoError(t, b.PrepareWorkflowQueues(ctx, queues))
+				task, err := b.GetWorkflowTask(ctx, queues)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				// Signals are delivered with the first task, after the started event
+				require.Len(t, task.NewEvents, 2)
+				require.Equal(t, history.EventType_WorkflowExecutionStarted, task.NewEvents[0].Type)
+				require.Equal(t, history.EventType_SignalReceived, task.NewEvents[1].Type)
+
+				a := task.NewEvents[1].Attributes.(*history.SignalReceivedAttributes)
+				require.Equal(t, "signal", a.Name)
+				require.Equal(t, arg, a.Arg)
+			},
+		},
 		{
 			name: "RemoveWorkflowInstance_ErrorWhenInstanceInProgress",
 			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
@@ -122,6 +163,90 @@ func BackendTest(t *testing.T, setup func(options ...backend.BackendOption) Test
 				require.Equal(t, backend.ErrInstanceNotFound, err)
 			},
 		},
+		{
+			name: "RetryWorkflowInstance_ErrorWhenInstanceActive",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				startWorkflow(t, ctx, b, wfi)
+
+				err := b.RetryWorkflowInstance(ctx, wfi)
+				require.ErrorIs(t, err, backend.ErrInstanceNotErrored)
+
+				state, err := b.GetWorkflowInstanceState(ctx, wfi)
+				require.NoError(t, err)
+				require.Equal(t, core.WorkflowInstanceStateActive, state)
+			},
+		},
+		{
+			name: "RetryWorkflowInstance_ErrorWhenInstanceFinished",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				startedEvent := history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+					Queue:    workflow.QueueDefault,
+					Name:     "some-workflow",
+					Inputs:   []payload.Payload{},
+					Metadata: &metadata.WorkflowMetadata{},
+				})
+
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err := b.CreateWorkflowInstance(ctx, wfi, startedEvent)
+				require.NoError(t, err)
+
+				queues := []workflow.Queue{workflow.QueueDefault, core.QueueSystem}
+				require.NoError(t, b.PrepareWorkflowQueues(ctx, queues))
+
+				task, err := b.GetWorkflowTask(ctx, queues)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				events := []*history.Event{
+					history.NewHistoryEvent(-1, time.Now(), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+					startedEvent,
+					history.NewHistoryEvent(-1, time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{}),
+				}
+
+				sequenceID := int64(1)
+				for i := range events {
+					sequenceID++
+					events[i].SequenceID = sequenceID
+				}
+
+				err = b.CompleteWorkflowTask(ctx, task, core.WorkflowInstanceStateFinished, events, []*history.Event{}, []*history.Event{}, []*history.WorkflowEvent{})
+				require.NoError(t, err)
+
+				err = b.RetryWorkflowInstance(ctx, wfi)
+				require.ErrorIs(t, err, backend.ErrInstanceNotErrored)
+
+				state, err := b.GetWorkflowInstanceState(ctx, wfi)
+				require.NoError(t, err)
+				require.Equal(t, core.WorkflowInstanceStateFinished, state)
+			},
+		},
+		{
+			name: "GetLatestWorkflowInstance_ReturnsInstance",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+				err := b.CreateWorkflowInstance(
+					ctx, wfi, history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+						Queue: workflow.QueueDefault,
+					}),
+				)
+				require.NoError(t, err)
+
+				latest, err := b.GetLatestWorkflowInstance(ctx, wfi.InstanceID)
+				require.NoError(t, err)
+				require.Equal(t, wfi.InstanceID, latest.InstanceID)
+				require.Equal(t, wfi.ExecutionID, latest.ExecutionID)
+			},
+		},
+		{
+			name: "GetLatestWorkflowInstance_ErrorWhenInstanceDoesNotExist",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				_, err := b.GetLatestWorkflowInstance(ctx, uuid.NewString())
+				require.Error(t, err)
+				require.Equal(t, backend.ErrInstanceNotFound, err)
+			},
+		},
 		{
 			name: "GetWorkflowTask_ReturnsNilWhenTimeout",
 			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
@@ -212,6 +337,115 @@ func BackendTest(t *testing.T, setup func(options ...backend.BackendOption) Test
 				require.True(t, err == nil || errors.Is(err, context.DeadlineExceeded))
 			},
 		},
+		{
+			name: "DeregisterWorker_ReleasesLockedTask",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err := b.CreateWorkflowInstance(
+					ctx, wfi, history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+						Queue: workflow.QueueDefault,
+					}),
+				)
+				require.NoError(t, err)
+
+				queues := []workflow.Queue{workflow.QueueDefault, core.QueueSystem}
+				require.NoError(t, b.PrepareWorkflowQueues(ctx, queues))
+
+				task, err := b.GetWorkflowTask(backend.WithWorkerID(ctx, "worker-1"), queues)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				err = b.DeregisterWorker(ctx, "worker-1")
+				if errors.As(err, &backend.ErrNotSupported{}) {
+					t.Skip("backend does not support deregistering workers")
+				}
+				require.NoError(t, err)
+
+				// Task is available again right away
+				task, err = b.GetWorkflowTask(backend.WithWorkerID(ctx, "worker-2"), queues)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, wfi.InstanceID, task.WorkflowInstance.InstanceID)
+			},
+		},
+		{
+			name: "DeregisterWorker_KeepsTasksOfOtherWorkers",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				queues := []workflow.Queue{workflow.QueueDefault, core.QueueSystem}
+				require.NoError(t, b.PrepareWorkflowQueues(ctx, queues))
+
+				wfi1 := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				wfi2 := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				for _, wfi := range []*core.WorkflowInstance{wfi1, wfi2} {
+					err := b.CreateWorkflowInstance(
+						ctx, wfi, history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+							Queue: workflow.QueueDefault,
+						}),
+					)
+					require.NoError(t, err)
+				}
+
+				// Both workers use the same backend instance
+				task1, err := b.GetWorkflowTask(backend.WithWorkerID(ctx, "worker-1"), queues)
+				require.NoError(t, err)
+				require.NotNil(t, task1)
+
+				task2, err := b.GetWorkflowTask(backend.WithWorkerID(ctx, "worker-2"), queues)
+				require.NoError(t, err)
+				require.NotNil(t, task2)
+
+				err = b.DeregisterWorker(ctx, "worker-1")
+				if errors.As(err, &backend.ErrNotSupported{}) {
+					t.Skip("backend does not support deregistering workers")
+				}
+				require.NoError(t, err)
+
+				// Only the task of the deregistered worker is available again
+				task, err := b.GetWorkflowTask(backend.WithWorkerID(ctx, "worker-3"), queues)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, task1.WorkflowInstance.InstanceID, task.WorkflowInstance.InstanceID)
+
+				ctx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+				defer cancel()
+
+				task, err = b.GetWorkflowTask(backend.WithWorkerID(ctx, "worker-3"), queues)
+				require.Nil(t, task)
+				require.True(t, err == nil || errors.Is(err, context.DeadlineExceeded))
+
+				// The other worker still holds its task
+				require.NoError(t, b.ExtendWorkflowTask(backend.WithWorkerID(context.Background(), "worker-2"), task2))
+			},
+		},
+		{
+			name: "ExtendWorkflowTask_ReturnsLockLostIfNotLocked",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err := b.CreateWorkflowInstance(ctx, wfi, history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+					Queue: workflow.QueueDefault,
+				}))
+				require.NoError(t, err)
+
+				queues := []workflow.Queue{workflow.QueueDefault, core.QueueSystem}
+				require.NoError(t, b.PrepareWorkflowQueues(ctx, queues))
+
+				tk, err := b.GetWorkflowTask(ctx, queues)
+				require.NoError(t, err)
+				require.NotNil(t, tk)
+
+				require.NoError(t, b.ExtendWorkflowTask(ctx, tk))
+
+				// Lose the lock, like when it expires and another worker picks up the task
+				err = b.DeregisterWorker(ctx, "")
+				if errors.As(err, &backend.ErrNotSupported{}) {
+					t.Skip("backend does not support deregistering workers")
+				}
+				require.NoError(t, err)
+
+				err = b.ExtendWorkflowTask(ctx, tk)
+				require.ErrorIs(t, err, backend.ErrTaskLockLost)
+			},
+		},
 		{
 			name: "CompleteWorkflowTask_ReturnsErrorIfNotLocked",
 			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
@@ -371,6 +605,51 @@ func BackendTest(t *testing.T, setup func(options ...backend.BackendOption) Test
 				require.Equal(t, history.EventType_WorkflowExecutionCanceled, task.NewEvents[len(task.NewEvents)-1].Type)
 			},
 		},
+		{
+			name:    "CompleteWorkflowTask_FutureEventsUseBackendClock",
+			options: []backend.BackendOption{backend.WithClock(clock.NewMock())},
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				c := b.Options().Clock.(*clock.Mock)
+				c.Set(time.Now())
+
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err := b.CreateWorkflowInstance(ctx, wfi, history.NewHistoryEvent(1, c.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+					Queue: workflow.QueueDefault,
+				}))
+				require.NoError(t, err)
+
+				queues := []workflow.Queue{workflow.QueueDefault, core.QueueSystem}
+				require.NoError(t, b.PrepareWorkflowQueues(ctx, queues))
+
+				task, err := b.GetWorkflowTask(ctx, queues)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				at := c.Now().Add(time.Hour)
+				timerEvent := history.NewPendingEvent(c.Now(), history.EventType_TimerFired, &history.TimerFiredAttributes{
+					ScheduledAt: c.Now(),
+					At:          at,
+				}, history.ScheduleEventID(1), history.VisibleAt(at))
+
+				err = b.CompleteWorkflowTask(ctx, task, core.WorkflowInstanceStateActive, task.NewEvents, []*history.Event{}, []*history.Event{timerEvent}, []*history.WorkflowEvent{})
+				require.NoError(t, err)
+
+				// Timer has not fired yet according to the backend clock
+				tctx, cancel := context.WithTimeout(ctx, time.Millisecond*100)
+				defer cancel()
+
+				task, err = b.GetWorkflowTask(tctx, queues)
+				require.True(t, err == nil || errors.Is(err, context.DeadlineExceeded))
+				require.Nil(t, task)
+
+				c.Add(2 * time.Hour)
+
+				task, err = b.GetWorkflowTask(ctx, queues)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, history.EventType_TimerFired, task.NewEvents[len(task.NewEvents)-1].Type)
+			},
+		},
 		{
 			name: "SignalWorkflow_ErrorWhenInstanceDoesNotExist",
 			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
@@ -441,6 +720,27 @@ func BackendTest(t *testing.T, setup func(options ...backend.BackendOption) Test
 				require.Equal(t, wfiCustom.InstanceID, task.WorkflowInstance.InstanceID)
 			},
 		},
+		{
+			name: "PauseActivityDispatch",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				require.NoError(t, b.PauseActivityDispatch(ctx, true))
+
+				wfi := runWorkflowWithActivity(t, ctx, b, workflow.QueueDefault, workflow.QueueDefault)
+
+				require.NoError(t, b.PrepareActivityQueues(ctx, []workflow.Queue{workflow.QueueDefault}))
+
+				task, err := b.GetActivityTask(ctx, []workflow.Queue{workflow.QueueDefault})
+				require.NoError(t, err)
+				require.Nil(t, task)
+
+				require.NoError(t, b.PauseActivityDispatch(ctx, false))
+
+				task, err = b.GetActivityTask(ctx, []workflow.Queue{workflow.QueueDefault})
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, wfi.InstanceID, task.WorkflowInstance.InstanceID)
+			},
+		},
 		{
 			name: "CompleteActivityTask_DeliversResultBackToWorkflowQueue",
 			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
@@ -464,11 +764,33 @@ func BackendTest(t *testing.T, setup func(options ...backend.BackendOption) Test
 				require.Equal(t, history.EventType_ActivityCompleted, wfTask.NewEvents[len(wfTask.NewEvents)-1].Type)
 			},
 		},
+		{
+			name: "CacheActivityResultIfAbsent_OnlyCachesOnce",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				key := uuid.NewString()
+
+				added, err := b.CacheActivityResultIfAbsent(ctx, key, payload.Payload("1"), time.Hour)
+				if errors.As(err, &backend.ErrNotSupported{}) {
+					t.Skip("backend does not support caching activity results")
+				}
+				require.NoError(t, err)
+				require.True(t, added)
+
+				added, err = b.CacheActivityResultIfAbsent(ctx, key, payload.Payload("2"), time.Hour)
+				require.NoError(t, err)
+				require.False(t, added)
+
+				result, ok, err := b.GetActivityResult(ctx, key)
+				require.NoError(t, err)
+				require.True(t, ok)
+				require.Equal(t, payload.Payload("1"), result)
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			b := setup()
+			b := setup(tt.options...)
 			ctx := context.Background()
 
 			t.Cleanup(func() {
@@ -2,17 +2,24 @@ package test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/cschleiden/go-workflows/activity"
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/client"
 	"github.com/cschleiden/go-workflows/core"
 	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/registry"
+	"github.com/cschleiden/go-workflows/replay"
 	"github.com/cschleiden/go-workflows/worker"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/cschleiden/go-workflows/workflow/executor"
@@ -108,6 +115,201 @@ func EndToEndBackendTest(t *testing.T, setup func(options ...backend.BackendOpti
 				require.Equal(t, int64(0), events[2].ScheduleEventID)
 			},
 		},
+		{
+			name: "SimpleWorkflow_HistoryWithoutActivityInputs",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				a := func(ctx context.Context, msg string) (string, error) {
+					return msg + " world", nil
+				}
+				wf := func(ctx workflow.Context, msg string) (string, error) {
+					return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, a, msg).Get(ctx)
+				}
+				register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+				instance := runWorkflow(t, ctx, c, wf, "hello")
+
+				output, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, "hello world", output)
+
+				scheduledAttributes := func(options ...backend.HistoryOption) *history.ActivityScheduledAttributes {
+					events, err := b.GetWorkflowInstanceHistory(ctx, instance, nil, options...)
+					require.NoError(t, err)
+
+					for _, event := range events {
+						if event.Type == history.EventType_ActivityScheduled {
+							return event.Attributes.(*history.ActivityScheduledAttributes)
+						}
+					}
+
+					require.FailNow(t, "activity scheduled event not found")
+					return nil
+				}
+
+				require.Len(t, scheduledAttributes().Inputs, 1)
+
+				attributes := scheduledAttributes(backend.HistoryWithoutActivityInputs())
+				require.NotEmpty(t, attributes.Name)
+				require.Nil(t, attributes.Inputs)
+			},
+		},
+		{
+			name: "SimpleWorkflow_MultipleResults",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				wf := func(ctx workflow.Context, msg string) (string, int, error) {
+					return msg + " world", 42, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf, "hello")
+
+				var r1 string
+				var r2 int
+				err := client.GetWorkflowResults(ctx, c, instance, time.Second*10, &r1, &r2)
+				require.NoError(t, err)
+				require.Equal(t, "hello world", r1)
+				require.Equal(t, 42, r2)
+
+				err = client.GetWorkflowResults(ctx, c, instance, time.Second*10, &r1)
+				require.ErrorContains(t, err, "mismatched result count")
+			},
+		},
+		{
+			name: "SimpleWorkflow_RegisteredActivityRetryOptions",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				var attempts int32
+				a := func(ctx context.Context) (int, error) {
+					atomic.AddInt32(&attempts, 1)
+					return 0, errors.New("activity failed")
+				}
+				wf := func(ctx workflow.Context) (int, error) {
+					return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+				}
+				require.NoError(t, w.RegisterActivity(a, registry.WithRetryOptions(workflow.RetryOptions{
+					MaxAttempts: 2,
+				})))
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				_, err := runWorkflowWithResult[int](t, ctx, c, wf)
+				require.Error(t, err)
+				require.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+			},
+		},
+		{
+			name: "SimpleWorkflow_ActivityValidationFailure",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				var validations, executions int32
+				a := func(ctx context.Context, msg string) (string, error) {
+					atomic.AddInt32(&executions, 1)
+					return msg, nil
+				}
+				wf := func(ctx workflow.Context) (string, error) {
+					return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, a, "").Get(ctx)
+				}
+				require.NoError(t, w.RegisterActivity(a, registry.WithValidator(func(ctx context.Context, args ...interface{}) error {
+					atomic.AddInt32(&validations, 1)
+
+					if args[0].(string) == "" {
+						return errors.New("msg must not be empty")
+					}
+
+					return nil
+				})))
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				_, err := runWorkflowWithResult[string](t, ctx, c, wf)
+				require.ErrorContains(t, err, "msg must not be empty")
+
+				// Validation errors are not retried
+				require.Equal(t, int32(1), atomic.LoadInt32(&validations))
+				require.Equal(t, int32(0), atomic.LoadInt32(&executions))
+			},
+		},
+		{
+			name: "SimpleWorkflow_ActivityResultValidation",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				var executions int32
+				a := func(ctx context.Context) (string, error) {
+					// Return an invalid result for the first execution
+					if atomic.AddInt32(&executions, 1) == 1 {
+						return "", nil
+					}
+
+					return "result", nil
+				}
+				wf := func(ctx workflow.Context) (string, error) {
+					return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+				}
+				require.NoError(t, w.RegisterActivity(a, registry.WithResultValidator(func(ctx context.Context, result interface{}) error {
+					if result.(string) == "" {
+						return errors.New("result must not be empty")
+					}
+
+					return nil
+				})))
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				output, err := runWorkflowWithResult[string](t, ctx, c, wf)
+				require.NoError(t, err)
+				require.Equal(t, "result", output)
+				require.Equal(t, int32(2), atomic.LoadInt32(&executions))
+			},
+		},
+		{
+			name: "SimpleWorkflow_ActivityResultValidation_Permanent",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				var executions int32
+				a := func(ctx context.Context) (string, error) {
+					atomic.AddInt32(&executions, 1)
+					return "", nil
+				}
+				wf := func(ctx workflow.Context) (string, error) {
+					return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+				}
+				require.NoError(t, w.RegisterActivity(a, registry.WithResultValidator(func(ctx context.Context, result interface{}) error {
+					return workflow.NewPermanentError(errors.New("result must not be empty"))
+				})))
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				_, err := runWorkflowWithResult[string](t, ctx, c, wf)
+				require.ErrorContains(t, err, "result must not be empty")
+				require.Equal(t, int32(1), atomic.LoadInt32(&executions))
+			},
+		},
+		{
+			name: "SimpleWorkflow_ActivityByName",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				a := func(ctx context.Context, msg string, n int) (string, error) {
+					return fmt.Sprintf("%s %d", msg, n), nil
+				}
+				wf := func(ctx workflow.Context) (string, error) {
+					args := []interface{}{"hello", 42}
+					return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, "Greet", args...).Get(ctx)
+				}
+				require.NoError(t, w.RegisterActivity(a, registry.WithName("Greet")))
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				output, err := runWorkflowWithResult[string](t, ctx, c, wf)
+				require.NoError(t, err)
+				require.Equal(t, "hello 42", output)
+			},
+		},
+		{
+			name: "SimpleWorkflow_ActivityByName_MismatchedArguments",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				a := func(ctx context.Context, msg string, n int) (string, error) {
+					return fmt.Sprintf("%s %d", msg, n), nil
+				}
+				wf := func(ctx workflow.Context) (string, error) {
+					return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, "Greet", 42, "hello").Get(ctx)
+				}
+				require.NoError(t, w.RegisterActivity(a, registry.WithName("Greet")))
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				_, err := runWorkflowWithResult[string](t, ctx, c, wf)
+				require.ErrorContains(t, err, "activity Greet: mismatched argument type")
+			},
+		},
 		{
 			name: "UnregisteredWorkflow_Errors",
 			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
@@ -205,6 +407,88 @@ func EndToEndBackendTest(t *testing.T, setup func(options ...backend.BackendOpti
 				require.Equal(t, 7, r)
 			},
 		},
+		{
+			name: "GetVersion_RecordsVersion",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				wf := func(ctx workflow.Context) (int, error) {
+					v1, err := workflow.GetVersion(ctx, "change", workflow.DefaultVersion, 2)
+					if err != nil {
+						return 0, err
+					}
+
+					// Do something to force the task to end
+					workflow.Sleep(ctx, time.Millisecond*1)
+
+					// The version is only recorded once
+					v2, err := workflow.GetVersion(ctx, "change", workflow.DefaultVersion, 3)
+					if err != nil {
+						return 0, err
+					}
+
+					return v1*10 + v2, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, 22, r)
+
+				h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+				require.NoError(t, err)
+
+				versions := 0
+				for _, event := range h {
+					if event.Type == history.EventType_VersionRecorded {
+						versions++
+					}
+				}
+				require.Equal(t, 1, versions)
+			},
+		},
+		{
+			name: "Signal_OnSignal",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				wf := func(ctx workflow.Context) (string, error) {
+					ctx, cancel := workflow.WithCancel(ctx)
+					defer cancel()
+
+					names := []string{}
+					workflow.OnSignal(ctx, "name", func(ctx workflow.Context, name string) {
+						names = append(names, name)
+					})
+
+					sum := 0
+					workflow.OnSignal(ctx, "count", func(ctx workflow.Context, n int) {
+						sum += n
+					})
+
+					workflow.NewSignalChannel[bool](ctx, "done").Receive(ctx)
+
+					return fmt.Sprintf("%s:%d", strings.Join(names, ","), sum), nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				// Wait for the handlers to be registered
+				require.Eventually(t, func() bool {
+					h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+					return err == nil && len(h) > 0
+				}, time.Second*10, time.Millisecond*10)
+
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "name", "a"))
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "count", 1))
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "name", "b"))
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "count", 2))
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "done", true))
+
+				r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, "a,b:3", r)
+			},
+		},
 		{
 			name: "Signal_after_completion",
 			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
@@ -222,6 +506,123 @@ func EndToEndBackendTest(t *testing.T, setup func(options ...backend.BackendOpti
 				require.ErrorIs(t, err, backend.ErrInstanceNotFound)
 			},
 		},
+		{
+			name: "Signal_InitialSignals",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				wf := func(ctx workflow.Context) (string, error) {
+					// Initial signals are available without waiting
+					name, ok := workflow.NewSignalChannel[string](ctx, "name").ReceiveNonBlocking()
+					if !ok {
+						return "", errors.New("name signal not received")
+					}
+
+					n, ok := workflow.NewSignalChannel[int](ctx, "count").ReceiveNonBlocking()
+					if !ok {
+						return "", errors.New("count signal not received")
+					}
+
+					return fmt.Sprintf("%s:%d", name, n), nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+					InstanceID: uuid.NewString(),
+					InitialSignals: []client.Signal{
+						{Name: "name", Arg: "a"},
+						{Name: "count", Arg: 42},
+					},
+				}, wf)
+				require.NoError(t, err)
+
+				r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, "a:42", r)
+			},
+		},
+		{
+			name: "Signal_DeliveredInOrder",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				wf := func(ctx workflow.Context) (string, error) {
+					// Signals sent while sleeping are received before the channel is created
+					if err := workflow.Sleep(ctx, time.Millisecond*200); err != nil {
+						return "", err
+					}
+
+					received := []string{}
+					sc := workflow.NewSignalChannel[string](ctx, "signal")
+					for len(received) < 6 {
+						s, _ := sc.Receive(ctx)
+						received = append(received, s)
+
+						// Interleave timers with the remaining signals
+						if err := workflow.Sleep(ctx, time.Millisecond*10); err != nil {
+							return "", err
+						}
+					}
+
+					return strings.Join(received, ","), nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				for i := 1; i <= 6; i++ {
+					require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "signal", strconv.Itoa(i)))
+
+					if i > 3 {
+						// Spread the remaining signals across multiple workflow tasks
+						time.Sleep(time.Millisecond * 20)
+					}
+				}
+
+				r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, "1,2,3,4,5,6", r)
+
+				// Signals are recorded in the order they were sent
+				h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+				require.NoError(t, err)
+
+				signals := []string{}
+				for _, event := range h {
+					if event.Type == history.EventType_SignalReceived {
+						var s string
+						require.NoError(t, b.Options().Converter.From(event.Attributes.(*history.SignalReceivedAttributes).Arg, &s))
+						signals = append(signals, s)
+					}
+				}
+				require.Equal(t, []string{"1", "2", "3", "4", "5", "6"}, signals)
+
+				// Replaying the history delivers the signals in the same order
+				r2 := registry.New()
+				require.NoError(t, r2.RegisterWorkflow(wf))
+				require.NoError(t, replay.New(r2, replay.WithConverter(b.Options().Converter)).Replay(ctx, instance, h))
+			},
+		},
+		{
+			name: "WaitForState",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				wf := func(ctx workflow.Context) error {
+					workflow.NewSignalChannel[any](ctx, "done").Receive(ctx)
+					return nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				require.NoError(t, c.WaitForState(ctx, instance.InstanceID, core.WorkflowInstanceStateActive, time.Second*10))
+
+				err := c.WaitForState(ctx, instance.InstanceID, core.WorkflowInstanceStateFinished, time.Millisecond*100)
+				require.ErrorIs(t, err, client.ErrWaitTimeout)
+
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "done", nil))
+
+				require.NoError(t, c.WaitForState(ctx, instance.InstanceID, core.WorkflowInstanceStateFinished, time.Second*10))
+
+				err = c.WaitForState(ctx, instance.InstanceID, core.WorkflowInstanceStateActive, time.Second*10)
+				require.ErrorIs(t, err, client.ErrStateNotReached)
+			},
+		},
 		{
 			name: "SubWorkflow/Simple",
 			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
@@ -245,6 +646,119 @@ func EndToEndBackendTest(t *testing.T, setup func(options ...backend.BackendOpti
 				require.Equal(t, 2, r)
 			},
 		},
+		{
+			name: "SubWorkflow/ForwardInputs",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				swf := func(ctx workflow.Context, msg string, i int) (string, error) {
+					return fmt.Sprintf("%s %d", msg, i), nil
+				}
+				wf := func(ctx workflow.Context, msg string, i int) (string, error) {
+					inputs := workflow.GetInput(ctx)
+
+					args := make([]any, len(inputs))
+					for i, input := range inputs {
+						args[i] = json.RawMessage(input)
+					}
+
+					return workflow.CreateSubWorkflowInstance[string](ctx, workflow.DefaultSubWorkflowOptions, "swf", args...).Get(ctx)
+				}
+				require.NoError(t, w.RegisterWorkflow(swf, registry.WithName("swf")))
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				r, err := runWorkflowWithResult[string](t, ctx, c, wf, "hello", 42)
+				require.NoError(t, err)
+				require.Equal(t, "hello 42", r)
+			},
+		},
+		{
+			name: "SubWorkflow/FanOut",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				swf := func(ctx workflow.Context, i int) (int, error) {
+					// Wait for the test to let this sub-workflow finish
+					workflow.NewSignalChannel[int](ctx, "finish").Receive(ctx)
+
+					return i, nil
+				}
+				wf := func(ctx workflow.Context) ([]int, error) {
+					const children = 3
+
+					pending := map[int]workflow.Future[int]{}
+					for i := 0; i < children; i++ {
+						pending[i] = workflow.CreateSubWorkflowInstance[int](ctx, workflow.SubWorkflowOptions{
+							InstanceID:   fmt.Sprintf("%s-%d", workflow.WorkflowInstance(ctx).InstanceID, i),
+							RetryOptions: workflow.DefaultSubWorkflowRetryOptions,
+						}, swf, i)
+					}
+
+					// Collect results in the order the sub-workflows finish
+					order := make([]int, 0, children)
+					for len(pending) > 0 {
+						cases := make([]workflow.SelectCase, 0, len(pending))
+						for i := 0; i < children; i++ {
+							f, ok := pending[i]
+							if !ok {
+								continue
+							}
+
+							i := i
+							cases = append(cases, workflow.Await(f, func(ctx workflow.Context, f workflow.Future[int]) {
+								delete(pending, i)
+
+								r, err := f.Get(ctx)
+								if err != nil {
+									r = -1
+								}
+
+								order = append(order, r)
+							}))
+						}
+
+						workflow.Select(ctx, cases...)
+					}
+
+					return order, nil
+				}
+				register(t, ctx, w, []interface{}{wf, swf}, nil)
+
+				// Completion order must not depend on the order sub-workflows were started in
+				for _, order := range [][]int{{0, 1, 2}, {2, 1, 0}, {1, 2, 0}, {0, 2, 1}} {
+					instance := runWorkflow(t, ctx, c, wf)
+
+					// Wait for all sub-workflows to be scheduled
+					subWorkflows := map[string]*workflow.Instance{}
+					require.Eventually(t, func() bool {
+						h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+						if err != nil {
+							return false
+						}
+
+						for _, event := range h {
+							if event.Type == history.EventType_SubWorkflowScheduled {
+								swi := event.Attributes.(*history.SubWorkflowScheduledAttributes).SubWorkflowInstance
+								subWorkflows[swi.InstanceID] = swi
+							}
+						}
+
+						return len(subWorkflows) == 3
+					}, time.Second*10, time.Millisecond*10)
+
+					// Finish sub-workflows one by one in the given order
+					for _, i := range order {
+						swi := subWorkflows[fmt.Sprintf("%s-%d", instance.InstanceID, i)]
+
+						require.Eventually(t, func() bool {
+							return c.SignalWorkflow(ctx, swi.InstanceID, "finish", 0) == nil
+						}, time.Second*10, time.Millisecond*10)
+
+						require.NoError(t, c.WaitForWorkflowInstance(ctx, swi, time.Second*10))
+					}
+
+					r, err := client.GetWorkflowResult[[]int](ctx, c, instance, time.Second*20)
+					require.NoError(t, err)
+					require.Equal(t, order, r)
+				}
+			},
+		},
 		{
 			name: "SubWorkflow/DuplicateActiveInstanceID",
 			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
@@ -613,6 +1127,44 @@ func EndToEndBackendTest(t *testing.T, setup func(options ...backend.BackendOpti
 				require.Equal(t, "hello-23", r)
 			},
 		},
+		{
+			name: "ContextPropagation_Headers",
+			f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+				a := func(ctx context.Context) (string, error) {
+					return activity.Header(ctx, "tenant"), nil
+				}
+
+				swf := func(ctx workflow.Context) (string, error) {
+					return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+				}
+
+				wf := func(ctx workflow.Context) (string, error) {
+					ar, err := workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+					if err != nil {
+						return "", err
+					}
+
+					sr, err := workflow.CreateSubWorkflowInstance[string](ctx, workflow.DefaultSubWorkflowOptions, swf).Get(ctx)
+					if err != nil {
+						return "", err
+					}
+
+					return ar + "-" + sr, nil
+				}
+
+				register(t, ctx, w, []interface{}{wf, swf}, []interface{}{a})
+
+				instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+					InstanceID: uuid.NewString(),
+					Headers:    map[string]string{"tenant": "contoso"},
+				}, wf)
+				require.NoError(t, err)
+
+				r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*5)
+				require.NoError(t, err)
+				require.Equal(t, "contoso-contoso", r)
+			},
+		},
 	}
 
 	tests = append(tests, e2eActivityTests...)
@@ -622,7 +1174,23 @@ func EndToEndBackendTest(t *testing.T, setup func(options ...backend.BackendOpti
 	tests = append(tests, e2eQueueTests...)
 	tests = append(tests, e2eRemovalTests...)
 	tests = append(tests, e2eContinueAsNewTests...)
+	tests = append(tests, e2eResetTests...)
 	tests = append(tests, e2eTracingTests...)
+	tests = append(tests, e2eUniqueKeyTests...)
+	tests = append(tests, e2eTagsTests...)
+	tests = append(tests, e2eSubscriptionTests...)
+	tests = append(tests, e2eExecuteTests...)
+	tests = append(tests, e2eEventCallbackTests...)
+	tests = append(tests, e2eWebhookTests...)
+	tests = append(tests, e2eCodecTests...)
+	tests = append(tests, e2eSubWorkflowTreeTests...)
+	tests = append(tests, e2eParentClosePolicyTests...)
+	tests = append(tests, e2eProgressTests...)
+	tests = append(tests, e2eBlobTests...)
+	tests = append(tests, e2eUsageTests...)
+	tests = append(tests, e2eConformanceTests...)
+	tests = append(tests, e2eHookTests...)
+	tests = append(tests, e2eNamespaceTests...)
 
 	run := func(suffix string, workerOptions worker.Options) {
 		for _, tt := range tests {
@@ -638,11 +1206,13 @@ func EndToEndBackendTest(t *testing.T, setup func(options ...backend.BackendOpti
 
 				c := client.New(b)
 
+				// Custom options only apply to this test
+				wo := workerOptions
 				if tt.customWorkerOptions != nil {
-					tt.customWorkerOptions(&workerOptions)
+					tt.customWorkerOptions(&wo)
 				}
 
-				w := worker.New(b, &workerOptions)
+				w := worker.New(b, &wo)
 
 				t.Cleanup(func() {
 					cancel()
@@ -1,13 +1,24 @@
 package test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/cschleiden/go-workflows/activity"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/registry"
+	"github.com/cschleiden/go-workflows/replay"
 	"github.com/cschleiden/go-workflows/worker"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/stretchr/testify/require"
@@ -22,6 +33,51 @@ func (e *CustomError) Error() string {
 }
 
 var e2eActivityTests = []backendTest{
+	{
+		name: "Activity/ExecuteActivityAsync_Cancel",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			a := func(context.Context) (int, error) {
+				time.Sleep(time.Millisecond * 200)
+				return 42, nil
+			}
+
+			wf := func(ctx workflow.Context) (int, error) {
+				h := workflow.ExecuteActivityAsync[int](ctx, workflow.ActivityOptions{
+					RetryOptions: workflow.RetryOptions{
+						MaxAttempts: 1,
+					},
+				}, a)
+
+				// Make sure the activity has been scheduled before canceling it
+				if _, err := workflow.ScheduleTimer(ctx, time.Millisecond*10).Get(ctx); err != nil {
+					return 0, err
+				}
+
+				h.Cancel(ctx)
+
+				if _, err := h.Future.Get(ctx); !errors.Is(err, workflow.Canceled) {
+					return 0, errors.New("expected activity to be canceled")
+				}
+
+				// Wait for the result of the canceled activity to arrive, it's ignored
+				if _, err := workflow.ScheduleTimer(ctx, time.Millisecond*500).Get(ctx); err != nil {
+					return 0, err
+				}
+
+				return 23, nil
+			}
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			instance := runWorkflow(t, ctx, c, wf)
+			r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 23, r)
+
+			// The result might have arrived in the same task as the timer, before the cancellation has been recorded
+			historyContains(ctx, t, b, instance, history.EventType_ActivityScheduled, history.EventType_ActivityCanceled)
+			historyContains(ctx, t, b, instance, history.EventType_ActivityScheduled, history.EventType_ActivityCompleted)
+		},
+	},
 	{
 		name: "Activity/Panic",
 		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
@@ -47,6 +103,64 @@ var e2eActivityTests = []backendTest{
 			require.NoError(t, err)
 		},
 	},
+	{
+		name: "Activity/Panic_ErrorRedactor",
+		customWorkerOptions: func(options *worker.Options) {
+			options.ActivityErrorRedactor = func(err error) error {
+				var werr *workflow.Error
+				if errors.As(err, &werr) {
+					redacted := *werr
+					redacted.Message = strings.ReplaceAll(werr.Message, "s3cr3t", "***")
+					redacted.Stacktrace = ""
+					return &redacted
+				}
+
+				return err
+			}
+		},
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			a := func(context.Context, string) error {
+				panic("invalid token s3cr3t")
+			}
+
+			wf := func(ctx workflow.Context) (string, error) {
+				_, err := workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{
+					RetryOptions: workflow.RetryOptions{
+						MaxAttempts: 1,
+					},
+				}, a, "s3cr3t").Get(ctx)
+
+				var perr *workflow.PanicError
+				if !errors.As(err, &perr) {
+					return "", errors.New("error should be PanicError")
+				}
+
+				return err.Error(), nil
+			}
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			instance := runWorkflow(t, ctx, c, wf)
+			output, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, "panic: invalid token ***", output)
+
+			// Neither message nor stack trace are persisted
+			h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+			require.NoError(t, err)
+
+			found := false
+			for _, event := range h {
+				if event.Type == history.EventType_ActivityFailed {
+					found = true
+
+					a := event.Attributes.(*history.ActivityFailedAttributes)
+					require.Equal(t, "panic: invalid token ***", a.Error.Message)
+					require.Empty(t, a.Error.Stacktrace)
+				}
+			}
+			require.True(t, found)
+		},
+	},
 	{
 		name: "Activity/CustomError",
 		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
@@ -109,6 +223,101 @@ var e2eActivityTests = []backendTest{
 			require.Equal(t, 2, maxAttempt)
 		},
 	},
+	{
+		name: "Activity/CorrelationID",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			a := func(ctx context.Context) (string, error) {
+				return activity.GetInfo(ctx).CorrelationID, nil
+			}
+
+			wf := func(ctx workflow.Context) (string, error) {
+				return workflow.ExecuteActivity[string](ctx, workflow.ActivityOptions{
+					RetryOptions:  workflow.DefaultRetryOptions,
+					CorrelationID: "order-42",
+				}, a).Get(ctx)
+			}
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			instance := runWorkflow(t, ctx, c, wf)
+			r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, "order-42", r)
+
+			h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+			require.NoError(t, err)
+
+			var scheduled, completed bool
+			for _, e := range h {
+				switch a := e.Attributes.(type) {
+				case *history.ActivityScheduledAttributes:
+					require.Equal(t, "order-42", a.CorrelationID)
+					scheduled = true
+				case *history.ActivityCompletedAttributes:
+					require.Equal(t, "order-42", a.CorrelationID)
+					completed = true
+				}
+			}
+
+			require.True(t, scheduled)
+			require.True(t, completed)
+		},
+	},
+	{
+		name: "Activity/GetAttempts",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			a := func(ctx context.Context) (int, error) {
+				attempt := activity.Attempt(ctx)
+				if attempt < 2 {
+					return 0, &CustomError{msg: fmt.Sprintf("attempt %d failed", attempt)}
+				}
+
+				return attempt, nil
+			}
+
+			wf := func(ctx workflow.Context) (int, error) {
+				return workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{
+					RetryOptions: workflow.RetryOptions{
+						MaxAttempts: 4,
+					},
+				}, a).Get(ctx)
+			}
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			instance := runWorkflow(t, ctx, c, wf)
+			r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 2, r)
+
+			// The activity is identified by the schedule event ID of its first attempt
+			var activityID int64
+			historyIterate(ctx, t, b, instance, func(event *history.Event) bool {
+				activityID = event.ScheduleEventID
+				return event.Type != history.EventType_ActivityScheduled
+			})
+
+			attempts, err := c.GetActivityAttempts(ctx, instance.InstanceID, activityID)
+			require.NoError(t, err)
+			require.Len(t, attempts, 3)
+
+			for i, attempt := range attempts {
+				require.Equal(t, i, attempt.Attempt)
+				require.False(t, attempt.ScheduledAt.IsZero())
+				require.False(t, attempt.FinishedAt.IsZero())
+			}
+
+			require.Equal(t, activityID, attempts[0].ScheduleEventID)
+
+			require.Equal(t, client.ActivityAttemptStateFailed, attempts[0].State)
+			require.ErrorContains(t, attempts[0].Error, "attempt 0 failed")
+			require.Equal(t, client.ActivityAttemptStateFailed, attempts[1].State)
+			require.ErrorContains(t, attempts[1].Error, "attempt 1 failed")
+			require.Equal(t, client.ActivityAttemptStateCompleted, attempts[2].State)
+			require.NoError(t, attempts[2].Error)
+
+			_, err = c.GetActivityAttempts(ctx, instance.InstanceID, 1000)
+			require.ErrorIs(t, err, client.ErrActivityNotFound)
+		},
+	},
 	{
 		name: "Activity/ExtendTask",
 		customWorkerOptions: func(w *worker.Options) {
@@ -135,4 +344,305 @@ var e2eActivityTests = []backendTest{
 			require.NoError(t, err)
 		},
 	},
+	{
+		name: "Activity/MaxConcurrency",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			var running, maxRunning, maxInFlight atomic.Int32
+			a := func(ctx context.Context) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+
+				for _, inFlight := range w.ActivitiesInFlight() {
+					if int32(inFlight) > maxInFlight.Load() {
+						maxInFlight.Store(int32(inFlight))
+					}
+				}
+
+				time.Sleep(time.Millisecond * 50)
+
+				return nil
+			}
+
+			wf := func(ctx workflow.Context) error {
+				futures := make([]workflow.Future[any], 0)
+				for i := 0; i < 4; i++ {
+					futures = append(futures, workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, a))
+				}
+
+				for _, f := range futures {
+					if _, err := f.Get(ctx); err != nil {
+						return err
+					}
+				}
+
+				return nil
+			}
+
+			require.NoError(t, w.RegisterActivity(a, registry.WithMaxConcurrency(1)))
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			_, err := runWorkflowWithResult[any](t, ctx, c, wf)
+			require.NoError(t, err)
+
+			require.Equal(t, int32(1), maxRunning.Load())
+			require.Equal(t, int32(1), maxInFlight.Load())
+			require.Empty(t, w.ActivitiesInFlight())
+		},
+	},
+	{
+		name: "Activity/ResultCache",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			executions := 0
+			a := func(ctx context.Context, x int) (int, error) {
+				executions++
+				return x * 2, nil
+			}
+
+			wf := func(ctx workflow.Context, x int) (int, error) {
+				return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a, x).Get(ctx)
+			}
+
+			require.NoError(t, w.RegisterActivity(a, registry.WithResultCache(time.Minute)))
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			if err := c.InvalidateActivityResult(ctx, a, 21); errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip()
+				return
+			}
+
+			// Second execution with the same input returns the cached result
+			for i := 0; i < 2; i++ {
+				r, err := runWorkflowWithResult[int](t, ctx, c, wf, 21)
+				require.NoError(t, err)
+				require.Equal(t, 42, r)
+			}
+			require.Equal(t, 1, executions)
+
+			// Different inputs are not cached
+			r, err := runWorkflowWithResult[int](t, ctx, c, wf, 1)
+			require.NoError(t, err)
+			require.Equal(t, 2, r)
+			require.Equal(t, 2, executions)
+
+			// Activity is executed again after invalidating the result
+			require.NoError(t, c.InvalidateActivityResult(ctx, a, 21))
+
+			r, err = runWorkflowWithResult[int](t, ctx, c, wf, 21)
+			require.NoError(t, err)
+			require.Equal(t, 42, r)
+			require.Equal(t, 3, executions)
+		},
+	},
+	{
+		name: "Activity/AtMostOnce",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			executions := 0
+			a := func(ctx context.Context, x int) (int, error) {
+				executions++
+				return x * 2, nil
+			}
+
+			wf := func(ctx workflow.Context, x int) (int, error) {
+				return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a, x).Get(ctx)
+			}
+
+			require.NoError(t, w.RegisterActivity(a, registry.WithExecutionGuarantee(registry.AtMostOnce)))
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			if err := c.InvalidateActivityResult(ctx, a, 21); errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip()
+				return
+			}
+
+			// Every scheduled execution is executed once
+			for i := 0; i < 2; i++ {
+				r, err := runWorkflowWithResult[int](t, ctx, c, wf, 21)
+				require.NoError(t, err)
+				require.Equal(t, 42, r)
+			}
+			require.Equal(t, 2, executions)
+		},
+	},
+	activityPayloadLogTest(),
+	{
+		name: "Activity/PreferLocalActivities",
+		customWorkerOptions: func(options *worker.Options) {
+			options.PreferLocalActivities = true
+
+			// Activities scheduled via the backend are never executed
+			options.ActivityPollers = 0
+		},
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			a := func(ctx context.Context, x int) (int, error) {
+				return x * 2, nil
+			}
+
+			var attempts atomic.Int32
+			flaky := func(ctx context.Context) (int, error) {
+				if attempts.Add(1) == 1 {
+					return 0, errors.New("first attempt fails")
+				}
+
+				return 1, nil
+			}
+
+			wf := func(ctx workflow.Context, x int) (int, error) {
+				r1, err := workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a, x).Get(ctx)
+				if err != nil {
+					return 0, err
+				}
+
+				r2, err := workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{
+					RetryOptions: workflow.RetryOptions{
+						MaxAttempts:        2,
+						FirstRetryInterval: time.Millisecond,
+					},
+				}, flaky).Get(ctx)
+				if err != nil {
+					return 0, err
+				}
+
+				return r1 + r2, nil
+			}
+
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a, flaky})
+
+			instance := runWorkflow(t, ctx, c, wf, 21)
+			r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 43, r)
+			require.Equal(t, int32(2), attempts.Load())
+
+			// Results are recorded like results of activities executed by an activity worker
+			h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+			require.NoError(t, err)
+
+			scheduled := map[int64]bool{}
+			var completed, failed int
+			for _, event := range h {
+				switch event.Type {
+				case history.EventType_ActivityScheduled:
+					scheduled[event.ScheduleEventID] = true
+				case history.EventType_ActivityCompleted:
+					require.True(t, scheduled[event.ScheduleEventID])
+					completed++
+				case history.EventType_ActivityFailed:
+					require.True(t, scheduled[event.ScheduleEventID])
+					failed++
+				}
+			}
+			require.Len(t, scheduled, 3)
+			require.Equal(t, 2, completed)
+			require.Equal(t, 1, failed)
+
+			// The history can be replayed by workers that do not execute activities locally
+			r2 := registry.New()
+			require.NoError(t, r2.RegisterWorkflow(wf))
+			require.NoError(t, r2.RegisterActivity(a))
+			require.NoError(t, r2.RegisterActivity(flaky))
+
+			require.NoError(t, replay.New(r2, replay.WithConverter(b.Options().Converter)).Replay(ctx, instance, h))
+		},
+	},
+}
+
+// activityPayloadLogTest checks that the payloads of sampled activity executions are logged after redacting them
+func activityPayloadLogTest() backendTest {
+	logs := &logBuffer{}
+
+	return backendTest{
+		name: "Activity/PayloadLogSampling",
+		options: []backend.BackendOption{
+			backend.WithLogger(slog.New(slog.NewJSONHandler(logs, nil))),
+		},
+		customWorkerOptions: func(options *worker.Options) {
+			options.ActivityPayloadLogSampleRate = 2
+			options.ActivityPayloadLogRedactor = func(activityName string, v any) any {
+				if v == float64(1) {
+					return "redacted"
+				}
+
+				return v
+			}
+		},
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			logs.Reset()
+
+			a := func(ctx context.Context, n int) (int, error) {
+				if n == 3 {
+					return 0, errors.New("three")
+				}
+
+				return n * 10, nil
+			}
+
+			wf := func(ctx workflow.Context) error {
+				for n := 0; n < 4; n++ {
+					_, _ = workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{
+						RetryOptions: workflow.RetryOptions{MaxAttempts: 1},
+					}, a, n+1).Get(ctx)
+				}
+
+				return nil
+			}
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			instance := runWorkflow(t, ctx, c, wf)
+			require.NoError(t, c.WaitForWorkflowInstance(ctx, instance, time.Second*10))
+
+			sampled := make([]map[string]any, 0)
+			for _, line := range logs.Lines() {
+				var record map[string]any
+				require.NoError(t, json.Unmarshal([]byte(line), &record))
+
+				if record["msg"] == "Sampled activity execution" {
+					sampled = append(sampled, record)
+				}
+			}
+
+			// The first and third execution are logged
+			require.Len(t, sampled, 2)
+
+			require.Equal(t, instance.InstanceID, sampled[0]["workflows.instance.id"])
+			require.Equal(t, []any{"redacted"}, sampled[0]["inputs"])
+			require.Equal(t, float64(10), sampled[0]["result"])
+
+			require.Equal(t, []any{float64(3)}, sampled[1]["inputs"])
+			require.Equal(t, "three", sampled[1]["error"])
+		},
+	}
+}
+
+// logBuffer collects log output of concurrently running workers
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *logBuffer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.buf.Write(p)
+}
+
+func (l *logBuffer) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf.Reset()
+}
+
+func (l *logBuffer) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return strings.Split(strings.TrimSpace(l.buf.String()), "\n")
 }
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/activity"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+// blobTestContent is larger than a single chunk of the blob stores and the codec stream
+var blobTestContent = strings.Repeat("secret-blob-content-", 64*1024)
+
+func blobTestWorkflow() (func(workflow.Context, workflow.BlobInput) (int, error), func(context.Context, workflow.BlobInput) (int, error)) {
+	a := func(ctx context.Context, input workflow.BlobInput) (int, error) {
+		r, err := activity.OpenBlob(ctx, input)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return 0, err
+		}
+
+		if string(content) != blobTestContent {
+			return 0, errors.New("unexpected blob content")
+		}
+
+		return len(content), nil
+	}
+
+	wf := func(ctx workflow.Context, input workflow.BlobInput) (int, error) {
+		return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a, input).Get(ctx)
+	}
+
+	return wf, a
+}
+
+var e2eBlobTests = []backendTest{
+	{
+		name: "BlobInput/StreamsContentToActivity",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf, a := blobTestWorkflow()
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			input, err := c.CreateBlobInput(ctx, strings.NewReader(blobTestContent))
+			if errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+
+			instance := runWorkflow(t, ctx, c, wf, input)
+
+			r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, len(blobTestContent), r)
+
+			// Only the handle is stored in the history
+			h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+			require.NoError(t, err)
+			for _, event := range h {
+				a, err := history.SerializeAttributes(event.Attributes)
+				require.NoError(t, err)
+				require.False(t, bytes.Contains(a, []byte("secret")), "content stored in %v", event.Type)
+			}
+
+			require.NoError(t, c.RemoveBlobInput(ctx, input))
+
+			_, err = b.Options().BlobStore.Get(ctx, input.Key)
+			require.ErrorIs(t, err, backend.ErrBlobNotFound)
+		},
+	},
+	{
+		name:    "BlobInput/EncodesContent",
+		options: []backend.BackendOption{backend.WithConverter(codecTestConverter())},
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf, a := blobTestWorkflow()
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			input, err := c.CreateBlobInput(ctx, strings.NewReader(blobTestContent))
+			if errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+
+			// Content is stored encrypted
+			rc, err := b.Options().BlobStore.Get(ctx, input.Key)
+			require.NoError(t, err)
+			stored, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+			require.False(t, bytes.Contains(stored, []byte("secret")))
+
+			instance := runWorkflow(t, ctx, c, wf, input)
+
+			r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, len(blobTestContent), r)
+		},
+	},
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/converter"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+var e2eCodecTests = []backendTest{
+	{
+		name:    "Codec/EncryptsStoredPayloads",
+		options: []backend.BackendOption{backend.WithConverter(codecTestConverter())},
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			a := func(ctx context.Context, s string) (string, error) {
+				return s + "-activity", nil
+			}
+			wf := func(ctx workflow.Context, s string) (string, error) {
+				r, err := workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, a, s).Get(ctx)
+				if err != nil {
+					return "", err
+				}
+
+				se, err := workflow.SideEffect(ctx, func(ctx workflow.Context) string {
+					return "secret-side-effect"
+				}).Get(ctx)
+				if err != nil {
+					return "", err
+				}
+
+				return r + "/" + se, nil
+			}
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			instance := runWorkflow(t, ctx, c, wf, "secret-input")
+
+			r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, "secret-input-activity/secret-side-effect", r)
+
+			h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+			require.NoError(t, err)
+
+			payloads := 0
+			for _, event := range h {
+				var ps []payload.Payload
+
+				switch a := event.Attributes.(type) {
+				case *history.ExecutionStartedAttributes:
+					ps = a.Inputs
+				case *history.ActivityScheduledAttributes:
+					ps = a.Inputs
+				case *history.ActivityCompletedAttributes:
+					ps = []payload.Payload{a.Result}
+				case *history.SideEffectResultAttributes:
+					ps = []payload.Payload{a.Result}
+				case *history.ExecutionCompletedAttributes:
+					ps = []payload.Payload{a.Result}
+				}
+
+				for _, p := range ps {
+					payloads++
+					require.False(t, bytes.Contains(p, []byte("secret")), "payload of %v stored in plain text", event.Type)
+				}
+			}
+
+			require.Equal(t, 5, payloads)
+		},
+	},
+	{
+		name: "Codec/CompressesLargeInputs",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context, config string, n int) (int, error) {
+				return len(config) + n, nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			c = client.New(b, client.WithInputCompression(1024))
+			config := strings.Repeat("large configuration ", 100)
+
+			for _, tt := range []struct {
+				config     string
+				compressed bool
+			}{
+				{"small", false},
+				{config, true},
+			} {
+				instance := runWorkflow(t, ctx, c, wf, tt.config, 1)
+
+				r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, len(tt.config)+1, r)
+
+				h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+				require.NoError(t, err)
+
+				for _, event := range h {
+					if a, ok := event.Attributes.(*history.ExecutionStartedAttributes); ok {
+						require.Equal(t, tt.compressed, a.InputsCompressed)
+						if tt.compressed {
+							require.Less(t, len(a.Inputs[0]), len(config))
+						}
+					}
+				}
+			}
+		},
+	},
+}
+
+func codecTestConverter() converter.Converter {
+	gz, err := converter.NewGzipCodec(gzip.DefaultCompression)
+	if err != nil {
+		panic(err)
+	}
+
+	aesgcm, err := converter.NewAESGCMCodec([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		panic(err)
+	}
+
+	return converter.NewCodecConverter(converter.DefaultConverter, gz, aesgcm)
+}
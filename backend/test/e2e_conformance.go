@@ -0,0 +1,199 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+// e2eConformanceTests cover the lifecycle of a workflow instance. Unlike other tests, they check the exact history
+// and state of instances, so backends cannot diverge in what they record.
+var e2eConformanceTests = []backendTest{
+	{
+		name: "Conformance/Start",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) error {
+				return nil
+			}
+
+			// Create the instance before starting the worker, so it stays pending
+			instance := runWorkflow(t, ctx, c, wf)
+
+			state, err := b.GetWorkflowInstanceState(ctx, instance)
+			require.NoError(t, err)
+			require.Equal(t, core.WorkflowInstanceStateActive, state)
+
+			latest, err := b.GetLatestWorkflowInstance(ctx, instance.InstanceID)
+			require.NoError(t, err)
+			require.Equal(t, instance, latest)
+
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			_, err = client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+		},
+	},
+	{
+		name: "Conformance/Completion",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context, msg string) (string, error) {
+				return msg + " world", nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf, "hello")
+
+			r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, "hello world", r)
+
+			requireFinished(ctx, t, b, instance)
+			requireHistory(ctx, t, b, instance,
+				history.EventType_WorkflowExecutionStarted,
+				history.EventType_WorkflowExecutionFinished,
+			)
+		},
+	},
+	{
+		name: "Conformance/ActivityRoundTrip",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			a := func(ctx context.Context, n int) (int, error) {
+				return n * 2, nil
+			}
+			wf := func(ctx workflow.Context, n int) (int, error) {
+				return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a, n).Get(ctx)
+			}
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			instance := runWorkflow(t, ctx, c, wf, 21)
+
+			r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 42, r)
+
+			requireFinished(ctx, t, b, instance)
+			requireHistory(ctx, t, b, instance,
+				history.EventType_WorkflowExecutionStarted,
+				history.EventType_ActivityScheduled,
+				history.EventType_ActivityCompleted,
+				history.EventType_WorkflowExecutionFinished,
+			)
+		},
+	},
+	{
+		name: "Conformance/TimerFires",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) error {
+				_, err := workflow.ScheduleTimer(ctx, time.Millisecond*100).Get(ctx)
+				return err
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf)
+
+			_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+
+			requireFinished(ctx, t, b, instance)
+			requireHistory(ctx, t, b, instance,
+				history.EventType_WorkflowExecutionStarted,
+				history.EventType_TimerScheduled,
+				history.EventType_TimerFired,
+				history.EventType_WorkflowExecutionFinished,
+			)
+
+			futureEvents, err := b.GetFutureEvents(ctx)
+			require.NoError(t, err)
+			require.Empty(t, futureEvents)
+		},
+	},
+	{
+		name: "Conformance/SignalDelivery",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) (string, error) {
+				v, _ := workflow.NewSignalChannel[string](ctx, "signal").Receive(ctx)
+				return v, nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf)
+
+			require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "signal", "hello"))
+
+			r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, "hello", r)
+
+			requireFinished(ctx, t, b, instance)
+			requireHistory(ctx, t, b, instance,
+				history.EventType_WorkflowExecutionStarted,
+				history.EventType_SignalReceived,
+				history.EventType_WorkflowExecutionFinished,
+			)
+		},
+	},
+	{
+		name: "Conformance/Cancellation",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) error {
+				_, err := workflow.ScheduleTimer(ctx, time.Hour).Get(ctx)
+				return err
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf)
+
+			// Wait for the timer to be scheduled
+			require.Eventually(t, func() bool {
+				futureEvents, err := b.GetFutureEvents(ctx)
+				return err == nil && len(futureEvents) == 1
+			}, time.Second*10, time.Millisecond*10)
+
+			require.NoError(t, c.CancelWorkflowInstance(ctx, instance))
+
+			_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+			require.EqualError(t, err, workflow.Canceled.Error())
+
+			requireFinished(ctx, t, b, instance)
+			requireHistory(ctx, t, b, instance,
+				history.EventType_WorkflowExecutionStarted,
+				history.EventType_TimerScheduled,
+				history.EventType_WorkflowExecutionCanceled,
+				history.EventType_TimerCanceled,
+				history.EventType_WorkflowExecutionFinished,
+			)
+
+			futureEvents, err := b.GetFutureEvents(ctx)
+			require.NoError(t, err)
+			require.Empty(t, futureEvents)
+		},
+	},
+}
+
+func requireFinished(ctx context.Context, t *testing.T, b TestBackend, instance *workflow.Instance) {
+	state, err := b.GetWorkflowInstanceState(ctx, instance)
+	require.NoError(t, err)
+	require.Equal(t, core.WorkflowInstanceStateFinished, state)
+}
+
+// requireHistory ensures the history consists of exactly the given event types, ignoring the events marking the
+// start of workflow tasks
+func requireHistory(ctx context.Context, t *testing.T, b TestBackend, instance *workflow.Instance, eventTypes ...history.EventType) {
+	types := []history.EventType{}
+	historyIterate(ctx, t, b, instance, func(event *history.Event) bool {
+		if event.Type != history.EventType_WorkflowTaskStarted {
+			types = append(types, event.Type)
+		}
+
+		return true
+	})
+
+	require.Equal(t, eventTypes, types)
+}
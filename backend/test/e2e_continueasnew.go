@@ -39,6 +39,65 @@ var e2eContinueAsNewTests = []backendTest{
 			require.Equal(t, core.WorkflowInstanceStateContinuedAsNew, state)
 		},
 	},
+	{
+		name: "ContinueAsNew/ContinueAsNewError",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context, run int) (int, error) {
+				run = run + 1
+				if run < 3 {
+					return run, workflow.ContinueAsNewError(run)
+				}
+
+				return run, nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf, 0)
+
+			require.Eventually(t, func() bool {
+				state, err := c.GetInstanceState(ctx, instance.InstanceID)
+				require.NoError(t, err)
+
+				return state == core.WorkflowInstanceStateFinished
+			}, time.Second*10, time.Millisecond*10)
+
+			// The last execution returns the final result
+			latest, err := b.GetLatestWorkflowInstance(ctx, instance.InstanceID)
+			require.NoError(t, err)
+			require.NotEqual(t, instance.ExecutionID, latest.ExecutionID)
+
+			r, err := client.GetWorkflowResult[int](ctx, c, latest, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 3, r)
+		},
+	},
+	{
+		name: "ContinueAsNew/GetInstanceStateReturnsLatestExecution",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context, run int) (int, error) {
+				run = run + 1
+				if run < 3 {
+					return run, workflow.ContinueAsNew(ctx, run)
+				}
+
+				return run, nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf, 0)
+
+			require.Eventually(t, func() bool {
+				state, err := c.GetInstanceState(ctx, instance.InstanceID)
+				require.NoError(t, err)
+
+				return state == core.WorkflowInstanceStateFinished
+			}, time.Second*10, time.Millisecond*10)
+
+			latest, err := b.GetLatestWorkflowInstance(ctx, instance.InstanceID)
+			require.NoError(t, err)
+			require.NotEqual(t, instance.ExecutionID, latest.ExecutionID)
+		},
+	},
 	{
 		name: "ContinueAsNew/Subworkflow",
 		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
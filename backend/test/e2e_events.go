@@ -0,0 +1,88 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+var e2eEventCallbackTests = []backendTest{
+	eventCallbackTest(),
+}
+
+func eventCallbackTest() backendTest {
+	var mu sync.Mutex
+	events := make(map[string][]history.EventType)
+
+	cb := func(instance *core.WorkflowInstance, event *history.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		events[instance.InstanceID] = append(events[instance.InstanceID], event.Type)
+	}
+
+	return backendTest{
+		name:    "EventCallback/ReceivesHistoryInOrder",
+		options: []backend.BackendOption{backend.WithEventCallback(cb)},
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			a := func(ctx context.Context) (int, error) {
+				return 42, nil
+			}
+			wf := func(ctx workflow.Context) (int, error) {
+				if _, err := workflow.ScheduleTimer(ctx, time.Millisecond).Get(ctx); err != nil {
+					return 0, err
+				}
+
+				return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+			}
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			instance := runWorkflow(t, ctx, c, wf)
+			r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 42, r)
+
+			expected := []history.EventType{
+				history.EventType_WorkflowExecutionStarted,
+				history.EventType_TimerScheduled,
+				history.EventType_TimerFired,
+				history.EventType_ActivityScheduled,
+				history.EventType_ActivityCompleted,
+				history.EventType_WorkflowExecutionFinished,
+			}
+
+			// Callbacks are invoked asynchronously
+			require.Eventually(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+
+				return len(withoutTaskEvents(events[instance.InstanceID])) == len(expected)
+			}, time.Second*5, time.Millisecond*10)
+
+			mu.Lock()
+			defer mu.Unlock()
+			require.Equal(t, expected, withoutTaskEvents(events[instance.InstanceID]))
+		},
+	}
+}
+
+// withoutTaskEvents removes the WorkflowTaskStarted events, which are recorded for every executed task
+func withoutTaskEvents(types []history.EventType) []history.EventType {
+	r := make([]history.EventType, 0, len(types))
+	for _, t := range types {
+		if t != history.EventType_WorkflowTaskStarted {
+			r = append(r, t)
+		}
+	}
+
+	return r
+}
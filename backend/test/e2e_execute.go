@@ -0,0 +1,121 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var e2eExecuteTests = []backendTest{
+	{
+		name: "ExecuteWorkflow/ExecutionTimeoutDeadline",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			type deadlines struct {
+				Workflow time.Time
+				Activity time.Time
+			}
+
+			a := func(ctx context.Context) (time.Time, error) {
+				deadline, _ := ctx.Deadline()
+				return deadline, nil
+			}
+
+			wf := func(ctx workflow.Context) (deadlines, error) {
+				activityDeadline, err := workflow.ExecuteActivity[time.Time](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+				if err != nil {
+					return deadlines{}, err
+				}
+
+				return deadlines{
+					Workflow: workflow.GetInfo(ctx).Deadline,
+					Activity: activityDeadline,
+				}, nil
+			}
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			before := time.Now()
+
+			r, err := client.ExecuteWorkflow[deadlines](ctx, c, client.WorkflowInstanceOptions{
+				InstanceID:       uuid.NewString(),
+				ExecutionTimeout: time.Hour,
+			}, wf)
+			require.NoError(t, err)
+
+			require.WithinRange(t, r.Workflow, before.Add(time.Hour-time.Second), time.Now().Add(time.Hour))
+			require.True(t, r.Workflow.Equal(r.Activity))
+		},
+	},
+	{
+		name: "ExecuteWorkflow/ReturnsResult",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context, msg string) (string, error) {
+				return msg + " world", nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			r, err := client.ExecuteWorkflow[string](ctx, c, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+			}, wf, "hello")
+			require.NoError(t, err)
+			require.Equal(t, "hello world", r)
+		},
+	},
+	{
+		name: "ExecuteWorkflow/ReturnsError",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) (int, error) {
+				return 0, errors.New("workflow failed")
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			_, err := client.ExecuteWorkflow[int](ctx, c, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+			}, wf)
+			require.ErrorContains(t, err, "workflow failed")
+		},
+	},
+	{
+		name: "ExecuteWorkflow/FollowsContinueAsNew",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context, run int) (int, error) {
+				if run < 3 {
+					return run, workflow.ContinueAsNew(ctx, run+1)
+				}
+
+				return run, nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			r, err := client.ExecuteWorkflow[int](ctx, c, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+			}, wf, 0)
+			require.NoError(t, err)
+			require.Equal(t, 3, r)
+		},
+	},
+	{
+		name: "ExecuteWorkflow/StopsWaitingWhenContextCanceled",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) (int, error) {
+				workflow.NewSignalChannel[bool](ctx, "done").Receive(ctx)
+				return 42, nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			waitCtx, cancel := context.WithTimeout(ctx, time.Millisecond*500)
+			defer cancel()
+
+			_, err := client.ExecuteWorkflow[int](waitCtx, c, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+			}, wf)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+		},
+	},
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+var e2eHookTests = []backendTest{
+	workflowTaskHooksTest(),
+	{
+		name: "Worker/Identity",
+		customWorkerOptions: func(options *worker.Options) {
+			options.WorkerIdentity = "test-worker"
+		},
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			a := func(ctx context.Context) (int, error) {
+				return 42, nil
+			}
+
+			wf := func(ctx workflow.Context) (int, error) {
+				return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+			}
+
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			instance := runWorkflow(t, ctx, c, wf)
+			_, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+
+			// Every task records the worker that executed it
+			tasks, activities := 0, 0
+			historyIterate(ctx, t, b, instance, func(event *history.Event) bool {
+				switch a := event.Attributes.(type) {
+				case *history.WorkflowTaskStartedAttributes:
+					tasks++
+					require.Equal(t, "test-worker", a.Worker)
+
+				case *history.ActivityCompletedAttributes:
+					activities++
+					require.Equal(t, "test-worker", a.Worker)
+				}
+
+				return true
+			})
+
+			require.Equal(t, 2, tasks)
+			require.Equal(t, 1, activities)
+		},
+	},
+}
+
+// workflowTaskHooksTest checks that the workflow task hooks are called around every workflow task, and that
+// panicking hooks do not affect task processing
+func workflowTaskHooksTest() backendTest {
+	var mu sync.Mutex
+	var before, after []string
+	var panicked bool
+
+	return backendTest{
+		name: "Worker/WorkflowTaskHooks",
+		customWorkerOptions: func(options *worker.Options) {
+			options.BeforeWorkflowTask = func(ctx context.Context, task *backend.WorkflowTask) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				before = append(before, task.WorkflowInstance.InstanceID)
+
+				if !panicked {
+					panicked = true
+					panic("hook panicked")
+				}
+			}
+			options.AfterWorkflowTask = func(ctx context.Context, task *backend.WorkflowTask, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				after = append(after, task.WorkflowInstance.InstanceID)
+
+				panic("hook panicked")
+			}
+		},
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			// The test runs with and without cache
+			mu.Lock()
+			before, after, panicked = nil, nil, false
+			mu.Unlock()
+
+			wf := func(ctx workflow.Context) (int, error) {
+				workflow.Sleep(ctx, time.Millisecond)
+
+				return 42, nil
+			}
+
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf)
+			r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 42, r)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			// Started and timer fired
+			require.Len(t, before, 2)
+			require.Equal(t, before, after)
+			for _, id := range before {
+				require.Equal(t, instance.InstanceID, id)
+			}
+		},
+	}
+}
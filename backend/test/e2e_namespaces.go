@@ -0,0 +1,55 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/registry"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var e2eNamespaceTests = []backendTest{
+	{
+		name: "Namespaces/SameNames",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			// Register a workflow, a sub-workflow, and an activity with the same names in both namespaces
+			for _, namespace := range []string{"tenant-a", "tenant-b"} {
+				r := registry.New()
+
+				a := func(ctx context.Context) (string, error) {
+					return namespace, nil
+				}
+				subwf := func(ctx workflow.Context) (string, error) {
+					return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, "activity").Get(ctx)
+				}
+				wf := func(ctx workflow.Context) (string, error) {
+					return workflow.CreateSubWorkflowInstance[string](ctx, workflow.DefaultSubWorkflowOptions, "subworkflow").Get(ctx)
+				}
+
+				require.NoError(t, r.RegisterWorkflow(wf, registry.WithName("workflow")))
+				require.NoError(t, r.RegisterWorkflow(subwf, registry.WithName("subworkflow")))
+				require.NoError(t, r.RegisterActivity(a, registry.WithName("activity")))
+				require.NoError(t, w.RegisterNamespace(namespace, r))
+			}
+
+			require.NoError(t, w.Start(ctx))
+
+			for _, namespace := range []string{"tenant-a", "tenant-b"} {
+				instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+					InstanceID: uuid.NewString(),
+					Namespace:  namespace,
+				}, "workflow")
+				require.NoError(t, err)
+
+				r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, namespace, r)
+			}
+		},
+	},
+}
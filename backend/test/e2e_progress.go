@@ -0,0 +1,93 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+type testProgress struct {
+	Percent int    `json:"percent"`
+	Step    string `json:"step"`
+}
+
+var e2eProgressTests = []backendTest{
+	{
+		name: "Progress/DescribeWorkflowInstance",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) error {
+				if err := workflow.SetProgress(ctx, testProgress{Percent: 10, Step: "started"}); err != nil {
+					return err
+				}
+
+				if err := workflow.SetProgress(ctx, testProgress{Percent: 50, Step: "waiting"}); err != nil {
+					return err
+				}
+
+				workflow.NewSignalChannel[bool](ctx, "continue").Receive(ctx)
+
+				return workflow.SetProgress(ctx, testProgress{Percent: 100, Step: "done"})
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf)
+
+			// Progress is available while the workflow is waiting for the signal
+			var progress testProgress
+			require.Eventually(t, func() bool {
+				d, err := c.DescribeWorkflowInstance(ctx, instance.InstanceID)
+				require.NoError(t, err)
+
+				ok, err := d.DecodeProgress(&progress)
+				require.NoError(t, err)
+
+				return ok
+			}, time.Second*10, time.Millisecond*10)
+			require.Equal(t, testProgress{Percent: 50, Step: "waiting"}, progress)
+
+			require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "continue", true))
+
+			_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+
+			d, err := c.DescribeWorkflowInstance(ctx, instance.InstanceID)
+			require.NoError(t, err)
+			require.Equal(t, instance, d.Instance)
+			require.Equal(t, core.WorkflowInstanceStateFinished, d.State)
+
+			ok, err := d.DecodeProgress(&progress)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, testProgress{Percent: 100, Step: "done"}, progress)
+		},
+	},
+	{
+		name: "Progress/NoProgress",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) error {
+				return nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf)
+
+			_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+
+			d, err := c.DescribeWorkflowInstance(ctx, instance.InstanceID)
+			require.NoError(t, err)
+			require.Nil(t, d.Progress)
+
+			var progress testProgress
+			ok, err := d.DecodeProgress(&progress)
+			require.NoError(t, err)
+			require.False(t, ok)
+		},
+	},
+}
@@ -0,0 +1,287 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var e2eResetTests = []backendTest{
+	{
+		name: "ResetWorkflow/ReExecutesWorkflow",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			executions := 0
+			a := func(ctx context.Context) (int, error) {
+				executions++
+				return executions, nil
+			}
+
+			wf := func(ctx workflow.Context) (int, error) {
+				return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+			}
+
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			instance := runWorkflow(t, ctx, c, wf)
+			r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 1, r)
+
+			// Reset to right after the workflow was started, before the activity was scheduled
+			var sequenceID int64
+			historyIterate(ctx, t, b, instance, func(event *history.Event) bool {
+				sequenceID = event.SequenceID
+				return event.Type != history.EventType_WorkflowExecutionStarted
+			})
+
+			err = c.ResetWorkflow(ctx, instance.InstanceID, sequenceID)
+			if errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip()
+				return
+			}
+			require.NoError(t, err)
+
+			r, err = client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 2, r)
+		},
+	},
+	{
+		name: "ResetWorkflow/RedeliversResults",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			executions := 0
+			a := func(ctx context.Context) (int, error) {
+				executions++
+				return executions, nil
+			}
+
+			wf := func(ctx workflow.Context) (int, error) {
+				return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+			}
+
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			instance := runWorkflow(t, ctx, c, wf)
+			r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 1, r)
+
+			// Reset to right after the activity was scheduled, the activity result has to be delivered again
+			var sequenceID int64
+			historyIterate(ctx, t, b, instance, func(event *history.Event) bool {
+				sequenceID = event.SequenceID
+				return event.Type != history.EventType_ActivityScheduled
+			})
+
+			err = c.ResetWorkflow(ctx, instance.InstanceID, sequenceID)
+			if errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip()
+				return
+			}
+			require.NoError(t, err)
+
+			r, err = client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 1, r)
+			require.Equal(t, 1, executions)
+		},
+	},
+	{
+		name: "ResetWorkflow/ErrorWhenResetPointDoesNotExist",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) error {
+				return nil
+			}
+
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf)
+			require.NoError(t, c.WaitForWorkflowInstance(ctx, instance, time.Second*10))
+
+			err := c.ResetWorkflow(ctx, instance.InstanceID, 1000)
+			if errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip()
+				return
+			}
+			require.ErrorIs(t, err, backend.ErrInvalidResetPoint)
+		},
+	},
+	{
+		name: "ResetWorkflow/ErrorWhenSubWorkflowsAreActive",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			swf := func(ctx workflow.Context) (int, error) {
+				r, _ := workflow.NewSignalChannel[int](ctx, "done").Receive(ctx)
+				return r, nil
+			}
+
+			subInstanceID := uuid.NewString()
+			wf := func(ctx workflow.Context) (int, error) {
+				return workflow.CreateSubWorkflowInstance[int](ctx, workflow.SubWorkflowOptions{
+					InstanceID: subInstanceID,
+				}, swf).Get(ctx)
+			}
+
+			register(t, ctx, w, []interface{}{wf, swf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf)
+
+			// Wait for the sub-workflow to be started
+			require.Eventually(t, func() bool {
+				_, err := b.GetLatestWorkflowInstance(ctx, subInstanceID)
+				return err == nil
+			}, time.Second*10, time.Millisecond*10)
+
+			// Reset to before the sub-workflow was started
+			var sequenceID int64
+			historyIterate(ctx, t, b, instance, func(event *history.Event) bool {
+				sequenceID = event.SequenceID
+				return event.Type != history.EventType_WorkflowExecutionStarted
+			})
+
+			err := c.ResetWorkflow(ctx, instance.InstanceID, sequenceID)
+			if errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip()
+				return
+			}
+			require.ErrorIs(t, err, backend.ErrSubWorkflowsActive)
+
+			require.NoError(t, c.SignalWorkflow(ctx, subInstanceID, "done", 42))
+
+			r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 42, r)
+		},
+	},
+	{
+		name: "RetryWorkflow/RetriesErroredInstance",
+		customWorkerOptions: func(options *worker.Options) {
+			options.MaxWorkflowTaskFailures = 1
+
+			// Workflow code changes are only noticed when replaying
+			options.WorkflowExecutorCache = &noopWorkflowExecutorCache{}
+		},
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			broken := false
+
+			a := func(ctx context.Context) (int, error) {
+				// Simulate deploying a bug while the activity is running
+				broken = true
+				return 42, nil
+			}
+
+			changed := func(ctx context.Context) (int, error) {
+				return 0, nil
+			}
+
+			wf := func(ctx workflow.Context) (int, error) {
+				if broken {
+					// Does not match the history, the workflow task fails
+					return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, changed).Get(ctx)
+				}
+
+				return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+			}
+
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a, changed})
+
+			instance := runWorkflow(t, ctx, c, wf)
+
+			require.Eventually(t, func() bool {
+				state, err := b.GetWorkflowInstanceState(ctx, instance)
+				require.NoError(t, err)
+				return state == core.WorkflowInstanceStateErrored
+			}, time.Second*10, time.Millisecond*10)
+
+			// Fix the bug and retry, the activity result is delivered again
+			broken = false
+
+			require.NoError(t, c.RetryWorkflow(ctx, instance.InstanceID))
+
+			r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, 42, r)
+		},
+	},
+	{
+		name: "RetryWorkflow/DeliversEventsReceivedWhileErrored",
+		customWorkerOptions: func(options *worker.Options) {
+			options.MaxWorkflowTaskFailures = 1
+
+			// Workflow code changes are only noticed when replaying
+			options.WorkflowExecutorCache = &noopWorkflowExecutorCache{}
+		},
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			broken := false
+
+			a := func(ctx context.Context) error {
+				broken = true
+				return nil
+			}
+
+			changed := func(ctx context.Context) error {
+				return nil
+			}
+
+			wf := func(ctx workflow.Context) (string, error) {
+				if broken {
+					_, err := workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, changed).Get(ctx)
+					return "", err
+				}
+
+				if _, err := workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, a).Get(ctx); err != nil {
+					return "", err
+				}
+
+				s, _ := workflow.NewSignalChannel[string](ctx, "signal").Receive(ctx)
+				return s, nil
+			}
+
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a, changed})
+
+			instance := runWorkflow(t, ctx, c, wf)
+
+			require.Eventually(t, func() bool {
+				state, err := b.GetWorkflowInstanceState(ctx, instance)
+				require.NoError(t, err)
+				return state == core.WorkflowInstanceStateErrored
+			}, time.Second*10, time.Millisecond*10)
+
+			// Errored instances still receive signals, they are delivered once the instance is retried
+			require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "signal", "hello"))
+
+			broken = false
+
+			require.NoError(t, c.RetryWorkflow(ctx, instance.InstanceID))
+
+			r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, "hello", r)
+		},
+	},
+	{
+		name: "RetryWorkflow/ErrorWhenWorkflowIsNotErrored",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) error {
+				return nil
+			}
+
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf)
+			require.NoError(t, c.WaitForWorkflowInstance(ctx, instance, time.Second*10))
+
+			err := c.RetryWorkflow(ctx, instance.InstanceID)
+			require.ErrorIs(t, err, client.ErrWorkflowNotErrored)
+		},
+	},
+}
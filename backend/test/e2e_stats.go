@@ -103,4 +103,56 @@ var e2eStatsTests = []backendTest{
 			require.Equal(t, int64(0), s.PendingActivityTasks[core.QueueDefault])
 		},
 	},
+	{
+		name: "Stats/QueueLag",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			activityRunning := make(chan bool, 1)
+			activityBlocked := make(chan bool, 1)
+
+			a := func(ctx context.Context) error {
+				activityRunning <- true
+				<-activityBlocked
+
+				return nil
+			}
+			wf := func(ctx workflow.Context) error {
+				_, err := workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+				return err
+			}
+
+			require.NoError(t, w.RegisterWorkflow(wf))
+			require.NoError(t, w.RegisterActivity(a))
+
+			workflowLag, activityLag, err := b.QueueLag(ctx)
+			require.NoError(t, err)
+			require.Equal(t, int64(0), workflowLag)
+			require.Equal(t, int64(0), activityLag)
+
+			wfi := runWorkflow(t, ctx, c, wf)
+
+			workflowLag, activityLag, err = b.QueueLag(ctx)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), workflowLag)
+			require.Equal(t, int64(0), activityLag)
+
+			require.NoError(t, w.Start(ctx))
+
+			// Wait until the activity is running, it is lag until it has been completed
+			<-activityRunning
+
+			workflowLag, activityLag, err = b.QueueLag(ctx)
+			require.NoError(t, err)
+			require.Equal(t, int64(0), workflowLag)
+			require.Equal(t, int64(1), activityLag)
+
+			activityBlocked <- true
+
+			require.NoError(t, c.WaitForWorkflowInstance(ctx, wfi, time.Second*10))
+
+			workflowLag, activityLag, err = b.QueueLag(ctx)
+			require.NoError(t, err)
+			require.Equal(t, int64(0), workflowLag)
+			require.Equal(t, int64(0), activityLag)
+		},
+	},
 }
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var e2eSubscriptionTests = []backendTest{
+	{
+		name: "Subscriptions/ReceivesUpdatesUntilFinished",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) error {
+				workflow.NewSignalChannel[bool](ctx, "done").Receive(ctx)
+				return nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+			}, wf)
+			require.NoError(t, err)
+
+			updates, err := c.SubscribeWorkflowEvents(ctx, instance.InstanceID)
+			if errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip()
+				return
+			}
+			require.NoError(t, err)
+
+			require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "done", true))
+
+			// Depending on timing, the update for the first workflow task might be received before the final one
+			update := receiveUpdate(t, updates)
+			if update.State == core.WorkflowInstanceStateActive {
+				update = receiveUpdate(t, updates)
+			}
+
+			require.Equal(t, instance.ExecutionID, update.Instance.ExecutionID)
+			require.Equal(t, core.WorkflowInstanceStateFinished, update.State)
+
+			_, ok := <-updates
+			require.False(t, ok, "channel should be closed after the instance has finished")
+		},
+	},
+	{
+		name: "Subscriptions/FinishedInstance",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) error {
+				return nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instance := runWorkflow(t, ctx, c, wf)
+			require.NoError(t, c.WaitForWorkflowInstance(ctx, instance, time.Second*10))
+
+			updates, err := c.SubscribeWorkflowEvents(ctx, instance.InstanceID)
+			if errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip()
+				return
+			}
+			require.NoError(t, err)
+
+			update := receiveUpdate(t, updates)
+			require.Equal(t, core.WorkflowInstanceStateFinished, update.State)
+
+			_, ok := <-updates
+			require.False(t, ok)
+		},
+	},
+	{
+		name: "Subscriptions/InstanceNotFound",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			register(t, ctx, w, nil, nil)
+
+			_, err := c.SubscribeWorkflowEvents(ctx, uuid.NewString())
+			if errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip()
+				return
+			}
+
+			require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+		},
+	},
+}
+
+func receiveUpdate(t *testing.T, updates <-chan *backend.WorkflowInstanceUpdate) *backend.WorkflowInstanceUpdate {
+	t.Helper()
+
+	select {
+	case update, ok := <-updates:
+		require.True(t, ok, "channel closed unexpectedly")
+		return update
+	case <-time.After(time.Second * 10):
+		require.FailNow(t, "timed out waiting for instance update")
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var e2eSubWorkflowTreeTests = []backendTest{
+	{
+		name: "SubWorkflow/Tree",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			leaf := func(ctx workflow.Context) error {
+				return nil
+			}
+			continuing := func(ctx workflow.Context, n int) error {
+				if n > 0 {
+					return workflow.ContinueAsNew(ctx, n-1)
+				}
+
+				return nil
+			}
+			middle := func(ctx workflow.Context) error {
+				_, err := workflow.CreateSubWorkflowInstance[any](ctx, workflow.SubWorkflowOptions{
+					InstanceID: workflow.WorkflowInstance(ctx).InstanceID + "-leaf",
+				}, leaf).Get(ctx)
+				return err
+			}
+			root := func(ctx workflow.Context) error {
+				id := workflow.WorkflowInstance(ctx).InstanceID
+
+				if _, err := workflow.CreateSubWorkflowInstance[any](ctx, workflow.SubWorkflowOptions{
+					InstanceID: id + "-middle",
+				}, middle).Get(ctx); err != nil {
+					return err
+				}
+
+				_, err := workflow.CreateSubWorkflowInstance[any](ctx, workflow.SubWorkflowOptions{
+					InstanceID: id + "-continuing",
+				}, continuing, 2).Get(ctx)
+				return err
+			}
+			register(t, ctx, w, []interface{}{root, middle, leaf, continuing}, nil)
+
+			instance := runWorkflow(t, ctx, c, root)
+			_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+
+			children, err := c.GetChildWorkflows(ctx, instance.InstanceID)
+			require.NoError(t, err)
+			require.Len(t, children, 2)
+			require.Equal(t, instance.InstanceID+"-middle", children[0].InstanceID)
+			require.Equal(t, instance.InstanceID+"-continuing", children[1].InstanceID)
+
+			// The most recent execution of the continued child is returned
+			latest, err := b.GetLatestWorkflowInstance(ctx, instance.InstanceID+"-continuing")
+			require.NoError(t, err)
+			require.Equal(t, latest.ExecutionID, children[1].ExecutionID)
+
+			grandchildren, err := c.GetChildWorkflows(ctx, children[0].InstanceID)
+			require.NoError(t, err)
+			require.Len(t, grandchildren, 1)
+			require.Equal(t, instance.InstanceID+"-middle-leaf", grandchildren[0].InstanceID)
+
+			leafChildren, err := c.GetChildWorkflows(ctx, grandchildren[0].InstanceID)
+			require.NoError(t, err)
+			require.Empty(t, leafChildren)
+
+			parent, err := c.GetParentWorkflow(ctx, grandchildren[0].InstanceID)
+			require.NoError(t, err)
+			require.Equal(t, children[0].InstanceID, parent.InstanceID)
+			require.Equal(t, children[0].ExecutionID, parent.ExecutionID)
+
+			parent, err = c.GetParentWorkflow(ctx, instance.InstanceID)
+			require.NoError(t, err)
+			require.Nil(t, parent)
+		},
+	},
+}
+
+var e2eParentClosePolicyTests = []backendTest{
+	{
+		name: "SubWorkflow/ParentClosePolicyAbandon",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			child := func(ctx workflow.Context) (string, error) {
+				s, _ := workflow.NewSignalChannel[string](ctx, "continue").Receive(ctx)
+				return s, nil
+			}
+			parent := func(ctx workflow.Context, childID string) error {
+				// Start the child, but finish without waiting for it. Abandon is the default policy.
+				workflow.CreateSubWorkflowInstance[string](ctx, workflow.SubWorkflowOptions{
+					InstanceID: childID,
+				}, child)
+
+				return workflow.Sleep(ctx, time.Millisecond*10)
+			}
+			register(t, ctx, w, []interface{}{parent, child}, nil)
+
+			childID := uuid.NewString()
+			instance := runWorkflow(t, ctx, c, parent, childID)
+
+			_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+
+			// The child keeps running after the parent has finished
+			childInstance, err := b.GetLatestWorkflowInstance(ctx, childID)
+			require.NoError(t, err)
+
+			require.NoError(t, c.SignalWorkflow(ctx, childID, "continue", "done"))
+
+			r, err := client.GetWorkflowResult[string](ctx, c, childInstance, time.Second*10)
+			require.NoError(t, err)
+			require.Equal(t, "done", r)
+		},
+	},
+	{
+		name: "SubWorkflow/ParentClosePolicyTerminate",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			testParentClosePolicy(t, ctx, c, w, b, workflow.ParentClosePolicyTerminate, client.ErrWorkflowTerminated.Error())
+		},
+	},
+	{
+		name: "SubWorkflow/ParentClosePolicyRequestCancel",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			testParentClosePolicy(t, ctx, c, w, b, workflow.ParentClosePolicyRequestCancel, workflow.Canceled.Error())
+		},
+	},
+}
+
+func testParentClosePolicy(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend, policy workflow.ParentClosePolicy, expectedErr string) {
+	child := func(ctx workflow.Context) error {
+		_, err := workflow.ScheduleTimer(ctx, time.Hour).Get(ctx)
+		return err
+	}
+	parent := func(ctx workflow.Context, childID string) error {
+		// Start the child, but finish without waiting for it
+		workflow.CreateSubWorkflowInstance[any](ctx, workflow.SubWorkflowOptions{
+			InstanceID:        childID,
+			ParentClosePolicy: policy,
+		}, child)
+
+		return workflow.Sleep(ctx, time.Millisecond*10)
+	}
+	register(t, ctx, w, []interface{}{parent, child}, nil)
+
+	childID := uuid.NewString()
+	instance := runWorkflow(t, ctx, c, parent, childID)
+
+	_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+	require.NoError(t, err)
+
+	childInstance, err := b.GetLatestWorkflowInstance(ctx, childID)
+	require.NoError(t, err)
+
+	_, err = client.GetWorkflowResult[any](ctx, c, childInstance, time.Second*10)
+	require.EqualError(t, err, expectedErr)
+
+	// The timer of the child has been canceled
+	futureEvents, err := b.GetFutureEvents(ctx)
+	require.NoError(t, err)
+	require.Empty(t, futureEvents)
+}
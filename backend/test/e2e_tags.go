@@ -0,0 +1,83 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var e2eTagsTests = []backendTest{
+	{
+		name: "Tags/ListAndCancelByTag",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) (bool, error) {
+				// Wait for the done signal, or until the instance is canceled
+				workflow.Select(ctx,
+					workflow.Receive(workflow.NewSignalChannel[bool](ctx, "done"), func(workflow.Context, bool, bool) {}),
+					workflow.Receive(ctx.Done(), func(workflow.Context, struct{}, bool) {}),
+				)
+
+				// Report whether the instance has been canceled
+				return ctx.Err() == workflow.Canceled, nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			tag := "batch-" + uuid.NewString()
+
+			first, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+				Tags:       []string{tag},
+			}, wf)
+			if errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip()
+				return
+			}
+			require.NoError(t, err)
+
+			second, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+				Tags:       []string{tag, "other-" + uuid.NewString()},
+			}, wf)
+			require.NoError(t, err)
+
+			// Not tagged
+			untagged, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+			}, wf)
+			require.NoError(t, err)
+
+			// Finish the first instance, it is still listed but not canceled
+			require.NoError(t, c.SignalWorkflow(ctx, first.InstanceID, "done", true))
+			require.NoError(t, c.WaitForWorkflowInstance(ctx, first, time.Second*10))
+
+			instances, err := c.ListWorkflowsByTag(ctx, tag)
+			require.NoError(t, err)
+			require.Len(t, instances, 2)
+			require.Equal(t, first.InstanceID, instances[0].Instance.InstanceID)
+			require.Equal(t, core.WorkflowInstanceStateFinished, instances[0].State)
+			require.Equal(t, second.InstanceID, instances[1].Instance.InstanceID)
+			require.Equal(t, core.WorkflowInstanceStateActive, instances[1].State)
+
+			canceled, err := c.CancelWorkflowsByTag(ctx, tag)
+			require.NoError(t, err)
+			require.Len(t, canceled, 1)
+			require.Equal(t, second.InstanceID, canceled[0].InstanceID)
+
+			wasCanceled, err := client.GetWorkflowResult[bool](ctx, c, second, time.Second*10)
+			require.NoError(t, err)
+			require.True(t, wasCanceled)
+
+			require.NoError(t, c.SignalWorkflow(ctx, untagged.InstanceID, "done", true))
+			require.NoError(t, c.WaitForWorkflowInstance(ctx, untagged, time.Second*10))
+		},
+	},
+}
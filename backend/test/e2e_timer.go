@@ -2,9 +2,11 @@ package test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/client"
 	"github.com/cschleiden/go-workflows/worker"
@@ -195,4 +197,50 @@ var e2eTimerTests = []backendTest{
 			require.NoError(t, err)
 		},
 	},
+	{
+		name:    "Timer/CanceledTimerCompaction",
+		options: []backend.BackendOption{backend.WithCanceledTimerCompaction()},
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			a := func(ctx context.Context) error {
+				return nil
+			}
+			wf := func(ctx workflow.Context) error {
+				for i := 0; i < 3; i++ {
+					tctx, cancel := workflow.WithCancel(ctx)
+					f := workflow.ScheduleTimer(tctx, time.Hour)
+
+					// Force a checkpoint, so that the timer is recorded before it's canceled
+					if _, err := workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, a).Get(ctx); err != nil {
+						return err
+					}
+
+					cancel()
+
+					if _, err := f.Get(ctx); !errors.Is(err, workflow.Canceled) {
+						return errors.New("timer was not canceled")
+					}
+				}
+
+				// Replaying the compacted history continues the workflow
+				_, err := workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+				return err
+			}
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+			// The worker needs to be started before skipping, otherwise waiting for it to complete blocks
+			if !b.FeatureSupported(backend.Feature_TimerCompaction) {
+				t.Skip()
+			}
+
+			instance := runWorkflow(t, ctx, c, wf)
+			_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+
+			historyIterate(ctx, t, b, instance, func(event *history.Event) bool {
+				require.NotEqual(t, history.EventType_TimerScheduled, event.Type)
+				require.NotEqual(t, history.EventType_TimerCanceled, event.Type)
+				return true
+			})
+		},
+	},
 }
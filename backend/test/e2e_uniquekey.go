@@ -0,0 +1,106 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var e2eUniqueKeyTests = []backendTest{
+	{
+		name: "UniqueKey/PreventsDuplicateActiveInstances",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context) error {
+				workflow.NewSignalChannel[bool](ctx, "done").Receive(ctx)
+				return nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			uniqueKey := "user-" + uuid.NewString()
+
+			instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+				UniqueKey:  uniqueKey,
+			}, wf)
+			if errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip()
+				return
+			}
+			require.NoError(t, err)
+
+			// Same key while the first instance is active
+			_, err = c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+				UniqueKey:  uniqueKey,
+			}, wf)
+			require.ErrorIs(t, err, backend.ErrInstanceAlreadyExists)
+
+			// Different key
+			other, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+				UniqueKey:  "user-" + uuid.NewString(),
+			}, wf)
+			require.NoError(t, err)
+
+			for _, i := range []*workflow.Instance{instance, other} {
+				require.NoError(t, c.SignalWorkflow(ctx, i.InstanceID, "done", true))
+				require.NoError(t, c.WaitForWorkflowInstance(ctx, i, time.Second*10))
+			}
+
+			// Key is released once the first instance has finished
+			_, err = c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+				UniqueKey:  uniqueKey,
+			}, wf)
+			require.NoError(t, err)
+		},
+	},
+	{
+		name: "UniqueKey/KeptWhenContinuedAsNew",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			wf := func(ctx workflow.Context, run int) (int, error) {
+				if run == 0 {
+					return 0, workflow.ContinueAsNew(ctx, run+1)
+				}
+
+				workflow.NewSignalChannel[bool](ctx, "done").Receive(ctx)
+				return run, nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			uniqueKey := "user-" + uuid.NewString()
+
+			instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+				UniqueKey:  uniqueKey,
+			}, wf, 0)
+			if errors.As(err, &backend.ErrNotSupported{}) {
+				t.Skip()
+				return
+			}
+			require.NoError(t, err)
+
+			// Wait for the first execution to continue as new
+			require.Eventually(t, func() bool {
+				latest, err := b.GetLatestWorkflowInstance(ctx, instance.InstanceID)
+				return err == nil && latest.ExecutionID != instance.ExecutionID
+			}, time.Second*10, time.Millisecond*10)
+
+			_, err = c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+				UniqueKey:  uniqueKey,
+			}, wf, 0)
+			require.ErrorIs(t, err, backend.ErrInstanceAlreadyExists)
+
+			require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "done", true))
+		},
+	},
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+var e2eUsageTests = []backendTest{
+	{
+		name: "Usage/DescribeWorkflowInstance",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			a := func(ctx context.Context) error {
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			}
+			failing := func(ctx context.Context) error {
+				return workflow.NewPermanentError(errors.New("activity failed"))
+			}
+			wf := func(ctx workflow.Context) error {
+				if _, err := workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, a).Get(ctx); err != nil {
+					return err
+				}
+
+				if err := workflow.Sleep(ctx, time.Millisecond); err != nil {
+					return err
+				}
+
+				_, err := workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, failing).Get(ctx)
+				if err == nil {
+					return errors.New("expected activity to fail")
+				}
+
+				return nil
+			}
+			register(t, ctx, w, []interface{}{wf}, []interface{}{a, failing})
+
+			instance := runWorkflow(t, ctx, c, wf)
+
+			_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+
+			var events, tasks, timers int64
+			historyIterate(ctx, t, b, instance, func(event *history.Event) bool {
+				events++
+
+				switch event.Type {
+				case history.EventType_WorkflowTaskStarted:
+					tasks++
+				case history.EventType_TimerScheduled:
+					timers++
+				}
+
+				return true
+			})
+
+			d, err := c.DescribeWorkflowInstance(ctx, instance.InstanceID)
+			require.NoError(t, err)
+			require.NotNil(t, d.Usage)
+			require.Equal(t, tasks, d.Usage.WorkflowTasks)
+			require.Equal(t, int64(2), d.Usage.Activities)
+			require.GreaterOrEqual(t, d.Usage.ActivityDuration, 20*time.Millisecond)
+			require.Equal(t, timers, d.Usage.Timers)
+			require.Equal(t, events, d.Usage.HistoryEvents)
+		},
+	},
+	{
+		name: "Usage/InstanceNotFound",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			register(t, ctx, w, nil, nil)
+
+			_, err := b.GetWorkflowInstanceUsage(ctx, &workflow.Instance{InstanceID: "unknown", ExecutionID: "unknown"})
+			require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+		},
+	},
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var e2eWebhookTests = []backendTest{
+	{
+		name: "CompletionWebhook/DeliversResult",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			bodies := make(chan map[string]any, 1)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				bodies <- body
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer srv.Close()
+
+			wf := func(ctx workflow.Context, msg string) (string, error) {
+				return msg + " world", nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instanceID := uuid.NewString()
+
+			// No delivery before the instance exists
+			_, err := c.GetCompletionWebhookDelivery(ctx, instanceID)
+			require.Error(t, err)
+
+			instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID:        instanceID,
+				CompletionWebhook: srv.URL,
+			}, wf, "hello")
+			require.NoError(t, err)
+
+			_, err = client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+			require.NoError(t, err)
+
+			select {
+			case body := <-bodies:
+				require.Equal(t, instanceID, body["instance_id"])
+				require.Equal(t, instance.ExecutionID, body["execution_id"])
+				require.Equal(t, "hello world", body["result"])
+				require.Nil(t, body["error"])
+			case <-time.After(time.Second * 10):
+				t.Fatal("webhook not called")
+			}
+
+			require.Eventually(t, func() bool {
+				d, err := c.GetCompletionWebhookDelivery(ctx, instanceID)
+				return err == nil && d.State == client.CompletionWebhookStateDelivered && d.StatusCode == http.StatusAccepted
+			}, time.Second*10, time.Millisecond*50)
+		},
+	},
+	{
+		name: "CompletionWebhook/DeliversError",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			bodies := make(chan map[string]any, 1)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				bodies <- body
+			}))
+			defer srv.Close()
+
+			wf := func(ctx workflow.Context) error {
+				return errors.New("something went wrong")
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			_, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID:        uuid.NewString(),
+				CompletionWebhook: srv.URL,
+			}, wf)
+			require.NoError(t, err)
+
+			select {
+			case body := <-bodies:
+				require.Nil(t, body["result"])
+				require.Equal(t, "something went wrong", body["error"].(map[string]any)["message"])
+			case <-time.After(time.Second * 10):
+				t.Fatal("webhook not called")
+			}
+		},
+	},
+	{
+		name: "CompletionWebhook/RejectedDeliveryFails",
+		f: func(t *testing.T, ctx context.Context, c *client.Client, w *worker.Worker, b TestBackend) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			defer srv.Close()
+
+			wf := func(ctx workflow.Context) error {
+				return nil
+			}
+			register(t, ctx, w, []interface{}{wf}, nil)
+
+			instanceID := uuid.NewString()
+			instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID:        instanceID,
+				CompletionWebhook: srv.URL,
+			}, wf)
+			require.NoError(t, err)
+
+			require.NoError(t, c.WaitForWorkflowInstance(ctx, instance, time.Second*10))
+
+			// Rejections are not retried
+			require.Eventually(t, func() bool {
+				d, err := c.GetCompletionWebhookDelivery(ctx, instanceID)
+				return err == nil && d.State == client.CompletionWebhookStateFailed && d.Error != nil
+			}, time.Second*10, time.Millisecond*50)
+		},
+	},
+}
@@ -0,0 +1,211 @@
+// Package faults provides a backend wrapper that injects faults, to test that workflows survive an unreliable
+// backend. It is meant for tests only.
+package faults
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// ErrInjectedFault is returned by operations that failed because of an injected fault
+var ErrInjectedFault = errors.New("injected fault")
+
+type options struct {
+	seed int64
+
+	getWorkflowTaskFailures      float64
+	completeWorkflowTaskFailures float64
+	getActivityTaskFailures      float64
+	completeActivityTaskFailures float64
+
+	completeWorkflowTaskDelays float64
+	completeWorkflowTaskDelay  time.Duration
+
+	droppedActivities float64
+}
+
+type Option func(*options)
+
+// WithSeed sets the seed for deciding which operations fail, to make a test run reproducible. Defaults to 1.
+func WithSeed(seed int64) Option {
+	return func(o *options) {
+		o.seed = seed
+	}
+}
+
+// WithGetWorkflowTaskFailures fails the given fraction of GetWorkflowTask calls, between 0 and 1.
+func WithGetWorkflowTaskFailures(p float64) Option {
+	return func(o *options) {
+		o.getWorkflowTaskFailures = p
+	}
+}
+
+// WithCompleteWorkflowTaskFailures fails the given fraction of CompleteWorkflowTask calls, between 0 and 1, without
+// completing the task. The task is picked up again once its lock has expired.
+func WithCompleteWorkflowTaskFailures(p float64) Option {
+	return func(o *options) {
+		o.completeWorkflowTaskFailures = p
+	}
+}
+
+// WithGetActivityTaskFailures fails the given fraction of GetActivityTask calls, between 0 and 1.
+func WithGetActivityTaskFailures(p float64) Option {
+	return func(o *options) {
+		o.getActivityTaskFailures = p
+	}
+}
+
+// WithCompleteActivityTaskFailures fails the given fraction of CompleteActivityTask calls, between 0 and 1, without
+// completing the task. The task is picked up again once its lock has expired.
+func WithCompleteActivityTaskFailures(p float64) Option {
+	return func(o *options) {
+		o.completeActivityTaskFailures = p
+	}
+}
+
+// WithCompleteWorkflowTaskDelays delays the given fraction of CompleteWorkflowTask calls, between 0 and 1, by delay.
+func WithCompleteWorkflowTaskDelays(p float64, delay time.Duration) Option {
+	return func(o *options) {
+		o.completeWorkflowTaskDelays = p
+		o.completeWorkflowTaskDelay = delay
+	}
+}
+
+// WithDroppedActivities drops the given fraction of activities scheduled by workflow tasks, between 0 and 1. The
+// workflow task completes successfully, but dropped activities are never executed, like messages lost by a queue.
+func WithDroppedActivities(p float64) Option {
+	return func(o *options) {
+		o.droppedActivities = p
+	}
+}
+
+// Stats counts the faults injected by a FaultInjectingBackend
+type Stats struct {
+	GetWorkflowTaskFailures      int
+	CompleteWorkflowTaskFailures int
+	GetActivityTaskFailures      int
+	CompleteActivityTaskFailures int
+	CompleteWorkflowTaskDelays   int
+	DroppedActivities            int
+}
+
+// FaultInjectingBackend delegates to a backend, and injects faults with the configured probabilities
+type FaultInjectingBackend struct {
+	backend.Backend
+
+	options options
+
+	mu    sync.Mutex
+	rnd   *rand.Rand
+	stats Stats
+}
+
+var _ backend.Backend = (*FaultInjectingBackend)(nil)
+
+// NewFaultInjectingBackend wraps the given backend. Without options, no faults are injected.
+func NewFaultInjectingBackend(b backend.Backend, opts ...Option) *FaultInjectingBackend {
+	o := options{
+		seed: 1,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &FaultInjectingBackend{
+		Backend: b,
+		options: o,
+		rnd:     rand.New(rand.NewSource(o.seed)),
+	}
+}
+
+// Stats returns the number of faults injected so far
+func (b *FaultInjectingBackend) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.stats
+}
+
+// inject decides whether to inject a fault with the given probability, and counts it if so
+func (b *FaultInjectingBackend) inject(p float64, count *int) bool {
+	if p <= 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rnd.Float64() >= p {
+		return false
+	}
+
+	*count++
+
+	return true
+}
+
+func (b *FaultInjectingBackend) GetWorkflowTask(ctx context.Context, queues []workflow.Queue) (*backend.WorkflowTask, error) {
+	if b.inject(b.options.getWorkflowTaskFailures, &b.stats.GetWorkflowTaskFailures) {
+		return nil, ErrInjectedFault
+	}
+
+	return b.Backend.GetWorkflowTask(ctx, queues)
+}
+
+func (b *FaultInjectingBackend) CompleteWorkflowTask(
+	ctx context.Context,
+	task *backend.WorkflowTask,
+	state core.WorkflowInstanceState,
+	executedEvents, activityEvents, timerEvents []*history.Event,
+	workflowEvents []*history.WorkflowEvent,
+) error {
+	if b.inject(b.options.completeWorkflowTaskDelays, &b.stats.CompleteWorkflowTaskDelays) {
+		select {
+		case <-time.After(b.options.completeWorkflowTaskDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if b.inject(b.options.completeWorkflowTaskFailures, &b.stats.CompleteWorkflowTaskFailures) {
+		return ErrInjectedFault
+	}
+
+	if b.options.droppedActivities > 0 {
+		kept := make([]*history.Event, 0, len(activityEvents))
+		for _, e := range activityEvents {
+			if !b.inject(b.options.droppedActivities, &b.stats.DroppedActivities) {
+				kept = append(kept, e)
+			}
+		}
+
+		activityEvents = kept
+	}
+
+	return b.Backend.CompleteWorkflowTask(ctx, task, state, executedEvents, activityEvents, timerEvents, workflowEvents)
+}
+
+func (b *FaultInjectingBackend) GetActivityTask(ctx context.Context, queues []workflow.Queue) (*backend.ActivityTask, error) {
+	if b.inject(b.options.getActivityTaskFailures, &b.stats.GetActivityTaskFailures) {
+		return nil, ErrInjectedFault
+	}
+
+	return b.Backend.GetActivityTask(ctx, queues)
+}
+
+func (b *FaultInjectingBackend) CompleteActivityTask(ctx context.Context, task *backend.ActivityTask, result *history.Event) error {
+	if b.inject(b.options.completeActivityTaskFailures, &b.stats.CompleteActivityTaskFailures) {
+		return ErrInjectedFault
+	}
+
+	return b.Backend.CompleteActivityTask(ctx, task, result)
+}
@@ -0,0 +1,103 @@
+package faults
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FaultInjectingBackend_Seed(t *testing.T) {
+	decisions := func(seed int64) []bool {
+		b := NewFaultInjectingBackend(nil, WithSeed(seed))
+
+		var count int
+		r := make([]bool, 100)
+		for i := range r {
+			r[i] = b.inject(0.5, &count)
+		}
+
+		return r
+	}
+
+	require.Equal(t, decisions(42), decisions(42))
+	require.NotEqual(t, decisions(42), decisions(43))
+}
+
+func Test_FaultInjectingBackend_NoFaults(t *testing.T) {
+	b := NewFaultInjectingBackend(nil)
+
+	for i := 0; i < 100; i++ {
+		require.False(t, b.inject(b.options.getWorkflowTaskFailures, &b.stats.GetWorkflowTaskFailures))
+	}
+
+	require.Equal(t, Stats{}, b.Stats())
+}
+
+func Test_FaultInjectingBackend_WorkflowCompletes(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	b := NewFaultInjectingBackend(
+		sqlite.NewInMemoryBackend(),
+		WithSeed(7),
+		WithGetWorkflowTaskFailures(0.5),
+		WithGetActivityTaskFailures(0.5),
+		WithCompleteWorkflowTaskDelays(0.5, 50*time.Millisecond),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options := worker.DefaultOptions
+	options.WorkflowPollingInterval = 10 * time.Millisecond
+	options.ActivityPollingInterval = 10 * time.Millisecond
+
+	w := worker.New(b, &options)
+
+	activity := func(ctx context.Context, i int) (int, error) {
+		return i * 2, nil
+	}
+
+	wf := func(ctx workflow.Context) (int, error) {
+		sum := 0
+		for i := 0; i < 5; i++ {
+			r, err := workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity, i).Get(ctx)
+			if err != nil {
+				return 0, err
+			}
+
+			sum += r
+		}
+
+		return sum, nil
+	}
+
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.RegisterActivity(activity))
+	require.NoError(t, w.Start(ctx))
+
+	c := client.New(b)
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: "faults",
+	}, wf)
+	require.NoError(t, err)
+
+	r, err := client.GetWorkflowResult[int](ctx, c, instance, 20*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 20, r)
+
+	stats := b.Stats()
+	require.Positive(t, stats.GetWorkflowTaskFailures)
+	require.Positive(t, stats.GetActivityTaskFailures)
+	require.Positive(t, stats.CompleteWorkflowTaskDelays)
+
+	cancel()
+	require.NoError(t, w.WaitForCompletion())
+}
@@ -0,0 +1,23 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/cschleiden/go-workflows/backend"
+)
+
+// RunSuite runs all backend and end-to-end tests against the backends returned by setup. Every backend
+// implementation is expected to pass the suite, tests for optional features are skipped if the backend returns
+// backend.ErrNotSupported.
+//
+// setup is called for every test with the options the test requires, teardown is called after every test if
+// not nil.
+func RunSuite(t *testing.T, setup func(options ...backend.BackendOption) TestBackend, teardown func(b TestBackend)) {
+	t.Run("Backend", func(t *testing.T) {
+		BackendTest(t, setup, teardown)
+	})
+
+	t.Run("EndToEnd", func(t *testing.T) {
+		EndToEndBackendTest(t, setup, teardown)
+	})
+}
@@ -0,0 +1,56 @@
+package backend
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/history"
+)
+
+// WorkflowInstanceUsage summarizes the resources used by an execution of a workflow instance, e.g., to bill tenants
+// by the complexity of their workflows.
+type WorkflowInstanceUsage struct {
+	// WorkflowTasks is the number of workflow tasks completed for the execution
+	WorkflowTasks int64 `json:"workflow_tasks,omitempty"`
+
+	// Activities is the number of activity executions whose result has been recorded, including failed attempts
+	Activities int64 `json:"activities,omitempty"`
+
+	// ActivityDuration is the total time spent executing activities, as reported by the workers
+	ActivityDuration time.Duration `json:"activity_duration,omitempty"`
+
+	// Timers is the number of timers scheduled
+	Timers int64 `json:"timers,omitempty"`
+
+	// HistoryEvents is the number of events recorded in the history of the execution
+	HistoryEvents int64 `json:"history_events,omitempty"`
+}
+
+// WorkflowTaskUsage returns the usage of a single workflow task that has recorded the given executed events.
+// Backends add it to the usage of the workflow instance when completing the task.
+func WorkflowTaskUsage(executedEvents []*history.Event) *WorkflowInstanceUsage {
+	usage := &WorkflowInstanceUsage{
+		WorkflowTasks: 1,
+		HistoryEvents: int64(len(executedEvents)),
+	}
+
+	for _, event := range executedEvents {
+		switch event.Type {
+		case history.EventType_ActivityCompleted:
+			usage.Activities++
+			if a, ok := event.Attributes.(*history.ActivityCompletedAttributes); ok {
+				usage.ActivityDuration += a.Duration
+			}
+
+		case history.EventType_ActivityFailed:
+			usage.Activities++
+			if a, ok := event.Attributes.(*history.ActivityFailedAttributes); ok {
+				usage.ActivityDuration += a.Duration
+			}
+
+		case history.EventType_TimerScheduled:
+			usage.Timers++
+		}
+	}
+
+	return usage
+}
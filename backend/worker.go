@@ -0,0 +1,18 @@
+package backend
+
+import "context"
+
+type workerIDKey struct{}
+
+// WithWorkerID returns a context for calls made on behalf of the worker with the given ID. Tasks retrieved with the
+// context are locked for that worker, and DeregisterWorker releases them when the worker shuts down.
+func WithWorkerID(ctx context.Context, workerID string) context.Context {
+	return context.WithValue(ctx, workerIDKey{}, workerID)
+}
+
+// WorkerID returns the ID of the worker the given context has been created for using WithWorkerID, or an empty string
+// if the call is not made on behalf of a worker.
+func WorkerID(ctx context.Context) string {
+	workerID, _ := ctx.Value(workerIDKey{}).(string)
+	return workerID
+}
@@ -32,6 +32,8 @@ var activities = flag.Int("activities", 2, "Number of activities to execute per
 var resultSize = flag.Int("resultsize", 100, "Size of activity result payload in bytes")
 var format = flag.String("format", "text", "Output format. Supported formats are:\n- text\n- csv\n")
 var cacheSize = flag.Int("cachesize", 128, "Size of the workflow executor cache")
+var completionBatch = flag.Int("completionbatch", 0, "Maximum number of workflow task completions to batch (redis only)")
+var completionDelay = flag.Duration("completiondelay", time.Millisecond, "Maximum delay for batching workflow task completions (redis only)")
 
 func main() {
 	flag.Parse()
@@ -161,7 +163,12 @@ func getBackend(b string, opt ...backend.BackendOption) backend.Backend {
 			panic(err)
 		}
 
-		b, err := redis.NewRedisBackend(rclient, redis.WithBackendOptions(opt...))
+		redisOptions := []redis.RedisBackendOption{redis.WithBackendOptions(opt...)}
+		if *completionBatch > 1 {
+			redisOptions = append(redisOptions, redis.WithCompletionBatching(*completionBatch, *completionDelay))
+		}
+
+		b, err := redis.NewRedisBackend(rclient, redisOptions...)
 		if err != nil {
 			panic(err)
 		}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/workflowerrors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrActivityNotFound is returned when the history of a workflow instance does not contain the requested activity.
+var ErrActivityNotFound = errors.New("activity not found")
+
+type ActivityAttemptState int
+
+const (
+	ActivityAttemptStatePending ActivityAttemptState = iota
+	ActivityAttemptStateCompleted
+	ActivityAttemptStateFailed
+	ActivityAttemptStateCanceled
+)
+
+// ActivityAttempt describes a single attempt of an activity
+type ActivityAttempt struct {
+	// Attempt is the number of the attempt, starting at 0
+	Attempt int
+
+	// ScheduleEventID identifies the attempt in the history of the workflow instance
+	ScheduleEventID int64
+
+	// ScheduledAt is the time the attempt was scheduled by the workflow
+	ScheduledAt time.Time
+
+	// FinishedAt is the time the outcome of the attempt was recorded, zero while the attempt is pending
+	FinishedAt time.Time
+
+	State ActivityAttemptState
+
+	// Error is the error the attempt failed with
+	Error error
+}
+
+// GetActivityAttempts returns all attempts of an activity executed by the latest execution of the given workflow
+// instance, in the order they were scheduled. activityID is the schedule event ID of the first attempt of the
+// activity. Returns ErrActivityNotFound if the history does not contain the activity.
+func (c *Client) GetActivityAttempts(ctx context.Context, instanceID string, activityID int64) ([]*ActivityAttempt, error) {
+	ctx, span := c.backend.Tracer().Start(ctx, "GetActivityAttempts", trace.WithAttributes(
+		attribute.String(log.InstanceIDKey, instanceID),
+		attribute.Int64(log.ScheduleEventIDKey, activityID),
+	))
+	defer span.End()
+
+	instance, err := c.backend.GetLatestWorkflowInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := c.backend.GetWorkflowInstanceHistory(ctx, instance, nil, backend.HistoryWithoutActivityInputs())
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow history: %w", err)
+	}
+
+	attempts := activityAttempts(h, activityID)
+	if len(attempts) == 0 {
+		return nil, ErrActivityNotFound
+	}
+
+	return attempts, nil
+}
+
+func activityAttempts(h []*history.Event, activityID int64) []*ActivityAttempt {
+	attempts := make([]*ActivityAttempt, 0)
+	byScheduleEventID := make(map[int64]*ActivityAttempt)
+
+	// Activities recorded before attempts were linked, by name. Their attempts are scheduled one after the other.
+	unlinked := make(map[string]int64)
+
+	for _, event := range h {
+		switch event.Type {
+		case history.EventType_ActivityScheduled:
+			a := event.Attributes.(*history.ActivityScheduledAttributes)
+
+			id := a.ActivityID
+			if id == 0 {
+				if a.Attempt == 0 {
+					unlinked[a.Name] = event.ScheduleEventID
+				}
+
+				id = unlinked[a.Name]
+			}
+
+			if id != activityID {
+				continue
+			}
+
+			attempt := &ActivityAttempt{
+				Attempt:         a.Attempt,
+				ScheduleEventID: event.ScheduleEventID,
+				ScheduledAt:     event.Timestamp,
+				State:           ActivityAttemptStatePending,
+			}
+
+			attempts = append(attempts, attempt)
+			byScheduleEventID[event.ScheduleEventID] = attempt
+
+		case history.EventType_ActivityCompleted, history.EventType_ActivityFailed, history.EventType_ActivityCanceled:
+			attempt, ok := byScheduleEventID[event.ScheduleEventID]
+			if !ok {
+				continue
+			}
+
+			attempt.FinishedAt = event.Timestamp
+
+			switch a := event.Attributes.(type) {
+			case *history.ActivityCompletedAttributes:
+				attempt.State = ActivityAttemptStateCompleted
+
+			case *history.ActivityFailedAttributes:
+				attempt.State = ActivityAttemptStateFailed
+				attempt.Error = workflowerrors.ToError(a.Error)
+
+			case *history.ActivityCanceledAttributes:
+				attempt.State = ActivityAttemptStateCanceled
+			}
+		}
+	}
+
+	return attempts
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"io"
+
+	"github.com/cschleiden/go-workflows/internal/blobs"
+	"github.com/cschleiden/go-workflows/workflow"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CreateBlobInput streams the content of the given reader into the blob store of the backend and returns a handle
+// that can be passed to workflows instead of the content. Activities read the content using activity.OpenBlob.
+// Returns backend.ErrNotSupported if the backend has no blob store.
+//
+// Blobs are not removed together with workflow instances, use RemoveBlobInput once they are no longer needed.
+func (c *Client) CreateBlobInput(ctx context.Context, r io.Reader) (workflow.BlobInput, error) {
+	ctx, span := c.backend.Tracer().Start(ctx, "CreateBlobInput")
+	defer span.End()
+
+	input, err := c.blobs().Create(ctx, r)
+	if err != nil {
+		return workflow.BlobInput{}, err
+	}
+
+	span.SetAttributes(attribute.String("blob", input.Key))
+
+	return input, nil
+}
+
+// RemoveBlobInput removes the content of the given blob input from the blob store of the backend
+func (c *Client) RemoveBlobInput(ctx context.Context, input workflow.BlobInput) error {
+	ctx, span := c.backend.Tracer().Start(ctx, "RemoveBlobInput", trace.WithAttributes(
+		attribute.String("blob", input.Key),
+	))
+	defer span.End()
+
+	return c.blobs().Delete(ctx, input)
+}
+
+func (c *Client) blobs() *blobs.Store {
+	return blobs.New(c.backend.Options().BlobStore, c.backend.Options().Converter)
+}
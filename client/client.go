@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/benbjohnson/clock"
@@ -11,11 +12,14 @@ import (
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/backend/metrics"
+	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/cschleiden/go-workflows/core"
+	iactivity "github.com/cschleiden/go-workflows/internal/activity"
 	a "github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/log"
 	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/internal/propagators"
 	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflowerrors"
 	"github.com/cschleiden/go-workflows/workflow"
@@ -30,25 +34,91 @@ var ErrWorkflowCanceled = errors.New("workflow canceled")
 // ErrWorkflowTerminated is returned when a workflow was already terminated.
 var ErrWorkflowTerminated = errors.New("workflow terminated")
 
+// ErrWorkflowNotErrored is returned when retrying a workflow instance that is not errored.
+var ErrWorkflowNotErrored = errors.New("workflow is not errored")
+
+// ErrStartRateLimited is returned when a workflow instance could not be created because the start rate limit
+// was hit.
+var ErrStartRateLimited = errors.New("workflow instance creation rate limited")
+
 type WorkflowInstanceOptions struct {
 	// Queue is the queue the workflow instance will be created in. Must be a valid queue
 	// for the given backend. If not set, will default to the default queue
 	Queue workflow.Queue
 
 	InstanceID string
+
+	// UniqueKey is an optional business key, for example a user ID. Only one active workflow instance can exist
+	// for a given key, creating another instance with the same key fails with backend.ErrInstanceAlreadyExists
+	// until the first one has finished. Not all backends support unique keys.
+	UniqueKey string
+
+	// Priority controls the order in which workflow tasks are dispatched to workers, tasks of instances with a
+	// higher priority are dispatched first. The default priority of 0 preserves the order in which tasks were
+	// queued. The priority is kept when the instance continues as new. Not all backends support priorities.
+	Priority int
+
+	// Tags are optional labels, for example to group instances belonging to the same batch job. Instances can be
+	// listed and canceled by tag. Tags are kept when the instance continues as new. Not all backends support tags.
+	Tags []string
+
+	// ExecutionTimeout is the time budget of the workflow execution. It is informational, the execution is not
+	// stopped when it runs out. Workflows can read the resulting deadline with workflow.GetInfo, and activities see
+	// it as the deadline of their context. Executions continued as new get the same budget.
+	ExecutionTimeout time.Duration
+
+	// CompletionWebhook is an optional URL the outcome of the instance is posted to once it has finished. Delivery
+	// is retried on failure, and its progress can be read using GetCompletionWebhookDelivery. Executions continued
+	// as new keep the webhook, it is only called when the last execution finishes.
+	CompletionWebhook string
+
+	// InitialSignals are added as signal events together with the workflow instance, and are received by the workflow
+	// before it first runs. Unlike signals sent after creating the instance, they cannot arrive too late. Executions
+	// continued as new do not receive them again.
+	InitialSignals []Signal
+
+	// Headers are custom values, for example a tenant ID, that are passed on to the workflow and to all activities and
+	// sub-workflows it schedules. Activities can read them using activity.Header.
+	Headers map[string]string
+
+	// Namespace selects the registry the workflow instance is executed with, for workers serving multiple namespaces
+	// registered with worker.RegisterNamespace. Activities and sub-workflows scheduled by the instance are executed
+	// in the same namespace. Instances without namespace use the registry of the worker.
+	Namespace string
+}
+
+// Signal is a signal sent to a workflow instance
+type Signal struct {
+	Name string
+	Arg  any
 }
 
 type Client struct {
 	backend backend.Backend
 	clock   clock.Clock
+	options Options
+
+	startLimiter *rateLimiter
 }
 
 // New creates a new client for the given backend.
-func New(backend backend.Backend) *Client {
-	return &Client{
+func New(backend backend.Backend, opts ...ClientOption) *Client {
+	options := DefaultOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	c := &Client{
 		backend: backend,
 		clock:   clock.New(),
+		options: options,
+	}
+
+	if options.StartRateLimit > 0 {
+		c.startLimiter = newRateLimiter(c.clock, options.StartRateLimit, options.StartBurst)
 	}
+
+	return c
 }
 
 // CreateWorkflowInstance creates a new workflow instance of the given workflow.
@@ -71,6 +141,11 @@ func (c *Client) CreateWorkflowInstance(ctx context.Context, options WorkflowIns
 		return nil, fmt.Errorf("converting arguments: %w", err)
 	}
 
+	inputs, inputsCompressed, err := c.compressInputs(inputs)
+	if err != nil {
+		return nil, fmt.Errorf("compressing inputs: %w", err)
+	}
+
 	if options.InstanceID == "" {
 		return nil, errors.New("InstanceID must be set")
 	}
@@ -79,6 +154,33 @@ func (c *Client) CreateWorkflowInstance(ctx context.Context, options WorkflowIns
 		options.Queue = workflow.QueueDefault
 	}
 
+	var signalEvents []*history.Event
+	for _, signal := range options.InitialSignals {
+		arg, err := c.backend.Options().Converter.To(signal.Arg)
+		if err != nil {
+			return nil, fmt.Errorf("converting argument of signal %q: %w", signal.Name, err)
+		}
+
+		signalEvents = append(signalEvents, history.NewPendingEvent(
+			c.clock.Now(),
+			history.EventType_SignalReceived,
+			&history.SignalReceivedAttributes{
+				Name: signal.Name,
+				Arg:  arg,
+			},
+		))
+	}
+
+	if c.startLimiter != nil {
+		if c.options.StartRateLimitNoWait {
+			if !c.startLimiter.Allow() {
+				return nil, ErrStartRateLimited
+			}
+		} else if err := c.startLimiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("waiting for start rate limit: %w", err)
+		}
+	}
+
 	wfi := core.NewWorkflowInstance(options.InstanceID, uuid.NewString())
 	metadata := &workflow.Metadata{}
 
@@ -97,20 +199,31 @@ func (c *Client) CreateWorkflowInstance(ctx context.Context, options WorkflowIns
 		}
 	}
 
+	propagators.InjectHeaders(metadata, options.Headers)
+	propagators.InjectNamespace(metadata, options.Namespace)
+
 	workflowSpanID := tracing.GetNewSpanID(c.backend.Tracer())
 
 	startedEvent := history.NewPendingEvent(
 		c.clock.Now(),
 		history.EventType_WorkflowExecutionStarted,
 		&history.ExecutionStartedAttributes{
-			Queue:          options.Queue,
-			Metadata:       metadata,
-			Name:           workflowName,
-			Inputs:         inputs,
-			WorkflowSpanID: workflowSpanID,
+			Queue:             options.Queue,
+			Metadata:          metadata,
+			Name:              workflowName,
+			Inputs:            inputs,
+			InputsCompressed:  inputsCompressed,
+			WorkflowSpanID:    workflowSpanID,
+			UniqueKey:         options.UniqueKey,
+			Priority:          options.Priority,
+			Tags:              options.Tags,
+			ExecutionTimeout:  options.ExecutionTimeout,
+			CompletionWebhook: options.CompletionWebhook,
+			InitialSignals:    len(signalEvents),
+			OrderedSignals:    true,
 		})
 
-	if err := c.backend.CreateWorkflowInstance(ctx, wfi, startedEvent); err != nil {
+	if err := c.backend.CreateWorkflowInstance(ctx, wfi, startedEvent, signalEvents...); err != nil {
 		return nil, fmt.Errorf("creating workflow instance: %w", err)
 	}
 
@@ -137,6 +250,76 @@ func (c *Client) CancelWorkflowInstance(ctx context.Context, instance *workflow.
 	return c.backend.CancelWorkflowInstance(ctx, instance, cancellationEvent)
 }
 
+// ResetWorkflow resets the latest execution of the workflow instance with the given ID to the history event with
+// the given sequence ID. All history after that event is discarded and the workflow is re-executed from that point
+// on, using the currently registered workflow code.
+//
+// Activities and timers scheduled after the reset point are discarded, results of activities that are still
+// executing will be dropped. Sub-workflows started after the reset point are not canceled, the reset fails with
+// backend.ErrSubWorkflowsActive while any of them is active or errored.
+func (c *Client) ResetWorkflow(ctx context.Context, instanceID string, sequenceID int64) error {
+	ctx, span := c.backend.Tracer().Start(ctx, "ResetWorkflow", trace.WithAttributes(
+		attribute.String(log.InstanceIDKey, instanceID),
+		attribute.Int64(log.SeqIDKey, sequenceID),
+	))
+	defer span.End()
+
+	instance, err := c.backend.GetLatestWorkflowInstance(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	if err := c.backend.ResetWorkflowInstance(ctx, instance, sequenceID); err != nil {
+		return fmt.Errorf("resetting workflow instance: %w", err)
+	}
+
+	c.backend.Options().Logger.Debug("Reset workflow instance", log.InstanceIDKey, instanceID, log.SeqIDKey, sequenceID)
+
+	return nil
+}
+
+// RetryWorkflow re-activates the latest execution of an errored workflow instance, for example after fixing a bug
+// that caused its workflow tasks to fail. The number of failed workflow tasks is reset, and a worker executes the
+// instance again with its pending events. Returns ErrWorkflowNotErrored if the instance is not errored.
+//
+// Instances become errored when their workflow tasks fail more often in a row than allowed by
+// worker.Options.MaxWorkflowTaskFailures. Use ResetWorkflow to re-execute workflows that finished with an error.
+func (c *Client) RetryWorkflow(ctx context.Context, instanceID string) error {
+	ctx, span := c.backend.Tracer().Start(ctx, "RetryWorkflow", trace.WithAttributes(
+		attribute.String(log.InstanceIDKey, instanceID),
+	))
+	defer span.End()
+
+	instance, err := c.backend.GetLatestWorkflowInstance(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	if err := c.backend.RetryWorkflowInstance(ctx, instance); err != nil {
+		if errors.Is(err, backend.ErrInstanceNotErrored) {
+			return ErrWorkflowNotErrored
+		}
+
+		return fmt.Errorf("retrying workflow instance: %w", err)
+	}
+
+	c.backend.Options().Logger.Debug("Retried workflow instance", log.InstanceIDKey, instanceID)
+
+	return nil
+}
+
+// InvalidateActivityResult removes the cached result of executing the given activity with the given arguments, if
+// result caching is enabled for the activity using registry.WithResultCache. The next execution with the same
+// arguments executes the activity again.
+func (c *Client) InvalidateActivityResult(ctx context.Context, activity workflow.Activity, args ...any) error {
+	inputs, err := a.ArgsToInputs(c.backend.Options().Converter, args...)
+	if err != nil {
+		return fmt.Errorf("converting arguments: %w", err)
+	}
+
+	return c.backend.InvalidateActivityResult(ctx, iactivity.ResultCacheKey(fn.Name(activity), inputs))
+}
+
 // SignalWorkflow signals a running workflow instance.
 func (c *Client) SignalWorkflow(ctx context.Context, instanceID string, name string, arg any) error {
 	ctx, span := c.backend.Tracer().Start(ctx, "SignalWorkflow", trace.WithAttributes(
@@ -175,6 +358,36 @@ func (c *Client) GetWorkflowInstanceState(ctx context.Context, instance *workflo
 	return c.backend.GetWorkflowInstanceState(ctx, instance)
 }
 
+// GetInstanceState returns the current state of the latest execution of the workflow instance with the given ID.
+//
+// If no instance with the given ID exists, backend.ErrInstanceNotFound is returned.
+func (c *Client) GetInstanceState(ctx context.Context, instanceID string) (core.WorkflowInstanceState, error) {
+	ctx, span := c.backend.Tracer().Start(ctx, "GetInstanceState", trace.WithAttributes(
+		attribute.String(log.InstanceIDKey, instanceID),
+	))
+	defer span.End()
+
+	return c.backend.GetLatestWorkflowInstanceState(ctx, instanceID)
+}
+
+// SubscribeWorkflowEvents returns a channel that receives an update whenever a workflow task of the workflow instance
+// with the given ID has been completed. Executions started via ContinueAsNew are followed. The channel is closed once
+// the latest execution has finished, or when the given context is canceled.
+//
+// If no instance with the given ID exists, backend.ErrInstanceNotFound is returned.
+func (c *Client) SubscribeWorkflowEvents(ctx context.Context, instanceID string) (<-chan *backend.WorkflowInstanceUpdate, error) {
+	return c.backend.SubscribeWorkflowInstanceUpdates(ctx, instanceID)
+}
+
+// TailInstanceLogs returns a channel that receives the log lines written by workflows and activities of the given
+// workflow instance, across all workers and executions. The channel is closed when the context is canceled.
+//
+// Log lines are only available when the backend is configured to publish them, otherwise backend.ErrNotSupported
+// is returned.
+func (c *Client) TailInstanceLogs(ctx context.Context, instanceID string) (<-chan *backend.InstanceLogEntry, error) {
+	return c.backend.TailWorkflowInstanceLogs(ctx, instanceID)
+}
+
 // WaitForWorkflowInstance waits for the given workflow instance to finish or until the given timeout has expired.
 func (c *Client) WaitForWorkflowInstance(ctx context.Context, instance *workflow.Instance, timeout time.Duration) error {
 	if timeout == 0 {
@@ -217,20 +430,71 @@ func (c *Client) WaitForWorkflowInstance(ctx context.Context, instance *workflow
 // GetWorkflowResult gets the workflow result for the given workflow result. It first waits for the workflow to finish or until
 // the given timeout has expired.
 func GetWorkflowResult[T any](ctx context.Context, c *Client, instance *workflow.Instance, timeout time.Duration) (T, error) {
-	b := c.backend
+	ctx, span := c.backend.Tracer().Start(ctx, "GetWorkflowResult", trace.WithAttributes(
+		attribute.String(log.InstanceIDKey, instance.InstanceID),
+	))
+	defer span.End()
 
-	ctx, span := b.Tracer().Start(ctx, "GetWorkflowResult", trace.WithAttributes(
+	result, err := c.getWorkflowResultPayload(ctx, instance, timeout)
+	if err != nil {
+		return *new(T), err
+	}
+
+	var r T
+	if err := c.backend.Options().Converter.From(result, &r); err != nil {
+		return *new(T), fmt.Errorf("converting result: %w", err)
+	}
+
+	return r, nil
+}
+
+// GetWorkflowResults gets the results of a workflow returning multiple values, e.g., (int, string, error), and
+// decodes them into the given pointers, in order. It first waits for the workflow to finish or until the given
+// timeout has expired.
+func GetWorkflowResults(ctx context.Context, c *Client, instance *workflow.Instance, timeout time.Duration, results ...interface{}) error {
+	ctx, span := c.backend.Tracer().Start(ctx, "GetWorkflowResults", trace.WithAttributes(
 		attribute.String(log.InstanceIDKey, instance.InstanceID),
 	))
 	defer span.End()
 
+	result, err := c.getWorkflowResultPayload(ctx, instance, timeout)
+	if err != nil {
+		return err
+	}
+
+	converter := c.backend.Options().Converter
+
+	var tuple []payload.Payload
+	if err := converter.From(result, &tuple); err != nil {
+		return fmt.Errorf("converting results: %w", err)
+	}
+
+	if len(tuple) != len(results) {
+		return fmt.Errorf("mismatched result count: workflow returned %d, got %d", len(tuple), len(results))
+	}
+
+	for i, p := range tuple {
+		if err := converter.From(p, results[i]); err != nil {
+			return fmt.Errorf("converting result %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) getWorkflowResultPayload(ctx context.Context, instance *workflow.Instance, timeout time.Duration) (payload.Payload, error) {
 	if err := c.WaitForWorkflowInstance(ctx, instance, timeout); err != nil {
-		return *new(T), fmt.Errorf("workflow did not finish in time: %w", err)
+		return nil, fmt.Errorf("workflow did not finish in time: %w", err)
 	}
 
-	h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil) // future: could optimize this by retriving only the very last entry in the history
+	return c.workflowResultPayload(ctx, instance)
+}
+
+// workflowResultPayload reads the result of the given finished workflow instance from its history
+func (c *Client) workflowResultPayload(ctx context.Context, instance *workflow.Instance) (payload.Payload, error) {
+	h, err := c.backend.GetWorkflowInstanceHistory(ctx, instance, nil) // future: could optimize this by retriving only the very last entry in the history
 	if err != nil {
-		return *new(T), fmt.Errorf("getting workflow history: %w", err)
+		return nil, fmt.Errorf("getting workflow history: %w", err)
 	}
 
 	// Iterate over history backwards
@@ -240,35 +504,32 @@ func GetWorkflowResult[T any](ctx context.Context, c *Client, instance *workflow
 		case history.EventType_WorkflowExecutionFinished:
 			a := event.Attributes.(*history.ExecutionCompletedAttributes)
 			if a.Error != nil {
-				return *new(T), workflowerrors.ToError(a.Error)
-			}
-
-			var r T
-			if err := b.Options().Converter.From(a.Result, &r); err != nil {
-				return *new(T), fmt.Errorf("converting result: %w", err)
+				// Terminated workflows finish with an error as well
+				if slices.ContainsFunc(h[:i], func(e *history.Event) bool {
+					return e.Type == history.EventType_WorkflowExecutionTerminated
+				}) {
+					return nil, ErrWorkflowTerminated
+				}
+
+				return nil, workflowerrors.ToError(a.Error)
 			}
 
-			return r, nil
+			return a.Result, nil
 
 		case history.EventType_WorkflowExecutionContinuedAsNew:
 			a := event.Attributes.(*history.ExecutionContinuedAsNewAttributes)
 
-			var r T
-			if err := b.Options().Converter.From(a.Result, &r); err != nil {
-				return *new(T), fmt.Errorf("converting result: %w", err)
-			}
-
-			return r, nil
+			return a.Result, nil
 
 		case history.EventType_WorkflowExecutionCanceled:
-			return *new(T), ErrWorkflowCanceled
+			return nil, ErrWorkflowCanceled
 
 		case history.EventType_WorkflowExecutionTerminated:
-			return *new(T), ErrWorkflowTerminated
+			return nil, ErrWorkflowTerminated
 		}
 	}
 
-	return *new(T), errors.New("workflow finished, but could not find result event")
+	return nil, errors.New("workflow finished, but could not find result event")
 }
 
 // RemoveWorkflowInstance removes the given workflow instance from the backend.
@@ -3,6 +3,7 @@ package client
 import (
 	"bytes"
 	"context"
+	"errors"
 	"log/slog"
 	"testing"
 	"time"
@@ -12,7 +13,9 @@ import (
 	"github.com/cschleiden/go-workflows/backend/converter"
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/diag"
 	"github.com/cschleiden/go-workflows/internal/metrics"
+	"github.com/cschleiden/go-workflows/internal/workflowerrors"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -71,6 +74,31 @@ func Test_Client_CreateWorkflowInstance_NameGiven(t *testing.T) {
 	b.AssertExpectations(t)
 }
 
+func Test_Client_CreateWorkflowInstance_StartRateLimited(t *testing.T) {
+	ctx := context.Background()
+
+	b := &backend.MockBackend{}
+	b.On("Options").Return(backend.ApplyOptions(backend.WithConverter(converter.DefaultConverter), backend.WithLogger(slog.Default())))
+	b.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	b.On("Metrics").Return(metrics.NewNoopMetricsClient())
+	b.On("CreateWorkflowInstance", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	c := New(b, WithStartRateLimit(0.001, 1), WithStartRateLimitNoWait())
+
+	result, err := c.CreateWorkflowInstance(ctx, WorkflowInstanceOptions{
+		InstanceID: "id",
+	}, "workflowName")
+	require.NoError(t, err)
+	require.NotZero(t, result)
+
+	result, err = c.CreateWorkflowInstance(ctx, WorkflowInstanceOptions{
+		InstanceID: "id2",
+	}, "workflowName")
+	require.ErrorIs(t, err, ErrStartRateLimited)
+	require.Nil(t, result)
+	b.AssertExpectations(t)
+}
+
 func Test_Client_GetWorkflowResultTimeout(t *testing.T) {
 	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
 
@@ -127,6 +155,136 @@ func Test_Client_GetWorkflowResultSuccess(t *testing.T) {
 	b.AssertExpectations(t)
 }
 
+func Test_Client_waitForLatestExecution_PollsWithoutSubscriptions(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	ctx := context.Background()
+
+	mockClock := clock.NewMock()
+
+	b := &backend.MockBackend{}
+	b.On("SubscribeWorkflowInstanceUpdates", mock.Anything, instance.InstanceID).Return(nil, backend.ErrNotSupported{})
+	b.On("GetLatestWorkflowInstance", mock.Anything, instance.InstanceID).Return(instance, nil)
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceStateActive, nil).Once().Run(func(args mock.Arguments) {
+		mockClock.Add(time.Second)
+	})
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceStateFinished, nil)
+
+	c := &Client{
+		backend: b,
+		clock:   mockClock,
+	}
+
+	finished, err := c.waitForLatestExecution(ctx, instance.InstanceID)
+	require.NoError(t, err)
+	require.Equal(t, instance, finished)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_WaitForState_Timeout(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	ctx := context.Background()
+
+	updates := make(chan *backend.WorkflowInstanceUpdate)
+
+	b := &backend.MockBackend{}
+	b.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	b.On("SubscribeWorkflowInstanceUpdates", mock.Anything, instance.InstanceID).Return((<-chan *backend.WorkflowInstanceUpdate)(updates), nil)
+	b.On("GetLatestWorkflowInstanceState", mock.Anything, instance.InstanceID).Return(core.WorkflowInstanceStateActive, nil)
+
+	c := &Client{
+		backend: b,
+		clock:   clock.New(),
+	}
+
+	// Subscriptions are closed when their context is canceled, close the channel after the timeout has expired
+	time.AfterFunc(time.Millisecond*50, func() {
+		close(updates)
+	})
+
+	err := c.WaitForState(ctx, instance.InstanceID, core.WorkflowInstanceStateFinished, time.Millisecond*10)
+	require.ErrorIs(t, err, ErrWaitTimeout)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_WaitForState_NotReached(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	ctx := context.Background()
+
+	updates := make(chan *backend.WorkflowInstanceUpdate, 1)
+	updates <- &backend.WorkflowInstanceUpdate{
+		Instance: instance,
+		State:    core.WorkflowInstanceStateFinished,
+	}
+	close(updates)
+
+	b := &backend.MockBackend{}
+	b.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	b.On("SubscribeWorkflowInstanceUpdates", mock.Anything, instance.InstanceID).Return((<-chan *backend.WorkflowInstanceUpdate)(updates), nil)
+	b.On("GetLatestWorkflowInstanceState", mock.Anything, instance.InstanceID).Return(core.WorkflowInstanceStateActive, nil)
+
+	c := &Client{
+		backend: b,
+		clock:   clock.New(),
+	}
+
+	err := c.WaitForState(ctx, instance.InstanceID, core.WorkflowInstanceStateContinuedAsNew, time.Second)
+	require.ErrorIs(t, err, ErrStateNotReached)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_WaitForState_AlreadyFinished(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	ctx := context.Background()
+
+	// The instance has already finished, no further updates are going to be sent
+	updates := make(chan *backend.WorkflowInstanceUpdate)
+
+	b := &backend.MockBackend{}
+	b.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	b.On("SubscribeWorkflowInstanceUpdates", mock.Anything, instance.InstanceID).Return((<-chan *backend.WorkflowInstanceUpdate)(updates), nil)
+	b.On("GetLatestWorkflowInstanceState", mock.Anything, instance.InstanceID).Return(core.WorkflowInstanceStateFinished, nil)
+
+	c := &Client{
+		backend: b,
+		clock:   clock.New(),
+	}
+
+	err := c.WaitForState(ctx, instance.InstanceID, core.WorkflowInstanceStateContinuedAsNew, time.Second*10)
+	require.ErrorIs(t, err, ErrStateNotReached)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_WaitForState_PollsWithoutSubscriptions(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+	continued := core.NewWorkflowInstance(instance.InstanceID, "continued")
+
+	ctx := context.Background()
+
+	mockClock := clock.NewMock()
+
+	b := &backend.MockBackend{}
+	b.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	b.On("SubscribeWorkflowInstanceUpdates", mock.Anything, instance.InstanceID).Return(nil, backend.ErrNotSupported{})
+	b.On("GetLatestWorkflowInstance", mock.Anything, instance.InstanceID).Return(instance, nil).Once()
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceStateActive, nil).Once().Run(func(args mock.Arguments) {
+		mockClock.Add(time.Second)
+	})
+	b.On("GetLatestWorkflowInstance", mock.Anything, instance.InstanceID).Return(continued, nil)
+
+	c := &Client{
+		backend: b,
+		clock:   mockClock,
+	}
+
+	err := c.WaitForState(ctx, instance.InstanceID, core.WorkflowInstanceStateContinuedAsNew, time.Second*10)
+	require.NoError(t, err)
+	b.AssertExpectations(t)
+}
+
 func Test_Client_SignalWorkflow(t *testing.T) {
 	instanceID := uuid.NewString()
 
@@ -179,3 +337,203 @@ func Test_Client_SignalWorkflow_WithArgs(t *testing.T) {
 	require.Nil(t, err)
 	b.AssertExpectations(t)
 }
+
+func Test_Client_GetInstanceState(t *testing.T) {
+	ctx := context.Background()
+
+	b := &backend.MockBackend{}
+	b.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	b.On("GetLatestWorkflowInstanceState", mock.Anything, "a").Return(core.WorkflowInstanceStateFinished, nil)
+
+	c := &Client{
+		backend: b,
+		clock:   clock.New(),
+	}
+
+	state, err := c.GetInstanceState(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, core.WorkflowInstanceStateFinished, state)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_GetInstanceState_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	b := &backend.MockBackend{}
+	b.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	b.On("GetLatestWorkflowInstanceState", mock.Anything, "a").Return(core.WorkflowInstanceStateActive, backend.ErrInstanceNotFound)
+
+	c := &Client{
+		backend: b,
+		clock:   clock.New(),
+	}
+
+	_, err := c.GetInstanceState(ctx, "a")
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_DescribeWorkflowInstance(t *testing.T) {
+	instance := core.NewWorkflowInstance("a", "b")
+
+	ctx := context.Background()
+
+	progress, err := converter.DefaultConverter.To(42)
+	require.NoError(t, err)
+
+	b := &backend.MockBackend{}
+	b.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	b.On("Options").Return(backend.ApplyOptions(backend.WithConverter(converter.DefaultConverter)))
+	b.On("GetLatestWorkflowInstance", mock.Anything, "a").Return(instance, nil)
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceStateActive, nil)
+	b.On("GetWorkflowInstanceProgress", mock.Anything, instance).Return(progress, nil)
+	usage := &backend.WorkflowInstanceUsage{WorkflowTasks: 2, Activities: 1}
+	b.On("GetWorkflowInstanceUsage", mock.Anything, instance).Return(usage, nil)
+
+	c := &Client{
+		backend: b,
+		clock:   clock.New(),
+	}
+
+	d, err := c.DescribeWorkflowInstance(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, instance, d.Instance)
+	require.Equal(t, core.WorkflowInstanceStateActive, d.State)
+
+	var p int
+	ok, err := d.DecodeProgress(&p)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 42, p)
+	require.Equal(t, usage, d.Usage)
+	b.AssertExpectations(t)
+}
+
+type diagMockBackend struct {
+	*backend.MockBackend
+
+	ref *diag.WorkflowInstanceRef
+}
+
+func (b *diagMockBackend) GetWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance) (*diag.WorkflowInstanceRef, error) {
+	return b.ref, nil
+}
+
+func (b *diagMockBackend) GetWorkflowInstances(ctx context.Context, afterInstanceID, afterExecutionID string, count int) ([]*diag.WorkflowInstanceRef, error) {
+	return nil, nil
+}
+
+func (b *diagMockBackend) GetWorkflowTree(ctx context.Context, instance *core.WorkflowInstance) (*diag.WorkflowInstanceTree, error) {
+	return nil, nil
+}
+
+func Test_Client_DescribeWorkflowInstance_LastActivityAt(t *testing.T) {
+	instance := core.NewWorkflowInstance("a", "b")
+
+	ctx := context.Background()
+
+	mb := &backend.MockBackend{}
+	mb.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	mb.On("Options").Return(backend.ApplyOptions(backend.WithConverter(converter.DefaultConverter)))
+	mb.On("GetLatestWorkflowInstance", mock.Anything, "a").Return(instance, nil)
+	mb.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceStateActive, nil)
+	mb.On("GetWorkflowInstanceProgress", mock.Anything, instance).Return(nil, nil)
+	mb.On("GetWorkflowInstanceUsage", mock.Anything, instance).Return(&backend.WorkflowInstanceUsage{}, nil)
+
+	lastActivityAt := time.Now().Add(-time.Hour)
+	b := &diagMockBackend{
+		MockBackend: mb,
+		ref:         &diag.WorkflowInstanceRef{Instance: instance, LastActivityAt: &lastActivityAt},
+	}
+
+	c := &Client{
+		backend: b,
+		clock:   clock.New(),
+	}
+
+	d, err := c.DescribeWorkflowInstance(ctx, "a")
+	require.NoError(t, err)
+	require.NotNil(t, d.LastActivityAt)
+	require.True(t, lastActivityAt.Equal(*d.LastActivityAt))
+	mb.AssertExpectations(t)
+}
+
+func Test_Client_RetryWorkflow_NotErrored(t *testing.T) {
+	instance := core.NewWorkflowInstance("a", "b")
+
+	ctx := context.Background()
+
+	b := &backend.MockBackend{}
+	b.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	b.On("GetLatestWorkflowInstance", mock.Anything, "a").Return(instance, nil)
+	b.On("RetryWorkflowInstance", mock.Anything, instance).Return(backend.ErrInstanceNotErrored)
+
+	c := &Client{
+		backend: b,
+		clock:   clock.New(),
+	}
+
+	err := c.RetryWorkflow(ctx, "a")
+	require.ErrorIs(t, err, ErrWorkflowNotErrored)
+	b.AssertExpectations(t)
+}
+
+func Test_activityAttempts_LinksAttemptsWithoutActivityID(t *testing.T) {
+	now := time.Now()
+	scheduled := func(id int64, name string, attempt int) *history.Event {
+		return history.NewHistoryEvent(1, now, history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+			Name:    name,
+			Attempt: attempt,
+		}, history.ScheduleEventID(id))
+	}
+
+	h := []*history.Event{
+		scheduled(1, "a", 0),
+		scheduled(2, "b", 0),
+		history.NewHistoryEvent(1, now, history.EventType_ActivityFailed, &history.ActivityFailedAttributes{
+			Error: workflowerrors.FromError(errors.New("failed")),
+		}, history.ScheduleEventID(1)),
+		history.NewHistoryEvent(1, now, history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{}, history.ScheduleEventID(2)),
+		scheduled(4, "a", 1),
+	}
+
+	attempts := activityAttempts(h, 1)
+	require.Len(t, attempts, 2)
+
+	require.Equal(t, int64(1), attempts[0].ScheduleEventID)
+	require.Equal(t, ActivityAttemptStateFailed, attempts[0].State)
+	require.EqualError(t, attempts[0].Error, "failed")
+
+	require.Equal(t, int64(4), attempts[1].ScheduleEventID)
+	require.Equal(t, 1, attempts[1].Attempt)
+	require.Equal(t, ActivityAttemptStatePending, attempts[1].State)
+	require.True(t, attempts[1].FinishedAt.IsZero())
+}
+
+func Test_Client_CancelWorkflowsByTag(t *testing.T) {
+	ctx := context.Background()
+
+	active := core.NewWorkflowInstance("a", "1")
+	finished := core.NewWorkflowInstance("b", "1")
+	removed := core.NewWorkflowInstance("c", "1")
+
+	b := &backend.MockBackend{}
+	b.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	b.On("GetWorkflowInstancesByTag", mock.Anything, "batch").Return([]*backend.TaggedWorkflowInstance{
+		{Instance: active, State: core.WorkflowInstanceStateActive},
+		{Instance: finished, State: core.WorkflowInstanceStateFinished},
+		{Instance: removed, State: core.WorkflowInstanceStateActive},
+	}, nil)
+	b.On("CancelWorkflowInstance", mock.Anything, active, mock.Anything).Return(nil)
+	b.On("CancelWorkflowInstance", mock.Anything, removed, mock.Anything).Return(backend.ErrInstanceNotFound)
+
+	c := &Client{
+		backend: b,
+		clock:   clock.New(),
+	}
+
+	canceled, err := c.CancelWorkflowsByTag(ctx, "batch")
+	require.NoError(t, err)
+	require.Equal(t, []*workflow.Instance{active}, canceled)
+	b.AssertExpectations(t)
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"compress/gzip"
+
+	"github.com/cschleiden/go-workflows/backend/converter"
+	"github.com/cschleiden/go-workflows/backend/payload"
+)
+
+// compressInputs compresses the given inputs if their total size exceeds the configured threshold. Returns whether
+// the inputs have been compressed.
+func (c *Client) compressInputs(inputs []payload.Payload) ([]payload.Payload, bool, error) {
+	if c.options.InputCompressionThreshold <= 0 {
+		return inputs, false, nil
+	}
+
+	size := 0
+	for _, input := range inputs {
+		size += len(input)
+	}
+
+	if size <= c.options.InputCompressionThreshold {
+		return inputs, false, nil
+	}
+
+	codec, err := converter.NewGzipCodec(gzip.DefaultCompression)
+	if err != nil {
+		return nil, false, err
+	}
+
+	compressed := make([]payload.Payload, len(inputs))
+	for i, input := range inputs {
+		if compressed[i], err = codec.Encode(input); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return compressed, true, nil
+}
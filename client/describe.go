@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/converter"
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/diag"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/workflow"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WorkflowInstanceDescription describes the latest execution of a workflow instance
+type WorkflowInstanceDescription struct {
+	Instance *workflow.Instance
+
+	State core.WorkflowInstanceState
+
+	// Progress is the latest progress reported by the workflow using workflow.SetProgress, or nil if it has not
+	// reported any progress.
+	Progress payload.Payload
+
+	// Usage summarizes the resources used by the execution so far
+	Usage *backend.WorkflowInstanceUsage
+
+	// LastActivityAt is the time the last workflow task for the execution was completed. Not all backends track this,
+	// it's nil then.
+	LastActivityAt *time.Time
+
+	converter converter.Converter
+}
+
+// DecodeProgress converts the latest progress reported by the workflow into v. Returns false if the workflow has not
+// reported any progress.
+func (d *WorkflowInstanceDescription) DecodeProgress(v any) (bool, error) {
+	if d.Progress == nil {
+		return false, nil
+	}
+
+	if err := d.converter.From(d.Progress, v); err != nil {
+		return false, fmt.Errorf("converting progress: %w", err)
+	}
+
+	return true, nil
+}
+
+// DescribeWorkflowInstance returns the state, the latest progress, the usage, and the time of the last completed
+// workflow task of the latest execution of the workflow instance with the given ID.
+//
+// If no instance with the given ID exists, backend.ErrInstanceNotFound is returned.
+func (c *Client) DescribeWorkflowInstance(ctx context.Context, instanceID string) (*WorkflowInstanceDescription, error) {
+	ctx, span := c.backend.Tracer().Start(ctx, "DescribeWorkflowInstance", trace.WithAttributes(
+		attribute.String(log.InstanceIDKey, instanceID),
+	))
+	defer span.End()
+
+	instance, err := c.backend.GetLatestWorkflowInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := c.backend.GetWorkflowInstanceState(ctx, instance)
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow instance state: %w", err)
+	}
+
+	progress, err := c.backend.GetWorkflowInstanceProgress(ctx, instance)
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow instance progress: %w", err)
+	}
+
+	usage, err := c.backend.GetWorkflowInstanceUsage(ctx, instance)
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow instance usage: %w", err)
+	}
+
+	var lastActivityAt *time.Time
+	if db, ok := c.backend.(diag.Backend); ok {
+		ref, err := db.GetWorkflowInstance(ctx, instance)
+		if err != nil {
+			return nil, fmt.Errorf("getting workflow instance: %w", err)
+		}
+
+		lastActivityAt = ref.LastActivityAt
+	}
+
+	return &WorkflowInstanceDescription{
+		Instance:       instance,
+		State:          state,
+		Progress:       progress,
+		Usage:          usage,
+		LastActivityAt: lastActivityAt,
+		converter:      c.backend.Options().Converter,
+	}, nil
+}
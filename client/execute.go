@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/workflow"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ExecuteWorkflow creates a workflow instance and waits for it to finish, e.g., to use workflows for request/response
+// style calls. It returns the decoded result of the workflow, or the error it failed with. If the workflow continues
+// as new, the result of the last execution is returned.
+//
+// Waiting only ends when the workflow has finished or when the given context is canceled, use a context with a
+// deadline to limit the time spent waiting. The instance keeps running if waiting is aborted.
+func ExecuteWorkflow[T any](ctx context.Context, c *Client, options WorkflowInstanceOptions, wf workflow.Workflow, args ...any) (T, error) {
+	ctx, span := c.backend.Tracer().Start(ctx, "ExecuteWorkflow", trace.WithAttributes(
+		attribute.String(log.InstanceIDKey, options.InstanceID),
+	))
+	defer span.End()
+
+	instance, err := c.CreateWorkflowInstance(ctx, options, wf, args...)
+	if err != nil {
+		return *new(T), err
+	}
+
+	instance, err = c.waitForLatestExecution(ctx, instance.InstanceID)
+	if err != nil {
+		return *new(T), err
+	}
+
+	result, err := c.workflowResultPayload(ctx, instance)
+	if err != nil {
+		return *new(T), err
+	}
+
+	var r T
+	if err := c.backend.Options().Converter.From(result, &r); err != nil {
+		return *new(T), fmt.Errorf("converting result: %w", err)
+	}
+
+	return r, nil
+}
+
+// waitForLatestExecution waits until the latest execution of the workflow instance with the given ID has finished
+// and returns it.
+func (c *Client) waitForLatestExecution(ctx context.Context, instanceID string) (*workflow.Instance, error) {
+	// Subscriptions report executions that have already finished, so a workflow finishing right after it has been
+	// created is not missed.
+	updates, err := c.backend.SubscribeWorkflowInstanceUpdates(ctx, instanceID)
+	if err != nil {
+		if errors.As(err, &backend.ErrNotSupported{}) {
+			return c.pollLatestExecution(ctx, instanceID)
+		}
+
+		return nil, fmt.Errorf("subscribing to workflow instance: %w", err)
+	}
+
+	for update := range updates {
+		if update.State == core.WorkflowInstanceStateFinished {
+			return update.Instance, nil
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, errors.New("subscription ended before workflow finished")
+}
+
+func (c *Client) pollLatestExecution(ctx context.Context, instanceID string) (*workflow.Instance, error) {
+	b := backoff.ExponentialBackOff{
+		InitialInterval:     time.Millisecond * 1,
+		MaxInterval:         time.Second * 1,
+		Multiplier:          1.5,
+		RandomizationFactor: 0.5,
+		Stop:                backoff.Stop,
+		Clock:               c.clock,
+	}
+	b.Reset()
+
+	ticker := backoff.NewTicker(&b)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		instance, err := c.backend.GetLatestWorkflowInstance(ctx, instanceID)
+		if err != nil {
+			return nil, fmt.Errorf("getting workflow instance: %w", err)
+		}
+
+		s, err := c.backend.GetWorkflowInstanceState(ctx, instance)
+		if err != nil {
+			return nil, fmt.Errorf("getting workflow state: %w", err)
+		}
+
+		if s == core.WorkflowInstanceStateFinished {
+			return instance, nil
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package client
+
+type Options struct {
+	// StartRateLimit is the maximum number of workflow instances per second the client creates. Zero disables
+	// rate limiting.
+	StartRateLimit float64
+
+	// StartBurst is the number of workflow instances that can be created at once before the rate limit applies.
+	StartBurst int
+
+	// StartRateLimitNoWait makes CreateWorkflowInstance return ErrStartRateLimited instead of waiting when the
+	// rate limit is hit.
+	StartRateLimitNoWait bool
+
+	// InputCompressionThreshold is the total size in bytes of the inputs of a new workflow instance above which they
+	// are compressed. Zero disables compression.
+	InputCompressionThreshold int
+}
+
+var DefaultOptions = Options{}
+
+type ClientOption func(*Options)
+
+// WithStartRateLimit limits the rate at which the client creates workflow instances to rps per second, allowing
+// bursts of up to burst instances. When the limit is hit, CreateWorkflowInstance waits until an instance can be
+// created or the context is canceled.
+func WithStartRateLimit(rps float64, burst int) ClientOption {
+	return func(o *Options) {
+		o.StartRateLimit = rps
+		o.StartBurst = burst
+	}
+}
+
+// WithStartRateLimitNoWait makes CreateWorkflowInstance return ErrStartRateLimited immediately instead of waiting
+// when the start rate limit is hit.
+func WithStartRateLimitNoWait() ClientOption {
+	return func(o *Options) {
+		o.StartRateLimitNoWait = true
+	}
+}
+
+// WithInputCompression compresses the inputs of new workflow instances with gzip if their total size exceeds
+// threshold bytes, e.g., when workflows are started with large configuration blobs. Workers decompress the inputs
+// before passing them to the workflow. Inputs of sub-workflows and executions continued as new are not compressed.
+func WithInputCompression(threshold int) ClientOption {
+	return func(o *Options) {
+		o.InputCompressionThreshold = threshold
+	}
+}
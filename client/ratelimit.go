@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// rateLimiter is a simple token bucket rate limiter
+type rateLimiter struct {
+	mu sync.Mutex
+
+	clock clock.Clock
+
+	rate  float64
+	burst float64
+
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(clock clock.Clock, rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimiter{
+		clock:  clock,
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}
+}
+
+// Allow takes a token if one is available right now
+func (l *rateLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+
+	if l.tokens < 1 {
+		return false
+	}
+
+	l.tokens--
+
+	return true
+}
+
+// Wait blocks until a token is available or the context is canceled
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	l.refill()
+
+	// Reserve a token, the balance might go negative which delays later callers
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	t := l.clock.Timer(delay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+
+	case <-ctx.Done():
+		// Return the reserved token
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+
+		return ctx.Err()
+	}
+}
+
+func (l *rateLimiter) refill() {
+	now := l.clock.Now()
+	elapsed := now.Sub(l.last)
+	l.last = now
+
+	l.tokens += elapsed.Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
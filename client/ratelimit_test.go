@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RateLimiter_Allow(t *testing.T) {
+	c := clock.NewMock()
+	l := newRateLimiter(c, 1, 2)
+
+	require.True(t, l.Allow())
+	require.True(t, l.Allow())
+	require.False(t, l.Allow())
+
+	c.Add(time.Second)
+
+	require.True(t, l.Allow())
+	require.False(t, l.Allow())
+}
+
+func Test_RateLimiter_Wait(t *testing.T) {
+	c := clock.NewMock()
+	l := newRateLimiter(c, 10, 1)
+
+	require.NoError(t, l.Wait(context.Background()))
+
+	done := make(chan error)
+	go func() {
+		done <- l.Wait(context.Background())
+	}()
+
+	select {
+	case <-done:
+		require.FailNow(t, "wait should block until a token is available")
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	c.Add(time.Millisecond * 100)
+
+	require.NoError(t, <-done)
+}
+
+func Test_RateLimiter_WaitCanceled(t *testing.T) {
+	c := clock.NewMock()
+	l := newRateLimiter(c, 1, 1)
+
+	require.True(t, l.Allow())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(t, l.Wait(ctx), context.Canceled)
+
+	// Canceled waits do not use up tokens
+	c.Add(time.Second)
+	require.True(t, l.Allow())
+}
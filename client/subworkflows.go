@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/workflow"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GetChildWorkflows returns the sub-workflow instances started by the latest execution of the given workflow instance,
+// in the order they were started. Call it for each child to walk the whole tree of sub-workflows.
+func (c *Client) GetChildWorkflows(ctx context.Context, instanceID string) ([]*workflow.Instance, error) {
+	ctx, span := c.backend.Tracer().Start(ctx, "GetChildWorkflows", trace.WithAttributes(
+		attribute.String(log.InstanceIDKey, instanceID),
+	))
+	defer span.End()
+
+	instance, err := c.backend.GetLatestWorkflowInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := c.backend.GetChildWorkflowInstances(ctx, instance)
+	if err != nil {
+		return nil, fmt.Errorf("getting child workflow instances: %w", err)
+	}
+
+	return children, nil
+}
+
+// GetParentWorkflow returns the execution that started the given workflow instance as a sub-workflow, or nil if the
+// instance is not a sub-workflow.
+func (c *Client) GetParentWorkflow(ctx context.Context, instanceID string) (*workflow.Instance, error) {
+	ctx, span := c.backend.Tracer().Start(ctx, "GetParentWorkflow", trace.WithAttributes(
+		attribute.String(log.InstanceIDKey, instanceID),
+	))
+	defer span.End()
+
+	instance, err := c.backend.GetLatestWorkflowInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return instance.Parent, nil
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/workflow"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ListWorkflowsByTag returns all executions of workflow instances that have been started with the given tag, in the
+// order they were created. Returns backend.ErrNotSupported if the backend does not support tags.
+func (c *Client) ListWorkflowsByTag(ctx context.Context, tag string) ([]*backend.TaggedWorkflowInstance, error) {
+	ctx, span := c.backend.Tracer().Start(ctx, "ListWorkflowsByTag", trace.WithAttributes(
+		attribute.String("tag", tag),
+	))
+	defer span.End()
+
+	instances, err := c.backend.GetWorkflowInstancesByTag(ctx, tag)
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow instances by tag: %w", err)
+	}
+
+	return instances, nil
+}
+
+// CancelWorkflowsByTag requests cancellation of all active workflow instances that have been started with the given
+// tag, and returns the instances it canceled. Cancellation is attempted for every instance, errors are joined.
+func (c *Client) CancelWorkflowsByTag(ctx context.Context, tag string) ([]*workflow.Instance, error) {
+	ctx, span := c.backend.Tracer().Start(ctx, "CancelWorkflowsByTag", trace.WithAttributes(
+		attribute.String("tag", tag),
+	))
+	defer span.End()
+
+	instances, err := c.backend.GetWorkflowInstancesByTag(ctx, tag)
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow instances by tag: %w", err)
+	}
+
+	canceled := make([]*workflow.Instance, 0)
+	var errs []error
+
+	for _, i := range instances {
+		if i.State != core.WorkflowInstanceStateActive {
+			continue
+		}
+
+		if err := c.CancelWorkflowInstance(ctx, i.Instance); err != nil {
+			// Instance might have been removed in the meantime
+			if errors.Is(err, backend.ErrInstanceNotFound) {
+				continue
+			}
+
+			errs = append(errs, fmt.Errorf("canceling workflow instance %s: %w", i.Instance.InstanceID, err))
+			continue
+		}
+
+		canceled = append(canceled, i.Instance)
+	}
+
+	return canceled, errors.Join(errs...)
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrWaitTimeout is returned when a workflow instance did not reach the state waited for in time.
+var ErrWaitTimeout = errors.New("workflow instance did not reach state in specified timeout")
+
+// ErrStateNotReached is returned when a workflow instance finished without reaching the state waited for.
+var ErrStateNotReached = errors.New("workflow instance finished without reaching state")
+
+// WaitForState waits until the workflow instance with the given ID reaches the target state, or until the given
+// timeout has expired, in which case ErrWaitTimeout is returned. Executions started via ContinueAsNew are followed,
+// so waiting for core.WorkflowInstanceStateFinished only returns once the latest execution has finished, and waiting
+// for core.WorkflowInstanceStateContinuedAsNew returns once an execution continues as new.
+//
+// Waiting uses the backend's workflow instance subscriptions, backends without support for subscriptions are polled.
+// If the instance finishes without reaching the target state, ErrStateNotReached is returned.
+func (c *Client) WaitForState(ctx context.Context, instanceID string, targetState core.WorkflowInstanceState, timeout time.Duration) error {
+	if timeout == 0 {
+		timeout = time.Second * 20
+	}
+
+	ctx, span := c.backend.Tracer().Start(ctx, "WaitForState", trace.WithAttributes(
+		attribute.String(log.InstanceIDKey, instanceID),
+	))
+	defer span.End()
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := c.waitForState(waitCtx, instanceID, targetState)
+	if err != nil && waitCtx.Err() != nil && ctx.Err() == nil {
+		return ErrWaitTimeout
+	}
+
+	return err
+}
+
+func (c *Client) waitForState(ctx context.Context, instanceID string, targetState core.WorkflowInstanceState) error {
+	updates, err := c.backend.SubscribeWorkflowInstanceUpdates(ctx, instanceID)
+	if err != nil {
+		if errors.As(err, &backend.ErrNotSupported{}) {
+			return c.pollState(ctx, instanceID, targetState)
+		}
+
+		return fmt.Errorf("subscribing to workflow instance: %w", err)
+	}
+
+	// Updates are only sent when workflow tasks are completed, the instance might already be in the target state
+	state, err := c.GetInstanceState(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("getting workflow state: %w", err)
+	}
+
+	if state == targetState {
+		return nil
+	}
+
+	if state == core.WorkflowInstanceStateFinished {
+		return ErrStateNotReached
+	}
+
+	for update := range updates {
+		if update.State == targetState {
+			return nil
+		}
+
+		if update.State == core.WorkflowInstanceStateFinished {
+			return ErrStateNotReached
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return errors.New("subscription ended before workflow reached state")
+}
+
+func (c *Client) pollState(ctx context.Context, instanceID string, targetState core.WorkflowInstanceState) error {
+	b := backoff.ExponentialBackOff{
+		InitialInterval:     time.Millisecond * 1,
+		MaxInterval:         time.Second * 1,
+		Multiplier:          1.5,
+		RandomizationFactor: 0.5,
+		Stop:                backoff.Stop,
+		Clock:               c.clock,
+	}
+	b.Reset()
+
+	ticker := backoff.NewTicker(&b)
+	defer ticker.Stop()
+
+	var executionID string
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		instance, err := c.backend.GetLatestWorkflowInstance(ctx, instanceID)
+		if err != nil {
+			return fmt.Errorf("getting workflow instance: %w", err)
+		}
+
+		// Polling only sees the latest execution, a new execution means the previous one has continued as new
+		if executionID != "" && instance.ExecutionID != executionID && targetState == core.WorkflowInstanceStateContinuedAsNew {
+			return nil
+		}
+		executionID = instance.ExecutionID
+
+		state, err := c.backend.GetWorkflowInstanceState(ctx, instance)
+		if err != nil {
+			return fmt.Errorf("getting workflow state: %w", err)
+		}
+
+		if state == targetState {
+			return nil
+		}
+
+		if state == core.WorkflowInstanceStateFinished {
+			return ErrStateNotReached
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/workflows"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrCompletionWebhookNotFound is returned when no completion webhook delivery exists for a workflow instance, either
+// because the instance has not finished yet or because it was started without a webhook.
+var ErrCompletionWebhookNotFound = errors.New("completion webhook not found")
+
+type CompletionWebhookState int
+
+const (
+	CompletionWebhookStatePending CompletionWebhookState = iota
+	CompletionWebhookStateDelivered
+	CompletionWebhookStateFailed
+)
+
+// CompletionWebhookDelivery describes the delivery of the completion webhook of a workflow instance
+type CompletionWebhookDelivery struct {
+	State CompletionWebhookState
+
+	// StatusCode is the HTTP status code returned by the webhook once delivered
+	StatusCode int
+
+	// Error is the error of the last attempt if all delivery attempts failed
+	Error error
+}
+
+// GetCompletionWebhookDelivery returns the delivery status of the completion webhook of the latest execution of the
+// given workflow instance. Deliveries are pending while they are being attempted or retried. Returns
+// ErrCompletionWebhookNotFound if there is no delivery for the instance.
+func (c *Client) GetCompletionWebhookDelivery(ctx context.Context, instanceID string) (*CompletionWebhookDelivery, error) {
+	ctx, span := c.backend.Tracer().Start(ctx, "GetCompletionWebhookDelivery", trace.WithAttributes(
+		attribute.String(log.InstanceIDKey, instanceID),
+	))
+	defer span.End()
+
+	instance, err := c.backend.GetLatestWorkflowInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := c.backend.GetLatestWorkflowInstance(ctx, workflows.CompletionWebhookInstanceID(instance))
+	if err != nil {
+		if errors.Is(err, backend.ErrInstanceNotFound) {
+			return nil, ErrCompletionWebhookNotFound
+		}
+
+		return nil, fmt.Errorf("getting completion webhook delivery: %w", err)
+	}
+
+	state, err := c.backend.GetWorkflowInstanceState(ctx, delivery)
+	if err != nil {
+		return nil, fmt.Errorf("getting completion webhook delivery state: %w", err)
+	}
+
+	if state != core.WorkflowInstanceStateFinished {
+		return &CompletionWebhookDelivery{State: CompletionWebhookStatePending}, nil
+	}
+
+	result, err := c.workflowResultPayload(ctx, delivery)
+	if err != nil {
+		return &CompletionWebhookDelivery{State: CompletionWebhookStateFailed, Error: err}, nil
+	}
+
+	var statusCode int
+	if err := c.backend.Options().Converter.From(result, &statusCode); err != nil {
+		return nil, fmt.Errorf("converting completion webhook result: %w", err)
+	}
+
+	return &CompletionWebhookDelivery{State: CompletionWebhookStateDelivered, StatusCode: statusCode}, nil
+}
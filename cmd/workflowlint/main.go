@@ -0,0 +1,18 @@
+// workflowlint checks workflow code for common errors, like non-deterministic calls, as a standalone vet-style tool.
+//
+//	go install github.com/cschleiden/go-workflows/cmd/workflowlint@latest
+//	workflowlint ./...
+//
+// It can also be run with go vet:
+//
+//	go vet -vettool=$(which workflowlint) ./...
+package main
+
+import (
+	"github.com/cschleiden/go-workflows/analyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.New())
+}
@@ -0,0 +1,15 @@
+package core
+
+// ParentClosePolicy determines what happens to a running sub-workflow instance when its parent workflow finishes
+type ParentClosePolicy int
+
+const (
+	// ParentClosePolicyAbandon keeps the sub-workflow running
+	ParentClosePolicyAbandon ParentClosePolicy = iota
+
+	// ParentClosePolicyRequestCancel cancels the sub-workflow, which can still run cleanup code
+	ParentClosePolicyRequestCancel
+
+	// ParentClosePolicyTerminate finishes the sub-workflow without running any more of its code
+	ParentClosePolicyTerminate
+)
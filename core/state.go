@@ -6,4 +6,8 @@ const (
 	WorkflowInstanceStateActive WorkflowInstanceState = iota
 	WorkflowInstanceStateContinuedAsNew
 	WorkflowInstanceStateFinished
+
+	// WorkflowInstanceStateErrored is the state of an instance whose workflow task failed more often in a row than
+	// allowed by the worker. It is not executed again until it is retried.
+	WorkflowInstanceStateErrored
 )
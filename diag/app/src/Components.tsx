@@ -132,6 +132,7 @@ function eventColor(event: string): [Color, string] {
     case "ActivityScheduled":
     case "ActivityCompleted":
     case "ActivityFailed":
+    case "ActivityCanceled":
       return ["dark", "warning"];
 
     case "TimerScheduled":
@@ -113,6 +113,13 @@ function Instance() {
           {!instance.completed_at ? <i>pending</i> : instance.completed_at}
         </dd>
 
+        {instance.last_activity_at && (
+          <>
+            <dt className="col-sm-4">Last activity at</dt>
+            <dd className="col-sm-8">{instance.last_activity_at}</dd>
+          </>
+        )}
+
         <dt className="col-sm-4">Queue</dt>
         <dd className="col-sm-8">{instance.queue}</dd>
       </dl>
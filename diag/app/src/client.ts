@@ -10,6 +10,8 @@ export interface WorkflowInstanceRef {
   created_at: string;
   completed_at?: string;
 
+  last_activity_at?: string;
+
   state: number;
   queue: string;
 }
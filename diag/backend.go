@@ -16,6 +16,10 @@ type WorkflowInstanceRef struct {
 	CompletedAt *time.Time                 `json:"completed_at,omitempty"`
 	State       core.WorkflowInstanceState `json:"state"`
 	Queue       string                     `json:"queue"`
+
+	// LastActivityAt is the time the last workflow task for the instance was completed. Not all backends track this,
+	// it's nil then.
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
 }
 
 type Event struct {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 
+	"github.com/cschleiden/go-workflows/internal/blobs"
 	"github.com/cschleiden/go-workflows/internal/log"
 	"github.com/cschleiden/go-workflows/workflow"
 )
@@ -13,19 +14,32 @@ type ActivityState struct {
 	Attempt    int
 	Instance   *workflow.Instance
 	Logger     *slog.Logger
+
+	// Name is the name the activity was scheduled with
+	Name string
+
+	// ScheduleEventID is the ID of the event that scheduled the activity in the history of the workflow instance
+	ScheduleEventID int64
+
+	// CorrelationID is the optional ID the activity was scheduled with to correlate it with external systems
+	CorrelationID string
+
+	// Blobs reads the content of blob inputs
+	Blobs *blobs.Store
 }
 
 func NewActivityState(activityID string, attempt int, instance *workflow.Instance, logger *slog.Logger) *ActivityState {
 	return &ActivityState{
-		activityID,
-		attempt,
-		instance,
-		logger.With(
+		ActivityID: activityID,
+		Attempt:    attempt,
+		Instance:   instance,
+		Logger: logger.With(
 			log.ActivityIDKey, activityID,
 			log.InstanceIDKey, instance.InstanceID,
 			log.ExecutionIDKey, instance.ExecutionID,
 			log.AttemptKey, attempt,
-		)}
+		),
+	}
 }
 
 type key int
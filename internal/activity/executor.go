@@ -12,7 +12,9 @@ import (
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/cschleiden/go-workflows/internal/args"
+	"github.com/cschleiden/go-workflows/internal/blobs"
 	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/propagators"
 	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflowerrors"
 	"github.com/cschleiden/go-workflows/registry"
@@ -27,6 +29,7 @@ type Executor struct {
 	converter   converter.Converter
 	propagators []wf.ContextPropagator
 	r           *registry.Registry
+	blobs       *blobs.Store
 }
 
 func NewExecutor(
@@ -35,6 +38,7 @@ func NewExecutor(
 	converter converter.Converter,
 	propagators []wf.ContextPropagator,
 	r *registry.Registry,
+	blobs *blobs.Store,
 ) *Executor {
 	return &Executor{
 		logger:      logger,
@@ -42,6 +46,7 @@ func NewExecutor(
 		converter:   converter,
 		propagators: propagators,
 		r:           r,
+		blobs:       blobs,
 	}
 }
 
@@ -54,8 +59,22 @@ func (e *Executor) ExecuteActivity(ctx context.Context, task *backend.ActivityTa
 		a.Attempt,
 		task.WorkflowInstance,
 		e.logger)
+	as.Name = a.Name
+	as.ScheduleEventID = task.Event.ScheduleEventID
+	as.CorrelationID = a.CorrelationID
+	as.Blobs = e.blobs
+	if a.CorrelationID != "" {
+		as.Logger = as.Logger.With(log.CorrelationIDKey, a.CorrelationID)
+	}
 	activityCtx := WithActivityState(ctx, as)
 
+	// Activities can observe the remaining time of the workflow execution
+	if a.Deadline != nil {
+		var cancel context.CancelFunc
+		activityCtx, cancel = context.WithDeadline(activityCtx, *a.Deadline)
+		defer cancel()
+	}
+
 	for _, propagator := range e.propagators {
 		var err error
 		activityCtx, err = propagator.Extract(activityCtx, a.Metadata)
@@ -71,7 +90,13 @@ func (e *Executor) ExecuteActivity(ctx context.Context, task *backend.ActivityTa
 		attribute.Int(log.AttemptKey, a.Attempt),
 	))
 
-	activity, err := e.r.GetActivity(a.Name)
+	// Activities are executed with the registry of the namespace of the workflow instance scheduling them
+	r, err := e.r.Namespace(propagators.Namespace(a.Metadata))
+	if err != nil {
+		return nil, workflowerrors.NewPermanentError(tracing.WithSpanError(span, fmt.Errorf("activity not found: %w", err)))
+	}
+
+	activity, err := r.GetActivity(a.Name)
 	if err != nil {
 		return nil, workflowerrors.NewPermanentError(tracing.WithSpanError(span, fmt.Errorf("activity not found: %w", err)))
 	}
@@ -93,6 +118,23 @@ func (e *Executor) ExecuteActivity(ctx context.Context, task *backend.ActivityTa
 		args[0] = reflect.ValueOf(activityCtx)
 	}
 
+	// Validate inputs before executing the activity
+	if validator, ok := r.ActivityValidator(a.Name); ok {
+		inputs := args
+		if addContext {
+			inputs = inputs[1:]
+		}
+
+		values := make([]interface{}, 0, len(inputs))
+		for _, input := range inputs {
+			values = append(values, input.Interface())
+		}
+
+		if err := validator(activityCtx, values...); err != nil {
+			return nil, workflowerrors.NewPermanentError(tracing.WithSpanError(span, fmt.Errorf("validating activity inputs: %w", err)))
+		}
+	}
+
 	done := make(chan struct{})
 	var rv []reflect.Value
 
@@ -131,7 +173,22 @@ func (e *Executor) ExecuteActivity(ctx context.Context, task *backend.ActivityTa
 	// Was an error returned?
 	errResult := rv[len(rv)-1]
 	if errResult.IsNil() {
-		// No error from activity execution
+		// No error from activity execution, validate the result before it is recorded
+		if validator, ok := r.ActivityResultValidator(a.Name); ok {
+			var value interface{}
+			if len(rv) > 1 {
+				value = rv[0].Interface()
+			}
+
+			if err := validator(activityCtx, value); err != nil {
+				if !workflowerrors.CanRetry(err) {
+					return nil, tracing.WithSpanError(span, err)
+				}
+
+				return nil, tracing.WithSpanError(span, fmt.Errorf("validating activity result: %w", err))
+			}
+		}
+
 		return result, nil
 	}
 
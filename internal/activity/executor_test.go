@@ -3,6 +3,7 @@ package activity
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"testing"
 	"time"
@@ -104,6 +105,110 @@ func TestExecutor_ExecuteActivity(t *testing.T) {
 				require.Equal(t, e.Type, "PanicError")
 			},
 		},
+		{
+			name: "validation failure",
+			setup: func(t *testing.T, r *registry.Registry) *history.ActivityScheduledAttributes {
+				a := func(context.Context, int) error {
+					require.FailNow(t, "activity should not be executed")
+					return nil
+				}
+				require.NoError(t, r.RegisterActivity(a, registry.WithValidator(func(ctx context.Context, args ...interface{}) error {
+					require.Equal(t, []interface{}{42}, args)
+
+					return errors.New("invalid input")
+				})))
+
+				inputs, _ := args.ArgsToInputs(converter.DefaultConverter, 42)
+
+				return &history.ActivityScheduledAttributes{
+					Name:   fn.Name(a),
+					Inputs: inputs,
+				}
+			},
+			result: func(t *testing.T, result payload.Payload, err error) {
+				require.Nil(t, result)
+				require.EqualError(t, err, "validating activity inputs: invalid input")
+
+				var expectedErr *workflowerrors.Error
+				require.ErrorAs(t, err, &expectedErr)
+				require.True(t, expectedErr.Permanent)
+			},
+		},
+		{
+			name: "validation success",
+			setup: func(t *testing.T, r *registry.Registry) *history.ActivityScheduledAttributes {
+				a := func(_ context.Context, i int) (int, error) {
+					return i * 2, nil
+				}
+				require.NoError(t, r.RegisterActivity(a, registry.WithValidator(func(ctx context.Context, args ...interface{}) error {
+					return nil
+				})))
+
+				inputs, _ := args.ArgsToInputs(converter.DefaultConverter, 21)
+
+				return &history.ActivityScheduledAttributes{
+					Name:   fn.Name(a),
+					Inputs: inputs,
+				}
+			},
+			result: func(t *testing.T, result payload.Payload, err error) {
+				require.NoError(t, err)
+
+				var r int
+				require.NoError(t, converter.DefaultConverter.From(result, &r))
+				require.Equal(t, 42, r)
+			},
+		},
+		{
+			name: "deadline of the workflow execution",
+			setup: func(t *testing.T, r *registry.Registry) *history.ActivityScheduledAttributes {
+				a := func(ctx context.Context) (time.Time, error) {
+					deadline, ok := ctx.Deadline()
+					if !ok {
+						return time.Time{}, errors.New("no deadline")
+					}
+
+					return deadline, nil
+				}
+				require.NoError(t, r.RegisterActivity(a))
+
+				deadline := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+				return &history.ActivityScheduledAttributes{
+					Name:     fn.Name(a),
+					Deadline: &deadline,
+				}
+			},
+			result: func(t *testing.T, result payload.Payload, err error) {
+				require.NoError(t, err)
+
+				var deadline time.Time
+				require.NoError(t, converter.DefaultConverter.From(result, &deadline))
+				require.True(t, deadline.Equal(time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)))
+			},
+		},
+		{
+			name: "activity state",
+			setup: func(t *testing.T, r *registry.Registry) *history.ActivityScheduledAttributes {
+				a := func(ctx context.Context) (string, error) {
+					as := GetActivityState(ctx)
+					return fmt.Sprintf("%s/%s/%d/%d", as.Instance.InstanceID, as.Name, as.ScheduleEventID, as.Attempt), nil
+				}
+				require.NoError(t, r.RegisterActivity(a, registry.WithName("activity")))
+
+				return &history.ActivityScheduledAttributes{
+					Name:    "activity",
+					Attempt: 2,
+				}
+			},
+			result: func(t *testing.T, result payload.Payload, err error) {
+				require.NoError(t, err)
+
+				var r string
+				require.NoError(t, converter.DefaultConverter.From(result, &r))
+				require.Equal(t, "instanceID/activity/1/2", r)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -119,7 +224,7 @@ func TestExecutor_ExecuteActivity(t *testing.T) {
 			got, err := e.ExecuteActivity(context.Background(), &backend.ActivityTask{
 				ID:               uuid.NewString(),
 				WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
-				Event:            history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, attr),
+				Event:            history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, attr, history.ScheduleEventID(1)),
 			})
 			tt.result(t, got, err)
 		})
@@ -0,0 +1,37 @@
+package activity
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/cschleiden/go-workflows/core"
+)
+
+// StartedMarkerTTL is how long markers of started at-most-once activities are kept. Markers are removed once the
+// activity task has been completed, the TTL only removes markers of tasks that are never completed.
+const StartedMarkerTTL = 7 * 24 * time.Hour
+
+// ResultCacheKey returns the key results of the activity with the given name and serialized inputs are cached
+// under. Inputs that serialize identically share a key.
+func ResultCacheKey(name string, inputs []payload.Payload) string {
+	h := sha256.New()
+
+	var l [8]byte
+	for _, input := range inputs {
+		// Length-prefix inputs, so that different splits of the same bytes do not collide
+		binary.BigEndian.PutUint64(l[:], uint64(len(input)))
+		h.Write(l[:])
+		h.Write(input)
+	}
+
+	return name + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// StartedMarkerKey returns the key the marker for a started execution of an at-most-once activity is stored under.
+// Markers are stored alongside cached activity results.
+func StartedMarkerKey(instance *core.WorkflowInstance, activityID string) string {
+	return "started:" + instance.InstanceID + ":" + instance.ExecutionID + ":" + activityID
+}
@@ -47,7 +47,7 @@ func InputsToArgs(c converter.Converter, fn reflect.Value, inputs []payload.Payl
 			arg := reflect.New(argT).Interface()
 			err := c.From(inputs[input], arg)
 			if err != nil {
-				return nil, false, fmt.Errorf("converting inputs: %w", err)
+				return nil, false, fmt.Errorf("converting inputs: argument %d to %s: %w", input, argT, err)
 			}
 
 			args[i] = reflect.ValueOf(arg).Elem()
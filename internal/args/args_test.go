@@ -56,6 +56,15 @@ func TestInputsToArgs(t *testing.T) {
 			wantErr: true,
 			err:     "mismatched argument count: expected 2, got 1",
 		},
+		{
+			name: "mismatched argument type",
+			args: args{
+				fn:     func(int, string) error { return nil },
+				inputs: []interface{}{42, 23},
+			},
+			wantErr: true,
+			err:     "converting inputs: argument 1 to string: json: cannot unmarshal number into Go value of type string",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,84 @@
+package blobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/converter"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+)
+
+// Store writes and reads the content of blob inputs, applying the codecs of the converter if it has any
+type Store struct {
+	store     backend.BlobStore
+	converter converter.Converter
+}
+
+func New(store backend.BlobStore, c converter.Converter) *Store {
+	return &Store{
+		store:     store,
+		converter: c,
+	}
+}
+
+var errNoBlobStore = backend.ErrNotSupported{Message: "blob inputs require a blob store, see backend.WithBlobStore"}
+
+// Create stores the content of the given reader as a new blob and returns the handle for it
+func (s *Store) Create(ctx context.Context, r io.Reader) (workflow.BlobInput, error) {
+	if s.store == nil {
+		return workflow.BlobInput{}, errNoBlobStore
+	}
+
+	if cc, ok := s.converter.(*converter.CodecConverter); ok {
+		encoded := cc.EncodeStream(r)
+		defer encoded.Close()
+
+		r = encoded
+	}
+
+	input := workflow.BlobInput{Key: uuid.NewString()}
+	if err := s.store.Put(ctx, input.Key, r); err != nil {
+		return workflow.BlobInput{}, fmt.Errorf("storing blob: %w", err)
+	}
+
+	return input, nil
+}
+
+// Open returns a reader for the content of the given blob
+func (s *Store) Open(ctx context.Context, input workflow.BlobInput) (io.ReadCloser, error) {
+	if s.store == nil {
+		return nil, errNoBlobStore
+	}
+
+	rc, err := s.store.Get(ctx, input.Key)
+	if err != nil {
+		return nil, fmt.Errorf("reading blob: %w", err)
+	}
+
+	if cc, ok := s.converter.(*converter.CodecConverter); ok {
+		return &decodedBlob{Reader: cc.DecodeStream(rc), Closer: rc}, nil
+	}
+
+	return rc, nil
+}
+
+// Delete removes the given blob
+func (s *Store) Delete(ctx context.Context, input workflow.BlobInput) error {
+	if s.store == nil {
+		return errNoBlobStore
+	}
+
+	if err := s.store.Delete(ctx, input.Key); err != nil {
+		return fmt.Errorf("removing blob: %w", err)
+	}
+
+	return nil
+}
+
+type decodedBlob struct {
+	io.Reader
+	io.Closer
+}
@@ -3,6 +3,7 @@ package command
 import (
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/backend/metadata"
 	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/cschleiden/go-workflows/core"
 	"github.com/cschleiden/go-workflows/internal/workflowerrors"
@@ -14,6 +15,19 @@ type CompleteWorkflowCommand struct {
 	Instance *core.WorkflowInstance
 	Result   payload.Payload
 	Error    *workflowerrors.Error
+
+	// CompletionWebhook, if set, is started when the workflow completes to deliver its outcome
+	CompletionWebhook *CompletionWebhook
+
+	// TerminateSubWorkflows are running sub-workflow instances terminated when the workflow completes
+	TerminateSubWorkflows []*core.WorkflowInstance
+}
+
+// CompletionWebhook describes the workflow instance delivering the completion webhook of a workflow
+type CompletionWebhook struct {
+	Instance *core.WorkflowInstance
+	Name     string
+	Inputs   []payload.Payload
 }
 
 var _ Command = (*CompleteWorkflowCommand)(nil)
@@ -96,6 +110,31 @@ func (c *CompleteWorkflowCommand) Execute(clock clock.Clock) *CommandResult {
 			}
 		}
 
+		for _, subWorkflowInstance := range c.TerminateSubWorkflows {
+			r.WorkflowEvents = append(r.WorkflowEvents, &history.WorkflowEvent{
+				WorkflowInstance: subWorkflowInstance,
+				HistoryEvent:     history.NewWorkflowTerminationEvent(clock.Now()),
+			})
+		}
+
+		if c.CompletionWebhook != nil {
+			r.WorkflowEvents = append(r.WorkflowEvents, &history.WorkflowEvent{
+				WorkflowInstance: c.CompletionWebhook.Instance,
+				HistoryEvent: history.NewPendingEvent(
+					clock.Now(),
+					history.EventType_WorkflowExecutionStarted,
+					&history.ExecutionStartedAttributes{
+						Queue:    core.QueueSystem,
+						Name:     c.CompletionWebhook.Name,
+						Metadata: &metadata.WorkflowMetadata{},
+						Inputs:   c.CompletionWebhook.Inputs,
+
+						OrderedSignals: true,
+					},
+				),
+			})
+		}
+
 		return r
 	}
 
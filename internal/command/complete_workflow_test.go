@@ -45,3 +45,27 @@ func TestCompleteWorkflowCommand_StateTransitions(t *testing.T) {
 		})
 	}
 }
+
+func TestCompleteWorkflowCommand_CompletionWebhook(t *testing.T) {
+	webhookInstance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	cmd := NewCompleteWorkflowCommand(1, core.NewWorkflowInstance(uuid.NewString(), ""), payload.Payload{}, nil)
+	cmd.CompletionWebhook = &CompletionWebhook{
+		Instance: webhookInstance,
+		Name:     "DeliverCompletionWebhook",
+		Inputs:   []payload.Payload{[]byte("{}")},
+	}
+
+	r := cmd.Execute(clock.NewMock())
+	require.Equal(t, core.WorkflowInstanceStateFinished, r.State)
+	require.Len(t, r.WorkflowEvents, 1)
+
+	we := r.WorkflowEvents[0]
+	require.Equal(t, webhookInstance, we.WorkflowInstance)
+	require.Equal(t, history.EventType_WorkflowExecutionStarted, we.HistoryEvent.Type)
+
+	a := we.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+	require.Equal(t, core.QueueSystem, a.Queue)
+	require.Equal(t, "DeliverCompletionWebhook", a.Name)
+	require.Equal(t, []payload.Payload{[]byte("{}")}, a.Inputs)
+}
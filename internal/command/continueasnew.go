@@ -1,6 +1,8 @@
 package command
 
 import (
+	"time"
+
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/backend/metadata"
@@ -18,12 +20,18 @@ type ContinueAsNewCommand struct {
 	Inputs   []payload.Payload
 	Result   payload.Payload
 
+	// ExecutionTimeout is passed on to the new execution
+	ExecutionTimeout time.Duration
+
+	// CompletionWebhook is passed on to the new execution
+	CompletionWebhook string
+
 	ContinuedExecutionID string
 }
 
 var _ Command = (*ContinueAsNewCommand)(nil)
 
-func NewContinueAsNewCommand(id int64, instance *core.WorkflowInstance, result payload.Payload, name string, metadata *metadata.WorkflowMetadata, inputs []payload.Payload) *ContinueAsNewCommand {
+func NewContinueAsNewCommand(id int64, instance *core.WorkflowInstance, result payload.Payload, name string, metadata *metadata.WorkflowMetadata, inputs []payload.Payload, executionTimeout time.Duration) *ContinueAsNewCommand {
 	return &ContinueAsNewCommand{
 		command: command{
 			id:    id,
@@ -35,6 +43,7 @@ func NewContinueAsNewCommand(id int64, instance *core.WorkflowInstance, result p
 		Metadata:             metadata,
 		Inputs:               inputs,
 		Result:               result,
+		ExecutionTimeout:     executionTimeout,
 		ContinuedExecutionID: uuid.NewString(),
 	}
 }
@@ -74,9 +83,12 @@ func (c *ContinueAsNewCommand) Execute(clock clock.Clock) *CommandResult {
 						clock.Now(),
 						history.EventType_WorkflowExecutionStarted,
 						&history.ExecutionStartedAttributes{
-							Name:     c.Name,
-							Metadata: c.Metadata,
-							Inputs:   c.Inputs,
+							Name:              c.Name,
+							Metadata:          c.Metadata,
+							Inputs:            c.Inputs,
+							ExecutionTimeout:  c.ExecutionTimeout,
+							CompletionWebhook: c.CompletionWebhook,
+							OrderedSignals:    true,
 						},
 					),
 				},
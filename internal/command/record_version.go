@@ -0,0 +1,53 @@
+package command
+
+import (
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/backend/history"
+)
+
+// RecordVersionCommand records the version of a change to the workflow code. Versions are read from the history
+// before replaying it, so the command does not consume a schedule event ID, and is not matched with its event.
+type RecordVersionCommand struct {
+	command
+
+	changeID string
+	version  int
+}
+
+var _ Command = (*RecordVersionCommand)(nil)
+
+// Command transitions are
+// Pending -> Done : Version has been recorded
+
+func NewRecordVersionCommand(changeID string, version int) *RecordVersionCommand {
+	return &RecordVersionCommand{
+		command: command{
+			name:  "RecordVersion",
+			state: CommandState_Pending,
+		},
+		changeID: changeID,
+		version:  version,
+	}
+}
+
+func (c *RecordVersionCommand) Execute(clock clock.Clock) *CommandResult {
+	switch c.state {
+	case CommandState_Pending:
+		c.state = CommandState_Done
+
+		return &CommandResult{
+			Events: []*history.Event{
+				history.NewPendingEvent(
+					clock.Now(),
+					history.EventType_VersionRecorded,
+					&history.VersionRecordedAttributes{
+						ChangeID: c.changeID,
+						Version:  c.version,
+					},
+				),
+			},
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordVersionCommand_StateTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, c *RecordVersionCommand, clock clock.Clock)
+	}{
+		{"Execute records version", func(t *testing.T, c *RecordVersionCommand, clock clock.Clock) {
+			r := assertExecuteWithEvent(t, c, CommandState_Done, history.EventType_VersionRecorded)
+
+			a := r.Events[0].Attributes.(*history.VersionRecordedAttributes)
+			require.Equal(t, "change", a.ChangeID)
+			require.Equal(t, 2, a.Version)
+		}},
+		{"Execute only once", func(t *testing.T, c *RecordVersionCommand, clock clock.Clock) {
+			c.Execute(clock)
+
+			assertExecuteNoEvent(t, c, CommandState_Done)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := clock.NewMock()
+			cmd := NewRecordVersionCommand("change", 2)
+
+			tt.f(t, cmd, clock)
+		})
+	}
+}
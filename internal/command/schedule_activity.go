@@ -1,6 +1,8 @@
 package command
 
 import (
+	"time"
+
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/backend/metadata"
@@ -9,29 +11,49 @@ import (
 )
 
 type ScheduleActivityCommand struct {
-	command
+	cancelableCommand
+
+	Name    string
+	Inputs  []payload.Payload
+	Attempt int
+
+	// ActivityID is the schedule event ID of the first attempt of the activity
+	ActivityID int64
 
-	Name     string
-	Inputs   []payload.Payload
-	Attempt  int
 	Metadata *metadata.WorkflowMetadata
 	Queue    core.Queue
+
+	// CorrelationID is the optional ID given to correlate the activity with external systems
+	CorrelationID string
+
+	// Deadline is the deadline of the workflow execution, zero if it has none
+	Deadline time.Time
+
+	// RetryOptions are the effective retry options of the activity. When replaying, they are replaced by the options
+	// recorded in the history, nil if the history does not contain them.
+	RetryOptions *history.RetryOptions
 }
 
-var _ Command = (*ScheduleActivityCommand)(nil)
+var _ CancelableCommand = (*ScheduleActivityCommand)(nil)
 
-func NewScheduleActivityCommand(id int64, name string, inputs []payload.Payload, attempt int, metadata *metadata.WorkflowMetadata, queue core.Queue) *ScheduleActivityCommand {
+func NewScheduleActivityCommand(id int64, name string, inputs []payload.Payload, attempt int, activityID int64, metadata *metadata.WorkflowMetadata, queue core.Queue, correlationID string, deadline time.Time, retryOptions *history.RetryOptions) *ScheduleActivityCommand {
 	return &ScheduleActivityCommand{
-		command: command{
-			id:    id,
-			name:  "ScheduleActivity",
-			state: CommandState_Pending,
+		cancelableCommand: cancelableCommand{
+			command: command{
+				id:    id,
+				name:  "ScheduleActivity",
+				state: CommandState_Pending,
+			},
 		},
-		Name:     name,
-		Attempt:  attempt,
-		Inputs:   inputs,
-		Metadata: metadata,
-		Queue:    queue,
+		Name:          name,
+		Attempt:       attempt,
+		ActivityID:    activityID,
+		Inputs:        inputs,
+		Metadata:      metadata,
+		Queue:         queue,
+		CorrelationID: correlationID,
+		Deadline:      deadline,
+		RetryOptions:  retryOptions,
 	}
 }
 
@@ -40,22 +62,46 @@ func (c *ScheduleActivityCommand) Execute(clock clock.Clock) *CommandResult {
 	case CommandState_Pending:
 		c.state = CommandState_Committed
 
+		a := &history.ActivityScheduledAttributes{
+			Name:          c.Name,
+			Inputs:        c.Inputs,
+			Attempt:       c.Attempt,
+			ActivityID:    c.ActivityID,
+			Metadata:      c.Metadata,
+			Queue:         c.Queue,
+			CorrelationID: c.CorrelationID,
+			RetryOptions:  c.RetryOptions,
+		}
+
+		if !c.Deadline.IsZero() {
+			deadline := c.Deadline
+			a.Deadline = &deadline
+		}
+
 		event := history.NewPendingEvent(
 			clock.Now(),
 			history.EventType_ActivityScheduled,
-			&history.ActivityScheduledAttributes{
-				Name:     c.Name,
-				Inputs:   c.Inputs,
-				Attempt:  c.Attempt,
-				Metadata: c.Metadata,
-				Queue:    c.Queue,
-			},
+			a,
 			history.ScheduleEventID(c.id))
 
 		return &CommandResult{
 			Events:         []*history.Event{event},
 			ActivityEvents: []*history.Event{event},
 		}
+
+	case CommandState_CancelPending:
+		c.state = CommandState_Canceled
+
+		return &CommandResult{
+			Events: []*history.Event{
+				history.NewPendingEvent(
+					clock.Now(),
+					history.EventType_ActivityCanceled,
+					&history.ActivityCanceledAttributes{},
+					history.ScheduleEventID(c.id),
+				),
+			},
+		}
 	}
 
 	return nil
@@ -1,6 +1,8 @@
 package command
 
 import (
+	"time"
+
 	"testing"
 
 	"github.com/benbjohnson/clock"
@@ -31,6 +33,37 @@ func TestScheduleActivityCommand_StateTransitions(t *testing.T) {
 		{"Done_after_commit", func(t *testing.T, c *ScheduleActivityCommand, clock clock.Clock) {
 			c.Commit()
 
+			c.Done()
+			require.Equal(t, CommandState_Done, c.State())
+		}},
+		{"Cancel before schedule yields no event", func(t *testing.T, c *ScheduleActivityCommand, clock clock.Clock) {
+			c.Cancel()
+			require.Equal(t, CommandState_Canceled, c.State())
+
+			assertExecuteNoEvent(t, c, CommandState_Canceled)
+		}},
+		{"Cancel after schedule yields cancel event", func(t *testing.T, c *ScheduleActivityCommand, clock clock.Clock) {
+			assertExecuteWithEvent(t, c, CommandState_Committed, history.EventType_ActivityScheduled)
+
+			c.Cancel()
+			require.Equal(t, CommandState_CancelPending, c.State())
+
+			assertExecuteWithEvent(t, c, CommandState_Canceled, history.EventType_ActivityCanceled)
+		}},
+		{"HandleCancel", func(t *testing.T, c *ScheduleActivityCommand, clock clock.Clock) {
+			c.Commit()
+
+			c.Cancel()
+			c.HandleCancel()
+			require.Equal(t, CommandState_Canceled, c.State())
+
+			assertExecuteNoEvent(t, c, CommandState_Canceled)
+		}},
+		{"Done_after_cancel", func(t *testing.T, c *ScheduleActivityCommand, clock clock.Clock) {
+			c.Commit()
+			c.Cancel()
+			c.HandleCancel()
+
 			c.Done()
 			require.Equal(t, CommandState_Done, c.State())
 		}},
@@ -38,7 +71,7 @@ func TestScheduleActivityCommand_StateTransitions(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			clock := clock.NewMock()
-			cmd := NewScheduleActivityCommand(1, "activity", []payload.Payload{}, 0, &metadata.WorkflowMetadata{}, core.QueueDefault)
+			cmd := NewScheduleActivityCommand(1, "activity", []payload.Payload{}, 0, 1, &metadata.WorkflowMetadata{}, core.QueueDefault, "", time.Time{}, nil)
 
 			tt.f(t, cmd, clock)
 		})
@@ -19,6 +19,9 @@ type ScheduleSubWorkflowCommand struct {
 
 	Name   string
 	Inputs []payload.Payload
+
+	// ParentClosePolicy is applied to the sub-workflow if it is still running when the workflow finishes
+	ParentClosePolicy core.ParentClosePolicy
 }
 
 var _ CancelableCommand = (*ScheduleSubWorkflowCommand)(nil)
@@ -82,6 +85,7 @@ func (c *ScheduleSubWorkflowCommand) Execute(clock clock.Clock) *CommandResult {
 							Inputs:         c.Inputs,
 							Metadata:       c.Metadata,
 							WorkflowSpanID: c.WorkflowSpanID,
+							OrderedSignals: true,
 						},
 					),
 				},
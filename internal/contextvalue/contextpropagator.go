@@ -3,3 +3,7 @@ package contextvalue
 type PropagatorsKey int
 
 var PropagatorsCtxKey PropagatorsKey
+
+type RegistryKey int
+
+var RegistryCtxKey RegistryKey
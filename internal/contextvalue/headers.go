@@ -0,0 +1,31 @@
+package contextvalue
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/sync"
+)
+
+type headersKey struct{}
+
+// WithHeaders returns a copy of the given context carrying the given headers
+func WithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	return context.WithValue(ctx, headersKey{}, headers)
+}
+
+// Headers returns the headers carried by the given context, if any
+func Headers(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(headersKey{}).(map[string]string)
+	return headers
+}
+
+// WithWorkflowHeaders returns a copy of the given workflow context carrying the given headers
+func WithWorkflowHeaders(ctx sync.Context, headers map[string]string) sync.Context {
+	return sync.WithValue(ctx, headersKey{}, headers)
+}
+
+// WorkflowHeaders returns the headers carried by the given workflow context, if any
+func WorkflowHeaders(ctx sync.Context) map[string]string {
+	headers, _ := ctx.Value(headersKey{}).(map[string]string)
+	return headers
+}
@@ -0,0 +1,18 @@
+package contextvalue
+
+import (
+	"github.com/cschleiden/go-workflows/internal/sync"
+)
+
+type namespaceKey struct{}
+
+// WithWorkflowNamespace returns a copy of the given workflow context carrying the namespace of the workflow instance
+func WithWorkflowNamespace(ctx sync.Context, namespace string) sync.Context {
+	return sync.WithValue(ctx, namespaceKey{}, namespace)
+}
+
+// WorkflowNamespace returns the namespace carried by the given workflow context, empty if there is none
+func WorkflowNamespace(ctx sync.Context) string {
+	namespace, _ := ctx.Value(namespaceKey{}).(string)
+	return namespace
+}
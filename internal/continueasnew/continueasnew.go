@@ -8,6 +8,14 @@ import (
 type Error struct {
 	Metadata *metadata.WorkflowMetadata
 	Inputs   []payload.Payload
+
+	// Args are the arguments for the new execution if they have not been converted to Inputs yet, because the
+	// error was created without access to the workflow context
+	Args []any
+
+	// InheritMetadata indicates that the metadata of the current execution should be carried over. Metadata
+	// takes precedence over inherited values.
+	InheritMetadata bool
 }
 
 var _ error = (*Error)(nil)
@@ -16,9 +24,19 @@ func (e *Error) Error() string {
 	return "ContinueAsNew"
 }
 
-func NewError(metadata *metadata.WorkflowMetadata, inputs []payload.Payload) error {
+// NewArgsError returns an error continuing the workflow with the given arguments, which are converted by the
+// executor. The metadata of the current execution is carried over.
+func NewArgsError(args []any) error {
+	return &Error{
+		Args:            args,
+		InheritMetadata: true,
+	}
+}
+
+func NewError(metadata *metadata.WorkflowMetadata, inputs []payload.Payload, inheritMetadata bool) error {
 	return &Error{
-		Metadata: metadata,
-		Inputs:   inputs,
+		Metadata:        metadata,
+		Inputs:          inputs,
+		InheritMetadata: inheritMetadata,
 	}
 }
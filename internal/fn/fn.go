@@ -7,6 +7,11 @@ import (
 )
 
 func Name(i interface{}) string {
+	// Already a name
+	if name, ok := i.(string); ok {
+		return name
+	}
+
 	// Adapted from https://stackoverflow.com/a/7053871
 	fnName := runtime.FuncForPC(reflect.ValueOf(i).Pointer()).Name()
 
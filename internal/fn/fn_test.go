@@ -48,6 +48,11 @@ func Test_FuncName(t *testing.T) {
 			i:    func() {},
 			want: "func1",
 		},
+		{
+			name: "name",
+			i:    "DoSomething",
+			want: "DoSomething",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
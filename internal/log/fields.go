@@ -10,6 +10,8 @@ const (
 	InstanceIDKey   = NamespaceKey + ".instance.id"
 	ExecutionIDKey  = NamespaceKey + ".execution.id"
 
+	CorrelationIDKey = NamespaceKey + ".activity.correlation_id"
+
 	ContinuedExecutionIDKey = NamespaceKey + ".continued_execution.id"
 
 	WorkflowNameKey = NamespaceKey + ".workflow.name"
@@ -31,6 +33,8 @@ const (
 	ExecutedEventsKey        = NamespaceKey + ".task.executed_events"
 	NewEventsKey             = NamespaceKey + ".task.new_events"
 
+	PendingEventsKey = NamespaceKey + ".instance.pending_events"
+
 	AttemptKey  = NamespaceKey + ".attempt"
 	DurationKey = NamespaceKey + ".duration_ms"
 
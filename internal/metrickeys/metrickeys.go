@@ -10,6 +10,8 @@ const (
 	WorkflowTaskProcessed = Prefix + "workflow.task.processed"
 	WorkflowTaskDelay     = Prefix + "workflow.task.time_in_queue"
 
+	WorkflowPendingEventsExceeded = Prefix + "workflow.pending_events.exceeded"
+
 	WorkflowInstanceCacheSize     = Prefix + "workflow.cache.size"
 	WorkflowInstanceCacheEviction = Prefix + "workflow.cache.eviction"
 
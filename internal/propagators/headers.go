@@ -0,0 +1,67 @@
+package propagators
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/internal/contextvalue"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// headerPrefix is the prefix for metadata keys holding custom headers
+const headerPrefix = "header-"
+
+// InjectHeaders stores the given headers in the metadata
+func InjectHeaders(metadata *workflow.Metadata, headers map[string]string) {
+	for key, value := range headers {
+		metadata.Set(headerPrefix+key, value)
+	}
+}
+
+func extractHeaders(metadata *workflow.Metadata) map[string]string {
+	var headers map[string]string
+	for _, key := range metadata.Keys() {
+		if name, ok := strings.CutPrefix(key, headerPrefix); ok {
+			if headers == nil {
+				headers = make(map[string]string)
+			}
+
+			headers[name] = metadata.Get(key)
+		}
+	}
+
+	return headers
+}
+
+// HeadersContextPropagator passes custom headers given when creating a workflow instance on to the workflow, and
+// from there to all activities and sub-workflows it schedules.
+type HeadersContextPropagator struct {
+}
+
+var _ workflow.ContextPropagator = &HeadersContextPropagator{}
+
+func (*HeadersContextPropagator) Inject(ctx context.Context, metadata *workflow.Metadata) error {
+	InjectHeaders(metadata, contextvalue.Headers(ctx))
+	return nil
+}
+
+func (*HeadersContextPropagator) Extract(ctx context.Context, metadata *workflow.Metadata) (context.Context, error) {
+	if headers := extractHeaders(metadata); headers != nil {
+		ctx = contextvalue.WithHeaders(ctx, headers)
+	}
+
+	return ctx, nil
+}
+
+func (*HeadersContextPropagator) InjectFromWorkflow(ctx workflow.Context, metadata *workflow.Metadata) error {
+	InjectHeaders(metadata, contextvalue.WorkflowHeaders(ctx))
+	return nil
+}
+
+func (*HeadersContextPropagator) ExtractToWorkflow(ctx workflow.Context, metadata *workflow.Metadata) (workflow.Context, error) {
+	if headers := extractHeaders(metadata); headers != nil {
+		ctx = contextvalue.WithWorkflowHeaders(ctx, headers)
+	}
+
+	return ctx, nil
+}
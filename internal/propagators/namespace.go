@@ -0,0 +1,55 @@
+package propagators
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/contextvalue"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// namespaceKey is the metadata key holding the namespace of a workflow instance
+const namespaceKey = "namespace"
+
+// InjectNamespace stores the given namespace in the metadata
+func InjectNamespace(metadata *workflow.Metadata, namespace string) {
+	if namespace != "" {
+		metadata.Set(namespaceKey, namespace)
+	}
+}
+
+// Namespace returns the namespace stored in the metadata, empty if there is none
+func Namespace(metadata *workflow.Metadata) string {
+	if metadata == nil {
+		return ""
+	}
+
+	return metadata.Get(namespaceKey)
+}
+
+// NamespaceContextPropagator passes the namespace a workflow instance has been created in on to all activities and
+// sub-workflows it schedules, so they are executed with the registry of the same namespace.
+type NamespaceContextPropagator struct {
+}
+
+var _ workflow.ContextPropagator = &NamespaceContextPropagator{}
+
+func (*NamespaceContextPropagator) Inject(ctx context.Context, metadata *workflow.Metadata) error {
+	return nil
+}
+
+func (*NamespaceContextPropagator) Extract(ctx context.Context, metadata *workflow.Metadata) (context.Context, error) {
+	return ctx, nil
+}
+
+func (*NamespaceContextPropagator) InjectFromWorkflow(ctx workflow.Context, metadata *workflow.Metadata) error {
+	InjectNamespace(metadata, contextvalue.WorkflowNamespace(ctx))
+	return nil
+}
+
+func (*NamespaceContextPropagator) ExtractToWorkflow(ctx workflow.Context, metadata *workflow.Metadata) (workflow.Context, error) {
+	if namespace := Namespace(metadata); namespace != "" {
+		ctx = contextvalue.WithWorkflowNamespace(ctx, namespace)
+	}
+
+	return ctx, nil
+}
@@ -80,6 +80,7 @@ func (c *channel[T]) Close() {
 
 func (c *channel[T]) Send(ctx Context, v T) {
 	cr := getCoState(ctx)
+	cr.iterate()
 
 	addedSender := false
 	sentValue := false
@@ -118,6 +119,7 @@ func (c *channel[T]) SendNonblocking(v T) bool {
 
 func (c *channel[T]) Receive(ctx Context) (v T, ok bool) {
 	cr := getCoState(ctx)
+	cr.iterate()
 
 	addedListener := false
 	receivedValue := false
@@ -12,6 +12,10 @@ const DeadlockDetection = 40 * time.Second
 
 var ErrCoroutineAlreadyFinished = errors.New("coroutine already finished")
 
+// ErrStuckInLoop is the error a coroutine fails with when it exceeds its maximum number of iterations without
+// yielding.
+var ErrStuckInLoop = errors.New("workflow stuck in loop")
+
 type CoroutineCreator interface {
 	NewCoroutine(ctx Context, fn func(Context) error)
 }
@@ -40,6 +44,16 @@ type key int
 
 var coroutinesCtxKey key
 
+var maxIterationsCtxKey key = 1
+
+// WithMaxIterations limits the number of iterations coroutines started with the returned context can execute
+// without yielding. Iterations are operations that could block but did not, like selecting a default case or
+// getting the value of a resolved future. A coroutine exceeding the limit is most likely stuck in a loop that never
+// waits for anything, and fails with ErrStuckInLoop. A limit of 0 disables the check.
+func WithMaxIterations(ctx Context, maxIterations int) Context {
+	return WithValue(ctx, maxIterationsCtxKey, maxIterations)
+}
+
 type logger interface {
 	Println(v ...interface{})
 }
@@ -54,6 +68,10 @@ type coState struct {
 
 	err error
 
+	// iterations is the number of iterations since the coroutine last yielded
+	iterations    int
+	maxIterations int
+
 	// logger logger
 	// idx    int
 
@@ -64,6 +82,7 @@ type coState struct {
 
 func NewCoroutine(ctx Context, fn func(ctx Context) error) Coroutine {
 	s := newState()
+	s.maxIterations, _ = ctx.Value(maxIterationsCtxKey).(int)
 	ctx = withCoState(ctx, s)
 
 	go func() {
@@ -75,6 +94,11 @@ func NewCoroutine(ctx Context, fn func(ctx Context) error) Coroutine {
 					return
 				}
 
+				if err, ok := r.(error); ok && errors.Is(err, ErrStuckInLoop) {
+					s.err = err
+					return
+				}
+
 				s.err = fmt.Errorf("panic: %v", r)
 			}
 		}()
@@ -147,10 +171,25 @@ func (s *coState) Yield() {
 	s.yield(true)
 }
 
+// iterate counts an iteration of the coroutine, and fails it if it exceeded its maximum number of iterations without
+// yielding
+func (s *coState) iterate() {
+	if s.maxIterations <= 0 {
+		return
+	}
+
+	s.iterations++
+	if s.iterations > s.maxIterations {
+		panic(fmt.Errorf("%w: %d iterations without yielding", ErrStuckInLoop, s.maxIterations))
+	}
+}
+
 func (s *coState) yield(markBlocking bool) {
 	// s.logger.Println("yielding")
 
 	if markBlocking {
+		s.iterations = 0
+
 		if s.shouldExit.Load() != nil {
 			// s.logger.Println("yielding, but should exit")
 			panic(ErrCoroutineAlreadyFinished)
@@ -224,3 +224,41 @@ func Test_Coroutine_Panic(t *testing.T) {
 	require.Error(t, c.Error())
 	require.Equal(t, c.Error().Error(), "panic: test panic")
 }
+
+func Test_Coroutine_StuckInLoop(t *testing.T) {
+	f := NewFuture[int]()
+	f.Set(42, nil)
+
+	c := NewCoroutine(WithMaxIterations(Background(), 10), func(ctx Context) error {
+		for {
+			f.Get(ctx)
+		}
+	})
+
+	c.Execute()
+
+	require.True(t, c.Finished())
+	require.ErrorIs(t, c.Error(), ErrStuckInLoop)
+}
+
+func Test_Coroutine_IterationsResetWhenYielding(t *testing.T) {
+	c := NewCoroutine(WithMaxIterations(Background(), 10), func(ctx Context) error {
+		s := getCoState(ctx)
+
+		for i := 0; i < 5; i++ {
+			for j := 0; j < 10; j++ {
+				s.iterate()
+			}
+
+			s.Yield()
+		}
+
+		return nil
+	})
+
+	for !c.Finished() {
+		c.Execute()
+	}
+
+	require.NoError(t, c.Error())
+}
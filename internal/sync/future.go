@@ -41,6 +41,8 @@ func (f *future[T]) HasValue() bool {
 }
 
 func (f *future[T]) Get(ctx Context) (T, error) {
+	getCoState(ctx).iterate()
+
 	for {
 		cr := getCoState(ctx)
 
@@ -33,15 +33,17 @@ func Default(handler func(ctx Context)) SelectCase {
 	}
 }
 
-func Select(ctx Context, cases ...SelectCase) {
+// Select blocks until one of the given cases is ready, handles it, and returns its index
+func Select(ctx Context, cases ...SelectCase) int {
 	cs := getCoState(ctx)
+	cs.iterate()
 
 	for {
 		// Is any case ready?
-		for _, c := range cases {
+		for i, c := range cases {
 			if c.Ready() {
 				c.Handle(ctx)
-				return
+				return i
 			}
 		}
 
@@ -50,6 +52,57 @@ func Select(ctx Context, cases ...SelectCase) {
 	}
 }
 
+// Selector selects from cases repeatedly, for example in a loop. Unless it is fair, it behaves like Select.
+type Selector struct {
+	fair bool
+
+	// next is the index of the case checked first by the next Select of a fair selector
+	next int
+}
+
+func NewSelector(fair bool) *Selector {
+	return &Selector{fair: fair}
+}
+
+// Select blocks until one of the given cases is ready, handles it, and returns its index. A fair selector starts checking cases after the
+// one it selected last, wrapping around, so that a case that is always ready cannot prevent other ready cases from
+// being selected. Default cases are only selected by a fair selector if no other case is ready.
+func (s *Selector) Select(ctx Context, cases ...SelectCase) int {
+	if !s.fair {
+		return Select(ctx, cases...)
+	}
+
+	cs := getCoState(ctx)
+	cs.iterate()
+
+	for {
+		def := -1
+		for i := range cases {
+			idx := (s.next + i) % len(cases)
+			c := cases[idx]
+
+			if _, ok := c.(*defaultCase); ok {
+				def = idx
+				continue
+			}
+
+			if c.Ready() {
+				s.next = idx + 1
+				c.Handle(ctx)
+				return idx
+			}
+		}
+
+		if def >= 0 {
+			cases[def].Handle(ctx)
+			return def
+		}
+
+		// else, yield and wait for result
+		cs.Yield()
+	}
+}
+
 type futureCase[T any] struct {
 	f  *future[T]
 	fn func(Context, Future[T])
@@ -60,7 +113,9 @@ func (fc *futureCase[T]) Ready() bool {
 }
 
 func (fc *futureCase[T]) Handle(ctx Context) {
-	fc.fn(ctx, fc.f)
+	if fc.fn != nil {
+		fc.fn(ctx, fc.f)
+	}
 }
 
 type channelReceiveCase[T any] struct {
@@ -74,7 +129,9 @@ func (crc *channelReceiveCase[T]) Ready() bool {
 
 func (crc *channelReceiveCase[T]) Handle(ctx Context) {
 	v, ok := crc.c.Receive(ctx)
-	crc.fn(ctx, v, ok)
+	if crc.fn != nil {
+		crc.fn(ctx, v, ok)
+	}
 }
 
 type channelSendCase[T any] struct {
@@ -89,7 +146,9 @@ func (csc *channelSendCase[T]) Ready() bool {
 
 func (csc *channelSendCase[T]) Handle(ctx Context) {
 	csc.c.Send(ctx, *csc.v)
-	csc.fn(ctx)
+	if csc.fn != nil {
+		csc.fn(ctx)
+	}
 }
 
 type defaultCase struct {
@@ -101,5 +160,7 @@ func (dc *defaultCase) Ready() bool {
 }
 
 func (dc *defaultCase) Handle(ctx Context) {
-	dc.fn(ctx)
+	if dc.fn != nil {
+		dc.fn(ctx)
+	}
 }
@@ -1,6 +1,7 @@
 package sync
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -227,3 +228,111 @@ func Test_ChannelSelector_SendNonBlocking(t *testing.T) {
 	// Channel does not have capacity, Select blocks
 	require.False(t, cs.Finished())
 }
+
+func Test_Selector_Fair(t *testing.T) {
+	tests := []struct {
+		name string
+		fair bool
+		want []int
+	}{
+		{"Unfair", false, []int{1, 1, 1, 1}},
+		{"Fair", true, []int{1, 2, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewBufferedChannel[int](10)
+			f := NewFuture[int]()
+			f.Set(2, nil)
+
+			order := make([]int, 0)
+
+			cs := NewCoroutine(Background(), func(ctx Context) error {
+				for i := 0; i < 4; i++ {
+					c.Send(ctx, 1)
+				}
+
+				s := NewSelector(tt.fair)
+				for i := 0; i < 4; i++ {
+					s.Select(
+						ctx,
+						Receive(c, func(ctx Context, v int, ok bool) {
+							order = append(order, v)
+						}),
+						Await(f, func(ctx Context, f Future[int]) {
+							v, _ := f.Get(ctx)
+							order = append(order, v)
+						}),
+					)
+				}
+
+				return nil
+			})
+
+			cs.Execute()
+
+			require.True(t, cs.Finished())
+			require.Equal(t, tt.want, order)
+		})
+	}
+}
+
+func Test_Selector_FairDefaultCase(t *testing.T) {
+	c := NewBufferedChannel[int](10)
+
+	order := make([]int, 0)
+
+	cs := NewCoroutine(Background(), func(ctx Context) error {
+		c.Send(ctx, 1)
+		c.Send(ctx, 1)
+
+		s := NewSelector(true)
+		for i := 0; i < 3; i++ {
+			s.Select(
+				ctx,
+				Default(func(ctx Context) {
+					order = append(order, 0)
+				}),
+				Receive(c, func(ctx Context, v int, ok bool) {
+					order = append(order, v)
+				}),
+			)
+		}
+
+		return nil
+	})
+
+	cs.Execute()
+
+	require.True(t, cs.Finished())
+	require.Equal(t, []int{1, 1, 0}, order)
+}
+
+func Test_Selector_ReturnsIndex(t *testing.T) {
+	for _, fair := range []bool{false, true} {
+		t.Run(fmt.Sprintf("fair=%v", fair), func(t *testing.T) {
+			c := NewBufferedChannel[int](10)
+			f := NewFuture[int]()
+
+			selected := make([]int, 0)
+
+			cs := NewCoroutine(Background(), func(ctx Context) error {
+				c.Send(ctx, 1)
+				c.Send(ctx, 1)
+
+				s := NewSelector(fair)
+				for i := 0; i < 3; i++ {
+					// Handlers are optional when using the index
+					selected = append(selected, s.Select(ctx, Receive[int](c, nil), Await[int](f, nil), Default(nil)))
+				}
+
+				return nil
+			})
+
+			cs.Execute()
+
+			require.True(t, cs.Finished())
+			require.Equal(t, []int{0, 0, 2}, selected)
+		})
+	}
+}
@@ -2,8 +2,10 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"github.com/benbjohnson/clock"
@@ -12,41 +14,106 @@ import (
 	"github.com/cschleiden/go-workflows/backend/metrics"
 	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/cschleiden/go-workflows/internal/activity"
+	"github.com/cschleiden/go-workflows/internal/blobs"
+	"github.com/cschleiden/go-workflows/internal/log"
 	"github.com/cschleiden/go-workflows/internal/metrickeys"
 	im "github.com/cschleiden/go-workflows/internal/metrics"
+	"github.com/cschleiden/go-workflows/internal/propagators"
 	"github.com/cschleiden/go-workflows/internal/workflowerrors"
 	"github.com/cschleiden/go-workflows/registry"
 	"github.com/cschleiden/go-workflows/workflow"
 )
 
+// errActivityAlreadyStarted is the error at-most-once activities fail with when their task is delivered again after an
+// execution has been started
+var errActivityAlreadyStarted = errors.New("at-most-once activity has been started before and might have been executed")
+
+type ActivityWorkerOptions struct {
+	WorkerOptions
+
+	// ErrorRedactor is applied to activity errors before they are persisted
+	ErrorRedactor func(error) error
+
+	// Concurrency enforces per-activity concurrency limits. If nil, activities are only limited by MaxParallelTasks.
+	Concurrency *ActivityConcurrency
+
+	// PayloadLogSampleRate logs the inputs and outputs of one in every PayloadLogSampleRate activity executions. If
+	// 0, payloads are not logged.
+	PayloadLogSampleRate int
+
+	// PayloadLogRedactor is applied to decoded inputs and outputs before they are logged. If nil, only their sizes
+	// are logged.
+	PayloadLogRedactor func(activityName string, v any) any
+}
+
 func NewActivityWorker(
 	b backend.Backend,
 	registry *registry.Registry,
 	clock clock.Clock,
-	options WorkerOptions,
+	options ActivityWorkerOptions,
 ) *Worker[backend.ActivityTask, history.Event] {
-	ae := activity.NewExecutor(b.Options().Logger, b.Tracer(), b.Options().Converter, b.Options().ContextPropagators, registry)
+	ae := activity.NewExecutor(b.Options().Logger, b.Tracer(), b.Options().Converter, b.Options().ContextPropagators, registry,
+		blobs.New(b.Options().BlobStore, b.Options().Converter))
 
 	tw := &ActivityTaskWorker{
 		backend:              b,
+		registry:             registry,
 		activityTaskExecutor: ae,
 		clock:                clock,
 		logger:               b.Options().Logger,
+		identity:             options.Identity,
+		errorRedactor:        options.ErrorRedactor,
+		concurrency:          options.Concurrency,
+		payloadLogSampleRate: options.PayloadLogSampleRate,
+		payloadLogRedactor:   options.PayloadLogRedactor,
 	}
 
-	return NewWorker(b, tw, &options)
+	if tw.concurrency == nil {
+		tw.concurrency = NewActivityConcurrency(registry)
+	}
+
+	w := NewWorker(b, tw, &options.WorkerOptions)
+
+	// Queues have been defaulted by the worker
+	tw.queues = options.Queues
+
+	return w
 }
 
 type ActivityTaskWorker struct {
 	backend              backend.Backend
+	registry             *registry.Registry
 	activityTaskExecutor *activity.Executor
 	clock                clock.Clock
 	logger               *slog.Logger
+	identity             string
+	errorRedactor        func(error) error
+	concurrency          *ActivityConcurrency
+	payloadLogSampleRate int
+	payloadLogRedactor   func(activityName string, v any) any
+	executions           atomic.Int64
+
+	// queues are the queues the worker listens to
+	queues []workflow.Queue
 }
 
 func (atw *ActivityTaskWorker) Complete(ctx context.Context, result *history.Event, task *backend.ActivityTask) error {
 	if err := atw.backend.CompleteActivityTask(ctx, task, result); err != nil {
+		if errors.Is(err, backend.ErrBackendBusy) {
+			// Let the worker back off from the backend
+			return fmt.Errorf("completing activity task: %w", err)
+		}
+
 		atw.backend.Options().Logger.Error("completing activity task", "error", err)
+		return nil
+	}
+
+	// The task won't be delivered again, the marker of an at-most-once activity is not needed anymore
+	a := task.Event.Attributes.(*history.ActivityScheduledAttributes)
+	if atw.executionGuarantee(a) == registry.AtMostOnce {
+		if err := atw.backend.InvalidateActivityResult(ctx, activity.StartedMarkerKey(task.WorkflowInstance, task.ActivityID)); err != nil {
+			atw.logger.ErrorContext(ctx, "removing activity started marker", log.ActivityNameKey, a.Name, log.ErrorKey, err)
+		}
 	}
 
 	return nil
@@ -72,12 +139,103 @@ func (atw *ActivityTaskWorker) Execute(ctx context.Context, task *backend.Activi
 	timer := im.NewTimer(ametrics, metrickeys.ActivityTaskProcessed, metrics.Tags{})
 	defer timer.Stop()
 
+	// Return a cached result, if the activity has been executed with the same inputs before
+	cacheKey, cacheTTL := "", time.Duration(0)
+	if ttl, ok := atw.activityResultCacheTTL(a); ok {
+		cacheKey, cacheTTL = activity.ResultCacheKey(a.Name, a.Inputs), ttl
+
+		result, ok, err := atw.backend.GetActivityResult(ctx, cacheKey)
+		if err != nil {
+			if !errors.As(err, &backend.ErrNotSupported{}) {
+				atw.logger.ErrorContext(ctx, "reading cached activity result", log.ActivityNameKey, a.Name, log.ErrorKey, err)
+			}
+
+			cacheKey = ""
+		} else if ok {
+			return atw.resultToEvent(task.Event.ScheduleEventID, a.CorrelationID, result, nil, 0), nil
+		}
+	}
+
+	// At-most-once activities record that they have been started, and are not executed again
+	if atw.executionGuarantee(a) == registry.AtMostOnce {
+		if err := atw.recordStarted(ctx, task); err != nil {
+			if errors.Is(err, errActivityAlreadyStarted) || errors.As(err, &backend.ErrNotSupported{}) {
+				atw.logger.WarnContext(ctx, "not executing at-most-once activity",
+					log.ActivityNameKey, a.Name, log.ActivityIDKey, task.ActivityID, log.ErrorKey, err)
+
+				return atw.resultToEvent(task.Event.ScheduleEventID, a.CorrelationID, nil, workflowerrors.NewPermanentError(err), 0), nil
+			}
+
+			return nil, fmt.Errorf("recording activity start: %w", err)
+		}
+	}
+
+	started := atw.clock.Now()
 	result, err := atw.activityTaskExecutor.ExecuteActivity(ctx, task)
-	event := atw.resultToEvent(task.Event.ScheduleEventID, result, err)
+	duration := atw.clock.Since(started)
+
+	if atw.sampleExecution() {
+		atw.logPayloads(ctx, task, result, err)
+	}
+
+	event := atw.resultToEvent(task.Event.ScheduleEventID, a.CorrelationID, result, err, duration)
+
+	if err == nil && cacheKey != "" {
+		if err := atw.backend.CacheActivityResult(ctx, cacheKey, result, cacheTTL); err != nil {
+			atw.logger.ErrorContext(ctx, "caching activity result", log.ActivityNameKey, a.Name, log.ErrorKey, err)
+		}
+	}
 
 	return event, nil
 }
 
+// Acquire implements TaskLimiter. It waits for a free slot if the activity is at its concurrency limit, the task is
+// kept locked while waiting.
+func (atw *ActivityTaskWorker) Acquire(ctx context.Context, task *backend.ActivityTask) (func(), error) {
+	a := task.Event.Attributes.(*history.ActivityScheduledAttributes)
+
+	return atw.concurrency.Acquire(ctx, propagators.Namespace(a.Metadata), a.Name)
+}
+
+// activityResultCacheTTL returns how long results of the given activity are cached, if caching has been enabled for it
+// in the namespace of the workflow instance that scheduled it
+func (atw *ActivityTaskWorker) activityResultCacheTTL(a *history.ActivityScheduledAttributes) (time.Duration, bool) {
+	r, err := atw.registry.Namespace(propagators.Namespace(a.Metadata))
+	if err != nil {
+		return 0, false
+	}
+
+	return r.ActivityResultCacheTTL(a.Name)
+}
+
+// executionGuarantee returns the execution guarantee of the given activity in the namespace of the workflow instance
+// that scheduled it
+func (atw *ActivityTaskWorker) executionGuarantee(a *history.ActivityScheduledAttributes) registry.ExecutionGuarantee {
+	r, err := atw.registry.Namespace(propagators.Namespace(a.Metadata))
+	if err != nil {
+		return registry.AtLeastOnce
+	}
+
+	return r.ActivityExecutionGuarantee(a.Name)
+}
+
+// recordStarted records that the given task of an at-most-once activity is about to be executed. It returns
+// errActivityAlreadyStarted if an execution of the task has been started before.
+func (atw *ActivityTaskWorker) recordStarted(ctx context.Context, task *backend.ActivityTask) error {
+	key := activity.StartedMarkerKey(task.WorkflowInstance, task.ActivityID)
+
+	recorded, err := atw.backend.CacheActivityResultIfAbsent(ctx, key, payload.Payload{}, activity.StartedMarkerTTL)
+	if err != nil {
+		return err
+	}
+
+	if !recorded {
+		return errActivityAlreadyStarted
+	}
+
+	return nil
+}
+
 func (atw *ActivityTaskWorker) Extend(ctx context.Context, task *backend.ActivityTask) error {
 	return atw.backend.ExtendActivityTask(ctx, task)
 }
@@ -86,13 +244,16 @@ func (atw *ActivityTaskWorker) Get(ctx context.Context, queues []workflow.Queue)
 	return atw.backend.GetActivityTask(ctx, queues)
 }
 
-func (atw *ActivityTaskWorker) resultToEvent(scheduleEventID int64, result payload.Payload, err error) *history.Event {
+func (atw *ActivityTaskWorker) resultToEvent(scheduleEventID int64, correlationID string, result payload.Payload, err error, duration time.Duration) *history.Event {
 	if err != nil {
 		return history.NewPendingEvent(
 			atw.clock.Now(),
 			history.EventType_ActivityFailed,
 			&history.ActivityFailedAttributes{
-				Error: workflowerrors.FromError(err),
+				Error:         atw.redactError(err),
+				CorrelationID: correlationID,
+				Worker:        atw.identity,
+				Duration:      duration,
 			},
 			history.ScheduleEventID(scheduleEventID),
 		)
@@ -102,7 +263,27 @@ func (atw *ActivityTaskWorker) resultToEvent(scheduleEventID int64, result paylo
 		atw.clock.Now(),
 		history.EventType_ActivityCompleted,
 		&history.ActivityCompletedAttributes{
-			Result: result,
+			Result:        result,
+			CorrelationID: correlationID,
+			Worker:        atw.identity,
+			Duration:      duration,
 		},
 		history.ScheduleEventID(scheduleEventID))
 }
+
+// redactError converts the given activity error into a workflow error, applying the configured redactor first.
+// The redactor receives a *workflowerrors.Error, including the message and stack trace of panics.
+func (atw *ActivityTaskWorker) redactError(err error) *workflowerrors.Error {
+	werr := workflowerrors.FromError(err)
+	if atw.errorRedactor == nil {
+		return werr
+	}
+
+	redacted := atw.errorRedactor(werr)
+	if redacted == nil {
+		// Activity failed, always record a failure
+		return &workflowerrors.Error{Type: werr.Type, Message: "redacted", Permanent: werr.Permanent}
+	}
+
+	return workflowerrors.FromError(redacted)
+}
@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/converter"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/activity"
+	"github.com/cschleiden/go-workflows/internal/blobs"
+	"github.com/cschleiden/go-workflows/internal/metrics"
+	"github.com/cschleiden/go-workflows/registry"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func Test_ActivityTaskWorker_AtMostOnce(t *testing.T) {
+	executions := 0
+	a := func(ctx context.Context) (int, error) {
+		executions++
+		return 42, nil
+	}
+
+	r := registry.New()
+	require.NoError(t, r.RegisterActivity(a, registry.WithName("a"), registry.WithExecutionGuarantee(registry.AtMostOnce)))
+
+	b := &backend.MockBackend{}
+	b.On("Metrics").Return(metrics.NewNoopMetricsClient())
+
+	atw := &ActivityTaskWorker{
+		backend:              b,
+		registry:             r,
+		activityTaskExecutor: activity.NewExecutor(slog.Default(), noop.NewTracerProvider().Tracer("test"), converter.DefaultConverter, nil, r, blobs.New(nil, converter.DefaultConverter)),
+		clock:                clock.New(),
+		logger:               slog.Default(),
+		concurrency:          NewActivityConcurrency(r),
+	}
+
+	instance := core.NewWorkflowInstance("instanceID", "executionID")
+	task := &backend.ActivityTask{
+		ID:               "activityID",
+		ActivityID:       "activityID",
+		WorkflowInstance: instance,
+		Event: history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+			Name: "a",
+		}, history.ScheduleEventID(1)),
+	}
+
+	key := activity.StartedMarkerKey(instance, "activityID")
+
+	// First delivery records the marker and executes the activity
+	b.On("CacheActivityResultIfAbsent", mock.Anything, key, payload.Payload{}, activity.StartedMarkerTTL).Return(true, nil).Once()
+
+	event, err := atw.Execute(context.Background(), task)
+	require.NoError(t, err)
+	require.Equal(t, history.EventType_ActivityCompleted, event.Type)
+	require.Equal(t, 1, executions)
+
+	// Delivering the task again without it having been completed fails the activity
+	b.On("CacheActivityResultIfAbsent", mock.Anything, key, payload.Payload{}, activity.StartedMarkerTTL).Return(false, nil).Once()
+
+	event, err = atw.Execute(context.Background(), task)
+	require.NoError(t, err)
+	require.Equal(t, history.EventType_ActivityFailed, event.Type)
+	require.Contains(t, event.Attributes.(*history.ActivityFailedAttributes).Error.Message, "might have been executed")
+	require.Equal(t, 1, executions)
+
+	b.AssertExpectations(t)
+}
+
+func Test_ActivityTaskWorker_LogPayloads_OnlySizesByDefault(t *testing.T) {
+	var logs bytes.Buffer
+
+	atw := &ActivityTaskWorker{
+		logger: slog.New(slog.NewJSONHandler(&logs, nil)),
+	}
+
+	task := &backend.ActivityTask{
+		ActivityID:       "activityID",
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		Event: history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+			Name:   "a",
+			Inputs: []payload.Payload{payload.Payload(`"secret"`)},
+		}, history.ScheduleEventID(1)),
+	}
+
+	atw.logPayloads(context.Background(), task, payload.Payload(`"secret result"`), nil)
+
+	require.NotContains(t, logs.String(), "secret")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+	require.Equal(t, []any{float64(8)}, record["input_sizes"])
+	require.Equal(t, float64(15), record["result_size"])
+}
@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cschleiden/go-workflows/registry"
+)
+
+// ActivityConcurrency enforces the concurrency limits registered for activities, and keeps track of the number of
+// executions of each activity.
+type ActivityConcurrency struct {
+	registry *registry.Registry
+
+	mu       sync.Mutex
+	limits   map[string]chan struct{}
+	inFlight map[string]int
+}
+
+func NewActivityConcurrency(registry *registry.Registry) *ActivityConcurrency {
+	return &ActivityConcurrency{
+		registry: registry,
+		limits:   make(map[string]chan struct{}),
+		inFlight: make(map[string]int),
+	}
+}
+
+// Acquire waits until the activity with the given name in the given namespace can be executed. The returned function
+// has to be called once the execution has finished.
+func (ac *ActivityConcurrency) Acquire(ctx context.Context, namespace, name string) (func(), error) {
+	// Activities in different namespaces are limited and counted separately
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+
+	if sem := ac.semaphore(namespace, name, key); sem != nil {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	ac.mu.Lock()
+	ac.inFlight[key]++
+	ac.mu.Unlock()
+
+	return func() {
+		ac.mu.Lock()
+		ac.inFlight[key]--
+		if ac.inFlight[key] == 0 {
+			delete(ac.inFlight, key)
+		}
+		ac.mu.Unlock()
+
+		if sem := ac.semaphore(namespace, name, key); sem != nil {
+			<-sem
+		}
+	}, nil
+}
+
+// InFlight returns the number of executions of each activity that are currently running. Activities of namespaces are
+// reported as namespace/name.
+func (ac *ActivityConcurrency) InFlight() map[string]int {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	inFlight := make(map[string]int, len(ac.inFlight))
+	for name, n := range ac.inFlight {
+		inFlight[name] = n
+	}
+
+	return inFlight
+}
+
+// semaphore returns the semaphore for the given activity, or nil if it is not limited. key identifies the activity
+// across namespaces. Semaphores are created on first use, since activities can be registered after the worker has been
+// created.
+func (ac *ActivityConcurrency) semaphore(namespace, name, key string) chan struct{} {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	if sem, ok := ac.limits[key]; ok {
+		return sem
+	}
+
+	var sem chan struct{}
+	if r, err := ac.registry.Namespace(namespace); err == nil {
+		if limit, ok := r.ActivityMaxConcurrency(name); ok {
+			sem = make(chan struct{}, limit)
+		}
+	}
+
+	ac.limits[key] = sem
+
+	return sem
+}
@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/workflowerrors"
+)
+
+// sampleExecution returns true if the inputs and outputs of the next activity execution should be logged
+func (atw *ActivityTaskWorker) sampleExecution() bool {
+	if atw.payloadLogSampleRate <= 0 {
+		return false
+	}
+
+	return (atw.executions.Add(1)-1)%int64(atw.payloadLogSampleRate) == 0
+}
+
+// logPayloads logs the inputs and the outcome of an activity execution. Without a payload redactor, only the sizes of
+// the payloads are logged, they might contain sensitive data. Errors are logged the way they are persisted, i.e., after
+// applying the error redactor.
+func (atw *ActivityTaskWorker) logPayloads(ctx context.Context, task *backend.ActivityTask, result payload.Payload, err error) {
+	a := task.Event.Attributes.(*history.ActivityScheduledAttributes)
+
+	attrs := []any{
+		log.InstanceIDKey, task.WorkflowInstance.InstanceID,
+		log.ExecutionIDKey, task.WorkflowInstance.ExecutionID,
+		log.ActivityNameKey, a.Name,
+		log.ActivityIDKey, task.ActivityID,
+		log.ScheduleEventIDKey, task.Event.ScheduleEventID,
+		log.AttemptKey, a.Attempt,
+	}
+
+	if atw.payloadLogRedactor != nil {
+		attrs = append(attrs, "inputs", atw.decodePayloads(a.Name, a.Inputs))
+	} else {
+		attrs = append(attrs, "input_sizes", payloadSizes(a.Inputs))
+	}
+
+	if err != nil {
+		attrs = append(attrs, log.ErrorKey, workflowerrors.ToError(atw.redactError(err)))
+	} else if atw.payloadLogRedactor != nil {
+		attrs = append(attrs, "result", atw.payloadLogRedactor(a.Name, atw.decodePayload(result)))
+	} else {
+		attrs = append(attrs, "result_size", len(result))
+	}
+
+	atw.logger.InfoContext(ctx, "Sampled activity execution", attrs...)
+}
+
+// decodePayloads decodes the given payloads and applies the payload redactor to them
+func (atw *ActivityTaskWorker) decodePayloads(name string, payloads []payload.Payload) []any {
+	values := make([]any, len(payloads))
+	for i, p := range payloads {
+		values[i] = atw.payloadLogRedactor(name, atw.decodePayload(p))
+	}
+
+	return values
+}
+
+func payloadSizes(payloads []payload.Payload) []int {
+	sizes := make([]int, len(payloads))
+	for i, p := range payloads {
+		sizes[i] = len(p)
+	}
+
+	return sizes
+}
+
+// decodePayload decodes the given payload with the converter of the backend. Payloads that cannot be decoded into a
+// generic value are logged as is.
+func (atw *ActivityTaskWorker) decodePayload(p payload.Payload) any {
+	if p == nil {
+		return nil
+	}
+
+	var v any
+	if err := atw.backend.Options().Converter.From(p, &v); err != nil {
+		return slog.StringValue(string(p))
+	}
+
+	return v
+}
@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+const (
+	// busyInitialBackoff is the initial delay before polling again after the backend reported being busy
+	busyInitialBackoff = 100 * time.Millisecond
+
+	// busyMaxBackoff is the maximum delay between polls while the backend is busy
+	busyMaxBackoff = 10 * time.Second
+)
+
+// busyBackoff tracks the backoff of a worker from a backend that reported being busy. It is shared by all pollers and
+// tasks of the worker, so that busy errors from polling as well as from completing tasks extend the same backoff.
+type busyBackoff struct {
+	mu sync.Mutex
+
+	b *backoff.ExponentialBackOff
+
+	// until is the end of the current backoff
+	until time.Time
+}
+
+func newBusyBackoff() *busyBackoff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = busyInitialBackoff
+	b.MaxInterval = busyMaxBackoff
+	b.MaxElapsedTime = 0
+	b.Reset()
+
+	return &busyBackoff{b: b}
+}
+
+// Busy records that the backend reported being busy, and returns the remaining backoff
+func (bb *busyBackoff) Busy() time.Duration {
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+
+	if until := time.Now().Add(bb.b.NextBackOff()); until.After(bb.until) {
+		bb.until = until
+	}
+
+	return time.Until(bb.until)
+}
+
+// Recovered resets the backoff after the backend served a request again. A backoff that is still active isn't
+// shortened.
+func (bb *busyBackoff) Recovered() {
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+
+	bb.b.Reset()
+}
+
+// Remaining returns the remaining backoff, zero if the worker isn't backing off
+func (bb *busyBackoff) Remaining() time.Duration {
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+
+	return max(time.Until(bb.until), 0)
+}
@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/internal/log"
+	"github.com/cschleiden/go-workflows/internal/propagators"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/cschleiden/go-workflows/workflow/executor"
+)
+
+// LocalActivityExecutor executes activities scheduled by workflow tasks in the same process, instead of scheduling
+// them via the backend.
+type LocalActivityExecutor interface {
+	// CanExecute returns true if the activity with the given namespace, name, and queue can be executed locally
+	CanExecute(namespace, name string, queue workflow.Queue) bool
+
+	// ExecuteLocal executes the activity task and returns the completed or failed event for it
+	ExecuteLocal(ctx context.Context, task *backend.ActivityTask) (*history.Event, error)
+}
+
+// CanExecute implements LocalActivityExecutor. Activities can be executed locally if they are registered and on one of
+// the queues the activity worker listens to.
+func (atw *ActivityTaskWorker) CanExecute(namespace, name string, queue workflow.Queue) bool {
+	r, err := atw.registry.Namespace(namespace)
+	if err != nil {
+		return false
+	}
+
+	if _, err := r.GetActivity(name); err != nil {
+		return false
+	}
+
+	return slices.Contains(atw.queues, queue)
+}
+
+// ExecuteLocal implements LocalActivityExecutor. Local executions count towards the concurrency limits of activities.
+func (atw *ActivityTaskWorker) ExecuteLocal(ctx context.Context, task *backend.ActivityTask) (*history.Event, error) {
+	release, err := atw.Acquire(ctx, task)
+	if err != nil {
+		return nil, fmt.Errorf("waiting for activity concurrency limit: %w", err)
+	}
+	defer release()
+
+	return atw.Execute(ctx, task)
+}
+
+// executeLocalActivities executes the activities scheduled by the workflow task that can be executed locally. Their
+// results are sent to the workflow instance like results of activities executed by an activity worker, so the
+// history has the same shape. The results are only delivered when the workflow task is completed, if the worker
+// crashes before that, the task is retried and the activities are executed again.
+func (wtw *WorkflowTaskWorker) executeLocalActivities(ctx context.Context, t *backend.WorkflowTask, result *executor.ExecutionResult) {
+	local := make([]*backend.ActivityTask, 0)
+	remote := make([]*history.Event, 0, len(result.ActivityEvents))
+
+	for _, event := range result.ActivityEvents {
+		a := event.Attributes.(*history.ActivityScheduledAttributes)
+		queue := a.Queue
+		if queue == "" {
+			// Default to workflow queue
+			queue = t.Queue
+		}
+
+		if !wtw.localActivities.CanExecute(propagators.Namespace(a.Metadata), a.Name, queue) {
+			remote = append(remote, event)
+			continue
+		}
+
+		local = append(local, &backend.ActivityTask{
+			ID:               event.ID,
+			ActivityID:       event.ID,
+			Queue:            queue,
+			WorkflowInstance: t.WorkflowInstance,
+			Event:            event,
+		})
+	}
+
+	if len(local) == 0 {
+		return
+	}
+
+	results := make([]*history.Event, len(local))
+
+	var wg sync.WaitGroup
+	for i, task := range local {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			r, err := wtw.localActivities.ExecuteLocal(ctx, task)
+			if err != nil {
+				wtw.logger.ErrorContext(ctx, "could not execute local activity, scheduling it instead",
+					log.ActivityIDKey, task.ActivityID, log.ErrorKey, err)
+				return
+			}
+
+			results[i] = r
+		}()
+	}
+
+	wg.Wait()
+
+	for i, task := range local {
+		if results[i] == nil {
+			remote = append(remote, task.Event)
+			continue
+		}
+
+		result.WorkflowEvents = append(result.WorkflowEvents, &history.WorkflowEvent{
+			WorkflowInstance: t.WorkflowInstance,
+			HistoryEvent:     results[i],
+		})
+	}
+
+	result.ActivityEvents = remote
+}
@@ -7,8 +7,10 @@ import (
 	"log/slog"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/core"
 	"github.com/cschleiden/go-workflows/workflow"
@@ -22,6 +24,22 @@ type TaskWorker[Task, Result any] interface {
 	Complete(context.Context, *Result, *Task) error
 }
 
+// TaskAbandoner is implemented by task workers that need to clean up when a task is abandoned because its lock was
+// lost. Abandoned tasks are not completed, they are picked up again by another worker.
+type TaskAbandoner[Task any] interface {
+	Abandon(context.Context, *Task)
+}
+
+// TaskLimiter is implemented by task workers that limit the number of concurrently executing tasks of some kind, in
+// addition to MaxParallelTasks.
+type TaskLimiter[Task any] interface {
+	// Acquire waits until the task can be executed. The returned function has to be called once it has finished.
+	Acquire(context.Context, *Task) (func(), error)
+}
+
+// errTaskLockLost is returned when executing a task whose lock was lost while it was running
+var errTaskLockLost = errors.New("task lock lost")
+
 type Worker[Task, TaskResult any] struct {
 	options *WorkerOptions
 
@@ -29,8 +47,14 @@ type Worker[Task, TaskResult any] struct {
 
 	taskQueue chan *Task
 
+	// slots limits the number of executing tasks, if MaxParallelTasks is set and the task worker limits tasks itself
+	slots chan struct{}
+
 	logger *slog.Logger
 
+	// busy is the backoff from the backend while it reports being busy
+	busy *busyBackoff
+
 	pollersWg sync.WaitGroup
 
 	dispatcherDone chan struct{}
@@ -43,9 +67,24 @@ type WorkerOptions struct {
 
 	HeartbeatInterval time.Duration
 
+	// HeartbeatRetryBackoff is the initial delay before retrying a failed extension of a task lock. Retries back off
+	// exponentially, up to HeartbeatInterval. If zero, failed extensions are retried with the next heartbeat.
+	HeartbeatRetryBackoff time.Duration
+
+	// LockTimeout is the time a task stays locked without being extended. If set, a task whose lock could not be
+	// extended for that long is considered lost and abandoned.
+	LockTimeout time.Duration
+
 	PollingInterval time.Duration
 
 	Queues []workflow.Queue
+
+	// Identity identifies the worker in the workflow history, e.g., for diagnosing which worker executed a task
+	Identity string
+
+	// WorkerID is passed to the backend with every call, see backend.WithWorkerID, so that tasks are locked for this
+	// worker and can be released when it shuts down.
+	WorkerID string
 }
 
 func NewWorker[Task, TaskResult any](
@@ -61,16 +100,30 @@ func NewWorker[Task, TaskResult any](
 		options.Queues = append(options.Queues, core.QueueSystem)
 	}
 
-	return &Worker[Task, TaskResult]{
+	w := &Worker[Task, TaskResult]{
 		tw:             tw,
 		options:        options,
 		taskQueue:      make(chan *Task),
 		logger:         b.Options().Logger,
+		busy:           newBusyBackoff(),
 		dispatcherDone: make(chan struct{}, 1),
 	}
+
+	if _, ok := tw.(TaskLimiter[Task]); ok && options.MaxParallelTasks > 0 {
+		w.slots = make(chan struct{}, options.MaxParallelTasks)
+	}
+
+	return w
+}
+
+// TaskWorker returns the task worker processing the tasks of this worker
+func (w *Worker[Task, TaskResult]) TaskWorker() TaskWorker[Task, TaskResult] {
+	return w.tw
 }
 
 func (w *Worker[Task, TaskResult]) Start(ctx context.Context) error {
+	ctx = backend.WithWorkerID(ctx, w.options.WorkerID)
+
 	if err := w.tw.Start(ctx, w.options.Queues); err != nil {
 		return fmt.Errorf("starting task worker: %w", err)
 	}
@@ -114,12 +167,34 @@ func (w *Worker[Task, TaskResult]) poller(ctx context.Context) {
 		default:
 		}
 
+		// Backend is overloaded, back off before polling again to give it a chance to recover
+		if delay := w.busy.Remaining(); delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+
+			continue
+		}
+
 		task, err := w.poll(ctx, 30*time.Second)
 		if err != nil {
+			if errors.Is(err, backend.ErrBackendBusy) {
+				delay := w.busy.Busy()
+				w.logger.WarnContext(ctx, "backend busy, delaying polling", "error", err, "delay", delay)
+
+				continue
+			}
+
 			w.logger.ErrorContext(ctx, "error polling task", "error", err)
-		} else if task != nil {
-			w.taskQueue <- task
-			continue // check for new tasks right away
+		} else {
+			w.busy.Recovered()
+
+			if task != nil {
+				w.taskQueue <- task
+				continue // check for new tasks right away
+			}
 		}
 
 		if w.options.PollingInterval > 0 {
@@ -136,14 +211,26 @@ func (w *Worker[Task, TaskResult]) dispatcher() {
 	var sem chan struct{}
 
 	if w.options.MaxParallelTasks > 0 {
-		sem = make(chan struct{}, w.options.MaxParallelTasks)
+		maxTasks := w.options.MaxParallelTasks
+		if w.slots != nil {
+			// Tasks waiting for the limit of the task worker don't take one of the MaxParallelTasks slots. Up to
+			// MaxParallelTasks tasks can wait in addition to the executing ones, before no more tasks are polled.
+			maxTasks *= 2
+		}
+
+		sem = make(chan struct{}, maxTasks)
 	}
 
 	var wg sync.WaitGroup
 
+	// reserved is the number of slots the dispatcher holds itself while the backend is busy
+	reserved := 0
+
 	for t := range w.taskQueue {
 		// If limited max tasks, wait for a slot to open up
 		if sem != nil {
+			reserved = w.reserveSlots(sem, reserved)
+
 			sem <- struct{}{}
 		}
 
@@ -154,7 +241,7 @@ func (w *Worker[Task, TaskResult]) dispatcher() {
 			defer wg.Done()
 
 			// Create new context to allow tasks to complete when root context is canceled
-			taskCtx := context.Background()
+			taskCtx := backend.WithWorkerID(context.Background(), w.options.WorkerID)
 			if err := w.handle(taskCtx, t); err != nil {
 				w.logger.ErrorContext(taskCtx, "error handling task", "error", err)
 			}
@@ -170,38 +257,186 @@ func (w *Worker[Task, TaskResult]) dispatcher() {
 	w.dispatcherDone <- struct{}{}
 }
 
-func (w *Worker[Task, TaskResult]) handle(ctx context.Context, t *Task) error {
-	if w.options.HeartbeatInterval > 0 {
-		// Start heartbeat while processing task
-		heartbeatCtx, cancelHeartbeat := context.WithCancel(ctx)
-		defer cancelHeartbeat()
-		go w.heartbeatTask(heartbeatCtx, t)
+// reserveSlots adjusts the number of slots held by the dispatcher, given the number currently held, and returns the
+// new number. While the backend is busy, half of the slots are held, so that fewer tasks execute concurrently. Taking
+// a slot waits for a running task to finish.
+func (w *Worker[Task, TaskResult]) reserveSlots(sem chan struct{}, reserved int) int {
+	target := 0
+	if w.busy.Remaining() > 0 {
+		target = cap(sem) / 2
+	}
+
+	for ; reserved < target; reserved++ {
+		sem <- struct{}{}
+	}
+
+	for ; reserved > target; reserved-- {
+		<-sem
 	}
 
-	result, err := w.tw.Execute(ctx, t)
+	return reserved
+}
+
+func (w *Worker[Task, TaskResult]) handle(ctx context.Context, t *Task) error {
+	result, err := w.execute(ctx, t)
 	if err != nil {
+		if errors.Is(err, errTaskLockLost) {
+			// Another worker might own the task now, completing it could record its result twice
+			w.abandon(ctx, t)
+			return nil
+		}
+
 		return fmt.Errorf("executing task: %w", err)
 	}
 
-	return w.tw.Complete(ctx, result, t)
+	if err := w.tw.Complete(ctx, result, t); err != nil {
+		if errors.Is(err, backend.ErrBackendBusy) {
+			delay := w.busy.Busy()
+			w.logger.WarnContext(ctx, "backend busy, delaying polling", "error", err, "delay", delay)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// execute executes the task, extending its lock every HeartbeatInterval while it is running. Extension has stopped
+// when execute returns, so a late extension cannot lock the task again after it has been completed. If the lock is
+// lost, the context of the execution is canceled and errTaskLockLost is returned.
+func (w *Worker[Task, TaskResult]) execute(ctx context.Context, t *Task) (*TaskResult, error) {
+	if w.options.HeartbeatInterval <= 0 {
+		return w.run(ctx, t)
+	}
+
+	executeCtx, cancelExecute := context.WithCancel(ctx)
+	defer cancelExecute()
+
+	heartbeatCtx, cancelHeartbeat := context.WithCancel(ctx)
+	heartbeatDone := make(chan struct{})
+
+	var lockLost atomic.Bool
+
+	go func() {
+		defer close(heartbeatDone)
+
+		if !w.heartbeatTask(heartbeatCtx, t) {
+			lockLost.Store(true)
+			cancelExecute()
+		}
+	}()
+
+	result, err := w.run(executeCtx, t)
+
+	cancelHeartbeat()
+	<-heartbeatDone
+
+	if lockLost.Load() {
+		return nil, errTaskLockLost
+	}
+
+	return result, err
 }
 
-func (w *Worker[Task, TaskResult]) heartbeatTask(ctx context.Context, task *Task) {
+// run executes the task. If the task worker limits tasks itself, its limit is acquired before one of the
+// MaxParallelTasks slots, so tasks waiting for their limit don't keep other tasks from executing.
+func (w *Worker[Task, TaskResult]) run(ctx context.Context, t *Task) (*TaskResult, error) {
+	if l, ok := w.tw.(TaskLimiter[Task]); ok {
+		release, err := l.Acquire(ctx, t)
+		if err != nil {
+			return nil, fmt.Errorf("waiting for task limit: %w", err)
+		}
+		defer release()
+	}
+
+	if w.slots != nil {
+		select {
+		case w.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		defer func() { <-w.slots }()
+	}
+
+	return w.tw.Execute(ctx, t)
+}
+
+// heartbeatTask extends the lock of the task until the context is canceled. It returns false if the lock was lost.
+func (w *Worker[Task, TaskResult]) heartbeatTask(ctx context.Context, task *Task) bool {
 	t := time.NewTicker(w.options.HeartbeatInterval)
 	defer t.Stop()
 
+	// Tasks are locked when they are handed out, this is a close enough approximation
+	lockedAt := time.Now()
+
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			return true
 		case <-t.C:
-			if err := w.tw.Extend(ctx, task); err != nil {
-				w.logger.ErrorContext(ctx, "could not heartbeat task", "error", err)
+			if !w.extendTask(ctx, task, &lockedAt) {
+				return false
 			}
 		}
 	}
 }
 
+// extendTask extends the lock of the task, retrying failed extensions with HeartbeatRetryBackoff. It returns false if
+// the lock was lost, either because the backend reported it or because it could not be extended within LockTimeout.
+func (w *Worker[Task, TaskResult]) extendTask(ctx context.Context, task *Task, lockedAt *time.Time) bool {
+	retryBackoff := backoff.NewExponentialBackOff()
+	retryBackoff.InitialInterval = w.options.HeartbeatRetryBackoff
+	retryBackoff.MaxInterval = w.options.HeartbeatInterval
+	retryBackoff.MaxElapsedTime = 0
+	retryBackoff.Reset()
+
+	for {
+		err := w.tw.Extend(ctx, task)
+		if err == nil {
+			*lockedAt = time.Now()
+			return true
+		}
+
+		if ctx.Err() != nil {
+			// Execution finished while extending
+			return true
+		}
+
+		if errors.Is(err, backend.ErrTaskLockLost) {
+			return false
+		}
+
+		if w.options.LockTimeout > 0 && time.Since(*lockedAt) >= w.options.LockTimeout {
+			w.logger.ErrorContext(ctx, "could not heartbeat task before its lock expired", "error", err)
+			return false
+		}
+
+		if w.options.HeartbeatRetryBackoff <= 0 {
+			w.logger.ErrorContext(ctx, "could not heartbeat task", "error", err)
+			return true
+		}
+
+		delay := retryBackoff.NextBackOff()
+		w.logger.WarnContext(ctx, "could not heartbeat task, retrying", "error", err, "delay", delay)
+
+		select {
+		case <-ctx.Done():
+			return true
+		case <-time.After(delay):
+		}
+	}
+}
+
+// abandon gives up a task whose lock was lost without completing it
+func (w *Worker[Task, TaskResult]) abandon(ctx context.Context, t *Task) {
+	if a, ok := w.tw.(TaskAbandoner[Task]); ok {
+		a.Abandon(ctx, t)
+		return
+	}
+
+	w.logger.WarnContext(ctx, "lost task lock, abandoning task")
+}
+
 func (w *Worker[Task, TaskResult]) poll(ctx context.Context, timeout time.Duration) (*Task, error) {
 	if timeout == 0 {
 		timeout = 30 * time.Second
@@ -0,0 +1,387 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+type heartbeatTaskWorker struct {
+	mu sync.Mutex
+
+	executeFor time.Duration
+
+	extensions int
+	extending  bool
+	completed  bool
+
+	// extendedDuringCompletion is set if an extension was in flight or started after completion began
+	extendedDuringCompletion bool
+}
+
+func (tw *heartbeatTaskWorker) Start(context.Context, []workflow.Queue) error { return nil }
+
+func (tw *heartbeatTaskWorker) Get(context.Context, []workflow.Queue) (*int, error) { return nil, nil }
+
+func (tw *heartbeatTaskWorker) Extend(ctx context.Context, task *int) error {
+	tw.mu.Lock()
+	tw.extensions++
+	tw.extending = true
+	if tw.completed {
+		tw.extendedDuringCompletion = true
+	}
+	tw.mu.Unlock()
+
+	time.Sleep(2 * time.Millisecond)
+
+	tw.mu.Lock()
+	tw.extending = false
+	tw.mu.Unlock()
+
+	return nil
+}
+
+func (tw *heartbeatTaskWorker) Execute(ctx context.Context, task *int) (*int, error) {
+	time.Sleep(tw.executeFor)
+	return task, nil
+}
+
+func (tw *heartbeatTaskWorker) Complete(ctx context.Context, result *int, task *int) error {
+	tw.mu.Lock()
+	tw.completed = true
+	if tw.extending {
+		tw.extendedDuringCompletion = true
+	}
+	tw.mu.Unlock()
+
+	// Give a heartbeat that is still running the chance to extend the completed task
+	time.Sleep(10 * time.Millisecond)
+
+	return nil
+}
+
+func Test_Worker_ExtendsTaskWhileExecuting(t *testing.T) {
+	tw := &heartbeatTaskWorker{executeFor: 50 * time.Millisecond}
+
+	w := &Worker[int, int]{
+		tw:      tw,
+		options: &WorkerOptions{HeartbeatInterval: 5 * time.Millisecond},
+		logger:  slog.Default(),
+	}
+
+	task := 1
+	require.NoError(t, w.handle(context.Background(), &task))
+
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	require.GreaterOrEqual(t, tw.extensions, 3)
+	require.False(t, tw.extendedDuringCompletion)
+}
+
+func Test_Worker_NoHeartbeat(t *testing.T) {
+	tw := &heartbeatTaskWorker{executeFor: 20 * time.Millisecond}
+
+	w := &Worker[int, int]{
+		tw:      tw,
+		options: &WorkerOptions{},
+		logger:  slog.Default(),
+	}
+
+	task := 1
+	require.NoError(t, w.handle(context.Background(), &task))
+	require.Zero(t, tw.extensions)
+}
+
+// lockLossTaskWorker simulates a task worker whose task lock cannot be extended
+type lockLossTaskWorker struct {
+	mu sync.Mutex
+
+	// extend is called for every extension
+	extend func(attempt int) error
+
+	extensions int
+	canceled   bool
+	completed  bool
+	abandoned  bool
+}
+
+func (tw *lockLossTaskWorker) Start(context.Context, []workflow.Queue) error { return nil }
+
+func (tw *lockLossTaskWorker) Get(context.Context, []workflow.Queue) (*int, error) { return nil, nil }
+
+func (tw *lockLossTaskWorker) Extend(ctx context.Context, task *int) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.extensions++
+	return tw.extend(tw.extensions)
+}
+
+func (tw *lockLossTaskWorker) Execute(ctx context.Context, task *int) (*int, error) {
+	select {
+	case <-ctx.Done():
+		tw.mu.Lock()
+		tw.canceled = true
+		tw.mu.Unlock()
+
+		return nil, ctx.Err()
+	case <-time.After(100 * time.Millisecond):
+		return task, nil
+	}
+}
+
+func (tw *lockLossTaskWorker) Complete(ctx context.Context, result *int, task *int) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.completed = true
+	return nil
+}
+
+func (tw *lockLossTaskWorker) Abandon(ctx context.Context, task *int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.abandoned = true
+}
+
+func Test_Worker_LockLoss(t *testing.T) {
+	errTransient := errors.New("transient error")
+
+	tests := []struct {
+		name          string
+		options       WorkerOptions
+		extend        func(attempt int) error
+		wantAbandoned bool
+	}{
+		{
+			name:    "LockLostMidTask",
+			options: WorkerOptions{HeartbeatInterval: 5 * time.Millisecond},
+			extend: func(attempt int) error {
+				if attempt > 1 {
+					return backend.ErrTaskLockLost
+				}
+
+				return nil
+			},
+			wantAbandoned: true,
+		},
+		{
+			name:    "RetriesFailedExtensions",
+			options: WorkerOptions{HeartbeatInterval: 20 * time.Millisecond, HeartbeatRetryBackoff: time.Millisecond, LockTimeout: time.Second},
+			extend: func(attempt int) error {
+				if attempt%3 != 0 {
+					return errTransient
+				}
+
+				return nil
+			},
+		},
+		{
+			name:    "LockExpires",
+			options: WorkerOptions{HeartbeatInterval: 5 * time.Millisecond, HeartbeatRetryBackoff: time.Millisecond, LockTimeout: 20 * time.Millisecond},
+			extend: func(attempt int) error {
+				return errTransient
+			},
+			wantAbandoned: true,
+		},
+		{
+			name:    "WithoutLockTimeout",
+			options: WorkerOptions{HeartbeatInterval: 5 * time.Millisecond},
+			extend: func(attempt int) error {
+				return errTransient
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tw := &lockLossTaskWorker{extend: tt.extend}
+
+			w := &Worker[int, int]{
+				tw:      tw,
+				options: &tt.options,
+				logger:  slog.Default(),
+			}
+
+			task := 1
+			require.NoError(t, w.handle(context.Background(), &task))
+
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			require.Greater(t, tw.extensions, 1)
+			require.Equal(t, tt.wantAbandoned, tw.abandoned)
+			require.Equal(t, tt.wantAbandoned, tw.canceled)
+			require.Equal(t, !tt.wantAbandoned, tw.completed)
+		})
+	}
+}
+
+// limitedTaskWorker allows only one odd task to execute at a time, the first odd task blocks until unblock is closed
+type limitedTaskWorker struct {
+	limit   chan struct{}
+	unblock chan struct{}
+
+	executed chan int
+}
+
+func (tw *limitedTaskWorker) Start(context.Context, []workflow.Queue) error { return nil }
+
+func (tw *limitedTaskWorker) Get(context.Context, []workflow.Queue) (*int, error) { return nil, nil }
+
+func (tw *limitedTaskWorker) Extend(ctx context.Context, task *int) error { return nil }
+
+func (tw *limitedTaskWorker) Acquire(ctx context.Context, task *int) (func(), error) {
+	if *task%2 == 0 {
+		return func() {}, nil
+	}
+
+	select {
+	case tw.limit <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return func() { <-tw.limit }, nil
+}
+
+func (tw *limitedTaskWorker) Execute(ctx context.Context, task *int) (*int, error) {
+	if *task == 1 {
+		<-tw.unblock
+	}
+
+	tw.executed <- *task
+	return task, nil
+}
+
+func (tw *limitedTaskWorker) Complete(ctx context.Context, result *int, task *int) error { return nil }
+
+func Test_Worker_TaskLimitDoesNotBlockOtherTasks(t *testing.T) {
+	tw := &limitedTaskWorker{
+		limit:    make(chan struct{}, 1),
+		unblock:  make(chan struct{}),
+		executed: make(chan int, 3),
+	}
+
+	b := &backend.MockBackend{}
+	b.On("Options").Return(backend.ApplyOptions())
+
+	w := NewWorker[int, int](b, tw, &WorkerOptions{MaxParallelTasks: 2})
+	go w.dispatcher()
+
+	// Task 3 waits for the limit held by task 1, it must not keep task 2 from executing
+	for _, task := range []int{1, 3, 2} {
+		task := task
+		w.taskQueue <- &task
+	}
+
+	select {
+	case task := <-tw.executed:
+		require.Equal(t, 2, task)
+	case <-time.After(time.Second):
+		require.FailNow(t, "task was blocked by a task waiting for its limit")
+	}
+
+	close(tw.unblock)
+	require.Equal(t, 1, <-tw.executed)
+	require.Equal(t, 3, <-tw.executed)
+
+	close(w.taskQueue)
+	<-w.dispatcherDone
+}
+
+// busyTaskWorker reports the backend as busy when completing the first task, and blocks all other tasks until
+// they are released
+type busyTaskWorker struct {
+	mu sync.Mutex
+
+	running    int
+	maxRunning int
+
+	release chan struct{}
+}
+
+func (tw *busyTaskWorker) Start(context.Context, []workflow.Queue) error { return nil }
+
+func (tw *busyTaskWorker) Get(context.Context, []workflow.Queue) (*int, error) { return nil, nil }
+
+func (tw *busyTaskWorker) Extend(ctx context.Context, task *int) error { return nil }
+
+func (tw *busyTaskWorker) Execute(ctx context.Context, task *int) (*int, error) {
+	if *task == 0 {
+		return task, nil
+	}
+
+	tw.mu.Lock()
+	tw.running++
+	tw.maxRunning = max(tw.maxRunning, tw.running)
+	tw.mu.Unlock()
+
+	<-tw.release
+
+	tw.mu.Lock()
+	tw.running--
+	tw.mu.Unlock()
+
+	return task, nil
+}
+
+func (tw *busyTaskWorker) Complete(ctx context.Context, result *int, task *int) error {
+	if *task == 0 {
+		return backend.ErrBackendBusy
+	}
+
+	return nil
+}
+
+func Test_Worker_BusyBackendReducesConcurrency(t *testing.T) {
+	tw := &busyTaskWorker{release: make(chan struct{})}
+
+	b := &backend.MockBackend{}
+	b.On("Options").Return(backend.ApplyOptions())
+
+	w := NewWorker[int, int](b, tw, &WorkerOptions{MaxParallelTasks: 4})
+	w.busy.b.InitialInterval = time.Minute
+	w.busy.b.Reset()
+	go w.dispatcher()
+
+	// Completing the first task reports the backend as busy
+	task := 0
+	w.taskQueue <- &task
+	require.Eventually(t, func() bool { return w.busy.Remaining() > 0 }, time.Second, time.Millisecond)
+
+	go func() {
+		for i := 1; i <= 4; i++ {
+			task := i
+			w.taskQueue <- &task
+		}
+
+		close(w.taskQueue)
+	}()
+
+	// Only half of the slots are used while the worker backs off
+	require.Eventually(t, func() bool {
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+
+		return tw.running == 2
+	}, time.Second, time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+
+	tw.mu.Lock()
+	require.Equal(t, 2, tw.maxRunning)
+	tw.mu.Unlock()
+
+	close(tw.release)
+	<-w.dispatcherDone
+}
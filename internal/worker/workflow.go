@@ -15,6 +15,7 @@ import (
 	"github.com/cschleiden/go-workflows/internal/log"
 	"github.com/cschleiden/go-workflows/internal/metrickeys"
 	im "github.com/cschleiden/go-workflows/internal/metrics"
+	"github.com/cschleiden/go-workflows/internal/propagators"
 	"github.com/cschleiden/go-workflows/registry"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/cschleiden/go-workflows/workflow/executor"
@@ -27,6 +28,22 @@ type WorkflowWorkerOptions struct {
 	WorkflowExecutorCache     executor.Cache
 	WorkflowExecutorCacheSize int
 	WorkflowExecutorCacheTTL  time.Duration
+
+	WorkflowTaskTimeout time.Duration
+
+	MaxWorkflowResultSize int
+
+	MaxWorkflowIterations int
+
+	MaxWorkflowTaskFailures int
+
+	// LocalActivities executes activities scheduled by workflow tasks in the same process. If nil, all activities are
+	// scheduled via the backend.
+	LocalActivities LocalActivityExecutor
+
+	// BeforeTask and AfterTask are called around the execution of every workflow task
+	BeforeTask func(ctx context.Context, task *backend.WorkflowTask)
+	AfterTask  func(ctx context.Context, task *backend.WorkflowTask, err error)
 }
 
 func NewWorkflowWorker(
@@ -43,6 +60,15 @@ func NewWorkflowWorker(
 		registry: registry,
 		cache:    options.WorkflowExecutorCache,
 		logger:   b.Options().Logger,
+
+		identity:        options.Identity,
+		taskTimeout:     options.WorkflowTaskTimeout,
+		maxResultSize:   options.MaxWorkflowResultSize,
+		maxIterations:   options.MaxWorkflowIterations,
+		maxTaskFailures: options.MaxWorkflowTaskFailures,
+		localActivities: options.LocalActivities,
+		beforeTask:      options.BeforeTask,
+		afterTask:       options.AfterTask,
 	}
 
 	return NewWorker(b, tw, &options.WorkerOptions)
@@ -53,6 +79,15 @@ type WorkflowTaskWorker struct {
 	registry *registry.Registry
 	cache    executor.Cache
 	logger   *slog.Logger
+
+	identity        string
+	taskTimeout     time.Duration
+	maxResultSize   int
+	maxIterations   int
+	maxTaskFailures int
+	localActivities LocalActivityExecutor
+	beforeTask      func(context.Context, *backend.WorkflowTask)
+	afterTask       func(context.Context, *backend.WorkflowTask, error)
 }
 
 func (wtw *WorkflowTaskWorker) Start(ctx context.Context, queues []workflow.Queue) error {
@@ -95,6 +130,16 @@ func (wtw *WorkflowTaskWorker) Complete(ctx context.Context, result *executor.Ex
 
 	wtw.backend.Metrics().Counter(metrickeys.ActivityTaskScheduled, metrics.Tags{}, int64(len(result.ActivityEvents)))
 
+	// Progress is stored together with the task completion, so it cannot overwrite progress reported by a later task
+	t.Progress = result.Progress
+
+	// Record which worker executed the task
+	for _, event := range result.Executed {
+		if a, ok := event.Attributes.(*history.WorkflowTaskStartedAttributes); ok {
+			a.Worker = wtw.identity
+		}
+	}
+
 	if err := wtw.backend.CompleteWorkflowTask(
 		ctx, t, state, result.Executed, result.ActivityEvents, result.TimerEvents, result.WorkflowEvents); err != nil {
 		logger.ErrorContext(ctx, "could not complete workflow task", "error", err)
@@ -105,6 +150,58 @@ func (wtw *WorkflowTaskWorker) Complete(ctx context.Context, result *executor.Ex
 }
 
 func (wtw *WorkflowTaskWorker) Execute(ctx context.Context, t *backend.WorkflowTask) (*executor.ExecutionResult, error) {
+	if wtw.beforeTask != nil {
+		wtw.runHook(ctx, t, "BeforeWorkflowTask", func() { wtw.beforeTask(ctx, t) })
+	}
+
+	result, err := wtw.execute(ctx, t)
+
+	if wtw.afterTask != nil {
+		wtw.runHook(ctx, t, "AfterWorkflowTask", func() { wtw.afterTask(ctx, t, err) })
+	}
+
+	// Tasks canceled because their lock was lost are abandoned, not failed
+	if err != nil && wtw.maxTaskFailures > 0 && ctx.Err() == nil {
+		wtw.fail(ctx, t)
+	}
+
+	return result, err
+}
+
+// fail records that executing a workflow task failed, the instance becomes errored once its tasks failed
+// maxTaskFailures times in a row
+func (wtw *WorkflowTaskWorker) fail(ctx context.Context, t *backend.WorkflowTask) {
+	if err := wtw.backend.FailWorkflowTask(ctx, t, wtw.maxTaskFailures); err != nil {
+		wtw.logger.ErrorContext(ctx, "could not record failed workflow task",
+			slog.String(log.TaskIDKey, t.ID),
+			slog.String(log.InstanceIDKey, t.WorkflowInstance.InstanceID),
+			"error", err,
+		)
+	}
+
+	// The executor might be in an inconsistent state, the next attempt starts from scratch
+	if err := wtw.cache.Evict(ctx, t.WorkflowInstance); err != nil {
+		wtw.logger.ErrorContext(ctx, "could not evict workflow executor from cache", "error", err)
+	}
+}
+
+// runHook calls a workflow task hook. A panicking hook is logged, it must not fail the task.
+func (wtw *WorkflowTaskWorker) runHook(ctx context.Context, t *backend.WorkflowTask, name string, hook func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			wtw.logger.ErrorContext(ctx, "workflow task hook panicked",
+				"hook", name,
+				slog.String(log.TaskIDKey, t.ID),
+				slog.String(log.InstanceIDKey, t.WorkflowInstance.InstanceID),
+				"panic", r,
+			)
+		}
+	}()
+
+	hook()
+}
+
+func (wtw *WorkflowTaskWorker) execute(ctx context.Context, t *backend.WorkflowTask) (*executor.ExecutionResult, error) {
 	// Record how long this task was in the queue
 	firstEvent := t.NewEvents[0]
 	var scheduledAt time.Time
@@ -126,19 +223,50 @@ func (wtw *WorkflowTaskWorker) Execute(ctx context.Context, t *backend.WorkflowT
 		metrickeys.EventName: eventName,
 	})
 
-	executor, err := wtw.getExecutor(ctx, t)
+	// If the instance has been reset, a cached executor has newer state than the task, start from scratch
+	for _, event := range t.NewEvents {
+		if event.Type == history.EventType_WorkflowExecutionReset {
+			if err := wtw.cache.Evict(ctx, t.WorkflowInstance); err != nil {
+				wtw.logger.ErrorContext(ctx, "could not evict workflow executor from cache", "error", err)
+			}
+
+			break
+		}
+	}
+
+	e, err := wtw.getExecutor(ctx, t)
 	if err != nil {
 		return nil, fmt.Errorf("getting executor: %w", err)
 	}
 
-	result, err := executor.ExecuteTask(ctx, t)
+	result, err := e.ExecuteTask(ctx, t)
 	if err != nil {
+		var ndErr *executor.NonDeterminismError
+		if errors.Is(err, executor.ErrWorkflowTaskTimeout) || errors.As(err, &ndErr) {
+			// The executor cannot be used anymore, ensure the next task for this instance starts from scratch
+			if err := wtw.cache.Evict(ctx, t.WorkflowInstance); err != nil {
+				wtw.logger.ErrorContext(ctx, "could not evict workflow executor from cache", "error", err)
+			}
+		}
+
 		return nil, fmt.Errorf("executing task: %w", err)
 	}
 
 	// Only record the time spent in the workflow code
 	timer.Stop()
 
+	if result.TimedOut {
+		// The workflow code of the task might still be running, the next task for this instance starts from scratch
+		if err := wtw.cache.Evict(ctx, t.WorkflowInstance); err != nil {
+			wtw.logger.ErrorContext(ctx, "could not evict workflow executor from cache", "error", err)
+		}
+	}
+
+	// Activities are executed while the task is still locked, their results are delivered when it is completed
+	if wtw.localActivities != nil && result.State == core.WorkflowInstanceStateActive {
+		wtw.executeLocalActivities(ctx, t, result)
+	}
+
 	return result, nil
 }
 
@@ -146,6 +274,20 @@ func (wtw *WorkflowTaskWorker) Extend(ctx context.Context, t *backend.WorkflowTa
 	return wtw.backend.ExtendWorkflowTask(ctx, t)
 }
 
+// Abandon gives up a workflow task whose lock was lost. Another worker executes the task again, so the cached
+// executor, which already executed it, cannot be used for the instance anymore.
+func (wtw *WorkflowTaskWorker) Abandon(ctx context.Context, t *backend.WorkflowTask) {
+	wtw.logger.WarnContext(ctx, "lost workflow task lock, abandoning task",
+		log.InstanceIDKey, t.WorkflowInstance.InstanceID,
+		log.ExecutionIDKey, t.WorkflowInstance.ExecutionID,
+		log.TaskIDKey, t.ID,
+	)
+
+	if err := wtw.cache.Evict(ctx, t.WorkflowInstance); err != nil {
+		wtw.logger.ErrorContext(ctx, "could not evict workflow executor from cache", "error", err)
+	}
+}
+
 func (wtw *WorkflowTaskWorker) Get(ctx context.Context, queues []workflow.Queue) (*backend.WorkflowTask, error) {
 	t, err := wtw.backend.GetWorkflowTask(ctx, queues)
 	if err != nil {
@@ -167,20 +309,25 @@ func (wtw *WorkflowTaskWorker) getExecutor(ctx context.Context, t *backend.Workf
 	}
 
 	if !ok {
-		e, err = executor.NewExecutor(
-			wtw.logger.With(
+		// Workflow instances are executed with the registry of their namespace
+		r, err := wtw.registry.Namespace(propagators.Namespace(t.Metadata))
+		if err != nil {
+			return nil, fmt.Errorf("creating workflow task executor: %w", err)
+		}
+
+		e, err = executor.NewExecutor(r, wtw.backend, t.WorkflowInstance, t.Metadata, executor.ExecutorOptions{
+			Logger: wtw.logger.With(
 				slog.String(log.InstanceIDKey, t.WorkflowInstance.InstanceID),
 				slog.String(log.ExecutionIDKey, t.WorkflowInstance.ExecutionID),
 			),
-			wtw.backend.Tracer(),
-			wtw.registry,
-			wtw.backend.Options().Converter,
-			wtw.backend.Options().ContextPropagators,
-			wtw.backend,
-			t.WorkflowInstance,
-			t.Metadata,
-			clock.New(),
-		)
+			Tracer:        wtw.backend.Tracer(),
+			Converter:     wtw.backend.Options().Converter,
+			Propagators:   wtw.backend.Options().ContextPropagators,
+			Clock:         clock.New(),
+			TaskTimeout:   wtw.taskTimeout,
+			MaxResultSize: wtw.maxResultSize,
+			MaxIterations: wtw.maxIterations,
+		})
 		if err != nil {
 			return nil, fmt.Errorf("creating workflow task executor: %w", err)
 		}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/backend/metadata"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/metrics"
+	"github.com/cschleiden/go-workflows/registry"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/cschleiden/go-workflows/workflow/executor/cache"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func Test_WorkflowTaskWorker_EvictsExecutorAfterTaskTimeout(t *testing.T) {
+	wf := func(ctx workflow.Context) error {
+		// Blocking call in workflow code
+		time.Sleep(time.Millisecond * 100)
+
+		return nil
+	}
+
+	r := registry.New()
+	require.NoError(t, r.RegisterWorkflow(wf, registry.WithName("wf")))
+
+	b := &backend.MockBackend{}
+	b.On("Metrics").Return(metrics.NewNoopMetricsClient())
+	b.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	b.On("Options").Return(backend.ApplyOptions())
+
+	c := cache.NewWorkflowExecutorLRUCache(metrics.NewNoopMetricsClient(), 10, time.Minute)
+
+	wtw := &WorkflowTaskWorker{
+		backend:     b,
+		registry:    r,
+		cache:       c,
+		logger:      slog.Default(),
+		taskTimeout: time.Millisecond * 10,
+	}
+
+	instance := core.NewWorkflowInstance("instanceID", "executionID")
+	task := &backend.WorkflowTask{
+		ID:               "taskID",
+		WorkflowInstance: instance,
+		Metadata:         &metadata.WorkflowMetadata{},
+		NewEvents: []*history.Event{
+			history.NewPendingEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+				Name: "wf",
+			}),
+		},
+	}
+
+	result, err := wtw.execute(context.Background(), task)
+	require.NoError(t, err)
+	require.True(t, result.TimedOut)
+
+	// The workflow code might still be running, the executor must not be used for the next task
+	_, ok, err := c.Get(context.Background(), instance)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
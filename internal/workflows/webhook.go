@@ -0,0 +1,122 @@
+package workflows
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/converter"
+	"github.com/cschleiden/go-workflows/backend/payload"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/workflowerrors"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// CompletionWebhook describes the outcome of a finished workflow execution, to be delivered to its webhook URL
+type CompletionWebhook struct {
+	URL      string                 `json:"url,omitempty"`
+	Instance *core.WorkflowInstance `json:"instance,omitempty"`
+	Result   payload.Payload        `json:"result,omitempty"`
+	Error    *workflowerrors.Error  `json:"error,omitempty"`
+}
+
+// CompletionWebhookRetryOptions are used for delivering completion webhooks
+var CompletionWebhookRetryOptions = workflow.RetryOptions{
+	MaxAttempts:        10,
+	FirstRetryInterval: 5 * time.Second,
+	MaxRetryInterval:   5 * time.Minute,
+	BackoffCoefficient: 2,
+}
+
+var webhookClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+// CompletionWebhookInstanceID returns the ID of the instance delivering the completion webhook of the given execution
+func CompletionWebhookInstanceID(instance *core.WorkflowInstance) string {
+	return fmt.Sprintf("completion-webhook:%s:%s", instance.InstanceID, instance.ExecutionID)
+}
+
+// DeliverCompletionWebhook posts the outcome of a finished workflow execution to its webhook URL, retrying failed
+// deliveries. Returns the HTTP status code of the successful delivery.
+func DeliverCompletionWebhook(ctx workflow.Context, w CompletionWebhook) (int, error) {
+	var a *Activities
+	return workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{
+		Queue:        core.QueueSystem,
+		RetryOptions: CompletionWebhookRetryOptions,
+	}, a.PostCompletionWebhook, w).Get(ctx)
+}
+
+// completionWebhookBody is the body of the webhook request
+type completionWebhookBody struct {
+	InstanceID  string                `json:"instance_id"`
+	ExecutionID string                `json:"execution_id"`
+	Result      json.RawMessage       `json:"result,omitempty"`
+	Error       *workflowerrors.Error `json:"error,omitempty"`
+}
+
+func (a *Activities) PostCompletionWebhook(ctx context.Context, w CompletionWebhook) (int, error) {
+	body := completionWebhookBody{
+		InstanceID:  w.Instance.InstanceID,
+		ExecutionID: w.Instance.ExecutionID,
+		Error:       w.Error,
+	}
+
+	result := w.Result
+
+	// Post the result as serialized by the converter, without compression or encryption
+	if cc, ok := a.Backend.Options().Converter.(*converter.CodecConverter); ok {
+		var err error
+		if result, err = cc.Decode(result); err != nil {
+			return 0, workflow.NewPermanentError(fmt.Errorf("decoding result: %w", err))
+		}
+	}
+
+	if len(result) > 0 {
+		if json.Valid(result) {
+			body.Result = json.RawMessage(result)
+		} else {
+			// Results of non-JSON converters are passed base64 encoded
+			r, err := json.Marshal([]byte(result))
+			if err != nil {
+				return 0, workflow.NewPermanentError(fmt.Errorf("encoding result: %w", err))
+			}
+
+			body.Result = r
+		}
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return 0, workflow.NewPermanentError(fmt.Errorf("encoding webhook body: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(b))
+	if err != nil {
+		return 0, workflow.NewPermanentError(fmt.Errorf("creating webhook request: %w", err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("posting webhook: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return res.StatusCode, nil
+
+	case res.StatusCode >= 400 && res.StatusCode < 500 &&
+		res.StatusCode != http.StatusRequestTimeout && res.StatusCode != http.StatusTooManyRequests:
+		// The request will not succeed when repeated
+		return 0, workflow.NewPermanentError(fmt.Errorf("webhook rejected with status %d", res.StatusCode))
+
+	default:
+		return 0, fmt.Errorf("webhook failed with status %d", res.StatusCode)
+	}
+}
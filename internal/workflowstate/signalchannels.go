@@ -49,11 +49,15 @@ func GetSignalChannel[T any](ctx sync.Context, wf *WfState, name string) sync.Ch
 		channel: c,
 	}
 
-	// Check for any pending signals, if there are, send to the channel in reverse order
+	// Check for any pending signals, if there are, send them to the channel in the order they were received. Executions
+	// started before signals were ordered receive them in reverse order.
 	pendingSignals, ok := wf.pendingSignals[name]
 	if ok {
-		for i := len(pendingSignals) - 1; i >= 0; i-- {
+		for i := range pendingSignals {
 			payload := pendingSignals[i]
+			if !wf.orderedSignals {
+				payload = pendingSignals[len(pendingSignals)-1-i]
+			}
 
 			var s T
 			if err := converter.From(payload, &s); err != nil {
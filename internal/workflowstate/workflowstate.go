@@ -60,14 +60,35 @@ type WfState struct {
 	pendingFutures  map[int64]*DecodingSettable
 	replaying       bool
 
+	inputs []payload.Payload
+
 	pendingSignals map[string][]payload.Payload
 	signalChannels map[string]*signalChannel
 
+	// orderedSignals is set if buffered signals are delivered in the order they were received
+	orderedSignals bool
+
+	// memos holds values memoized by the workflow, they are never persisted
+	memos map[string]interface{}
+
+	// progress is the latest progress reported by the workflow during the current task, it is persisted outside of
+	// the history
+	progress payload.Payload
+
+	// sequences holds the last value of each named sequence, they are rebuilt by replaying the workflow
+	sequences map[string]int
+
+	// versions holds the version of each change to the workflow code, they are read from the history before replaying
+	versions map[string]int
+
 	logger *slog.Logger
 	tracer trace.Tracer
 
 	clock clock.Clock
 	time  time.Time
+
+	executionTimeout time.Duration
+	deadline         time.Time
 }
 
 func NewWorkflowState(instance *core.WorkflowInstance, logger *slog.Logger, tracer trace.Tracer, clock clock.Clock) *WfState {
@@ -79,6 +100,9 @@ func NewWorkflowState(instance *core.WorkflowInstance, logger *slog.Logger, trac
 
 		pendingSignals: map[string][]payload.Payload{},
 		signalChannels: make(map[string]*signalChannel),
+		memos:          map[string]interface{}{},
+		sequences:      map[string]int{},
+		versions:       map[string]int{},
 
 		tracer: tracer,
 
@@ -158,6 +182,81 @@ func (wf *WfState) Replaying() bool {
 	return wf.replaying
 }
 
+func (wf *WfState) Memo(key string) (interface{}, bool) {
+	v, ok := wf.memos[key]
+	return v, ok
+}
+
+func (wf *WfState) SetMemo(key string, v interface{}) {
+	wf.memos[key] = v
+}
+
+func (wf *WfState) NextSequence(name string) int {
+	wf.sequences[name]++
+	return wf.sequences[name]
+}
+
+// SetProgress records the latest progress of the workflow. Progress reported while replaying has already been
+// persisted and is ignored.
+func (wf *WfState) SetProgress(progress payload.Payload) {
+	if wf.replaying {
+		return
+	}
+
+	wf.progress = progress
+}
+
+// TakeProgress returns the progress reported since it was last taken, or nil if no progress was reported
+func (wf *WfState) TakeProgress() payload.Payload {
+	progress := wf.progress
+	wf.progress = nil
+	return progress
+}
+
+func (wf *WfState) SetInputs(inputs []payload.Payload) {
+	wf.inputs = inputs
+}
+
+func (wf *WfState) Inputs() []payload.Payload {
+	return wf.inputs
+}
+
+// Version returns the version of the change with the given ID, if it is known
+func (wf *WfState) Version(changeID string) (int, bool) {
+	v, ok := wf.versions[changeID]
+	return v, ok
+}
+
+// SetVersion sets the version of the change with the given ID
+func (wf *WfState) SetVersion(changeID string, version int) {
+	wf.versions[changeID] = version
+}
+
+// SetOrderedSignals sets whether buffered signals are delivered in the order they were received, or, for executions
+// started by earlier versions, in reverse order.
+func (wf *WfState) SetOrderedSignals(ordered bool) {
+	wf.orderedSignals = ordered
+}
+
+// SetExecutionTimeout sets the time budget of the execution, and derives the deadline of the execution from the
+// recorded time the execution was started at.
+func (wf *WfState) SetExecutionTimeout(started time.Time, timeout time.Duration) {
+	wf.executionTimeout = timeout
+
+	if timeout > 0 {
+		wf.deadline = started.Add(timeout)
+	}
+}
+
+func (wf *WfState) ExecutionTimeout() time.Duration {
+	return wf.executionTimeout
+}
+
+// Deadline returns the deadline of the execution, zero if it does not have one
+func (wf *WfState) Deadline() time.Time {
+	return wf.deadline
+}
+
 func (wf *WfState) SetTime(t time.Time) {
 	wf.time = t
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend/converter"
 	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/contextvalue"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -29,3 +30,52 @@ func Test_PendingFutures(t *testing.T) {
 
 	require.False(t, wfState.HasPendingFutures())
 }
+
+func Test_PendingSignals_Order(t *testing.T) {
+	tests := []struct {
+		name     string
+		ordered  bool
+		expected []string
+	}{
+		{"ordered", true, []string{"a", "b", "c"}},
+		// Executions started before signals were ordered keep receiving them in reverse order
+		{"legacy", false, []string{"c", "b", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := core.NewWorkflowInstance(uuid.NewString(), "")
+
+			wfState := NewWorkflowState(i, slog.Default(), noop.NewTracerProvider().Tracer("test"), clock.New())
+			wfState.SetOrderedSignals(tt.ordered)
+
+			// Signals received before the workflow created the channel
+			for _, s := range []string{"a", "b", "c"} {
+				p, err := converter.DefaultConverter.To(s)
+				require.NoError(t, err)
+
+				ReceiveSignal(wfState, "signal", p)
+			}
+
+			received := []string{}
+
+			ctx := contextvalue.WithConverter(sync.Background(), converter.DefaultConverter)
+			cr := sync.NewCoroutine(ctx, func(ctx sync.Context) error {
+				c := GetSignalChannel[string](ctx, wfState, "signal")
+
+				for {
+					s, ok := c.ReceiveNonBlocking()
+					if !ok {
+						return nil
+					}
+
+					received = append(received, s)
+				}
+			})
+			cr.Execute()
+
+			require.True(t, cr.Finished())
+			require.Equal(t, tt.expected, received)
+		})
+	}
+}
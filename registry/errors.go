@@ -31,3 +31,11 @@ type ErrActivityAlreadyRegistered struct {
 func (e *ErrActivityAlreadyRegistered) Error() string {
 	return e.msg
 }
+
+type ErrNamespaceAlreadyRegistered struct {
+	msg string
+}
+
+func (e *ErrNamespaceAlreadyRegistered) Error() string {
+	return e.msg
+}
@@ -1,10 +1,13 @@
 package registry
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/fn"
@@ -16,18 +19,72 @@ type Registry struct {
 
 	workflowMap map[string]wf.Workflow
 	activityMap map[string]interface{}
+
+	activityRetryOptions     map[string]wf.RetryOptions
+	activityValidators       map[string]ActivityValidator
+	activityResultValidators map[string]ActivityResultValidator
+	activityResultTTLs       map[string]time.Duration
+	activityConcurrency      map[string]int
+	activityGuarantees       map[string]ExecutionGuarantee
+
+	namespaces map[string]*Registry
 }
 
+// ActivityValidator validates the inputs of an activity before it is executed. args are the deserialized
+// inputs of the activity, without the context. Returning an error fails the activity without retries.
+type ActivityValidator func(ctx context.Context, args ...interface{}) error
+
+// ActivityResultValidator validates the result of a successful activity execution before it is recorded. result is
+// the value returned by the activity, nil for activities only returning an error. Returning an error fails the
+// execution, which is retried like any other failed execution. Errors created with workflow.NewPermanentError fail the
+// activity without retries.
+type ActivityResultValidator func(ctx context.Context, result interface{}) error
+
+// ExecutionGuarantee declares whether an activity may be executed again when its task is delivered again, e.g.,
+// after a worker crashed while executing it.
+type ExecutionGuarantee int
+
+const (
+	// AtLeastOnce executes an activity again when its task is delivered again. This is the default, activities
+	// should be idempotent.
+	AtLeastOnce ExecutionGuarantee = iota
+
+	// AtMostOnce records that an activity has been started before executing it. If its task is delivered again
+	// without the execution having completed, the activity fails instead of being executed again.
+	AtMostOnce
+)
+
 // New creates a new registry instance.
 func New() *Registry {
 	return &Registry{
 		workflowMap: make(map[string]wf.Workflow),
 		activityMap: make(map[string]interface{}),
+
+		activityRetryOptions:     make(map[string]wf.RetryOptions),
+		activityValidators:       make(map[string]ActivityValidator),
+		activityResultValidators: make(map[string]ActivityResultValidator),
+		activityResultTTLs:       make(map[string]time.Duration),
+		activityConcurrency:      make(map[string]int),
+		activityGuarantees:       make(map[string]ExecutionGuarantee),
+
+		namespaces: make(map[string]*Registry),
 	}
 }
 
 type registerConfig struct {
 	Name string
+
+	RetryOptions *wf.RetryOptions
+
+	Validator ActivityValidator
+
+	ResultValidator ActivityResultValidator
+
+	ResultCacheTTL time.Duration
+
+	MaxConcurrency int
+
+	ExecutionGuarantee ExecutionGuarantee
 }
 
 func (r *Registry) RegisterWorkflow(workflow wf.Workflow, opts ...RegisterOption) error {
@@ -54,13 +111,8 @@ func (r *Registry) RegisterWorkflow(workflow wf.Workflow, opts ...RegisterOption
 		return &ErrInvalidWorkflow{"workflow must return error"}
 	}
 
-	if wfType.NumOut() > 2 {
-		return &ErrInvalidWorkflow{"workflow must return at most two values"}
-	}
-
 	errType := reflect.TypeOf((*error)(nil)).Elem()
-	if (wfType.NumOut() == 1 && !wfType.Out(0).Implements(errType)) ||
-		(wfType.NumOut() == 2 && !wfType.Out(1).Implements(errType)) {
+	if !wfType.Out(wfType.NumOut() - 1).Implements(errType) {
 		return &ErrInvalidWorkflow{"workflow must return error as last return value"}
 	}
 
@@ -82,7 +134,7 @@ func (r *Registry) RegisterActivity(activity wf.Activity, opts ...RegisterOption
 
 	// Activities on struct
 	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
-		return r.registerActivitiesFromStruct(activity)
+		return r.registerActivitiesFromStruct(activity, cfg)
 	}
 
 	// Activity as function
@@ -103,10 +155,34 @@ func (r *Registry) RegisterActivity(activity wf.Activity, opts ...RegisterOption
 	}
 	r.activityMap[name] = activity
 
+	if cfg.RetryOptions != nil {
+		r.activityRetryOptions[name] = *cfg.RetryOptions
+	}
+
+	if cfg.Validator != nil {
+		r.activityValidators[name] = cfg.Validator
+	}
+
+	if cfg.ResultValidator != nil {
+		r.activityResultValidators[name] = cfg.ResultValidator
+	}
+
+	if cfg.ResultCacheTTL > 0 {
+		r.activityResultTTLs[name] = cfg.ResultCacheTTL
+	}
+
+	if cfg.MaxConcurrency > 0 {
+		r.activityConcurrency[name] = cfg.MaxConcurrency
+	}
+
+	if cfg.ExecutionGuarantee != AtLeastOnce {
+		r.activityGuarantees[name] = cfg.ExecutionGuarantee
+	}
+
 	return nil
 }
 
-func (r *Registry) registerActivitiesFromStruct(a interface{}) error {
+func (r *Registry) registerActivitiesFromStruct(a interface{}, cfg registerConfig) error {
 	// Enumerate functions defined on a
 	v := reflect.ValueOf(a)
 	t := v.Type()
@@ -129,6 +205,30 @@ func (r *Registry) registerActivitiesFromStruct(a interface{}) error {
 
 		name := mt.Name
 		r.activityMap[name] = mv.Interface()
+
+		if cfg.RetryOptions != nil {
+			r.activityRetryOptions[name] = *cfg.RetryOptions
+		}
+
+		if cfg.Validator != nil {
+			r.activityValidators[name] = cfg.Validator
+		}
+
+		if cfg.ResultValidator != nil {
+			r.activityResultValidators[name] = cfg.ResultValidator
+		}
+
+		if cfg.ResultCacheTTL > 0 {
+			r.activityResultTTLs[name] = cfg.ResultCacheTTL
+		}
+
+		if cfg.MaxConcurrency > 0 {
+			r.activityConcurrency[name] = cfg.MaxConcurrency
+		}
+
+		if cfg.ExecutionGuarantee != AtLeastOnce {
+			r.activityGuarantees[name] = cfg.ExecutionGuarantee
+		}
 	}
 
 	return nil
@@ -172,3 +272,132 @@ func (r *Registry) GetActivity(name string) (interface{}, error) {
 
 	return nil, errors.New("activity not found")
 }
+
+// RegisterNamespace registers the registry serving the workflows and activities of the given namespace. Names only
+// have to be unique within a namespace, so independently developed workflows can be hosted by the same worker.
+func (r *Registry) RegisterNamespace(namespace string, registry *Registry) error {
+	if namespace == "" {
+		return errors.New("namespace must not be empty")
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.namespaces[namespace]; ok {
+		return &ErrNamespaceAlreadyRegistered{fmt.Sprintf("namespace %q already registered", namespace)}
+	}
+	r.namespaces[namespace] = registry
+
+	return nil
+}
+
+// Namespace returns the registry serving the given namespace. The empty namespace is served by the registry itself.
+func (r *Registry) Namespace(namespace string) (*Registry, error) {
+	if namespace == "" {
+		return r, nil
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if registry, ok := r.namespaces[namespace]; ok {
+		return registry, nil
+	}
+
+	return nil, fmt.Errorf("namespace %q not found", namespace)
+}
+
+// Workflows returns the names of all registered workflows, sorted by name.
+func (r *Registry) Workflows() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	names := make([]string, 0, len(r.workflowMap))
+	for name := range r.workflowMap {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// Activities returns the names of all registered activities, sorted by name.
+func (r *Registry) Activities() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	names := make([]string, 0, len(r.activityMap))
+	for name := range r.activityMap {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// HasWorkflow returns whether a workflow with the given name is registered.
+func (r *Registry) HasWorkflow(name string) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	_, ok := r.workflowMap[name]
+	return ok
+}
+
+// ActivityRetryOptions returns the default retry options registered for the activity with the given name, if any.
+func (r *Registry) ActivityRetryOptions(name string) (wf.RetryOptions, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	retryOptions, ok := r.activityRetryOptions[name]
+	return retryOptions, ok
+}
+
+// ActivityValidator returns the input validator registered for the activity with the given name, if any.
+func (r *Registry) ActivityValidator(name string) (ActivityValidator, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	validator, ok := r.activityValidators[name]
+	return validator, ok
+}
+
+// ActivityResultValidator returns the result validator registered for the activity with the given name, if any.
+func (r *Registry) ActivityResultValidator(name string) (ActivityResultValidator, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	validator, ok := r.activityResultValidators[name]
+	return validator, ok
+}
+
+// ActivityResultCacheTTL returns how long results of the activity with the given name are cached, if caching has
+// been enabled for it.
+func (r *Registry) ActivityResultCacheTTL(name string) (time.Duration, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	ttl, ok := r.activityResultTTLs[name]
+	return ttl, ok
+}
+
+// ActivityMaxConcurrency returns the maximum number of concurrent executions of the activity with the given name per
+// worker, if a limit has been registered for it.
+func (r *Registry) ActivityMaxConcurrency(name string) (int, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	limit, ok := r.activityConcurrency[name]
+	return limit, ok
+}
+
+// ActivityExecutionGuarantee returns the execution guarantee registered for the activity with the given name.
+// Defaults to AtLeastOnce.
+func (r *Registry) ActivityExecutionGuarantee(name string) ExecutionGuarantee {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.activityGuarantees[name]
+}
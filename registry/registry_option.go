@@ -1,5 +1,11 @@
 package registry
 
+import (
+	"time"
+
+	wf "github.com/cschleiden/go-workflows/workflow"
+)
+
 type RegisterOption interface {
 	applyRegisterOption(registerConfig) registerConfig
 }
@@ -19,6 +25,72 @@ func (f registerOptionFunc) applyRegisterOption(cfg registerConfig) registerConf
 	return f(cfg)
 }
 
+// WithRetryOptions registers default retry options for an activity. They are used for every execution of the
+// activity that does not specify custom retry options.
+func WithRetryOptions(retryOptions wf.RetryOptions) RegisterOption {
+	return registerOptionFunc(func(cfg registerConfig) registerConfig {
+		cfg.RetryOptions = &retryOptions
+		return cfg
+	})
+}
+
+// WithValidator registers a validator for the inputs of an activity. The validator is called with the
+// deserialized inputs before the activity is executed. If it returns an error, the activity fails without
+// being executed and is not retried.
+func WithValidator(validator ActivityValidator) RegisterOption {
+	return registerOptionFunc(func(cfg registerConfig) registerConfig {
+		cfg.Validator = validator
+		return cfg
+	})
+}
+
+// WithResultValidator registers a validator for the results of an activity. The validator is called after the
+// activity returned successfully, before its result is recorded. If it returns an error, the execution fails and is
+// retried according to the retry options of the activity, unless the error has been created with
+// workflow.NewPermanentError.
+func WithResultValidator(validator ActivityResultValidator) RegisterOption {
+	return registerOptionFunc(func(cfg registerConfig) registerConfig {
+		cfg.ResultValidator = validator
+		return cfg
+	})
+}
+
+// WithResultCache caches the results of an activity for the given duration. Executions of the activity with inputs
+// that serialize identically to a cached execution, from any workflow instance, return the cached result instead
+// of executing the activity again. Only use this for activities without side effects. Failed executions are not
+// cached. Cached results can be removed with client.InvalidateActivityResult.
+//
+// If the backend does not support caching activity results, the activity is always executed.
+func WithResultCache(ttl time.Duration) RegisterOption {
+	return registerOptionFunc(func(cfg registerConfig) registerConfig {
+		cfg.ResultCacheTTL = ttl
+		return cfg
+	})
+}
+
+// WithMaxConcurrency limits how many executions of an activity a worker runs at the same time, in addition to the
+// overall limit configured for the worker. Tasks for an activity that is at its limit are kept locked by the worker
+// and wait until a running execution has finished.
+func WithMaxConcurrency(limit int) RegisterOption {
+	return registerOptionFunc(func(cfg registerConfig) registerConfig {
+		cfg.MaxConcurrency = limit
+		return cfg
+	})
+}
+
+// WithExecutionGuarantee declares whether an activity may be executed again when its task is delivered again. By
+// default, activities are executed at least once. Activities registered with AtMostOnce fail instead of being
+// executed again, if a previous execution of the same task has started but not completed.
+//
+// At-most-once execution records a marker in the backend before executing the activity. If the backend does not
+// support caching activity results, activities registered with AtMostOnce fail without being executed.
+func WithExecutionGuarantee(guarantee ExecutionGuarantee) RegisterOption {
+	return registerOptionFunc(func(cfg registerConfig) registerConfig {
+		cfg.ExecutionGuarantee = guarantee
+		return cfg
+	})
+}
+
 func WithName(name string) RegisterOption {
 	return registerOptionFunc(func(cfg registerConfig) registerConfig {
 		cfg.Name = name
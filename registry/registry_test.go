@@ -3,6 +3,7 @@ package registry
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/sync"
@@ -52,6 +53,19 @@ func TestRegistry_RegisterWorkflow(t *testing.T) {
 				workflow: func(ctx sync.Context, a, b int) (int, error) { return 42, nil },
 			},
 		},
+		{
+			name: "valid workflow with multiple results",
+			args: args{
+				workflow: func(ctx sync.Context) (int, string, error) { return 42, "", nil },
+			},
+		},
+		{
+			name: "missing error with multiple results",
+			args: args{
+				workflow: func(ctx sync.Context) (int, string) { return 42, "" },
+			},
+			wantErr: true,
+		},
 		{
 			name: "missing parameter",
 			args: args{
@@ -235,3 +249,101 @@ func Test_ActivityRegistrationOnStruct_Invalid(t *testing.T) {
 	err := r.RegisterActivity(a)
 	require.Error(t, err)
 }
+
+func Test_ActivityRetryOptions(t *testing.T) {
+	r := New()
+
+	retryOptions := wf.RetryOptions{MaxAttempts: 5}
+
+	require.NoError(t, r.RegisterActivity(reg_activity, WithRetryOptions(retryOptions)))
+	require.NoError(t, r.RegisterActivity(reg_activity, WithName("other")))
+
+	o, ok := r.ActivityRetryOptions(fn.Name(reg_activity))
+	require.True(t, ok)
+	require.Equal(t, retryOptions, o)
+
+	_, ok = r.ActivityRetryOptions("other")
+	require.False(t, ok)
+}
+
+func Test_ActivityResultCacheTTL(t *testing.T) {
+	r := New()
+
+	require.NoError(t, r.RegisterActivity(reg_activity, WithResultCache(time.Minute)))
+	require.NoError(t, r.RegisterActivity(reg_activity, WithName("other")))
+
+	ttl, ok := r.ActivityResultCacheTTL(fn.Name(reg_activity))
+	require.True(t, ok)
+	require.Equal(t, time.Minute, ttl)
+
+	_, ok = r.ActivityResultCacheTTL("other")
+	require.False(t, ok)
+}
+
+func Test_ActivityResultValidator(t *testing.T) {
+	r := New()
+
+	require.NoError(t, r.RegisterActivity(reg_activity, WithResultValidator(func(ctx context.Context, result interface{}) error {
+		return nil
+	})))
+	require.NoError(t, r.RegisterActivity(reg_activity, WithName("other")))
+
+	_, ok := r.ActivityResultValidator(fn.Name(reg_activity))
+	require.True(t, ok)
+
+	_, ok = r.ActivityResultValidator("other")
+	require.False(t, ok)
+}
+
+func Test_ActivityMaxConcurrency(t *testing.T) {
+	r := New()
+
+	require.NoError(t, r.RegisterActivity(reg_activity, WithMaxConcurrency(2)))
+	require.NoError(t, r.RegisterActivity(reg_activity, WithName("other")))
+
+	limit, ok := r.ActivityMaxConcurrency(fn.Name(reg_activity))
+	require.True(t, ok)
+	require.Equal(t, 2, limit)
+
+	_, ok = r.ActivityMaxConcurrency("other")
+	require.False(t, ok)
+}
+
+func Test_ListRegistrations(t *testing.T) {
+	r := New()
+
+	require.Empty(t, r.Workflows())
+	require.Empty(t, r.Activities())
+
+	require.NoError(t, r.RegisterWorkflow(reg_workflow1, WithName("b")))
+	require.NoError(t, r.RegisterWorkflow(reg_workflow1, WithName("a")))
+	require.NoError(t, r.RegisterActivity(reg_activity))
+
+	require.Equal(t, []string{"a", "b"}, r.Workflows())
+	require.Equal(t, []string{fn.Name(reg_activity)}, r.Activities())
+
+	require.True(t, r.HasWorkflow("a"))
+	require.False(t, r.HasWorkflow(fn.Name(reg_workflow1)))
+}
+
+func Test_Namespace(t *testing.T) {
+	r := New()
+	ns := New()
+
+	require.NoError(t, r.RegisterNamespace("tenant", ns))
+	require.Error(t, r.RegisterNamespace("", ns))
+
+	var alreadyRegistered *ErrNamespaceAlreadyRegistered
+	require.ErrorAs(t, r.RegisterNamespace("tenant", New()), &alreadyRegistered)
+
+	got, err := r.Namespace("tenant")
+	require.NoError(t, err)
+	require.Same(t, ns, got)
+
+	got, err = r.Namespace("")
+	require.NoError(t, err)
+	require.Same(t, r, got)
+
+	_, err = r.Namespace("other")
+	require.Error(t, err)
+}
@@ -0,0 +1,172 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/converter"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/backend/metadata"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/workflowerrors"
+	"github.com/cschleiden/go-workflows/registry"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/cschleiden/go-workflows/workflow/executor"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type options struct {
+	Logger      *slog.Logger
+	Converter   converter.Converter
+	Propagators []workflow.ContextPropagator
+}
+
+type ReplayerOption func(*options)
+
+// WithLogger sets the logger used while replaying
+func WithLogger(logger *slog.Logger) ReplayerOption {
+	return func(o *options) {
+		o.Logger = logger
+	}
+}
+
+// WithConverter sets the converter used to decode the payloads in the history. It has to match the converter of the
+// backend the history was recorded with.
+func WithConverter(converter converter.Converter) ReplayerOption {
+	return func(o *options) {
+		o.Converter = converter
+	}
+}
+
+// WithContextPropagator adds a context propagator, workflows might depend on the context they extract
+func WithContextPropagator(prop workflow.ContextPropagator) ReplayerOption {
+	return func(o *options) {
+		o.Propagators = append(o.Propagators, prop)
+	}
+}
+
+// Replayer replays recorded histories of workflow instances with the workflows registered in its registry. This
+// allows checking that changed workflow code can still continue instances started with the previous version, e.g.,
+// before deploying it.
+type Replayer struct {
+	registry         *registry.Registry
+	options          *options
+	versionOverrides map[string]int
+}
+
+// New creates a replayer for the workflows registered in the given registry
+func New(registry *registry.Registry, opts ...ReplayerOption) *Replayer {
+	options := &options{
+		Logger:    slog.Default(),
+		Converter: converter.DefaultConverter,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &Replayer{
+		registry: registry,
+		options:  options,
+	}
+}
+
+// WithVersionOverrides returns a replayer that replays histories as if they had recorded the given versions for the
+// changes with the given IDs, see workflow.GetVersion. This allows checking how instances that recorded a specific
+// version replay, without a history of such an instance.
+func (r *Replayer) WithVersionOverrides(versions map[string]int) *Replayer {
+	overrides := make(map[string]int, len(versions))
+	for changeID, version := range versions {
+		overrides[changeID] = version
+	}
+
+	return &Replayer{
+		registry:         r.registry,
+		options:          r.options,
+		versionOverrides: overrides,
+	}
+}
+
+// Replay replays the given history of the workflow instance. If the workflow diverges from the history, an
+// *executor.NonDeterminismError is returned. An error is also returned when the workflow finishes while replaying a
+// history that has not finished, e.g., because the workflow now fails, or when it does not finish while replaying a
+// history that has.
+func (r *Replayer) Replay(ctx context.Context, instance *core.WorkflowInstance, h []*history.Event) error {
+	if len(h) == 0 {
+		return errors.New("history is empty")
+	}
+
+	md := &metadata.WorkflowMetadata{}
+	if a, ok := h[0].Attributes.(*history.ExecutionStartedAttributes); ok && a.Metadata != nil {
+		md = a.Metadata
+	}
+
+	e, err := executor.NewExecutor(r.registry, &historyProvider{h}, instance, md, executor.ExecutorOptions{
+		Logger:      r.options.Logger,
+		Tracer:      noop.NewTracerProvider().Tracer("replay"),
+		Converter:   r.options.Converter,
+		Propagators: r.options.Propagators,
+		Clock:       clock.New(),
+
+		VersionOverrides: r.versionOverrides,
+	})
+	if err != nil {
+		return fmt.Errorf("creating workflow executor: %w", err)
+	}
+	defer e.Close()
+
+	// A task without new events only replays the history
+	result, err := e.ExecuteTask(ctx, &backend.WorkflowTask{
+		ID:                    "replay",
+		WorkflowInstance:      instance,
+		WorkflowInstanceState: core.WorkflowInstanceStateActive,
+		Metadata:              md,
+		LastSequenceID:        h[len(h)-1].SequenceID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if finished(h) {
+		if result.State == core.WorkflowInstanceStateActive {
+			return errors.New("workflow did not finish while replaying finished history")
+		}
+
+		return nil
+	}
+
+	if result.State == core.WorkflowInstanceStateActive {
+		return nil
+	}
+
+	for _, event := range result.Executed {
+		if a, ok := event.Attributes.(*history.ExecutionCompletedAttributes); ok && a.Error != nil {
+			return fmt.Errorf("workflow finished while replaying unfinished history: %w", workflowerrors.ToError(a.Error))
+		}
+	}
+
+	return errors.New("workflow finished while replaying unfinished history")
+}
+
+func finished(h []*history.Event) bool {
+	for _, event := range h {
+		switch event.Type {
+		case history.EventType_WorkflowExecutionFinished, history.EventType_WorkflowExecutionContinuedAsNew:
+			return true
+		}
+	}
+
+	return false
+}
+
+type historyProvider struct {
+	history []*history.Event
+}
+
+func (hp *historyProvider) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64, options ...backend.HistoryOption) ([]*history.Event, error) {
+	return hp.history, nil
+}
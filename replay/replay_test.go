@@ -0,0 +1,234 @@
+package replay_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/registry"
+	"github.com/cschleiden/go-workflows/replay"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/cschleiden/go-workflows/workflow/executor"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func activity1(ctx context.Context) (int, error) {
+	return 1, nil
+}
+
+func activity2(ctx context.Context) (int, error) {
+	return 2, nil
+}
+
+// record runs the given workflow until waitFor returns true for its history, and returns the recorded history
+func record(t *testing.T, wf workflow.Workflow, waitFor func(h []*history.Event) bool) (*core.WorkflowInstance, []*history.Event) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := sqlite.NewInMemoryBackend(sqlite.WithBackendOptions(backend.WithStickyTimeout(0)))
+	defer b.Close()
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(wf, registry.WithName("wf")))
+	require.NoError(t, w.RegisterActivity(activity1))
+	require.NoError(t, w.RegisterActivity(activity2))
+	require.NoError(t, w.Start(ctx))
+
+	c := client.New(b)
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, "wf")
+	require.NoError(t, err)
+
+	var h []*history.Event
+	require.Eventually(t, func() bool {
+		h, err = b.GetWorkflowInstanceHistory(ctx, instance, nil)
+		return err == nil && waitFor(h)
+	}, time.Second*10, time.Millisecond*10)
+
+	cancel()
+	require.NoError(t, w.WaitForCompletion())
+
+	return instance, h
+}
+
+func finished(h []*history.Event) bool {
+	for _, event := range h {
+		if event.Type == history.EventType_WorkflowExecutionFinished {
+			return true
+		}
+	}
+
+	return false
+}
+
+func activityCompleted(h []*history.Event) bool {
+	for _, event := range h {
+		if event.Type == history.EventType_ActivityCompleted {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newReplayer(t *testing.T, wf workflow.Workflow) *replay.Replayer {
+	r := registry.New()
+	require.NoError(t, r.RegisterWorkflow(wf, registry.WithName("wf")))
+	require.NoError(t, r.RegisterActivity(activity1))
+	require.NoError(t, r.RegisterActivity(activity2))
+
+	return replay.New(r)
+}
+
+func replayHistory(t *testing.T, wf workflow.Workflow, instance *core.WorkflowInstance, h []*history.Event) error {
+	return newReplayer(t, wf).Replay(context.Background(), instance, h)
+}
+
+func Test_Replayer(t *testing.T) {
+	v1 := func(ctx workflow.Context) (int, error) {
+		return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity1).Get(ctx)
+	}
+
+	instance, h := record(t, v1, finished)
+
+	t.Run("Unchanged", func(t *testing.T) {
+		require.NoError(t, replayHistory(t, v1, instance, h))
+	})
+
+	t.Run("DifferentActivity", func(t *testing.T) {
+		v2 := func(ctx workflow.Context) (int, error) {
+			return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity2).Get(ctx)
+		}
+
+		err := replayHistory(t, v2, instance, h)
+
+		var ndErr *executor.NonDeterminismError
+		require.ErrorAs(t, err, &ndErr)
+	})
+
+	t.Run("AdditionalCommand", func(t *testing.T) {
+		v2 := func(ctx workflow.Context) (int, error) {
+			workflow.SideEffect(ctx, func(ctx workflow.Context) int { return 42 }).Get(ctx)
+
+			return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity1).Get(ctx)
+		}
+
+		err := replayHistory(t, v2, instance, h)
+
+		var ndErr *executor.NonDeterminismError
+		require.ErrorAs(t, err, &ndErr)
+	})
+
+	t.Run("DoesNotFinish", func(t *testing.T) {
+		v2 := func(ctx workflow.Context) (int, error) {
+			r, err := workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity1).Get(ctx)
+			if err != nil {
+				return 0, err
+			}
+
+			workflow.NewSignalChannel[int](ctx, "signal").Receive(ctx)
+
+			return r, nil
+		}
+
+		require.EqualError(t, replayHistory(t, v2, instance, h), "workflow did not finish while replaying finished history")
+	})
+}
+
+func Test_Replayer_Unfinished(t *testing.T) {
+	v1 := func(ctx workflow.Context) (int, error) {
+		r, err := workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity1).Get(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		workflow.NewSignalChannel[int](ctx, "signal").Receive(ctx)
+
+		return r, nil
+	}
+
+	instance, h := record(t, v1, activityCompleted)
+
+	t.Run("Unchanged", func(t *testing.T) {
+		require.NoError(t, replayHistory(t, v1, instance, h))
+	})
+
+	t.Run("Fails", func(t *testing.T) {
+		v2 := func(ctx workflow.Context) (int, error) {
+			if _, err := workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity1).Get(ctx); err != nil {
+				return 0, err
+			}
+
+			return 0, errors.New("broken")
+		}
+
+		require.ErrorContains(t, replayHistory(t, v2, instance, h), "workflow finished while replaying unfinished history: broken")
+	})
+}
+
+func Test_Replayer_Versions(t *testing.T) {
+	v1 := func(ctx workflow.Context) (int, error) {
+		return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity1).Get(ctx)
+	}
+
+	v2 := func(ctx workflow.Context) (int, error) {
+		version, err := workflow.GetVersion(ctx, "activity2", workflow.DefaultVersion, 1)
+		if err != nil {
+			return 0, err
+		}
+
+		if version == workflow.DefaultVersion {
+			return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity1).Get(ctx)
+		}
+
+		return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity2).Get(ctx)
+	}
+
+	v1Instance, v1History := record(t, v1, finished)
+	v2Instance, v2History := record(t, v2, finished)
+
+	t.Run("HistoryBeforeChange", func(t *testing.T) {
+		require.NoError(t, replayHistory(t, v2, v1Instance, v1History))
+	})
+
+	t.Run("RecordedVersion", func(t *testing.T) {
+		require.NoError(t, replayHistory(t, v2, v2Instance, v2History))
+	})
+
+	t.Run("VersionOverride", func(t *testing.T) {
+		r := newReplayer(t, v2).WithVersionOverrides(map[string]int{"activity2": 1})
+
+		// An instance that recorded version 1 executes activity2, but the history contains activity1
+		err := r.Replay(context.Background(), v1Instance, v1History)
+
+		var ndErr *executor.NonDeterminismError
+		require.ErrorAs(t, err, &ndErr)
+
+		r = newReplayer(t, v2).WithVersionOverrides(map[string]int{"activity2": workflow.DefaultVersion})
+		require.NoError(t, r.Replay(context.Background(), v1Instance, v1History))
+	})
+
+	t.Run("UnsupportedVersion", func(t *testing.T) {
+		v3 := func(ctx workflow.Context) (int, error) {
+			if _, err := workflow.GetVersion(ctx, "activity2", 1, 1); err != nil {
+				return 0, err
+			}
+
+			return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity2).Get(ctx)
+		}
+
+		require.NoError(t, replayHistory(t, v3, v2Instance, v2History))
+		// The workflow fails on the unsupported version instead of executing the activity recorded in the history
+		var ndErr *executor.NonDeterminismError
+		require.ErrorAs(t, replayHistory(t, v3, v1Instance, v1History), &ndErr)
+	})
+}
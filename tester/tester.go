@@ -20,6 +20,7 @@ import (
 	"github.com/cschleiden/go-workflows/core"
 	"github.com/cschleiden/go-workflows/internal/activity"
 	"github.com/cschleiden/go-workflows/internal/args"
+	"github.com/cschleiden/go-workflows/internal/blobs"
 	"github.com/cschleiden/go-workflows/internal/command"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/log"
@@ -38,7 +39,7 @@ type testHistoryProvider struct {
 	history []*history.Event
 }
 
-func (t *testHistoryProvider) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64) ([]*history.Event, error) {
+func (t *testHistoryProvider) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64, options ...backend.HistoryOption) ([]*history.Event, error) {
 	return t.history, nil
 }
 
@@ -347,7 +348,13 @@ func (wt *workflowTester[TResult]) Execute(ctx context.Context, args ...any) {
 			tw.pendingEvents = tw.pendingEvents[:0]
 
 			// Execute task
-			e, err := executor.NewExecutor(wt.logger, wt.tracer, wt.registry, wt.converter, wt.propagators, &testHistoryProvider{tw.history}, tw.instance, tw.metadata, wt.clock)
+			e, err := executor.NewExecutor(wt.registry, &testHistoryProvider{tw.history}, tw.instance, tw.metadata, executor.ExecutorOptions{
+				Logger:      wt.logger,
+				Tracer:      wt.tracer,
+				Converter:   wt.converter,
+				Propagators: wt.propagators,
+				Clock:       wt.clock,
+			})
 			if err != nil {
 				panic(fmt.Errorf("could not create workflow executor: %v", err))
 			}
@@ -694,7 +701,7 @@ func (wt *workflowTester[TResult]) scheduleActivity(wfi *core.WorkflowInstance,
 			}
 
 		} else {
-			executor := activity.NewExecutor(wt.logger, wt.tracer, wt.converter, wt.propagators, wt.registry)
+			executor := activity.NewExecutor(wt.logger, wt.tracer, wt.converter, wt.propagators, wt.registry, blobs.New(nil, wt.converter))
 			activityResult, activityErr = executor.ExecuteActivity(context.Background(), &backend.ActivityTask{
 				ID:               uuid.NewString(),
 				WorkflowInstance: wfi,
@@ -712,7 +719,8 @@ func (wt *workflowTester[TResult]) scheduleActivity(wfi *core.WorkflowInstance,
 					wt.clock.Now(),
 					history.EventType_ActivityFailed,
 					&history.ActivityFailedAttributes{
-						Error: aerr,
+						Error:         aerr,
+						CorrelationID: e.CorrelationID,
 					},
 					history.ScheduleEventID(event.ScheduleEventID),
 				)
@@ -721,7 +729,8 @@ func (wt *workflowTester[TResult]) scheduleActivity(wfi *core.WorkflowInstance,
 					wt.clock.Now(),
 					history.EventType_ActivityCompleted,
 					&history.ActivityCompletedAttributes{
-						Result: activityResult,
+						Result:        activityResult,
+						CorrelationID: e.CorrelationID,
 					},
 					history.ScheduleEventID(event.ScheduleEventID),
 				)
@@ -877,6 +886,8 @@ func (wt *workflowTester[TResult]) getInitialEvent(wf interface{}, a []interface
 			Name:     name,
 			Metadata: &metadata.WorkflowMetadata{},
 			Inputs:   inputs,
+
+			OrderedSignals: true,
 		},
 	)
 }
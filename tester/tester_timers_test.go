@@ -243,3 +243,76 @@ func Test_Timers_MultipleTimers(t *testing.T) {
 	require.Empty(t, werr)
 	tester.AssertExpectations(t)
 }
+
+func Test_SelectWithTimeout(t *testing.T) {
+	wf := func(ctx workflow.Context) (bool, error) {
+		return workflow.SelectWithTimeout(ctx, time.Second*10,
+			workflow.Receive(workflow.NewSignalChannel[string](ctx, "signal"), func(ctx workflow.Context, signal string, ok bool) {
+				// do nothing
+			}),
+		), nil
+	}
+
+	tests := []struct {
+		name     string
+		signal   bool
+		selected bool
+	}{
+		{name: "case selected", signal: true, selected: true},
+		{name: "timeout", signal: false, selected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tester := NewWorkflowTester[bool](wf)
+
+			if tt.signal {
+				tester.ScheduleCallback(time.Second*2, func() {
+					tester.SignalWorkflow("signal", "s42")
+				})
+			}
+
+			tester.Execute(context.Background())
+
+			require.True(t, tester.WorkflowFinished())
+			wr, wErr := tester.WorkflowResult()
+			require.Empty(t, wErr)
+			require.Equal(t, tt.selected, wr)
+		})
+	}
+}
+
+func Test_Selector_SelectWithTimeout(t *testing.T) {
+	wf := func(ctx workflow.Context) (int, error) {
+		c := workflow.NewSignalChannel[string](ctx, "signal")
+
+		signals := 0
+		s := workflow.NewFairSelector()
+		for s.SelectWithTimeout(ctx, time.Second*10,
+			workflow.Receive(c, func(ctx workflow.Context, signal string, ok bool) {
+				signals++
+			}),
+		) {
+		}
+
+		return signals, nil
+	}
+
+	tester := NewWorkflowTester[int](wf)
+	start := tester.Now()
+
+	tester.ScheduleCallback(time.Second*2, func() {
+		tester.SignalWorkflow("signal", "s1")
+	})
+	tester.ScheduleCallback(time.Second*4, func() {
+		tester.SignalWorkflow("signal", "s2")
+	})
+
+	tester.Execute(context.Background())
+
+	require.True(t, tester.WorkflowFinished())
+	wr, wErr := tester.WorkflowResult()
+	require.Empty(t, wErr)
+	require.Equal(t, 2, wr)
+	require.Equal(t, start.Add(time.Second*14), tester.Now())
+}
@@ -1,8 +1,12 @@
 package worker
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"time"
 
+	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/cschleiden/go-workflows/workflow/executor"
 )
@@ -19,6 +23,19 @@ type WorkflowWorkerOptions struct {
 	// to 25 seconds
 	WorkflowHeartbeatInterval time.Duration
 
+	// WorkflowHeartbeatFraction, if set, determines the interval between heartbeat attempts on workflow tasks as a
+	// fraction of the backend's WorkflowLockTimeout, e.g., 0.3 extends the lock of a task every 18 seconds with a
+	// lock timeout of one minute. Must be between 0 and 1, otherwise starting the worker fails. Takes precedence over
+	// WorkflowHeartbeatInterval. Defaults to 0.
+	WorkflowHeartbeatFraction float64
+
+	// WorkflowHeartbeatRetryBackoff is the initial delay before retrying a failed extension of a workflow task lock.
+	// Retries back off exponentially, up to the heartbeat interval. If the backend reports that the lock has been
+	// lost, or it cannot be extended within the backend's WorkflowLockTimeout, the worker stops executing the task
+	// and abandons it without completing it, another worker picks it up again. If 0, failed extensions are only
+	// retried with the next heartbeat. Defaults to 1 second.
+	WorkflowHeartbeatRetryBackoff time.Duration
+
 	// WorkflowPollingInterval is the interval between polling for new workflow tasks.
 	// Note that if you use a backend that can wait for tasks to be available (e.g. redis) this field has no effect.
 	// Defaults to 200ms.
@@ -34,13 +51,76 @@ type WorkflowWorkerOptions struct {
 	// will be used.
 	WorkflowExecutorCache executor.Cache
 
+	// WorkflowTaskTimeout is the maximum time the execution of a single workflow task may take. Workflow code
+	// should never block, so exceeding this usually means workflow code is performing blocking I/O. If exceeded,
+	// the stalled workflow instance is logged, and it fails with executor.ErrWorkflowTaskTimeout. The failure is
+	// recorded in its history. Defaults to 0, which disables the timeout.
+	//
+	// Only the execution of new events is timed, the time spent replaying the history of an instance is not
+	// counted. Workflow code exceeding the timeout cannot be stopped, its goroutine is abandoned and keeps running
+	// in the background. The cached executor of the instance is evicted.
+	//
+	// This is independent of the backend's WorkflowLockTimeout, and should be lower than it.
+	WorkflowTaskTimeout time.Duration
+
+	// MaxWorkflowResultSize is the maximum size in bytes of a serialized workflow result. Workflows returning a
+	// larger result fail with executor.ErrWorkflowResultTooLarge instead of storing the result. Defaults to 0,
+	// which does not limit the result size.
+	MaxWorkflowResultSize int
+
+	// MaxWorkflowIterations is the maximum number of iterations workflow code may execute without yielding, e.g.,
+	// selecting a default case or getting the value of a resolved future. Workflows exceeding it are most likely
+	// stuck in a loop that never waits for anything, they fail with executor.ErrWorkflowStuckInLoop and are logged.
+	// Defaults to 0, which uses executor.DefaultMaxIterations.
+	MaxWorkflowIterations int
+
+	// MaxWorkflowTaskFailures is the number of times in a row the workflow task of an instance may fail, e.g.,
+	// because the workflow is not registered or behaves non-deterministically, before the instance becomes errored.
+	// Errored instances are not executed until they are retried with client.RetryWorkflow, for example after fixing
+	// the bug. Defaults to 0, which executes failed workflow tasks again until they succeed.
+	MaxWorkflowTaskFailures int
+
 	// WorkflowQueues are the queue the worker listens to
 	WorkflowQueues []workflow.Queue
+
+	// BeforeWorkflowTask is called before each workflow task is executed, e.g., to set up per-task state or start
+	// measuring its duration. Panics are recovered and logged, the task is executed regardless. Defaults to nil.
+	BeforeWorkflowTask func(ctx context.Context, task *backend.WorkflowTask)
+
+	// AfterWorkflowTask is called after each workflow task has been executed, before it is completed, with the error
+	// executing it, if any. Panics are recovered and logged, the task is completed regardless. Defaults to nil.
+	AfterWorkflowTask func(ctx context.Context, task *backend.WorkflowTask, err error)
+
+	// WorkerIdentity identifies a worker created with NewWorkflowWorker in the WorkflowTaskStarted events it records,
+	// workers created with New use Options.WorkerIdentity. Defaults to the hostname and process ID of the worker.
+	WorkerIdentity string
 }
 
 type Options struct {
 	WorkflowWorkerOptions
 	ActivityWorkerOptions
+
+	// PreferLocalActivities executes activities scheduled by workflow tasks in the same process, if they are
+	// registered with the worker and on one of its ActivityQueues, instead of scheduling them via the backend. The
+	// workflow task stays locked while its activities run, and their results are recorded when it is completed, so
+	// the history is the same as for activities executed by any activity worker. Activities that fail are retried
+	// like other activities. Defaults to false.
+	PreferLocalActivities bool
+
+	// WorkerIdentity identifies the worker in the history of workflow instances. The WorkflowTaskStarted event of
+	// every workflow task, and the completed or failed event of every activity, record the identity of the worker
+	// that executed them. Defaults to the hostname and process ID of the worker.
+	WorkerIdentity string
+}
+
+// defaultWorkerIdentity returns the identity of a worker without a configured identity
+func defaultWorkerIdentity() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
 }
 
 type ActivityWorkerOptions struct {
@@ -62,14 +142,36 @@ type ActivityWorkerOptions struct {
 
 	// ActivityQueues are the queues the worker listens to
 	ActivityQueues []workflow.Queue
+
+	// ActivityErrorRedactor is called with every error returned by an activity, including panics, before it is
+	// persisted in the workflow history. The error passed in is a *workflow.Error, the redactor can return a copy
+	// with sensitive data removed from the message or stack trace. Defaults to nil, which persists errors as is.
+	ActivityErrorRedactor func(err error) error
+
+	// ActivityPayloadLogSampleRate logs the inputs and the result or error of one in every
+	// ActivityPayloadLogSampleRate activity executions. Only the sizes of inputs and results are logged, unless
+	// ActivityPayloadLogRedactor is set. Errors are logged after applying ActivityErrorRedactor. Defaults to 0, which
+	// does not log any payloads.
+	ActivityPayloadLogSampleRate int
+
+	// ActivityPayloadLogRedactor is called with every input and the result of sampled activity executions, decoded with
+	// the converter of the backend, and returns the value to log instead, e.g., with sensitive fields removed. Defaults
+	// to nil, which only logs the sizes of the payloads.
+	ActivityPayloadLogRedactor func(activityName string, v any) any
+
+	// WorkerIdentity identifies a worker created with NewActivityWorker in the completed or failed events of the
+	// activities it executes, workers created with New use Options.WorkerIdentity. Defaults to the hostname and process
+	// ID of the worker.
+	WorkerIdentity string
 }
 
 var DefaultOptions = Options{
 	WorkflowWorkerOptions: WorkflowWorkerOptions{
-		WorkflowPollers:           2,
-		WorkflowPollingInterval:   200 * time.Millisecond,
-		MaxParallelWorkflowTasks:  0,
-		WorkflowHeartbeatInterval: 25 * time.Second,
+		WorkflowPollers:               2,
+		WorkflowPollingInterval:       200 * time.Millisecond,
+		MaxParallelWorkflowTasks:      0,
+		WorkflowHeartbeatInterval:     25 * time.Second,
+		WorkflowHeartbeatRetryBackoff: time.Second,
 
 		WorkflowExecutorCacheSize: 128,
 		WorkflowExecutorCacheTTL:  time.Second * 10,
@@ -2,24 +2,37 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/client"
 	"github.com/cschleiden/go-workflows/internal/signals"
 	internal "github.com/cschleiden/go-workflows/internal/worker"
 	"github.com/cschleiden/go-workflows/internal/workflows"
 	"github.com/cschleiden/go-workflows/registry"
 	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
 )
 
 type Worker struct {
 	backend backend.Backend
 
+	// id identifies this worker towards the backend, see backend.WithWorkerID
+	id string
+
 	registry *registry.Registry
 
 	workers []worker
+
+	// activityConcurrency tracks activity executions, nil if the worker doesn't process activities
+	activityConcurrency *internal.ActivityConcurrency
+
+	// optionsErr is returned from Start if the worker was created with invalid options
+	optionsErr error
 }
 
 type worker interface {
@@ -35,28 +48,76 @@ func New(backend backend.Backend, options *Options) *Worker {
 		options = &DefaultOptions
 	}
 
-	workflowWorker := newWorkflowWorker(backend, registry, &options.WorkflowWorkerOptions)
-	activityWorker := newActivityWorker(backend, registry, &options.ActivityWorkerOptions)
+	identity := options.WorkerIdentity
+	if identity == "" {
+		identity = defaultWorkerIdentity()
+	}
+
+	concurrency := internal.NewActivityConcurrency(registry)
+
+	id := uuid.NewString()
+
+	activityWorker := newActivityWorker(backend, registry, concurrency, &options.ActivityWorkerOptions, identity, id)
+
+	var localActivities internal.LocalActivityExecutor
+	if options.PreferLocalActivities {
+		localActivities = activityWorker.TaskWorker().(*internal.ActivityTaskWorker)
+	}
+
+	workflowWorker := newWorkflowWorker(backend, registry, &options.WorkflowWorkerOptions, localActivities, identity, id)
 
 	// Register internal activities
-	return newWorker(backend, registry, []worker{workflowWorker, activityWorker})
+	w := newWorker(backend, registry, id, []worker{workflowWorker, activityWorker})
+	w.activityConcurrency = concurrency
+	w.optionsErr = validateWorkflowWorkerOptions(&options.WorkflowWorkerOptions)
+
+	return w
 }
 
 // NewWorkflowWorker creates a worker that only processes workflows.
 func NewWorkflowWorker(backend backend.Backend, options *WorkflowWorkerOptions) *Worker {
 	registry := registry.New()
 
-	return newWorker(backend, registry, []worker{newWorkflowWorker(backend, registry, options)})
+	id := uuid.NewString()
+
+	if options == nil {
+		options = &DefaultOptions.WorkflowWorkerOptions
+	}
+
+	identity := options.WorkerIdentity
+	if identity == "" {
+		identity = defaultWorkerIdentity()
+	}
+
+	w := newWorker(backend, registry, id, []worker{newWorkflowWorker(backend, registry, options, nil, identity, id)})
+	w.optionsErr = validateWorkflowWorkerOptions(options)
+
+	return w
 }
 
 // NewActivityWorker creates a worker that only processes activities.
 func NewActivityWorker(backend backend.Backend, options *ActivityWorkerOptions) *Worker {
 	registry := registry.New()
+	concurrency := internal.NewActivityConcurrency(registry)
+
+	if options == nil {
+		options = &DefaultOptions.ActivityWorkerOptions
+	}
+
+	identity := options.WorkerIdentity
+	if identity == "" {
+		identity = defaultWorkerIdentity()
+	}
+
+	id := uuid.NewString()
 
-	return newWorker(backend, registry, []worker{newActivityWorker(backend, registry, options)})
+	w := newWorker(backend, registry, id, []worker{newActivityWorker(backend, registry, concurrency, options, identity, id)})
+	w.activityConcurrency = concurrency
+
+	return w
 }
 
-func newWorker(backend backend.Backend, registry *registry.Registry, workers []worker) *Worker {
+func newWorker(backend backend.Backend, registry *registry.Registry, id string, workers []worker) *Worker {
 	// Register system activites and workflows
 	if err := registry.RegisterActivity(&signals.Activities{Signaler: client.New(backend)}); err != nil {
 		panic(fmt.Errorf("registering internal activities: %w", err))
@@ -70,46 +131,95 @@ func newWorker(backend backend.Backend, registry *registry.Registry, workers []w
 		panic(fmt.Errorf("registering internal workflow: %w", err))
 	}
 
+	if err := registry.RegisterWorkflow(workflows.DeliverCompletionWebhook); err != nil {
+		panic(fmt.Errorf("registering internal workflow: %w", err))
+	}
+
 	return &Worker{
 		backend: backend,
+		id:      id,
 
 		workers:  workers,
 		registry: registry,
 	}
 }
 
-func newActivityWorker(backend backend.Backend, registry *registry.Registry, options *ActivityWorkerOptions) worker {
+// validateWorkflowWorkerOptions returns an error if the given options are invalid
+func validateWorkflowWorkerOptions(options *WorkflowWorkerOptions) error {
+	if options == nil {
+		return nil
+	}
+
+	if options.WorkflowHeartbeatFraction < 0 || options.WorkflowHeartbeatFraction >= 1 {
+		return fmt.Errorf("workflow heartbeat fraction must be between 0 and 1, got %v", options.WorkflowHeartbeatFraction)
+	}
+
+	return nil
+}
+
+func newActivityWorker(
+	backend backend.Backend, registry *registry.Registry, concurrency *internal.ActivityConcurrency, options *ActivityWorkerOptions,
+	identity, id string,
+) *internal.Worker[backend.ActivityTask, history.Event] {
 	if options == nil {
 		options = &DefaultOptions.ActivityWorkerOptions
 	}
 
-	activityWorker := internal.NewActivityWorker(backend, registry, clock.New(), internal.WorkerOptions{
-		Pollers:           options.ActivityPollers,
-		PollingInterval:   options.ActivityPollingInterval,
-		MaxParallelTasks:  options.MaxParallelActivityTasks,
-		HeartbeatInterval: options.ActivityHeartbeatInterval,
-		Queues:            options.ActivityQueues,
+	activityWorker := internal.NewActivityWorker(backend, registry, clock.New(), internal.ActivityWorkerOptions{
+		WorkerOptions: internal.WorkerOptions{
+			Pollers:           options.ActivityPollers,
+			PollingInterval:   options.ActivityPollingInterval,
+			MaxParallelTasks:  options.MaxParallelActivityTasks,
+			HeartbeatInterval: options.ActivityHeartbeatInterval,
+			Queues:            options.ActivityQueues,
+			Identity:          identity,
+			WorkerID:          id,
+		},
+		ErrorRedactor:        options.ActivityErrorRedactor,
+		PayloadLogSampleRate: options.ActivityPayloadLogSampleRate,
+		PayloadLogRedactor:   options.ActivityPayloadLogRedactor,
+		Concurrency:          concurrency,
 	})
 
 	return activityWorker
 }
 
-func newWorkflowWorker(backend backend.Backend, registry *registry.Registry, options *WorkflowWorkerOptions) worker {
+func newWorkflowWorker(
+	backend backend.Backend, registry *registry.Registry, options *WorkflowWorkerOptions, localActivities internal.LocalActivityExecutor,
+	identity, id string,
+) worker {
 	if options == nil {
 		options = &DefaultOptions.WorkflowWorkerOptions
 	}
 
+	// An invalid fraction is reported when starting the worker
+	heartbeatInterval := options.WorkflowHeartbeatInterval
+	if options.WorkflowHeartbeatFraction > 0 && options.WorkflowHeartbeatFraction < 1 {
+		heartbeatInterval = time.Duration(float64(backend.Options().WorkflowLockTimeout) * options.WorkflowHeartbeatFraction)
+	}
+
 	workflowWorker := internal.NewWorkflowWorker(backend, registry, internal.WorkflowWorkerOptions{
 		WorkerOptions: internal.WorkerOptions{
-			Pollers:           options.WorkflowPollers,
-			PollingInterval:   options.WorkflowPollingInterval,
-			MaxParallelTasks:  options.MaxParallelWorkflowTasks,
-			HeartbeatInterval: options.WorkflowHeartbeatInterval,
-			Queues:            options.WorkflowQueues,
+			Pollers:               options.WorkflowPollers,
+			PollingInterval:       options.WorkflowPollingInterval,
+			MaxParallelTasks:      options.MaxParallelWorkflowTasks,
+			HeartbeatInterval:     heartbeatInterval,
+			HeartbeatRetryBackoff: options.WorkflowHeartbeatRetryBackoff,
+			LockTimeout:           backend.Options().WorkflowLockTimeout,
+			Queues:                options.WorkflowQueues,
+			Identity:              identity,
+			WorkerID:              id,
 		},
 		WorkflowExecutorCache:     options.WorkflowExecutorCache,
 		WorkflowExecutorCacheSize: options.WorkflowExecutorCacheSize,
 		WorkflowExecutorCacheTTL:  options.WorkflowExecutorCacheTTL,
+		WorkflowTaskTimeout:       options.WorkflowTaskTimeout,
+		MaxWorkflowResultSize:     options.MaxWorkflowResultSize,
+		MaxWorkflowIterations:     options.MaxWorkflowIterations,
+		MaxWorkflowTaskFailures:   options.MaxWorkflowTaskFailures,
+		LocalActivities:           localActivities,
+		BeforeTask:                options.BeforeWorkflowTask,
+		AfterTask:                 options.AfterWorkflowTask,
 	})
 
 	return workflowWorker
@@ -120,6 +230,10 @@ func newWorkflowWorker(backend backend.Backend, registry *registry.Registry, opt
 // To stop the worker, cancel the context passed to Start. To wait for completion of the active
 // tasks, call `WaitForCompletion`.
 func (w *Worker) Start(ctx context.Context) error {
+	if w.optionsErr != nil {
+		return fmt.Errorf("invalid worker options: %w", w.optionsErr)
+	}
+
 	for _, worker := range w.workers {
 		if err := worker.Start(ctx); err != nil {
 			return fmt.Errorf("starting worker: %w", err)
@@ -129,7 +243,8 @@ func (w *Worker) Start(ctx context.Context) error {
 	return nil
 }
 
-// WaitForCompletion waits for all active tasks to complete.
+// WaitForCompletion waits for all active tasks to complete. Afterwards, work still held by the worker, like
+// workflow instances sticky to it, is released to other workers.
 func (w *Worker) WaitForCompletion() error {
 	for _, worker := range w.workers {
 		if err := worker.WaitForCompletion(); err != nil {
@@ -137,6 +252,11 @@ func (w *Worker) WaitForCompletion() error {
 		}
 	}
 
+	// Hand over anything still held by this worker, so that other workers don't have to wait for locks to expire
+	if err := w.backend.DeregisterWorker(context.Background(), w.id); err != nil && !errors.As(err, &backend.ErrNotSupported{}) {
+		return fmt.Errorf("deregistering worker: %w", err)
+	}
+
 	return nil
 }
 
@@ -149,3 +269,21 @@ func (w *Worker) RegisterWorkflow(wf workflow.Workflow, opts ...registry.Registe
 func (w *Worker) RegisterActivity(a workflow.Activity, opts ...registry.RegisterOption) error {
 	return w.registry.RegisterActivity(a, opts...)
 }
+
+// RegisterNamespace registers a registry serving the workflows and activities of the given namespace. Workflow
+// instances created with client.WorkflowInstanceOptions.Namespace, and the activities and sub-workflows they schedule,
+// are executed with the registry of their namespace, so names only have to be unique within a namespace.
+func (w *Worker) RegisterNamespace(namespace string, r *registry.Registry) error {
+	return w.registry.RegisterNamespace(namespace, r)
+}
+
+// ActivitiesInFlight returns the number of executions of each activity currently running on this worker. Tasks
+// waiting for a concurrency limit registered with registry.WithMaxConcurrency are not included. Activities of
+// namespaces are reported as namespace/name.
+func (w *Worker) ActivitiesInFlight() map[string]int {
+	if w.activityConcurrency == nil {
+		return map[string]int{}
+	}
+
+	return w.activityConcurrency.InFlight()
+}
@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/metrics"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func Test_Start_InvalidHeartbeatFraction(t *testing.T) {
+	b := &backend.MockBackend{}
+	b.On("Options").Return(backend.ApplyOptions())
+	b.On("Tracer").Return(noop.NewTracerProvider().Tracer("test"))
+	b.On("Metrics").Return(metrics.NewNoopMetricsClient())
+
+	options := DefaultOptions
+	options.WorkflowHeartbeatFraction = 1.5
+
+	w := New(b, &options)
+	require.NotNil(t, w)
+
+	err := w.Start(context.Background())
+	require.ErrorContains(t, err, "workflow heartbeat fraction must be between 0 and 1")
+}
@@ -19,44 +19,133 @@ type ActivityOptions struct {
 
 	// RetryOptions defines how to retry the activity in case of failure.
 	RetryOptions RetryOptions
+
+	// CorrelationID is an optional ID to correlate the activity with external systems, e.g., a business operation.
+	// It is recorded in the history events of the activity and available to the activity via activity.GetInfo.
+	CorrelationID string
 }
 
 var DefaultActivityOptions = ActivityOptions{
 	RetryOptions: DefaultRetryOptions,
 }
 
-// ExecuteActivity schedules the given activity to be executed
+// ExecuteActivity schedules the given activity to be executed. activity is either the activity function or the
+// name of a registered activity. When only the name is given, arguments are validated against the activity if it
+// is registered with the current worker, otherwise they are converted by the worker executing the activity.
+//
+// If options do not specify custom retry options, i.e., they are empty or DefaultRetryOptions, retry options
+// registered for the activity are used instead.
 func ExecuteActivity[TResult any](ctx Context, options ActivityOptions, activity Activity, args ...any) Future[TResult] {
-	return WithRetries(ctx, options.RetryOptions, func(ctx Context, attempt int) Future[TResult] {
-		return executeActivity[TResult](ctx, options, attempt, activity, args...)
-	})
+	givenRetryOptions := options.RetryOptions
+	options.RetryOptions = activityRetryOptions(ctx, activity, options.RetryOptions)
+
+	// All attempts are recorded with the schedule event ID of the first attempt, to link them in the history
+	var first *command.ScheduleActivityCommand
+
+	attempt := func(ctx Context, attempt int) Future[TResult] {
+		var activityID int64
+		if first != nil {
+			activityID = first.ID()
+		}
+
+		f, cmd := executeActivity[TResult](ctx, options, attempt, activityID, activity, args...)
+		if attempt == 0 {
+			first = cmd
+		}
+
+		return f
+	}
+
+	if !isDefaultRetryOptions(givenRetryOptions) {
+		return WithRetries(ctx, options.RetryOptions, attempt)
+	}
+
+	// Registered retry options are recorded with the first attempt. Retries are based on the recorded options, so
+	// replay does not depend on the registry.
+	return withRetries(ctx, func() RetryOptions {
+		if first == nil || first.RetryOptions == nil {
+			// The first attempt could not be scheduled, or the history was recorded before retry options were
+			// recorded and registered options were not supported
+			return givenRetryOptions
+		}
+
+		return fromHistoryRetryOptions(first.RetryOptions)
+	}, attempt)
+}
+
+// ActivityHandle is a handle for an activity started with ExecuteActivityAsync
+type ActivityHandle[TResult any] struct {
+	// Future resolves to the result of the activity, or to Canceled if the activity has been canceled
+	Future Future[TResult]
+
+	cancel CancelFunc
+}
+
+// Cancel cancels the activity if it hasn't completed yet. Its future resolves to Canceled right away, and no further
+// retries are attempted. An activity that is already executing is not interrupted, but its result is ignored.
+func (h *ActivityHandle[TResult]) Cancel(ctx Context) {
+	h.cancel()
 }
 
-func executeActivity[TResult any](ctx Context, options ActivityOptions, attempt int, activity Activity, args ...any) Future[TResult] {
+// activityCancelableKey marks the context of activities started with ExecuteActivityAsync. Canceling such a context
+// cancels the activity.
+type activityCancelableKey struct{}
+
+// ExecuteActivityAsync schedules the given activity like ExecuteActivity, and returns a handle that allows to
+// cancel it later, e.g., when speculatively executing work that might not be needed. Canceling the given context
+// cancels the activity as well.
+func ExecuteActivityAsync[TResult any](ctx Context, options ActivityOptions, activity Activity, args ...any) *ActivityHandle[TResult] {
+	ctx, cancel := WithCancel(ctx)
+	ctx = WithValue(ctx, activityCancelableKey{}, true)
+
+	return &ActivityHandle[TResult]{
+		Future: ExecuteActivity[TResult](ctx, options, activity, args...),
+		cancel: cancel,
+	}
+}
+
+// executeActivity schedules a single attempt of the given activity. activityID is the schedule event ID of the first
+// attempt, 0 for the first attempt itself. It returns the command scheduling the attempt, or nil if the attempt could
+// not be scheduled.
+func executeActivity[TResult any](ctx Context, options ActivityOptions, attempt int, activityID int64, activity Activity, args ...any) (Future[TResult], *command.ScheduleActivityCommand) {
 	f := sync.NewFuture[TResult]()
 
 	if ctx.Err() != nil {
 		f.Set(*new(TResult), ctx.Err())
-		return f
+		return f, nil
 	}
 
-	// Check return type
-	if err := a.ReturnTypeMatch[TResult](activity); err != nil {
-		f.Set(*new(TResult), err)
-		return f
+	// Activity given by name, validate against the registered activity if available
+	fnActivity := activity
+	if name, ok := activity.(string); ok {
+		fnActivity = nil
+
+		if r, ok := registryFromContext(ctx); ok {
+			if registered, err := r.GetActivity(name); err == nil {
+				fnActivity = registered
+			}
+		}
 	}
 
-	// Check arguments
-	if err := a.ParamsMatch(activity, args...); err != nil {
-		f.Set(*new(TResult), err)
-		return f
+	if fnActivity != nil {
+		// Check return type
+		if err := a.ReturnTypeMatch[TResult](fnActivity); err != nil {
+			f.Set(*new(TResult), fmt.Errorf("activity %s: %w", fn.Name(activity), err))
+			return f, nil
+		}
+
+		// Check arguments
+		if err := a.ParamsMatch(fnActivity, args...); err != nil {
+			f.Set(*new(TResult), fmt.Errorf("activity %s: %w", fn.Name(activity), err))
+			return f, nil
+		}
 	}
 
 	cv := contextvalue.Converter(ctx)
 	inputs, err := a.ArgsToInputs(cv, args...)
 	if err != nil {
 		f.Set(*new(TResult), fmt.Errorf("converting activity input: %w", err))
-		return f
+		return f, nil
 	}
 
 	wfState := workflowstate.WorkflowState(ctx)
@@ -69,10 +158,14 @@ func executeActivity[TResult any](ctx Context, options ActivityOptions, attempt
 	metadata := &Metadata{}
 	if err := injectFromWorkflow(ctx, metadata, propagators); err != nil {
 		f.Set(*new(TResult), fmt.Errorf("injecting workflow context: %w", err))
-		return f
+		return f, nil
+	}
+
+	if activityID == 0 {
+		activityID = scheduleEventID
 	}
 
-	cmd := command.NewScheduleActivityCommand(scheduleEventID, name, inputs, attempt, metadata, options.Queue)
+	cmd := command.NewScheduleActivityCommand(scheduleEventID, name, inputs, attempt, activityID, metadata, options.Queue, options.CorrelationID, wfState.Deadline(), toHistoryRetryOptions(options.RetryOptions))
 	wfState.AddCommand(cmd)
 	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(cv, fmt.Sprintf("activity: %s", name), f))
 
@@ -82,7 +175,7 @@ func executeActivity[TResult any](ctx Context, options ActivityOptions, attempt
 			if _, ok := c.ReceiveNonBlocking(); ok {
 				// Workflow has been canceled, check if the activity has already been scheduled, no need to schedule otherwise
 				if cmd.State() == command.CommandState_Pending {
-					cmd.Done()
+					cmd.Cancel()
 					wfState.RemoveFuture(scheduleEventID)
 					f.Set(*new(TResult), Canceled)
 				}
@@ -90,5 +183,62 @@ func executeActivity[TResult any](ctx Context, options ActivityOptions, attempt
 		}
 	}
 
-	return f
+	// Activities started with ExecuteActivityAsync are canceled together with their context
+	if c, cancelable := ctx.Done().(sync.CancelChannel); cancelable && ctx.Value(activityCancelableKey{}) != nil {
+		cancelReceiver := &sync.Receiver[struct{}]{
+			Receive: func(v struct{}, ok bool) {
+				cmd.Cancel()
+
+				// Resolve the future right away, a result reported for the activity later on is ignored
+				if fi, ok := f.(sync.FutureInternal[TResult]); ok && !fi.Ready() {
+					wfState.RemoveFuture(scheduleEventID)
+					f.Set(*new(TResult), Canceled)
+				}
+			},
+		}
+
+		c.AddReceiveCallback(cancelReceiver)
+
+		cmd.WhenDone(func() {
+			c.RemoveReceiveCallback(cancelReceiver)
+		})
+	}
+
+	return f, cmd
+}
+
+// activityRegistry provides access to the activities registered with the worker executing the workflow
+type activityRegistry interface {
+	GetActivity(name string) (interface{}, error)
+	ActivityRetryOptions(name string) (RetryOptions, bool)
+}
+
+func registryFromContext(ctx Context) (activityRegistry, bool) {
+	r, ok := ctx.Value(contextvalue.RegistryCtxKey).(activityRegistry)
+	return r, ok
+}
+
+// activityRetryOptions returns the retry options to use for the given activity. Retry options registered for the
+// activity take precedence over default retry options.
+func activityRetryOptions(ctx Context, activity Activity, retryOptions RetryOptions) RetryOptions {
+	if !isDefaultRetryOptions(retryOptions) {
+		return retryOptions
+	}
+
+	r, ok := registryFromContext(ctx)
+	if !ok {
+		return retryOptions
+	}
+
+	registered, ok := r.ActivityRetryOptions(fn.Name(activity))
+	if !ok {
+		return retryOptions
+	}
+
+	return registered
+}
+
+// isDefaultRetryOptions returns whether the given retry options are not custom, i.e., empty or DefaultRetryOptions
+func isDefaultRetryOptions(retryOptions RetryOptions) bool {
+	return retryOptions == (RetryOptions{}) || retryOptions == DefaultRetryOptions
 }
@@ -0,0 +1,9 @@
+package workflow
+
+// BlobInput is a handle to content stored in the blob store of the backend, created with client.CreateBlobInput.
+// Passing a handle instead of the content as an input to workflows keeps large inputs out of the history. Workflows
+// only pass the handle on, activities read the content as a stream using activity.OpenBlob.
+type BlobInput struct {
+	// Key identifies the blob in the blob store
+	Key string `json:"key"`
+}
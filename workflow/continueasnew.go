@@ -9,8 +9,35 @@ import (
 	"github.com/cschleiden/go-workflows/internal/continueasnew"
 )
 
-// ContinueAsNew restarts the current workflow with the given arguments.
+type ContinueAsNewOptions struct {
+	// InheritMetadata determines whether the metadata of the current execution is carried over to the new execution.
+	InheritMetadata bool
+
+	// Metadata is added to the metadata of the new execution. Values override inherited metadata as well as
+	// metadata injected by context propagators.
+	Metadata map[string]string
+}
+
+var DefaultContinueAsNewOptions = ContinueAsNewOptions{
+	InheritMetadata: true,
+}
+
+// ContinueAsNew restarts the current workflow with the given arguments. The metadata of the current
+// execution is carried over to the new execution.
 func ContinueAsNew(ctx Context, args ...any) error {
+	return ContinueAsNewWithOptions(ctx, DefaultContinueAsNewOptions, args...)
+}
+
+// ContinueAsNewError returns an error that restarts the current workflow with the given arguments when it is
+// returned by the workflow, like the error returned by ContinueAsNew. The error is recognized even if it has been
+// wrapped. Since no workflow context is available, context propagators are not applied, but the metadata of the
+// current execution is carried over to the new execution.
+func ContinueAsNewError(args ...any) error {
+	return continueasnew.NewArgsError(args)
+}
+
+// ContinueAsNewWithOptions restarts the current workflow with the given arguments and options.
+func ContinueAsNewWithOptions(ctx Context, options ContinueAsNewOptions, args ...any) error {
 	// Capture context
 	propagators := propagators(ctx)
 	metadata := &metadata.WorkflowMetadata{}
@@ -18,11 +45,15 @@ func ContinueAsNew(ctx Context, args ...any) error {
 		return fmt.Errorf("injecting workflow context: %w", err)
 	}
 
+	for k, v := range options.Metadata {
+		metadata.Set(k, v)
+	}
+
 	cv := contextvalue.Converter(ctx)
 	inputs, err := a.ArgsToInputs(cv, args...)
 	if err != nil {
 		return fmt.Errorf("converting inputs for continuing workflow execution: %w", err)
 	}
 
-	return continueasnew.NewError(metadata, inputs)
+	return continueasnew.NewError(metadata, inputs, options.InheritMetadata)
 }
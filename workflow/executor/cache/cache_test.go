@@ -2,14 +2,11 @@ package cache
 
 import (
 	"context"
-	"log/slog"
 	"runtime"
 	"testing"
 	"time"
 
-	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend"
-	"github.com/cschleiden/go-workflows/backend/converter"
 	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/backend/metadata"
 	"github.com/cschleiden/go-workflows/core"
@@ -18,7 +15,6 @@ import (
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/cschleiden/go-workflows/workflow/executor"
 	"github.com/stretchr/testify/require"
-	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func Test_Cache_StoreAndGet(t *testing.T) {
@@ -28,17 +24,11 @@ func Test_Cache_StoreAndGet(t *testing.T) {
 	require.NoError(t, r.RegisterWorkflow(workflowWithActivity))
 
 	i := core.NewWorkflowInstance("instanceID", "executionID")
-	e, err := executor.NewExecutor(
-		slog.Default(), noop.NewTracerProvider().Tracer(backend.TracerName), r, converter.DefaultConverter,
-		[]workflow.ContextPropagator{}, &testHistoryProvider{}, i, &metadata.WorkflowMetadata{}, clock.New(),
-	)
+	e, err := executor.NewExecutor(r, &testHistoryProvider{}, i, &metadata.WorkflowMetadata{}, executor.ExecutorOptions{})
 	require.NoError(t, err)
 
 	i2 := core.NewWorkflowInstance("instanceID2", "executionID2")
-	e2, err := executor.NewExecutor(
-		slog.Default(), noop.NewTracerProvider().Tracer(backend.TracerName), r, converter.DefaultConverter,
-		[]workflow.ContextPropagator{}, &testHistoryProvider{}, i, &metadata.WorkflowMetadata{}, clock.New(),
-	)
+	e2, err := executor.NewExecutor(r, &testHistoryProvider{}, i, &metadata.WorkflowMetadata{}, executor.ExecutorOptions{})
 	require.NoError(t, err)
 
 	err = c.Store(context.Background(), i, e)
@@ -68,11 +58,7 @@ func Test_Cache_AutoEviction(t *testing.T) {
 	i := core.NewWorkflowInstance("instanceID", "executionID")
 	r := registry.New()
 	require.NoError(t, r.RegisterWorkflow(workflowWithActivity))
-	e, err := executor.NewExecutor(
-		slog.Default(), noop.NewTracerProvider().Tracer(backend.TracerName), r,
-		converter.DefaultConverter, []workflow.ContextPropagator{}, &testHistoryProvider{}, i,
-		&metadata.WorkflowMetadata{}, clock.New(),
-	)
+	e, err := executor.NewExecutor(r, &testHistoryProvider{}, i, &metadata.WorkflowMetadata{}, executor.ExecutorOptions{})
 	require.NoError(t, err)
 
 	err = c.Store(context.Background(), i, e)
@@ -98,11 +84,7 @@ func Test_Cache_Evict(t *testing.T) {
 	i := core.NewWorkflowInstance("instanceID", "executionID")
 	r := registry.New()
 	require.NoError(t, r.RegisterWorkflow(workflowWithActivity))
-	e, err := executor.NewExecutor(
-		slog.Default(), noop.NewTracerProvider().Tracer(backend.TracerName), r,
-		converter.DefaultConverter, []workflow.ContextPropagator{}, &testHistoryProvider{}, i,
-		&metadata.WorkflowMetadata{}, clock.New(),
-	)
+	e, err := executor.NewExecutor(r, &testHistoryProvider{}, i, &metadata.WorkflowMetadata{}, executor.ExecutorOptions{})
 	require.NoError(t, err)
 
 	err = c.Store(context.Background(), i, e)
@@ -140,6 +122,6 @@ type testHistoryProvider struct {
 	history []*history.Event
 }
 
-func (t *testHistoryProvider) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64) ([]*history.Event, error) {
+func (t *testHistoryProvider) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64, options ...backend.HistoryOption) ([]*history.Event, error) {
 	return t.history, nil
 }
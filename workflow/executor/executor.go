@@ -1,12 +1,14 @@
 package executor
 
 import (
+	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"reflect"
 	"slices"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -17,18 +19,23 @@ import (
 	"github.com/cschleiden/go-workflows/backend/metadata"
 	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/cschleiden/go-workflows/core"
+	a "github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/command"
 	"github.com/cschleiden/go-workflows/internal/contextvalue"
 	"github.com/cschleiden/go-workflows/internal/continueasnew"
+	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/log"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflowerrors"
+	"github.com/cschleiden/go-workflows/internal/workflows"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
 	"github.com/cschleiden/go-workflows/registry"
 	wf "github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 type ExecutionResult struct {
@@ -46,10 +53,36 @@ type ExecutionResult struct {
 
 	// Events for other workflow instances
 	WorkflowEvents []*history.WorkflowEvent
+
+	// Progress is the latest progress the workflow reported during the task, or nil if it did not report progress
+	Progress payload.Payload
+
+	// TimedOut is set when the task exceeded the task timeout and the workflow has been failed. The executor cannot
+	// be used anymore and must not be cached.
+	TimedOut bool
 }
 
+// ErrWorkflowTaskTimeout is returned when the execution of a single workflow task exceeds the configured
+// workflow task timeout.
+var ErrWorkflowTaskTimeout = errors.New("workflow task timed out")
+
+// ErrWorkflowResultTooLarge is the error a workflow fails with when its serialized result exceeds the configured
+// maximum result size.
+var ErrWorkflowResultTooLarge = errors.New("workflow result too large")
+
+// ErrWorkflowStuckInLoop is the error a workflow fails with when its code executes more than the configured maximum
+// number of iterations without yielding, e.g., when it loops selecting a default case without ever waiting.
+var ErrWorkflowStuckInLoop = sync.ErrStuckInLoop
+
+// DefaultMaxIterations is the maximum number of iterations workflow code can execute without yielding, used if no
+// other limit is configured.
+const DefaultMaxIterations = 1_000_000
+
+// errWorkflowTerminated is the error a terminated workflow finishes with
+var errWorkflowTerminated = errors.New("workflow terminated")
+
 type WorkflowHistoryProvider interface {
-	GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64) ([]*history.Event, error)
+	GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64, options ...backend.HistoryOption) ([]*history.Event, error)
 }
 
 type WorkflowExecutor interface {
@@ -64,6 +97,7 @@ type executor struct {
 	workflow          *workflow
 	workflowName      string
 	workflowState     *workflowstate.WfState
+	metadata          *metadata.WorkflowMetadata
 	workflowCtx       sync.Context
 	workflowCtxCancel sync.CancelFunc
 	cv                converter.Converter
@@ -71,33 +105,108 @@ type executor struct {
 	logger            *slog.Logger
 	tracer            trace.Tracer
 	lastSequenceID    int64
+	taskTimeout       time.Duration
+	maxResultSize     int
+	maxIterations     int
+	versionOverrides  map[string]int
+
+	// completionWebhook is the URL the outcome of the workflow is delivered to when it finishes
+	completionWebhook string
+
+	// terminated is set when the workflow has been terminated, no more workflow code is executed then
+	terminated bool
+
+	// initialSignals is the number of initial signals still to be received before the workflow first runs
+	initialSignals int
+
+	// timedOut is set when a task exceeded the task timeout. The workflow coroutines might still be
+	// blocked in that case, and the executor cannot be used anymore.
+	timedOut atomic.Bool
 
 	parentSpan   trace.Span
 	workflowSpan trace.Span
 }
 
+// ExecutorOptions configure a workflow executor.
+type ExecutorOptions struct {
+	// Logger is the logger used by the executor and the workflow. Defaults to slog.Default().
+	Logger *slog.Logger
+
+	// Tracer is used to trace workflow executions. Defaults to a tracer that does not record spans.
+	Tracer trace.Tracer
+
+	// Converter is used to serialize workflow inputs and results. Defaults to converter.DefaultConverter.
+	Converter converter.Converter
+
+	// Propagators propagate context to the workflow, and from the workflow to activities and sub-workflows.
+	Propagators []wf.ContextPropagator
+
+	// Clock is the clock used by the executor. Defaults to the wall clock.
+	Clock clock.Clock
+
+	// TaskTimeout is the maximum duration of a single workflow task. If 0, tasks are not timed out.
+	//
+	// Only the execution of the new events of a task is timed, replaying the history of the instance is not counted.
+	// Workflow code that exceeds the timeout cannot be stopped: its goroutine is abandoned and keeps running, and the
+	// executor cannot be used anymore.
+	TaskTimeout time.Duration
+
+	// MaxResultSize is the maximum size of a serialized workflow result in bytes. If 0, results are not limited.
+	MaxResultSize int
+
+	// MaxIterations is the maximum number of iterations workflow code can execute without yielding. Defaults to
+	// DefaultMaxIterations.
+	MaxIterations int
+
+	// VersionOverrides replace the versions of changes recorded in the history, e.g., to check how an instance that
+	// recorded other versions replays.
+	VersionOverrides map[string]int
+}
+
 func NewExecutor(
-	logger *slog.Logger,
-	tracer trace.Tracer,
 	registry *registry.Registry,
-	cv converter.Converter,
-	propagators []wf.ContextPropagator,
 	historyProvider WorkflowHistoryProvider,
 	instance *core.WorkflowInstance,
 	metadata *metadata.WorkflowMetadata,
-	clock clock.Clock,
+	options ExecutorOptions,
 ) (WorkflowExecutor, error) {
-	s := workflowstate.NewWorkflowState(instance, logger, tracer, clock)
+	logger := options.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	tracer := options.Tracer
+	if tracer == nil {
+		tracer = noop.NewTracerProvider().Tracer("executor")
+	}
+
+	cv := options.Converter
+	if cv == nil {
+		cv = converter.DefaultConverter
+	}
+
+	clk := options.Clock
+	if clk == nil {
+		clk = clock.New()
+	}
+
+	maxIterations := options.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxIterations
+	}
+
+	s := workflowstate.NewWorkflowState(instance, logger, tracer, clk)
 
 	wfCtx := sync.Background()
 	wfCtx = contextvalue.WithConverter(wfCtx, cv)
 	wfCtx = workflowstate.WithWorkflowState(wfCtx, s)
-	wfCtx = sync.WithValue(wfCtx, contextvalue.PropagatorsCtxKey, propagators)
+	wfCtx = sync.WithValue(wfCtx, contextvalue.PropagatorsCtxKey, options.Propagators)
+	wfCtx = sync.WithValue(wfCtx, contextvalue.RegistryCtxKey, registry)
 	wfCtx, cancel := sync.WithCancel(wfCtx)
 
 	// As part of this, the default tracing propagator will run, and set the parent span
 	// in the context, which will be picked up by our workflow span later.
-	for _, propagator := range propagators {
+	for _, propagator := range options.Propagators {
 		var err error
 		wfCtx, err = propagator.ExtractToWorkflow(wfCtx, metadata)
 		if err != nil {
@@ -114,16 +223,107 @@ func NewExecutor(
 		registry:          registry,
 		historyProvider:   historyProvider,
 		workflowState:     s,
+		metadata:          metadata,
 		workflowCtx:       wfCtx,
 		workflowCtxCancel: cancel,
 		cv:                cv,
-		clock:             clock,
+		clock:             clk,
 		logger:            logger,
 		tracer:            tracer,
+		taskTimeout:       options.TaskTimeout,
+		maxResultSize:     options.MaxResultSize,
+		maxIterations:     maxIterations,
+		versionOverrides:  options.VersionOverrides,
 	}, nil
 }
 
 func (e *executor) ExecuteTask(ctx context.Context, t *backend.WorkflowTask) (*ExecutionResult, error) {
+	if e.timedOut.Load() {
+		return nil, ErrWorkflowTaskTimeout
+	}
+
+	return e.executeTask(ctx, t)
+}
+
+// executeNewEventsWithTimeout executes the given new events like executeNewEvents, but gives up waiting for the
+// workflow code if it runs longer than the task timeout. timedOut is set in that case, the workflow code is left
+// running in the background and the executor cannot be used anymore.
+func (e *executor) executeNewEventsWithTimeout(t *backend.WorkflowTask, newEvents []*history.Event) (executed []*history.Event, timedOut bool, err error) {
+	if e.taskTimeout <= 0 {
+		executed, err := e.executeNewEvents(newEvents)
+		return executed, false, err
+	}
+
+	// Determine the name before starting execution, the workflow name is only set once the first task has executed
+	workflowName := e.workflowName
+	for _, event := range t.NewEvents {
+		if a, ok := event.Attributes.(*history.ExecutionStartedAttributes); ok {
+			workflowName = a.Name
+		}
+	}
+
+	type executeResult struct {
+		executed []*history.Event
+		err      error
+	}
+
+	done := make(chan executeResult, 1)
+	go func() {
+		executed, err := e.executeNewEvents(newEvents)
+		done <- executeResult{executed, err}
+	}()
+
+	// The timeout is about the wall-clock time spent executing workflow code, so don't use the executor clock here
+	timer := time.NewTimer(e.taskTimeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		return r.executed, false, r.err
+
+	case <-timer.C:
+		e.timedOut.Store(true)
+
+		e.logger.Error("Workflow task exceeded task timeout, workflow code might be blocking, failing workflow",
+			log.WorkflowNameKey, workflowName,
+			log.TaskIDKey, t.ID,
+			log.DurationKey, e.taskTimeout.Milliseconds(),
+		)
+
+		return nil, true, fmt.Errorf("executing workflow %s: %w", workflowName, ErrWorkflowTaskTimeout)
+	}
+}
+
+// timedOutResult fails the workflow after executing the given events exceeded the task timeout. The workflow code
+// might still be running and reading the events and the state of the executor, so the failure is recorded without
+// them: copies of the events are added to the history, followed by the failure. Running sub-workflows are abandoned,
+// and no completion webhook is delivered.
+func (e *executor) timedOutResult(t *backend.WorkflowTask, events []*history.Event, err error) *ExecutionResult {
+	executed := make([]*history.Event, 0, len(events)+1)
+	for _, event := range events {
+		copied := *event
+		executed = append(executed, &copied)
+	}
+
+	cmd := command.NewCompleteWorkflowCommand(0, t.WorkflowInstance, nil, workflowerrors.FromError(err))
+	r := cmd.Execute(e.clock)
+	executed = append(executed, r.Events...)
+
+	for i, event := range executed {
+		event.SequenceID = t.LastSequenceID + int64(i) + 1
+	}
+
+	return &ExecutionResult{
+		State:          r.State,
+		Executed:       executed,
+		ActivityEvents: []*history.Event{},
+		TimerEvents:    []*history.Event{},
+		WorkflowEvents: r.WorkflowEvents,
+		TimedOut:       true,
+	}
+}
+
+func (e *executor) executeTask(ctx context.Context, t *backend.WorkflowTask) (*ExecutionResult, error) {
 	logger := e.logger.With(
 		log.TaskIDKey, t.ID,
 	)
@@ -161,8 +361,21 @@ func (e *executor) ExecuteTask(ctx context.Context, t *backend.WorkflowTask) (*E
 	// Execute new events received from the backend
 	if !skipNewEvents {
 		var err error
-		executedEvents, err = e.executeNewEvents(toExecute)
+		var timedOut bool
+		executedEvents, timedOut, err = e.executeNewEventsWithTimeout(t, toExecute)
+		if timedOut {
+			return e.timedOutResult(t, toExecute, err), nil
+		}
+
 		if err != nil {
+			// Don't record any new events if the workflow diverged from its history, fail the task instead
+			var ndErr *NonDeterminismError
+			if errors.As(err, &ndErr) {
+				logger.Error("Non-determinism detected while executing new events", "error", err)
+
+				return nil, err
+			}
+
 			logger.Error("Error while executing new events", "error", err)
 
 			// Transition workflow to error state
@@ -216,6 +429,7 @@ func (e *executor) ExecuteTask(ctx context.Context, t *backend.WorkflowTask) (*E
 		ActivityEvents: activityEvents,
 		TimerEvents:    timerEvents,
 		WorkflowEvents: workflowEvents,
+		Progress:       e.workflowState.TakeProgress(),
 	}, nil
 }
 
@@ -229,12 +443,22 @@ func (e *executor) catchupOnHistory(ctx context.Context, t *backend.WorkflowTask
 			log.TaskSequenceIDKey, t.LastSequenceID,
 			log.LocalSequenceIDKey, e.lastSequenceID)
 
-		h, err := e.historyProvider.GetWorkflowInstanceHistory(ctx, t.WorkflowInstance, &e.lastSequenceID)
+		// Activity inputs are not required for replay, skip loading them
+		h, err := e.historyProvider.GetWorkflowInstanceHistory(ctx, t.WorkflowInstance, &e.lastSequenceID, backend.HistoryWithoutActivityInputs())
 		if err != nil {
 			return false, fmt.Errorf("getting workflow history: %w", err)
 		}
 
 		if err := e.replayHistory(h); err != nil {
+			// Workflow code diverged from its history, fail the task instead of the workflow. This allows the
+			// task to succeed once the workflow code has been fixed.
+			var ndErr *NonDeterminismError
+			if errors.As(err, &ndErr) {
+				logger.Error("Non-determinism detected while replaying history", "error", err)
+
+				return false, err
+			}
+
 			logger.Error("Error while replaying history", "error", err)
 
 			// Fail workflow with an error. Skip executing new events, but still go through the commands
@@ -244,7 +468,17 @@ func (e *executor) catchupOnHistory(ctx context.Context, t *backend.WorkflowTask
 			e.lastSequenceID = t.LastSequenceID
 
 			return true, nil
-		} else if t.LastSequenceID != e.lastSequenceID {
+		}
+
+		if !isResetTask(t) {
+			if err := e.checkReplayedCommands(); err != nil {
+				logger.Error("Non-determinism detected while replaying history", "error", err)
+
+				return false, err
+			}
+		}
+
+		if t.LastSequenceID != e.lastSequenceID {
 			logger.Error("After replaying history, task still has newer history than current state",
 				log.TaskSequenceIDKey, t.LastSequenceID,
 				log.LocalSequenceIDKey, e.lastSequenceID)
@@ -256,8 +490,30 @@ func (e *executor) catchupOnHistory(ctx context.Context, t *backend.WorkflowTask
 	return false, nil
 }
 
+func isResetTask(t *backend.WorkflowTask) bool {
+	for _, event := range t.NewEvents {
+		if event.Type == history.EventType_WorkflowExecutionReset {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (e *executor) replayHistory(h []*history.Event) error {
 	e.workflowState.SetReplaying(true)
+
+	// Versions are recorded after the workflow code asked for them, make them available before replaying that code
+	for _, event := range h {
+		if a, ok := event.Attributes.(*history.VersionRecordedAttributes); ok {
+			e.workflowState.SetVersion(a.ChangeID, a.Version)
+		}
+	}
+
+	for changeID, version := range e.versionOverrides {
+		e.workflowState.SetVersion(changeID, version)
+	}
+
 	for _, event := range h {
 		if event.SequenceID < e.lastSequenceID {
 			e.logger.Error("history has older events than current state")
@@ -274,6 +530,27 @@ func (e *executor) replayHistory(h []*history.Event) error {
 	return nil
 }
 
+// checkReplayedCommands ensures that the workflow did not produce more commands while replaying than are recorded in
+// its history. Commands are recorded in the same task they are produced in, so any command that is still pending
+// after replaying the history was added to the workflow code, e.g., an additional side effect. Commands produced after
+// it would otherwise be matched with events recorded for other commands, and receive their results.
+//
+// This does not hold after a reset, history then ends in the middle of a task.
+func (e *executor) checkReplayedCommands() error {
+	for _, c := range e.workflowState.Commands() {
+		if c.State() == command.CommandState_Pending {
+			return &NonDeterminismError{
+				Message:         "workflow produced a command that is not recorded in history",
+				SequenceID:      e.lastSequenceID,
+				ScheduleEventID: c.ID(),
+				CommandType:     c.Type(),
+			}
+		}
+	}
+
+	return nil
+}
+
 func (e *executor) executeNewEvents(newEvents []*history.Event) ([]*history.Event, error) {
 	e.workflowState.SetReplaying(false)
 
@@ -281,11 +558,30 @@ func (e *executor) executeNewEvents(newEvents []*history.Event) ([]*history.Even
 		if err := e.executeEvent(event); err != nil {
 			return newEvents[:i], err
 		}
+
+		if e.terminated {
+			// Timers won't fire anymore, events received after the termination are discarded
+			for _, c := range e.workflowState.Commands() {
+				if stc, ok := c.(*command.ScheduleTimerCommand); ok && stc.State() == command.CommandState_Committed {
+					stc.Cancel()
+				}
+			}
+
+			e.workflowSpan.End()
+			e.workflowCompleted(nil, errWorkflowTerminated)
+
+			return newEvents[:i+1], nil
+		}
 	}
 
 	if e.workflow.Completed() {
 		defer e.workflowSpan.End()
 
+		// Running sub-workflows are closed or abandoned with the workflow, their results are not waited for
+		for _, c := range e.runningSubWorkflows() {
+			e.workflowState.RemoveFuture(c.ID())
+		}
+
 		if e.workflowState.HasPendingFutures() {
 			// This should not happen, provide debug information to the developer
 			var pending []string
@@ -303,8 +599,13 @@ func (e *executor) executeNewEvents(newEvents []*history.Event) ([]*history.Even
 				e.workflowSpan, fmt.Errorf("workflow completed, but there are still pending futures: %s", pending))
 		}
 
-		if canErr, ok := e.workflow.Error().(*continueasnew.Error); ok {
-			e.workflowRestarted(e.workflow.Result(), canErr)
+		var canErr *continueasnew.Error
+		if errors.As(e.workflow.Error(), &canErr) {
+			if err := e.workflowRestarted(e.workflow.Result(), canErr); err != nil {
+				e.workflowCompleted(nil, err)
+			}
+		} else if err := e.checkResultSize(e.workflow.Result()); err != nil {
+			e.workflowCompleted(nil, err)
 		} else {
 			e.workflowCompleted(e.workflow.Result(), e.workflow.Error())
 		}
@@ -314,6 +615,12 @@ func (e *executor) executeNewEvents(newEvents []*history.Event) ([]*history.Even
 }
 
 func (e *executor) Close() {
+	if e.timedOut.Load() {
+		// Workflow coroutines might still be blocked, trying to stop them would block as well.
+		e.logger.Warn("Not stopping workflow executor after task timeout")
+		return
+	}
+
 	if e.workflow != nil {
 		e.logger.Debug("Stopping workflow executor", log.InstanceIDKey, e.workflowState.Instance().InstanceID)
 
@@ -348,9 +655,15 @@ func (e *executor) executeEvent(event *history.Event) error {
 	case history.EventType_WorkflowExecutionFinished:
 	// Ignore
 
+	case history.EventType_WorkflowExecutionReset:
+	// Ignore, history has already been reset by the backend
+
 	case history.EventType_WorkflowExecutionCanceled:
 		err = e.handleWorkflowCanceled()
 
+	case history.EventType_WorkflowExecutionTerminated:
+		e.terminated = true
+
 	case history.EventType_WorkflowTaskStarted:
 		err = e.handleWorkflowTaskStarted(event, event.Attributes.(*history.WorkflowTaskStartedAttributes))
 
@@ -363,6 +676,9 @@ func (e *executor) executeEvent(event *history.Event) error {
 	case history.EventType_ActivityCompleted:
 		err = e.handleActivityCompleted(event, event.Attributes.(*history.ActivityCompletedAttributes))
 
+	case history.EventType_ActivityCanceled:
+		err = e.handleActivityCanceled(event, event.Attributes.(*history.ActivityCanceledAttributes))
+
 	case history.EventType_TimerScheduled:
 		err = e.handleTimerScheduled(event, event.Attributes.(*history.TimerScheduledAttributes))
 
@@ -390,6 +706,9 @@ func (e *executor) executeEvent(event *history.Event) error {
 	case history.EventType_TraceStarted:
 		err = e.handleTraceStarted(event, event.Attributes.(*history.TraceStartedAttributes))
 
+	case history.EventType_VersionRecorded:
+	// Ignore, versions have been read before replaying the history
+
 	default:
 		return fmt.Errorf("unknown event type: %v", event.Type)
 	}
@@ -399,6 +718,7 @@ func (e *executor) executeEvent(event *history.Event) error {
 
 func (e *executor) handleWorkflowExecutionStarted(event *history.Event, a *history.ExecutionStartedAttributes) error {
 	e.workflowName = a.Name
+	e.completionWebhook = a.CompletionWebhook
 
 	wfFn, err := e.registry.GetWorkflow(a.Name)
 	if err != nil {
@@ -418,10 +738,49 @@ func (e *executor) handleWorkflowExecutionStarted(event *history.Event, a *histo
 
 	// Set in context for workflow execution
 	e.workflowCtx = tracing.ContextWithSpan(e.workflowCtx, span)
+	e.workflowCtx = sync.WithMaxIterations(e.workflowCtx, e.maxIterations)
 	e.workflowSpan = span
 
+	inputs := a.Inputs
+	if a.InputsCompressed {
+		if inputs, err = decompressInputs(inputs); err != nil {
+			return fmt.Errorf("decompressing inputs: %w", err)
+		}
+	}
+
+	e.workflowState.SetInputs(inputs)
+
+	// The started event is part of the history, so the deadline is the same when replaying
+	e.workflowState.SetExecutionTimeout(event.Timestamp, a.ExecutionTimeout)
+
+	e.workflowState.SetOrderedSignals(a.OrderedSignals)
+
 	e.workflow = newWorkflow(reflect.ValueOf(wfFn))
-	return e.workflow.Execute(e.workflowCtx, a.Inputs)
+
+	// Initial signals directly follow the started event, the workflow only runs once they have been buffered
+	if a.InitialSignals > 0 {
+		e.initialSignals = a.InitialSignals
+		return nil
+	}
+
+	return e.workflow.Execute(e.workflowCtx, inputs)
+}
+
+// decompressInputs reverses the compression of workflow inputs by the client
+func decompressInputs(inputs []payload.Payload) ([]payload.Payload, error) {
+	codec, err := converter.NewGzipCodec(gzip.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	decompressed := make([]payload.Payload, len(inputs))
+	for i, input := range inputs {
+		if decompressed[i], err = codec.Decode(input); err != nil {
+			return nil, err
+		}
+	}
+
+	return decompressed, nil
 }
 
 func (e *executor) handleWorkflowCanceled() error {
@@ -439,19 +798,22 @@ func (e *executor) handleWorkflowTaskStarted(event *history.Event, a *history.Wo
 func (e *executor) handleActivityScheduled(event *history.Event, a *history.ActivityScheduledAttributes) error {
 	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
 	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled an activity which could not be found")
+		return newNonDeterminismError(event, nil, "workflow did not schedule an activity")
 	}
 
 	sac, ok := c.(*command.ScheduleActivityCommand)
 	if !ok {
-		return fmt.Errorf("previous workflow execution scheduled an activity, not: %v", c.Type())
+		return newNonDeterminismError(event, c, "workflow did not schedule an activity")
 	}
 
 	// Ensure the same activity was scheduled again
 	if a.Name != sac.Name {
-		return fmt.Errorf("previous workflow execution scheduled different type of activity: %s, %s", a.Name, sac.Name)
+		return newNonDeterminismError(event, c, "workflow scheduled activity %q, history recorded %q", sac.Name, a.Name)
 	}
 
+	// Retries are based on the recorded retry options, options registered for the activity might have changed
+	sac.RetryOptions = a.RetryOptions
+
 	sac.Commit()
 
 	return nil
@@ -460,6 +822,11 @@ func (e *executor) handleActivityScheduled(event *history.Event, a *history.Acti
 func (e *executor) handleActivityCompleted(event *history.Event, a *history.ActivityCompletedAttributes) error {
 	f, ok := e.workflowState.FutureByScheduleEventID(event.ScheduleEventID)
 	if !ok {
+		if e.activityCanceled(event) {
+			// Activity has been canceled by the workflow, ignore its result
+			return nil
+		}
+
 		return fmt.Errorf("could not find pending future for activity completion")
 	}
 
@@ -472,12 +839,12 @@ func (e *executor) handleActivityCompleted(event *history.Event, a *history.Acti
 
 	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
 	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled an activity which could not be found")
+		return newNonDeterminismError(event, nil, "workflow did not schedule an activity")
 	}
 
 	sac, ok := c.(*command.ScheduleActivityCommand)
 	if !ok {
-		return fmt.Errorf("previous workflow execution scheduled an activity, not: %v", c.Type())
+		return newNonDeterminismError(event, c, "workflow did not schedule an activity")
 	}
 
 	sac.Done()
@@ -488,6 +855,11 @@ func (e *executor) handleActivityCompleted(event *history.Event, a *history.Acti
 func (e *executor) handleActivityFailed(event *history.Event, a *history.ActivityFailedAttributes) error {
 	f, ok := e.workflowState.FutureByScheduleEventID(event.ScheduleEventID)
 	if !ok {
+		if e.activityCanceled(event) {
+			// Activity has been canceled by the workflow, ignore its result
+			return nil
+		}
+
 		return errors.New("no pending future for activity failed event")
 	}
 
@@ -500,12 +872,12 @@ func (e *executor) handleActivityFailed(event *history.Event, a *history.Activit
 
 	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
 	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled an activity which could not be found")
+		return newNonDeterminismError(event, nil, "workflow did not schedule an activity")
 	}
 
 	sac, ok := c.(*command.ScheduleActivityCommand)
 	if !ok {
-		return fmt.Errorf("previous workflow execution scheduled an activity, not: %v", c.Type())
+		return newNonDeterminismError(event, c, "workflow did not schedule an activity")
 	}
 
 	sac.Done()
@@ -513,14 +885,56 @@ func (e *executor) handleActivityFailed(event *history.Event, a *history.Activit
 	return e.workflow.Continue()
 }
 
+func (e *executor) handleActivityCanceled(event *history.Event, a *history.ActivityCanceledAttributes) error {
+	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
+	if c == nil {
+		return newNonDeterminismError(event, nil, "workflow did not schedule the canceled activity")
+	}
+
+	sac, ok := c.(*command.ScheduleActivityCommand)
+	if !ok {
+		return newNonDeterminismError(event, c, "workflow did not schedule the canceled activity")
+	}
+
+	sac.HandleCancel()
+
+	return nil
+}
+
+// activityCanceled returns whether the activity the given event belongs to has been canceled by the workflow. The
+// activity command is marked as done, since no further events are expected for it.
+func (e *executor) activityCanceled(event *history.Event) bool {
+	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
+	if c == nil {
+		return false
+	}
+
+	sac, ok := c.(*command.ScheduleActivityCommand)
+	if !ok {
+		return false
+	}
+
+	switch sac.State() {
+	case command.CommandState_Canceled:
+		sac.Done()
+		return true
+
+	case command.CommandState_CancelPending:
+		// Canceled while handling the events of this task, the cancellation is still recorded when the task completes
+		return true
+	}
+
+	return false
+}
+
 func (e *executor) handleTimerScheduled(event *history.Event, a *history.TimerScheduledAttributes) error {
 	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
 	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled a timer")
+		return newNonDeterminismError(event, nil, "workflow did not schedule a timer")
 	}
 
 	if _, ok := c.(*command.ScheduleTimerCommand); !ok {
-		return fmt.Errorf("previous workflow execution scheduled a timer, not: %v", c.Type())
+		return newNonDeterminismError(event, c, "workflow did not schedule a timer")
 	}
 
 	c.Commit()
@@ -566,7 +980,7 @@ func (e *executor) handleTimerFired(event *history.Event, a *history.TimerFiredA
 	}
 
 	if _, ok := c.(*command.ScheduleTimerCommand); !ok {
-		return fmt.Errorf("schedule timer command not found, instead: %v", c.Type())
+		return newNonDeterminismError(event, c, "workflow did not schedule a timer")
 	}
 
 	c.Done()
@@ -577,12 +991,12 @@ func (e *executor) handleTimerFired(event *history.Event, a *history.TimerFiredA
 func (e *executor) handleTimerCanceled(event *history.Event, a *history.TimerCanceledAttributes) error {
 	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
 	if c == nil {
-		return fmt.Errorf("previous workflow execution canceled a timer")
+		return newNonDeterminismError(event, nil, "workflow did not schedule the canceled timer")
 	}
 
 	stc, ok := c.(*command.ScheduleTimerCommand)
 	if !ok {
-		return fmt.Errorf("previous workflow execution canceled a timer, not: %v", c.Type())
+		return newNonDeterminismError(event, c, "workflow did not schedule the canceled timer")
 	}
 
 	stc.HandleCancel()
@@ -606,16 +1020,16 @@ func (e *executor) handleTimerCanceled(event *history.Event, a *history.TimerCan
 func (e *executor) handleSubWorkflowScheduled(event *history.Event, a *history.SubWorkflowScheduledAttributes) error {
 	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
 	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled a sub workflow")
+		return newNonDeterminismError(event, nil, "workflow did not schedule a sub-workflow")
 	}
 
 	sswc, ok := c.(*command.ScheduleSubWorkflowCommand)
 	if !ok {
-		return fmt.Errorf("previous workflow execution scheduled a sub workflow, not: %v", c.Type())
+		return newNonDeterminismError(event, c, "workflow did not schedule a sub-workflow")
 	}
 
 	if a.Name != sswc.Name {
-		return fmt.Errorf("previous workflow execution scheduled different type of sub workflow: %s, %s", a.Name, sswc.Name)
+		return newNonDeterminismError(event, c, "workflow scheduled sub-workflow %q, history recorded %q", sswc.Name, a.Name)
 	}
 
 	// If we are replaying this event, the command will have generated a new instance ID. Ensure we use the same one as
@@ -630,12 +1044,12 @@ func (e *executor) handleSubWorkflowScheduled(event *history.Event, a *history.S
 func (e *executor) handleSubWorkflowCancellationRequest(event *history.Event, a *history.SubWorkflowCancellationRequestedAttributes) error {
 	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
 	if c == nil {
-		return fmt.Errorf("previous workflow execution cancelled a sub-workflow execution")
+		return newNonDeterminismError(event, nil, "workflow did not schedule the canceled sub-workflow")
 	}
 
 	sswc, ok := c.(*command.ScheduleSubWorkflowCommand)
 	if !ok {
-		return fmt.Errorf("previous workflow execution cancelled a sub-workflow execution, not: %v", c.Type())
+		return newNonDeterminismError(event, c, "workflow did not schedule the canceled sub-workflow")
 	}
 
 	sswc.HandleCancel()
@@ -659,11 +1073,11 @@ func (e *executor) handleSubWorkflowFailed(event *history.Event, a *history.SubW
 
 	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
 	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled a sub-workflow execution")
+		return newNonDeterminismError(event, nil, "workflow did not schedule a sub-workflow")
 	}
 
 	if _, ok := c.(*command.ScheduleSubWorkflowCommand); !ok {
-		return fmt.Errorf("previous workflow execution cancelled a sub-workflow execution, not: %v", c.Type())
+		return newNonDeterminismError(event, c, "workflow did not schedule a sub-workflow")
 	}
 
 	c.Done()
@@ -685,11 +1099,11 @@ func (e *executor) handleSubWorkflowCompleted(event *history.Event, a *history.S
 
 	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
 	if c == nil {
-		return fmt.Errorf("previous workflow execution cancelled a sub-workflow execution")
+		return newNonDeterminismError(event, nil, "workflow did not schedule a sub-workflow")
 	}
 
 	if _, ok := c.(*command.ScheduleSubWorkflowCommand); !ok {
-		return fmt.Errorf("previous workflow execution cancelled a sub-workflow execution, not: %v", c.Type())
+		return newNonDeterminismError(event, c, "workflow did not schedule a sub-workflow")
 	}
 
 	c.Done()
@@ -701,18 +1115,27 @@ func (e *executor) handleSignalReceived(event *history.Event, a *history.SignalR
 	// Send signal to workflow channel
 	workflowstate.ReceiveSignal(e.workflowState, a.Name, a.Arg)
 
+	if e.initialSignals > 0 {
+		e.initialSignals--
+		if e.initialSignals > 0 {
+			return nil
+		}
+
+		return e.workflow.Execute(e.workflowCtx, e.workflowState.Inputs())
+	}
+
 	return e.workflow.Continue()
 }
 
 func (e *executor) handleSideEffectResult(event *history.Event, a *history.SideEffectResultAttributes) error {
 	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
 	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled a side effect")
+		return newNonDeterminismError(event, nil, "workflow did not execute a side effect")
 	}
 
 	sec, ok := c.(*command.SideEffectCommand)
 	if !ok {
-		return fmt.Errorf("previous workflow execution scheduled a side effect, not: %v", c.Type())
+		return newNonDeterminismError(event, c, "workflow did not execute a side effect")
 	}
 
 	sec.Done()
@@ -734,12 +1157,12 @@ func (e *executor) handleSideEffectResult(event *history.Event, a *history.SideE
 func (e *executor) handleTraceStarted(event *history.Event, a *history.TraceStartedAttributes) error {
 	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
 	if c == nil {
-		return fmt.Errorf("previous workflow execution started a trace")
+		return newNonDeterminismError(event, nil, "workflow did not start a trace")
 	}
 
 	stc, ok := c.(*command.StartTraceCommand)
 	if !ok {
-		return fmt.Errorf("previous workflow execution started a trace, not: %v", c.Type())
+		return newNonDeterminismError(event, c, "workflow did not start a trace")
 	}
 
 	stc.Done()
@@ -759,22 +1182,131 @@ func (e *executor) handleTraceStarted(event *history.Event, a *history.TraceStar
 }
 
 func (e *executor) workflowCompleted(result payload.Payload, wfErr error) {
+	if errors.Is(wfErr, ErrWorkflowStuckInLoop) {
+		e.logger.Error("Workflow stuck in loop, failing it",
+			log.WorkflowNameKey, e.workflowName,
+			log.ErrorKey, wfErr,
+		)
+	}
+
 	eventId := e.workflowState.GetNextScheduleEventID()
 
 	cmd := command.NewCompleteWorkflowCommand(eventId, e.workflowState.Instance(), result, workflowerrors.FromError(wfErr))
+
+	if e.completionWebhook != "" {
+		webhook, err := e.completionWebhookCommand(cmd)
+		if err != nil {
+			// The workflow outcome is recorded regardless
+			e.logger.Error("could not schedule completion webhook", log.ErrorKey, err)
+		} else {
+			cmd.CompletionWebhook = webhook
+		}
+	}
+
+	// Apply the parent close policy of sub-workflows that are still running
+	for _, c := range e.runningSubWorkflows() {
+		switch c.ParentClosePolicy {
+		case core.ParentClosePolicyRequestCancel:
+			c.Cancel()
+
+		case core.ParentClosePolicyTerminate:
+			if c.State() == command.CommandState_Pending {
+				// Sub-workflow has not been started yet, don't start it
+				c.Cancel()
+			} else {
+				cmd.TerminateSubWorkflows = append(cmd.TerminateSubWorkflows, c.Instance)
+			}
+		}
+	}
+
 	e.workflowState.AddCommand(cmd)
 }
 
-func (e *executor) workflowRestarted(result payload.Payload, continueAsNew *continueasnew.Error) {
+// runningSubWorkflows returns the commands of sub-workflows that have been scheduled and have not finished or been
+// canceled yet
+func (e *executor) runningSubWorkflows() []*command.ScheduleSubWorkflowCommand {
+	var running []*command.ScheduleSubWorkflowCommand
+	for _, c := range e.workflowState.Commands() {
+		sswc, ok := c.(*command.ScheduleSubWorkflowCommand)
+		if !ok {
+			continue
+		}
+
+		if sswc.State() == command.CommandState_Pending || sswc.State() == command.CommandState_Committed {
+			running = append(running, sswc)
+		}
+	}
+
+	return running
+}
+
+// completionWebhookCommand returns the workflow instance delivering the outcome of the given completion
+func (e *executor) completionWebhookCommand(cmd *command.CompleteWorkflowCommand) (*command.CompletionWebhook, error) {
+	instance := e.workflowState.Instance()
+
+	inputs, err := a.ArgsToInputs(e.cv, workflows.CompletionWebhook{
+		URL:      e.completionWebhook,
+		Instance: instance,
+		Result:   cmd.Result,
+		Error:    cmd.Error,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("converting completion webhook inputs: %w", err)
+	}
+
+	return &command.CompletionWebhook{
+		Instance: core.NewWorkflowInstance(workflows.CompletionWebhookInstanceID(instance), uuid.NewString()),
+		Name:     fn.Name(workflows.DeliverCompletionWebhook),
+		Inputs:   inputs,
+	}, nil
+}
+
+// checkResultSize returns an error if the given serialized workflow result exceeds the maximum result size
+func (e *executor) checkResultSize(result payload.Payload) error {
+	if e.maxResultSize <= 0 || len(result) <= e.maxResultSize {
+		return nil
+	}
+
+	return fmt.Errorf("%w: result is %d bytes, maximum is %d bytes", ErrWorkflowResultTooLarge, len(result), e.maxResultSize)
+}
+
+func (e *executor) workflowRestarted(result payload.Payload, continueAsNew *continueasnew.Error) error {
+	inputs := continueAsNew.Inputs
+	if continueAsNew.Args != nil {
+		var err error
+		inputs, err = a.ArgsToInputs(e.cv, continueAsNew.Args...)
+		if err != nil {
+			return fmt.Errorf("converting inputs for continuing workflow execution: %w", err)
+		}
+	}
+
 	eventId := e.workflowState.GetNextScheduleEventID()
 
+	md := continueAsNew.Metadata
+	if continueAsNew.InheritMetadata && e.metadata != nil {
+		md = &metadata.WorkflowMetadata{}
+		for k, v := range *e.metadata {
+			md.Set(k, v)
+		}
+
+		// Metadata given when continuing takes precedence
+		if continueAsNew.Metadata != nil {
+			for k, v := range *continueAsNew.Metadata {
+				md.Set(k, v)
+			}
+		}
+	}
+
 	cmd := command.NewContinueAsNewCommand(
-		eventId, e.workflowState.Instance(), result, e.workflowName, continueAsNew.Metadata, continueAsNew.Inputs)
+		eventId, e.workflowState.Instance(), result, e.workflowName, md, inputs, e.workflowState.ExecutionTimeout())
+	cmd.CompletionWebhook = e.completionWebhook
 	e.workflowState.AddCommand(cmd)
 
 	e.workflowSpan.SetAttributes(
 		attribute.String(log.ContinuedExecutionIDKey, cmd.ContinuedExecutionID),
 	)
+
+	return nil
 }
 
 func (e *executor) nextSequenceID() int64 {
@@ -2,9 +2,12 @@ package executor
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"log/slog"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,6 +22,7 @@ import (
 	"github.com/cschleiden/go-workflows/internal/command"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowerrors"
 	"github.com/cschleiden/go-workflows/registry"
 	wf "github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
@@ -31,15 +35,34 @@ type testHistoryProvider struct {
 	history []*history.Event
 }
 
-func (t *testHistoryProvider) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64) ([]*history.Event, error) {
+func (t *testHistoryProvider) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64, options ...backend.HistoryOption) ([]*history.Event, error) {
 	return t.history, nil
 }
 
+// slowHistoryProvider delays returning the history of its provider
+type slowHistoryProvider struct {
+	WorkflowHistoryProvider
+
+	delay time.Duration
+}
+
+func (p *slowHistoryProvider) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64, options ...backend.HistoryOption) ([]*history.Event, error) {
+	time.Sleep(p.delay)
+
+	return p.WorkflowHistoryProvider.GetWorkflowInstanceHistory(ctx, instance, lastSequenceID, options...)
+}
+
 func newExecutor(r *registry.Registry, i *core.WorkflowInstance, historyProvider WorkflowHistoryProvider) (*executor, error) {
 	logger := slog.Default()
 	tracer := noop.NewTracerProvider().Tracer("test")
 
-	e, err := NewExecutor(logger, tracer, r, converter.DefaultConverter, []wf.ContextPropagator{}, historyProvider, i, &metadata.WorkflowMetadata{}, clock.New())
+	e, err := NewExecutor(r, historyProvider, i, &metadata.WorkflowMetadata{}, ExecutorOptions{
+		Logger:      logger,
+		Tracer:      tracer,
+		Converter:   converter.DefaultConverter,
+		Propagators: []wf.ContextPropagator{},
+		Clock:       clock.New(),
+	})
 
 	return e.(*executor), err
 }
@@ -187,6 +210,89 @@ func Test_Executor(t *testing.T) {
 				require.Len(t, e.workflowState.Commands(), 2)
 			},
 		},
+		{
+			name: "Activity records registered retry options",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflowWithActivity := func(ctx sync.Context) error {
+					_, err := wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 42).Get(ctx)
+					return err
+				}
+
+				r.RegisterWorkflow(workflowWithActivity)
+				r.RegisterActivity(activity1, registry.WithRetryOptions(wf.RetryOptions{MaxAttempts: 5}))
+
+				task := startWorkflowTask("instanceID", workflowWithActivity)
+
+				result, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.Len(t, result.ActivityEvents, 1)
+
+				a := result.ActivityEvents[0].Attributes.(*history.ActivityScheduledAttributes)
+				require.Equal(t, &history.RetryOptions{MaxAttempts: 5}, a.RetryOptions)
+			},
+		},
+		{
+			name: "Activity retries are based on recorded retry options",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflowWithActivity := func(ctx sync.Context) error {
+					_, err := wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 42).Get(ctx)
+					return err
+				}
+
+				r.RegisterWorkflow(workflowWithActivity)
+				// Registered options changed after the activity was scheduled
+				r.RegisterActivity(activity1, registry.WithRetryOptions(wf.RetryOptions{MaxAttempts: 5}))
+
+				inputs, _ := converter.DefaultConverter.To(42)
+
+				hp.history = []*history.Event{
+					history.NewHistoryEvent(
+						1,
+						time.Now(),
+						history.EventType_WorkflowExecutionStarted,
+						&history.ExecutionStartedAttributes{
+							Name:   fn.Name(workflowWithActivity),
+							Inputs: []payload.Payload{},
+						},
+					),
+					history.NewHistoryEvent(
+						2,
+						time.Now(),
+						history.EventType_ActivityScheduled,
+						&history.ActivityScheduledAttributes{
+							Name:         "activity1",
+							Inputs:       []payload.Payload{inputs},
+							RetryOptions: &history.RetryOptions{MaxAttempts: 1},
+						},
+						history.ScheduleEventID(1),
+					),
+				}
+
+				task := &backend.WorkflowTask{
+					ID:               "taskID",
+					WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+					Metadata:         &metadata.WorkflowMetadata{},
+					LastSequenceID:   2,
+					NewEvents: []*history.Event{
+						history.NewPendingEvent(
+							time.Now(),
+							history.EventType_ActivityFailed,
+							&history.ActivityFailedAttributes{
+								Error: workflowerrors.FromError(errors.New("activity failed")),
+							},
+							history.ScheduleEventID(1),
+						),
+					},
+				}
+
+				result, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.Empty(t, result.ActivityEvents)
+				require.Empty(t, result.TimerEvents)
+				require.True(t, e.workflow.Completed())
+				require.Error(t, e.workflow.err)
+			},
+		},
 		{
 			name: "Workflow with new events",
 			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
@@ -321,6 +427,179 @@ func Test_Executor(t *testing.T) {
 				require.IsType(t, &command.ScheduleTimerCommand{}, e.workflowState.Commands()[1])
 			},
 		},
+		{
+			name: "Workflow with selector replays chosen case",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				var chosen int
+
+				workflowWithSelector := func(ctx sync.Context) error {
+					f1 := wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 1)
+					f2 := wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 2)
+
+					sync.Select(
+						ctx,
+						sync.Await[int](f1, func(ctx sync.Context, f sync.Future[int]) {
+							chosen = 1
+						}),
+						sync.Await[int](f2, func(ctx sync.Context, f sync.Future[int]) {
+							chosen = 2
+						}),
+					)
+
+					return nil
+				}
+
+				r.RegisterWorkflow(workflowWithSelector)
+				r.RegisterActivity(activity1)
+
+				task := startWorkflowTask(i.InstanceID, workflowWithSelector)
+				taskResult, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.Len(t, e.workflowState.Commands(), 2)
+
+				executed := taskResult.Executed
+
+				// Both activities complete before the next task, the second one first
+				result, _ := converter.DefaultConverter.To(42)
+				taskResult, err = e.ExecuteTask(context.Background(), continueTask(i.InstanceID, []*history.Event{
+					history.NewPendingEvent(time.Now(), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{
+						Result: result,
+					}, history.ScheduleEventID(2)),
+					history.NewPendingEvent(time.Now(), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{
+						Result: result,
+					}, history.ScheduleEventID(1)),
+				}, executed[len(executed)-1].SequenceID))
+				require.NoError(t, err)
+				require.True(t, e.workflow.Completed())
+				require.Equal(t, 2, chosen)
+
+				executed = append(executed, taskResult.Executed...)
+
+				// Replaying the history in a new executor, where both futures are ready, picks the same case
+				chosen = 0
+				hp.history = executed
+
+				e2, err := newExecutor(r, i, hp)
+				require.NoError(t, err)
+				defer e2.Close()
+
+				_, err = e2.ExecuteTask(context.Background(), continueTask(i.InstanceID, []*history.Event{}, executed[len(executed)-1].SequenceID))
+				require.NoError(t, err)
+				require.True(t, e2.workflow.Completed())
+				require.Equal(t, 2, chosen)
+			},
+		},
+		{
+			name: "Workflow sequences are identical on replay",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				var sequences []int
+
+				workflowWithSequences := func(ctx sync.Context) error {
+					sequences = append(sequences, wf.NextSequence(ctx, "a"), wf.NextSequence(ctx, "a"), wf.NextSequence(ctx, "b"))
+
+					if _, err := wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 1).Get(ctx); err != nil {
+						return err
+					}
+
+					sequences = append(sequences, wf.NextSequence(ctx, "b"), wf.NextSequence(ctx, "a"))
+
+					return nil
+				}
+
+				r.RegisterWorkflow(workflowWithSequences)
+				r.RegisterActivity(activity1)
+
+				task := startWorkflowTask(i.InstanceID, workflowWithSequences)
+				taskResult, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+
+				executed := taskResult.Executed
+
+				result, _ := converter.DefaultConverter.To(1)
+				taskResult, err = e.ExecuteTask(context.Background(), continueTask(i.InstanceID, []*history.Event{
+					history.NewPendingEvent(time.Now(), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{
+						Result: result,
+					}, history.ScheduleEventID(1)),
+				}, executed[len(executed)-1].SequenceID))
+				require.NoError(t, err)
+				require.True(t, e.workflow.Completed())
+				require.Equal(t, []int{1, 2, 1, 2, 3}, sequences)
+
+				executed = append(executed, taskResult.Executed...)
+
+				// Replaying the history in a new executor produces the same values
+				sequences = nil
+				hp.history = executed
+
+				e2, err := newExecutor(r, i, hp)
+				require.NoError(t, err)
+				defer e2.Close()
+
+				_, err = e2.ExecuteTask(context.Background(), continueTask(i.InstanceID, []*history.Event{}, executed[len(executed)-1].SequenceID))
+				require.NoError(t, err)
+				require.True(t, e2.workflow.Completed())
+				require.Equal(t, []int{1, 2, 1, 2, 3}, sequences)
+			},
+		},
+		{
+			name: "Workflow result exceeding maximum size fails workflow",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				wf := func(ctx sync.Context) (string, error) {
+					return strings.Repeat("a", 100), nil
+				}
+
+				r.RegisterWorkflow(wf)
+
+				e.maxResultSize = 64
+
+				task := startWorkflowTask(i.InstanceID, wf)
+
+				_, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.True(t, e.workflow.Completed())
+
+				cmd, ok := e.workflowState.Commands()[0].(*command.CompleteWorkflowCommand)
+				require.True(t, ok)
+				require.Nil(t, cmd.Result)
+				require.NotNil(t, cmd.Error)
+				require.Contains(t, cmd.Error.Error(), "workflow result too large: result is 102 bytes, maximum is 64 bytes")
+			},
+		},
+		{
+			name: "Workflow stuck in loop fails workflow",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				wf := func(ctx sync.Context) error {
+					c := wf.NewChannel[int]()
+
+					done := false
+					for !done {
+						wf.Select(ctx,
+							wf.Receive(c, func(ctx sync.Context, v int, ok bool) {
+								done = true
+							}),
+							wf.Default(func(ctx sync.Context) {}),
+						)
+					}
+
+					return nil
+				}
+
+				r.RegisterWorkflow(wf)
+
+				e.maxIterations = 100
+
+				task := startWorkflowTask(i.InstanceID, wf)
+
+				_, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.True(t, e.workflow.Completed())
+
+				cmd, ok := e.workflowState.Commands()[0].(*command.CompleteWorkflowCommand)
+				require.True(t, ok)
+				require.NotNil(t, cmd.Error)
+				require.Contains(t, cmd.Error.Error(), "workflow stuck in loop: 100 iterations without yielding")
+			},
+		},
 		{
 			name: "Workflow with timer",
 			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
@@ -376,7 +655,7 @@ func Test_Executor(t *testing.T) {
 					wf.ScheduleTimer(tctx, time.Millisecond*5)
 
 					// Cause checkpoint
-					wf.ExecuteActivity[any](ctx, wf.DefaultActivityOptions, activity1, 42).Get(ctx)
+					wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 42).Get(ctx)
 
 					cancel()
 					cancel()
@@ -403,6 +682,57 @@ func Test_Executor(t *testing.T) {
 				require.Nil(t, e.workflow.err)
 			},
 		},
+		{
+			name: "Activity result arriving with its cancellation is ignored",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				var activityErr error
+
+				workflowWithCanceledActivity := func(ctx sync.Context) error {
+					h := wf.ExecuteActivityAsync[int](ctx, wf.DefaultActivityOptions, activity1, 42)
+
+					if _, err := wf.ScheduleTimer(ctx, time.Millisecond*5).Get(ctx); err != nil {
+						return err
+					}
+
+					h.Cancel(ctx)
+
+					_, activityErr = h.Future.Get(ctx)
+
+					return nil
+				}
+
+				r.RegisterWorkflow(workflowWithCanceledActivity)
+				r.RegisterActivity(activity1)
+
+				task := startWorkflowTask(i.InstanceID, workflowWithCanceledActivity)
+
+				result, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.Len(t, e.workflowState.Commands(), 2)
+
+				// The activity completed before the workflow saw the timer fire and canceled it
+				task2 := continueTask(i.InstanceID, []*history.Event{
+					history.NewPendingEvent(time.Now(), history.EventType_TimerFired, &history.TimerFiredAttributes{}, history.ScheduleEventID(2)),
+					history.NewPendingEvent(time.Now(), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{}, history.ScheduleEventID(1)),
+				}, result.Executed[len(result.Executed)-1].SequenceID)
+
+				result, err = e.ExecuteTask(context.Background(), task2)
+				require.NoError(t, err)
+				require.Nil(t, e.workflow.err)
+				require.ErrorIs(t, activityErr, wf.Canceled)
+				require.Equal(t, core.WorkflowInstanceStateFinished, result.State)
+
+				var eventTypes []history.EventType
+				for _, event := range result.Executed {
+					eventTypes = append(eventTypes, event.Type)
+
+					if a, ok := event.Attributes.(*history.ExecutionCompletedAttributes); ok {
+						require.Nil(t, a.Error)
+					}
+				}
+				require.Contains(t, eventTypes, history.EventType_ActivityCanceled)
+			},
+		},
 		{
 			name: "Workflow with signal",
 			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
@@ -454,6 +784,65 @@ func Test_Executor(t *testing.T) {
 				require.Len(t, e.workflowState.Commands(), 1)
 			},
 		},
+		{
+			name: "Initial signals are received before the workflow first runs",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				var received []string
+
+				workflowWithInitialSignals := func(ctx sync.Context) error {
+					c := wf.NewSignalChannel[string](ctx, "signal1")
+					for {
+						s, ok := c.ReceiveNonBlocking()
+						if !ok {
+							return nil
+						}
+
+						received = append(received, s)
+					}
+				}
+
+				r.RegisterWorkflow(workflowWithInitialSignals)
+
+				events := []*history.Event{
+					history.NewPendingEvent(
+						time.Now(),
+						history.EventType_WorkflowExecutionStarted,
+						&history.ExecutionStartedAttributes{
+							Name:           fn.Name(workflowWithInitialSignals),
+							Inputs:         []payload.Payload{},
+							InitialSignals: 2,
+							OrderedSignals: true,
+						},
+					),
+				}
+				for _, arg := range []string{"a", "b"} {
+					s, err := converter.DefaultConverter.To(arg)
+					require.NoError(t, err)
+
+					events = append(events, history.NewPendingEvent(
+						time.Now(),
+						history.EventType_SignalReceived,
+						&history.SignalReceivedAttributes{
+							Name: "signal1",
+							Arg:  s,
+						},
+					))
+				}
+
+				task := &backend.WorkflowTask{
+					ID:               "taskID",
+					WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+					Metadata:         &metadata.WorkflowMetadata{},
+					NewEvents:        events,
+				}
+
+				_, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.Nil(t, e.workflow.err)
+				require.Equal(t, []string{"a", "b"}, received)
+				require.True(t, e.workflow.Completed())
+			},
+		},
 		{
 			name: "Completes workflow on unhandled error",
 			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
@@ -583,23 +972,19 @@ func Test_Executor(t *testing.T) {
 			},
 		},
 		{
-			name: "Pending futures result in panic",
+			name: "Parent close policy abandons running subworkflow",
 			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
 				subworkflow := func(ctx wf.Context) error {
 					return nil
 				}
 
 				workflow := func(ctx wf.Context) error {
-					// Start but not wait for sub-workflow
+					// Start but not wait for sub-workflow, abandon is the default policy
 					wf.CreateSubWorkflowInstance[any](ctx, wf.SubWorkflowOptions{
 						InstanceID: "subworkflow",
 					}, subworkflow)
 
-					// Schedule but not wait for timer
-					wf.ScheduleTimer(ctx, time.Second*2)
-
-					// Schedule but not wait for named timer
-					wf.ScheduleTimer(ctx, time.Second*2, wf.WithTimerName("delay"))
+					wf.Sleep(ctx, time.Millisecond)
 
 					return nil
 				}
@@ -608,25 +993,202 @@ func Test_Executor(t *testing.T) {
 				r.RegisterWorkflow(subworkflow)
 
 				task := startWorkflowTask("instanceID", workflow)
+				result, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.Len(t, result.WorkflowEvents, 1)
 
-				require.PanicsWithValue(t, "workflow completed, but there are still pending futures: [1-subworkflow:1 2-timer:2s 3-timer-delay:2s]", func() {
-					e.ExecuteTask(context.Background(), task)
-				})
+				hp.history = append(hp.history, result.Executed...)
+				result, err = e.ExecuteTask(context.Background(), continueTask("instanceID", []*history.Event{
+					result.TimerEvents[0],
+				}, result.Executed[len(result.Executed)-1].SequenceID))
+
+				require.NoError(t, err)
+				require.True(t, e.workflow.Completed())
+				require.Equal(t, core.WorkflowInstanceStateFinished, result.State)
+				require.Empty(t, result.WorkflowEvents)
+
+				executed := result.Executed
+				require.Equal(t, history.EventType_WorkflowExecutionFinished, executed[len(executed)-1].Type)
+				require.Nil(t, executed[len(executed)-1].Attributes.(*history.ExecutionCompletedAttributes).Error)
 			},
 		},
 		{
-			name: "Close_removes_any_goroutines",
+			name: "Parent close policy terminates running subworkflow",
 			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
-				wf := func(ctx sync.Context) error {
-					c := wf.NewSignalChannel[int](ctx, "signal")
-
-					// Block workflow
-					c.Receive(ctx)
-
+				subworkflow := func(ctx wf.Context) error {
 					return nil
 				}
 
-				r.RegisterWorkflow(wf)
+				workflow := func(ctx wf.Context) error {
+					// Start but not wait for sub-workflow
+					wf.CreateSubWorkflowInstance[any](ctx, wf.SubWorkflowOptions{
+						InstanceID:        "subworkflow",
+						ParentClosePolicy: wf.ParentClosePolicyTerminate,
+					}, subworkflow)
+
+					wf.Sleep(ctx, time.Millisecond)
+
+					return nil
+				}
+
+				r.RegisterWorkflow(workflow)
+				r.RegisterWorkflow(subworkflow)
+
+				task := startWorkflowTask("instanceID", workflow)
+				result, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.Len(t, result.WorkflowEvents, 1)
+
+				subWorkflowInstance := result.WorkflowEvents[0].WorkflowInstance
+
+				hp.history = append(hp.history, result.Executed...)
+				result, err = e.ExecuteTask(context.Background(), continueTask("instanceID", []*history.Event{
+					result.TimerEvents[0],
+				}, result.Executed[len(result.Executed)-1].SequenceID))
+
+				require.NoError(t, err)
+				require.True(t, e.workflow.Completed())
+				require.Equal(t, core.WorkflowInstanceStateFinished, result.State)
+				require.Len(t, result.WorkflowEvents, 1)
+				require.Equal(t, history.EventType_WorkflowExecutionTerminated, result.WorkflowEvents[0].HistoryEvent.Type)
+				require.Equal(t, subWorkflowInstance, result.WorkflowEvents[0].WorkflowInstance)
+			},
+		},
+		{
+			name: "Parent close policy requests cancellation of running subworkflow",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				subworkflow := func(ctx wf.Context) error {
+					return nil
+				}
+
+				workflow := func(ctx wf.Context) error {
+					// Start but not wait for sub-workflow
+					wf.CreateSubWorkflowInstance[any](ctx, wf.SubWorkflowOptions{
+						InstanceID:        "subworkflow",
+						ParentClosePolicy: wf.ParentClosePolicyRequestCancel,
+					}, subworkflow)
+
+					wf.Sleep(ctx, time.Millisecond)
+
+					return nil
+				}
+
+				r.RegisterWorkflow(workflow)
+				r.RegisterWorkflow(subworkflow)
+
+				task := startWorkflowTask("instanceID", workflow)
+				result, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.Len(t, result.WorkflowEvents, 1)
+
+				subWorkflowInstance := result.WorkflowEvents[0].WorkflowInstance
+
+				hp.history = append(hp.history, result.Executed...)
+				result, err = e.ExecuteTask(context.Background(), continueTask("instanceID", []*history.Event{
+					result.TimerEvents[0],
+				}, result.Executed[len(result.Executed)-1].SequenceID))
+
+				require.NoError(t, err)
+				require.Equal(t, core.WorkflowInstanceStateFinished, result.State)
+				require.Len(t, result.WorkflowEvents, 1)
+				require.Equal(t, history.EventType_WorkflowExecutionCanceled, result.WorkflowEvents[0].HistoryEvent.Type)
+				require.Equal(t, subWorkflowInstance, result.WorkflowEvents[0].WorkflowInstance)
+
+				executed := result.Executed
+				require.Equal(t, history.EventType_SubWorkflowCancellationRequested, executed[len(executed)-2].Type)
+				require.Equal(t, history.EventType_WorkflowExecutionFinished, executed[len(executed)-1].Type)
+			},
+		},
+		{
+			name: "Terminated workflow finishes without executing workflow code",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				received := false
+
+				workflow := func(ctx wf.Context) error {
+					wf.NewSignalChannel[int](ctx, "signal").Receive(ctx)
+					received = true
+
+					return nil
+				}
+
+				r.RegisterWorkflow(workflow)
+
+				task := startWorkflowTask("instanceID", workflow)
+				result, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+
+				signalArg, _ := converter.DefaultConverter.To(42)
+
+				hp.history = append(hp.history, result.Executed...)
+				result, err = e.ExecuteTask(context.Background(), continueTask("instanceID", []*history.Event{
+					history.NewWorkflowTerminationEvent(time.Now()),
+					history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{
+						Name: "signal",
+						Arg:  signalArg,
+					}),
+				}, result.Executed[len(result.Executed)-1].SequenceID))
+
+				require.NoError(t, err)
+				require.False(t, received)
+				require.Equal(t, core.WorkflowInstanceStateFinished, result.State)
+
+				// Signal is discarded
+				types := []history.EventType{}
+				for _, event := range result.Executed {
+					types = append(types, event.Type)
+				}
+				require.Equal(t, []history.EventType{
+					history.EventType_WorkflowTaskStarted,
+					history.EventType_WorkflowExecutionTerminated,
+					history.EventType_WorkflowExecutionFinished,
+				}, types)
+			},
+		},
+		{
+			name: "Pending futures result in panic",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				subworkflow := func(ctx wf.Context) error {
+					return nil
+				}
+
+				workflow := func(ctx wf.Context) error {
+					// Start but not wait for sub-workflow, it's abandoned
+					wf.CreateSubWorkflowInstance[any](ctx, wf.SubWorkflowOptions{
+						InstanceID: "subworkflow",
+					}, subworkflow)
+
+					// Schedule but not wait for timer
+					wf.ScheduleTimer(ctx, time.Second*2)
+
+					// Schedule but not wait for named timer
+					wf.ScheduleTimer(ctx, time.Second*2, wf.WithTimerName("delay"))
+
+					return nil
+				}
+
+				r.RegisterWorkflow(workflow)
+				r.RegisterWorkflow(subworkflow)
+
+				task := startWorkflowTask("instanceID", workflow)
+
+				require.PanicsWithValue(t, "workflow completed, but there are still pending futures: [2-timer:2s 3-timer-delay:2s]", func() {
+					e.ExecuteTask(context.Background(), task)
+				})
+			},
+		},
+		{
+			name: "Close_removes_any_goroutines",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				wf := func(ctx sync.Context) error {
+					c := wf.NewSignalChannel[int](ctx, "signal")
+
+					// Block workflow
+					c.Receive(ctx)
+
+					return nil
+				}
+
+				r.RegisterWorkflow(wf)
 
 				task := startWorkflowTask(i.InstanceID, wf)
 
@@ -707,6 +1269,352 @@ func Test_Executor(t *testing.T) {
 				require.Equal(t, goRoutines, runtime.NumGoroutine())
 			},
 		},
+		{
+			name: "Deadline derived from recorded start and execution timeout",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				var deadline time.Time
+				workflowWithActivity := func(ctx sync.Context) error {
+					deadline = wf.GetInfo(ctx).Deadline
+
+					_, err := wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 42).Get(ctx)
+					return err
+				}
+
+				r.RegisterWorkflow(workflowWithActivity)
+				r.RegisterActivity(activity1)
+
+				started := time.Now().Add(-time.Hour)
+
+				task := &backend.WorkflowTask{
+					ID:               "taskID",
+					WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+					Metadata:         &metadata.WorkflowMetadata{},
+					NewEvents: []*history.Event{
+						history.NewHistoryEvent(
+							1,
+							started,
+							history.EventType_WorkflowExecutionStarted,
+							&history.ExecutionStartedAttributes{
+								Name:             fn.Name(workflowWithActivity),
+								Inputs:           []payload.Payload{},
+								ExecutionTimeout: time.Hour * 2,
+							},
+						),
+					},
+				}
+
+				result, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.Equal(t, started.Add(time.Hour*2), deadline)
+
+				require.Len(t, result.ActivityEvents, 1)
+				a := result.ActivityEvents[0].Attributes.(*history.ActivityScheduledAttributes)
+				require.NotNil(t, a.Deadline)
+				require.Equal(t, deadline, *a.Deadline)
+			},
+		},
+		{
+			name: "Task completes within task timeout",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				wf := func(ctx sync.Context) error {
+					return nil
+				}
+
+				r.RegisterWorkflow(wf)
+
+				e.taskTimeout = time.Second
+
+				result, err := e.ExecuteTask(context.Background(), startWorkflowTask(i.InstanceID, wf))
+				require.NoError(t, err)
+				require.Equal(t, core.WorkflowInstanceStateFinished, result.State)
+			},
+		},
+		{
+			name: "Task exceeding task timeout fails workflow",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				wf := func(ctx sync.Context) error {
+					// Blocking call in workflow code
+					time.Sleep(time.Millisecond * 100)
+
+					return nil
+				}
+
+				r.RegisterWorkflow(wf)
+
+				e.taskTimeout = time.Millisecond * 10
+
+				task := startWorkflowTask(i.InstanceID, wf)
+				result, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+
+				// The workflow fails, the events of the task are recorded before the failure
+				require.True(t, result.TimedOut)
+				require.Equal(t, core.WorkflowInstanceStateFinished, result.State)
+				require.Len(t, result.Executed, 3)
+				require.Equal(t, history.EventType_WorkflowTaskStarted, result.Executed[0].Type)
+				require.Equal(t, task.NewEvents[0].ID, result.Executed[1].ID)
+				require.Equal(t, history.EventType_WorkflowExecutionFinished, result.Executed[2].Type)
+				for i, event := range result.Executed {
+					require.Equal(t, int64(i+1), event.SequenceID)
+				}
+
+				// The events of the task are still used by the workflow code, they are recorded as copies
+				require.NotSame(t, task.NewEvents[0], result.Executed[1])
+				require.Equal(t, int64(0), task.NewEvents[0].SequenceID)
+
+				a := result.Executed[2].Attributes.(*history.ExecutionCompletedAttributes)
+				require.NotNil(t, a.Error)
+				require.Contains(t, a.Error.Error(), ErrWorkflowTaskTimeout.Error())
+
+				// Executor cannot be used anymore
+				_, err = e.ExecuteTask(context.Background(), continueTask(i.InstanceID, []*history.Event{}, 1))
+				require.ErrorIs(t, err, ErrWorkflowTaskTimeout)
+			},
+		},
+		{
+			name: "Fetching history does not count towards task timeout",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				wf := func(ctx sync.Context) error {
+					_, err := wf.ScheduleTimer(ctx, time.Millisecond).Get(ctx)
+					return err
+				}
+
+				r.RegisterWorkflow(wf)
+
+				result, err := e.ExecuteTask(context.Background(), startWorkflowTask(i.InstanceID, wf))
+				require.NoError(t, err)
+				require.Equal(t, core.WorkflowInstanceStateActive, result.State)
+
+				hp.history = result.Executed
+
+				// A new executor has to fetch the history from a slow backend
+				e2, err := newExecutor(r, i, &slowHistoryProvider{hp, time.Millisecond * 100})
+				require.NoError(t, err)
+				defer e2.Close()
+
+				e2.taskTimeout = time.Millisecond * 50
+
+				result, err = e2.ExecuteTask(context.Background(), continueTask(i.InstanceID, []*history.Event{
+					history.NewPendingEvent(time.Now(), history.EventType_TimerFired, &history.TimerFiredAttributes{},
+						history.ScheduleEventID(result.TimerEvents[0].ScheduleEventID)),
+				}, result.Executed[len(result.Executed)-1].SequenceID))
+				require.NoError(t, err)
+				require.Equal(t, core.WorkflowInstanceStateFinished, result.State)
+				require.False(t, e2.timedOut.Load())
+			},
+		},
+		{
+			name: "ContinueAsNew inherits metadata",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflow := func(ctx wf.Context) error {
+					return wf.ContinueAsNewWithOptions(ctx, wf.ContinueAsNewOptions{
+						InheritMetadata: true,
+						Metadata:        map[string]string{"overridden": "new"},
+					})
+				}
+
+				r.RegisterWorkflow(workflow)
+
+				e.metadata = &metadata.WorkflowMetadata{"inherited": "value", "overridden": "old"}
+
+				result, err := e.ExecuteTask(context.Background(), startWorkflowTask(i.InstanceID, workflow))
+				require.NoError(t, err)
+				require.Equal(t, core.WorkflowInstanceStateContinuedAsNew, result.State)
+				require.Len(t, result.WorkflowEvents, 1)
+
+				a := result.WorkflowEvents[0].HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+				require.Equal(t, "value", a.Metadata.Get("inherited"))
+				require.Equal(t, "new", a.Metadata.Get("overridden"))
+			},
+		},
+		{
+			name: "ContinueAsNew without inheriting metadata",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflow := func(ctx wf.Context) error {
+					return wf.ContinueAsNewWithOptions(ctx, wf.ContinueAsNewOptions{})
+				}
+
+				r.RegisterWorkflow(workflow)
+
+				e.metadata = &metadata.WorkflowMetadata{"inherited": "value"}
+
+				result, err := e.ExecuteTask(context.Background(), startWorkflowTask(i.InstanceID, workflow))
+				require.NoError(t, err)
+				require.Len(t, result.WorkflowEvents, 1)
+
+				a := result.WorkflowEvents[0].HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+				require.Empty(t, a.Metadata.Get("inherited"))
+			},
+		},
+		{
+			name: "ContinueAsNewError restarts workflow",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflow := func(ctx wf.Context, run int) error {
+					return fmt.Errorf("next run: %w", wf.ContinueAsNewError(run+1))
+				}
+
+				r.RegisterWorkflow(workflow)
+
+				e.metadata = &metadata.WorkflowMetadata{"inherited": "value"}
+
+				result, err := e.ExecuteTask(context.Background(), startWorkflowTask(i.InstanceID, workflow, 1))
+				require.NoError(t, err)
+				require.Equal(t, core.WorkflowInstanceStateContinuedAsNew, result.State)
+				require.Len(t, result.WorkflowEvents, 1)
+
+				a := result.WorkflowEvents[0].HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+				require.Equal(t, "value", a.Metadata.Get("inherited"))
+				require.Len(t, a.Inputs, 1)
+
+				var run int
+				require.NoError(t, converter.DefaultConverter.From(a.Inputs[0], &run))
+				require.Equal(t, 2, run)
+			},
+		},
+		{
+			name: "ContinueAsNewError with invalid arguments fails workflow",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflow := func(ctx wf.Context) error {
+					return wf.ContinueAsNewError(func() {})
+				}
+
+				r.RegisterWorkflow(workflow)
+
+				result, err := e.ExecuteTask(context.Background(), startWorkflowTask(i.InstanceID, workflow))
+				require.NoError(t, err)
+				require.Equal(t, core.WorkflowInstanceStateFinished, result.State)
+				require.Empty(t, result.WorkflowEvents)
+
+				var finished *history.ExecutionCompletedAttributes
+				for _, event := range result.Executed {
+					if event.Type == history.EventType_WorkflowExecutionFinished {
+						finished = event.Attributes.(*history.ExecutionCompletedAttributes)
+					}
+				}
+				require.NotNil(t, finished)
+				require.ErrorContains(t, workflowerrors.ToError(finished.Error), "converting inputs")
+			},
+		},
+		{
+			name: "Replaying diverging workflow fails task",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflow := func(ctx wf.Context) error {
+					_, err := wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 42).Get(ctx)
+					return err
+				}
+
+				r.RegisterWorkflow(workflow)
+				r.RegisterActivity(activity1)
+
+				result, err := e.ExecuteTask(context.Background(), startWorkflowTask(i.InstanceID, workflow))
+				require.NoError(t, err)
+				require.Len(t, result.ActivityEvents, 1)
+
+				hp.history = append(hp.history, result.Executed...)
+
+				// Workflow code changed, now schedules a timer instead of an activity
+				changedWorkflow := func(ctx wf.Context) error {
+					return wf.Sleep(ctx, time.Second)
+				}
+
+				r2 := registry.New()
+				require.NoError(t, r2.RegisterWorkflow(changedWorkflow, registry.WithName(fn.Name(workflow))))
+
+				e2, err := newExecutor(r2, i, hp)
+				require.NoError(t, err)
+				defer e2.Close()
+
+				r1, _ := converter.DefaultConverter.To(42)
+				_, err = e2.ExecuteTask(context.Background(), continueTask(i.InstanceID, []*history.Event{
+					history.NewPendingEvent(time.Now(), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{
+						Result: r1,
+					}, history.ScheduleEventID(1)),
+				}, result.Executed[len(result.Executed)-1].SequenceID))
+
+				var ndErr *NonDeterminismError
+				require.ErrorAs(t, err, &ndErr)
+				require.Equal(t, history.EventType_ActivityScheduled, ndErr.EventType)
+				require.Equal(t, int64(1), ndErr.ScheduleEventID)
+				require.Equal(t, "ScheduleTimer", ndErr.CommandType)
+			},
+		},
+		{
+			name: "Additional side effect during replay fails task",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflow := func(ctx wf.Context) (int, error) {
+					a, _ := wf.SideEffect(ctx, func(ctx wf.Context) int { return 1 }).Get(ctx)
+					b, _ := wf.SideEffect(ctx, func(ctx wf.Context) int { return 2 }).Get(ctx)
+
+					x, _ := wf.NewSignalChannel[int](ctx, "signal").Receive(ctx)
+
+					return a + b + x, nil
+				}
+
+				r.RegisterWorkflow(workflow)
+
+				result, err := e.ExecuteTask(context.Background(), startWorkflowTask(i.InstanceID, workflow))
+				require.NoError(t, err)
+
+				hp.history = append(hp.history, result.Executed...)
+
+				// Workflow code changed, executes another side effect between the recorded ones. Without the guard,
+				// the new side effect would receive the result recorded for the second one.
+				changedWorkflow := func(ctx wf.Context) (int, error) {
+					a, _ := wf.SideEffect(ctx, func(ctx wf.Context) int { return 1 }).Get(ctx)
+					c, _ := wf.SideEffect(ctx, func(ctx wf.Context) int { return 3 }).Get(ctx)
+					b, _ := wf.SideEffect(ctx, func(ctx wf.Context) int { return 2 }).Get(ctx)
+
+					x, _ := wf.NewSignalChannel[int](ctx, "signal").Receive(ctx)
+
+					return a + b + c + x, nil
+				}
+
+				r2 := registry.New()
+				require.NoError(t, r2.RegisterWorkflow(changedWorkflow, registry.WithName(fn.Name(workflow))))
+
+				e2, err := newExecutor(r2, i, hp)
+				require.NoError(t, err)
+				defer e2.Close()
+
+				arg, _ := converter.DefaultConverter.To(42)
+				_, err = e2.ExecuteTask(context.Background(), continueTask(i.InstanceID, []*history.Event{
+					history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{
+						Name: "signal",
+						Arg:  arg,
+					}),
+				}, result.Executed[len(result.Executed)-1].SequenceID))
+
+				// Replay stops at the trace started for the side effect that is not recorded
+				var ndErr *NonDeterminismError
+				require.ErrorAs(t, err, &ndErr)
+				require.Equal(t, history.EventType(0), ndErr.EventType)
+				require.Equal(t, int64(5), ndErr.ScheduleEventID)
+				require.Equal(t, "StartTrace", ndErr.CommandType)
+				require.Contains(t, err.Error(), "not recorded in history")
+			},
+		},
+		{
+			name: "New event for different command fails task",
+			f: func(t *testing.T, r *registry.Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflow := func(ctx wf.Context) error {
+					return wf.Sleep(ctx, time.Second)
+				}
+
+				r.RegisterWorkflow(workflow)
+
+				result, err := e.ExecuteTask(context.Background(), startWorkflowTask(i.InstanceID, workflow))
+				require.NoError(t, err)
+
+				result, err = e.ExecuteTask(context.Background(), continueTask(i.InstanceID, []*history.Event{
+					history.NewPendingEvent(time.Now(), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{}, history.ScheduleEventID(1)),
+				}, result.Executed[len(result.Executed)-1].SequenceID))
+
+				var ndErr *NonDeterminismError
+				require.ErrorAs(t, err, &ndErr)
+				require.Nil(t, result)
+				require.Contains(t, err.Error(), "non-determinism detected")
+			},
+		},
 	}
 
 	for _, tt := range tests {
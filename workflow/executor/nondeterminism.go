@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/backend/history"
+	"github.com/cschleiden/go-workflows/internal/command"
+)
+
+// NonDeterminismError is returned when a workflow produces commands during replay that do not match the events
+// recorded in its history. This usually happens when workflow code changed in a non-deterministic way.
+type NonDeterminismError struct {
+	// Message describes the divergence
+	Message string
+
+	// EventType is the type of the recorded event, 0 if the workflow produced a command for which no event has been
+	// recorded.
+	EventType history.EventType
+
+	// SequenceID is the sequence id of the recorded event, or of the last replayed event if no event has been recorded
+	SequenceID int64
+
+	// ScheduleEventID is the schedule event id of the recorded event
+	ScheduleEventID int64
+
+	// CommandType is the type of the command the workflow produced for the schedule event id, empty if
+	// the workflow did not produce any command.
+	CommandType string
+}
+
+func (e *NonDeterminismError) Error() string {
+	var sb strings.Builder
+
+	sb.WriteString("non-determinism detected: ")
+	sb.WriteString(e.Message)
+	if e.EventType != 0 {
+		fmt.Fprintf(&sb, " (event: %v, sequence id: %d, schedule event id: %d", e.EventType, e.SequenceID, e.ScheduleEventID)
+	} else {
+		fmt.Fprintf(&sb, " (no event, sequence id: %d, schedule event id: %d", e.SequenceID, e.ScheduleEventID)
+	}
+
+	if e.CommandType != "" {
+		fmt.Fprintf(&sb, ", command: %s)", e.CommandType)
+	} else {
+		sb.WriteString(", no command)")
+	}
+
+	return sb.String()
+}
+
+func newNonDeterminismError(event *history.Event, c command.Command, format string, args ...interface{}) error {
+	err := &NonDeterminismError{
+		Message:         fmt.Sprintf(format, args...),
+		EventType:       event.Type,
+		SequenceID:      event.SequenceID,
+		ScheduleEventID: event.ScheduleEventID,
+	}
+
+	if c != nil {
+		err.CommandType = c.Type()
+	}
+
+	return err
+}
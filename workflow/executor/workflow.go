@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"runtime/debug"
 
+	"github.com/cschleiden/go-workflows/backend/converter"
 	"github.com/cschleiden/go-workflows/backend/payload"
 	"github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/contextvalue"
@@ -46,6 +47,11 @@ func (w *workflow) Execute(ctx sync.Context, inputs []payload.Payload) error {
 		// Handle panics in workflows
 		defer func() {
 			if r := recover(); r != nil {
+				if err, ok := r.(error); ok && errors.Is(err, sync.ErrStuckInLoop) {
+					w.err = err
+					return
+				}
+
 				stack := string(debug.Stack())
 
 				w.err = workflowerrors.NewPanicError(fmt.Sprintf("panic in workflow: %v\n%v", r, stack))
@@ -56,23 +62,13 @@ func (w *workflow) Execute(ctx sync.Context, inputs []payload.Payload) error {
 		r := w.fn.Call(args)
 
 		// Process result
-		if len(r) < 1 || len(r) > 2 {
-			return errors.New("workflow has to return either (error) or (result, error)")
+		if len(r) < 1 {
+			return errors.New("workflow has to return either (error), (result, error), or (results..., error)")
 		}
 
-		var result payload.Payload
-
-		if len(r) > 1 {
-			var err error
-			result, err = converter.To(r[0].Interface())
-			if err != nil {
-				return fmt.Errorf("converting workflow result: %w", err)
-			}
-		} else {
-			result, err = converter.To(nil)
-			if err != nil {
-				return fmt.Errorf("converting workflow result: %w", err)
-			}
+		result, err := resultsToPayload(converter, r[:len(r)-1])
+		if err != nil {
+			return fmt.Errorf("converting workflow result: %w", err)
 		}
 
 		w.result = result
@@ -93,6 +89,31 @@ func (w *workflow) Execute(ctx sync.Context, inputs []payload.Payload) error {
 	return w.s.Execute()
 }
 
+// resultsToPayload converts the non-error return values of a workflow to a payload. A single value is converted
+// as is, multiple values are converted individually and then stored as a tuple.
+func resultsToPayload(converter converter.Converter, results []reflect.Value) (payload.Payload, error) {
+	switch len(results) {
+	case 0:
+		return converter.To(nil)
+
+	case 1:
+		return converter.To(results[0].Interface())
+
+	default:
+		tuple := make([]payload.Payload, 0, len(results))
+		for _, r := range results {
+			p, err := converter.To(r.Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			tuple = append(tuple, p)
+		}
+
+		return converter.To(tuple)
+	}
+}
+
 func (w *workflow) Continue() error {
 	return w.s.Execute()
 }
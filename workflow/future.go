@@ -1,6 +1,61 @@
 package workflow
 
+import "errors"
+
 type Future[T any] interface {
 	// Get returns the value if set, blocks otherwise
 	Get(ctx Context) (T, error)
 }
+
+type GetAllOptions struct {
+	// CollectErrors waits for all futures to resolve and returns all encountered errors, instead of returning
+	// as soon as any future resolves with an error.
+	CollectErrors bool
+}
+
+var DefaultGetAllOptions = GetAllOptions{}
+
+// GetAll waits for all given futures and returns their results in the same order as the futures. It returns
+// as soon as any future resolves with an error.
+func GetAll[T any](ctx Context, futures []Future[T]) ([]T, error) {
+	return GetAllWithOptions(ctx, DefaultGetAllOptions, futures)
+}
+
+// GetAllWithOptions waits for all given futures and returns their results in the same order as the futures.
+func GetAllWithOptions[T any](ctx Context, options GetAllOptions, futures []Future[T]) ([]T, error) {
+	results := make([]T, len(futures))
+	errs := make([]error, len(futures))
+
+	pending := make(map[int]Future[T], len(futures))
+	for i, f := range futures {
+		pending[i] = f
+	}
+
+	for len(pending) > 0 {
+		cases := make([]SelectCase, 0, len(pending))
+		for i := range futures {
+			f, ok := pending[i]
+			if !ok {
+				continue
+			}
+
+			i := i
+			cases = append(cases, Await(f, func(ctx Context, f Future[T]) {
+				delete(pending, i)
+				results[i], errs[i] = f.Get(ctx)
+			}))
+		}
+
+		Select(ctx, cases...)
+
+		if !options.CollectErrors {
+			for _, err := range errs {
+				if err != nil {
+					return results, err
+				}
+			}
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
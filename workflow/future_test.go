@@ -0,0 +1,94 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetAll(t *testing.T) {
+	ctx := sync.Background()
+
+	f1 := sync.NewFuture[int]()
+	f2 := sync.NewFuture[int]()
+
+	var results []int
+	var err error
+
+	c := sync.NewCoroutine(ctx, func(ctx Context) error {
+		results, err = GetAll(ctx, []Future[int]{f1, f2})
+
+		return nil
+	})
+
+	c.Execute()
+	require.False(t, c.Finished())
+
+	// Resolve out of order
+	f2.Set(2, nil)
+	c.Execute()
+	require.False(t, c.Finished())
+
+	f1.Set(1, nil)
+	c.Execute()
+	require.True(t, c.Finished())
+
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, results)
+}
+
+func Test_GetAll_FailsFast(t *testing.T) {
+	ctx := sync.Background()
+
+	f1 := sync.NewFuture[int]()
+	f2 := sync.NewFuture[int]()
+
+	var err error
+
+	c := sync.NewCoroutine(ctx, func(ctx Context) error {
+		_, err = GetAll(ctx, []Future[int]{f1, f2})
+
+		return nil
+	})
+
+	c.Execute()
+
+	f2.Set(0, errors.New("f2 failed"))
+	c.Execute()
+	require.True(t, c.Finished())
+
+	require.EqualError(t, err, "f2 failed")
+}
+
+func Test_GetAllWithOptions_CollectErrors(t *testing.T) {
+	ctx := sync.Background()
+
+	f1 := sync.NewFuture[int]()
+	f2 := sync.NewFuture[int]()
+	f3 := sync.NewFuture[int]()
+
+	var results []int
+	var err error
+
+	c := sync.NewCoroutine(ctx, func(ctx Context) error {
+		results, err = GetAllWithOptions(ctx, GetAllOptions{CollectErrors: true}, []Future[int]{f1, f2, f3})
+
+		return nil
+	})
+
+	c.Execute()
+
+	f3.Set(0, errors.New("f3 failed"))
+	c.Execute()
+	require.False(t, c.Finished())
+
+	f1.Set(0, errors.New("f1 failed"))
+	f2.Set(2, nil)
+	c.Execute()
+	require.True(t, c.Finished())
+
+	require.EqualError(t, err, "f1 failed\nf3 failed")
+	require.Equal(t, []int{0, 2, 0}, results)
+}
@@ -0,0 +1,23 @@
+package workflow
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// Info describes the current workflow execution
+type Info struct {
+	// Deadline is the time the execution timeout of the workflow execution runs out, derived from the recorded start
+	// time of the execution. Zero if the execution was started without a timeout.
+	Deadline time.Time
+}
+
+// GetInfo returns information about the current workflow execution
+func GetInfo(ctx Context) *Info {
+	wfState := workflowstate.WorkflowState(ctx)
+
+	return &Info{
+		Deadline: wfState.Deadline(),
+	}
+}
@@ -0,0 +1,19 @@
+package workflow
+
+import (
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// GetInput returns the serialized inputs the current workflow instance was started with, one for each argument of
+// the workflow function. This allows forwarding inputs without decoding and re-encoding them, e.g., with the default
+// JSON converter an input can be passed on to a sub-workflow or activity as json.RawMessage.
+func GetInput(ctx Context) [][]byte {
+	wfState := workflowstate.WorkflowState(ctx)
+
+	inputs := make([][]byte, len(wfState.Inputs()))
+	for i, input := range wfState.Inputs() {
+		inputs[i] = append([]byte(nil), input...)
+	}
+
+	return inputs
+}
@@ -0,0 +1,31 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// Memoize returns the value memoized for the given key in the current workflow execution. If no value has been
+// memoized yet, f is called and its result memoized.
+//
+// Memoized values are kept in memory only. They are not recorded in the history, so f is called again when the
+// workflow is replayed, and they are not carried over when the workflow continues as new. Like all workflow code,
+// f must be deterministic.
+func Memoize[T any](ctx Context, key string, f func() T) T {
+	wfState := workflowstate.WorkflowState(ctx)
+
+	if v, ok := wfState.Memo(key); ok {
+		r, ok := v.(T)
+		if !ok {
+			panic(fmt.Sprintf("memoized value for key %q is of type %T, not %T", key, v, *new(T)))
+		}
+
+		return r
+	}
+
+	r := f()
+	wfState.SetMemo(key, r)
+
+	return r
+}
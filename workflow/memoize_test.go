@@ -0,0 +1,47 @@
+package workflow
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func Test_Memoize(t *testing.T) {
+	newCtx := func() Context {
+		return workflowstate.WithWorkflowState(
+			sync.Background(),
+			workflowstate.NewWorkflowState(
+				core.NewWorkflowInstance("a", ""), slog.Default(), noop.NewTracerProvider().Tracer("test"), clock.New()),
+		)
+	}
+
+	calls := 0
+	f := func() int {
+		calls++
+		return 42
+	}
+
+	ctx := newCtx()
+
+	require.Equal(t, 42, Memoize(ctx, "a", f))
+	require.Equal(t, 42, Memoize(ctx, "a", f))
+	require.Equal(t, 1, calls)
+
+	// Different key
+	require.Equal(t, 42, Memoize(ctx, "b", f))
+	require.Equal(t, 2, calls)
+
+	// New execution
+	require.Equal(t, 42, Memoize(newCtx(), "a", f))
+	require.Equal(t, 3, calls)
+
+	require.Panics(t, func() {
+		Memoize(ctx, "a", func() string { return "" })
+	})
+}
@@ -0,0 +1,24 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/contextvalue"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// SetProgress reports the progress of the workflow, for example a percentage or a structured status. The latest
+// progress is stored with the workflow instance when the current workflow task completes, and can be read using
+// the client's DescribeWorkflowInstance, even while the workflow is not executing.
+//
+// Progress is not recorded in the history. Executions continued as new start without progress.
+func SetProgress(ctx Context, progress any) error {
+	p, err := contextvalue.Converter(ctx).To(progress)
+	if err != nil {
+		return fmt.Errorf("converting progress: %w", err)
+	}
+
+	workflowstate.WorkflowState(ctx).SetProgress(p)
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/cschleiden/go-workflows/backend/history"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/workflowerrors"
 )
@@ -30,18 +31,44 @@ var DefaultRetryOptions = RetryOptions{
 	BackoffCoefficient: 1,
 }
 
+func toHistoryRetryOptions(retryOptions RetryOptions) *history.RetryOptions {
+	return &history.RetryOptions{
+		MaxAttempts:        retryOptions.MaxAttempts,
+		FirstRetryInterval: retryOptions.FirstRetryInterval,
+		MaxRetryInterval:   retryOptions.MaxRetryInterval,
+		BackoffCoefficient: retryOptions.BackoffCoefficient,
+		RetryTimeout:       retryOptions.RetryTimeout,
+	}
+}
+
+func fromHistoryRetryOptions(retryOptions *history.RetryOptions) RetryOptions {
+	return RetryOptions{
+		MaxAttempts:        retryOptions.MaxAttempts,
+		FirstRetryInterval: retryOptions.FirstRetryInterval,
+		MaxRetryInterval:   retryOptions.MaxRetryInterval,
+		BackoffCoefficient: retryOptions.BackoffCoefficient,
+		RetryTimeout:       retryOptions.RetryTimeout,
+	}
+}
+
 // WithRetries executes the given function with retries.
 func WithRetries[T any](ctx Context, retryOptions RetryOptions, fn func(ctx Context, attempt int) Future[T]) Future[T] {
+	if retryOptions.MaxAttempts <= 1 {
+		// Short-circuit if we don't need to retry
+		return fn(ctx, 0)
+	}
+
+	return withRetries(ctx, func() RetryOptions { return retryOptions }, fn)
+}
+
+// withRetries executes the given function with retries. The retry options are only determined once the first attempt
+// has failed.
+func withRetries[T any](ctx Context, getRetryOptions func() RetryOptions, fn func(ctx Context, attempt int) Future[T]) Future[T] {
 	attempt := 0
 	firstAttempt := Now(ctx)
 
 	f := fn(ctx, attempt)
 
-	if retryOptions.MaxAttempts <= 1 {
-		// Short-circuit if we don't need to retry
-		return f
-	}
-
 	// Start a separate co-routine for retries
 	r := sync.NewFuture[T]()
 
@@ -49,11 +76,6 @@ func WithRetries[T any](ctx Context, retryOptions RetryOptions, fn func(ctx Cont
 		var result T
 		var err error
 
-		var retryExpiration time.Time
-		if retryOptions.RetryTimeout != 0 {
-			retryExpiration = firstAttempt.Add(retryOptions.RetryTimeout)
-		}
-
 		for {
 			// Wait for active operation to finish
 			result, err = f.Get(ctx)
@@ -61,6 +83,8 @@ func WithRetries[T any](ctx Context, retryOptions RetryOptions, fn func(ctx Cont
 				break
 			}
 
+			retryOptions := getRetryOptions()
+
 			attempt++
 
 			if attempt >= retryOptions.MaxAttempts {
@@ -82,7 +106,7 @@ func WithRetries[T any](ctx Context, retryOptions RetryOptions, fn func(ctx Cont
 				backoffDuration = time.Duration(math.Min(float64(backoffDuration), float64(retryOptions.MaxRetryInterval)))
 			}
 
-			if !retryExpiration.IsZero() && Now(ctx).Add(backoffDuration).After(retryExpiration) {
+			if retryOptions.RetryTimeout != 0 && Now(ctx).Add(backoffDuration).After(firstAttempt.Add(retryOptions.RetryTimeout)) {
 				// Waiting would reach maximum retry time, abort retries
 				break
 			}
@@ -1,16 +1,68 @@
 package workflow
 
-import "github.com/cschleiden/go-workflows/internal/sync"
+import (
+	"slices"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/sync"
+)
 
 type SelectCase = sync.SelectCase
 
 // Select is the workflow-save equivalent of the select statement.
+//
+// Unlike the select statement, Select is deterministic: if multiple cases are ready, the first one in the given order
+// is chosen. The workflow continues after every history event, so during replay cases become ready in the same order
+// as during the original execution and Select reproduces the original choice without recording it in the history.
 func Select(ctx Context, cases ...SelectCase) {
 	sync.Select(ctx, cases...)
 }
 
+// Selector selects from cases repeatedly, for workflows that call Select in a loop.
+type Selector struct {
+	s *sync.Selector
+}
+
+// NewSelector returns a selector that behaves like Select.
+func NewSelector() *Selector {
+	return &Selector{s: sync.NewSelector(false)}
+}
+
+// NewFairSelector returns a selector that rotates among ready cases. Every Select starts checking cases after the
+// one selected last, so a case that is always ready, like a buffered channel receiving many signals, cannot starve
+// other cases. Default cases are only selected if no other case is ready. The choice only depends on the order
+// cases become ready and on previous choices of the selector, so it is the same during replay.
+//
+// Cases have to be passed in the same order to every Select.
+func NewFairSelector() *Selector {
+	return &Selector{s: sync.NewSelector(true)}
+}
+
+// Select blocks until one of the given cases is ready and handles it.
+func (s *Selector) Select(ctx Context, cases ...SelectCase) {
+	s.s.Select(ctx, cases...)
+}
+
+// SelectIndex behaves like Select, and returns the index of the selected case. This allows handling cases in a
+// switch statement instead of in handlers, handlers of cases can be nil then. A Receive case with a nil handler
+// discards the received value.
+func (s *Selector) SelectIndex(ctx Context, cases ...SelectCase) int {
+	return s.s.Select(ctx, cases...)
+}
+
+// SelectWithTimeout behaves like Select, but gives up waiting after the given timeout, so that a selector waiting
+// only on signals does not block forever. It returns true if one of the given cases was selected before the timeout
+// expired, false otherwise. See SelectWithTimeout.
+func (s *Selector) SelectWithTimeout(ctx Context, timeout time.Duration, cases ...SelectCase) bool {
+	return selectWithTimeout(ctx, timeout, s.s.Select, cases)
+}
+
 // Await calls the provided handler when the given future is ready.
 func Await[T any](f Future[T], handler func(Context, Future[T])) SelectCase {
+	if handler == nil {
+		return sync.Await[T](f, nil)
+	}
+
 	return sync.Await[T](f, func(ctx sync.Context, f sync.Future[T]) {
 		handler(ctx, f)
 	})
@@ -31,3 +83,50 @@ func Send[T any](c Channel[T], value *T, handler func(ctx Context)) SelectCase {
 func Default(handler func(Context)) SelectCase {
 	return sync.Default(handler)
 }
+
+// SelectWithTimeout behaves like Select, but gives up waiting after the given timeout. It returns true if one
+// of the given cases was selected before the timeout expired, false otherwise. The timer backing the timeout is
+// canceled if one of the cases is selected first. If the timer has fired and a case is ready, the case is selected.
+func SelectWithTimeout(ctx Context, timeout time.Duration, cases ...SelectCase) bool {
+	return selectWithTimeout(ctx, timeout, sync.Select, cases)
+}
+
+func selectWithTimeout(ctx Context, timeout time.Duration, sel func(Context, ...SelectCase) int, cases []SelectCase) bool {
+	tctx, cancel := WithCancel(ctx)
+	defer cancel()
+
+	timedOut := false
+	t := ScheduleTimer(tctx, timeout, WithTimerName("select-timeout"))
+
+	// Clip the cases, appending must not write into the backing array of the caller
+	sel(ctx, append(slices.Clip(cases), &timeoutCase{
+		SelectCase: Await(t, func(ctx Context, f Future[any]) {
+			timedOut = true
+		}),
+		cases: cases,
+	})...)
+
+	return !timedOut
+}
+
+// timeoutCase is only ready if the timer has fired and none of the other cases is ready, also when a fair selector
+// checks it first
+type timeoutCase struct {
+	SelectCase
+
+	cases []SelectCase
+}
+
+func (tc *timeoutCase) Ready() bool {
+	if !tc.SelectCase.Ready() {
+		return false
+	}
+
+	for _, c := range tc.cases {
+		if c.Ready() {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,45 @@
+package workflow
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/backend/converter"
+	"github.com/cschleiden/go-workflows/core"
+	"github.com/cschleiden/go-workflows/internal/contextvalue"
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func Test_SelectWithTimeout_KeepsCallerCases(t *testing.T) {
+	state := workflowstate.NewWorkflowState(
+		core.NewWorkflowInstance("a", ""), slog.Default(), noop.NewTracerProvider().Tracer("test"), clock.New())
+
+	ctx := contextvalue.WithConverter(sync.Background(), converter.DefaultConverter)
+	ctx = workflowstate.WithWorkflowState(ctx, state)
+
+	f := sync.NewFuture[int]()
+	f.Set(42, nil)
+
+	// Cases with spare capacity, the timeout case must not be written into it
+	sentinel := Default(func(Context) {})
+	cases := make([]SelectCase, 2, 3)
+	cases[0] = Await(f, func(Context, Future[int]) {})
+	cases[1] = Default(func(Context) {})
+	spare := cases[:3]
+	spare[2] = sentinel
+
+	c := sync.NewCoroutine(ctx, func(ctx Context) error {
+		require.True(t, SelectWithTimeout(ctx, time.Second, cases...))
+
+		return nil
+	})
+	c.Execute()
+	require.True(t, c.Finished())
+
+	require.Same(t, sentinel, spare[2])
+}
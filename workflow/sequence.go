@@ -0,0 +1,15 @@
+package workflow
+
+import "github.com/cschleiden/go-workflows/internal/workflowstate"
+
+// NextSequence returns the next value of the named counter, starting at 1. Counters with different names are
+// independent of each other.
+//
+// Counters are derived from the order of calls in the workflow code and not recorded in the history. The workflow
+// reproduces the same values when it is replayed, as long as it is deterministic. Counters start over when the
+// workflow continues as new.
+func NextSequence(ctx Context, name string) int {
+	wfState := workflowstate.WorkflowState(ctx)
+
+	return wfState.NextSequence(name)
+}
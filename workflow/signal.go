@@ -32,3 +32,46 @@ func SignalWorkflow[T any](ctx Context, instanceID string, name string, arg T) F
 		Queue: core.QueueSystem,
 	}, a.DeliverWorkflowSignal, instanceID, name, arg)
 }
+
+// OnSignal calls the given handler for every signal with the given name received by the workflow. Handlers run in
+// their own workflow goroutine, one signal at a time and in the order the signals were received, so they are
+// executed deterministically during replay. Handlers for different signal names can be registered side by side.
+//
+// Signals stop being handled when the given context is canceled. Like any other workflow goroutine, a registered
+// handler keeps the workflow from completing, so cancel the context before returning from the workflow:
+//
+//	ctx, cancel := workflow.WithCancel(ctx)
+//	defer cancel()
+//
+//	workflow.OnSignal(ctx, "name", func(ctx workflow.Context, name string) { ... })
+func OnSignal[T any](ctx Context, name string, handler func(ctx Context, v T)) {
+	c := NewSignalChannel[T](ctx, name)
+
+	Go(ctx, func(ctx Context) {
+		done := ctx.Done()
+
+		for {
+			var v T
+			received, canceled := false, false
+
+			if done == nil {
+				v, received = c.Receive(ctx)
+			} else {
+				Select(ctx,
+					Receive(c, func(ctx Context, rv T, ok bool) {
+						v, received = rv, ok
+					}),
+					Receive(done, func(ctx Context, _ struct{}, _ bool) {
+						canceled = true
+					}),
+				)
+			}
+
+			if canceled || !received {
+				return
+			}
+
+			handler(ctx, v)
+		}
+	})
+}
@@ -22,6 +22,10 @@ type SubWorkflowOptions struct {
 	Queue Queue
 
 	RetryOptions RetryOptions
+
+	// ParentClosePolicy determines what happens to the sub-workflow when the calling workflow finishes before it.
+	// Defaults to ParentClosePolicyAbandon.
+	ParentClosePolicy ParentClosePolicy
 }
 
 var (
@@ -101,6 +105,7 @@ func createSubWorkflowInstance[TResult any](ctx Context, options SubWorkflowOpti
 		metadata,
 		workflowSpanID,
 	)
+	cmd.ParentClosePolicy = options.ParentClosePolicy
 
 	wfState.AddCommand(cmd)
 	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(cv, fmt.Sprintf("subworkflow:%s", workflowName), f))
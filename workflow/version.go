@@ -0,0 +1,42 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// DefaultVersion is the version of a change for executions that ran the workflow code before the change was made
+const DefaultVersion = -1
+
+// GetVersion returns the version of the change with the given ID, which allows changing workflow code while
+// instances started with the previous code are still running. The first time an execution reaches the change, the
+// maxSupported version is recorded in its history, and returned for every later call. When replaying a history that
+// reached the code before the change was made, DefaultVersion is returned.
+//
+// An error is returned if the version of the execution is outside of the given range, e.g., because support for an
+// old version has been removed while executions using it are still running.
+func GetVersion(ctx Context, changeID string, minSupported, maxSupported int) (int, error) {
+	wfState := workflowstate.WorkflowState(ctx)
+
+	version, ok := wfState.Version(changeID)
+	if !ok {
+		if Replaying(ctx) {
+			// The history was recorded before the change was made
+			version = DefaultVersion
+		} else {
+			version = maxSupported
+			wfState.AddCommand(command.NewRecordVersionCommand(changeID, version))
+		}
+
+		wfState.SetVersion(changeID, version)
+	}
+
+	if version < minSupported || version > maxSupported {
+		return version, fmt.Errorf(
+			"version %d of change %q is not supported, supported versions are %d to %d", version, changeID, minSupported, maxSupported)
+	}
+
+	return version, nil
+}
@@ -12,6 +12,9 @@ type (
 	// Instance represents a workflow instance.
 	Instance = core.WorkflowInstance
 
+	// ParentClosePolicy determines what happens to a running sub-workflow when its parent finishes.
+	ParentClosePolicy = core.ParentClosePolicy
+
 	// Metadata represents the metadata of a workflow instance.
 	Metadata = metadata.WorkflowMetadata
 
@@ -22,4 +25,8 @@ type (
 
 const (
 	QueueDefault = core.QueueDefault
+
+	ParentClosePolicyAbandon       = core.ParentClosePolicyAbandon
+	ParentClosePolicyRequestCancel = core.ParentClosePolicyRequestCancel
+	ParentClosePolicyTerminate     = core.ParentClosePolicyTerminate
 )